@@ -6,6 +6,7 @@ import (
   "fmt"
   "golang.org/x/sync/errgroup"
   imp31feb4b39618eab1 "github.com/alecthomas/zero/providers/logging"
+  imp5def2d4df5e20b9e "github.com/alecthomas/zero/providers/clock"
   imp71bef56b62085424 "github.com/alecthomas/zero/providers/cron"
   imp9b258f273adc01df "github.com/alecthomas/zero/providers/leases"
   imp9c34c006eb3c10fa "github.com/alecthomas/zero"
@@ -127,6 +128,14 @@ func ZeroConstructSingletons[T any](ctx context.Context, injector *Injector) (ou
 		o := NewCronJobs(p0)
 		return any(o).(T), nil
 
+	case reflect.TypeOf((*imp9c34c006eb3c10fa.Clock)(nil)).Elem():
+		o := imp5def2d4df5e20b9e.Real()
+		return any(o).(T), nil
+
+	case reflect.TypeOf((*imp71bef56b62085424.History)(nil)).Elem():
+		o := imp71bef56b62085424.NewMemoryHistory()
+		return any(o).(T), nil
+
 	case reflect.TypeOf((**imp71bef56b62085424.Scheduler)(nil)).Elem():
 		p0, err := ZeroConstructSingletons[context.Context](ctx, injector)
 		if err != nil {
@@ -140,7 +149,15 @@ func ZeroConstructSingletons[T any](ctx context.Context, injector *Injector) (ou
 		if err != nil {
 			return out, err
 		}
-		o := imp71bef56b62085424.NewScheduler(p0, p1, p2)
+		p3, err := ZeroConstructSingletons[imp71bef56b62085424.History](ctx, injector)
+		if err != nil {
+			return out, err
+		}
+		p4, err := ZeroConstructSingletons[imp9c34c006eb3c10fa.Clock](ctx, injector)
+		if err != nil {
+			return out, err
+		}
+		o := imp71bef56b62085424.NewScheduler(p0, p1, p2, p3, p4)
 		return any(o).(T), nil
 
 	case reflect.TypeOf((**slog.Logger)(nil)).Elem():
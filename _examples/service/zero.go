@@ -305,6 +305,10 @@ func ZeroConstructSingletons[T any](ctx context.Context, injector *Injector) (ou
 		o := imp31feb4b39618eab1.ProvideLogger(p0)
 		return any(o).(T), nil
 
+	case reflect.TypeOf((**imp9c34c006eb3c10fa.Readiness)(nil)).Elem():
+		o := imp3773070ca4e7a2b8.DefaultReadiness()
+		return any(o).(T), nil
+
 	case reflect.TypeOf((**http.ServeMux)(nil)).Elem():
 		o := imp3773070ca4e7a2b8.DefaultServeMux()
 		return any(o).(T), nil
@@ -326,7 +330,11 @@ func ZeroConstructSingletons[T any](ctx context.Context, injector *Injector) (ou
 		if err != nil {
 			return out, err
 		}
-		o := imp3773070ca4e7a2b8.DefaultServer(p0, p1, p2, p3)
+		p4, err := ZeroConstructSingletons[*imp9c34c006eb3c10fa.Readiness](ctx, injector)
+		if err != nil {
+			return out, err
+		}
+		o := imp3773070ca4e7a2b8.DefaultServer(p0, p1, p2, p3, p4)
 		return any(o).(T), nil
 
 	case reflect.TypeOf((*imp9c34c006eb3c10fa.ErrorEncoder)(nil)).Elem():
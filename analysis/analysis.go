@@ -0,0 +1,153 @@
+// Package analysis is the programmatic entry point to Zero, for building custom generators on top of Zero's
+// dependency analysis.
+//
+// A typical generator analyses a package, inspects or extends the resulting [Graph], then generates the
+// bootstrap code:
+//
+//	graph, err := analysis.Analyse(ctx, "./cmd/service", analysis.WithRoots("*example.com/service.Service"))
+//	if err != nil {
+//		return err
+//	}
+//	err = analysis.Generate(w, graph)
+package analysis
+
+import (
+	"context"
+	"io"
+
+	"github.com/alecthomas/errors"
+	"github.com/alecthomas/zero/internal/codewriter"
+	"github.com/alecthomas/zero/internal/depgraph"
+	"github.com/alecthomas/zero/internal/generator"
+)
+
+// Graph is Zero's dependency injection graph.
+type Graph = depgraph.Graph
+
+// Provider is a constructor for a type, annotated with //zero:provider.
+type Provider = depgraph.Provider
+
+// Config is a configuration struct, annotated with //zero:config.
+type Config = depgraph.Config
+
+// Group is a slice of an interface type collected from providers annotated with //zero:provider group=<iface>.
+type Group = depgraph.Group
+
+// API is a method exposed as an API endpoint, annotated with //zero:api.
+type API = depgraph.API
+
+// CronJob is a method called periodically, annotated with //zero:cron.
+type CronJob = depgraph.CronJob
+
+// Subscription is a method subscribed to a pubsub topic, annotated with //zero:subscribe.
+type Subscription = depgraph.Subscription
+
+// Middleware is an HTTP middleware function, annotated with //zero:middleware.
+type Middleware = depgraph.Middleware
+
+// JSONGraph is the machine-readable representation of a [Graph].
+type JSONGraph = depgraph.JSONGraph
+
+// Plugin handles a custom //zero:<directive> annotation, contributing the types it requires to the graph.
+//
+// Plugins that also implement [GeneratorPlugin] generate code for their annotations.
+type Plugin = depgraph.Plugin
+
+// GeneratorPlugin is implemented by a [Plugin] that generates code for its annotations.
+type GeneratorPlugin = generator.Plugin
+
+// Annotation is a declaration annotated with a custom directive handled by a [Plugin].
+type Annotation = depgraph.Annotation
+
+// Writer writes generated code, collecting imports.
+type Writer = codewriter.Writer
+
+// Option configures [Analyse].
+type Option = depgraph.Option
+
+// WithRoots selects a set of root types that will always be included in the graph.
+func WithRoots(roots ...string) Option { return depgraph.WithRoots(roots...) }
+
+// WithProviders selects a provider for a type if multiple are available.
+func WithProviders(pick ...string) Option { return depgraph.WithProviders(pick...) }
+
+// WithPatterns adds additional package patterns to search for annotations.
+func WithPatterns(patterns ...string) Option { return depgraph.WithPatterns(patterns...) }
+
+// WithModules selects all providers in the given modules, as if each had been passed to [WithProviders].
+func WithModules(modules ...string) Option { return depgraph.WithModules(modules...) }
+
+// WithProfiles enables providers annotated with //zero:provider profile=<name> for any of the given profiles.
+func WithProfiles(profiles ...string) Option { return depgraph.WithProfiles(profiles...) }
+
+// WithTags adds build tags used when loading packages.
+func WithTags(tags ...string) Option { return depgraph.WithTags(tags...) }
+
+// WithPlugins registers plugins handling custom //zero:<directive> annotations.
+func WithPlugins(plugins ...Plugin) Option { return depgraph.WithPlugins(plugins...) }
+
+// WithDebug enables debug logging.
+func WithDebug(enable bool) Option { return depgraph.WithDebug(enable) }
+
+// WithTests analyses the _test.go files of the destination package, collecting test providers for [GenerateTest].
+func WithTests(enable bool) Option { return depgraph.WithTests(enable) }
+
+// WithTrailingSlash sets the trailing slash policy of APIs without a slash=<policy> label.
+func WithTrailingSlash(policy string) Option { return depgraph.WithTrailingSlash(policy) }
+
+// WithMod sets the -mod flag of the Go toolchain, eg. "vendor".
+func WithMod(mode string) Option { return depgraph.WithMod(mode) }
+
+// WithFixMod allows "go mod tidy" to be run if the destination module's requirements are out of date.
+func WithFixMod(enable bool) Option { return depgraph.WithFixMod(enable) }
+
+// Analyse loads the Go package in dest, along with its dependencies, and builds Zero's dependency injection graph
+// from their //zero:... annotations.
+func Analyse(ctx context.Context, dest string, options ...Option) (*Graph, error) {
+	graph, err := depgraph.Analyse(ctx, dest, options...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return graph, nil
+}
+
+// GenerateOption configures [Generate].
+type GenerateOption = generator.Option
+
+// WithBuildTags sets the //go:build tags included in the generated code.
+func WithBuildTags(tags ...string) GenerateOption { return generator.WithTags(tags...) }
+
+// Templates are user-supplied template fragments merged with the generated code.
+type Templates = generator.Templates
+
+// WithTemplates merges user-supplied template fragments with the generated code.
+func WithTemplates(templates Templates) GenerateOption { return generator.WithTemplates(templates) }
+
+// Runtime is an alternate entrypoint to Run, eg. "lambda".
+type Runtime = generator.Runtime
+
+// WithRuntimes generates alternate entrypoints to Run for the given runtimes.
+func WithRuntimes(runtimes ...Runtime) GenerateOption { return generator.WithRuntimes(runtimes...) }
+
+// WithFastJSON generates JSON encoders and decoders for API request and response types, avoiding reflection.
+func WithFastJSON(enable bool) GenerateOption { return generator.WithFastJSON(enable) }
+
+// WithBenchmarks generates BenchmarkZeroRoutes in the test harness written by [GenerateTest].
+func WithBenchmarks(enable bool) GenerateOption { return generator.WithBenchmarks(enable) }
+
+// Generate writes Zero's bootstrap code for graph to w.
+func Generate(w io.Writer, graph *Graph, options ...GenerateOption) error {
+	return errors.WithStack(generator.Generate(w, graph, options...))
+}
+
+// GenerateTest writes the test harness for graph to w, for zero_test.go. The graph must be analysed [WithTests].
+func GenerateTest(w io.Writer, graph *Graph, options ...GenerateOption) error {
+	return errors.WithStack(generator.GenerateTest(w, graph, options...))
+}
+
+// Snapshot returns a canonical text serialisation of graph, which only changes when the graph does, for committing and
+// comparing in tests with [DiffSnapshots].
+func Snapshot(graph *Graph) string { return depgraph.Snapshot(graph) }
+
+// DiffSnapshots returns the lines added to and removed from the expected snapshot, prefixed with "+" and "-".
+func DiffSnapshots(expected, actual string) []string { return depgraph.DiffSnapshots(expected, actual) }
@@ -0,0 +1,42 @@
+package analysis
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/alecthomas/zero/internal/buildtesting"
+)
+
+func TestAnalyseAndGenerate(t *testing.T) {
+	t.Parallel()
+	testCode := `
+package main
+
+type DB struct{}
+
+//zero:provider
+func NewDB() *DB {
+	return &DB{}
+}
+
+type Service struct{}
+
+//zero:provider
+func NewService(db *DB) *Service {
+	return &Service{}
+}
+`
+	tmpDir := buildtesting.Prepare(t, testCode)
+	graph, err := Analyse(t.Context(), tmpDir, WithRoots("*test.Service"))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(graph.Providers["*test.DB"]))
+	assert.Equal(t, 1, len(graph.Providers["*test.Service"]))
+	assert.Contains(t, Snapshot(graph), "provider *test.Service test.NewService(*test.DB)\n")
+
+	w := &strings.Builder{}
+	err = Generate(w, graph, WithBuildTags("zero"))
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), "//go:build zero")
+	assert.Contains(t, w.String(), "NewService(")
+}
@@ -0,0 +1,9 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/alecthomas/zero/internal/buildtesting"
+)
+
+func TestMain(m *testing.M) { buildtesting.Run(m) }
@@ -0,0 +1,72 @@
+package a
+
+import (
+	"context"
+	"net/http"
+
+	"keys"
+)
+
+type User struct {
+	Name string
+}
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service { return &Service{} }
+
+//zero:provider
+func NewNothing() {} // want `provider function NewNothing must return \(T\) or \(T, error\)`
+
+/* want `failed to parse pattern` */ //zero:api GET users
+func (s *Service) Invalid() {
+}
+
+/* want `unexpected token "unknown"` */ //zero:unknown
+func (s *Service) Unknown() {
+}
+
+//zero:plugin whatever
+func (s *Service) Custom() {}
+
+//zero:api GET /users/{id}
+func (s *Service) GetUser(id string) (User, error) { return User{}, nil }
+
+//zero:api GET /users
+func (s *Service) ListUsers() (User, string) { return User{}, "" } // want `function ListUsers second return value must be error`
+
+//zero:api GET /users/{id}/posts header=token
+func (s *Service) ListPosts(id string) error { return nil } // want `API method ListPosts has no parameter token to bind to the header Token`
+
+//zero:api GET /me authenticated
+func (s *Service) Me(subject keys.Subject) (User, error) { return User{}, nil }
+
+//zero:api GET /me/posts
+func (s *Service) MyPosts(subject keys.Subject) error { return nil } // want `parameter subject of type keys.Subject requires the authenticated label`
+
+//zero:cron 1h
+func (s *Service) Cleanup(ctx context.Context) error { return nil }
+
+//zero:cron 1h
+func Cleanup(ctx context.Context) error { return nil } // want `//zero:cron annotation is only valid on methods`
+
+//zero:middleware
+func Logging(next http.Handler) http.Handler { return next }
+
+//zero:config prefix="server-"
+type Config struct {
+	Port    int `help:"Port." default:"8080"`
+	Timeout int `defualt:"10"` // want `Timeout: unknown tag "defualt", did you mean "default"\?`
+}
+
+//zero:config
+type ModeConfig struct {
+	Mode string `enum:"a,b" default:"c"` // want `Mode: default "c" must be one of a,b`
+}
+
+//zero:provider
+var DefaultUser = User{Name: "admin"}
+
+//zero:api GET /value
+var Misplaced = 1 // want `only //zero:provider is valid on var and const declarations`
@@ -0,0 +1,4 @@
+package keys
+
+//zero:contextkey authenticated
+type Subject string
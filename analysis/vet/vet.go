@@ -0,0 +1,87 @@
+// Package vet provides an [analysis.Analyzer] that reports invalid //zero: annotations, for use with go vet and editor
+// integrations such as gopls.
+//
+// The analyzer checks each package in isolation, so it reports invalid directives, signatures and config tags without
+// running the full dependency analysis. Use the zero-vet command to run it with go vet:
+//
+//	go vet -vettool=$(which zero-vet) ./...
+package vet
+
+import (
+	"go/types"
+	"strings"
+
+	"github.com/alecthomas/zero/internal/depgraph"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/packages"
+)
+
+// Analyzer reports invalid //zero: annotations.
+var Analyzer = &analysis.Analyzer{
+	Name:      "zero",
+	Doc:       "report invalid //zero: annotations\n\nChecks directive syntax, the signatures of annotated providers, APIs, cron jobs, subscriptions and middleware, and the tags of config structs.",
+	URL:       "https://github.com/alecthomas/zero",
+	Run:       run,
+	FactTypes: []analysis.Fact{new(contextKeyFact)},
+}
+
+var custom stringList
+
+func init() {
+	Analyzer.Flags.Var(&custom, "custom", "comma-separated list of custom directives handled by plugins, which are not checked")
+}
+
+// contextKeyFact is exported for types annotated with //zero:contextkey, so that APIs in dependent packages can
+// receive them.
+type contextKeyFact struct {
+	Label string
+}
+
+func (*contextKeyFact) AFact() {}
+
+func (f *contextKeyFact) String() string { return "contextkey " + f.Label }
+
+// stringList is a comma-separated flag value.
+type stringList []string
+
+func (s *stringList) String() string { return strings.Join(*s, ",") }
+
+func (s *stringList) Set(value string) error {
+	*s = nil
+	for item := range strings.SplitSeq(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*s = append(*s, item)
+		}
+	}
+	return nil
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	contextKeys := map[string]*depgraph.ContextKey{}
+	for _, fact := range pass.AllObjectFacts() {
+		key, ok := fact.Fact.(*contextKeyFact)
+		if !ok {
+			continue
+		}
+		keyType := fact.Object.Type()
+		contextKeys[types.TypeString(keyType, nil)] = &depgraph.ContextKey{Type: keyType, Label: key.Label}
+	}
+	pkg := &packages.Package{
+		ID:        pass.Pkg.Path(),
+		Name:      pass.Pkg.Name(),
+		PkgPath:   pass.Pkg.Path(),
+		Fset:      pass.Fset,
+		Syntax:    pass.Files,
+		Types:     pass.Pkg,
+		TypesInfo: pass.TypesInfo,
+	}
+	for _, diagnostic := range depgraph.Vet(pkg, contextKeys, custom...) {
+		pass.Reportf(diagnostic.Pos, "%s", diagnostic.Message)
+	}
+	for _, key := range contextKeys {
+		if named, ok := key.Type.(*types.Named); ok && named.Obj().Pkg() == pass.Pkg {
+			pass.ExportObjectFact(named.Obj(), &contextKeyFact{Label: key.Label})
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,16 @@
+package vet_test
+
+import (
+	"testing"
+
+	"github.com/alecthomas/zero/analysis/vet"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestAnalyzer(t *testing.T) {
+	err := vet.Analyzer.Flags.Set("custom", "plugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	analysistest.Run(t, analysistest.TestData(), vet.Analyzer, "a")
+}
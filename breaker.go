@@ -0,0 +1,233 @@
+package zero
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/errors"
+)
+
+// ErrBreakerOpen is returned by a [Breaker] that is rejecting calls to a failing dependency.
+//
+// APIs returning it respond with 503 Service Unavailable.
+var ErrBreakerOpen = errors.New("circuit breaker is open")
+
+// BreakerState is the state of a [Breaker].
+type BreakerState int
+
+const (
+	// BreakerClosed permits all calls.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen rejects all calls until its cooldown has elapsed.
+	BreakerOpen
+	// BreakerHalfOpen permits a limited number of probe calls, which close the breaker if they succeed.
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// BreakerConfig configures the thresholds of a [Breaker].
+type BreakerConfig struct {
+	Failures int           `help:"Consecutive failures after which the circuit breaker opens." default:"5"`
+	Cooldown time.Duration `help:"Time the circuit breaker stays open before probing the dependency." default:"30s"`
+	Probes   int           `help:"Concurrent probes permitted while half-open, all of which must succeed to close the circuit breaker." default:"1"`
+}
+
+// BreakerHooks are called by a [Breaker] on events of interest, typically to record metrics. Any hook may be nil.
+type BreakerHooks struct {
+	// OnStateChange is called with the breaker lock held when the breaker changes state.
+	OnStateChange func(name string, from, to BreakerState)
+	// OnReject is called when a call is rejected with [ErrBreakerOpen].
+	OnReject func(name string)
+}
+
+// Breaker is a circuit breaker guarding calls to the dependency T, eg. a database, a downstream service or a topic.
+//
+// A closed breaker permits calls until Failures consecutive calls have failed, at which point it opens and rejects
+// calls with [ErrBreakerOpen] for Cooldown. It then becomes half-open and permits up to Probes concurrent calls. If all
+// of those succeed the breaker closes, while any failure reopens it.
+//
+// Breaker must be created with [NewBreaker], and copies share state.
+type Breaker[T any] struct {
+	state *breakerState
+}
+
+// NewBreaker creates a closed [Breaker] for T.
+func NewBreaker[T any](config BreakerConfig, hooks BreakerHooks) Breaker[T] {
+	return Breaker[T]{state: &breakerState{
+		name:     reflect.TypeFor[T]().String(),
+		config:   config,
+		hooks:    hooks,
+		now:      time.Now,
+		failures: max(config.Failures, 1),
+		probes:   max(config.Probes, 1),
+	}}
+}
+
+// Name of the breaker, which is the name of T.
+func (b Breaker[T]) Name() string { return b.state.name }
+
+// State returns the current state of the breaker.
+func (b Breaker[T]) State() BreakerState {
+	b.state.lock.Lock()
+	defer b.state.lock.Unlock()
+	b.state.refresh()
+	return b.state.current
+}
+
+// Allow reserves a call through the breaker, returning [ErrBreakerOpen] if the call is rejected.
+//
+// Otherwise, done must be called with the result of the call. Context cancellation is not considered a failure.
+func (b Breaker[T]) Allow() (done func(err error), err error) {
+	return b.state.allow()
+}
+
+// Do calls fn if the breaker permits it, recording whether it failed.
+func (b Breaker[T]) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	done, err := b.Allow()
+	if err != nil {
+		return err
+	}
+	err = fn(ctx)
+	done(err)
+	return err
+}
+
+// RoundTripper wraps next such that HTTP requests are guarded by the breaker.
+//
+// Network errors and 5xx responses are considered failures.
+func (b Breaker[T]) RoundTripper(next http.RoundTripper) http.RoundTripper {
+	return breakerRoundTripper{state: b.state, next: next}
+}
+
+type breakerRoundTripper struct {
+	state *breakerState
+	next  http.RoundTripper
+}
+
+func (b breakerRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	done, err := b.state.allow()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := b.next.RoundTrip(req)
+	if err == nil && resp.StatusCode >= 500 {
+		done(errors.Errorf("%s", resp.Status))
+	} else {
+		done(err)
+	}
+	return resp, err
+}
+
+type breakerState struct {
+	name     string
+	config   BreakerConfig
+	hooks    BreakerHooks
+	now      func() time.Time
+	failures int
+	probes   int
+
+	lock        sync.Mutex
+	current     BreakerState
+	generation  int // Incremented on each state change, so that results of calls from previous states are ignored.
+	consecutive int
+	inflight    int
+	succeeded   int
+	openedAt    time.Time
+}
+
+func (s *breakerState) allow() (func(err error), error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.refresh()
+	switch s.current {
+	case BreakerOpen:
+		s.reject()
+		return nil, errors.WithStack(ErrBreakerOpen)
+	case BreakerHalfOpen:
+		if s.inflight >= s.probes {
+			s.reject()
+			return nil, errors.WithStack(ErrBreakerOpen)
+		}
+		s.inflight++
+	case BreakerClosed:
+	}
+	generation := s.generation
+	var once sync.Once
+	return func(err error) {
+		once.Do(func() { s.record(generation, err) })
+	}, nil
+}
+
+func (s *breakerState) record(generation int, err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if generation != s.generation {
+		return
+	}
+	failed := err != nil && !errors.Is(err, context.Canceled)
+	switch s.current {
+	case BreakerClosed:
+		if !failed {
+			s.consecutive = 0
+			return
+		}
+		s.consecutive++
+		if s.consecutive >= s.failures {
+			s.transition(BreakerOpen)
+		}
+	case BreakerHalfOpen:
+		s.inflight--
+		if failed {
+			s.transition(BreakerOpen)
+			return
+		}
+		s.succeeded++
+		if s.succeeded >= s.probes {
+			s.transition(BreakerClosed)
+		}
+	case BreakerOpen:
+	}
+}
+
+// refresh moves an open breaker to half-open once its cooldown has elapsed.
+func (s *breakerState) refresh() {
+	if s.current == BreakerOpen && s.now().Sub(s.openedAt) >= s.config.Cooldown {
+		s.transition(BreakerHalfOpen)
+	}
+}
+
+func (s *breakerState) transition(to BreakerState) {
+	from := s.current
+	s.current = to
+	s.generation++
+	s.consecutive = 0
+	s.inflight = 0
+	s.succeeded = 0
+	if to == BreakerOpen {
+		s.openedAt = s.now()
+	}
+	if s.hooks.OnStateChange != nil {
+		s.hooks.OnStateChange(s.name, from, to)
+	}
+}
+
+func (s *breakerState) reject() {
+	if s.hooks.OnReject != nil {
+		s.hooks.OnReject(s.name)
+	}
+}
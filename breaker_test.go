@@ -0,0 +1,95 @@
+package zero_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/alecthomas/zero"
+)
+
+type breakerDependency struct{}
+
+func TestBreaker(t *testing.T) {
+	var transitions []string
+	rejected := 0
+	breaker := zero.NewBreaker[breakerDependency](zero.BreakerConfig{Failures: 2, Cooldown: 20 * time.Millisecond, Probes: 1}, zero.BreakerHooks{
+		OnStateChange: func(name string, from, to zero.BreakerState) {
+			transitions = append(transitions, name+": "+from.String()+" -> "+to.String())
+		},
+		OnReject: func(name string) { rejected++ },
+	})
+	failure := errors.New("failed")
+	fail := func(ctx context.Context) error { return failure }
+	succeed := func(ctx context.Context) error { return nil }
+	ctx := t.Context()
+
+	assert.Equal(t, failure, breaker.Do(ctx, fail))
+	assert.NoError(t, breaker.Do(ctx, succeed))
+	assert.Equal(t, failure, breaker.Do(ctx, fail))
+	assert.Equal(t, zero.BreakerClosed, breaker.State())
+	assert.Equal(t, failure, breaker.Do(ctx, fail))
+	assert.Equal(t, zero.BreakerOpen, breaker.State())
+
+	called := false
+	err := breaker.Do(ctx, func(ctx context.Context) error { called = true; return nil })
+	assert.IsError(t, err, zero.ErrBreakerOpen)
+	assert.False(t, called)
+	assert.Equal(t, 1, rejected)
+
+	// Once the cooldown has elapsed a single probe is permitted, and its failure reopens the breaker.
+	time.Sleep(30 * time.Millisecond)
+	done, err := breaker.Allow()
+	assert.NoError(t, err)
+	assert.Equal(t, zero.BreakerHalfOpen, breaker.State())
+	_, err = breaker.Allow()
+	assert.IsError(t, err, zero.ErrBreakerOpen)
+	done(failure)
+	assert.Equal(t, zero.BreakerOpen, breaker.State())
+
+	// A successful probe closes it.
+	time.Sleep(30 * time.Millisecond)
+	assert.NoError(t, breaker.Do(ctx, succeed))
+	assert.Equal(t, zero.BreakerClosed, breaker.State())
+
+	assert.Equal(t, []string{
+		"zero_test.breakerDependency: closed -> open",
+		"zero_test.breakerDependency: open -> half-open",
+		"zero_test.breakerDependency: half-open -> open",
+		"zero_test.breakerDependency: open -> half-open",
+		"zero_test.breakerDependency: half-open -> closed",
+	}, transitions)
+}
+
+func TestBreakerIgnoresCancellation(t *testing.T) {
+	breaker := zero.NewBreaker[breakerDependency](zero.BreakerConfig{Failures: 1, Cooldown: time.Minute}, zero.BreakerHooks{})
+	err := breaker.Do(t.Context(), func(ctx context.Context) error { return context.Canceled })
+	assert.IsError(t, err, context.Canceled)
+	assert.Equal(t, zero.BreakerClosed, breaker.State())
+}
+
+func TestBreakerRoundTripper(t *testing.T) {
+	status := http.StatusServiceUnavailable
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	breaker := zero.NewBreaker[breakerDependency](zero.BreakerConfig{Failures: 1, Cooldown: time.Minute}, zero.BreakerHooks{})
+	client := &http.Client{Transport: breaker.RoundTripper(http.DefaultTransport)}
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, server.URL, nil)
+	assert.NoError(t, err)
+
+	resp, err := client.Do(req)
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, zero.BreakerOpen, breaker.State())
+
+	status = http.StatusOK
+	_, err = client.Do(req)
+	assert.IsError(t, err, zero.ErrBreakerOpen)
+}
@@ -0,0 +1,65 @@
+package zero
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/errors"
+)
+
+// ByteSize is a size in bytes, parsed from strings such as "512KB" or "1MB".
+//
+// Units are case-insensitive powers of 1024: B, KB, MB and GB, optionally as KiB, MiB and GiB.
+type ByteSize int64
+
+var byteSizeUnits = []struct {
+	suffix string
+	size   ByteSize
+}{
+	{"kib", 1 << 10}, {"mib", 1 << 20}, {"gib", 1 << 30},
+	{"kb", 1 << 10}, {"mb", 1 << 20}, {"gb", 1 << 30},
+	{"b", 1},
+}
+
+// ParseByteSize parses a size such as "1MB" into a ByteSize.
+func ParseByteSize(s string) (ByteSize, error) {
+	value := strings.ToLower(strings.TrimSpace(s))
+	unit := ByteSize(1)
+	for _, candidate := range byteSizeUnits {
+		if number, ok := strings.CutSuffix(value, candidate.suffix); ok {
+			value = strings.TrimSpace(number)
+			unit = candidate.size
+			break
+		}
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return 0, errors.Errorf("invalid byte size %q", s)
+	}
+	return ByteSize(n) * unit, nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (b *ByteSize) UnmarshalText(text []byte) error {
+	size, err := ParseByteSize(string(text))
+	if err != nil {
+		return err
+	}
+	*b = size
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (b ByteSize) MarshalText() ([]byte, error) { return []byte(b.String()), nil }
+
+func (b ByteSize) String() string {
+	for _, unit := range []struct {
+		suffix string
+		size   ByteSize
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}} {
+		if b != 0 && b%unit.size == 0 {
+			return strconv.FormatInt(int64(b/unit.size), 10) + unit.suffix
+		}
+	}
+	return strconv.FormatInt(int64(b), 10) + "B"
+}
@@ -0,0 +1,44 @@
+package zero_test
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/alecthomas/zero"
+)
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		input string
+		want  zero.ByteSize
+		err   string
+	}{
+		{input: "0", want: 0},
+		{input: "1024", want: 1024},
+		{input: "10B", want: 10},
+		{input: "512KB", want: 512 << 10},
+		{input: "1MB", want: 1 << 20},
+		{input: "1mib", want: 1 << 20},
+		{input: "2 GB", want: 2 << 30},
+		{input: "MB", err: `invalid byte size "MB"`},
+		{input: "-1KB", err: `invalid byte size "-1KB"`},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			size, err := zero.ParseByteSize(test.input)
+			if test.err != "" {
+				assert.EqualError(t, err, test.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, size)
+		})
+	}
+}
+
+func TestByteSizeString(t *testing.T) {
+	assert.Equal(t, "0B", zero.ByteSize(0).String())
+	assert.Equal(t, "1MB", zero.ByteSize(1<<20).String())
+	assert.Equal(t, "1536KB", zero.ByteSize(1536<<10).String())
+	assert.Equal(t, "100B", zero.ByteSize(100).String())
+}
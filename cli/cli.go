@@ -0,0 +1,109 @@
+// Package cli generates shell completion scripts and man pages from the Kong model of a command-line interface, such
+// as the ZeroCLI generated by "zero --cli".
+package cli
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/alecthomas/kong"
+)
+
+// command is a visible command of a CLI, with the flags and arguments that can be given once it has been selected.
+type command struct {
+	// Path of command names from the application, eg. "cron run", or "" for the application itself.
+	path     string
+	node     *kong.Node
+	commands []*kong.Node
+	// Flags of the command and its ancestors, as Kong accepts them anywhere after the command.
+	flags []*kong.Flag
+}
+
+// commands returns the visible commands of app, depth first, starting with the application itself.
+func commands(app *kong.Application) []command {
+	var out []command
+	var walk func(node *kong.Node, path string)
+	walk = func(node *kong.Node, path string) {
+		cmd := command{path: path, node: node}
+		for _, group := range node.AllFlags(true) {
+			cmd.flags = append(cmd.flags, group...)
+		}
+		for _, child := range node.Children {
+			if child.Type == kong.CommandNode && !child.Hidden {
+				cmd.commands = append(cmd.commands, child)
+			}
+		}
+		out = append(out, cmd)
+		for _, child := range cmd.commands {
+			walk(child, strings.TrimSpace(path+" "+child.Name))
+		}
+	}
+	walk(app.Node, "")
+	return out
+}
+
+// commandNames returns the names a command can be given as, including its aliases.
+func commandNames(node *kong.Node) []string {
+	return append([]string{node.Name}, node.Aliases...)
+}
+
+// flagNames returns the long names of a flag, including its aliases and negation, eg. "--tls" and "--no-tls".
+func flagNames(flag *kong.Flag) []string {
+	names := []string{"--" + flag.Name}
+	for _, alias := range flag.Aliases {
+		names = append(names, "--"+alias)
+	}
+	switch flag.Tag.Negatable {
+	case "":
+	case "_":
+		names = append(names, "--no-"+flag.Name)
+	default:
+		names = append(names, "--"+flag.Tag.Negatable)
+	}
+	return names
+}
+
+// takesValue returns true if the flag must be followed by a value.
+func takesValue(flag *kong.Flag) bool {
+	return !flag.IsBool() && !flag.IsCounter()
+}
+
+// isPath returns true if the value of a flag or argument is a path on the filesystem.
+func isPath(value *kong.Value) bool {
+	return slices.Contains([]string{"path", "existingfile", "existingdir", "filecontent"}, value.Tag.Type)
+}
+
+// enumValues returns the values an enum flag or argument accepts, if any.
+func enumValues(value *kong.Value) []string {
+	if value.Enum == "" {
+		return nil
+	}
+	var out []string
+	for _, v := range strings.Split(value.Enum, ",") {
+		out = append(out, strings.TrimSpace(v))
+	}
+	return out
+}
+
+// argumentValues returns the values that can be completed for the positional arguments of a command.
+func argumentValues(node *kong.Node) []string {
+	var out []string
+	for _, positional := range node.Positional {
+		out = append(out, enumValues(positional)...)
+	}
+	return out
+}
+
+var nonIdentifierRe = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// identifier returns a shell function name derived from the name of the application.
+func identifier(name string) string {
+	return "_" + nonIdentifierRe.ReplaceAllString(name, "_")
+}
+
+// summary returns the first line of a help string.
+func summary(help string) string {
+	help, _, _ = strings.Cut(help, "\n")
+	return strings.TrimSpace(help)
+}
@@ -0,0 +1,282 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/alecthomas/errors"
+	"github.com/alecthomas/kong"
+)
+
+// Shells are the shells that [Completion] can write completion scripts for.
+var Shells = []string{"bash", "zsh", "fish"}
+
+// Completion writes a completion script for the CLI described by app to w, for one of [Shells].
+//
+// Scripts complete commands, flags, and the values of enum and path flags and arguments. They are static, so must be
+// regenerated when the CLI changes, eg.
+//
+//	source <(service completions bash)
+func Completion(w io.Writer, app *kong.Application, shell string) error {
+	var script string
+	switch shell {
+	case "bash":
+		script = bashCompletion(app)
+	case "zsh":
+		script = zshCompletion(app)
+	case "fish":
+		script = fishCompletion(app)
+	default:
+		return errors.Errorf("unsupported shell %q, must be one of %s", shell, strings.Join(Shells, ", "))
+	}
+	_, err := io.WriteString(w, script)
+	return errors.WithStack(err)
+}
+
+// transition is a command word that selects a subcommand, eg. "run" after "cron".
+type transition struct {
+	from, word, to string
+}
+
+// transitions returns the words selecting each subcommand, including aliases.
+func transitions(cmds []command) []transition {
+	var out []transition
+	for _, cmd := range cmds {
+		for _, child := range cmd.commands {
+			to := strings.TrimSpace(cmd.path + " " + child.Name)
+			for _, name := range commandNames(child) {
+				out = append(out, transition{from: cmd.path, word: name, to: to})
+			}
+		}
+	}
+	return out
+}
+
+// valueFlags returns the flags of all commands that take a value, sorted by name.
+func valueFlags(cmds []command) []*kong.Flag {
+	seen := map[string]*kong.Flag{}
+	for _, cmd := range cmds {
+		for _, flag := range cmd.flags {
+			if takesValue(flag) {
+				seen[flag.Name] = flag
+			}
+		}
+	}
+	flags := make([]*kong.Flag, 0, len(seen))
+	for _, flag := range seen {
+		flags = append(flags, flag)
+	}
+	slices.SortFunc(flags, func(a, b *kong.Flag) int { return strings.Compare(a.Name, b.Name) })
+	return flags
+}
+
+// valueFlagNames returns the names a flag taking a value can be given as, including its short name.
+func valueFlagNames(flag *kong.Flag) []string {
+	names := []string{"--" + flag.Name}
+	for _, alias := range flag.Aliases {
+		names = append(names, "--"+alias)
+	}
+	if flag.Short != 0 {
+		names = append(names, "-"+string(flag.Short))
+	}
+	return names
+}
+
+// candidate is a word that can be completed, with its description.
+type candidate struct {
+	word, help string
+}
+
+// candidates returns the words that can be completed after a command has been selected, either its flags or its
+// subcommands and argument values, as flags are only completed once "-" has been typed.
+func candidates(cmd command, flags bool) []candidate {
+	var out []candidate
+	if flags {
+		for _, flag := range cmd.flags {
+			for _, name := range flagNames(flag) {
+				out = append(out, candidate{name, summary(flag.Help)})
+			}
+		}
+		return out
+	}
+	for _, child := range cmd.commands {
+		for _, name := range commandNames(child) {
+			out = append(out, candidate{name, summary(child.Help)})
+		}
+	}
+	for _, value := range argumentValues(cmd.node) {
+		out = append(out, candidate{word: value})
+	}
+	return out
+}
+
+func candidateWords(cmd command, flags bool) string {
+	var out []string
+	for _, c := range candidates(cmd, flags) {
+		out = append(out, c.word)
+	}
+	return strings.Join(out, " ")
+}
+
+// zshCandidates formats candidates for _describe, which separates words from their descriptions with ":".
+func zshCandidates(cmd command, flags bool) string {
+	items := []string{}
+	for _, c := range candidates(cmd, flags) {
+		item := strings.ReplaceAll(c.word, ":", `\:`)
+		if c.help != "" {
+			item += ":" + c.help
+		}
+		items = append(items, shellQuote(item))
+	}
+	return strings.Join(items, " ")
+}
+
+// shellQuote quotes s for POSIX shells and zsh.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func bashCompletion(app *kong.Application) string {
+	cmds := commands(app)
+	fn := identifier(app.Name)
+	w := &strings.Builder{}
+	fmt.Fprintf(w, "# bash completion for %s, generated from its Kong model.\n", app.Name)
+	fmt.Fprintf(w, "%s() {\n", fn)
+	fmt.Fprintf(w, "\tlocal cur=\"${COMP_WORDS[COMP_CWORD]}\" prev=\"${COMP_WORDS[COMP_CWORD-1]}\" cmdpath=\"\" i\n")
+	fmt.Fprintf(w, "\tfor ((i = 1; i < COMP_CWORD; i++)); do\n")
+	fmt.Fprintf(w, "\t\tcase \"$cmdpath:${COMP_WORDS[i]}\" in\n")
+	for _, t := range transitions(cmds) {
+		fmt.Fprintf(w, "\t\t%s) cmdpath=%s ;;\n", shellQuote(t.from+":"+t.word), shellQuote(t.to))
+	}
+	fmt.Fprintf(w, "\t\tesac\n")
+	fmt.Fprintf(w, "\tdone\n")
+	fmt.Fprintf(w, "\tcase \"$prev\" in\n")
+	for _, flag := range valueFlags(cmds) {
+		names := strings.Join(quoteAll(valueFlagNames(flag)), "|")
+		switch {
+		case flag.Enum != "":
+			fmt.Fprintf(w, "\t%s) COMPREPLY=($(compgen -W %s -- \"$cur\")); return ;;\n", names, shellQuote(strings.Join(enumValues(flag.Value), " ")))
+		case isPath(flag.Value):
+			fmt.Fprintf(w, "\t%s) COMPREPLY=($(compgen -f -- \"$cur\")); return ;;\n", names)
+		default:
+			fmt.Fprintf(w, "\t%s) COMPREPLY=(); return ;;\n", names)
+		}
+	}
+	fmt.Fprintf(w, "\tesac\n")
+	fmt.Fprintf(w, "\tcase \"$cmdpath:$cur\" in\n")
+	for _, cmd := range cmds {
+		fmt.Fprintf(w, "\t%s-*) COMPREPLY=($(compgen -W %s -- \"$cur\")) ;;\n", shellQuote(cmd.path+":"), shellQuote(candidateWords(cmd, true)))
+		fmt.Fprintf(w, "\t%s*) COMPREPLY=($(compgen -W %s -- \"$cur\")) ;;\n", shellQuote(cmd.path+":"), shellQuote(candidateWords(cmd, false)))
+	}
+	fmt.Fprintf(w, "\tesac\n")
+	fmt.Fprintf(w, "}\n")
+	fmt.Fprintf(w, "complete -F %s %s\n", fn, app.Name)
+	return w.String()
+}
+
+func zshCompletion(app *kong.Application) string {
+	cmds := commands(app)
+	fn := identifier(app.Name)
+	w := &strings.Builder{}
+	fmt.Fprintf(w, "#compdef %s\n", app.Name)
+	fmt.Fprintf(w, "# zsh completion for %s, generated from its Kong model.\n\n", app.Name)
+	fmt.Fprintf(w, "%s() {\n", fn)
+	fmt.Fprintf(w, "\tlocal cmdpath=\"\" i\n")
+	fmt.Fprintf(w, "\tfor ((i = 2; i < CURRENT; i++)); do\n")
+	fmt.Fprintf(w, "\t\tcase \"$cmdpath:${words[i]}\" in\n")
+	for _, t := range transitions(cmds) {
+		fmt.Fprintf(w, "\t\t(%s) cmdpath=%s ;;\n", shellQuote(t.from+":"+t.word), shellQuote(t.to))
+	}
+	fmt.Fprintf(w, "\t\tesac\n")
+	fmt.Fprintf(w, "\tdone\n")
+	fmt.Fprintf(w, "\tcase \"${words[CURRENT-1]}\" in\n")
+	for _, flag := range valueFlags(cmds) {
+		names := strings.Join(quoteAll(valueFlagNames(flag)), "|")
+		switch {
+		case flag.Enum != "":
+			fmt.Fprintf(w, "\t(%s) compadd -- %s; return ;;\n", names, strings.Join(quoteAll(enumValues(flag.Value)), " "))
+		case isPath(flag.Value):
+			fmt.Fprintf(w, "\t(%s) _files; return ;;\n", names)
+		default:
+			fmt.Fprintf(w, "\t(%s) return ;;\n", names)
+		}
+	}
+	fmt.Fprintf(w, "\tesac\n")
+	fmt.Fprintf(w, "\tlocal -a candidates\n")
+	fmt.Fprintf(w, "\tcase \"$cmdpath:$PREFIX\" in\n")
+	for _, cmd := range cmds {
+		fmt.Fprintf(w, "\t(%s-*) candidates=(%s) ;;\n", shellQuote(cmd.path+":"), zshCandidates(cmd, true))
+		fmt.Fprintf(w, "\t(%s*) candidates=(%s) ;;\n", shellQuote(cmd.path+":"), zshCandidates(cmd, false))
+	}
+	fmt.Fprintf(w, "\tesac\n")
+	fmt.Fprintf(w, "\t_describe %s candidates\n", shellQuote(app.Name))
+	fmt.Fprintf(w, "}\n\n")
+	fmt.Fprintf(w, "compdef %s %s\n", fn, app.Name)
+	return w.String()
+}
+
+func fishCompletion(app *kong.Application) string {
+	cmds := commands(app)
+	fn := "_" + identifier(app.Name) + "_at"
+	w := &strings.Builder{}
+	fmt.Fprintf(w, "# fish completion for %s, generated from its Kong model.\n", app.Name)
+	fmt.Fprintf(w, "function %s --description 'Test if the command line has selected the given command'\n", fn)
+	fmt.Fprintf(w, "\tset -l cmdpath ''\n")
+	fmt.Fprintf(w, "\tfor word in (commandline -opc)[2..-1]\n")
+	fmt.Fprintf(w, "\t\tswitch \"$cmdpath:$word\"\n")
+	for _, t := range transitions(cmds) {
+		fmt.Fprintf(w, "\t\t\tcase %s\n", shellQuote(t.from+":"+t.word))
+		fmt.Fprintf(w, "\t\t\t\tset cmdpath %s\n", shellQuote(t.to))
+	}
+	fmt.Fprintf(w, "\t\tend\n")
+	fmt.Fprintf(w, "\tend\n")
+	fmt.Fprintf(w, "\ttest \"$cmdpath\" = \"$argv[1]\"\n")
+	fmt.Fprintf(w, "end\n\n")
+	fmt.Fprintf(w, "complete -c %s -f\n", app.Name)
+	for _, cmd := range cmds {
+		condition := fmt.Sprintf("-n %s", fishQuote(fn+" "+fishQuote(cmd.path)))
+		for _, child := range cmd.commands {
+			for _, name := range commandNames(child) {
+				fmt.Fprintf(w, "complete -c %s %s -a %s -d %s\n", app.Name, condition, fishQuote(name), fishQuote(summary(child.Help)))
+			}
+		}
+		if values := argumentValues(cmd.node); len(values) > 0 {
+			fmt.Fprintf(w, "complete -c %s %s -a %s\n", app.Name, condition, fishQuote(strings.Join(values, " ")))
+		}
+		for _, flag := range cmd.flags {
+			options := ""
+			if flag.Short != 0 {
+				options += " -s " + fishQuote(string(flag.Short))
+			}
+			for _, name := range flagNames(flag) {
+				options += " -l " + fishQuote(strings.TrimPrefix(name, "--"))
+			}
+			switch {
+			case !takesValue(flag):
+			case flag.Enum != "":
+				options += " -x -a " + fishQuote(strings.Join(enumValues(flag.Value), " "))
+			case isPath(flag.Value):
+				options += " -r -F"
+			default:
+				options += " -x"
+			}
+			fmt.Fprintf(w, "complete -c %s %s%s -d %s\n", app.Name, condition, options, fishQuote(summary(flag.Help)))
+		}
+	}
+	return w.String()
+}
+
+// fishQuote quotes s for fish, which escapes quotes within single quotes with a backslash, unlike POSIX shells.
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+func quoteAll(words []string) []string {
+	out := make([]string, len(words))
+	for i, word := range words {
+		out[i] = shellQuote(word)
+	}
+	return out
+}
@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/alecthomas/kong"
+)
+
+type testCLI struct {
+	Bind     string `help:"Address to listen on." default:":8080" env:"SVC_BIND"`
+	Level    string `help:"Log level." enum:"debug,info" default:"info" short:"l"`
+	TLS      bool   `help:"Enable TLS." negatable:""`
+	CertFile string `help:"TLS certificate." type:"path"`
+	Secret   string `hidden:""`
+
+	Serve struct{} `cmd:"" help:"Run the service."`
+	Cron  struct {
+		Run struct {
+			Job   string `arg:"" enum:"cleanup,report" help:"Job to run."`
+			Force bool   `help:"Run even if disabled."`
+		} `cmd:"" help:"Run a cron job once."`
+	} `cmd:"" aliases:"crons" help:"Manage cron jobs."`
+}
+
+func newTestApp(t *testing.T) *kong.Application {
+	t.Helper()
+	var cli testCLI
+	parser, err := kong.New(&cli, kong.Name("svc"), kong.Description("A test service."))
+	assert.NoError(t, err)
+	return parser.Model
+}
+
+func TestCompletionUnsupportedShell(t *testing.T) {
+	err := Completion(&strings.Builder{}, newTestApp(t), "powershell")
+	assert.EqualError(t, err, `unsupported shell "powershell", must be one of bash, zsh, fish`)
+}
+
+func TestBashCompletion(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not found")
+	}
+	script := &strings.Builder{}
+	assert.NoError(t, Completion(script, newTestApp(t), "bash"))
+
+	complete := func(words ...string) string {
+		t.Helper()
+		quoted := make([]string, len(words))
+		for i, word := range words {
+			quoted[i] = shellQuote(word)
+		}
+		cmd := exec.CommandContext(t.Context(), bash, "-c", script.String()+
+			"COMP_WORDS=(svc "+strings.Join(quoted, " ")+")\n"+
+			"COMP_CWORD=${#COMP_WORDS[@]}; COMP_CWORD=$((COMP_CWORD-1))\n"+
+			"_svc\n"+
+			`echo "${COMPREPLY[*]}"`)
+		output, err := cmd.CombinedOutput()
+		assert.NoError(t, err, "%s", output)
+		return strings.TrimSpace(string(output))
+	}
+	assert.Equal(t, "serve cron crons", complete(""))
+	assert.Equal(t, "--level", complete("--le"))
+	assert.Equal(t, "--no-tls", complete("--tls", "--n"), "flags are completed after other flags")
+	assert.Equal(t, "debug info", complete("--level", ""))
+	assert.Equal(t, "debug", complete("-l", "d"))
+	assert.Equal(t, "", complete("--bind", ""), "free-form values are not completed")
+	assert.Equal(t, "run", complete("crons", ""))
+	assert.Equal(t, "cleanup report", complete("cron", "run", ""))
+	assert.Equal(t, "--force", complete("--bind", ":80", "cron", "run", "--f"))
+	assert.Equal(t, "", complete("--sec"), "hidden flags are not completed")
+}
+
+func TestZshCompletion(t *testing.T) {
+	script := &strings.Builder{}
+	assert.NoError(t, Completion(script, newTestApp(t), "zsh"))
+	assert.Contains(t, script.String(), "#compdef svc\n")
+	assert.Contains(t, script.String(), "\t\t('cron:run') cmdpath='cron run' ;;\n")
+	assert.Contains(t, script.String(), "\t('--level'|'-l') compadd -- 'debug' 'info'; return ;;\n")
+	assert.Contains(t, script.String(), "\t('--cert-file') _files; return ;;\n")
+	assert.Contains(t, script.String(), "\t('cron run:'-*) candidates=('--help:Show context-sensitive help.' ")
+	assert.Contains(t, script.String(), "\t('cron:'*) candidates=('run:Run a cron job once.') ;;\n")
+	assert.Contains(t, script.String(), "compdef _svc svc\n")
+}
+
+func TestFishCompletion(t *testing.T) {
+	script := &strings.Builder{}
+	assert.NoError(t, Completion(script, newTestApp(t), "fish"))
+	assert.Contains(t, script.String(), "complete -c svc -n '__svc_at \\'\\'' -a 'serve' -d 'Run the service.'\n")
+	assert.Contains(t, script.String(), "complete -c svc -n '__svc_at \\'cron run\\'' -a 'cleanup report'\n")
+	assert.Contains(t, script.String(), "complete -c svc -n '__svc_at \\'\\'' -s 'l' -l 'level' -x -a 'debug info' -d 'Log level.'\n")
+	assert.Contains(t, script.String(), "-l 'tls' -l 'no-tls' -d 'Enable TLS.'\n")
+	assert.NotContains(t, script.String(), "secret")
+}
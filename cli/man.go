@@ -0,0 +1,127 @@
+package cli
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/alecthomas/errors"
+	"github.com/alecthomas/kong"
+)
+
+// ManPage writes a man page in roff format for the CLI described by app to w, eg.
+//
+//	service man > /usr/local/share/man/man1/service.1
+func ManPage(w io.Writer, app *kong.Application) error {
+	cmds := commands(app)
+	b := &strings.Builder{}
+	fmt.Fprintf(b, ".TH %s 1\n", roff(strings.ToUpper(app.Name)))
+	fmt.Fprintf(b, ".SH NAME\n%s", roff(app.Name))
+	if help := summary(app.Help); help != "" {
+		fmt.Fprintf(b, " \\- %s", roff(help))
+	}
+	fmt.Fprintf(b, "\n.SH SYNOPSIS\n.B %s\n", roff(app.Name))
+	if len(cmds[0].commands) > 0 {
+		b.WriteString("[\\fIflags\\fR] \\fIcommand\\fR [\\fIargs\\fR]\n")
+	} else {
+		b.WriteString("[\\fIflags\\fR]\n")
+	}
+	if detail := strings.TrimSpace(app.Detail); detail != "" {
+		fmt.Fprintf(b, ".SH DESCRIPTION\n%s\n", roffText(detail))
+	}
+
+	if len(cmds) > 1 {
+		b.WriteString(".SH COMMANDS\n")
+		for _, cmd := range cmds[1:] {
+			fmt.Fprintf(b, ".TP\n\\fB%s\\fR", roff(cmd.path))
+			for _, positional := range cmd.node.Positional {
+				fmt.Fprintf(b, " \\fI%s\\fR", roff(positional.Summary()))
+			}
+			b.WriteString("\n")
+			fmt.Fprintf(b, "%s\n", roffText(cmp.Or(cmd.node.Detail, cmd.node.Help)))
+			if len(cmd.node.Aliases) > 0 {
+				fmt.Fprintf(b, ".br\nAliases: %s.\n", roff(strings.Join(cmd.node.Aliases, ", ")))
+			}
+			// Flags of ancestors are described with them.
+			flags := slices.DeleteFunc(slices.Clone(cmd.node.Flags), func(flag *kong.Flag) bool { return flag.Hidden })
+			if len(flags) > 0 {
+				b.WriteString(".RS\n")
+				writeManFlags(b, flags)
+				b.WriteString(".RE\n")
+			}
+		}
+	}
+
+	flags := slices.DeleteFunc(slices.Clone(app.Flags), func(flag *kong.Flag) bool { return flag.Hidden })
+	if len(flags) > 0 {
+		b.WriteString(".SH FLAGS\n")
+		writeManFlags(b, flags)
+	}
+
+	var envs []string
+	for _, cmd := range cmds {
+		for _, flag := range cmd.flags {
+			for _, env := range flag.Envs {
+				envs = append(envs, fmt.Sprintf(".TP\n.B %s\nSets \\fB\\-\\-%s\\fR.\n", roff(env), roff(flag.Name)))
+			}
+		}
+	}
+	slices.Sort(envs)
+	envs = slices.Compact(envs)
+	if len(envs) > 0 {
+		b.WriteString(".SH ENVIRONMENT\n")
+		b.WriteString(strings.Join(envs, ""))
+	}
+	_, err := io.WriteString(w, b.String())
+	return errors.WithStack(err)
+}
+
+func writeManFlags(b *strings.Builder, flags []*kong.Flag) {
+	for _, flag := range flags {
+		names := flagNames(flag)
+		if flag.Short != 0 {
+			names = append([]string{"-" + string(flag.Short)}, names...)
+		}
+		b.WriteString(".TP\n")
+		for i, name := range names {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			fmt.Fprintf(b, "\\fB%s\\fR", roff(name))
+		}
+		if takesValue(flag) {
+			fmt.Fprintf(b, "=\\fI%s\\fR", roff(flag.FormatPlaceHolder()))
+		}
+		b.WriteString("\n")
+		help := flag.Help
+		if values := enumValues(flag.Value); len(values) > 0 && !strings.Contains(help, flag.Enum) {
+			help += fmt.Sprintf(" One of: %s.", strings.Join(values, ", "))
+		}
+		if flag.HasDefault && flag.Default != "" {
+			help += fmt.Sprintf(" Defaults to %s.", flag.Default)
+		}
+		fmt.Fprintf(b, "%s\n", roffText(strings.TrimSpace(help)))
+	}
+}
+
+// roff escapes s for use within a line of roff.
+func roff(s string) string {
+	return strings.NewReplacer(`\`, `\e`, "-", `\-`).Replace(s)
+}
+
+// roffText escapes multi-line text, so that lines starting with control characters are not interpreted as requests,
+// and blank lines separate paragraphs.
+func roffText(s string) string {
+	lines := strings.Split(roff(s), "\n")
+	for i, line := range lines {
+		switch {
+		case strings.TrimSpace(line) == "":
+			lines[i] = ".PP"
+		case strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'"):
+			lines[i] = `\&` + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,22 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestManPage(t *testing.T) {
+	page := &strings.Builder{}
+	assert.NoError(t, ManPage(page, newTestApp(t)))
+	assert.Contains(t, page.String(), ".TH SVC 1\n.SH NAME\nsvc \\- A test service.\n")
+	assert.Contains(t, page.String(), ".SH COMMANDS\n.TP\n\\fBserve\\fR\nRun the service.\n")
+	assert.Contains(t, page.String(), ".TP\n\\fBcron\\fR\nManage cron jobs.\n.br\nAliases: crons.\n")
+	assert.Contains(t, page.String(), ".TP\n\\fBcron run\\fR \\fI<job>\\fR\nRun a cron job once.\n"+
+		".RS\n.TP\n\\fB\\-\\-force\\fR\nRun even if disabled.\n.RE\n")
+	assert.Contains(t, page.String(), ".TP\n\\fB\\-l\\fR, \\fB\\-\\-level\\fR=\\fI\"info\"\\fR\nLog level. One of: debug, info. Defaults to info.\n")
+	assert.Contains(t, page.String(), ".TP\n\\fB\\-\\-tls\\fR, \\fB\\-\\-no\\-tls\\fR\nEnable TLS.\n")
+	assert.Contains(t, page.String(), ".SH ENVIRONMENT\n.TP\n.B SVC_BIND\nSets \\fB\\-\\-bind\\fR.\n")
+	assert.NotContains(t, page.String(), "secret")
+}
@@ -0,0 +1,22 @@
+package zero
+
+import "time"
+
+// Clock is a source of time.
+//
+// Providers with time-dependent behaviour, such as cron schedules and cache expiry, depend on a Clock rather than the
+// time package so that tests can substitute a fake. The default provider returns [RealClock].
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// After returns a channel that receives the current time once d has elapsed.
+	After(d time.Duration) <-chan time.Time
+}
+
+// RealClock is a [Clock] backed by the system clock.
+type RealClock struct{}
+
+var _ Clock = RealClock{}
+
+func (RealClock) Now() time.Time                         { return time.Now() }
+func (RealClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
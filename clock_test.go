@@ -0,0 +1,16 @@
+package zero_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/alecthomas/zero"
+)
+
+func TestRealClock(t *testing.T) {
+	var clock zero.Clock = zero.RealClock{}
+	start := clock.Now()
+	fired := <-clock.After(time.Millisecond)
+	assert.False(t, fired.Before(start.Add(time.Millisecond)))
+}
@@ -0,0 +1,11 @@
+// Command zero-vet reports invalid //zero: annotations when run by go vet:
+//
+//	go vet -vettool=$(which zero-vet) ./...
+package main
+
+import (
+	"github.com/alecthomas/zero/analysis/vet"
+	"golang.org/x/tools/go/analysis/unitchecker"
+)
+
+func main() { unitchecker.Main(vet.Analyzer) }
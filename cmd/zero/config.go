@@ -0,0 +1,337 @@
+package main
+
+import (
+	"cmp"
+	"go/build"
+	"io"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/alecthomas/errors"
+	"github.com/alecthomas/kong"
+	kongtoml "github.com/alecthomas/kong-toml"
+	"github.com/alecthomas/zero/internal/depgraph"
+	"github.com/alecthomas/zero/internal/generator"
+	"github.com/alecthomas/zero/internal/lint"
+	"github.com/pelletier/go-toml"
+)
+
+// profileConfig is the configuration for a single [profiles.<name>] section of .zero.toml.
+//
+// The keys are the same as the corresponding command-line flags.
+type profileConfig struct {
+	Root     []string `toml:"root"`
+	Resolve  []string `toml:"resolve"`
+	Module   []string `toml:"module"`
+	Patterns []string `toml:"patterns"`
+}
+
+// profiles loaded from the configuration file, keyed by name.
+var profiles = map[string]profileConfig{}
+
+// serviceConfig is the configuration for a single [services.<name>] section of .zero.toml, a destination package
+// generated with its own roots and picks.
+//
+// The keys are the same as the corresponding command-line flags, and are combined with them.
+type serviceConfig struct {
+	Dest    string   `toml:"dest"`
+	Root    []string `toml:"root"`
+	Resolve []string `toml:"resolve"`
+	Module  []string `toml:"module"`
+	Library bool     `toml:"library"`
+}
+
+// services loaded from the configuration file, keyed by name.
+var services = map[string]serviceConfig{}
+
+// environmentConfig is the configuration for a single [environments.<name>] section of .zero.toml, selecting the
+// providers of zero_<name>.go, which is guarded by its output tags so that each environment is compiled separately.
+//
+// The keys are the same as the corresponding command-line flags, and are combined with them.
+type environmentConfig struct {
+	OutputTags []string `toml:"output-tags"`
+	Root       []string `toml:"root"`
+	Resolve    []string `toml:"resolve"`
+	Module     []string `toml:"module"`
+}
+
+// environments loaded from the configuration file, keyed by name.
+var environments = map[string]environmentConfig{}
+
+// lintRules loaded from [[lint]] sections of the configuration file.
+var lintRules []lint.Rule
+
+// budget loaded from the [budget] section of the configuration file.
+var budget lint.Budget
+
+// templates loaded from the [templates] section of the configuration file.
+var templates generator.Templates
+
+// openAPIConfig loaded from the [openapi] section of the configuration file.
+var openAPIConfig depgraph.OpenAPIConfig
+
+// configLoader loads .zero.toml, extracting [profiles.<name>] sections into profiles, [services.<name>] sections into
+// services, [environments.<name>] sections into environments, [[lint]] sections into lintRules, the [budget] section
+// into budget, the [templates] section into templates and the [openapi] section into openAPIConfig, before passing the
+// remaining configuration through to the kong-toml resolver.
+func configLoader(r io.Reader) (kong.Resolver, error) {
+	tree, err := toml.LoadReader(r)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if profileTree, ok := tree.Get("profiles").(*toml.Tree); ok {
+		for _, name := range profileTree.Keys() {
+			section, ok := profileTree.Get(name).(*toml.Tree)
+			if !ok {
+				return nil, errors.Errorf("profiles.%s: expected a table", name)
+			}
+			var profile profileConfig
+			if err := section.Unmarshal(&profile); err != nil {
+				return nil, errors.Errorf("profiles.%s: %w", name, err)
+			}
+			profiles[name] = profile
+		}
+		if err := tree.Delete("profiles"); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	if serviceTree, ok := tree.Get("services").(*toml.Tree); ok {
+		for _, name := range serviceTree.Keys() {
+			section, ok := serviceTree.Get(name).(*toml.Tree)
+			if !ok {
+				return nil, errors.Errorf("services.%s: expected a table", name)
+			}
+			var service serviceConfig
+			if err := section.Unmarshal(&service); err != nil {
+				return nil, errors.Errorf("services.%s: %w", name, err)
+			}
+			services[name] = service
+		}
+		if err := tree.Delete("services"); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	if environmentTree, ok := tree.Get("environments").(*toml.Tree); ok {
+		for _, name := range environmentTree.Keys() {
+			section, ok := environmentTree.Get(name).(*toml.Tree)
+			if !ok {
+				return nil, errors.Errorf("environments.%s: expected a table", name)
+			}
+			var environment environmentConfig
+			if err := section.Unmarshal(&environment); err != nil {
+				return nil, errors.Errorf("environments.%s: %w", name, err)
+			}
+			environments[name] = environment
+		}
+		if err := tree.Delete("environments"); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	if tree.Has("lint") {
+		sections, ok := tree.Get("lint").([]*toml.Tree)
+		if !ok {
+			return nil, errors.Errorf("lint: expected an array of tables")
+		}
+		for i, section := range sections {
+			var rule lint.Rule
+			if err := section.Unmarshal(&rule); err != nil {
+				return nil, errors.Errorf("lint[%d]: %w", i, err)
+			}
+			lintRules = append(lintRules, rule)
+		}
+		if err := tree.Delete("lint"); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	if tree.Has("budget") {
+		section, ok := tree.Get("budget").(*toml.Tree)
+		if !ok {
+			return nil, errors.Errorf("budget: expected a table")
+		}
+		if err := section.Unmarshal(&budget); err != nil {
+			return nil, errors.Errorf("budget: %w", err)
+		}
+		if err := tree.Delete("budget"); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	if tree.Has("templates") {
+		section, ok := tree.Get("templates").(*toml.Tree)
+		if !ok {
+			return nil, errors.Errorf("templates: expected a table")
+		}
+		if err := section.Unmarshal(&templates); err != nil {
+			return nil, errors.Errorf("templates: %w", err)
+		}
+		if err := tree.Delete("templates"); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	if tree.Has("openapi") {
+		section, ok := tree.Get("openapi").(*toml.Tree)
+		if !ok {
+			return nil, errors.Errorf("openapi: expected a table")
+		}
+		if err := section.Unmarshal(&openAPIConfig); err != nil {
+			return nil, errors.Errorf("openapi: %w", err)
+		}
+		if err := openAPIConfig.Validate(); err != nil {
+			return nil, errors.Errorf("openapi: %w", err)
+		}
+		if err := tree.Delete("openapi"); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	remaining, err := tree.ToTomlString()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var name string
+	if named, ok := r.(interface{ Name() string }); ok {
+		name = named.Name()
+	}
+	return kongtoml.Loader(namedReader{Reader: strings.NewReader(remaining), name: name})
+}
+
+// applyProfiles merges the configuration for each selected profile into the CLI, after the flags and top-level keys,
+// in the order the profiles were selected.
+//
+// It returns the selected profiles without a [profiles.<name>] section, which must instead enable providers annotated
+// with profile=<name>, as checked by [checkProfiles] once the providers have been discovered.
+func applyProfiles(names []string) (unconfigured []string) {
+	for _, name := range names {
+		profile, ok := profiles[name]
+		if !ok {
+			unconfigured = append(unconfigured, name)
+			continue
+		}
+		cli.Root = append(cli.Root, profile.Root...)
+		cli.Resolve = append(cli.Resolve, profile.Resolve...)
+		cli.Module = append(cli.Module, profile.Module...)
+		cli.Patterns = append(cli.Patterns, profile.Patterns...)
+	}
+	return unconfigured
+}
+
+// checkProfiles returns an error for the first unconfigured profile that is not one of the annotated profiles of the
+// discovered providers, as selecting it would otherwise have no effect.
+func checkProfiles(unconfigured, annotated []string) error {
+	for _, name := range unconfigured {
+		if !slices.Contains(annotated, name) {
+			return errors.Errorf("unknown profile %q, expected a [profiles.%s] section in the configuration file or a provider annotated with profile=%s", name, name, name)
+		}
+	}
+	return nil
+}
+
+// destination is a destination package to generate, for one of the configured environments if there are any.
+type destination struct {
+	depgraph.Destination
+	// Environment is the name of the [environments.<name>] section to generate, or empty to generate zero.go.
+	Environment string
+}
+
+// destinations returns the destination packages to generate, from --dest and the selected [services.<name>] sections,
+// once for each selected [environments.<name>] section.
+//
+// If neither --dest nor --service is given, all configured services are generated, or the current directory if there
+// are none. Likewise, all configured environments are generated if no --environment is given.
+func destinations() ([]destination, error) {
+	type base struct {
+		dir     string
+		service serviceConfig
+	}
+	var bases []base
+	for _, dir := range cli.Dest {
+		bases = append(bases, base{dir: dir})
+	}
+	names := cli.Service
+	if len(names) == 0 && len(cli.Dest) == 0 {
+		names = slices.Sorted(maps.Keys(services))
+	}
+	for _, name := range names {
+		service, ok := services[name]
+		if !ok {
+			return nil, errors.Errorf("unknown service %q, expected a [services.%s] section in the configuration file", name, name)
+		}
+		bases = append(bases, base{dir: cmp.Or(service.Dest, "."), service: service})
+	}
+	if len(bases) == 0 {
+		bases = append(bases, base{dir: "."})
+	}
+	envNames := cli.Environment
+	if len(envNames) == 0 {
+		envNames = slices.Sorted(maps.Keys(environments))
+	}
+	for _, name := range envNames {
+		environment, ok := environments[name]
+		if !ok {
+			return nil, errors.Errorf("unknown environment %q, expected an [environments.%s] section in the configuration file", name, name)
+		}
+		if len(environment.OutputTags) == 0 {
+			return nil, errors.Errorf("environments.%s: output-tags are required, so that only one environment is compiled", name)
+		}
+		if _, err := environmentFile(name, false); err != nil {
+			return nil, errors.Errorf("environments.%s: %w", name, err)
+		}
+	}
+	if len(envNames) == 0 {
+		envNames = []string{""}
+	}
+	var dests []destination
+	for _, base := range bases {
+		dir, err := filepath.Abs(filepath.Join(string(cli.Chdir), base.dir))
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		for _, name := range envNames {
+			environment := environments[name]
+			dest := destination{Destination: depgraph.Destination{Dir: dir}, Environment: name}
+			if roots := slices.Concat(base.service.Root, environment.Root); len(roots) > 0 {
+				dest.Options = append(dest.Options, depgraph.WithRoots(slices.Concat(cli.Root, roots)...))
+			}
+			if resolve := slices.Concat(base.service.Resolve, environment.Resolve); len(resolve) > 0 {
+				dest.Options = append(dest.Options, depgraph.WithProviders(slices.Concat(cli.Resolve, resolve)...))
+			}
+			if modules := slices.Concat(base.service.Module, environment.Module); len(modules) > 0 {
+				dest.Options = append(dest.Options, depgraph.WithModules(slices.Concat(cli.Module, modules)...))
+			}
+			if base.service.Library {
+				dest.Options = append(dest.Options, depgraph.WithLibrary(true))
+			}
+			dests = append(dests, dest)
+		}
+	}
+	return dests, nil
+}
+
+// environmentFile returns the name of the file generated for an environment, or its test harness.
+//
+// Environments are rejected if the go command would otherwise treat the file as a test, or only build it for an
+// operating system or architecture, eg. zero_linux.go.
+func environmentFile(name string, test bool) (string, error) {
+	file := "zero.go"
+	if name != "" {
+		file = "zero_" + name + ".go"
+	}
+	ctx := build.Context{GOOS: "none", GOARCH: "none", Compiler: "gc", OpenFile: func(string) (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader("package zero\n")), nil
+	}}
+	if match, err := ctx.MatchFile(".", file); err != nil || !match || strings.HasSuffix(file, "_test.go") {
+		return "", errors.Errorf("environment %q can't be used in a file name, as %s would be treated specially by the go command", name, file)
+	}
+	if test {
+		file = strings.TrimSuffix(file, ".go") + "_test.go"
+	}
+	return file, nil
+}
+
+// namedReader preserves the configuration filename for kong-toml error messages.
+type namedReader struct {
+	io.Reader
+	name string
+}
+
+func (n namedReader) Name() string { return n.name }
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestConfigLoaderProfiles(t *testing.T) {
+	t.Cleanup(func() { profiles = map[string]profileConfig{} })
+	_, err := configLoader(strings.NewReader(`
+root = ["*example.com/service.Service"]
+
+[profiles.production]
+resolve = ["github.com/alecthomas/zero/providers/pubsub/postgres.New"]
+module = ["postgres"]
+
+[profiles.debug]
+root = ["*example.com/service.Debug"]
+patterns = ["./debug/..."]
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]profileConfig{
+		"production": {
+			Resolve: []string{"github.com/alecthomas/zero/providers/pubsub/postgres.New"},
+			Module:  []string{"postgres"},
+		},
+		"debug": {
+			Root:     []string{"*example.com/service.Debug"},
+			Patterns: []string{"./debug/..."},
+		},
+	}, profiles)
+
+	_, err = configLoader(strings.NewReader(`profiles = { production = "postgres" }`))
+	assert.EqualError(t, err, "profiles.production: expected a table")
+}
+
+func TestApplyProfiles(t *testing.T) {
+	saved := cli
+	t.Cleanup(func() {
+		cli = saved
+		profiles = map[string]profileConfig{}
+	})
+	profiles = map[string]profileConfig{
+		"production": {Resolve: []string{"postgres.New"}, Module: []string{"postgres"}},
+		"debug":      {Root: []string{"*service.Debug"}, Resolve: []string{"memory.New"}, Patterns: []string{"./debug/..."}},
+	}
+	cli.Root = []string{"*service.Service"}
+	cli.Resolve = []string{"sql.New"}
+
+	unconfigured := applyProfiles([]string{"debug", "dev", "production"})
+	assert.Equal(t, []string{"dev"}, unconfigured)
+	// Flags come first, followed by each profile in the order selected.
+	assert.Equal(t, []string{"*service.Service", "*service.Debug"}, cli.Root)
+	assert.Equal(t, []string{"sql.New", "memory.New", "postgres.New"}, cli.Resolve)
+	assert.Equal(t, []string{"postgres"}, cli.Module)
+	assert.Equal(t, []string{"./debug/..."}, cli.Patterns)
+}
+
+func TestCheckProfiles(t *testing.T) {
+	assert.NoError(t, checkProfiles(nil, nil))
+	// Profiles without a section may still enable annotated providers.
+	assert.NoError(t, checkProfiles([]string{"dev"}, []string{"dev", "prod"}))
+	assert.EqualError(t, checkProfiles([]string{"dev", "staging"}, []string{"dev", "prod"}),
+		`unknown profile "staging", expected a [profiles.staging] section in the configuration file or a provider annotated with profile=staging`)
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/errors"
+	"github.com/alecthomas/zero/internal/depgraph"
+	"github.com/pelletier/go-toml"
+)
+
+// candidate is a provider that can be selected interactively.
+type candidate struct {
+	// Resolve is the value of --resolve selecting the provider.
+	Resolve string
+	// Detail describes the provider, eg. "(weak) at providers/sql/sql.go:156:1".
+	Detail string
+}
+
+// resolveInteractively analyses the destinations, and whenever a type has ambiguous providers, or a dependency is
+// missing a provider but weak providers of its type exist, prompts for the provider to select. Each selection is added
+// to --resolve and to the resolve list of the configuration file, before analysing again.
+func resolveInteractively(analyse func() ([]*depgraph.Graph, error)) ([]*depgraph.Graph, error) {
+	in := bufio.NewReader(os.Stdin)
+	path := cmp.Or(string(cli.Config), ".zero.toml")
+	for {
+		graphs, err := analyse()
+		typ, candidates := interactiveCandidates(graphs, err)
+		if len(candidates) == 0 {
+			return graphs, err
+		}
+		selected, perr := prompt(in, os.Stderr, typ, candidates)
+		if perr != nil {
+			return nil, errors.Join(err, perr)
+		}
+		// A selection that didn't resolve the type would otherwise be prompted for forever.
+		if slices.Contains(cli.Resolve, selected.Resolve) {
+			return graphs, err
+		}
+		cli.Resolve = append(cli.Resolve, selected.Resolve)
+		if err := addResolve(path, selected.Resolve); err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(os.Stderr, "Added %s to resolve in %s\n", selected.Resolve, path)
+	}
+}
+
+// interactiveCandidates returns the first type that requires a provider to be selected, and its candidates.
+func interactiveCandidates(graphs []*depgraph.Graph, err error) (string, []candidate) {
+	var ambiguous *depgraph.AmbiguousProvidersError
+	if errors.As(err, &ambiguous) {
+		var candidates []candidate
+		for _, provider := range ambiguous.Candidates {
+			detail := ""
+			if provider.Directive.Weak {
+				detail = "(weak) "
+			}
+			detail += "at " + relativePosition(provider.Position.String())
+			candidates = append(candidates, candidate{Resolve: ambiguous.Resolve(provider), Detail: detail})
+		}
+		return ambiguous.Type, candidates
+	}
+	if err != nil {
+		return "", nil
+	}
+	for _, graph := range graphs {
+		for _, dep := range graph.MissingDependencies() {
+			if len(dep.Candidates) == 0 {
+				continue
+			}
+			var candidates []candidate
+			for _, name := range dep.Candidates {
+				candidates = append(candidates, candidate{Resolve: name, Detail: "(weak)"})
+			}
+			return dep.Type.String(), candidates
+		}
+	}
+	return "", nil
+}
+
+// prompt writes a numbered list of candidates to w, and reads the number of the selected candidate from r until a
+// valid number is entered.
+func prompt(r *bufio.Reader, w io.Writer, typ string, candidates []candidate) (candidate, error) {
+	fmt.Fprintf(w, "Select a provider for %s:\n", typ)
+	for i, candidate := range candidates {
+		fmt.Fprintf(w, "  %d) %s %s\n", i+1, candidate.Resolve, candidate.Detail)
+	}
+	for {
+		fmt.Fprintf(w, "Provider [1-%d]: ", len(candidates))
+		line, err := r.ReadString('\n')
+		if n, perr := strconv.Atoi(strings.TrimSpace(line)); perr == nil && n >= 1 && n <= len(candidates) {
+			return candidates[n-1], nil
+		}
+		if err != nil {
+			return candidate{}, errors.Errorf("no provider selected for %s: %w", typ, err)
+		}
+	}
+}
+
+// addResolve appends a provider to the top-level resolve list of the TOML configuration file at path, creating the
+// file if necessary.
+//
+// Only the resolve key is rewritten, rather than re-encoding the file, so that the rest of it is preserved as is.
+func addResolve(path, resolve string) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return errors.WithStack(err)
+	}
+	tree, err := toml.LoadBytes(data)
+	if err != nil {
+		return errors.Errorf("%s: %w", path, err)
+	}
+	var values []string
+	if existing, ok := tree.Get("resolve").([]any); ok {
+		for _, value := range existing {
+			if value, ok := value.(string); ok {
+				values = append(values, value)
+			}
+		}
+	}
+	values = append(values, resolve)
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = strconv.Quote(value)
+	}
+	entry := []string{"resolve = [" + strings.Join(quoted, ", ") + "]"}
+	if len(values) > 1 {
+		entry = []string{"resolve = ["}
+		for _, value := range quoted {
+			entry = append(entry, "  "+value+",")
+		}
+		entry = append(entry, "]")
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(data) == 0 {
+		lines = nil
+	}
+	start, end := resolveSpan(lines)
+	if start == -1 {
+		// Top-level keys must precede the first table, and comments immediately preceding it belong to the table.
+		start = slices.IndexFunc(lines, func(line string) bool { return strings.HasPrefix(strings.TrimSpace(line), "[") })
+		if start == -1 {
+			start = len(lines)
+		}
+		for start > 0 && strings.HasPrefix(strings.TrimSpace(lines[start-1]), "#") {
+			start--
+		}
+		end = start
+		if start < len(lines) {
+			entry = append(entry, "")
+		}
+	}
+	lines = slices.Concat(lines[:start], entry, lines[end:])
+	return errors.WithStack(os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600))
+}
+
+// resolveSpan returns the range of lines containing the top-level resolve key and its value, or -1 if there is none.
+func resolveSpan(lines []string) (start, end int) {
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") {
+			break
+		}
+		key, _, ok := strings.Cut(trimmed, "=")
+		if !ok || strings.TrimSpace(key) != "resolve" {
+			continue
+		}
+		// The value ends when its brackets are balanced, ignoring brackets in strings, eg. "pubsub.Topic[T]=New".
+		depth := 0
+		var quote rune
+		for j := i; j < len(lines); j++ {
+			escaped := false
+		chars:
+			for _, r := range lines[j] {
+				switch {
+				case escaped:
+					escaped = false
+				case quote == '"' && r == '\\':
+					escaped = true
+				case quote != 0:
+					if r == quote {
+						quote = 0
+					}
+				case r == '"' || r == '\'':
+					quote = r
+				case r == '#':
+					break chars
+				case r == '[':
+					depth++
+				case r == ']':
+					depth--
+				}
+			}
+			if depth == 0 {
+				return i, j + 1
+			}
+		}
+		return i, len(lines)
+	}
+	return -1, -1
+}
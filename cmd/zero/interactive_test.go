@@ -0,0 +1,97 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestAddResolve(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing *string
+		resolve  string
+		expected string
+	}{
+		{
+			name:     "MissingFile",
+			resolve:  "example.com/service.NewStore",
+			expected: "resolve = [\"example.com/service.NewStore\"]\n",
+		},
+		{
+			name:     "EmptyFile",
+			existing: ptr(""),
+			resolve:  "example.com/service.NewStore",
+			expected: "resolve = [\"example.com/service.NewStore\"]\n",
+		},
+		{
+			name:     "ExistingSingleLine",
+			existing: ptr("root = [\"*example.com/service.Service\"]\nresolve = [\"a.New\"] # selected\n\n[budget]\nmax-providers = 10\n"),
+			resolve:  "b.New",
+			expected: "root = [\"*example.com/service.Service\"]\nresolve = [\n  \"a.New\",\n  \"b.New\",\n]\n\n[budget]\nmax-providers = 10\n",
+		},
+		{
+			name:     "ExistingMultiLine",
+			existing: ptr("resolve = [\n  \"a.New\", # first\n  \"b.New\",\n]\nroot = [\"*example.com/service.Service\"]\n"),
+			resolve:  "c.New",
+			expected: "resolve = [\n  \"a.New\",\n  \"b.New\",\n  \"c.New\",\n]\nroot = [\"*example.com/service.Service\"]\n",
+		},
+		{
+			name:     "ValueContainingBracket",
+			existing: ptr("resolve = [\n  \"pubsub.Topic[T]=New\",\n  'x.Map[K, V]=New',\n]\nroot = [\"*example.com/service.Service\"]\n"),
+			resolve:  "c.New",
+			expected: "resolve = [\n  \"pubsub.Topic[T]=New\",\n  \"x.Map[K, V]=New\",\n  \"c.New\",\n]\nroot = [\"*example.com/service.Service\"]\n",
+		},
+		{
+			name:     "CommentsBeforeFirstTable",
+			existing: ptr("root = [\"*example.com/service.Service\"]\n\n# Budget for the service.\n[budget]\nmax-providers = 10\n"),
+			resolve:  "a.New",
+			expected: "root = [\"*example.com/service.Service\"]\n\nresolve = [\"a.New\"]\n\n# Budget for the service.\n[budget]\nmax-providers = 10\n",
+		},
+		{
+			name:     "ResolveInTableIsNotTopLevel",
+			existing: ptr("[services.api]\nresolve = [\"a.New\"]\n"),
+			resolve:  "b.New",
+			expected: "resolve = [\"b.New\"]\n\n[services.api]\nresolve = [\"a.New\"]\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ".zero.toml")
+			if test.existing != nil {
+				assert.NoError(t, os.WriteFile(path, []byte(*test.existing), 0600))
+			}
+			assert.NoError(t, addResolve(path, test.resolve))
+			data, err := os.ReadFile(path)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, string(data))
+		})
+	}
+}
+
+func TestResolveSpan(t *testing.T) {
+	tests := []struct {
+		name       string
+		lines      []string
+		start, end int
+	}{
+		{"None", []string{"root = []"}, -1, -1},
+		{"SingleLine", []string{"root = []", "resolve = [\"a.New\"]", "dest = \".\""}, 1, 2},
+		{"MultiLine", []string{"resolve = [", "  \"a.New\",", "]", "dest = \".\""}, 0, 3},
+		{"BracketInString", []string{"resolve = [", "  \"pubsub.Topic[T]=New\",", "]"}, 0, 3},
+		{"BracketInComment", []string{"resolve = [ # [", "  \"a.New\",", "]"}, 0, 3},
+		{"EscapedQuote", []string{`resolve = ["a\"]", "b.New"]`, "dest = \".\""}, 0, 1},
+		{"AfterTable", []string{"[budget]", "resolve = [\"a.New\"]"}, -1, -1},
+		{"Unterminated", []string{"resolve = [", "  \"a.New\","}, 0, 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			start, end := resolveSpan(test.lines)
+			assert.Equal(t, [2]int{test.start, test.end}, [2]int{start, end})
+		})
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
@@ -1,38 +1,84 @@
 package main
 
 import (
+	"bytes"
+	"cmp"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime/debug"
+	"slices"
 	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/alecthomas/errors"
 	"github.com/alecthomas/kong"
-	kongtoml "github.com/alecthomas/kong-toml"
 	"github.com/alecthomas/zero/internal/depgraph"
 	"github.com/alecthomas/zero/internal/generator"
+	"github.com/alecthomas/zero/internal/lint"
+	"github.com/alecthomas/zero/internal/mocks"
+	"github.com/alecthomas/zero/internal/openapidiff"
+	"github.com/alecthomas/zero/internal/sbom"
+	"github.com/alecthomas/zero/internal/scaffold"
+	"github.com/go-openapi/spec"
 	"github.com/kballard/go-shellquote"
 )
 
 var cli struct {
-	Config         kong.ConfigFlag    `help:"Path to the configuration file." placeholder:"FILE" short:"c"`
-	Version        kong.VersionFlag   `help:"Print the version and exit."`
-	Chdir          kong.ChangeDirFlag `help:"Change to this directory before running." placeholder:"DIR" short:"C"`
-	Debug          bool               `help:"Enable debug logging." short:"d"`
-	Tags           []string           `help:"Tags to enable during type analysis (will also be read from $GOFLAGS)." placeholder:"TAG" short:"t"`
-	OutputTags     []string           `help:"Tags to add to generated code." placeholder:"TAG" short:"T"`
-	Resolve        []string           `help:"Resolve an ambiguous type with this provider." placeholder:"REF" short:"r"`
-	List           bool               `group:"Actions:" help:"List all dependencies." xor:"action"`
-	OpenAPI        bool               `group:"Actions:" name:"openapi" help:"Generate OpenAPI specification." xor:"action"`
-	OpenAPITitle   string             `help:"Title for the OpenAPI specification." placeholder:"TITLE" name:"openapi-title" default:"My Zero Service"`
-	OpenAPIVersion string             `help:"Version for the OpenAPI specification." placeholder:"VERSION" name:"openapi-version" default:"dev"`
-	Root           []string           `help:"Prune dependencies outside these root types."  placeholder:"REF" short:"R"`
-	Dest           string             `help:"Destination package directory for generated files." default:"."`
-	Patterns       []string           `help:"Additional packages pattern to scan." arg:"" optional:""`
+	Config         kong.ConfigFlag     `help:"Path to the configuration file." placeholder:"FILE" short:"c"`
+	Version        kong.VersionFlag    `help:"Print the version and exit."`
+	Chdir          kong.ChangeDirFlag  `help:"Change to this directory before running." placeholder:"DIR" short:"C"`
+	Debug          bool                `help:"Enable debug logging." short:"d"`
+	Tags           []string            `help:"Tags to enable during type analysis (will also be read from $GOFLAGS)." placeholder:"TAG" short:"t"`
+	OutputTags     []string            `help:"Tags to add to generated code." placeholder:"TAG" short:"T"`
+	Runtime        []generator.Runtime `help:"Generate an alternate entrypoint to Run for this runtime (${enum})." enum:"lambda,cgi,fcgi,systemd" placeholder:"RUNTIME"`
+	CLI            bool                `name:"cli" help:"Generate a ZeroCLI Kong struct with subcommands for serving and operating the service."`
+	Test           bool                `help:"Also generate zero_test.go, a test harness in which types are provided by test providers."`
+	FastJSON       bool                `name:"fast-json" help:"Generate JSON encoders and decoders for API request and response types, avoiding reflection."`
+	CloudEvents    bool                `name:"cloudevents" help:"Generate a POST /events/<topic> endpoint for each PubSub topic, publishing CloudEvents sent to it."`
+	EventLabels    string              `name:"cloudevents-labels" help:"Labels of the CloudEvents endpoints, selecting their middleware and request limits as for //zero:api, eg. \"authenticated maxbody=1MB\"." placeholder:"LABELS"`
+	Parallel       bool                `help:"Generate a Run that constructs independent providers concurrently at startup, reducing cold-start time."`
+	Trace          bool                `name:"trace-construction" help:"Generate construction code that logs each provider at debug level and fails providers exceeding --construction-timeout."`
+	Strict         bool                `help:"Fail instead of including weak providers by default or through require=, or inferring roots from receivers, so that every provider and root is selected explicitly."`
+	Interactive    bool                `help:"Prompt for a provider to select when a type has ambiguous providers, or a missing provider has weak candidates, and add it to the resolve list of the configuration file."`
+	Library        bool                `help:"Generate only the injector and constructors for the providers of a library package, without handlers or Run, for use by the services importing it."`
+	TrailingSlash  string              `help:"Trailing slash policy for APIs without a slash=<policy> label (strict, redirect or ignore), defaulting to the behaviour of http.ServeMux." enum:",strict,redirect,ignore" default:"" placeholder:"POLICY"`
+	Only           []generator.Section `help:"Only regenerate these sections of the existing zero.go (${enum}), keeping the rest of it byte-for-byte." enum:"handlers,injector,openapi" placeholder:"SECTION"`
+	Bench          bool                `help:"Also generate BenchmarkZeroRoutes into zero_test.go, benchmarking each GET endpoint (implies --test)."`
+	Mod            string              `help:"Module download mode passed to the go command as -mod (${enum}), overriding $GOFLAGS." enum:",mod,readonly,vendor" default:"" placeholder:"MODE"`
+	FixMod         bool                `name:"fix-mod" help:"Allow zero to update go.mod, and vendor/ if present, with 'go get' and 'go mod tidy' when they are out of date."`
+	FailOn         []string            `help:"Fail on warnings of these kinds (${enum}) instead of printing them." enum:"all,unreachable-provider,unused-middleware,unused-config,unneeded-pick,deprecated-provider" placeholder:"KIND"`
+	Resolve        []string            `help:"Resolve an ambiguous type with this provider, optionally scoped to a single type with <type>=<provider>." placeholder:"REF" short:"r"`
+	Module         []string            `help:"Resolve ambiguous types with providers from this module." placeholder:"NAME" short:"m"`
+	Profile        []string            `help:"Enable providers conditional on this profile, and merge its [profiles.<name>] configuration." placeholder:"NAME" short:"p"`
+	List           bool                `group:"Actions:" help:"List all dependencies." xor:"action"`
+	Routes         bool                `group:"Actions:" help:"List the routing table, with the labels and middleware applied to each route." xor:"action"`
+	DryRun         bool                `group:"Actions:" help:"Print which providers would be included, defaulted or pruned, and why, without writing zero.go." xor:"action"`
+	Stats          bool                `group:"Actions:" help:"Print the time taken by each phase of generation, graph statistics and the longest dependency chains, without writing zero.go." xor:"action"`
+	Snapshot       bool                `group:"Actions:" help:"Print a canonical snapshot of the dependency graph, to commit and check with --snapshot-check." xor:"action"`
+	SnapshotCheck  string              `group:"Actions:" name:"snapshot-check" help:"Compare the dependency graph against a snapshot written by --snapshot, failing if it has changed." placeholder:"FILE" type:"existingfile" xor:"action"`
+	SBOM           bool                `group:"Actions:" name:"sbom" help:"Print a CycloneDX software bill of materials of the modules and packages linked into the service by the pruned graph." xor:"action"`
+	Format         string              `help:"Output format for --list, --routes, --dry-run and --stats (${enum})." enum:"text,json" default:"text"`
+	Lint           bool                `group:"Actions:" help:"Check the dependency graph against the [[lint]] rules in the configuration file." xor:"action"`
+	OpenAPI        bool                `group:"Actions:" name:"openapi" help:"Generate OpenAPI specification." xor:"action"`
+	OpenAPITitle   string              `help:"Title for the OpenAPI specification, overriding the configuration (default: My Zero Service)." placeholder:"TITLE" name:"openapi-title"`
+	OpenAPIVersion string              `help:"Version for the OpenAPI specification, overriding the configuration (default: dev)." placeholder:"VERSION" name:"openapi-version"`
+	AsyncAPI       bool                `group:"Actions:" name:"asyncapi" help:"Generate AsyncAPI specification for PubSub topics, with the same title and version as the OpenAPI specification." xor:"action"`
+	OpenAPIDiff    string              `group:"Actions:" name:"openapi-diff" help:"Compare the OpenAPI specification against a previously generated specification, failing on breaking changes." placeholder:"FILE" type:"existingfile" xor:"action"`
+	Mocks          bool                `group:"Actions:" help:"Generate recording fakes of the interfaces required by providers into the zeromocks package." xor:"action"`
+	ExportManifest bool                `group:"Actions:" name:"export-manifest" help:"Write a zero-providers.json manifest of the annotations of each package matching the patterns, or of the destination package, allowing zero to skip type-checking function bodies that don't need it." xor:"action"`
+	DeployScaffold string              `group:"Actions:" help:"Write a Dockerfile, Kubernetes manifest and docker-compose.yml for the service into this directory." placeholder:"DIR" xor:"action"`
+	EnvPrefix      string              `help:"Environment variable prefix passed to kong.DefaultEnvars() by the service, for --deploy-scaffold." placeholder:"PREFIX"`
+	Root           []string            `help:"Prune dependencies outside these root types."  placeholder:"REF" short:"R"`
+	Dest           []string            `help:"Destination package directory for generated files, defaulting to the current directory. Repeat to generate several services from a single analysis." placeholder:"DIR"`
+	Service        []string            `help:"Generate the service with this name from the [services.<name>] configuration, defaulting to all configured services if no --dest is given." placeholder:"NAME" short:"s"`
+	Environment    []string            `help:"Generate zero_<name>.go, guarded by its output tags, from the [environments.<name>] configuration, defaulting to all configured environments." placeholder:"NAME" short:"e"`
+	Patterns       []string            `help:"Additional packages pattern to scan." arg:"" optional:""`
 }
 
 func main() {
@@ -40,7 +86,8 @@ func main() {
 	if info, ok := debug.ReadBuildInfo(); ok {
 		version = info.Main.Version
 	}
-	kctx := kong.Parse(&cli, kong.Vars{"version": version}, kong.Configuration(kongtoml.Loader, ".zero.toml"))
+	kctx := kong.Parse(&cli, kong.Vars{"version": version}, kong.Configuration(configLoader, ".zero.toml"))
+	unconfiguredProfiles := applyProfiles(cli.Profile)
 	extraOptions := []depgraph.Option{}
 	if cli.Debug {
 		extraOptions = append(extraOptions, depgraph.WithDebug(true))
@@ -48,37 +95,104 @@ func main() {
 	ctx := context.Background()
 
 	// Verify/add the version of zero being used.
-	err := ensureGoModuleVersion(kctx, version)
+	err := ensureGoModuleVersion(ctx, kctx, version)
 	kctx.FatalIfErrorf(err)
 
-	cli.Dest, err = filepath.Abs(filepath.Join(string(cli.Chdir), cli.Dest))
+	dests, err := destinations()
 	kctx.FatalIfErrorf(err)
 
 	// Combine explicit tags and tags from GOFLAGS
 	tags := append(cli.Tags, parseGoTags()...)
 
-	graph, err := depgraph.Analyse(ctx, cli.Dest,
-		depgraph.WithRoots(cli.Root...),
-		depgraph.WithPatterns(cli.Patterns...),
-		depgraph.WithProviders(cli.Resolve...),
-		depgraph.WithOptions(extraOptions...),
-		depgraph.WithTags(tags...),
-	)
+	if cli.ExportManifest {
+		patterns := cli.Patterns
+		if len(patterns) == 0 {
+			patterns = []string{"."}
+		}
+		for i, dest := range dests {
+			// Environments of a destination share its manifests.
+			if i > 0 && dests[i-1].Dir == dest.Dir {
+				continue
+			}
+			paths, err := depgraph.WriteManifests(ctx, dest.Dir, patterns, tags...)
+			kctx.FatalIfErrorf(err)
+			for _, path := range paths {
+				fmt.Println(relativePosition(path))
+			}
+		}
+		kctx.Exit(0)
+	}
+
+	// Destinations are recomputed for each analysis, as --interactive may select providers between them.
+	analyse := func() ([]*depgraph.Graph, error) {
+		dests, err = destinations()
+		if err != nil {
+			return nil, err
+		}
+		analysed := make([]depgraph.Destination, len(dests))
+		for i, dest := range dests {
+			analysed[i] = dest.Destination
+		}
+		return depgraph.AnalyseAll(ctx, analysed,
+			depgraph.WithRoots(cli.Root...),
+			depgraph.WithPatterns(cli.Patterns...),
+			depgraph.WithProviders(cli.Resolve...),
+			depgraph.WithModules(cli.Module...),
+			depgraph.WithProfiles(cli.Profile...),
+			depgraph.WithOptions(extraOptions...),
+			depgraph.WithTags(tags...),
+			depgraph.WithTests(cli.Test || cli.Bench),
+			depgraph.WithTrailingSlash(cli.TrailingSlash),
+			depgraph.WithMod(cli.Mod),
+			depgraph.WithFixMod(cli.FixMod),
+			depgraph.WithLibrary(cli.Library),
+			depgraph.WithStrict(cli.Strict),
+		)
+	}
+	var graphs []*depgraph.Graph
+	if cli.Interactive {
+		graphs, err = resolveInteractively(analyse)
+	} else {
+		graphs, err = analyse()
+	}
 	kctx.FatalIfErrorf(err)
 
-	if len(graph.Missing) > 0 {
-		for fn, missing := range graph.Missing {
-			missingStr := []string{}
-			for _, typ := range missing {
-				missingStr = append(missingStr, typ.String())
+	var annotatedProfiles []string
+	for _, graph := range graphs {
+		annotatedProfiles = append(annotatedProfiles, graph.Profiles()...)
+	}
+	kctx.FatalIfErrorf(checkProfiles(unconfiguredProfiles, annotatedProfiles))
+
+	for _, graph := range graphs {
+		checkGraph(ctx, kctx, graph)
+	}
+
+	if len(graphs) > 1 {
+		if cli.List || cli.Routes || cli.Snapshot || cli.SnapshotCheck != "" || cli.SBOM || cli.DryRun || cli.Stats || cli.OpenAPI || cli.AsyncAPI || cli.OpenAPIDiff != "" || cli.Mocks || cli.DeployScaffold != "" {
+			kctx.Fatalf("actions other than --lint require a single destination and environment, but %d were given", len(graphs))
+		}
+		if !cli.Lint {
+			for i, graph := range graphs {
+				generate(kctx, graph, dests[i].Environment)
 			}
-			kctx.Errorf("%s() is missing a provider for %s", fn.FullName(), strings.Join(missingStr, ", "))
 		}
-		kctx.Exit(1)
+		kctx.Exit(0)
 	}
+	graph, environment := graphs[0], dests[0].Environment
 
 	// Run actions if any
 	switch {
+	case cli.Lint:
+		kctx.Exit(0)
+
+	case cli.List && cli.Format == "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(graph); err != nil {
+			kctx.Fatalf("failed to encode graph: %v", err)
+		}
+		kctx.Exit(0)
+
 	case cli.List:
 		g := graph.Graph()
 		for root, deps := range g {
@@ -89,22 +203,326 @@ func main() {
 		}
 		kctx.Exit(0)
 
+	case cli.Routes && cli.Format == "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(graph.Routes()); err != nil {
+			kctx.Fatalf("failed to encode routes: %v", err)
+		}
+		kctx.Exit(0)
+
+	case cli.Routes:
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "METHOD\tPATTERN\tHANDLER\tLABELS\tMIDDLEWARE\tPOSITION")
+		for _, route := range graph.Routes() {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", route.Method, route.Pattern, route.Handler,
+				cmp.Or(strings.Join(route.Labels, " "), "-"), cmp.Or(strings.Join(route.Middleware, " "), "-"), relativePosition(route.Position))
+		}
+		kctx.FatalIfErrorf(tw.Flush())
+		kctx.Exit(0)
+
+	case cli.Snapshot:
+		fmt.Print(depgraph.Snapshot(graph))
+		kctx.Exit(0)
+
+	case cli.SnapshotCheck != "":
+		expected, err := os.ReadFile(cli.SnapshotCheck)
+		kctx.FatalIfErrorf(err)
+		diff := depgraph.DiffSnapshots(string(expected), depgraph.Snapshot(graph))
+		for _, line := range diff {
+			fmt.Fprintln(os.Stderr, line)
+		}
+		if len(diff) > 0 {
+			kctx.Fatalf("dependency graph differs from %s, update it with 'zero --snapshot > %s' if the change is intended", cli.SnapshotCheck, cli.SnapshotCheck)
+		}
+		kctx.Exit(0)
+
+	case cli.SBOM:
+		bom, err := sbom.Generate(ctx, graph, version)
+		kctx.FatalIfErrorf(err)
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(bom); err != nil {
+			kctx.Fatalf("failed to encode SBOM: %v", err)
+		}
+		kctx.Exit(0)
+
 	case cli.OpenAPI:
+		swagger, err := generateOpenAPISpec(graph)
+		kctx.FatalIfErrorf(err)
 		enc := json.NewEncoder(os.Stdout)
 		enc.SetIndent("", "  ")
-		if err := enc.Encode(graph.GenerateOpenAPISpec(cli.OpenAPITitle, cli.OpenAPIVersion)); err != nil {
+		if err := enc.Encode(swagger); err != nil {
 			kctx.Fatalf("failed to encode OpenAPI spec: %v", err)
 		}
 		kctx.Exit(0)
+
+	case cli.AsyncAPI:
+		title, version, err := specInfo(graph)
+		kctx.FatalIfErrorf(err)
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(graph.GenerateAsyncAPISpec(title, version)); err != nil {
+			kctx.Fatalf("failed to encode AsyncAPI spec: %v", err)
+		}
+		kctx.Exit(0)
+
+	case cli.OpenAPIDiff != "":
+		data, err := os.ReadFile(cli.OpenAPIDiff)
+		kctx.FatalIfErrorf(err)
+		previous := &spec.Swagger{}
+		if err := previous.UnmarshalJSON(data); err != nil {
+			kctx.Fatalf("failed to decode OpenAPI spec %s: %v", cli.OpenAPIDiff, err)
+		}
+		swagger, err := generateOpenAPISpec(graph)
+		kctx.FatalIfErrorf(err)
+		changes := openapidiff.Diff(previous, swagger)
+		for _, change := range changes {
+			kctx.Errorf("breaking change: %s", change)
+		}
+		if len(changes) > 0 {
+			kctx.Exit(1)
+		}
+		kctx.Exit(0)
+
+	case cli.Mocks:
+		dir := filepath.Join(graph.DestDir, mocks.Package)
+		err = os.MkdirAll(dir, 0750)
+		kctx.FatalIfErrorf(err)
+		path := filepath.Join(dir, mocks.Package+".go")
+		w, err := os.Create(path)
+		kctx.FatalIfErrorf(err)
+		err = mocks.Generate(w, graph)
+		kctx.FatalIfErrorf(err)
+		fmt.Println(path)
+		kctx.Exit(0)
+
+	case cli.DeployScaffold != "":
+		service, err := scaffold.Analyse(graph, graph.DestDir, cli.EnvPrefix)
+		kctx.FatalIfErrorf(err)
+		files, err := service.Files()
+		kctx.FatalIfErrorf(err)
+		err = os.MkdirAll(cli.DeployScaffold, 0750)
+		kctx.FatalIfErrorf(err)
+		for name, content := range files {
+			path := filepath.Join(cli.DeployScaffold, name)
+			if _, err := os.Stat(path); err == nil {
+				kctx.Fatalf("%s already exists, not overwriting", path)
+			}
+			err = os.WriteFile(path, content, 0600)
+			kctx.FatalIfErrorf(err)
+			fmt.Println(path)
+		}
+		kctx.Exit(0)
+	}
+
+	if cli.DryRun {
+		// Generate into the void so that generation errors are reported by the plan.
+		err = generator.Generate(io.Discard, graph, generatorOptions(kctx, graph, environment)...)
+		kctx.FatalIfErrorf(err)
+		kctx.FatalIfErrorf(printPlan(graph.Plan()))
+		kctx.Exit(0)
+	}
+
+	if cli.Stats {
+		options := generatorOptions(kctx, graph, environment)
+		start := time.Now()
+		err = generator.Generate(io.Discard, graph, options...)
+		kctx.FatalIfErrorf(err)
+		stats := graph.Stats()
+		stats.Phases = append(stats.Phases, depgraph.PhaseTiming{Phase: "generate", Duration: time.Since(start)})
+		kctx.FatalIfErrorf(printStats(stats))
+		kctx.Exit(0)
+	}
+
+	generate(kctx, graph, environment)
+}
+
+// checkGraph fails if the graph has missing providers, violates architecture rules or exceeds the budget, and prints
+// its warnings.
+func checkGraph(ctx context.Context, kctx *kong.Context, graph *depgraph.Graph) {
+	if missing := graph.MissingDependencies(); len(missing) > 0 {
+		for _, dep := range missing {
+			fmt.Fprintln(os.Stderr, dep)
+		}
+		kctx.Fatalf("%d missing providers", len(missing))
+	}
+
+	// Architecture rules are always enforced, failing generation
+	if violations := lint.Check(graph, lintRules); len(violations) > 0 {
+		for _, violation := range violations {
+			kctx.Errorf("%s", violation)
+		}
+		kctx.Exit(1)
+	}
+
+	// Budgets are checked after pruning, against the providers and packages that will be linked into the service
+	violations, err := lint.CheckBudget(ctx, graph, budget)
+	kctx.FatalIfErrorf(err)
+	if len(violations) > 0 {
+		for _, violation := range violations {
+			kctx.Errorf("%s", violation)
+		}
+		kctx.Exit(1)
+	}
+
+	// Annotations without effect are warnings, unless promoted to errors
+	failed := false
+	for _, warning := range graph.Warnings() {
+		warning.Position.Filename = relativePosition(warning.Position.Filename)
+		if slices.Contains(cli.FailOn, "all") || slices.Contains(cli.FailOn, string(warning.Kind)) {
+			kctx.Errorf("%s", warning)
+			failed = true
+		} else {
+			fmt.Fprintf(os.Stderr, "warning: %s\n", warning)
+		}
+	}
+	if failed {
+		kctx.Exit(1)
 	}
+}
+
+// generatorOptions returns the options for generating the code of a destination package, in an environment if not
+// empty.
+func generatorOptions(kctx *kong.Context, graph *depgraph.Graph, environment string) []generator.Option {
+	tags := slices.Concat(cli.OutputTags, environments[environment].OutputTags)
+	options := []generator.Option{generator.WithTags(tags...), generator.WithTemplates(templates), generator.WithRuntimes(cli.Runtime...), generator.WithFastJSON(cli.FastJSON), generator.WithCloudEvents(cli.CloudEvents), generator.WithCloudEventsLabels(cli.EventLabels), generator.WithParallelConstruction(cli.Parallel), generator.WithConstructionTracing(cli.Trace)}
+	if cli.CLI && !graph.Library {
+		swagger, err := generateOpenAPISpec(graph)
+		kctx.FatalIfErrorf(err)
+		openAPI, err := json.MarshalIndent(swagger, "", "  ")
+		kctx.FatalIfErrorf(err)
+		options = append(options, generator.WithCLI(openAPI))
+	}
+	return options
+}
 
-	w, err := os.Create(filepath.Join(cli.Dest, "zero.go"))
+// generate writes zero.go, and zero_test.go if enabled, into the destination package of the graph, or
+// zero_<environment>.go and zero_<environment>_test.go if an environment is given.
+func generate(kctx *kong.Context, graph *depgraph.Graph, environment string) {
+	options := generatorOptions(kctx, graph, environment)
+	code := &bytes.Buffer{}
+	err := generator.Generate(code, graph, options...)
 	kctx.FatalIfErrorf(err)
-	err = generator.Generate(w, graph, generator.WithTags(cli.OutputTags...))
+	file, err := environmentFile(environment, false)
 	kctx.FatalIfErrorf(err)
+	dest := filepath.Join(graph.DestDir, file)
+	if len(cli.Only) > 0 {
+		existing, err := os.ReadFile(dest)
+		kctx.FatalIfErrorf(err, "--only requires an existing %s", file)
+		merged, err := generator.Merge(existing, code.Bytes(), cli.Only...)
+		kctx.FatalIfErrorf(err)
+		code = bytes.NewBuffer(merged)
+	}
+	err = writeIfChanged(dest, code.Bytes())
+	kctx.FatalIfErrorf(err)
+
+	if cli.Test || cli.Bench {
+		code := &bytes.Buffer{}
+		err = generator.GenerateTest(code, graph, append(options, generator.WithBenchmarks(cli.Bench))...)
+		kctx.FatalIfErrorf(err)
+		file, err := environmentFile(environment, true)
+		kctx.FatalIfErrorf(err)
+		err = writeIfChanged(filepath.Join(graph.DestDir, file), code.Bytes())
+		kctx.FatalIfErrorf(err)
+	}
 }
 
-func ensureGoModuleVersion(kctx *kong.Context, version string) error {
+// writeIfChanged writes content to path unless the file already has exactly that content, so that unchanged generated
+// files keep their modification time and don't trigger rebuilds.
+func writeIfChanged(path string, content []byte) error {
+	if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, content) { //nolint
+		return nil
+	}
+	return errors.WithStack(os.WriteFile(path, content, 0600))
+}
+
+// printStats prints generation statistics in the output format.
+func printStats(stats depgraph.Stats) error {
+	if cli.Format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return errors.WithStack(enc.Encode(stats))
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	var total time.Duration
+	for _, phase := range stats.Phases {
+		total += phase.Duration
+		fmt.Fprintf(tw, "%s\t%s\t\n", phase.Phase, phase.Duration.Round(time.Millisecond))
+	}
+	fmt.Fprintf(tw, "total\t%s\t\n", total.Round(time.Millisecond))
+	if err := tw.Flush(); err != nil {
+		return errors.WithStack(err)
+	}
+	fmt.Printf("\n%d packages, %d providers (%d included, %d pruned), %d configs, %d APIs, %d cron jobs, %d subscriptions, %d middleware\n",
+		stats.Packages, stats.Discovered, stats.Included, stats.Pruned, stats.Configs, stats.APIs, stats.CronJobs,
+		stats.Subscriptions, stats.Middleware)
+	if len(stats.Chains) > 0 {
+		fmt.Println("\nLongest dependency chains:")
+		for _, chain := range stats.Chains {
+			fmt.Printf("  %d: %s\n", len(chain), strings.Join(chain, " -> "))
+		}
+	}
+	return nil
+}
+
+// printPlan prints the generation plan in the output format, marking included providers with +, defaults with ~ and
+// pruned providers with -.
+func printPlan(plan []depgraph.PlanEntry) error {
+	if cli.Format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return errors.WithStack(enc.Encode(plan))
+	}
+	symbols := map[depgraph.PlanAction]string{depgraph.PlanInclude: "+", depgraph.PlanDefault: "~", depgraph.PlanPrune: "-"}
+	counts := map[depgraph.PlanAction]int{}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, entry := range plan {
+		counts[entry.Action]++
+		fmt.Fprintf(tw, "%s %s\t%s\t%s\n", symbols[entry.Action], entry.Type, entry.Provider, entry.Reason)
+	}
+	if err := tw.Flush(); err != nil {
+		return errors.WithStack(err)
+	}
+	fmt.Printf("\nPlan: %d to include, %d defaults, %d to prune. zero.go was not written.\n",
+		counts[depgraph.PlanInclude], counts[depgraph.PlanDefault], counts[depgraph.PlanPrune])
+	return nil
+}
+
+// generateOpenAPISpec generates the OpenAPI specification for the graph.
+func generateOpenAPISpec(graph *depgraph.Graph) (*spec.Swagger, error) {
+	title, version, err := specInfo(graph)
+	if err != nil {
+		return nil, err
+	}
+	return graph.GenerateOpenAPISpec(title, version), nil
+}
+
+// specInfo merges the OpenAPI configuration into the graph, returning the title and version for generated specs.
+//
+// The [openapi] section of .zero.toml takes precedence over //zero:openapi directives, and flags over both.
+func specInfo(graph *depgraph.Graph) (title, version string, err error) {
+	graph.OpenAPI.Merge(openAPIConfig)
+	graph.OpenAPI.Merge(depgraph.OpenAPIConfig{Title: cli.OpenAPITitle, Version: cli.OpenAPIVersion})
+	if err := graph.OpenAPI.Validate(); err != nil {
+		return "", "", errors.Errorf("invalid OpenAPI configuration: %w", err)
+	}
+	return cmp.Or(graph.OpenAPI.Title, "My Zero Service"), cmp.Or(graph.OpenAPI.Version, "dev"), nil
+}
+
+// relativePosition returns a source position relative to the working directory, if possible.
+func relativePosition(position string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return position
+	}
+	if rel, err := filepath.Rel(wd, position); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return position
+}
+
+func ensureGoModuleVersion(ctx context.Context, kctx *kong.Context, version string) error {
 	if strings.Contains(version, "+dirty") {
 		return nil
 	}
@@ -116,6 +534,10 @@ func ensureGoModuleVersion(kctx *kong.Context, version string) error {
 	if moduleVersion == "v0.0.0-00010101000000-000000000000" || moduleVersion == version {
 		return nil
 	}
+	if !cli.FixMod {
+		kctx.Printf("warning: zero %s differs from github.com/alecthomas/zero@%s required by go.mod, pass --fix-mod to update it", version, moduleVersion)
+		return nil
+	}
 	kctx.Printf("updating to github.com/alecthomas/zero@%s", version)
 	cmd := exec.Command("go", "get", "github.com/alecthomas/zero/...@"+version) //nolint
 	cmd.Stdout = os.Stdout
@@ -123,10 +545,11 @@ func ensureGoModuleVersion(kctx *kong.Context, version string) error {
 	if err := cmd.Run(); err != nil {
 		return errors.Wrap(err, "failed to update to github.com/alecthomas/zero@"+version)
 	}
-	cmd = exec.Command("go", "mod", "tidy") //nolint
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
+	dir, err := os.Getwd()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := depgraph.TidyModule(ctx, dir); err != nil {
 		return errors.Wrap(err, "failed to update to github.com/alecthomas/zero@"+version)
 	}
 	return nil
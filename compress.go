@@ -0,0 +1,240 @@
+package zero
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentEncoding is an HTTP content coding used to compress responses, eg. gzip.
+type ContentEncoding struct {
+	// Name of the content coding in the Accept-Encoding and Content-Encoding headers, eg. "br".
+	Name string
+	// NewWriter returns a writer compressing to w, which is closed once the response is complete.
+	//
+	// If the writer has a Flush() error method, it is called when the response is flushed.
+	NewWriter func(w io.Writer) io.WriteCloser
+}
+
+// ContentEncodings used to compress responses, in order of preference.
+type ContentEncodings []ContentEncoding
+
+// Gzip compresses responses with gzip at the default compression level.
+var Gzip = ContentEncoding{
+	Name: "gzip",
+	NewWriter: func(w io.Writer) io.WriteCloser {
+		gz := gzipWriters.Get().(*gzip.Writer) //nolint
+		gz.Reset(w)
+		return &pooledGzipWriter{gz}
+	},
+}
+
+var gzipWriters = sync.Pool{New: func() any { return gzip.NewWriter(nil) }}
+
+// pooledGzipWriter returns its gzip.Writer to the pool when closed.
+type pooledGzipWriter struct{ *gzip.Writer }
+
+func (p *pooledGzipWriter) Close() error {
+	err := p.Writer.Close()
+	gzipWriters.Put(p.Writer)
+	return err
+}
+
+// Compress returns middleware compressing response bodies of at least minSize bytes with the most preferred of
+// encodings accepted by the client, or gzip if no encodings are given.
+//
+// Responses are passed through unchanged if they are already encoded, partial, server-sent events, or of a media type
+// that is typically already compressed, such as images and archives. Responses flushed before reaching minSize are
+// also passed through, so that streaming responses aren't delayed. Strong ETags of compressed responses are weakened,
+// as the compressed body differs from the one they were computed over.
+func Compress(minSize ByteSize, encodings ...ContentEncoding) Middleware {
+	if len(encodings) == 0 {
+		encodings = ContentEncodings{Gzip}
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !slices.ContainsFunc(w.Header().Values("Vary"), func(vary string) bool { return strings.EqualFold(vary, "Accept-Encoding") }) {
+				w.Header().Add("Vary", "Accept-Encoding")
+			}
+			encoding, ok := negotiateEncoding(r.Header.Get("Accept-Encoding"), encodings)
+			if !ok || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+			cw := &compressWriter{ResponseWriter: w, encoding: encoding, minSize: int(minSize)}
+			next.ServeHTTP(cw, r)
+			_ = cw.close()
+		})
+	}
+}
+
+// negotiateEncoding returns the encoding with the highest quality in the Accept-Encoding header, preferring earlier
+// encodings in case of a tie.
+func negotiateEncoding(accept string, encodings []ContentEncoding) (ContentEncoding, bool) {
+	qualities := map[string]float64{}
+	for element := range strings.SplitSeq(accept, ",") {
+		name, params, _ := strings.Cut(element, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		quality := 1.0
+		for param := range strings.SplitSeq(params, ";") {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(key, "q") {
+				if q, err := strconv.ParseFloat(value, 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		qualities[name] = quality
+	}
+	var best ContentEncoding
+	var bestQuality float64
+	for _, encoding := range encodings {
+		quality, ok := qualities[strings.ToLower(encoding.Name)]
+		if !ok {
+			quality = qualities["*"]
+		}
+		if quality > bestQuality {
+			best, bestQuality = encoding, quality
+		}
+	}
+	return best, bestQuality > 0
+}
+
+// compressWriter buffers the start of a response until it reaches the minimum size, then either compresses or passes
+// through the remainder.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding ContentEncoding
+	minSize  int
+	status   int
+	buf      []byte
+	started  bool
+	// writer is the compressing writer, if the response is being compressed.
+	writer io.WriteCloser
+}
+
+func (c *compressWriter) Unwrap() http.ResponseWriter { return c.ResponseWriter }
+
+func (c *compressWriter) WriteHeader(status int) {
+	switch {
+	case c.started || (status >= 100 && status < 200 && status != http.StatusSwitchingProtocols):
+		c.ResponseWriter.WriteHeader(status)
+	case c.status == 0:
+		c.status = status
+		if !c.compressible() {
+			_ = c.start(false)
+		}
+	}
+}
+
+func (c *compressWriter) Write(data []byte) (int, error) {
+	if c.status == 0 {
+		c.WriteHeader(http.StatusOK)
+	}
+	if c.started {
+		if c.writer != nil {
+			return c.writer.Write(data)
+		}
+		return c.ResponseWriter.Write(data)
+	}
+	c.buf = append(c.buf, data...)
+	if len(c.buf) >= c.minSize {
+		if err := c.start(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+// Flush implements http.Flusher.
+func (c *compressWriter) Flush() {
+	if !c.started {
+		if c.status == 0 {
+			c.status = http.StatusOK
+		}
+		_ = c.start(false)
+	}
+	if flusher, ok := c.writer.(interface{ Flush() error }); ok {
+		_ = flusher.Flush()
+	}
+	_ = http.NewResponseController(c.ResponseWriter).Flush()
+}
+
+// start writes the response header and any buffered body, compressing the rest of the response if compress is true
+// and the response is compressible.
+func (c *compressWriter) start(compress bool) error {
+	c.started = true
+	header := c.Header()
+	if compress && len(c.buf) > 0 && header.Get("Content-Type") == "" {
+		// Sniff the content type before compression, as net/http would otherwise sniff the compressed body.
+		header.Set("Content-Type", http.DetectContentType(c.buf))
+	}
+	if compress && c.compressible() {
+		header.Set("Content-Encoding", c.encoding.Name)
+		header.Del("Content-Length")
+		if etag := header.Get("ETag"); strings.HasPrefix(etag, `"`) {
+			header.Set("ETag", "W/"+etag)
+		}
+		c.writer = c.encoding.NewWriter(c.ResponseWriter)
+	}
+	c.ResponseWriter.WriteHeader(c.status)
+	buf := c.buf
+	c.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := c.Write(buf)
+	return err
+}
+
+// close writes any response buffered below the minimum size uncompressed, and completes compressed responses.
+func (c *compressWriter) close() error {
+	if !c.started && c.status != 0 {
+		if err := c.start(false); err != nil {
+			return err
+		}
+	}
+	if c.writer != nil {
+		return c.writer.Close()
+	}
+	return nil
+}
+
+// compressible returns false if the response is known to be ineligible for compression from its status and headers.
+func (c *compressWriter) compressible() bool {
+	switch {
+	case c.status < 200, c.status == http.StatusNoContent, c.status == http.StatusNotModified, c.status == http.StatusPartialContent:
+		return false
+	}
+	header := c.Header()
+	if header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" {
+		return false
+	}
+	if length, err := strconv.Atoi(header.Get("Content-Length")); err == nil && length < c.minSize {
+		return false
+	}
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type")) //nolint
+	return !incompressible(mediaType)
+}
+
+// incompressible returns true for media types that are streamed, or typically already compressed.
+func incompressible(mediaType string) bool {
+	switch mediaType {
+	case "image/svg+xml":
+		return false
+	case "text/event-stream", "application/zip", "application/gzip", "application/x-gzip", "application/zstd",
+		"application/x-bzip2", "application/x-xz", "application/x-7z-compressed", "application/vnd.rar",
+		"application/x-rar-compressed", "application/pdf", "font/woff", "font/woff2":
+		return true
+	}
+	return strings.HasPrefix(mediaType, "image/") || strings.HasPrefix(mediaType, "audio/") ||
+		strings.HasPrefix(mediaType, "video/")
+}
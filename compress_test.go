@@ -0,0 +1,177 @@
+package zero_test
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/alecthomas/zero"
+)
+
+func TestCompress(t *testing.T) {
+	t.Parallel()
+	large := strings.Repeat("hello world ", 100)
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		handler        http.HandlerFunc
+		encoding       string
+		contentType    string
+		etag           string
+	}{
+		{
+			name:           "Large",
+			acceptEncoding: "gzip, deflate",
+			handler:        func(w http.ResponseWriter, r *http.Request) { _, _ = io.WriteString(w, large) },
+			encoding:       "gzip",
+			contentType:    "text/plain; charset=utf-8",
+		},
+		{
+			name:           "Small",
+			acceptEncoding: "gzip",
+			handler:        func(w http.ResponseWriter, r *http.Request) { _, _ = io.WriteString(w, "hello") },
+		},
+		{
+			name:           "NotAccepted",
+			acceptEncoding: "br, gzip;q=0",
+			handler:        func(w http.ResponseWriter, r *http.Request) { _, _ = io.WriteString(w, large) },
+		},
+		{
+			name:           "Wildcard",
+			acceptEncoding: "*",
+			handler:        func(w http.ResponseWriter, r *http.Request) { _, _ = io.WriteString(w, large) },
+			encoding:       "gzip",
+			contentType:    "text/plain; charset=utf-8",
+		},
+		{
+			name:           "AlreadyCompressed",
+			acceptEncoding: "gzip",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "image/png")
+				_, _ = io.WriteString(w, large)
+			},
+			contentType: "image/png",
+		},
+		{
+			name:           "AlreadyEncoded",
+			acceptEncoding: "gzip",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Encoding", "identity")
+				_, _ = io.WriteString(w, large)
+			},
+			encoding: "identity",
+		},
+		{
+			name:           "ServerSentEvents",
+			acceptEncoding: "gzip",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/event-stream")
+				_, _ = io.WriteString(w, large)
+			},
+			contentType: "text/event-stream",
+		},
+		{
+			name:           "FlushedBeforeMinSize",
+			acceptEncoding: "gzip",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.WriteString(w, "hello")
+				_ = http.NewResponseController(w).Flush()
+				_, _ = io.WriteString(w, large)
+			},
+		},
+		{
+			name:           "WeakensETag",
+			acceptEncoding: "gzip",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("ETag", `"abc"`)
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = io.WriteString(w, large)
+			},
+			encoding:    "gzip",
+			contentType: "application/json",
+			etag:        `W/"abc"`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Accept-Encoding", test.acceptEncoding)
+			expected := httptest.NewRecorder()
+			test.handler(expected, r)
+
+			zero.Compress(1024)(test.handler).ServeHTTP(w, r)
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+			assert.Equal(t, test.encoding, w.Header().Get("Content-Encoding"))
+			if test.contentType != "" {
+				assert.Equal(t, test.contentType, w.Header().Get("Content-Type"))
+			}
+			assert.Equal(t, test.etag, w.Header().Get("ETag"))
+
+			body := w.Body.String()
+			if test.encoding == "gzip" {
+				reader, err := gzip.NewReader(w.Body)
+				assert.NoError(t, err)
+				data, err := io.ReadAll(reader)
+				assert.NoError(t, err)
+				body = string(data)
+			}
+			assert.Equal(t, expected.Body.String(), body)
+		})
+	}
+}
+
+func TestCompressStatus(t *testing.T) {
+	t.Parallel()
+	handler := zero.Compress(0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotModified)
+	}))
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Equal(t, "", w.Header().Get("Content-Encoding"))
+
+	handler = zero.Compress(0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_, _ = io.WriteString(w, "created")
+	}))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+}
+
+func TestCompressNegotiation(t *testing.T) {
+	t.Parallel()
+	identity := zero.ContentEncoding{Name: "test", NewWriter: func(w io.Writer) io.WriteCloser { return nopWriteCloser{w} }}
+	handler := zero.Compress(0, identity, zero.Gzip)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "hello")
+	}))
+	tests := map[string]string{
+		"gzip, test":         "test",
+		"gzip, test;q=0.5":   "gzip",
+		"GZIP":               "gzip",
+		"test;q=0, *;q=0.1":  "gzip",
+		"identity":           "",
+		"":                   "",
+		"gzip;q=0, test;q=0": "",
+	}
+	for accept, expected := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept-Encoding", accept)
+		handler.ServeHTTP(w, r)
+		assert.Equal(t, expected, w.Header().Get("Content-Encoding"), accept)
+	}
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
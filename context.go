@@ -0,0 +1,19 @@
+package zero
+
+import "context"
+
+type contextValueKey[T any] struct{}
+
+// WithContextValue returns a copy of ctx carrying value, which is retrieved with [ContextValue].
+//
+// Middleware uses this to set values of types annotated with //zero:contextkey <label>, which are then injected into
+// API methods with the same label as ordinary parameters.
+func WithContextValue[T any](ctx context.Context, value T) context.Context {
+	return context.WithValue(ctx, contextValueKey[T]{}, value)
+}
+
+// ContextValue returns the value of type T set in ctx with [WithContextValue], and whether it was set.
+func ContextValue[T any](ctx context.Context) (T, bool) {
+	value, ok := ctx.Value(contextValueKey[T]{}).(T)
+	return value, ok
+}
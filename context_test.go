@@ -0,0 +1,26 @@
+package zero_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/alecthomas/zero"
+)
+
+type Subject string
+
+type TenantID string
+
+func TestContextValue(t *testing.T) {
+	ctx := zero.WithContextValue(context.Background(), Subject("alice"))
+	subject, ok := zero.ContextValue[Subject](ctx)
+	assert.True(t, ok)
+	assert.Equal(t, Subject("alice"), subject)
+
+	// Values are keyed by type, so types with the same underlying type are distinct.
+	_, ok = zero.ContextValue[TenantID](ctx)
+	assert.False(t, ok)
+	_, ok = zero.ContextValue[string](ctx)
+	assert.False(t, ok)
+}
@@ -0,0 +1,86 @@
+package zero
+
+import (
+	"encoding"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/alecthomas/errors"
+)
+
+// DecodeHeader decodes the request header name into an API parameter of type T.
+//
+// T may be a string, integer, float, bool or [encoding.TextUnmarshaler]. The header is required unless T is a pointer,
+// in which case it is nil if the header is absent.
+func DecodeHeader[T any](r *http.Request, name string) (T, error) {
+	values := r.Header.Values(name)
+	if len(values) == 0 {
+		return decodeParameter[T]("header", name, "", false)
+	}
+	return decodeParameter[T]("header", name, values[0], true)
+}
+
+// DecodeCookie decodes the request cookie name into an API parameter of type T, as for [DecodeHeader].
+func DecodeCookie[T any](r *http.Request, name string) (T, error) {
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return decodeParameter[T]("cookie", name, "", false)
+	}
+	return decodeParameter[T]("cookie", name, cookie.Value, true)
+}
+
+func decodeParameter[T any](in, name, value string, present bool) (T, error) {
+	var out T
+	target := reflect.ValueOf(&out).Elem()
+	if target.Kind() == reflect.Pointer {
+		if !present {
+			return out, nil
+		}
+		target.Set(reflect.New(target.Type().Elem()))
+		target = target.Elem()
+	} else if !present {
+		return out, APIErrorf(http.StatusBadRequest, "%s %s is required", in, name)
+	}
+	if err := decodeParameterValue(target, value); err != nil {
+		return out, APIErrorf(http.StatusBadRequest, "invalid %s %s: %w", in, name, err)
+	}
+	return out, nil
+}
+
+func decodeParameterValue(target reflect.Value, value string) error {
+	if unmarshaler, ok := target.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return errors.WithStack(unmarshaler.UnmarshalText([]byte(value)))
+	}
+	switch target.Kind() {
+	case reflect.String:
+		target.SetString(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, target.Type().Bits())
+		if err != nil {
+			return errors.Errorf("expected an integer: %w", err)
+		}
+		target.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, target.Type().Bits())
+		if err != nil {
+			return errors.Errorf("expected an unsigned integer: %w", err)
+		}
+		target.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(value, target.Type().Bits())
+		if err != nil {
+			return errors.Errorf("expected a number: %w", err)
+		}
+		target.SetFloat(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.Errorf("expected a boolean: %w", err)
+		}
+		target.SetBool(b)
+	default:
+		return errors.Errorf("unsupported parameter type %s", target.Type())
+	}
+	return nil
+}
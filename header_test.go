@@ -0,0 +1,67 @@
+package zero_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/alecthomas/zero"
+)
+
+func TestDecodeHeader(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Api-Key", "secret")
+	r.Header.Set("X-Retries", "3")
+	r.Header.Set("X-Client", "10.0.0.1")
+	r.Header.Set("X-Debug", "yes")
+
+	key, err := zero.DecodeHeader[string](r, "X-Api-Key")
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", key)
+
+	retries, err := zero.DecodeHeader[int](r, "X-Retries")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, retries)
+
+	client, err := zero.DecodeHeader[netip.Addr](r, "X-Client")
+	assert.NoError(t, err)
+	assert.Equal(t, netip.MustParseAddr("10.0.0.1"), client)
+
+	optional, err := zero.DecodeHeader[*int](r, "X-Retries")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, *optional)
+
+	missing, err := zero.DecodeHeader[*string](r, "X-Missing")
+	assert.NoError(t, err)
+	assert.Zero(t, missing)
+
+	_, err = zero.DecodeHeader[string](r, "X-Missing")
+	assert.EqualError(t, err, "400: header X-Missing is required")
+
+	_, err = zero.DecodeHeader[bool](r, "X-Debug")
+	assert.Error(t, err)
+	var apiErr zero.APIError
+	assert.True(t, errors.As(err, &apiErr))
+	w := httptest.NewRecorder()
+	apiErr.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestDecodeCookie(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+
+	session, err := zero.DecodeCookie[string](r, "session")
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", session)
+
+	theme, err := zero.DecodeCookie[*string](r, "theme")
+	assert.NoError(t, err)
+	assert.Zero(t, theme)
+
+	_, err = zero.DecodeCookie[string](r, "theme")
+	assert.EqualError(t, err, "400: cookie theme is required")
+}
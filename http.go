@@ -1,15 +1,21 @@
 package zero
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
 	"maps"
+	"mime"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/alecthomas/errors"
 	"github.com/dyninc/qstring"
@@ -28,6 +34,11 @@ type ResponseEncoder func(logger *slog.Logger, r *http.Request, w http.ResponseW
 // Middleware is a convenience type for Zero middleware.
 type Middleware func(next http.Handler) http.Handler
 
+// RedactedFields are the JSON names of the fields of an API's request and response types tagged `log:"-"`.
+//
+// Middleware factories with a RedactedFields parameter are passed those of the API they are applied to.
+type RedactedFields []string
+
 // An APIError is an error that is also a http.Handler used to encode the error.
 //
 // Any request handler returning an error
@@ -41,6 +52,49 @@ type StatusCode interface {
 	StatusCode() int
 }
 
+// Status is an HTTP status code returned by an API method before its response, overriding the default of 200 OK, eg.
+//
+//	//zero:api POST /users
+//	func (s *Service) CreateUser(user User) (zero.Status, User, error) {
+//		return http.StatusCreated, user, nil
+//	}
+//
+// An int may also be used. A zero status is equivalent to 200 OK.
+type Status int
+
+// WithStatus returns a ResponseWriter that responds with status where the response would otherwise be 200 OK.
+//
+// Generated handlers use it to apply the status returned by API methods. A zero status returns w unchanged.
+func WithStatus(w http.ResponseWriter, status int) http.ResponseWriter {
+	if status == 0 || status == http.StatusOK {
+		return w
+	}
+	return &statusWriter{ResponseWriter: w, status: status}
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (s *statusWriter) WriteHeader(status int) {
+	if !s.wroteHeader && status == http.StatusOK {
+		status = s.status
+	}
+	s.wroteHeader = true
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusWriter) Write(data []byte) (int, error) {
+	if !s.wroteHeader {
+		s.WriteHeader(http.StatusOK)
+	}
+	return s.ResponseWriter.Write(data)
+}
+
+func (s *statusWriter) Unwrap() http.ResponseWriter { return s.ResponseWriter }
+
 // APIErrorf can be used with HTTP handlers to return a JSON-encoded error body in the form {"error: <msg>", "code": <code>}
 func APIErrorf(code int, format string, args ...any) APIError {
 	return apiError{
@@ -65,12 +119,146 @@ func (a apiError) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(map[string]string{"error": a.err.Error(), "code": strconv.Itoa(a.code)}) //nolint
 }
 
+// RequestLimits returns middleware limiting the duration of requests and the size of request bodies.
+//
+// The request context is cancelled after timeout, and reading more than maxBody bytes from the request body fails.
+// Handlers returning the resulting errors respond with 408 and 413 respectively. Zero disables either limit.
+func RequestLimits(logger *slog.Logger, errorEncoder ErrorEncoder, timeout time.Duration, maxBody ByteSize) Middleware {
+	return func(next http.Handler) http.Handler {
+		if timeout <= 0 && maxBody <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if maxBody > 0 {
+				if r.ContentLength > int64(maxBody) {
+					errorEncoder(logger, w, "request body too large", http.StatusRequestEntityTooLarge)
+					return
+				}
+				r.Body = http.MaxBytesReader(w, r.Body, int64(maxBody))
+			}
+			if timeout > 0 {
+				ctx, cancel := context.WithTimeout(r.Context(), timeout)
+				defer cancel()
+				r = r.WithContext(ctx)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RedirectTrailingSlash permanently redirects a request to its path with the trailing slash added or removed, with
+// 301 Moved Permanently for GET and HEAD requests, and 308 Permanent Redirect otherwise so that the method and body
+// are preserved.
+func RedirectTrailingSlash(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.EscapedPath()
+	if trimmed, ok := strings.CutSuffix(path, "/"); ok {
+		path = trimmed
+	} else {
+		path += "/"
+	}
+	if r.URL.RawQuery != "" {
+		path += "?" + r.URL.RawQuery
+	}
+	status := http.StatusPermanentRedirect
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		status = http.StatusMovedPermanently
+	}
+	// http.Redirect cleans the path, so it can't be a protocol-relative URL such as //example.com.
+	http.Redirect(w, r, path, status)
+}
+
+// CheckContentType returns an APIError with a 415 status if the media type of the request's Content-Type header is
+// not one of mediaTypes, which may contain wildcards such as "image/*" or "*/*".
+func CheckContentType(r *http.Request, mediaTypes ...string) error {
+	header := r.Header.Get("Content-Type")
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return APIErrorf(http.StatusUnsupportedMediaType, "unsupported Content-Type %q, expected one of %s", header, strings.Join(mediaTypes, ", "))
+	}
+	for _, accepted := range mediaTypes {
+		if accepted == "*/*" || strings.EqualFold(accepted, mediaType) {
+			return nil
+		}
+		if prefix, ok := strings.CutSuffix(accepted, "/*"); ok && strings.HasPrefix(mediaType, strings.ToLower(prefix)+"/") {
+			return nil
+		}
+	}
+	return APIErrorf(http.StatusUnsupportedMediaType, "unsupported Content-Type %q, expected one of %s", header, strings.Join(mediaTypes, ", "))
+}
+
+// ETag buffers successful responses to GET and HEAD requests and sets a strong ETag header computed over the
+// response body, responding with 304 Not Modified if it matches the If-None-Match request header.
+//
+// Responses that already have an ETag header are passed through unchanged.
+func ETag(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+		rec := &etagRecorder{header: w.Header()}
+		next.ServeHTTP(rec, r)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		if rec.status == http.StatusOK && w.Header().Get("ETag") == "" {
+			hash := sha256.Sum256(rec.body.Bytes())
+			etag := `"` + hex.EncodeToString(hash[:16]) + `"`
+			w.Header().Set("ETag", etag)
+			if etagMatches(r.Header.Get("If-None-Match"), etag) {
+				w.Header().Del("Content-Type")
+				w.Header().Del("Content-Length")
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+		w.WriteHeader(rec.status)
+		_, _ = w.Write(rec.body.Bytes())
+	})
+}
+
+// etagMatches reports whether the If-None-Match header matches etag, using weak comparison as per RFC 9110.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for candidate := range strings.SplitSeq(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// etagRecorder buffers a response so that its ETag can be computed before it is written.
+type etagRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (e *etagRecorder) Header() http.Header { return e.header }
+
+func (e *etagRecorder) WriteHeader(status int) {
+	if e.status == 0 {
+		e.status = status
+	}
+}
+
+func (e *etagRecorder) Write(data []byte) (int, error) {
+	if e.status == 0 {
+		e.status = http.StatusOK
+	}
+	return e.body.Write(data)
+}
+
 // DecodeRequest decodes the JSON request body into T for PATCH/POST/PUT methods, and query parameters for all other method types.
 func DecodeRequest[T any](method string, r *http.Request) (T, error) {
 	var result T
 	method = strings.ToUpper(method)
 	if method == http.MethodPatch || method == http.MethodPost || method == http.MethodPut {
 		if err := json.NewDecoder(r.Body).Decode(&result); err != nil {
+			if maxBytesErr := (*http.MaxBytesError)(nil); errors.As(err, &maxBytesErr) {
+				return result, APIErrorf(http.StatusRequestEntityTooLarge, "request body too large: %w", err)
+			}
 			return result, APIErrorf(http.StatusBadRequest, "failed to decode JSON request body: %w", err)
 		}
 	} else if err := qstring.Unmarshal(r.URL.Query(), &result); err != nil {
@@ -100,9 +288,17 @@ func EncodeError(logger *slog.Logger, w http.ResponseWriter, msg string, status
 func EncodeResponse(logger *slog.Logger, r *http.Request, w http.ResponseWriter, errorEncoder ErrorEncoder, data any, outErr error) {
 	if outErr != nil {
 		var handler http.Handler
-		if errors.As(outErr, &handler) {
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.As(outErr, &handler):
 			handler.ServeHTTP(w, nil)
-		} else {
+		case errors.Is(outErr, context.DeadlineExceeded):
+			errorEncoder(logger, w, outErr.Error(), http.StatusRequestTimeout)
+		case errors.Is(outErr, ErrBreakerOpen):
+			errorEncoder(logger, w, outErr.Error(), http.StatusServiceUnavailable)
+		case errors.As(outErr, &maxBytesErr):
+			errorEncoder(logger, w, outErr.Error(), http.StatusRequestEntityTooLarge)
+		default:
 			errorEncoder(logger, w, outErr.Error(), http.StatusInternalServerError)
 		}
 		return
@@ -182,6 +378,9 @@ func EncodeResponse(logger *slog.Logger, r *http.Request, w http.ResponseWriter,
 			return
 		}
 
+	case JSONEncodable:
+		encodeJSONResponse(logger, w, errorEncoder, data, statusCode)
+
 	default:
 		w.Header().Set("Content-Type", "application/json; charset=utf-8")
 		w.WriteHeader(statusCode)
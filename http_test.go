@@ -9,6 +9,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/alecthomas/assert/v2"
 	"github.com/alecthomas/zero"
@@ -187,6 +188,46 @@ func TestEncodeResponseHTTPHandler(t *testing.T) {
 	assert.Equal(t, "handler-value", w.Header().Get("Handler-Header"))
 }
 
+func TestEncodeResponseWithStatus(t *testing.T) {
+	t.Parallel()
+	logger := slog.Default()
+	errorEncoder := zero.EncodeError
+
+	t.Run("JSON", func(t *testing.T) {
+		t.Parallel()
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		zero.EncodeResponse(logger, r, zero.WithStatus(w, http.StatusCreated), errorEncoder, map[string]string{"id": "1"}, nil)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, `{"id":"1"}`+"\n", w.Body.String())
+	})
+
+	t.Run("ImplicitWriteHeader", func(t *testing.T) {
+		t.Parallel()
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write([]byte("queued")) })
+		zero.EncodeResponse(logger, r, zero.WithStatus(w, http.StatusAccepted), errorEncoder, handler, nil)
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.Equal(t, "queued", w.Body.String())
+	})
+
+	t.Run("ExplicitStatusTakesPrecedence", func(t *testing.T) {
+		t.Parallel()
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		data := mockStatusCoder{Data: "teapot", Code: http.StatusTeapot}
+		zero.EncodeResponse(logger, r, zero.WithStatus(w, http.StatusCreated), errorEncoder, data, nil)
+		assert.Equal(t, http.StatusTeapot, w.Code)
+	})
+
+	t.Run("ZeroStatus", func(t *testing.T) {
+		t.Parallel()
+		w := httptest.NewRecorder()
+		assert.Equal(t, http.ResponseWriter(w), zero.WithStatus(w, 0))
+	})
+}
+
 func TestEncodeResponseWithError(t *testing.T) {
 	t.Parallel()
 	logger := slog.Default()
@@ -229,6 +270,16 @@ func TestEncodeResponseWithError(t *testing.T) {
 		assert.Equal(t, "bad request error", response["error"])
 		assert.Equal(t, "400", response["code"])
 	})
+
+	t.Run("BreakerOpenError", func(t *testing.T) {
+		t.Parallel()
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+		zero.EncodeResponse(logger, r, w, errorEncoder, nil, fmt.Errorf("query failed: %w", zero.ErrBreakerOpen))
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	})
 }
 
 func TestEncodeResponseNamedWithSpecialCharacters(t *testing.T) {
@@ -252,3 +303,139 @@ func TestEncodeResponseNamedWithSpecialCharacters(t *testing.T) {
 	assert.Equal(t, "application/octet-stream", w.Header().Get("Content-Type"))
 	assert.Equal(t, `attachment; filename*=UTF-8''file+with+spaces+%26+symbols+%F0%9F%A4%94.txt`, w.Header().Get("Content-Disposition"))
 }
+
+func TestRequestLimits(t *testing.T) {
+	t.Parallel()
+	logger := slog.Default()
+	type request struct {
+		Name string `json:"name"`
+	}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := zero.DecodeRequest[request](r.Method, r)
+		if err == nil {
+			<-r.Context().Done()
+			err = r.Context().Err()
+		}
+		zero.EncodeResponse(logger, r, w, zero.EncodeError, nil, err)
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name": "bob"}`))
+		zero.RequestLimits(logger, zero.EncodeError, time.Millisecond, 0)(handler).ServeHTTP(w, r)
+		assert.Equal(t, http.StatusRequestTimeout, w.Code)
+	})
+
+	t.Run("ContentLengthTooLarge", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name": "bob"}`))
+		zero.RequestLimits(logger, zero.EncodeError, 0, 4)(handler).ServeHTTP(w, r)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	})
+
+	t.Run("BodyTooLarge", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name": "bob"}`))
+		r.ContentLength = -1
+		zero.RequestLimits(logger, zero.EncodeError, 0, 4)(handler).ServeHTTP(w, r)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	})
+
+	t.Run("StreamedBodyTooLarge", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("0123456789"))
+		r.ContentLength = -1
+		stream := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, err := io.Copy(io.Discard, r.Body)
+			zero.EncodeResponse(logger, r, w, zero.EncodeError, nil, err)
+		})
+		zero.RequestLimits(logger, zero.EncodeError, 0, 4)(stream).ServeHTTP(w, r)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	})
+}
+
+func TestCheckContentType(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		contentType string
+		mediaTypes  []string
+		err         string
+	}{
+		{contentType: "application/pdf", mediaTypes: []string{"application/pdf"}},
+		{contentType: "Image/PNG; charset=binary", mediaTypes: []string{"text/plain", "image/*"}},
+		{contentType: "text/csv", mediaTypes: []string{"*/*"}},
+		{contentType: "text/csv", mediaTypes: []string{"image/*"}, err: `415: unsupported Content-Type "text/csv", expected one of image/*`},
+		{contentType: "", mediaTypes: []string{"application/pdf"}, err: `415: unsupported Content-Type "", expected one of application/pdf`},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		r.Header.Set("Content-Type", test.contentType)
+		err := zero.CheckContentType(r, test.mediaTypes...)
+		if test.err != "" {
+			assert.EqualError(t, err, test.err)
+		} else {
+			assert.NoError(t, err, test.contentType)
+		}
+	}
+}
+
+func TestRedirectTrailingSlash(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		method   string
+		target   string
+		status   int
+		location string
+	}{
+		{method: http.MethodGet, target: "/users?q=1", status: http.StatusMovedPermanently, location: "/users/?q=1"},
+		{method: http.MethodHead, target: "/users/", status: http.StatusMovedPermanently, location: "/users"},
+		{method: http.MethodPost, target: "/users/", status: http.StatusPermanentRedirect, location: "/users"},
+		{method: http.MethodGet, target: "/a%2Fb", status: http.StatusMovedPermanently, location: "/a%2Fb/"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		zero.RedirectTrailingSlash(w, httptest.NewRequest(test.method, test.target, nil))
+		assert.Equal(t, test.status, w.Code, test.target)
+		assert.Equal(t, test.location, w.Header().Get("Location"), test.target)
+	}
+}
+
+func TestETag(t *testing.T) {
+	t.Parallel()
+	logger := slog.Default()
+	var err error
+	handler := zero.ETag(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		zero.EncodeResponse(logger, r, w, zero.EncodeError, []string{"alice", "bob"}, err)
+	}))
+	request := func(method, ifNoneMatch string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, "/", nil)
+		if ifNoneMatch != "" {
+			r.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	w := request(http.MethodGet, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "[\"alice\",\"bob\"]\n", w.Body.String())
+	etag := w.Header().Get("ETag")
+	assert.True(t, strings.HasPrefix(etag, `"`) && strings.HasSuffix(etag, `"`), "expected a strong ETag, got %s", etag)
+
+	w = request(http.MethodGet, etag)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Equal(t, "", w.Body.String())
+	assert.Equal(t, etag, w.Header().Get("ETag"))
+
+	w = request(http.MethodGet, `"other", W/`+etag)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	w = request(http.MethodGet, `"other"`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	err = fmt.Errorf("failed")
+	w = request(http.MethodGet, etag)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "", w.Header().Get("ETag"))
+}
@@ -0,0 +1,20 @@
+package zero
+
+import "github.com/gofrs/uuid/v5"
+
+// IDGenerator generates unique IDs.
+//
+// Providers that generate IDs, such as the request ID middleware, depend on an IDGenerator rather than generating
+// them directly, so that tests can substitute a deterministic sequence. The default provider returns
+// [UUIDv7Generator].
+type IDGenerator interface {
+	// NewID returns a new unique ID.
+	NewID() string
+}
+
+// UUIDv7Generator is an [IDGenerator] of time-ordered UUIDv7s, eg. "01890a5d-ac96-774b-bcce-b302099a8057".
+type UUIDv7Generator struct{}
+
+var _ IDGenerator = UUIDv7Generator{}
+
+func (UUIDv7Generator) NewID() string { return uuid.Must(uuid.NewV7()).String() }
@@ -0,0 +1,19 @@
+package zero_test
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/alecthomas/zero"
+	"github.com/gofrs/uuid/v5"
+)
+
+func TestUUIDv7Generator(t *testing.T) {
+	var generator zero.IDGenerator = zero.UUIDv7Generator{}
+	first, second := generator.NewID(), generator.NewID()
+	assert.NotEqual(t, first, second)
+	id, err := uuid.FromString(first)
+	assert.NoError(t, err)
+	assert.Equal(t, byte(uuid.V7), id.Version())
+	assert.True(t, first < second, "UUIDv7s should be time-ordered")
+}
@@ -13,7 +13,11 @@ type Event[T any] struct {
 	Time            time.Time `json:"time"`
 	ID              string    `json:"id"`
 	DataContentType string    `json:"datacontenttype"`
-	Data            T         `json:"data"`
+	// DataVersion is an extension attribute carrying the version of the data schema, if any.
+	DataVersion int `json:"dataversion,omitempty"`
+	// Tenant is an extension attribute carrying the ID of the tenant the event was published on behalf of, if any.
+	Tenant string `json:"tenant,omitempty"`
+	Data   T      `json:"data"`
 }
 
 func New[T any](id, source string, created time.Time, data T) Event[T] {
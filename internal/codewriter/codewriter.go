@@ -109,29 +109,7 @@ func (c *Writer) write(w io.Writer) {
 	fmt.Fprintf(w, "// Code generated by zero. DO NOT EDIT.\n")
 	fmt.Fprintf(w, "package %s\n", c.pkg)
 	fmt.Fprintf(w, "\n")
-	imports := []string{}
-	seen := map[string]bool{}
-	for _, pkg := range *c.imports {
-		key := strings.Trim(pkg, "\"")
-		if key == "" || seen[key] {
-			continue
-		}
-		seen[key] = true
-		imports = append(imports, pkg)
-	}
-	sort.Strings(imports)
-	if len(imports) > 0 {
-		fmt.Fprintf(w, "import (\n")
-		for _, pkg := range imports {
-			if strings.HasSuffix(pkg, "\"") {
-				fmt.Fprintf(w, "  %s\n", pkg)
-			} else {
-				fmt.Fprintf(w, "  %q\n", pkg)
-			}
-		}
-		fmt.Fprintf(w, ")\n")
-		fmt.Fprintf(w, "\n")
-	}
+	io.WriteString(w, ImportBlock(*c.imports))
 	io.Copy(w, c.w)
 	for _, trailer := range c.trailer {
 		trailer.writeBody(w)
@@ -145,3 +123,33 @@ func (c *Writer) writeBody(w io.Writer) {
 		fmt.Fprintln(w, trailer.Body())
 	}
 }
+
+// ImportBlock returns the sorted and deduplicated import declaration of generated code, followed by a blank line, or
+// "" if there are no imports. Imports are either a path, or an alias followed by a quoted path.
+func ImportBlock(imports []string) string {
+	unique := []string{}
+	seen := map[string]bool{}
+	for _, pkg := range imports {
+		key := strings.Trim(pkg, "\"")
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, pkg)
+	}
+	if len(unique) == 0 {
+		return ""
+	}
+	sort.Strings(unique)
+	out := &strings.Builder{}
+	fmt.Fprintf(out, "import (\n")
+	for _, pkg := range unique {
+		if strings.HasSuffix(pkg, "\"") {
+			fmt.Fprintf(out, "  %s\n", pkg)
+		} else {
+			fmt.Fprintf(out, "  %q\n", pkg)
+		}
+	}
+	fmt.Fprintf(out, ")\n\n")
+	return out.String()
+}
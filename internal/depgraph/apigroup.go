@@ -0,0 +1,222 @@
+package depgraph
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"slices"
+	"strings"
+
+	"github.com/alecthomas/errors"
+	"github.com/alecthomas/zero/internal/directiveparser"
+	"golang.org/x/tools/go/packages"
+)
+
+// APIGroup is a group of APIs sharing a path prefix and OpenAPI tag, declared with a //zero:api-group directive on
+// the package clause or on the API receiver type, eg.
+//
+//	//zero:api-group prefix=/v2 tag=v2
+//
+// A group on a receiver type takes precedence over a group on its package.
+type APIGroup struct {
+	// Position is the position of the directive.
+	Position token.Position
+	// Prefix is the path prefixed to the path of each API in the group, eg. /v2
+	Prefix string
+	// Tag is the OpenAPI tag of each API in the group, or empty to use the package name.
+	Tag string
+
+	segments []directiveparser.Segment
+}
+
+// apiLabels are labels inherited by every API on a receiver type, declared with a //zero:api-labels directive on the
+// receiver type or its provider, eg.
+//
+//	//zero:api-labels authenticated tenant
+type apiLabels struct {
+	position token.Position
+	labels   []*directiveparser.Label
+}
+
+// apiGroups are the API groups and labels declared in a package.
+type apiGroups struct {
+	pkg       *APIGroup
+	receivers map[*types.TypeName]*APIGroup
+	labels    map[*types.TypeName]*apiLabels
+}
+
+// forReceiver returns the API group of a receiver type, if any.
+func (a apiGroups) forReceiver(receiver types.Type) *APIGroup {
+	if typeName := receiverTypeName(receiver); typeName != nil {
+		if group, ok := a.receivers[typeName]; ok {
+			return group
+		}
+	}
+	return a.pkg
+}
+
+// labelsForReceiver returns the labels inherited by the APIs of a receiver type, if any.
+func (a apiGroups) labelsForReceiver(receiver types.Type) *apiLabels {
+	if typeName := receiverTypeName(receiver); typeName != nil {
+		return a.labels[typeName]
+	}
+	return nil
+}
+
+// receiverTypeName returns the name of a named type or pointer to a named type, or nil.
+func receiverTypeName(receiver types.Type) *types.TypeName {
+	if ptr, ok := receiver.(*types.Pointer); ok {
+		receiver = ptr.Elem()
+	}
+	if named, ok := receiver.(*types.Named); ok {
+		return named.Obj()
+	}
+	return nil
+}
+
+// packageAPIGroups collects the //zero:api-group directives on the package clause and type declarations of a package,
+// and the //zero:api-labels directives on its type declarations and providers, which must be known before its APIs are
+// analysed.
+func packageAPIGroups(pkg *packages.Package, fset *token.FileSet) (apiGroups, error) {
+	groups := apiGroups{receivers: map[*types.TypeName]*APIGroup{}, labels: map[*types.TypeName]*apiLabels{}}
+	for _, file := range pkg.Syntax {
+		if file.Doc != nil {
+			for _, comment := range file.Doc.List {
+				if !strings.HasPrefix(comment.Text, "//zero:api-group") {
+					continue
+				}
+				group, err := createAPIGroup(comment.Text[2:], fset.Position(comment.Pos()))
+				if err != nil {
+					return groups, err
+				}
+				if groups.pkg != nil {
+					return groups, errors.Errorf("%s: package %s already has an API group at %s", group.Position, pkg.PkgPath, groups.pkg.Position)
+				}
+				groups.pkg = group
+			}
+		}
+		for _, decl := range file.Decls {
+			if err := collectAPILabels(decl, pkg, groups.labels, fset); err != nil {
+				return groups, err
+			}
+			decl, ok := decl.(*ast.GenDecl)
+			if !ok || decl.Tok != token.TYPE || decl.Doc == nil {
+				continue
+			}
+			for _, comment := range decl.Doc.List {
+				if !strings.HasPrefix(comment.Text, "//zero:api-group") {
+					continue
+				}
+				group, err := createAPIGroup(comment.Text[2:], fset.Position(comment.Pos()))
+				if err != nil {
+					return groups, err
+				}
+				for _, spec := range decl.Specs {
+					typeSpec, ok := spec.(*ast.TypeSpec)
+					if !ok {
+						continue
+					}
+					if typeName, ok := pkg.TypesInfo.Defs[typeSpec.Name].(*types.TypeName); ok {
+						groups.receivers[typeName] = group
+					}
+				}
+			}
+		}
+	}
+	return groups, nil
+}
+
+// collectAPILabels records the //zero:api-labels directive of a type declaration, or of a provider function against
+// the type it provides.
+func collectAPILabels(decl ast.Decl, pkg *packages.Package, labels map[*types.TypeName]*apiLabels, fset *token.FileSet) error {
+	var doc *ast.CommentGroup
+	var typeNames []*types.TypeName
+	switch decl := decl.(type) {
+	case *ast.GenDecl:
+		if decl.Tok != token.TYPE {
+			return nil
+		}
+		doc = decl.Doc
+		for _, spec := range decl.Specs {
+			if typeSpec, ok := spec.(*ast.TypeSpec); ok {
+				if typeName, ok := pkg.TypesInfo.Defs[typeSpec.Name].(*types.TypeName); ok {
+					typeNames = append(typeNames, typeName)
+				}
+			}
+		}
+	case *ast.FuncDecl:
+		doc = decl.Doc
+		if fn, ok := pkg.TypesInfo.Defs[decl.Name].(*types.Func); ok && fn.Signature().Results().Len() > 0 {
+			if typeName := receiverTypeName(fn.Signature().Results().At(0).Type()); typeName != nil {
+				typeNames = append(typeNames, typeName)
+			}
+		}
+	}
+	if doc == nil {
+		return nil
+	}
+	for _, comment := range doc.List {
+		if !strings.HasPrefix(comment.Text, "//zero:api-labels") {
+			continue
+		}
+		position := fset.Position(comment.Pos())
+		directive, err := directiveparser.Parse(comment.Text[2:])
+		if err != nil {
+			return errors.Errorf("%s: %w", position, err)
+		}
+		labelsDirective, ok := directive.(*directiveparser.DirectiveAPILabels)
+		if !ok {
+			return errors.Errorf("%s: %s: unexpected directive", position, directive)
+		}
+		if len(typeNames) == 0 {
+			return errors.Errorf("%s: //zero:api-labels must be on an API receiver type or a provider of one", position)
+		}
+		for _, typeName := range typeNames {
+			if existing, ok := labels[typeName]; ok {
+				return errors.Errorf("%s: %s already has API labels at %s", position, typeName.Name(), existing.position)
+			}
+			labels[typeName] = &apiLabels{position: position, labels: labelsDirective.Labels}
+		}
+	}
+	return nil
+}
+
+func createAPIGroup(text string, position token.Position) (*APIGroup, error) {
+	directive, err := directiveparser.Parse(text)
+	if err != nil {
+		return nil, errors.Errorf("%s: %w", position, err)
+	}
+	groupDirective, ok := directive.(*directiveparser.DirectiveAPIGroup)
+	if !ok {
+		return nil, errors.Errorf("%s: %s: unexpected directive", position, directive)
+	}
+	segments, err := groupDirective.Prefix()
+	if err != nil {
+		return nil, errors.Errorf("%s: %w", position, err)
+	}
+	group := &APIGroup{Position: position, Tag: groupDirective.Tag(), segments: segments}
+	for _, segment := range segments {
+		group.Prefix += segment.String()
+	}
+	return group, nil
+}
+
+// apply prefixes the path of an API directive with the group prefix.
+func (g *APIGroup) apply(directive *directiveparser.DirectiveAPI) {
+	directive.Segments = slices.Concat(g.segments, directive.Segments)
+}
+
+// apply adds the labels to an API directive, unless it has a label of the same name, and validates the result.
+func (l *apiLabels) apply(directive *directiveparser.DirectiveAPI) error {
+	var inherited []*directiveparser.Label
+	for _, label := range l.labels {
+		if _, ok := directive.Label(label.Name); !ok {
+			inherited = append(inherited, label)
+		}
+	}
+	directive.Labels = slices.Concat(inherited, directive.Labels)
+	if err := directive.Validate(); err != nil {
+		return errors.Errorf("labels inherited from %s: %w", l.position, err)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package depgraph
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestAPIGroups(t *testing.T) {
+	t.Parallel()
+	graph := analyseTestCode(t, `
+//zero:api-group prefix=/v1 tag=v1
+package test
+
+type User struct {
+	Name string
+}
+
+type ServiceV1 struct{}
+
+//zero:provider
+func NewServiceV1() *ServiceV1 { return &ServiceV1{} }
+
+//zero:api GET /users
+func (s *ServiceV1) ListUsers() ([]User, error) { return nil, nil }
+
+//zero:api GET /users/{id} tag=admin
+func (s *ServiceV1) GetUser(id string) (User, error) { return User{}, nil }
+
+//zero:api-group prefix=/orgs/{org}/v2 tag=v2
+type ServiceV2 struct{}
+
+//zero:provider
+func NewServiceV2() *ServiceV2 { return &ServiceV2{} }
+
+//zero:api GET /users
+func (s *ServiceV2) ListUsers(org string) ([]User, error) { return nil, nil }
+`)
+	var patterns []string
+	for _, route := range graph.Routes() {
+		patterns = append(patterns, route.Method+" "+route.Pattern)
+	}
+	assert.Equal(t, []string{"GET /v1/users", "GET /v1/users/{id}", "GET /orgs/{org}/v2/users"}, patterns)
+
+	swagger := graph.GenerateOpenAPISpec("Test", "1.0")
+	assert.Equal(t, []string{"v1"}, swagger.Paths.Paths["/v1/users"].Get.Tags)
+	assert.Equal(t, []string{"admin"}, swagger.Paths.Paths["/v1/users/{id}"].Get.Tags)
+	v2 := swagger.Paths.Paths["/orgs/{org}/v2/users"].Get
+	assert.Equal(t, []string{"v2"}, v2.Tags)
+	assert.Equal(t, "org", v2.Parameters[0].Name)
+	assert.Equal(t, "path", v2.Parameters[0].In)
+}
+
+func TestAPIGroupErrors(t *testing.T) {
+	t.Parallel()
+	_, err := analyseTestCodeWithError(t, `
+package test
+
+//zero:api-group prefix=/v2/
+type Service struct{}
+
+//zero:provider
+func NewService() *Service { return &Service{} }
+
+//zero:api GET /users
+func (s *Service) ListUsers() error { return nil }
+`)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `main.go:5:1: api-group prefix "/v2/" must not end with /`)
+}
+
+func TestAPILabels(t *testing.T) {
+	t.Parallel()
+	graph := analyseTestCode(t, `
+package test
+
+import "net/http"
+
+//zero:middleware authenticated
+func Auth(next http.Handler) http.Handler { return next }
+
+//zero:api-labels authenticated role=user
+type Users struct{}
+
+//zero:provider
+func NewUsers() *Users { return &Users{} }
+
+//zero:api GET /users
+func (s *Users) List() {}
+
+//zero:api DELETE /users/{id} role=admin
+func (s *Users) Delete(id string) {}
+
+type Health struct{}
+
+//zero:provider
+//zero:api-labels timeout=1s
+func NewHealth() *Health { return &Health{} }
+
+//zero:api GET /health
+func (s *Health) Check() {}
+`)
+	var routes []string
+	for _, route := range graph.Routes() {
+		routes = append(routes, fmt.Sprintf("%s %s %v %v", route.Method, route.Pattern, route.Labels, route.Middleware))
+	}
+	assert.Equal(t, []string{
+		"GET /users [authenticated role=user] [test.Auth]",
+		"DELETE /users/{id} [authenticated role=admin] [test.Auth]",
+		"GET /health [timeout=1s] []",
+	}, routes)
+}
+
+func TestAPILabelsErrors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		code string
+		err  string
+	}{
+		{"Invalid", `
+//zero:api-labels etag
+type Service struct{}
+
+//zero:provider
+func NewService() *Service { return &Service{} }
+
+//zero:api POST /users
+func (s *Service) Create() {}
+`, "API method Create: labels inherited from "},
+		{"Duplicate", `
+//zero:api-labels authenticated
+type Service struct{}
+
+//zero:provider
+//zero:api-labels authenticated
+func NewService() *Service { return &Service{} }
+`, "Service already has API labels at "},
+		{"NoType", `
+//zero:api-labels authenticated
+func Setup() {}
+`, "//zero:api-labels must be on an API receiver type or a provider of one"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := analyseTestCodeWithError(t, "package test\n"+test.code)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), test.err)
+		})
+	}
+}
@@ -0,0 +1,244 @@
+package depgraph
+
+import (
+	"go/types"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/alecthomas/zero/internal/strcase"
+	"github.com/go-openapi/spec"
+	"golang.org/x/tools/go/packages"
+)
+
+const pubsubTopicType = "github.com/alecthomas/zero/providers/pubsub.Topic"
+
+// AsyncAPI is an AsyncAPI 3.0 document describing the pubsub topics of the graph.
+type AsyncAPI struct {
+	AsyncAPI   string                        `json:"asyncapi"`
+	Info       AsyncAPIInfo                  `json:"info"`
+	Channels   map[string]*AsyncAPIChannel   `json:"channels"`
+	Operations map[string]*AsyncAPIOperation `json:"operations"`
+	Components AsyncAPIComponents            `json:"components"`
+}
+
+type AsyncAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// AsyncAPIChannel is a pubsub topic.
+type AsyncAPIChannel struct {
+	Address  string                  `json:"address"`
+	Messages map[string]*AsyncAPIRef `json:"messages"`
+}
+
+// AsyncAPIOperation is a publisher or subscriber of a topic.
+type AsyncAPIOperation struct {
+	// Action is "send" for publishers and "receive" for subscribers.
+	Action      string         `json:"action"`
+	Channel     *AsyncAPIRef   `json:"channel"`
+	Summary     string         `json:"summary,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Messages    []*AsyncAPIRef `json:"messages"`
+}
+
+type AsyncAPIComponents struct {
+	Messages map[string]*AsyncAPIMessage `json:"messages"`
+	Schemas  spec.Definitions            `json:"schemas"`
+}
+
+// AsyncAPIMessage is an event, encoded as a structured CloudEvent with the payload in "data".
+type AsyncAPIMessage struct {
+	Name        string       `json:"name"`
+	ContentType string       `json:"contentType"`
+	Payload     *spec.Schema `json:"payload"`
+}
+
+type AsyncAPIRef struct {
+	Ref string `json:"$ref"`
+}
+
+// GenerateAsyncAPISpec creates an AsyncAPI specification from all pubsub topics published to or subscribed to.
+//
+// Topics are published to by providers requiring a pubsub.Topic[T], and subscribed to by //zero:subscribe methods.
+func (g *Graph) GenerateAsyncAPISpec(title, version string) *AsyncAPI {
+	doc := &AsyncAPI{
+		AsyncAPI:   "3.0.0",
+		Info:       AsyncAPIInfo{Title: title, Version: version, Description: g.OpenAPI.Description},
+		Channels:   map[string]*AsyncAPIChannel{},
+		Operations: map[string]*AsyncAPIOperation{},
+		Components: AsyncAPIComponents{
+			Messages: map[string]*AsyncAPIMessage{},
+			Schemas:  spec.Definitions{},
+		},
+	}
+	for _, provider := range g.topicPublishers() {
+		for _, payload := range provider.payloads {
+			channel, message := doc.addTopic(provider.pkg, payload)
+			name := "publish_" + channel
+			if operation, ok := doc.Operations[name]; ok {
+				operation.Summary += ", " + provider.name
+				continue
+			}
+			doc.Operations[name] = &AsyncAPIOperation{
+				Action:   "send",
+				Channel:  &AsyncAPIRef{Ref: "#/channels/" + channel},
+				Summary:  "Published by " + provider.name,
+				Messages: []*AsyncAPIRef{{Ref: "#/channels/" + channel + "/messages/" + message}},
+			}
+		}
+	}
+	for _, subscription := range g.Subscriptions {
+		if subscription.TopicType == nil {
+			continue
+		}
+		channel, message := doc.addTopic(subscription.Package, subscription.TopicType)
+		name := subscription.Function.Name()
+		if recv := subscription.Function.Signature().Recv(); recv != nil {
+			name = strings.TrimPrefix(types.TypeString(recv.Type(), (*types.Package).Name), "*") + "." + name
+		}
+		doc.Operations[name] = &AsyncAPIOperation{
+			Action:   "receive",
+			Channel:  &AsyncAPIRef{Ref: "#/channels/" + channel},
+			Messages: []*AsyncAPIRef{{Ref: "#/channels/" + channel + "/messages/" + message}},
+		}
+	}
+	for name, schema := range doc.Components.Schemas {
+		rewriteSchemaRefs(&schema)
+		doc.Components.Schemas[name] = schema
+	}
+	for _, message := range doc.Components.Messages {
+		rewriteSchemaRefs(message.Payload)
+	}
+	return doc
+}
+
+// addTopic adds the channel and message for a topic with the given payload type, returning their names.
+func (a *AsyncAPI) addTopic(pkg *packages.Package, payload types.Type) (channel, message string) {
+	channel = topicName(payload)
+	payloadSchema := (&API{Package: pkg}).generateSchemaFromType(payload, a.Components.Schemas)
+	message = strings.TrimPrefix(payloadSchema.Ref.String(), "#/definitions/")
+	if message == "" {
+		message = channel
+	}
+	if _, ok := a.Channels[channel]; !ok {
+		a.Channels[channel] = &AsyncAPIChannel{
+			Address:  channel,
+			Messages: map[string]*AsyncAPIRef{message: {Ref: "#/components/messages/" + message}},
+		}
+	}
+	if _, ok := a.Components.Messages[message]; !ok {
+		a.Components.Messages[message] = &AsyncAPIMessage{
+			Name:        message,
+			ContentType: "application/cloudevents+json; charset=utf-8",
+			Payload:     cloudEventSchema(payloadSchema),
+		}
+	}
+	return channel, message
+}
+
+// cloudEventSchema returns the schema of a structured CloudEvent, as encoded by pubsub.Event[T].
+func cloudEventSchema(data *spec.Schema) *spec.Schema {
+	schema := &spec.Schema{}
+	schema.Type = []string{"object"}
+	schema.Required = []string{"specversion", "type", "source", "id", "data"}
+	schema.Properties = map[string]spec.Schema{
+		"specversion":     *spec.StringProperty(),
+		"type":            *spec.StringProperty(),
+		"source":          *spec.StringProperty(),
+		"time":            *spec.DateTimeProperty(),
+		"id":              *spec.StringProperty(),
+		"datacontenttype": *spec.StringProperty(),
+		"data":            *data,
+	}
+	return schema
+}
+
+// rewriteSchemaRefs rewrites references to OpenAPI definitions to AsyncAPI component schemas.
+func rewriteSchemaRefs(schema *spec.Schema) {
+	if name, ok := strings.CutPrefix(schema.Ref.String(), "#/definitions/"); ok {
+		schema.Ref = spec.MustCreateRef("#/components/schemas/" + name)
+	}
+	for name, property := range schema.Properties {
+		rewriteSchemaRefs(&property)
+		schema.Properties[name] = property
+	}
+	if schema.Items != nil && schema.Items.Schema != nil {
+		rewriteSchemaRefs(schema.Items.Schema)
+	}
+}
+
+type topicPublisher struct {
+	name     string
+	pkg      *packages.Package
+	payloads []types.Type
+}
+
+// Topics returns the payload types of all pubsub topics published to or subscribed to, keyed by topic name.
+func (g *Graph) Topics() map[string]types.Type {
+	topics := map[string]types.Type{}
+	for _, provider := range g.topicPublishers() {
+		for _, payload := range provider.payloads {
+			topics[topicName(payload)] = payload
+		}
+	}
+	for _, subscription := range g.Subscriptions {
+		if subscription.TopicType != nil {
+			topics[topicName(subscription.TopicType)] = subscription.TopicType
+		}
+	}
+	return topics
+}
+
+// topicPublishers returns the providers requiring a pubsub.Topic[T], ordered by name.
+func (g *Graph) topicPublishers() []topicPublisher {
+	var out []topicPublisher
+	for _, key := range slices.Sorted(maps.Keys(g.Providers)) {
+		for _, provider := range g.Providers[key] {
+			if provider.IsGeneric || provider.Function == nil {
+				continue
+			}
+			publisher := topicPublisher{name: provider.FullName(), pkg: provider.Package}
+			for _, require := range provider.Requires {
+				if payload := topicPayload(require); payload != nil {
+					publisher.payloads = append(publisher.payloads, payload)
+				}
+			}
+			if len(publisher.payloads) > 0 {
+				out = append(out, publisher)
+			}
+		}
+	}
+	return out
+}
+
+// topicPayload returns T if t is pubsub.Topic[T], or nil.
+func topicPayload(t types.Type) types.Type {
+	named, ok := t.(*types.Named)
+	if !ok || named.TypeArgs().Len() != 1 {
+		return nil
+	}
+	obj := named.Origin().Obj()
+	if obj.Pkg() == nil || obj.Pkg().Path()+"."+obj.Name() != pubsubTopicType {
+		return nil
+	}
+	return named.TypeArgs().At(0)
+}
+
+// topicName returns the name of the topic for a payload type, matching pubsub.TopicName.
+func topicName(t types.Type) string {
+	for {
+		ptr, ok := t.(*types.Pointer)
+		if !ok {
+			break
+		}
+		t = ptr.Elem()
+	}
+	name := types.TypeString(t, nil)
+	if named, ok := t.(*types.Named); ok {
+		name = named.Obj().Name()
+	}
+	return strings.ReplaceAll(strings.ToLower(strings.Join(strcase.Split(name), "_")), "__", "_")
+}
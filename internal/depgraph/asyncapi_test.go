@@ -0,0 +1,61 @@
+package depgraph
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestGenerateAsyncAPISpec(t *testing.T) {
+	t.Parallel()
+	graph := analyseTestCode(t, `
+package main
+
+import (
+	"context"
+	"github.com/alecthomas/zero/providers/pubsub"
+)
+
+type UserCreated struct {
+	// ID of the user.
+	UserID string `+"`json:\"userId\"`"+`
+}
+
+type Service struct {
+	topic pubsub.Topic[UserCreated]
+}
+
+//zero:provider
+func NewService(topic pubsub.Topic[UserCreated]) *Service {
+	return &Service{topic: topic}
+}
+
+//zero:subscribe
+func (s *Service) OnUserCreated(ctx context.Context, event pubsub.Event[UserCreated]) error {
+	return nil
+}
+`, WithRoots("*test.Service"), WithProviders("github.com/alecthomas/zero/providers/pubsub.NewMemoryTopic"))
+
+	doc := graph.GenerateAsyncAPISpec("Events", "1.0.0")
+	assert.Equal(t, "3.0.0", doc.AsyncAPI)
+	assert.Equal(t, &AsyncAPIChannel{
+		Address:  "user_created",
+		Messages: map[string]*AsyncAPIRef{"main.UserCreated": {Ref: "#/components/messages/main.UserCreated"}},
+	}, doc.Channels["user_created"])
+
+	publish := doc.Operations["publish_user_created"]
+	assert.Equal(t, "send", publish.Action)
+	assert.Equal(t, "Published by test.NewService", publish.Summary)
+	receive := doc.Operations["main.Service.OnUserCreated"]
+	assert.Equal(t, "receive", receive.Action)
+	assert.Equal(t, "#/channels/user_created/messages/main.UserCreated", receive.Messages[0].Ref)
+
+	message := doc.Components.Messages["main.UserCreated"]
+	data := message.Payload.Properties["data"]
+	assert.Equal(t, "#/components/schemas/main.UserCreated", data.Ref.String())
+	assert.Equal(t, "ID of the user.", doc.Components.Schemas["main.UserCreated"].Properties["userId"].Description)
+
+	_, err := json.Marshal(doc)
+	assert.NoError(t, err)
+}
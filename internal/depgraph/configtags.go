@@ -0,0 +1,432 @@
+package depgraph
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/alecthomas/errors"
+)
+
+// kongTags are the struct tag keys understood by Kong.
+var kongTags = []string{
+	"aliases", "and", "arg", "cmd", "default", "embed", "enum", "env", "envprefix", "format", "group", "help", "hidden",
+	"mapsep", "name", "negatable", "optional", "passthrough", "placeholder", "prefix", "required", "sep", "set", "short",
+	"type", "xor", "xorprefix",
+}
+
+// renameTags are the struct tag keys used by Zero to rename config fields, see [ConfigRename].
+var renameTags = []string{"rename", "renameenv"}
+
+// decodingTags are the Kong tags of a field that control how its value is decoded, and so are copied to the flags of
+// its former names.
+var decodingTags = []string{"type", "sep", "mapsep", "format"}
+
+// otherTags are common struct tag keys of other packages that are similar to a Kong tag key.
+var otherTags = []string{"json", "yaml", "toml", "hcl", "xml", "mapstructure", "validate", "log"}
+
+// builtinFlags are the flags that Kong adds to every application.
+var builtinFlags = []string{"--help", "-h"}
+
+// configFlag is a flag declared by a field of a config struct.
+type configFlag struct {
+	position token.Position
+	config   string
+}
+
+// checkConfigTags validates the Kong tags of the fields of configs in the graph, so that misconfigurations are reported
+// during generation rather than when the service starts.
+//
+// Tag keys similar to a Kong tag key are reported as typos, and defaults and enum values must be valid for the type of
+// the field. Flags, including aliases, short flags and negations, must be unique across all configs in the graph once
+// prefixed, as Kong panics on startup otherwise. Fields using the kong:"..." tag syntax are not checked.
+//
+// The former names of renamed fields are recorded in [Config.Renames], and must not conflict with other flags either.
+// Configs with a Validate() error method are recorded in [Config.Validates].
+func checkConfigTags(graph *Graph, fset *token.FileSet) error {
+	flags := map[string]configFlag{}
+	checked := map[string]bool{}
+	for _, key := range slices.Sorted(maps.Keys(graph.Configs)) {
+		config := graph.Configs[key]
+		configType := config.Type
+		if ptr, ok := configType.(*types.Pointer); ok {
+			configType = ptr.Elem()
+		}
+		config.Validates = hasValidateMethod(configType)
+		name := types.TypeString(configType, nil)
+		if checked[name] {
+			continue
+		}
+		checked[name] = true
+		prefix := ""
+		if config.Directive != nil {
+			prefix = config.Directive.Prefix
+		}
+		config.Renames = nil
+		if err := checkConfigFields(configType, name, prefix, nil, &config.Renames, flags, fset); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkConfigFields(t types.Type, config, prefix string, path []string, renames *[]ConfigRename, flags map[string]configFlag, fset *token.FileSet) error {
+	strct, ok := t.Underlying().(*types.Struct)
+	if !ok {
+		return nil
+	}
+	for i := range strct.NumFields() {
+		field := strct.Field(i)
+		if !field.Exported() {
+			continue
+		}
+		position := fset.Position(field.Pos())
+		tags, err := parseStructTag(strct.Tag(i))
+		if err != nil {
+			return errors.Errorf("%s: %s: %w", position, field.Name(), err)
+		}
+		if _, ok := tags["kong"]; ok {
+			continue
+		}
+		for key := range tags {
+			if suggestion := similarKongTag(key); suggestion != "" {
+				return errors.Errorf("%s: %s: unknown tag %q, did you mean %q?", position, field.Name(), key, suggestion)
+			}
+		}
+		fieldPath := append(slices.Clone(path), field.Name())
+		_, renamed := tags["rename"]
+		_, renamedEnv := tags["renameenv"]
+		if renamedEnv && !renamed {
+			return errors.Errorf("%s: %s: renameenv requires rename", position, field.Name())
+		}
+		if _, ok := tags["embed"]; ok || field.Anonymous() {
+			if renamed {
+				return errors.Errorf("%s: %s: embedded structs can not be renamed", position, field.Name())
+			}
+			if err := checkConfigFields(field.Type(), config, prefix+tags["prefix"], fieldPath, renames, flags, fset); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, ok := tags["arg"]; ok {
+			if renamed {
+				return errors.Errorf("%s: %s: positional arguments can not be renamed", position, field.Name())
+			}
+			continue
+		}
+		if err := checkConfigDefaults(field.Type(), tags); err != nil {
+			return errors.Errorf("%s: %s: %w", position, field.Name(), err)
+		}
+		names, err := configFlagNames(field, prefix, tags)
+		if err != nil {
+			return errors.Errorf("%s: %s: %w", position, field.Name(), err)
+		}
+		if renamed {
+			rename, err := configRename(field, fieldPath, prefix, names[0], tags)
+			if err != nil {
+				return errors.Errorf("%s: %s: %w", position, field.Name(), err)
+			}
+			rename.Position = position
+			*renames = append(*renames, rename)
+			names = append(names, rename.Flags...)
+		}
+		for _, name := range names {
+			if slices.Contains(builtinFlags, name) {
+				return errors.Errorf("%s: flag %s of %s conflicts with the builtin %s flag", position, name, config, name)
+			}
+			if existing, ok := flags[name]; ok {
+				return errors.Errorf("%s: flag %s of %s conflicts with %s of %s at %s", position, name, config, name, existing.config, existing.position)
+			}
+			flags[name] = configFlag{position: position, config: config}
+		}
+	}
+	return nil
+}
+
+// configFlagNames returns the flags declared by a field, as Kong names them: the prefixed flag, its aliases, which are
+// not prefixed, its short flag and its negation.
+func configFlagNames(field *types.Var, prefix string, tags map[string]string) ([]string, error) {
+	name := tags["name"]
+	if name == "" {
+		name = toKebabCase(field.Name())
+	}
+	name = prefix + name
+	names := []string{"--" + name}
+	for _, alias := range splitTagList(tags["aliases"]) {
+		names = append(names, "--"+alias)
+	}
+	if short := tags["short"]; short != "" {
+		names = append(names, "-"+short)
+	}
+	if negation, ok := tags["negatable"]; ok {
+		t := field.Type()
+		if ptr, ok := t.(*types.Pointer); ok {
+			t = ptr.Elem()
+		}
+		if basic, ok := t.Underlying().(*types.Basic); !ok || basic.Kind() != types.Bool {
+			return nil, errors.Errorf("negatable can only be set on booleans")
+		}
+		if negation == "" {
+			negation = "no-" + name
+		}
+		names = append(names, "--"+negation)
+	}
+	return names, nil
+}
+
+// configRename returns the former names of a field renamed with a rename:"<old-name>" tag.
+func configRename(field *types.Var, path []string, prefix, flag string, tags map[string]string) (ConfigRename, error) {
+	rename := ConfigRename{Path: path, Type: field.Type(), Flag: flag}
+	for _, name := range splitTagList(tags["rename"]) {
+		rename.Flags = append(rename.Flags, "--"+prefix+name)
+	}
+	if len(rename.Flags) == 0 {
+		return ConfigRename{}, errors.Errorf("rename requires the former name of the flag")
+	}
+	rename.Envs = splitTagList(tags["renameenv"])
+	var decoding []string
+	for _, key := range decodingTags {
+		if value, ok := tags[key]; ok {
+			decoding = append(decoding, fmt.Sprintf("%s:%q", key, value))
+		}
+	}
+	rename.Tags = strings.Join(decoding, " ")
+	return rename, nil
+}
+
+// hasValidateMethod returns true if t, or a pointer to t, has a Validate() error method.
+func hasValidateMethod(t types.Type) bool {
+	selection := types.NewMethodSet(types.NewPointer(t)).Lookup(nil, "Validate")
+	if selection == nil {
+		return false
+	}
+	sig, ok := selection.Type().(*types.Signature)
+	if !ok || sig.Params().Len() != 0 || sig.Results().Len() != 1 {
+		return false
+	}
+	return types.Identical(sig.Results().At(0).Type(), types.Universe.Lookup("error").Type())
+}
+
+func splitTagList(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' })
+}
+
+// checkConfigDefaults checks that the default and enum values of a field are valid for its type.
+func checkConfigDefaults(t types.Type, tags map[string]string) error {
+	if _, ok := tags["type"]; ok {
+		// Values are decoded by a named mapper.
+		return nil
+	}
+	sep, err := tagSeparator(tags, "sep", ',')
+	if err != nil {
+		return err
+	}
+	if _, err := tagSeparator(tags, "mapsep", ';'); err != nil {
+		return err
+	}
+	if short := tags["short"]; short != "" && utf8.RuneCountInString(short) != 1 {
+		return errors.Errorf("short flag %q must be a single character", short)
+	}
+	var enum []string
+	if value, ok := tags["enum"]; ok && !strings.Contains(value, "${") {
+		for element := range strings.SplitSeq(value, ",") {
+			element = strings.TrimSpace(element)
+			if err := checkConfigValue(t, element, -1); err != nil {
+				return errors.Errorf("invalid enum value %q: %w", element, err)
+			}
+			enum = append(enum, element)
+		}
+	}
+	value := tags["default"]
+	if value == "" || strings.Contains(value, "${") {
+		return nil
+	}
+	if err := checkConfigValue(t, value, sep); err != nil {
+		return errors.Errorf("invalid default %q: %w", value, err)
+	}
+	if enum == nil {
+		return nil
+	}
+	values := []string{value}
+	if _, ok := t.Underlying().(*types.Slice); ok && sep != -1 {
+		values = strings.Split(value, string(sep))
+	}
+	for _, value := range values {
+		if !slices.Contains(enum, value) {
+			return errors.Errorf("default %q must be one of %s", value, strings.Join(enum, ","))
+		}
+	}
+	return nil
+}
+
+// checkConfigValue checks that value can be decoded by Kong into a value of type t.
+//
+// Types that Kong decodes with custom mappers, such as those implementing encoding.TextUnmarshaler, are not checked.
+func checkConfigValue(t types.Type, value string, sep rune) error {
+	if hasCustomDecoder(t) {
+		return nil
+	}
+	if types.TypeString(t, nil) == "time.Duration" {
+		_, err := time.ParseDuration(value)
+		return errors.WithStack(err)
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Pointer:
+		return checkConfigValue(u.Elem(), value, sep)
+	case *types.Slice:
+		if sep == -1 || value == "" {
+			return checkConfigValue(u.Elem(), value, -1)
+		}
+		for element := range strings.SplitSeq(value, string(sep)) {
+			if err := checkConfigValue(u.Elem(), element, -1); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *types.Basic:
+		return checkBasicValue(u, value)
+	}
+	return nil
+}
+
+func checkBasicValue(t *types.Basic, value string) error {
+	var err error
+	switch t.Kind() {
+	case types.Bool:
+		switch value {
+		case "true", "1", "yes", "false", "0", "no":
+		default:
+			return errors.Errorf("expected one of true, false, 1, 0, yes or no")
+		}
+	case types.Int, types.Int64:
+		_, err = strconv.ParseInt(value, 0, 64)
+	case types.Int8:
+		_, err = strconv.ParseInt(value, 0, 8)
+	case types.Int16:
+		_, err = strconv.ParseInt(value, 0, 16)
+	case types.Int32:
+		_, err = strconv.ParseInt(value, 0, 32)
+	case types.Uint, types.Uint64, types.Uintptr:
+		_, err = strconv.ParseUint(value, 0, 64)
+	case types.Uint8:
+		_, err = strconv.ParseUint(value, 0, 8)
+	case types.Uint16:
+		_, err = strconv.ParseUint(value, 0, 16)
+	case types.Uint32:
+		_, err = strconv.ParseUint(value, 0, 32)
+	case types.Float32:
+		_, err = strconv.ParseFloat(value, 32)
+	case types.Float64:
+		_, err = strconv.ParseFloat(value, 64)
+	default:
+	}
+	if err != nil {
+		return errors.Errorf("expected %s", t.Name())
+	}
+	return nil
+}
+
+// hasCustomDecoder returns true if Kong decodes t with a method of t, rather than by its kind.
+func hasCustomDecoder(t types.Type) bool {
+	if _, ok := t.(*types.Pointer); !ok {
+		t = types.NewPointer(t)
+	}
+	methods := types.NewMethodSet(t)
+	for _, name := range []string{"Decode", "UnmarshalText", "UnmarshalBinary", "UnmarshalJSON"} {
+		if methods.Lookup(nil, name) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func tagSeparator(tags map[string]string, key string, dflt rune) (rune, error) {
+	value := tags[key]
+	switch {
+	case value == "none":
+		return -1, nil
+	case value == "":
+		return dflt, nil
+	case utf8.RuneCountInString(value) != 1:
+		return 0, errors.Errorf("%s %q must be a single character or none", key, value)
+	}
+	r, _ := utf8.DecodeRuneInString(value)
+	return r, nil
+}
+
+// similarKongTag returns the Kong tag key that key is likely a typo of, if any.
+func similarKongTag(key string) string {
+	if slices.Contains(kongTags, key) || slices.Contains(renameTags, key) || slices.Contains(otherTags, key) {
+		return ""
+	}
+	threshold := 2
+	if len(key) <= 4 {
+		threshold = 1
+	}
+	for _, tag := range kongTags {
+		if editDistance(strings.ToLower(key), tag) <= threshold {
+			return tag
+		}
+	}
+	return ""
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// parseStructTag parses a struct tag in the conventional key:"value" format into a map.
+func parseStructTag(tag string) (map[string]string, error) {
+	tags := map[string]string{}
+	for {
+		tag = strings.TrimLeft(tag, " ")
+		if tag == "" {
+			return tags, nil
+		}
+		colon := strings.Index(tag, `:"`)
+		if colon <= 0 || strings.ContainsAny(tag[:colon], " \"") {
+			return nil, errors.Errorf("malformed struct tag %q", tag)
+		}
+		key := tag[:colon]
+		tag = tag[colon+1:]
+		end := 1
+		for end < len(tag) && tag[end] != '"' {
+			if tag[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(tag) {
+			return nil, errors.Errorf("malformed struct tag value for %q", key)
+		}
+		value, err := strconv.Unquote(tag[:end+1])
+		if err != nil {
+			return nil, errors.Errorf("malformed struct tag value for %q: %w", key, err)
+		}
+		tags[key] = value
+		tag = tag[end+1:]
+	}
+}
@@ -0,0 +1,244 @@
+package depgraph
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestConfigTags(t *testing.T) {
+	t.Parallel()
+	analyseTestCode(t, `
+package test
+
+import "time"
+
+type Options struct {
+	Timeout time.Duration `+"`"+`help:"Timeout." default:"5s" json:"timeout" yaml:"timeout"`+"`"+`
+}
+
+//zero:config prefix="client-"
+type Config struct {
+	Options
+	Level   string   `+"`"+`enum:"debug,info" default:"info"`+"`"+`
+	Retries int      `+"`"+`default:"0x10"`+"`"+`
+	Enabled bool     `+"`"+`default:"true" negatable:""`+"`"+`
+	Hosts   []string `+"`"+`default:"a,b"`+"`"+`
+	Ports   []int    `+"`"+`default:"80;443" sep:";"`+"`"+`
+	Name    string   `+"`"+`default:"${name}"`+"`"+`
+}
+
+type Service struct{}
+
+//zero:provider
+func New(config Config) *Service { return &Service{} }
+`, WithRoots("*test.Service"))
+}
+
+func TestConfigRenames(t *testing.T) {
+	t.Parallel()
+	graph := analyseTestCode(t, `
+package test
+
+type Network struct {
+	Hosts []string `+"`"+`sep:";" rename:"host"`+"`"+`
+}
+
+//zero:config prefix="server-"
+type Config struct {
+	Network `+"`"+`embed:"" prefix:"net-"`+"`"+`
+	Bind string `+"`"+`rename:"listen,addr" renameenv:"SERVER_LISTEN"`+"`"+`
+	TLS  bool
+}
+
+type Service struct{}
+
+//zero:provider
+func New(config Config) *Service { return &Service{} }
+`, WithRoots("*test.Service"))
+	renames := graph.Configs["test.Config"].Renames
+	assert.Equal(t, 2, len(renames))
+	assert.Equal(t, []string{"Network", "Hosts"}, renames[0].Path)
+	assert.Equal(t, "--server-net-hosts", renames[0].Flag)
+	assert.Equal(t, []string{"--server-net-host"}, renames[0].Flags)
+	assert.Equal(t, `sep:";"`, renames[0].Tags)
+	assert.Equal(t, "[]string", renames[0].Type.String())
+	assert.Equal(t, []string{"Bind"}, renames[1].Path)
+	assert.Equal(t, "--server-bind", renames[1].Flag)
+	assert.Equal(t, []string{"--server-listen", "--server-addr"}, renames[1].Flags)
+	assert.Equal(t, []string{"SERVER_LISTEN"}, renames[1].Envs)
+}
+
+func TestConfigValidates(t *testing.T) {
+	t.Parallel()
+	graph := analyseTestCode(t, `
+package test
+
+//zero:config prefix="server-"
+type ServerConfig struct {
+	Bind string
+}
+
+func (s *ServerConfig) Validate() error { return nil }
+
+//zero:config prefix="db-"
+type DBConfig struct {
+	DSN string
+}
+
+// Validate does not have the signature Kong uses, so is not called.
+func (d DBConfig) Validate() bool { return true }
+
+type Service struct{}
+
+//zero:provider
+func New(server ServerConfig, db DBConfig) *Service { return &Service{} }
+`, WithRoots("*test.Service"))
+	assert.True(t, graph.Configs["test.ServerConfig"].Validates)
+	assert.False(t, graph.Configs["test.DBConfig"].Validates)
+}
+
+func TestConfigTagErrors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name   string
+		fields string
+		err    string
+	}{
+		{"Typo", "Addr string `defualt:\"localhost\"`", `main.go:9:2: Addr: unknown tag "defualt", did you mean "default"?`},
+		{"InvalidInt", "Port int `default:\"http\"`", `main.go:9:2: Port: invalid default "http": expected int`},
+		{"InvalidDuration", "Timeout time.Duration `default:\"5\"`", `main.go:9:2: Timeout: invalid default "5": time: missing unit in duration "5"`},
+		{"InvalidBool", "Enabled bool `default:\"on\"`", `main.go:9:2: Enabled: invalid default "on": expected one of true, false, 1, 0, yes or no`},
+		{"InvalidSliceElement", "Ports []uint8 `default:\"80,443\"`", `main.go:9:2: Ports: invalid default "80,443": expected uint8`},
+		{"DefaultNotInEnum", "Level string `enum:\"debug,info\" default:\"warn\"`", `main.go:9:2: Level: default "warn" must be one of debug,info`},
+		{"InvalidSep", "Hosts []string `sep:\"::\"`", `main.go:9:2: Hosts: sep "::" must be a single character or none`},
+		{"NegatableNotBool", "Name string `negatable:\"\"`", `main.go:9:2: Name: negatable can only be set on booleans`},
+		{"DuplicateFlag", "Addr string\n\tAddress string `name:\"addr\"`", `main.go:10:2: flag --addr of test.Config conflicts with --addr of test.Config at`},
+		{"RenameEnvWithoutRename", "Addr string `renameenv:\"LISTEN\"`", `main.go:9:2: Addr: renameenv requires rename`},
+		{"RenameWithoutName", "Addr string `rename:\"\"`", `main.go:9:2: Addr: rename requires the former name of the flag`},
+		{"RenamedToExistingFlag", "Addr string\n\tBind string `rename:\"addr\"`", `main.go:10:2: flag --addr of test.Config conflicts with --addr of test.Config at`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := analyseTestCodeWithError(t, `
+package test
+
+import "time"
+
+//zero:config
+type Config struct {
+	_ time.Duration
+	`+test.fields+`
+}
+
+type Service struct{}
+
+//zero:provider
+func New(config Config) *Service { return &Service{} }
+`, WithRoots("*test.Service"))
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), test.err)
+		})
+	}
+}
+
+func TestConfigTagDuplicatePrefixedFlags(t *testing.T) {
+	t.Parallel()
+	_, err := analyseTestCodeWithError(t, `
+package test
+
+//zero:config prefix="db-"
+type DBConfig struct {
+	URL string
+}
+
+//zero:config prefix="db-"
+type CacheConfig struct {
+	URL string
+}
+
+type Service struct{}
+
+//zero:provider
+func New(db DBConfig, cache CacheConfig) *Service { return &Service{} }
+`, WithRoots("*test.Service"))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `main.go:6:2: flag --db-url of test.DBConfig conflicts with --db-url of test.CacheConfig at`)
+}
+
+func TestConfigFlagCollisions(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{"Unprefixed", "type OtherConfig struct {\n\tServerBind string\n}",
+			`main.go:12:2: flag --server-bind of test.OtherConfig conflicts with --server-bind of test.Config at`},
+		{"Embedded", "type OtherConfig struct {\n\tServer `embed:\"\" prefix:\"server-\"`\n}\n\ntype Server struct {\n\tBind string\n}",
+			`main.go:16:2: flag --server-bind of test.OtherConfig conflicts with --server-bind of test.Config at`},
+		{"Alias", "type OtherConfig struct {\n\tListen string `aliases:\"server-bind\"`\n}",
+			`main.go:12:2: flag --server-bind of test.OtherConfig conflicts with --server-bind of test.Config at`},
+		{"Short", "type OtherConfig struct {\n\tVerbose bool `short:\"b\"`\n}",
+			`main.go:12:2: flag -b of test.OtherConfig conflicts with -b of test.Config at`},
+		{"Negation", "type OtherConfig struct {\n\tNoServerTLS bool\n}",
+			`main.go:12:2: flag --no-server-tls of test.OtherConfig conflicts with --no-server-tls of test.Config at`},
+		{"Renamed", "type OtherConfig struct {\n\tAddr string `rename:\"server-bind\"`\n}",
+			`main.go:12:2: flag --server-bind of test.OtherConfig conflicts with --server-bind of test.Config at`},
+		{"Builtin", "type OtherConfig struct {\n\tHelp bool\n}",
+			`main.go:12:2: flag --help of test.OtherConfig conflicts with the builtin --help flag`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := analyseTestCodeWithError(t, `
+package test
+
+//zero:config prefix="server-"
+type Config struct {
+	Bind string `+"`"+`short:"b"`+"`"+`
+	TLS  bool   `+"`"+`negatable:""`+"`"+`
+}
+
+//zero:config
+`+test.config+`
+
+type Service struct{}
+
+//zero:provider
+func New(config Config, other OtherConfig) *Service { return &Service{} }
+`, WithRoots("*test.Service"))
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), test.err)
+		})
+	}
+}
+
+func TestConfigFlagCollisionsGeneric(t *testing.T) {
+	t.Parallel()
+	_, err := analyseTestCodeWithError(t, `
+package test
+
+type User struct{}
+
+//zero:config prefix="store-${type}-"
+type StoreConfig[T any] struct {
+	Path string
+}
+
+//zero:config prefix="store-user-"
+type UserStoreConfig struct {
+	Path string
+}
+
+type Service struct{}
+
+//zero:provider
+func New(store StoreConfig[User], users UserStoreConfig) *Service { return &Service{} }
+`, WithRoots("*test.Service"))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `flag --store-user-path of test.UserStoreConfig conflicts with --store-user-path of test.StoreConfig[test.User] at`)
+	assert.Contains(t, err.Error(), `main.go:8:2`)
+	assert.Contains(t, err.Error(), `main.go:13:2`)
+}
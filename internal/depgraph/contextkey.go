@@ -0,0 +1,82 @@
+package depgraph
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"slices"
+
+	"github.com/alecthomas/errors"
+	"github.com/alecthomas/zero/internal/directiveparser"
+	"golang.org/x/tools/go/packages"
+)
+
+// ContextKey is a type whose values are set in the request context by middleware, and injected into API methods as
+// parameters. Context keys are annotated like so:
+//
+//	//zero:contextkey <label>
+//
+// Only APIs with the label may receive the value, and middleware with the label must be in the graph to set it.
+type ContextKey struct {
+	// Position is the position of the type declaration.
+	Position token.Position
+	// Type is the type of the context value.
+	Type types.Type
+	// Label is the label of the APIs receiving the value, and of the middleware setting it.
+	Label string
+}
+
+// collectContextKeys finds all //zero:contextkey types, which must be known before APIs are analysed.
+//
+// Directives named in custom are handled by plugins, and are skipped.
+func collectContextKeys(pkgs []*packages.Package, graph *Graph, fset *token.FileSet, custom ...string) error {
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Syntax {
+			for _, decl := range file.Decls {
+				decl, ok := decl.(*ast.GenDecl)
+				if !ok || decl.Tok != token.TYPE {
+					continue
+				}
+				directive, err := parseDirective(decl.Doc, custom...)
+				if err != nil {
+					return errors.Errorf("%s: %s", fset.Position(decl.Pos()), err)
+				}
+				contextKey, ok := directive.(*directiveparser.DirectiveContextKey)
+				if !ok {
+					continue
+				}
+				for _, spec := range decl.Specs {
+					typeSpec, ok := spec.(*ast.TypeSpec)
+					if !ok {
+						continue
+					}
+					keyType := pkg.TypesInfo.TypeOf(typeSpec.Name)
+					if keyType == nil {
+						continue
+					}
+					graph.ContextKeys[types.TypeString(keyType, nil)] = &ContextKey{
+						Position: fset.Position(typeSpec.Pos()),
+						Type:     keyType,
+						Label:    contextKey.Label,
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// checkContextKeys returns an error if an API receives a context value that no middleware in the graph sets.
+func checkContextKeys(graph *Graph) error {
+	for _, api := range graph.APIs {
+		for _, key := range api.ContextKeys {
+			if !slices.ContainsFunc(graph.Middleware, func(m *Middleware) bool {
+				return slices.Contains(m.Directive.Labels, key.Label) && m.Match(api)
+			}) {
+				return errors.Errorf("%s: %s() receives %s from the request context, but no %s middleware is applied to set it",
+					api.Position, api.Function.FullName(), types.TypeString(key.Type, nil), key.Label)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package depgraph
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestContextKeys(t *testing.T) {
+	t.Parallel()
+	const header = `
+package main
+
+import "net/http"
+
+//zero:contextkey authenticated
+type Subject string
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service { return &Service{} }
+`
+	const middleware = `
+//zero:middleware authenticated
+func Authenticate(next http.Handler) http.Handler { return next }
+`
+	tests := []struct {
+		name string
+		code string
+		err  string
+	}{
+		{"Valid", middleware + `
+//zero:api GET /users/{id} authenticated
+func (s *Service) GetUser(id string, subject Subject) {}
+`, ""},
+		{"MissingLabel", middleware + `
+//zero:api GET /users/{id}
+func (s *Service) GetUser(id string, subject Subject) {}
+`, "API method GetUser parameter subject of type test.Subject requires the authenticated label"},
+		{"MissingMiddleware", `
+//zero:api GET /users/{id} authenticated
+func (s *Service) GetUser(id string, subject Subject) {}
+`, "(*test.Service).GetUser() receives test.Subject from the request context, but no authenticated middleware is applied to set it"},
+		{"SkippedMiddleware", middleware + `
+//zero:api GET /users/{id} authenticated skip=Authenticate
+func (s *Service) GetUser(id string, subject Subject) {}
+`, "no authenticated middleware is applied to set it"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			graph, err := analyseTestCodeWithError(t, header+test.code)
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), test.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "authenticated", graph.ContextKeys["test.Subject"].Label)
+			assert.Equal(t, "authenticated", graph.APIs[0].ContextKeys[1].Label)
+			// Context values are not request parameters.
+			operation := graph.APIs[0].GenerateOpenAPIOperation(nil)
+			assert.Equal(t, 1, len(operation.Parameters))
+			assert.Equal(t, "id", operation.Parameters[0].Name)
+		})
+	}
+}
@@ -2,23 +2,29 @@
 package depgraph
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 	"go/ast"
+	"go/constant"
 	"go/token"
 	"go/types"
 	"hash/fnv"
 	"log"
 	"maps"
+	"net/http"
 	"os"
-	"os/exec"
 	"path"
 	"path/filepath"
 	"reflect"
 	"slices"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
 
 	"github.com/alecthomas/errors"
+	"github.com/alecthomas/zero"
 	"github.com/alecthomas/zero/internal/directiveparser"
 	"github.com/alecthomas/zero/internal/strcase"
 	"github.com/go-openapi/spec"
@@ -31,6 +37,17 @@ type Ref struct {
 	Pkg    string // database/sql
 	Import string // "database/sql" or impe1d11ad6baa4124f "database/sql"
 	Ref    string // *sql.DB or *impe1d11ad6baa4124f.DB
+	// Imports of packages referenced by type arguments or element types, eg. "log/slog" for iter.Seq[*slog.Logger],
+	// separated by newlines so that Ref remains comparable.
+	argImports string
+}
+
+// Imports returns the imports required by the reference, including those of its type arguments.
+func (r Ref) Imports() []string {
+	if r.argImports == "" {
+		return []string{r.Import}
+	}
+	return append([]string{r.Import}, strings.Split(r.argImports, "\n")...)
 }
 
 // String is the fully-qualified type reference, eg. *database/sql.DB
@@ -51,18 +68,35 @@ type Provider struct {
 	// Position is the position of the function declaration.
 	Position  token.Position
 	Directive *directiveparser.DirectiveProvider
-	// Function is the function that provides the type.
+	// Function is the function that provides the type, nil for value providers.
 	Function *types.Func
+	// Value is the package-level variable or constant that provides the type, nil for function providers.
+	Value types.Object
 	// Package is the package that contains the function.
-	Package  *packages.Package
-	Provides types.Type
+	Package *packages.Package
+	// Module is the name of the module the provider belongs to, declared with //zero:module on the package clause.
+	Module string
+	// Documentation is the doc comment of the provider, excluding directives.
+	Documentation string
+	Provides      types.Type
+	// Requires are the types injected into the provider. For method providers the first element is the receiver.
 	Requires []types.Type
+	// Receiver is the receiver type of a method provider, nil for functions.
+	Receiver types.Type
 	// IsGeneric indicates if this provider is a generic function
 	IsGeneric bool
 	// TypeParams holds the type parameters for generic providers
 	TypeParams *types.TypeParamList
 }
 
+// FullName returns the fully-qualified name of the provider function or value, eg. "github.com/alecthomas/zero/providers/sql.New"
+func (p *Provider) FullName() string {
+	if p.Value != nil {
+		return p.Value.Pkg().Path() + "." + p.Value.Name()
+	}
+	return p.Function.FullName()
+}
+
 // API represents a method that is an exposed API endpoint. API endpoints are annotated like so:
 //
 //	//zero:api [<method>] [<host>]/[<path>] [<option>[=<value>] ...]
@@ -79,6 +113,29 @@ type API struct {
 	Package *packages.Package
 	// OpenAPI is the OpenAPI operation spec for this endpoint
 	OpenAPI *spec.Operation
+	// ContextKeys are the parameters injected from the request context, by parameter index
+	ContextKeys map[int]*ContextKey
+	// Headers are the parameters bound to request headers and cookies, by parameter index
+	Headers map[int]*HeaderParameter
+	// Params are the fields of the params struct parameter bound to path wildcards and query parameters, by parameter
+	// index
+	Params map[int][]*ParamsField
+	// Group is the API group of the receiver, whose prefix has been applied to Pattern, or nil.
+	Group *APIGroup
+	// Statuses are the success status codes returned by an API returning (status, response, error), where they are
+	// constant. A non-constant or zero status is recorded as 200.
+	Statuses []int
+	// Methods are the HTTP methods the API is registered for. This is the method of the pattern if it has one,
+	// otherwise the methods of [anyMethods] not registered by another API with the same host and path.
+	Methods []string
+	// TrailingSlash is the trailing slash policy of the API, from its slash=<policy> label or [WithTrailingSlash], or
+	// empty for the behaviour of http.ServeMux.
+	TrailingSlash string
+	// RedactedFields are the JSON names of the fields of the request and response types tagged `log:"-"`, passed to
+	// middleware accepting a zero.RedactedFields parameter.
+	RedactedFields []string
+
+	alternatePatterns []string
 }
 
 func (a *API) Label(name string) string {
@@ -92,29 +149,96 @@ func (a *API) Label(name string) string {
 
 // GenerateOpenAPIOperation creates an OpenAPI operation spec for this API endpoint
 func (a *API) GenerateOpenAPIOperation(definitions spec.Definitions) *spec.Operation {
+	summary, description := splitDocumentation(a.Documentation)
 	operation := &spec.Operation{
 		OperationProps: spec.OperationProps{
-			Description: a.extractDocumentation(),
+			Summary:     summary,
+			Description: description,
 			Parameters:  a.generateParameters(definitions),
 			Responses:   a.generateResponses(definitions),
 			Tags:        []string{a.extractTag()},
+			Consumes:    a.consumes(),
 		},
 	}
+	if _, ok := a.Pattern.Label("consumes"); ok {
+		operation.Responses.StatusCodeResponses[415] = spec.Response{
+			ResponseProps: spec.ResponseProps{
+				Description: "Unsupported Media Type",
+			},
+		}
+	}
 	return operation
 }
 
-func (a *API) extractDocumentation() string {
-	if a.Documentation != "" {
-		return a.Documentation
+// consumes returns the media types of the API's request body, from its consumes=<media type>[,...] label, defaulting
+// to application/octet-stream for request bodies streamed into an io.Reader.
+func (a *API) consumes() []string {
+	// Labels are validated during analysis.
+	if mediaTypes, _ := a.Pattern.Consumes(); mediaTypes != nil {
+		return mediaTypes
 	}
-	return ""
+	params := a.Function.Signature().Params()
+	for i := range params.Len() {
+		if isReaderType(params.At(i).Type()) {
+			return []string{"application/octet-stream"}
+		}
+	}
+	return nil
+}
+
+// OperationID returns the OpenAPI operation ID of the API, which is the name of its receiver type followed by the
+// name of its method, eg. "UserServiceGetUser".
+func (a *API) OperationID() string {
+	recv := a.Function.Signature().Recv()
+	if recv == nil {
+		return a.Function.Name()
+	}
+	receiver := recv.Type()
+	if ptr, ok := receiver.(*types.Pointer); ok {
+		receiver = ptr.Elem()
+	}
+	name := types.TypeString(receiver, func(*types.Package) string { return "" })
+	if named, ok := receiver.(*types.Named); ok {
+		name = named.Obj().Name()
+	}
+	return name + a.Function.Name()
+}
+
+// upperFirst returns s with its first letter in upper case.
+func upperFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// splitDocumentation splits a doc comment into its first sentence, which is used as the summary of an OpenAPI
+// operation, and the remainder, which is used as its description.
+func splitDocumentation(doc string) (summary, description string) {
+	paragraph, rest, _ := strings.Cut(doc, "\n\n")
+	end := len(paragraph)
+	for i := range len(paragraph) - 1 {
+		if paragraph[i] == '.' && (paragraph[i+1] == ' ' || paragraph[i+1] == '\n') {
+			end = i + 1
+			break
+		}
+	}
+	summary = strings.Join(strings.Fields(paragraph[:end]), " ")
+	description = strings.TrimSpace(paragraph[end:])
+	if rest != "" {
+		description = strings.TrimSpace(description + "\n\n" + rest)
+	}
+	return summary, description
 }
 
 func (a *API) extractTag() string {
-	// Extract tag from package name or directive labels
+	// Extract tag from directive labels, API group or package name
 	if tag := a.Label("tag"); tag != "" {
 		return tag
 	}
+	if a.Group != nil && a.Group.Tag != "" {
+		return a.Group.Tag
+	}
 	return a.Package.Name
 }
 
@@ -123,13 +247,60 @@ func (a *API) generateParameters(definitions spec.Definitions) []spec.Parameter
 	signature := a.Function.Signature()
 	params := signature.Params()
 
+	var cookies []string
+	var cookiesRequired bool
 	for i := range params.Len() {
 		param := params.At(i)
 		paramType := param.Type()
 		paramName := param.Name()
 
-		// Skip context parameters
-		if isContextType(paramType) {
+		// Skip context parameters, and values from the request context
+		if isContextType(paramType) || a.ContextKeys[i] != nil {
+			continue
+		}
+
+		if fields := a.Params[i]; fields != nil {
+			for _, field := range fields {
+				parameters = append(parameters, paramsFieldSpec(field))
+			}
+			continue
+		}
+
+		if header := a.Headers[i]; header != nil {
+			parameterType, required := headerParameterType(paramType)
+			if header.In == "cookie" {
+				cookies = append(cookies, header.Name)
+				cookiesRequired = cookiesRequired || required
+				continue
+			}
+			parameters = append(parameters, spec.Parameter{
+				ParamProps: spec.ParamProps{
+					Name:     header.Name,
+					In:       "header",
+					Required: required,
+				},
+				SimpleSchema: spec.SimpleSchema{
+					Type: parameterType,
+				},
+			})
+			continue
+		}
+
+		// The request body is streamed into io.Reader parameters as is
+		if isReaderType(paramType) {
+			parameters = append(parameters, spec.Parameter{
+				ParamProps: spec.ParamProps{
+					Name:     "body",
+					In:       "body",
+					Required: true,
+					Schema: &spec.Schema{
+						SchemaProps: spec.SchemaProps{
+							Type:   spec.StringOrArray{"string"},
+							Format: "binary",
+						},
+					},
+				},
+			})
 			continue
 		}
 
@@ -138,7 +309,9 @@ func (a *API) generateParameters(definitions spec.Definitions) []spec.Parameter
 			continue // Skip standard HTTP types
 		}
 
-		if isBodyParameterStruct(paramType) {
+		if isPageRequestType(paramType) {
+			parameters = append(parameters, pageRequestParameters()...)
+		} else if isBodyParameterStruct(paramType) {
 			// Body parameter
 			schema := a.generateSchemaFromType(paramType, definitions)
 			parameters = append(parameters, spec.Parameter{
@@ -175,6 +348,35 @@ func (a *API) generateParameters(definitions spec.Definitions) []spec.Parameter
 		}
 	}
 
+	if len(cookies) > 0 {
+		parameters = append(parameters, cookieParameterSpec(cookies, cookiesRequired))
+	}
+
+	if _, ok := a.Pattern.Label("etag"); ok {
+		parameters = append(parameters, spec.Parameter{
+			ParamProps: spec.ParamProps{
+				Name:        "If-None-Match",
+				In:          "header",
+				Description: "Respond with 304 Not Modified if the response matches one of these ETags.",
+			},
+			SimpleSchema: spec.SimpleSchema{
+				Type: "string",
+			},
+		})
+	}
+	if _, ok := a.Pattern.Label("idempotent"); ok {
+		parameters = append(parameters, spec.Parameter{
+			ParamProps: spec.ParamProps{
+				Name:        "Idempotency-Key",
+				In:          "header",
+				Description: "Responses to requests with a previously used key are replayed.",
+			},
+			SimpleSchema: spec.SimpleSchema{
+				Type: "string",
+			},
+		})
+	}
+
 	return parameters
 }
 
@@ -202,17 +404,35 @@ func (a *API) generateResponses(definitions spec.Definitions) *spec.Responses {
 				Description: "No Content",
 			},
 		}
-	} else if results.Len() >= 1 {
-		firstResult := results.At(0)
-		if !isErrorType(firstResult.Type()) {
-			// Has a return value - 200 OK
-			schema := a.generateSchemaFromType(firstResult.Type(), definitions)
-			responses.StatusCodeResponses[200] = spec.Response{
+	} else if response := responseType(signature); response != nil {
+		// Has a return value - 200 OK, unless the API returns a status
+		schema := a.generateSchemaFromType(response, definitions)
+		statuses := a.Statuses
+		if len(statuses) == 0 {
+			statuses = []int{http.StatusOK}
+		}
+		for _, status := range statuses {
+			description := "Success"
+			if status != http.StatusOK {
+				description = http.StatusText(status)
+			}
+			operationResponse := spec.Response{
 				ResponseProps: spec.ResponseProps{
-					Description: "Success",
-					Schema:      schema,
+					Description: description,
 				},
 			}
+			if status != http.StatusNoContent {
+				operationResponse.Schema = schema
+			}
+			if isPageType(response) {
+				operationResponse.Headers = map[string]spec.Header{
+					"Link": {
+						HeaderProps:  spec.HeaderProps{Description: `Link to the next page of results with rel="next", if any.`},
+						SimpleSchema: spec.SimpleSchema{Type: "string"},
+					},
+				}
+			}
+			responses.StatusCodeResponses[status] = operationResponse
 		}
 	}
 
@@ -227,6 +447,33 @@ func (a *API) generateResponses(definitions spec.Definitions) *spec.Responses {
 			Description: "Internal Server Error",
 		},
 	}
+	if _, ok := a.Pattern.Label("etag"); ok {
+		if response, ok := responses.StatusCodeResponses[200]; ok {
+			response.Headers = map[string]spec.Header{
+				"ETag": {SimpleSchema: spec.SimpleSchema{Type: "string"}},
+			}
+			responses.StatusCodeResponses[200] = response
+		}
+		responses.StatusCodeResponses[304] = spec.Response{
+			ResponseProps: spec.ResponseProps{
+				Description: "Not Modified",
+			},
+		}
+	}
+	if _, ok := a.Pattern.Label("idempotent"); ok {
+		responses.StatusCodeResponses[409] = spec.Response{
+			ResponseProps: spec.ResponseProps{
+				Description: "Conflict",
+			},
+		}
+	}
+	if _, ok := a.Pattern.Label("flag"); ok {
+		responses.StatusCodeResponses[404] = spec.Response{
+			ResponseProps: spec.ResponseProps{
+				Description: "Not Found",
+			},
+		}
+	}
 
 	return responses
 }
@@ -272,8 +519,13 @@ func (a *API) generateSchemaFromType(t types.Type, definitions spec.Definitions)
 			if field.Exported() {
 				fieldName := getJSONFieldName(field, typ.Tag(i))
 				if fieldName != "" {
+					tag := reflect.StructTag(typ.Tag(i))
 					fieldSchema := a.generateSchemaFromType(field.Type(), definitions)
+					a.applyFieldMetadata(fieldSchema, field, tag)
 					schema.Properties[fieldName] = *fieldSchema
+					if required, ok := tag.Lookup("required"); ok && required != "false" {
+						schema.Required = append(schema.Required, fieldName)
+					}
 				}
 			}
 		}
@@ -293,6 +545,16 @@ func (a *API) generateSchemaFromType(t types.Type, definitions spec.Definitions)
 		} else {
 			defName = typeName
 		}
+		// Instantiated generic types each need their own definition, eg. "zero.Page-main.User".
+		for arg := range typ.TypeArgs().Types() {
+			argName := types.TypeString(arg, func(p *types.Package) string { return p.Name() })
+			defName += "-" + strings.Map(func(r rune) rune {
+				if r == '.' || r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+					return r
+				}
+				return '_'
+			}, argName)
+		}
 
 		// Add to definitions if not already present
 		if _, exists := definitions[defName]; !exists {
@@ -311,6 +573,105 @@ func (a *API) generateSchemaFromType(t types.Type, definitions spec.Definitions)
 	return schema
 }
 
+// applyFieldMetadata adds the documentation, example, enum values and format of a struct field to its schema.
+//
+// Documentation is taken from the `doc:"..."` tag, or the field's comments. Enum values are comma separated, eg.
+// `enum:"red,green,blue"`, and apply to the elements of slices.
+func (a *API) applyFieldMetadata(schema *spec.Schema, field *types.Var, tag reflect.StructTag) {
+	if doc, ok := tag.Lookup("doc"); ok {
+		schema.Description = doc
+	} else {
+		schema.Description = a.fieldDocumentation(field)
+	}
+	if format, ok := tag.Lookup("format"); ok {
+		schema.Format = format
+	}
+	if example, ok := tag.Lookup("example"); ok {
+		schema.Example = parseSchemaValue(schema, example)
+	}
+	if enum, ok := tag.Lookup("enum"); ok {
+		target := schema
+		if schema.Type.Contains("array") && schema.Items != nil && schema.Items.Schema != nil {
+			target = schema.Items.Schema
+		}
+		for value := range strings.SplitSeq(enum, ",") {
+			target.Enum = append(target.Enum, parseSchemaValue(target, strings.TrimSpace(value)))
+		}
+	}
+}
+
+// parseSchemaValue converts a value from a struct tag to the type of schema, falling back to the raw string.
+func parseSchemaValue(schema *spec.Schema, value string) any {
+	switch {
+	case schema.Type.Contains("integer"):
+		if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return v
+		}
+	case schema.Type.Contains("number"):
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			return v
+		}
+	case schema.Type.Contains("boolean"):
+		if v, err := strconv.ParseBool(value); err == nil {
+			return v
+		}
+	}
+	return value
+}
+
+// fieldDocumentation returns the doc comment, or failing that the line comment, of a struct field.
+//
+// Comments are only available for fields declared in packages loaded with syntax, ie. not in dependencies.
+func (a *API) fieldDocumentation(field *types.Var) string {
+	pkg := findSyntaxPackage(a.Package, field.Pkg(), map[*packages.Package]bool{})
+	if pkg == nil {
+		return ""
+	}
+	for _, file := range pkg.Syntax {
+		if field.Pos() < file.FileStart || field.Pos() >= file.FileEnd {
+			continue
+		}
+		var comments *ast.CommentGroup
+		ast.Inspect(file, func(node ast.Node) bool {
+			decl, ok := node.(*ast.Field)
+			if !ok {
+				return comments == nil
+			}
+			for _, name := range decl.Names {
+				if name.Pos() == field.Pos() {
+					comments = decl.Doc
+					if comments == nil {
+						comments = decl.Comment
+					}
+				}
+			}
+			return true
+		})
+		if comments != nil {
+			return strings.TrimSpace(comments.Text())
+		}
+		return ""
+	}
+	return ""
+}
+
+// findSyntaxPackage finds the package for target with syntax, searching pkg and its transitive imports.
+func findSyntaxPackage(pkg *packages.Package, target *types.Package, seen map[*packages.Package]bool) *packages.Package {
+	if pkg == nil || target == nil || seen[pkg] {
+		return nil
+	}
+	seen[pkg] = true
+	if pkg.Types == target && len(pkg.Syntax) > 0 {
+		return pkg
+	}
+	for _, imp := range pkg.Imports {
+		if found := findSyntaxPackage(imp, target, seen); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
 // getJSONFieldName returns the JSON field name from the struct tag if present,
 // otherwise returns the field name with the first letter lowercased.
 func getJSONFieldName(field *types.Var, tag string) string {
@@ -348,6 +709,9 @@ type CronJob struct {
 	Function *types.Func
 	// Package is the package that contains the function
 	Package *packages.Package
+	// Params is the injected slice type []T of a parameterised cron job with the signature
+	// func(context.Context, T) error, which is run once per element on each tick, or nil.
+	Params types.Type
 }
 
 type Subscription struct {
@@ -359,6 +723,81 @@ type Subscription struct {
 	Package *packages.Package
 	// TopicType is the event type extracted from pubsub.Event[T]
 	TopicType types.Type
+	// Version is the payload schema version from //zero:subscribe version=N, or nil to receive all events.
+	//
+	// Versioned subscriptions to a topic are routed by a single pubsub.VersionRouter.
+	Version *int
+	// Concurrency is the maximum number of events processed at once from //zero:subscribe concurrency=N, or nil for
+	// the topic's default.
+	Concurrency *int
+	// Prefetch is the number of events claimed ahead of the workers from //zero:subscribe prefetch=N, or nil for the
+	// topic's default.
+	Prefetch *int
+	// Batch is the maximum number of events delivered at once from //zero:subscribe batch=N, for subscribers receiving
+	// []pubsub.Event[T], or nil for subscribers receiving a single event.
+	Batch *int
+	// Window is the maximum time to wait for a batch to fill from //zero:subscribe window=D, or 0 for the default.
+	Window time.Duration
+	// Dedupe is the window within which duplicate deliveries of an event are suppressed from //zero:subscribe
+	// dedupe=D, or 0 to deliver duplicates.
+	Dedupe time.Duration
+	// Labels select the job middleware applied to the subscriber.
+	Labels []string
+}
+
+// Step represents a step of a durable workflow in the graph. The steps of a receiver type form a workflow, run in
+// source order.
+//
+//	//zero:step [compensate=<method>]
+type Step struct {
+	// Position is the position of the function declaration.
+	Position token.Position
+	// Function is the method running the step
+	Function *types.Func
+	// Package is the package that contains the function
+	Package *packages.Package
+	// StateType is the workflow state type S from the *S parameter, shared by all steps of a workflow.
+	StateType types.Type
+	// Compensate is the method undoing the step if a later step fails, or nil if the step has no compensation.
+	Compensate *types.Func
+}
+
+// Worker represents a method processing the jobs of a queue in the graph.
+//
+//	//zero:worker [concurrency=N] [attempts=N] [backoff=D] [visibility=D] [labels...]
+type Worker struct {
+	// Position is the position of the function declaration.
+	Position token.Position
+	// Function is the method processing jobs
+	Function *types.Func
+	// Package is the package that contains the function
+	Package *packages.Package
+	// PayloadType is the job payload type extracted from queue.Job[T]
+	PayloadType types.Type
+	// QueueType is the *queue.Queue[T] the worker processes jobs from.
+	QueueType types.Type
+	// Concurrency is the maximum number of jobs processed at once from //zero:worker concurrency=N, or nil for the
+	// default.
+	Concurrency *int
+	// Attempts is the number of times a job is attempted from //zero:worker attempts=N, or nil for the default.
+	Attempts *int
+	// Backoff is the delay before the first retry from //zero:worker backoff=D, or 0 for the default.
+	Backoff time.Duration
+	// Visibility is the visibility timeout of claimed jobs from //zero:worker visibility=D, or 0 for the default.
+	Visibility time.Duration
+	// Labels select the job middleware applied to the worker.
+	Labels []string
+}
+
+// Group is a slice of an interface type collected from every provider annotated like so:
+//
+//	//zero:provider group=<iface>
+type Group struct {
+	// Type is the slice type injected, eg. []HealthCheck
+	Type      types.Type
+	Interface types.Type
+	// Members are ordered by their fully-qualified provider name.
+	Members []*Provider
 }
 
 // Config represents command-line/file configuration. Config structs are annotated like so:
@@ -373,11 +812,36 @@ type Config struct {
 	IsGeneric bool
 	// TypeParams holds the type parameters for generic configs
 	TypeParams *types.TypeParamList
+	// Renames are the fields of the config that have been renamed with a rename:"<old-name>" tag.
+	Renames []ConfigRename
+	// Validates is true if the config has a Validate() error method, called before anything is constructed.
+	Validates bool
 }
 
-// Middleware represents a function that is an HTTP middleware. Middleware functions are annotated like so:
+// ConfigRename is a config field renamed with a rename:"<old-name>" tag, whose former flags, and the former environment
+// variables listed in a renameenv:"<OLD_NAME>" tag, are still accepted with a deprecation warning.
+type ConfigRename struct {
+	// Position of the field.
+	Position token.Position
+	// Path of field names from the config to the renamed field, eg. ["RetryConfig", "Min"].
+	Path []string
+	Type types.Type
+	// Flag is the current flag of the field, eg. "--http-bind".
+	Flag string
+	// Flags are the former flags of the field, prefixed like the current flag, eg. "--http-listen".
+	Flags []string
+	// Envs are the former environment variables of the field.
+	Envs []string
+	// Tags are the Kong tags of the field that control how its value is decoded, eg. sep:";".
+	Tags string
+}
+
+// Middleware represents a function that is an HTTP or job middleware. Middleware functions are annotated like so:
 //
 //	//zero:middleware [<label>]
+//
+// Job middleware wraps zero.JobFunc rather than http.Handler, and is applied to cron jobs and subscribers rather than
+// APIs.
 type Middleware struct {
 	// Position is the position of the function declaration.
 	Position token.Position
@@ -391,11 +855,24 @@ type Middleware struct {
 	Requires []types.Type
 	// Factory represents whether the middleware is a factory, or direct middleware function
 	Factory bool
+	// Job represents whether the middleware wraps cron jobs and subscribers, rather than APIs
+	Job bool
 }
 
+// Match returns true if the middleware applies to the API.
+//
+// Global middleware applies to all APIs, except those labelled with no-middleware. Any middleware may be excluded from
+// an API by name with skip=<name>[,<name>...].
 func (m *Middleware) Match(api *API) bool {
+	if m.Job {
+		return false
+	}
+	if slices.ContainsFunc(api.Pattern.SkipMiddleware(), m.Is) {
+		return false
+	}
 	if len(m.Directive.Labels) == 0 {
-		return true
+		_, excluded := api.Pattern.Label("no-middleware")
+		return !excluded
 	}
 	for _, label := range m.Directive.Labels {
 		for _, apiLabel := range api.Pattern.Labels {
@@ -407,15 +884,53 @@ func (m *Middleware) Match(api *API) bool {
 	return false
 }
 
+// MatchJob returns true if the middleware is job middleware that applies to a cron job or subscriber with the given
+// labels.
+func (m *Middleware) MatchJob(labels []string) bool {
+	if !m.Job {
+		return false
+	}
+	if len(m.Directive.Labels) == 0 {
+		return true
+	}
+	for _, label := range m.Directive.Labels {
+		if slices.Contains(labels, label) {
+			return true
+		}
+	}
+	return false
+}
+
+// Is returns true if name refers to the middleware function, either by its name or fully-qualified name.
+func (m *Middleware) Is(name string) bool {
+	return name == m.Function.Name() || name == m.Function.FullName()
+}
+
 type graphOptions struct {
 	// Roots of the graph, defaulting to service endpoint receivers if nil.
 	roots []string
 	// Providers to pick to resolve duplicate providers.
 	pick []string
 	// Additional package patterns to search for annotations.
-	patterns   []string
+	patterns []string
+	// Modules whose providers will be selected to resolve duplicate providers.
+	modules []string
+	// Profiles enabling providers annotated with profile=<name>.
+	profiles []string
+	// Plugins handling custom directives, keyed by directive.
+	plugins    map[string]Plugin
 	debug      bool
 	buildFlags []string
+	// Analyse the _test.go files of the destination package for test providers.
+	tests bool
+	// Trailing slash policy of APIs without a slash=<policy> label.
+	trailingSlash string
+	// Allow "go mod tidy" to be run if the destination module's requirements are out of date.
+	fixMod bool
+	// Analyse the destination as a library, rooted at its own providers.
+	library bool
+	// Fail instead of selecting weak providers or roots implicitly.
+	strict bool
 }
 
 type Option func(*graphOptions) error
@@ -444,6 +959,46 @@ func WithPatterns(patterns ...string) Option {
 	}
 }
 
+// WithModules selects all providers in the given modules, as if each had been passed to [WithProviders].
+//
+// Modules are declared by annotating a package clause with //zero:module <name>.
+func WithModules(modules ...string) Option {
+	return func(o *graphOptions) error {
+		o.modules = modules
+		return nil
+	}
+}
+
+// WithProfiles enables providers annotated with //zero:provider profile=<name> for any of the given profiles.
+//
+// Providers without a profile are always enabled.
+func WithProfiles(profiles ...string) Option {
+	return func(o *graphOptions) error {
+		o.profiles = profiles
+		return nil
+	}
+}
+
+// WithPlugins registers plugins handling custom //zero:<directive> annotations.
+func WithPlugins(plugins ...Plugin) Option {
+	return func(o *graphOptions) error {
+		if o.plugins == nil {
+			o.plugins = map[string]Plugin{}
+		}
+		for _, plugin := range plugins {
+			name := plugin.Directive()
+			if slices.Contains(directiveparser.Builtins, name) {
+				return errors.Errorf("plugin directive %q conflicts with a builtin directive", name)
+			}
+			if _, ok := o.plugins[name]; ok {
+				return errors.Errorf("duplicate plugin for directive %q", name)
+			}
+			o.plugins[name] = plugin
+		}
+		return nil
+	}
+}
+
 // WithDebug enables debug logging.
 func WithDebug(enable bool) Option {
 	return func(o *graphOptions) error {
@@ -452,6 +1007,30 @@ func WithDebug(enable bool) Option {
 	}
 }
 
+// WithTests analyses the _test.go files of the destination package, collecting test providers into
+// [Graph.TestProviders] for the test harness.
+//
+// Test providers are those annotated with //zero:provider test, or declared in _test.go files. They are never used to
+// construct the graph itself, but the types they require are included in it.
+func WithTests(enable bool) Option {
+	return func(o *graphOptions) error {
+		o.tests = enable
+		return nil
+	}
+}
+
+// WithTrailingSlash sets the trailing slash policy of APIs without a slash=<policy> label, one of
+// [directiveparser.TrailingSlashPolicies]. By default, patterns follow the behaviour of http.ServeMux.
+func WithTrailingSlash(policy string) Option {
+	return func(o *graphOptions) error {
+		if policy != "" && !slices.Contains(directiveparser.TrailingSlashPolicies, policy) {
+			return errors.Errorf("invalid trailing slash policy %q, must be one of %s", policy, strings.Join(directiveparser.TrailingSlashPolicies, ", "))
+		}
+		o.trailingSlash = policy
+		return nil
+	}
+}
+
 func WithOptions(options ...Option) Option {
 	return func(o *graphOptions) error {
 		for _, opt := range options {
@@ -464,6 +1043,49 @@ func WithOptions(options ...Option) Option {
 	}
 }
 
+// WithMod sets the -mod flag of the Go toolchain, eg. "vendor". The toolchain's default is used if mode is empty.
+func WithMod(mode string) Option {
+	return func(o *graphOptions) error {
+		switch mode {
+		case "":
+		case "mod", "readonly", "vendor":
+			o.buildFlags = append(o.buildFlags, "-mod="+mode)
+		default:
+			return errors.Errorf("invalid -mod %q, expected one of mod, readonly or vendor", mode)
+		}
+		return nil
+	}
+}
+
+// WithFixMod allows "go mod tidy", and "go mod vendor" for vendored modules, to be run if packages can't be loaded
+// because the destination module's requirements are out of date.
+func WithFixMod(enable bool) Option {
+	return func(o *graphOptions) error {
+		o.fixMod = enable
+		return nil
+	}
+}
+
+// WithLibrary analyses the destination package as a library, for which only the injector and constructors are
+// generated. The providers declared in the package are the roots of the graph if no roots are given, and handlers,
+// cron jobs, subscribers, workflow steps and workers are ignored, as they are registered by the services importing it.
+func WithLibrary(enable bool) Option {
+	return func(o *graphOptions) error {
+		o.library = enable
+		return nil
+	}
+}
+
+// WithStrict fails analysis if any part of the graph was selected implicitly, requiring weak providers included by
+// default or promoted by require=<provider> to be selected with [WithProviders] or [WithModules], and roots to be
+// given with [WithRoots] rather than inferred from receivers.
+func WithStrict(enable bool) Option {
+	return func(o *graphOptions) error {
+		o.strict = enable
+		return nil
+	}
+}
+
 // WithTags adds build tags to the Go toolchain flags.
 func WithTags(tags ...string) Option {
 	return func(o *graphOptions) error {
@@ -474,27 +1096,73 @@ func WithTags(tags ...string) Option {
 
 type Graph struct {
 	Dest           *types.Package
+	DestDir        string                 // Absolute directory of the destination package
 	Providers      map[string][]*Provider // All providers including multi and generic
+	Roots          []string               // Root types, from which all providers in the graph are reachable
 	Configs        map[string]*Config
 	GenericConfigs map[string][]*Config // Generic configs by base type name
+	Groups         map[string]*Group    // Interface groups by slice type
 	APIs           []*API
 	CronJobs       []*CronJob
 	Subscriptions  []*Subscription
+	Steps          []*Step // Workflow steps, ordered by position within each receiver type
+	Workers        []*Worker
 	Middleware     []*Middleware
+	ContextKeys    map[string]*ContextKey   // Types injected from the request context, by type
+	Annotations    map[string][]*Annotation // Custom directives handled by plugins, keyed by directive
+	GRPCServices   []*GRPCService           // API receivers also served over gRPC
+	OpenAPI        OpenAPIConfig            // OpenAPI metadata from //zero:openapi directives
 	Missing        map[*types.Func][]types.Type
+	TestProviders  []*Provider // Test providers in construction order, if analysed WithTests
+	Library        bool        // The destination was analysed WithLibrary
+
+	// All discovered providers, including those pruned from the graph.
+	discovered map[string][]*Provider
+	// State retained for [Graph.Plan] and [Graph.Warnings].
+	candidates  map[string][]*Provider
+	pick        []string
+	modulePicks []string
+	profiles    []string
+	excluded    map[string]bool
+	replaced    map[string]string // Providers replaced with replaces=<provider>, mapped to their replacements
+	// Configs and middleware before unreferenced ones were pruned, for [Graph.Warnings].
+	discoveredConfigs    map[string]*Config
+	discoveredMiddleware []*Middleware
+	// State retained for [Graph.Imports].
+	buildFlags  []string
+	destImports []string
+	// State retained for [Graph.Stats].
+	phases     []PhaseTiming
+	phaseStart time.Time
+	packages   int
 }
 
 // Analyse statically loads Go packages, then analyses them for //zero:... annotations in order to build the
 // Zero's dependency injection graph.
 func Analyse(ctx context.Context, dest string, options ...Option) (*Graph, error) {
-	graph := &Graph{
-		Providers:      make(map[string][]*Provider),
-		Configs:        make(map[string]*Config),
-		GenericConfigs: make(map[string][]*Config),
-		APIs:           make([]*API, 0),
-		CronJobs:       make([]*CronJob, 0),
-		Middleware:     make([]*Middleware, 0),
-		Missing:        make(map[*types.Func][]types.Type),
+	graphs, err := AnalyseAll(ctx, []Destination{{Dir: dest}}, options...)
+	if err != nil {
+		return nil, err
+	}
+	return graphs[0], nil
+}
+
+// Destination is a destination package analysed by [AnalyseAll], with options that only apply to it, such as its roots
+// and picks.
+type Destination struct {
+	Dir     string
+	Options []Option
+}
+
+// AnalyseAll analyses the dependency graph of each destination package, loading packages once for all of them, so that
+// several services in one module share the cost of loading and type-checking.
+//
+// Options affecting which packages are loaded, such as patterns, tags and tests, apply to all destinations and can't
+// be given for a single destination. Each destination is a separate program, so the packages of other destinations are
+// not scanned for annotations.
+func AnalyseAll(ctx context.Context, dests []Destination, options ...Option) ([]*Graph, error) {
+	if len(dests) == 0 {
+		return nil, errors.Errorf("no destination packages to analyse")
 	}
 	opts := &graphOptions{}
 	for _, opt := range options {
@@ -503,23 +1171,81 @@ func Analyse(ctx context.Context, dest string, options ...Option) (*Graph, error
 			return nil, errors.WithStack(err)
 		}
 	}
-
-	destImport, err := importPathForDir(dest)
+	loaded, err := loadPackages(ctx, dests, opts)
 	if err != nil {
-		return nil, errors.Errorf("failed to determine import path for destination directory %s: %w", dest, err)
+		return nil, err
+	}
+	graphs := make([]*Graph, 0, len(dests))
+	for i, dest := range dests {
+		destOpts := *opts
+		destOpts.roots = slices.Clone(opts.roots)
+		destOpts.pick = slices.Clone(opts.pick)
+		destOpts.modules = slices.Clone(opts.modules)
+		destOpts.profiles = slices.Clone(opts.profiles)
+		for _, opt := range dest.Options {
+			if err := opt(&destOpts); err != nil {
+				return nil, errors.Errorf("%s: %w", dest.Dir, err)
+			}
+		}
+		if !slices.Equal(destOpts.patterns, opts.patterns) || !slices.Equal(destOpts.buildFlags, opts.buildFlags) ||
+			destOpts.tests != opts.tests || destOpts.fixMod != opts.fixMod {
+			return nil, errors.Errorf("%s: patterns, tags, tests and module options can't be given for a single destination", dest.Dir)
+		}
+		graph, err := analyseDestination(loaded, i, &destOpts)
+		if err != nil {
+			if len(dests) > 1 {
+				return nil, errors.Errorf("%s: %w", dest.Dir, err)
+			}
+			return nil, err
+		}
+		graphs = append(graphs, graph)
 	}
+	return graphs, nil
+}
 
-	var logf func(string, ...any)
-	if opts.debug {
-		logf = log.Printf
-	}
+// loadedPackages are the packages loaded for the destinations passed to [AnalyseAll].
+type loadedPackages struct {
+	fileset   *token.FileSet
+	pkgs      []*packages.Package
+	workspace []string
+	// Absolute directory and import path of each destination.
+	dirs    []string
+	imports []string
+	elapsed time.Duration
+}
+
+// loadPackages loads and type-checks the destination packages, Zero's providers, the packages of other workspace
+// modules and any additional patterns.
+func loadPackages(ctx context.Context, dests []Destination, opts *graphOptions) (*loadedPackages, error) {
+	start := time.Now()
+	loaded := &loadedPackages{}
+	for _, dest := range dests {
+		destImport, err := importPathForDir(dest.Dir)
+		if err != nil {
+			return nil, errors.Errorf("failed to determine import path for destination directory %s: %w", dest.Dir, err)
+		}
+		dir, err := filepath.Abs(dest.Dir)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		loaded.imports = append(loaded.imports, destImport)
+		loaded.dirs = append(loaded.dirs, dir)
+	}
+	dest := dests[0].Dir
+
+	var logf func(string, ...any)
+	if opts.debug {
+		logf = log.Printf
+	}
 
 	// Create a new FileSet for this analysis to avoid race conditions
-	fileset := token.NewFileSet()
+	loaded.fileset = token.NewFileSet()
 	cfg := &packages.Config{
 		Logf:       logf,
-		Fset:       fileset,
+		Fset:       loaded.fileset,
 		BuildFlags: opts.buildFlags,
+		Tests:      opts.tests,
+		ParseFile:  newManifestParser().parseFile,
 		Mode: packages.NeedName | packages.NeedFiles | packages.NeedCompiledGoFiles |
 			packages.NeedImports | packages.NeedTypes | packages.NeedSyntax |
 			packages.NeedTypesInfo,
@@ -533,21 +1259,34 @@ func Analyse(ctx context.Context, dest string, options ...Option) (*Graph, error
 	} else {
 		destPattern = dest
 	}
-	opts.patterns = append(opts.patterns, "github.com/alecthomas/zero/providers/...")
-	pkgs, err := packages.Load(cfg, append(opts.patterns, destPattern)...)
+	patterns := append(slices.Clone(opts.patterns), "github.com/alecthomas/zero/providers/...")
+	// All modules of a go.work workspace are scanned for annotations.
+	workspace, err := workspaceModules(loaded.dirs[0])
+	if err != nil {
+		return nil, err
+	}
+	// Zero's own providers are already included.
+	workspace = slices.DeleteFunc(workspace, func(module string) bool { return module == "github.com/alecthomas/zero" })
+	for _, module := range workspace {
+		patterns = append(patterns, module+"/...")
+	}
+	loaded.workspace = workspace
+	// Other destinations are loaded by import path, as patterns are relative to the first.
+	patterns = append(patterns, destPattern)
+	patterns = append(patterns, loaded.imports[1:]...)
+	pkgs, err := packages.Load(cfg, patterns...)
 	if err != nil {
 		return nil, errors.Errorf("failed to load packages: %w", err)
 	}
 	// No error and no packages returned because "go mod tidy" needs to be run...super annoying.
-	// We'll run it and see if that fixes it.
 	if len(pkgs) == 0 {
-		cmd := exec.CommandContext(ctx, "go", "mod", "-C", dest, "tidy")
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		if err := cmd.Run(); err != nil {
-			return nil, errors.Errorf("failed to run 'go mod -C %q tidy': %w", dest, err)
+		if !opts.fixMod {
+			return nil, errors.Errorf("failed to load any packages, the requirements of the module in %q may be out of date: run 'go mod tidy', or allow zero to with --fix-mod", dest)
+		}
+		if err := TidyModule(ctx, loaded.dirs[0]); err != nil {
+			return nil, err
 		}
-		pkgs, err = packages.Load(cfg, append(opts.patterns, destPattern)...)
+		pkgs, err = packages.Load(cfg, patterns...)
 		if err != nil {
 			return nil, errors.Errorf("failed to load packages: %w", err)
 		}
@@ -555,7 +1294,46 @@ func Analyse(ctx context.Context, dest string, options ...Option) (*Graph, error
 			return nil, errors.Errorf("failed to load any packages, try running 'go list -C %q' and checking for errors", dest)
 		}
 	}
+	loaded.pkgs = pkgs
+	loaded.elapsed = time.Since(start)
+	return loaded, nil
+}
 
+// analyseDestination analyses the dependency graph of the destination at index in the loaded packages.
+func analyseDestination(loaded *loadedPackages, index int, opts *graphOptions) (*Graph, error) {
+	graph := &Graph{
+		Providers:      make(map[string][]*Provider),
+		Configs:        make(map[string]*Config),
+		GenericConfigs: make(map[string][]*Config),
+		Groups:         make(map[string]*Group),
+		APIs:           make([]*API, 0),
+		CronJobs:       make([]*CronJob, 0),
+		Middleware:     make([]*Middleware, 0),
+		ContextKeys:    make(map[string]*ContextKey),
+		Annotations:    make(map[string][]*Annotation),
+		Missing:        make(map[*types.Func][]types.Type),
+		DestDir:        loaded.dirs[index],
+		phases:         []PhaseTiming{{Phase: "load", Duration: loaded.elapsed}},
+	}
+	destImport := loaded.imports[index]
+	fileset := loaded.fileset
+	// The main packages of other workspace modules, and other destinations, are separate programs.
+	pkgs := slices.DeleteFunc(slices.Clone(loaded.pkgs), func(pkg *packages.Package) bool {
+		if pkg.PkgPath == destImport {
+			return false
+		}
+		return (pkg.Name == "main" && inModules(pkg.PkgPath, loaded.workspace)) || slices.Contains(loaded.imports, pkg.PkgPath)
+	})
+
+	if opts.tests {
+		pkgs = selectTestVariants(pkgs, destImport)
+	}
+	graph.packages = len(pkgs)
+	graph.startPhase("analyse")
+
+	if err := collectContextKeys(pkgs, graph, fileset, slices.Collect(maps.Keys(opts.plugins))...); err != nil {
+		return nil, err
+	}
 	providers := map[string][]*Provider{}
 	for _, pkg := range pkgs {
 		if opts.debug {
@@ -568,8 +1346,9 @@ func Analyse(ctx context.Context, dest string, options ...Option) (*Graph, error
 		}
 		if pkg.PkgPath == destImport {
 			graph.Dest = pkg.Types
+			graph.destImports = handwrittenImports(pkg)
 		}
-		err := analysePackage(pkg, graph, providers, fileset)
+		err := analysePackage(pkg, graph, providers, opts.plugins, fileset)
 		if err != nil {
 			return nil, err
 		}
@@ -578,8 +1357,46 @@ func Analyse(ctx context.Context, dest string, options ...Option) (*Graph, error
 		return nil, errors.Errorf("destination package %q not found", destImport)
 	}
 
+	testProviders := extractTestProviders(providers)
+	var err error
+	if opts.tests {
+		graph.TestProviders, err = orderTestProviders(testProviders)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	graph.candidates = snapshotProviders(providers)
+	filterProfileProviders(providers, opts.profiles)
+
+	// Providers in selected modules are treated as picks, but unlike explicit picks they need not end up in the graph.
+	modulePicks, err := selectModuleProviders(providers, opts.modules)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	pick := slices.Concat(opts.pick, modulePicks)
+	graph.pick, graph.modulePicks, graph.profiles = opts.pick, modulePicks, opts.profiles
+	graph.buildFlags = opts.buildFlags
+	graph.replaced = replaceProviders(providers, pick)
+
+	if err := collectGroups(graph, providers, pkgs, pick); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
 	// Prune weak provider APIs first, before calculating roots
-	excludedProviders := pruneWeakProviderAPIs(graph, providers, opts.pick)
+	excludedProviders := pruneWeakProviderAPIs(graph, providers, pick)
+	graph.excluded = excludedProviders
+
+	inferredRoots := opts.roots == nil
+
+	// Libraries don't serve anything, so their roots are the providers they declare.
+	if opts.library {
+		graph.Library = true
+		graph.APIs, graph.CronJobs, graph.Subscriptions, graph.Steps, graph.Workers = nil, nil, nil, nil, nil
+		if opts.roots == nil {
+			opts.roots = libraryRoots(providers, destImport)
+		}
+	}
 
 	// If no roots provided, use API, Cron, and Subscription receivers as roots
 	if opts.roots == nil {
@@ -605,6 +1422,24 @@ func Analyse(ctx context.Context, dest string, options ...Option) (*Graph, error
 				opts.roots = append(opts.roots, receiverTypeStr)
 			}
 		}
+		for _, step := range graph.Steps {
+			if recv := step.Function.Signature().Recv(); recv != nil {
+				receiverType := recv.Type()
+				receiverTypeStr := types.TypeString(receiverType, nil)
+				opts.roots = append(opts.roots, receiverTypeStr)
+			}
+		}
+		for _, worker := range graph.Workers {
+			if recv := worker.Function.Signature().Recv(); recv != nil {
+				receiverType := recv.Type()
+				receiverTypeStr := types.TypeString(receiverType, nil)
+				opts.roots = append(opts.roots, receiverTypeStr)
+			}
+		}
+	}
+	var inferred []string
+	if inferredRoots {
+		inferred = slices.Compact(slices.Sorted(slices.Values(opts.roots)))
 	}
 
 	// Add infrastructure roots based on remaining APIs/jobs after pruning
@@ -615,7 +1450,60 @@ func Analyse(ctx context.Context, dest string, options ...Option) (*Graph, error
 		opts.roots = append(opts.roots, "*github.com/alecthomas/zero/providers/cron.Scheduler")
 	}
 	if len(graph.Subscriptions) > 0 {
-		opts.roots = append(opts.roots, "github.com/alecthomas/zero/providers/pubsub.Topic")
+		opts.roots = append(opts.roots, "github.com/alecthomas/zero/providers/pubsub.Topic", "*github.com/alecthomas/zero/providers/pubsub.Subscriptions")
+	}
+	if len(graph.Steps) > 0 {
+		opts.roots = append(opts.roots, "*github.com/alecthomas/zero/providers/workflow.Engine")
+	}
+	graph.GRPCServices = findGRPCServices(graph.APIs)
+	if len(graph.GRPCServices) > 0 {
+		opts.roots = append(opts.roots, grpcConfigType)
+		if _, ok := providers[grpcServerType]; ok {
+			opts.roots = append(opts.roots, grpcServerType)
+		}
+	}
+
+	for _, api := range graph.APIs {
+		for _, name := range api.Pattern.SkipMiddleware() {
+			if !slices.ContainsFunc(graph.Middleware, func(m *Middleware) bool { return !m.Job && m.Is(name) }) {
+				return nil, errors.Errorf("%s: skip=%s does not match any middleware", api.Position, name)
+			}
+		}
+	}
+
+	// Types required by middleware applied to APIs, cron jobs and subscribers
+	for _, middleware := range graph.Middleware {
+		if !slices.ContainsFunc(graph.APIs, middleware.Match) && !graph.usesJobMiddleware(middleware) {
+			continue
+		}
+		for _, req := range middleware.Requires {
+			opts.roots = append(opts.roots, types.TypeString(req, nil))
+		}
+	}
+
+	// Types required by plugin annotations
+	for _, annotations := range graph.Annotations {
+		for _, annotation := range annotations {
+			for _, req := range annotation.Requires {
+				opts.roots = append(opts.roots, types.TypeString(req, nil))
+			}
+		}
+	}
+
+	// Types required by test providers, other than those provided by test providers
+	testProvided := map[string]bool{}
+	for _, provider := range graph.TestProviders {
+		testProvided[types.TypeString(provider.Provides, nil)] = true
+	}
+	for _, provider := range graph.TestProviders {
+		for _, req := range provider.Requires {
+			if _, ok := OptionalType(req); ok {
+				continue
+			}
+			if key := types.TypeString(UnwrapDependency(req), nil); !testProvided[key] {
+				opts.roots = append(opts.roots, key)
+			}
+		}
 	}
 
 	// Check if Dashboard API is present and Components exist
@@ -640,12 +1528,38 @@ func Analyse(ctx context.Context, dest string, options ...Option) (*Graph, error
 		}
 	}
 
-	if err := pruneUnreferencedTypes(graph, opts.roots, providers, opts.pick, excludedProviders); err != nil {
+	graph.startPhase("prune")
+	graph.discoveredConfigs, graph.discoveredMiddleware = maps.Clone(graph.Configs), slices.Clone(graph.Middleware)
+	if err := pruneUnreferencedTypes(graph, opts.roots, providers, pick, excludedProviders); err != nil {
 		return nil, errors.WithStack(err)
 	}
+	graph.Roots = slices.Compact(slices.Sorted(slices.Values(opts.roots)))
 
+	graph.discovered = providers
 	findMissingDependencies(graph)
 
+	if err := checkForCycles(graph); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if err := resolveMethods(graph); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	resolveTrailingSlash(graph, opts.trailingSlash)
+
+	if err := checkForConflictingRoutes(graph); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if err := checkContextKeys(graph); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if err := checkConfigTags(graph, fileset); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
 	// Prune unreferenced providers and configs based on roots
 	// if len(opts.roots) == 0 && len(graph.APIs) == 0 && len(graph.CronJobs) == 0 {
 	// 	return nil, errors.Errorf("no root types provided and no API endpoints or cron jobs found")
@@ -659,6 +1573,13 @@ func Analyse(ctx context.Context, dest string, options ...Option) (*Graph, error
 		return nil, errors.WithStack(err)
 	}
 
+	if opts.strict {
+		if err := errors.Join(strictErrors(graph, inferred)...); err != nil {
+			return nil, err
+		}
+	}
+	graph.endPhase()
+
 	return graph, nil
 }
 
@@ -667,7 +1588,9 @@ func Analyse(ctx context.Context, dest string, options ...Option) (*Graph, error
 // A type reference string is in the form [*]<pkg>.<type>, eg. *net/http.ServeMux
 func (g *Graph) ParseTypeRef(ref string) Ref {
 	ptr := strings.HasPrefix(ref, "*")
-	cut := strings.LastIndex(ref, ".")
+	// Type arguments may themselves be qualified, eg. pubsub.Topic[imp57144815321973d3.Event].
+	base, _, _ := strings.Cut(ref, "[")
+	cut := strings.LastIndex(base, ".")
 	if cut == -1 {
 		panic(fmt.Sprintf("invalid type reference: %s", ref))
 	}
@@ -716,6 +1639,20 @@ func (g *Graph) TypeRef(t types.Type) Ref {
 	var pkg, typeName string
 	var imp, ref string
 
+	// Packages referenced by type arguments and element types are qualified by their import alias, and imported.
+	var argImports []string
+	qualifier := func(p *types.Package) string {
+		if p.Path() == g.Dest.Path() {
+			return ""
+		}
+		if alias := g.ImportAlias(p.Path()); alias != "" {
+			argImports = append(argImports, fmt.Sprintf("%s %q", alias, p.Path()))
+			return alias
+		}
+		argImports = append(argImports, strconv.Quote(p.Path()))
+		return p.Name()
+	}
+
 	// Extract package and type name directly from the type
 	if named, ok := t.(*types.Named); ok {
 		if named.Obj().Pkg() != nil {
@@ -728,7 +1665,7 @@ func (g *Graph) TypeRef(t types.Type) Ref {
 				for i := range typeArgs.Len() {
 					argType := typeArgs.At(i)
 					// Use types.TypeString for type arguments to avoid recursion
-					argString := types.TypeString(argType, types.RelativeTo(g.Dest))
+					argString := types.TypeString(argType, qualifier)
 					typeName += argString
 					if i < typeArgs.Len()-1 {
 						typeName += ", "
@@ -742,7 +1679,7 @@ func (g *Graph) TypeRef(t types.Type) Ref {
 		}
 	} else {
 		// For non-named types, fall back to string representation
-		typ := types.TypeString(t, types.RelativeTo(g.Dest))
+		typ := types.TypeString(t, qualifier)
 		typeName = typ
 	}
 
@@ -771,16 +1708,26 @@ func (g *Graph) TypeRef(t types.Type) Ref {
 	}
 
 	return Ref{
-		Pkg:    pkg,
-		Import: imp,
-		Ref:    ref,
+		Pkg:        pkg,
+		Import:     imp,
+		Ref:        ref,
+		argImports: strings.Join(argImports, "\n"),
 	}
 }
 
 // FunctionRef returns a reference to a function, including import information if needed.
 func (g *Graph) FunctionRef(fn *types.Func) Ref {
-	name := fn.Name()
-	pkg := fn.Pkg().Path()
+	return g.objectRef(fn)
+}
+
+// ValueRef returns a reference to a package-level variable or constant, including import information if needed.
+func (g *Graph) ValueRef(value types.Object) Ref {
+	return g.objectRef(value)
+}
+
+func (g *Graph) objectRef(obj types.Object) Ref {
+	name := obj.Name()
+	pkg := obj.Pkg().Path()
 
 	var imp, ref string
 	if alias := g.ImportAlias(pkg); alias != "" {
@@ -825,6 +1772,43 @@ func (g *Graph) GetProviders(typeStr string) []*Provider {
 	return nil
 }
 
+// IsProvided returns true if a value of type t can be constructed from the graph.
+func (g *Graph) IsProvided(t types.Type) bool {
+	key := types.TypeString(t, nil)
+	if isContextType(t) || len(g.Providers[key]) > 0 || g.Groups[key] != nil {
+		return true
+	}
+	return isProvidedByConfig(t, g)
+}
+
+// RequiredInterfaces returns the interface types required by providers in the graph, ordered by name.
+//
+// Instantiations of generic interfaces are returned as their generic type, and interfaces in the standard library or
+// without methods are omitted.
+func (g *Graph) RequiredInterfaces() []*types.Named {
+	seen := map[*types.Named]bool{}
+	for _, providers := range g.Providers {
+		for _, provider := range providers {
+			for _, req := range provider.Requires {
+				named, ok := types.Unalias(UnwrapDependency(req)).(*types.Named)
+				if !ok || named.Obj().Pkg() == nil {
+					continue
+				}
+				named = named.Origin()
+				if _, isStdlib := stdlib[named.Obj().Pkg().Path()]; isStdlib {
+					continue
+				}
+				if iface, ok := named.Underlying().(*types.Interface); ok && iface.NumMethods() > 0 {
+					seen[named] = true
+				}
+			}
+		}
+	}
+	return slices.SortedFunc(maps.Keys(seen), func(a, b *types.Named) int {
+		return strings.Compare(types.TypeString(a, nil), types.TypeString(b, nil))
+	})
+}
+
 // Graph returns the dependency graph as a map where keys are type strings
 // and values are slices of their dependency type strings.
 func (g *Graph) Graph() map[string][]string {
@@ -835,13 +1819,22 @@ func (g *Graph) Graph() map[string][]string {
 		deps := make([]string, 0)
 		for _, provider := range providers {
 			for _, reqType := range provider.Requires {
-				depTypeStr := types.TypeString(reqType, types.RelativeTo(g.Dest))
+				depTypeStr := types.TypeString(UnwrapDependency(reqType), types.RelativeTo(g.Dest))
 				deps = append(deps, depTypeStr)
 			}
 		}
 		result[typeStr] = deps
 	}
 
+	// Add groups
+	for typeStr, group := range g.Groups {
+		deps := make([]string, 0, len(group.Members))
+		for _, member := range group.Members {
+			deps = append(deps, types.TypeString(member.Provides, types.RelativeTo(g.Dest)))
+		}
+		result[typeStr] = deps
+	}
+
 	// Add configs (they have no dependencies)
 	for typeStr := range g.Configs {
 		if _, exists := result[typeStr]; !exists {
@@ -861,7 +1854,15 @@ func (g *Graph) Graph() map[string][]string {
 }
 
 // GenerateOpenAPISpec creates a complete OpenAPI specification from all API endpoints
+//
+// If title or version are empty, they are taken from the OpenAPI configuration.
 func (g *Graph) GenerateOpenAPISpec(title, version string) *spec.Swagger {
+	if title == "" {
+		title = g.OpenAPI.Title
+	}
+	if version == "" {
+		version = g.OpenAPI.Version
+	}
 	swagger := &spec.Swagger{
 		SwaggerProps: spec.SwaggerProps{
 			Swagger: "2.0",
@@ -877,9 +1878,11 @@ func (g *Graph) GenerateOpenAPISpec(title, version string) *spec.Swagger {
 			Definitions: make(spec.Definitions),
 		},
 	}
+	g.OpenAPI.apply(swagger)
 
 	// Group APIs by path and generate operations with shared definitions
 	pathOperations := make(map[string]map[string]*spec.Operation)
+	operationIDs := map[string]bool{}
 
 	for _, api := range g.APIs {
 		if api.Pattern == nil {
@@ -887,18 +1890,32 @@ func (g *Graph) GenerateOpenAPISpec(title, version string) *spec.Swagger {
 		}
 
 		path := api.Pattern.Path()
-		method := strings.ToLower(api.Pattern.Method)
-		if method == "" {
-			method = "get"
-		}
-
 		if pathOperations[path] == nil {
 			pathOperations[path] = make(map[string]*spec.Operation)
 		}
 
-		// Generate operation with shared definitions
-		operation := api.GenerateOpenAPIOperation(swagger.Definitions)
-		pathOperations[path][method] = operation
+		methods := api.methods()
+		for _, method := range methods {
+			// Generate operation with shared definitions
+			operation := api.GenerateOpenAPIOperation(swagger.Definitions)
+			operation.ID = api.OperationID()
+			if len(methods) > 1 {
+				operation.ID += upperFirst(strings.ToLower(method))
+			}
+			if operationIDs[operation.ID] {
+				operation.ID = upperFirst(api.Package.Name) + operation.ID
+			}
+			operationIDs[operation.ID] = true
+			if _, ok := api.Pattern.Label("authenticated"); ok && len(g.OpenAPI.Security) > 0 {
+				operation.Security = g.OpenAPI.securityRequirements()
+				operation.Responses.StatusCodeResponses[401] = spec.Response{
+					ResponseProps: spec.ResponseProps{
+						Description: "Unauthorized",
+					},
+				}
+			}
+			pathOperations[path][strings.ToLower(method)] = operation
+		}
 	}
 
 	// Convert to PathItems
@@ -934,24 +1951,49 @@ func (g *Graph) GenerateOpenAPISpec(title, version string) *spec.Swagger {
 }
 
 // Parse a directive from a comment. Will return (nil, nil) if a directive is not found.
-func parseDirective(doc *ast.CommentGroup) (directiveparser.Directive, error) {
+//
+// Directives named in custom are handled by plugins, and are skipped.
+func parseDirective(doc *ast.CommentGroup, custom ...string) (directiveparser.Directive, error) {
 	if doc == nil {
 		return nil, nil
 	}
 	for _, comment := range doc.List {
-		if strings.HasPrefix(comment.Text, "//zero:") {
+		// Collected by packageAPIGroups, and may accompany another directive, eg. on a provider
+		if strings.HasPrefix(comment.Text, "//zero:api-labels") {
+			continue
+		}
+		if text, ok := strings.CutPrefix(comment.Text, "//zero:"); ok {
+			if name, _, _ := strings.Cut(text, " "); slices.Contains(custom, name) {
+				continue
+			}
 			return errors.WithStack2(directiveparser.Parse(comment.Text[2:]))
 		}
 	}
 	return nil, nil
 }
 
-func analysePackage(pkg *packages.Package, graph *Graph, providers map[string][]*Provider, fset *token.FileSet) error {
+func analysePackage(pkg *packages.Package, graph *Graph, providers map[string][]*Provider, plugins map[string]Plugin, fset *token.FileSet) error {
+	module, err := packageModule(pkg, fset)
+	if err != nil {
+		return err
+	}
+	if err := packageOpenAPI(pkg, fset, &graph.OpenAPI); err != nil {
+		return err
+	}
+	groups, err := packageAPIGroups(pkg, fset)
+	if err != nil {
+		return err
+	}
+	custom := slices.Collect(maps.Keys(plugins))
 	for _, file := range pkg.Syntax {
 		for _, decl := range file.Decls {
+			// Builtin directives accompanying a plugin directive are analysed as usual
+			if err := analysePluginDecl(decl, pkg, graph, plugins, fset); err != nil {
+				return err
+			}
 			switch decl := decl.(type) {
 			case *ast.FuncDecl:
-				directive, err := parseDirective(decl.Doc)
+				directive, err := parseDirective(decl.Doc, custom...)
 				if err != nil {
 					return errors.Errorf("%s: %w", fset.Position(decl.Pos()), err)
 				} else if directive == nil {
@@ -964,6 +2006,7 @@ func analysePackage(pkg *packages.Package, graph *Graph, providers map[string][]
 						return err
 					}
 					if provider != nil {
+						provider.Module = module
 						if provider.IsGeneric {
 							// For generic providers, store by base type name
 							baseType := getBaseTypeName(provider.Provides)
@@ -975,7 +2018,7 @@ func analysePackage(pkg *packages.Package, graph *Graph, providers map[string][]
 					}
 
 				case *directiveparser.DirectiveAPI:
-					api, err := createAPI(decl, pkg, directive, fset)
+					api, err := createAPI(decl, pkg, directive, groups, graph.ContextKeys, fset)
 					if err != nil {
 						return err
 					}
@@ -1002,17 +2045,47 @@ func analysePackage(pkg *packages.Package, graph *Graph, providers map[string][]
 					}
 
 				case *directiveparser.DirectiveSubscribe:
-					subscription, err := createSubscription(decl, pkg, fset)
+					subscription, err := createSubscription(decl, pkg, directive, fset)
 					if err != nil {
 						return err
 					}
+					if err := checkSubscriptionVersion(graph.Subscriptions, subscription); err != nil {
+						return err
+					}
 					if subscription != nil {
 						graph.Subscriptions = append(graph.Subscriptions, subscription)
 					}
+
+				case *directiveparser.DirectiveStep:
+					step, err := createStep(decl, pkg, directive, fset)
+					if err != nil {
+						return err
+					}
+					if err := checkStepState(graph.Steps, step); err != nil {
+						return err
+					}
+					if step != nil {
+						graph.Steps = append(graph.Steps, step)
+					}
+
+				case *directiveparser.DirectiveWorker:
+					worker, err := createWorker(decl, pkg, directive, fset)
+					if err != nil {
+						return err
+					}
+					if worker != nil {
+						graph.Workers = append(graph.Workers, worker)
+					}
 				}
 
 			case *ast.GenDecl:
-				directive, err := parseDirective(decl.Doc)
+				if decl.Tok == token.VAR || decl.Tok == token.CONST {
+					if err := analyseValueDecl(decl, pkg, module, providers, fset, custom...); err != nil {
+						return err
+					}
+					continue
+				}
+				directive, err := parseDirective(decl.Doc, custom...)
 				if err != nil {
 					return errors.Errorf("%s: %s", fset.Position(decl.Pos()), err)
 				} else if directive == nil {
@@ -1053,6 +2126,12 @@ func analysePackage(pkg *packages.Package, graph *Graph, providers map[string][]
 							}
 						}
 
+					case *directiveparser.DirectiveContextKey:
+						// Collected by collectContextKeys before APIs are analysed
+
+					case *directiveparser.DirectiveAPIGroup:
+						// Collected by packageAPIGroups before APIs are analysed
+
 					default:
 						return errors.Errorf("%s: %s: unknown directive type", fset.Position(typeSpec.Pos()), directive)
 					}
@@ -1063,6 +2142,236 @@ func analysePackage(pkg *packages.Package, graph *Graph, providers map[string][]
 	return nil
 }
 
+// packageModule returns the module declared by a //zero:module directive on the package clause, or "" if there is none.
+func packageModule(pkg *packages.Package, fset *token.FileSet) (string, error) {
+	module := ""
+	for _, file := range pkg.Syntax {
+		if file.Doc == nil {
+			continue
+		}
+		// The package clause may have multiple directives, eg. //zero:openapi
+		for _, comment := range file.Doc.List {
+			if !strings.HasPrefix(comment.Text, "//zero:module") {
+				continue
+			}
+			directive, err := directiveparser.Parse(comment.Text[2:])
+			if err != nil {
+				return "", errors.Errorf("%s: %w", fset.Position(file.Package), err)
+			}
+			moduleDirective, ok := directive.(*directiveparser.DirectiveModule)
+			if !ok {
+				continue
+			}
+			if module != "" && module != moduleDirective.Name {
+				return "", errors.Errorf("%s: package %s is already a member of module %q", fset.Position(file.Package), pkg.PkgPath, module)
+			}
+			module = moduleDirective.Name
+		}
+	}
+	return module, nil
+}
+
+// collectGroups collects providers annotated with group=<iface> into graph.Groups, keyed by the slice type []<iface>.
+//
+// Weak providers are only collected if picked.
+func collectGroups(graph *Graph, providers map[string][]*Provider, pkgs []*packages.Package, pick []string) error {
+	loaded := map[string]*packages.Package{}
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) { loaded[pkg.PkgPath] = pkg })
+	for _, providerList := range providers {
+		for _, provider := range providerList {
+			for _, ref := range provider.Directive.Group {
+				iface, err := resolveGroupInterface(provider.Package, ref, loaded)
+				if err != nil {
+					return errors.Errorf("%s: %w", provider.Position, err)
+				}
+				if provider.IsGeneric {
+					return errors.Errorf("%s: generic provider %s can not be a member of group %s", provider.Position, provider.FullName(), iface)
+				}
+				if !types.Implements(provider.Provides, iface.Underlying().(*types.Interface)) { //nolint:forcetypeassert
+					return errors.Errorf("%s: %s does not implement group interface %s", provider.Position, provider.Provides, iface)
+				}
+				if provider.Directive.Weak && !slices.Contains(pickedProviders(pick), provider.FullName()) {
+					continue
+				}
+				sliceType := types.NewSlice(iface)
+				key := types.TypeString(sliceType, nil)
+				if _, exists := providers[key]; exists {
+					return errors.Errorf("%s: group %s conflicts with existing providers of %s", provider.Position, iface, key)
+				}
+				group, ok := graph.Groups[key]
+				if !ok {
+					group = &Group{Type: sliceType, Interface: iface}
+					graph.Groups[key] = group
+				}
+				group.Members = append(group.Members, provider)
+			}
+		}
+	}
+	for _, group := range graph.Groups {
+		slices.SortFunc(group.Members, func(a, b *Provider) int { return strings.Compare(a.FullName(), b.FullName()) })
+	}
+	return nil
+}
+
+// resolveGroupInterface resolves a group reference, either a local type name or a fully-qualified
+// "<pkg>.<type>", to a named interface type.
+func resolveGroupInterface(pkg *packages.Package, ref string, loaded map[string]*packages.Package) (types.Type, error) {
+	if !strings.Contains(ref, ".") {
+		ref = pkg.PkgPath + "." + ref
+	}
+	dot := strings.LastIndex(ref, ".")
+	pkgPath, name := ref[:dot], ref[dot+1:]
+	target, ok := loaded[pkgPath]
+	if !ok {
+		return nil, errors.Errorf("group interface %s not found, package %q was not loaded", ref, pkgPath)
+	}
+	obj := target.Types.Scope().Lookup(name)
+	if obj == nil {
+		return nil, errors.Errorf("group interface %s not found", ref)
+	}
+	if _, ok := obj.Type().Underlying().(*types.Interface); !ok {
+		return nil, errors.Errorf("group type %s is not an interface", ref)
+	}
+	return obj.Type(), nil
+}
+
+// Profiles returns the sorted profiles that discovered providers are annotated with, whether or not they are enabled.
+func (g *Graph) Profiles() []string {
+	var profiles []string
+	for _, providers := range g.candidates {
+		for _, provider := range providers {
+			profiles = append(profiles, provider.Directive.Profile...)
+		}
+	}
+	slices.Sort(profiles)
+	return slices.Compact(profiles)
+}
+
+// filterProfileProviders removes providers annotated with profile=<name>,... where none of the profiles are enabled.
+func filterProfileProviders(providers map[string][]*Provider, profiles []string) {
+	for key, providerList := range providers {
+		providerList = slices.DeleteFunc(providerList, func(p *Provider) bool {
+			return len(p.Directive.Profile) > 0 && !slices.ContainsFunc(p.Directive.Profile, func(profile string) bool {
+				return slices.Contains(profiles, profile)
+			})
+		})
+		if len(providerList) == 0 {
+			delete(providers, key)
+		} else {
+			providers[key] = providerList
+		}
+	}
+}
+
+// replaceProviders removes providers that another provider of the same type replaces with
+// //zero:provider replaces=<provider>, unless they were selected explicitly. It returns the names of the removed
+// providers, mapped to the names of the providers replacing them.
+func replaceProviders(providers map[string][]*Provider, pick []string) map[string]string {
+	picked := pickedProviders(pick)
+	replaced := map[string]string{}
+	for key, providerList := range providers {
+		for _, provider := range providerList {
+			for _, name := range provider.Directive.Replaces {
+				name = resolveRequireFunc(provider.Package, name)
+				if !slices.Contains(picked, name) && slices.ContainsFunc(providerList, func(p *Provider) bool { return p.FullName() == name }) {
+					replaced[name] = provider.FullName()
+				}
+			}
+		}
+		providers[key] = slices.DeleteFunc(providerList, func(p *Provider) bool { return replaced[p.FullName()] != "" })
+	}
+	return replaced
+}
+
+// selectModuleProviders returns the names of all providers that are members of the given modules.
+func selectModuleProviders(providers map[string][]*Provider, modules []string) ([]string, error) {
+	members := map[string][]string{}
+	for _, providerList := range providers {
+		for _, p := range providerList {
+			if p.Module != "" {
+				members[p.Module] = append(members[p.Module], p.FullName())
+			}
+		}
+	}
+	var picks []string
+	for _, module := range modules {
+		names, ok := members[module]
+		if !ok {
+			return nil, errors.Errorf("requested module %q not found in discovered modules: %s", module, strings.Join(slices.Sorted(maps.Keys(members)), ", "))
+		}
+		picks = append(picks, names...)
+	}
+	return picks, nil
+}
+
+// analyseValueDecl collects value providers from package-level var and const declarations.
+//
+// The directive may be attached to the declaration itself, or to individual specs in a grouped declaration.
+func analyseValueDecl(decl *ast.GenDecl, pkg *packages.Package, module string, providers map[string][]*Provider, fset *token.FileSet, custom ...string) error {
+	declDirective, err := parseDirective(decl.Doc, custom...)
+	if err != nil {
+		return errors.Errorf("%s: %w", fset.Position(decl.Pos()), err)
+	}
+	for _, spec := range decl.Specs {
+		valueSpec, ok := spec.(*ast.ValueSpec)
+		if !ok {
+			continue
+		}
+		directive, err := parseDirective(valueSpec.Doc, custom...)
+		if err != nil {
+			return errors.Errorf("%s: %w", fset.Position(valueSpec.Pos()), err)
+		}
+		if directive == nil {
+			directive = declDirective
+		}
+		if directive == nil {
+			continue
+		}
+		providerDirective, ok := directive.(*directiveparser.DirectiveProvider)
+		if !ok {
+			return errors.Errorf("%s: %s: only //zero:provider is valid on var and const declarations", fset.Position(valueSpec.Pos()), directive)
+		}
+		for _, name := range valueSpec.Names {
+			provider, err := createValueProvider(name, pkg, providerDirective, fset)
+			if err != nil {
+				return err
+			}
+			if provider != nil {
+				provider.Module = module
+				provider.Documentation = strings.TrimSpace(valueSpec.Doc.Text())
+				if provider.Documentation == "" && len(decl.Specs) == 1 {
+					provider.Documentation = strings.TrimSpace(decl.Doc.Text())
+				}
+				key := types.TypeString(provider.Provides, nil)
+				providers[key] = append(providers[key], provider)
+			}
+		}
+	}
+	return nil
+}
+
+func createValueProvider(name *ast.Ident, pkg *packages.Package, directive *directiveparser.DirectiveProvider, fset *token.FileSet) (*Provider, error) {
+	if name.Name == "_" {
+		return nil, nil
+	}
+	obj := pkg.TypesInfo.ObjectOf(name)
+	switch obj.(type) {
+	case *types.Var, *types.Const:
+	default:
+		return nil, nil
+	}
+	if basic, ok := obj.Type().(*types.Basic); ok && basic.Info()&types.IsUntyped != 0 {
+		return nil, errors.Errorf("%s: value provider %s must have an explicit type", fset.Position(name.Pos()), name.Name)
+	}
+	return &Provider{
+		Directive: directive,
+		Value:     obj,
+		Package:   pkg,
+		Position:  fset.Position(name.Pos()),
+		Provides:  obj.Type(),
+	}, nil
+}
+
 func createProvider(fn *ast.FuncDecl, pkg *packages.Package, directive *directiveparser.DirectiveProvider, fset *token.FileSet) (*Provider, error) {
 	obj := pkg.TypesInfo.ObjectOf(fn.Name)
 	if obj == nil {
@@ -1092,10 +2401,19 @@ func createProvider(fn *ast.FuncDecl, pkg *packages.Package, directive *directiv
 		}
 	}
 
+	// The receiver of a method provider is itself resolved through the graph.
+	var receiverType types.Type
+	requiredTypes := []types.Type{}
+	if recv := sig.Recv(); recv != nil {
+		if sig.RecvTypeParams().Len() > 0 {
+			return nil, errors.Errorf("%s: provider method %s can not have a generic receiver", fset.Position(fn.Pos()), fn.Name.Name)
+		}
+		receiverType = recv.Type()
+		requiredTypes = append(requiredTypes, receiverType)
+	}
 	params := sig.Params()
-	requiredTypes := make([]types.Type, params.Len())
 	for i := range params.Len() {
-		requiredTypes[i] = params.At(i).Type()
+		requiredTypes = append(requiredTypes, params.At(i).Type())
 	}
 
 	// Check if this is a generic function
@@ -1103,18 +2421,20 @@ func createProvider(fn *ast.FuncDecl, pkg *packages.Package, directive *directiv
 	isGeneric := typeParams != nil && typeParams.Len() > 0
 
 	return &Provider{
-		Directive:  directive,
-		Function:   funcObj,
-		Package:    pkg,
-		Position:   fset.Position(fn.Pos()),
-		Provides:   providedType,
-		Requires:   requiredTypes,
-		IsGeneric:  isGeneric,
-		TypeParams: typeParams,
+		Directive:     directive,
+		Function:      funcObj,
+		Package:       pkg,
+		Position:      fset.Position(fn.Pos()),
+		Documentation: strings.TrimSpace(fn.Doc.Text()),
+		Provides:      providedType,
+		Requires:      requiredTypes,
+		Receiver:      receiverType,
+		IsGeneric:     isGeneric,
+		TypeParams:    typeParams,
 	}, nil
 }
 
-func createAPI(fn *ast.FuncDecl, pkg *packages.Package, directive *directiveparser.DirectiveAPI, fset *token.FileSet) (*API, error) {
+func createAPI(fn *ast.FuncDecl, pkg *packages.Package, directive *directiveparser.DirectiveAPI, groups apiGroups, contextKeys map[string]*ContextKey, fset *token.FileSet) (*API, error) {
 	// API annotations are only valid on methods (functions with receivers)
 	if fn.Recv == nil {
 		return nil, errors.Errorf("//zero:api annotation is only valid on methods, not functions: %s", fn.Name.Name)
@@ -1133,11 +2453,22 @@ func createAPI(fn *ast.FuncDecl, pkg *packages.Package, directive *directivepars
 	signature := funcObj.Signature()
 
 	// Check if receiver is a config type - configs cannot have API methods
+	var group *APIGroup
 	if sig := signature; sig.Recv() != nil {
 		receiverType := sig.Recv().Type()
 		if isConfigType(receiverType, pkg) {
 			return nil, errors.Errorf("//zero:api annotation cannot be used on config types: %s", fn.Name.Name)
 		}
+		// The prefix must be applied before parameters are matched against path wildcards
+		group = groups.forReceiver(receiverType)
+		if group != nil {
+			group.apply(directive)
+		}
+		if labels := groups.labelsForReceiver(receiverType); labels != nil {
+			if err := labels.apply(directive); err != nil {
+				return nil, errors.Errorf("API method %s: %w", fn.Name.Name, err)
+			}
+		}
 	}
 
 	results := signature.Results()
@@ -1148,28 +2479,89 @@ func createAPI(fn *ast.FuncDecl, pkg *packages.Package, directive *directivepars
 		if !isErrorType(secondResult) {
 			return nil, errors.Errorf("function %s second return value must be error", fn.Name.Name)
 		}
+	case 3:
+		if !isStatusType(results.At(0).Type()) {
+			return nil, errors.Errorf("function %s first return value must be an int or zero.Status when returning three values", fn.Name.Name)
+		}
+		if !isErrorType(results.At(2).Type()) {
+			return nil, errors.Errorf("function %s third return value must be error", fn.Name.Name)
+		}
 	default:
-		return nil, errors.Errorf("function %s can only return one or two values", fn.Name.Name)
+		return nil, errors.Errorf("function %s can only return one or two values, or a status, response and error", fn.Name.Name)
 	}
 
 	// Validate parameter types
 	params := signature.Params()
-	var bodyParamCount int
+	var bodyParamCount, readerParamCount int
+	apiContextKeys := map[int]*ContextKey{}
+	apiHeaders := map[int]*HeaderParameter{}
+	apiParams := map[int][]*ParamsField{}
+	headers, err := headerParameters(directive)
+	if err != nil {
+		return nil, errors.Errorf("API method %s: %w", fn.Name.Name, err)
+	}
 	for i := range params.Len() {
 		param := params.At(i)
 		paramType := param.Type()
 		paramName := param.Name()
 
+		if header, ok := headers[paramName]; ok {
+			if !isHeaderParameterType(paramType) {
+				return nil, errors.Errorf("API method %s parameter %s of type %s cannot be bound to a %s",
+					fn.Name.Name, paramName, types.TypeString(paramType, nil), header.In)
+			}
+			apiHeaders[i] = header
+			delete(headers, paramName)
+			continue
+		}
+
+		if fields := paramsFields(paramType); fields != nil {
+			if len(apiParams) > 0 {
+				return nil, errors.Errorf("API method %s can only have one params struct parameter", fn.Name.Name)
+			}
+			if err := checkParamsFields(directive, paramType, fields); err != nil {
+				return nil, errors.Errorf("API method %s parameter %s: %w", fn.Name.Name, paramName, err)
+			}
+			apiParams[i] = fields
+			continue
+		}
+
+		if key, ok := contextKeys[types.TypeString(paramType, nil)]; ok {
+			if _, ok := directive.Label(key.Label); !ok {
+				return nil, errors.Errorf("API method %s parameter %s of type %s requires the %s label",
+					fn.Name.Name, paramName, types.TypeString(paramType, nil), key.Label)
+			}
+			apiContextKeys[i] = key
+			continue
+		}
+
+		if isReaderType(paramType) {
+			readerParamCount++
+		}
+
 		if !isValidAPIParameterType(paramType, paramName, directive, &bodyParamCount) {
 			return nil, errors.Errorf("invalid parameter type for API method %s: parameter %s of type %s is not allowed",
 				fn.Name.Name, paramName, types.TypeString(paramType, nil))
 		}
 	}
 
+	if len(headers) > 0 {
+		param := slices.Sorted(maps.Keys(headers))[0]
+		return nil, errors.Errorf("API method %s has no parameter %s to bind to the %s %s", fn.Name.Name, param, headers[param].In, headers[param].Name)
+	}
+
 	if bodyParamCount > 1 {
 		return nil, errors.Errorf("API method %s can only have one struct parameter for request body/query parameters", fn.Name.Name)
 	}
 
+	if readerParamCount > 1 || (readerParamCount > 0 && bodyParamCount > 0) {
+		return nil, errors.Errorf("API method %s can only have one io.Reader parameter, and it cannot be combined with a request body struct", fn.Name.Name)
+	}
+
+	if _, ok := directive.Label("etag"); ok && responseType(signature) == nil {
+		return nil, errors.Errorf("API method %s must return a response to use etag", fn.Name.Name)
+	}
+
 	// Extract documentation from function comments
 	var documentation string
 	if fn.Doc != nil {
@@ -1177,11 +2569,17 @@ func createAPI(fn *ast.FuncDecl, pkg *packages.Package, directive *directivepars
 	}
 
 	api := &API{
-		Pattern:       directive,
-		Function:      funcObj,
-		Documentation: documentation,
-		Package:       pkg,
-		Position:      fset.Position(fn.Pos()),
+		Pattern:        directive,
+		Function:       funcObj,
+		Documentation:  documentation,
+		Package:        pkg,
+		Position:       fset.Position(fn.Pos()),
+		ContextKeys:    apiContextKeys,
+		Headers:        apiHeaders,
+		Params:         apiParams,
+		Group:          group,
+		Statuses:       returnedStatuses(fn, pkg),
+		RedactedFields: redactedFields(signature),
 	}
 
 	// Generate OpenAPI operation spec
@@ -1190,6 +2588,63 @@ func createAPI(fn *ast.FuncDecl, pkg *packages.Package, directive *directivepars
 	return api, nil
 }
 
+// redactedFields returns the JSON names of the request and response fields tagged `log:"-"`.
+func redactedFields(signature *types.Signature) []string {
+	fields := map[string]bool{}
+	seen := map[types.Type]bool{}
+	for i := range signature.Params().Len() {
+		collectRedactedFields(signature.Params().At(i).Type(), fields, seen)
+	}
+	if response := responseType(signature); response != nil {
+		collectRedactedFields(response, fields, seen)
+	}
+	return slices.Sorted(maps.Keys(fields))
+}
+
+func collectRedactedFields(t types.Type, fields map[string]bool, seen map[types.Type]bool) {
+	if seen[t] {
+		return
+	}
+	seen[t] = true
+	switch t := t.(type) {
+	case *types.Pointer:
+		collectRedactedFields(t.Elem(), fields, seen)
+	case *types.Slice:
+		collectRedactedFields(t.Elem(), fields, seen)
+	case *types.Array:
+		collectRedactedFields(t.Elem(), fields, seen)
+	case *types.Map:
+		collectRedactedFields(t.Elem(), fields, seen)
+	case *types.Named:
+		if t.Obj().Pkg() != nil {
+			if _, ok := stdlib[t.Obj().Pkg().Path()]; ok {
+				return
+			}
+		}
+		for i := range t.TypeArgs().Len() {
+			collectRedactedFields(t.TypeArgs().At(i), fields, seen)
+		}
+		collectRedactedFields(t.Underlying(), fields, seen)
+	case *types.Struct:
+		for i := range t.NumFields() {
+			field := t.Field(i)
+			if !field.Exported() {
+				continue
+			}
+			tag := reflect.StructTag(t.Tag(i))
+			if tag.Get("log") == "-" {
+				// Named as by encoding/json, rather than the OpenAPI schema
+				name, _, _ := strings.Cut(tag.Get("json"), ",")
+				if name != "-" {
+					fields[cmp.Or(name, field.Name())] = true
+				}
+				continue
+			}
+			collectRedactedFields(field.Type(), fields, seen)
+		}
+	}
+}
+
 func createCron(fn *ast.FuncDecl, pkg *packages.Package, directive *directiveparser.DirectiveCron, fset *token.FileSet) (*CronJob, error) {
 	// Cron annotations are only valid on methods (functions with receivers)
 	if fn.Recv == nil {
@@ -1216,10 +2671,11 @@ func createCron(fn *ast.FuncDecl, pkg *packages.Package, directive *directivepar
 		}
 	}
 
-	// Validate exact signature: Cron(context.Context) error
+	// Validate exact signature: Cron(context.Context) error, or Cron(context.Context, T) error to run once for each
+	// element of an injected []T
 	params := signature.Params()
-	if params.Len() != 1 {
-		return nil, errors.Errorf("cron method %s must have exactly one parameter of type context.Context", fn.Name.Name)
+	if params.Len() != 1 && params.Len() != 2 {
+		return nil, errors.Errorf("cron method %s must have one parameter of type context.Context, optionally followed by an element of an injected slice", fn.Name.Name)
 	}
 
 	// Check first parameter is context.Context
@@ -1240,18 +2696,172 @@ func createCron(fn *ast.FuncDecl, pkg *packages.Package, directive *directivepar
 		return nil, errors.Errorf("cron method %s must return error, got %s", fn.Name.Name, types.TypeString(returnType, nil))
 	}
 
-	return &CronJob{
+	cronJob := &CronJob{
 		Schedule: directive,
 		Function: funcObj,
 		Package:  pkg,
 		Position: fset.Position(fn.Pos()),
+	}
+	if params.Len() == 2 {
+		cronJob.Params = types.NewSlice(params.At(1).Type())
+	}
+	return cronJob, nil
+}
+
+func createSubscription(fn *ast.FuncDecl, pkg *packages.Package, directive *directiveparser.DirectiveSubscribe, fset *token.FileSet) (*Subscription, error) {
+	// Subscription annotations are only valid on methods (functions with receivers)
+	if fn.Recv == nil {
+		return nil, errors.Errorf("//zero:subscribe annotation is only valid on methods, not functions: %s", fn.Name.Name)
+	}
+
+	obj := pkg.TypesInfo.ObjectOf(fn.Name)
+	if obj == nil {
+		return nil, errors.Errorf("failed to retrieve object for function %s", fn.Name.Name)
+	}
+
+	funcObj, ok := obj.(*types.Func)
+	if !ok {
+		return nil, nil
+	}
+
+	signature := funcObj.Signature()
+
+	// Check if receiver is a config type - configs cannot have subscription methods
+	if sig := signature; sig.Recv() != nil {
+		receiverType := sig.Recv().Type()
+		if isConfigType(receiverType, pkg) {
+			return nil, errors.Errorf("//zero:subscribe annotation cannot be used on config types: %s", fn.Name.Name)
+		}
+	}
+
+	// Validate exact signature: Method(context.Context, pubsub.Event[T]) error, or
+	// Method(context.Context, []pubsub.Event[T]) error for batches
+	params := signature.Params()
+	if params.Len() != 2 {
+		return nil, errors.Errorf("subscription method %s must have exactly two parameters: context.Context and pubsub.Event[T]", fn.Name.Name)
+	}
+
+	// Check first parameter is context.Context
+	param := params.At(0)
+	paramType := param.Type()
+	if !isContextType(paramType) {
+		return nil, errors.Errorf("subscription method %s first parameter must be context.Context, got %s", fn.Name.Name, types.TypeString(paramType, nil))
+	}
+
+	// Check second parameter is pubsub.Event[T] or []pubsub.Event[T]
+	eventParam := params.At(1)
+	eventType := eventParam.Type()
+	slice, batched := eventType.(*types.Slice)
+	if batched {
+		eventType = slice.Elem()
+	}
+	switch {
+	case batched && directive.Batch == nil:
+		return nil, errors.Errorf("subscription method %s receives a batch of events and must set a batch size, eg. //zero:subscribe batch=100", fn.Name.Name)
+	case !batched && directive.Batch != nil:
+		return nil, errors.Errorf("subscription method %s sets a batch size and must receive a batch of events: []pubsub.Event[T]", fn.Name.Name)
+	case batched && directive.Version != nil:
+		return nil, errors.Errorf("subscription method %s receives a batch of events and cannot be versioned", fn.Name.Name)
+	}
+	window, err := directive.WindowDuration()
+	if err != nil {
+		return nil, errors.Errorf("subscription method %s: %w", fn.Name.Name, err)
+	}
+	dedupe, err := directive.DedupeDuration()
+	if err != nil {
+		return nil, errors.Errorf("subscription method %s: %w", fn.Name.Name, err)
+	}
+
+	// Extract the event type from pubsub.Event[T]
+	payloadType, err := extractEventPayloadType(eventType)
+	if err != nil {
+		return nil, errors.Errorf("subscription method %s second parameter must be pubsub.Event[T], got %s: %v", fn.Name.Name, types.TypeString(eventParam.Type(), nil), err)
+	}
+
+	// Validate return type is error
+	results := signature.Results()
+	if results.Len() != 1 {
+		return nil, errors.Errorf("subscription method %s must return exactly one value of type error", fn.Name.Name)
+	}
+
+	returnType := results.At(0).Type()
+	if !isErrorType(returnType) {
+		return nil, errors.Errorf("subscription method %s must return error, got %s", fn.Name.Name, types.TypeString(returnType, nil))
+	}
+
+	return &Subscription{
+		Function:    funcObj,
+		Package:     pkg,
+		Position:    fset.Position(fn.Pos()),
+		TopicType:   payloadType,
+		Version:     directive.Version,
+		Concurrency: directive.Concurrency,
+		Prefetch:    directive.Prefetch,
+		Batch:       directive.Batch,
+		Window:      window,
+		Dedupe:      dedupe,
+		Labels:      directive.Labels,
 	}, nil
 }
 
-func createSubscription(fn *ast.FuncDecl, pkg *packages.Package, fset *token.FileSet) (*Subscription, error) {
-	// Subscription annotations are only valid on methods (functions with receivers)
+func createStep(fn *ast.FuncDecl, pkg *packages.Package, directive *directiveparser.DirectiveStep, fset *token.FileSet) (*Step, error) {
+	// Step annotations are only valid on methods (functions with receivers)
+	if fn.Recv == nil {
+		return nil, errors.Errorf("//zero:step annotation is only valid on methods, not functions: %s", fn.Name.Name)
+	}
+
+	obj := pkg.TypesInfo.ObjectOf(fn.Name)
+	if obj == nil {
+		return nil, errors.Errorf("failed to retrieve object for function %s", fn.Name.Name)
+	}
+
+	funcObj, ok := obj.(*types.Func)
+	if !ok {
+		return nil, nil
+	}
+
+	signature := funcObj.Signature()
+	receiverType := signature.Recv().Type()
+	if isConfigType(receiverType, pkg) {
+		return nil, errors.Errorf("//zero:step annotation cannot be used on config types: %s", fn.Name.Name)
+	}
+
+	// Validate exact signature: Step(context.Context, *S) error
+	stateType, err := stepStateType(signature)
+	if err != nil {
+		return nil, errors.Errorf("step method %s %s", fn.Name.Name, err)
+	}
+
+	step := &Step{
+		Function:  funcObj,
+		Package:   pkg,
+		Position:  fset.Position(fn.Pos()),
+		StateType: stateType,
+	}
+	if directive.Compensate == "" {
+		return step, nil
+	}
+	compensate, _, _ := types.LookupFieldOrMethod(receiverType, true, pkg.Types, directive.Compensate)
+	compensateFunc, ok := compensate.(*types.Func)
+	if !ok {
+		return nil, errors.Errorf("step method %s: compensation %s is not a method of %s", fn.Name.Name, directive.Compensate, types.TypeString(receiverType, nil))
+	}
+	compensateState, err := stepStateType(compensateFunc.Signature())
+	if err != nil {
+		return nil, errors.Errorf("step method %s: compensation %s %s", fn.Name.Name, directive.Compensate, err)
+	}
+	if !types.Identical(compensateState, stateType) {
+		return nil, errors.Errorf("step method %s: compensation %s must accept *%s, got *%s", fn.Name.Name, directive.Compensate,
+			types.TypeString(stateType, nil), types.TypeString(compensateState, nil))
+	}
+	step.Compensate = compensateFunc
+	return step, nil
+}
+
+func createWorker(fn *ast.FuncDecl, pkg *packages.Package, directive *directiveparser.DirectiveWorker, fset *token.FileSet) (*Worker, error) {
+	// Worker annotations are only valid on methods (functions with receivers)
 	if fn.Recv == nil {
-		return nil, errors.Errorf("//zero:subscribe annotation is only valid on methods, not functions: %s", fn.Name.Name)
+		return nil, errors.Errorf("//zero:worker annotation is only valid on methods, not functions: %s", fn.Name.Name)
 	}
 
 	obj := pkg.TypesInfo.ObjectOf(fn.Name)
@@ -1265,55 +2875,110 @@ func createSubscription(fn *ast.FuncDecl, pkg *packages.Package, fset *token.Fil
 	}
 
 	signature := funcObj.Signature()
-
-	// Check if receiver is a config type - configs cannot have subscription methods
-	if sig := signature; sig.Recv() != nil {
-		receiverType := sig.Recv().Type()
-		if isConfigType(receiverType, pkg) {
-			return nil, errors.Errorf("//zero:subscribe annotation cannot be used on config types: %s", fn.Name.Name)
-		}
+	if isConfigType(signature.Recv().Type(), pkg) {
+		return nil, errors.Errorf("//zero:worker annotation cannot be used on config types: %s", fn.Name.Name)
 	}
 
-	// Validate exact signature: Method(context.Context, pubsub.Event[T]) error
+	// Validate exact signature: Method(context.Context, queue.Job[T]) error
 	params := signature.Params()
 	if params.Len() != 2 {
-		return nil, errors.Errorf("subscription method %s must have exactly two parameters: context.Context and pubsub.Event[T]", fn.Name.Name)
+		return nil, errors.Errorf("worker method %s must have exactly two parameters: context.Context and queue.Job[T]", fn.Name.Name)
 	}
-
-	// Check first parameter is context.Context
-	param := params.At(0)
-	paramType := param.Type()
-	if !isContextType(paramType) {
-		return nil, errors.Errorf("subscription method %s first parameter must be context.Context, got %s", fn.Name.Name, types.TypeString(paramType, nil))
+	if !isContextType(params.At(0).Type()) {
+		return nil, errors.Errorf("worker method %s first parameter must be context.Context, got %s", fn.Name.Name, types.TypeString(params.At(0).Type(), nil))
+	}
+	jobType, ok := params.At(1).Type().(*types.Named)
+	if !ok || jobType.Obj().Pkg() == nil || jobType.Obj().Pkg().Path() != "github.com/alecthomas/zero/providers/queue" ||
+		jobType.Obj().Name() != "Job" || jobType.TypeArgs().Len() != 1 {
+		return nil, errors.Errorf("worker method %s second parameter must be queue.Job[T], got %s", fn.Name.Name, types.TypeString(params.At(1).Type(), nil))
+	}
+	results := signature.Results()
+	if results.Len() != 1 || !isErrorType(results.At(0).Type()) {
+		return nil, errors.Errorf("worker method %s must return error", fn.Name.Name)
 	}
 
-	// Check second parameter is pubsub.Event[T]
-	eventParam := params.At(1)
-	eventType := eventParam.Type()
+	payloadType := jobType.TypeArgs().At(0)
+	queueObj, ok := jobType.Obj().Pkg().Scope().Lookup("Queue").(*types.TypeName)
+	if !ok {
+		return nil, errors.Errorf("worker method %s: queue.Queue not found", fn.Name.Name)
+	}
+	queueType, err := types.Instantiate(nil, queueObj.Type(), []types.Type{payloadType}, false)
+	if err != nil {
+		return nil, errors.Errorf("worker method %s: failed to instantiate queue type: %w", fn.Name.Name, err)
+	}
 
-	// Extract the event type from pubsub.Event[T]
-	payloadType, err := extractEventPayloadType(eventType)
+	backoff, err := directive.BackoffDuration()
 	if err != nil {
-		return nil, errors.Errorf("subscription method %s second parameter must be pubsub.Event[T], got %s: %v", fn.Name.Name, types.TypeString(eventType, nil), err)
+		return nil, errors.Errorf("worker method %s: %w", fn.Name.Name, err)
 	}
+	visibility, err := directive.VisibilityDuration()
+	if err != nil {
+		return nil, errors.Errorf("worker method %s: %w", fn.Name.Name, err)
+	}
+
+	return &Worker{
+		Function:    funcObj,
+		Package:     pkg,
+		Position:    fset.Position(fn.Pos()),
+		PayloadType: payloadType,
+		QueueType:   types.NewPointer(queueType),
+		Concurrency: directive.Concurrency,
+		Attempts:    directive.Attempts,
+		Backoff:     backoff,
+		Visibility:  visibility,
+		Labels:      directive.Labels,
+	}, nil
+}
 
-	// Validate return type is error
+// stepStateType returns the workflow state type S of a step or compensation with the signature
+// func(context.Context, *S) error.
+func stepStateType(signature *types.Signature) (types.Type, error) {
+	params := signature.Params()
+	if params.Len() != 2 {
+		return nil, errors.Errorf("must have exactly two parameters: context.Context and a pointer to the workflow state")
+	}
+	if !isContextType(params.At(0).Type()) {
+		return nil, errors.Errorf("first parameter must be context.Context, got %s", types.TypeString(params.At(0).Type(), nil))
+	}
+	ptr, ok := params.At(1).Type().(*types.Pointer)
+	if !ok {
+		return nil, errors.Errorf("second parameter must be a pointer to the workflow state, got %s", types.TypeString(params.At(1).Type(), nil))
+	}
 	results := signature.Results()
-	if results.Len() != 1 {
-		return nil, errors.Errorf("subscription method %s must return exactly one value of type error", fn.Name.Name)
+	if results.Len() != 1 || !isErrorType(results.At(0).Type()) {
+		return nil, errors.Errorf("must return error")
 	}
+	return ptr.Elem(), nil
+}
 
-	returnType := results.At(0).Type()
-	if !isErrorType(returnType) {
-		return nil, errors.Errorf("subscription method %s must return error, got %s", fn.Name.Name, types.TypeString(returnType, nil))
+// checkStepState returns an error if a step's workflow state type differs from that of the other steps of its receiver.
+func checkStepState(existing []*Step, step *Step) error {
+	if step == nil {
+		return nil
 	}
+	receiver := step.Function.Signature().Recv().Type()
+	for _, other := range existing {
+		if types.Identical(other.Function.Signature().Recv().Type(), receiver) && !types.Identical(other.StateType, step.StateType) {
+			return errors.Errorf("%s: %s: workflow state *%s differs from *%s of step %s", step.Position, step.Function.Name(),
+				types.TypeString(step.StateType, nil), types.TypeString(other.StateType, nil), other.Function.FullName())
+		}
+	}
+	return nil
+}
 
-	return &Subscription{
-		Function:  funcObj,
-		Package:   pkg,
-		Position:  fset.Position(fn.Pos()),
-		TopicType: payloadType,
-	}, nil
+// checkSubscriptionVersion returns an error if a versioned subscription's version is already subscribed to on the
+// same topic.
+func checkSubscriptionVersion(existing []*Subscription, subscription *Subscription) error {
+	if subscription == nil || subscription.Version == nil {
+		return nil
+	}
+	for _, other := range existing {
+		if other.Version != nil && *other.Version == *subscription.Version && types.Identical(other.TopicType, subscription.TopicType) {
+			return errors.Errorf("%s: %s: version %d of %s is already subscribed to by %s", subscription.Position,
+				subscription.Function.Name(), *subscription.Version, types.TypeString(subscription.TopicType, nil), other.Function.FullName())
+		}
+	}
+	return nil
 }
 
 // checkReceiverDependency checks if a function's receiver type is provided and adds it to missing if not
@@ -1388,7 +3053,11 @@ func createMiddleware(fn *ast.FuncDecl, pkg *packages.Package, directive *direct
 	// 1. func(http.Handler) http.Handler - direct middleware
 	// 2. func(...deps) func(http.Handler) http.Handler - middleware factory
 	// 3. func(...deps) zero.Middleware - middleware factory returning zero.Middleware type
+	// Or the equivalent job middleware, wrapping zero.JobFunc rather than http.Handler.
 
+	if isValidJobMiddlewareSignature(signature) {
+		return createJobMiddleware(fn, funcObj, pkg, directive, fset)
+	}
 	if !isValidMiddlewareSignature(signature) {
 		return nil, errors.Errorf("invalid middleware function signature for %s: must be func(http.Handler) http.Handler or func(...deps) func(http.Handler) http.Handler", fn.Name.Name)
 	}
@@ -1410,7 +3079,10 @@ func createMiddleware(fn *ast.FuncDecl, pkg *packages.Package, directive *direct
 			paramName := param.Name()
 
 			// String/int parameters must be labels
-			if isStringOrIntType(paramType) {
+			if IsRedactedFieldsType(paramType) {
+				// Passed the redacted fields of each API
+				continue
+			} else if isStringOrIntType(paramType) {
 				if !labelNames[paramName] {
 					return nil, errors.Errorf("parameter %s of type %s in middleware %s must match a label name", paramName, paramType.String(), fn.Name.Name)
 				}
@@ -1433,6 +3105,58 @@ func createMiddleware(fn *ast.FuncDecl, pkg *packages.Package, directive *direct
 	return middleware, nil
 }
 
+func createJobMiddleware(fn *ast.FuncDecl, funcObj *types.Func, pkg *packages.Package, directive *directiveparser.DirectiveMiddleware, fset *token.FileSet) (*Middleware, error) {
+	direct := isJobMiddleware(funcObj.Signature())
+	var requires []types.Type
+	if !direct {
+		params := funcObj.Signature().Params()
+		for i := range params.Len() {
+			param := params.At(i)
+			// Cron and subscribe labels have no values to pass to the middleware
+			if isStringOrIntType(param.Type()) {
+				return nil, errors.Errorf("parameter %s of type %s in job middleware %s must be a dependency", param.Name(), param.Type().String(), fn.Name.Name)
+			}
+			requires = append(requires, param.Type())
+		}
+	}
+	return &Middleware{
+		Position:  fset.Position(fn.Pos()),
+		Directive: directive,
+		Function:  funcObj,
+		Package:   pkg,
+		Requires:  requires,
+		Factory:   !direct,
+		Job:       true,
+	}, nil
+}
+
+// isValidJobMiddlewareSignature returns true for func(zero.JobFunc) zero.JobFunc, or a factory returning it or
+// zero.JobMiddleware.
+func isValidJobMiddlewareSignature(sig *types.Signature) bool {
+	if isJobMiddleware(sig) {
+		return true
+	}
+	if sig.Results().Len() != 1 {
+		return false
+	}
+	returnType := sig.Results().At(0).Type()
+	if isZeroType(returnType, "JobMiddleware") {
+		return true
+	}
+	result, ok := returnType.(*types.Signature)
+	return ok && isJobMiddleware(result)
+}
+
+func isJobMiddleware(sig *types.Signature) bool {
+	return sig.Params().Len() == 1 && isZeroType(sig.Params().At(0).Type(), "JobFunc") &&
+		sig.Results().Len() == 1 && isZeroType(sig.Results().At(0).Type(), "JobFunc")
+}
+
+func isZeroType(t types.Type, name string) bool {
+	named, ok := t.(*types.Named)
+	return ok && named.Obj().Name() == name && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == "github.com/alecthomas/zero"
+}
+
 func isValidMiddlewareSignature(sig *types.Signature) bool {
 	results := sig.Results()
 
@@ -1504,6 +3228,11 @@ func isValidAPIParameterType(paramType types.Type, paramName string, directive *
 		return directive.Wildcard(paramName)
 	}
 
+	// Pagination is always decoded from query parameters, so may be combined with a request struct
+	if isPageRequestType(paramType) {
+		return true
+	}
+
 	// Check if it's a struct type (for request body/query parameters)
 	if isBodyParameterStruct(paramType) {
 		*bodyParamCount++
@@ -1538,7 +3267,7 @@ func isStandardHTTPType(t types.Type) bool {
 		}
 
 		// Check for io.Reader
-		if obj.Name() == "Reader" && obj.Pkg().Path() == "io" {
+		if isReaderType(t) {
 			return true
 		}
 	}
@@ -1546,6 +3275,16 @@ func isStandardHTTPType(t types.Type) bool {
 	return false
 }
 
+// isReaderType returns true if t is io.Reader, into which the request body is streamed.
+func isReaderType(t types.Type) bool {
+	named, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	return obj.Name() == "Reader" && obj.Pkg() != nil && obj.Pkg().Path() == "io"
+}
+
 func isStringOrIntType(t types.Type) bool {
 	if basic, ok := t.(*types.Basic); ok {
 		return basic.Kind() == types.String ||
@@ -1604,6 +3343,56 @@ func hasMethod(t types.Type, methodName string) bool {
 	return false
 }
 
+// IsRedactedFieldsType returns true if t is zero.RedactedFields.
+func IsRedactedFieldsType(t types.Type) bool {
+	return types.TypeString(t, nil) == "github.com/alecthomas/zero.RedactedFields"
+}
+
+// isPageRequestType returns true if t is zero.PageRequest.
+func isPageRequestType(t types.Type) bool {
+	return types.TypeString(t, nil) == "github.com/alecthomas/zero.PageRequest"
+}
+
+// isPageType returns true if t is zero.Page[T] or a pointer to it.
+func isPageType(t types.Type) bool {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Origin().Obj()
+	return obj.Name() == "Page" && obj.Pkg() != nil && obj.Pkg().Path() == "github.com/alecthomas/zero"
+}
+
+// pageRequestParameters returns the query parameters decoded into a zero.PageRequest.
+func pageRequestParameters() []spec.Parameter {
+	minimum, maximum := float64(1), float64(zero.MaxPageLimit)
+	return []spec.Parameter{
+		{
+			ParamProps: spec.ParamProps{
+				Name:        "limit",
+				In:          "query",
+				Description: "Maximum number of items to return.",
+			},
+			SimpleSchema: spec.SimpleSchema{Type: "integer", Default: zero.DefaultPageLimit},
+			CommonValidations: spec.CommonValidations{
+				Minimum: &minimum,
+				Maximum: &maximum,
+			},
+		},
+		{
+			ParamProps: spec.ParamProps{
+				Name:        "cursor",
+				In:          "query",
+				Description: "Cursor returned in nextCursor of the previous page.",
+			},
+			SimpleSchema: spec.SimpleSchema{Type: "string"},
+		},
+	}
+}
+
 func isBodyParameterStruct(t types.Type) bool {
 	// Handle pointer to struct
 	if ptr, ok := t.(*types.Pointer); ok {
@@ -1629,6 +3418,66 @@ func isBodyParameterStruct(t types.Type) bool {
 	return false
 }
 
+// isStatusType returns true if t is an int or zero.Status, as returned by an API before its response.
+func isStatusType(t types.Type) bool {
+	if isZeroType(t, "Status") {
+		return true
+	}
+	basic, ok := t.(*types.Basic)
+	return ok && basic.Kind() == types.Int
+}
+
+// responseType returns the type of the response returned by an API, or nil if it only returns an error or nothing.
+func responseType(signature *types.Signature) types.Type {
+	results := signature.Results()
+	switch results.Len() {
+	case 1:
+		if isErrorType(results.At(0).Type()) {
+			return nil
+		}
+		return results.At(0).Type()
+	case 2:
+		return results.At(0).Type()
+	case 3:
+		return results.At(1).Type()
+	default:
+		return nil
+	}
+}
+
+// returnedStatuses returns the sorted success status codes of the return statements of an API returning
+// (status, response, error). Statuses of return statements with a non-nil error are ignored.
+func returnedStatuses(fn *ast.FuncDecl, pkg *packages.Package) []int {
+	if fn.Type.Results == nil || fn.Type.Results.NumFields() != 3 || fn.Body == nil {
+		return nil
+	}
+	statuses := map[int]bool{}
+	ast.Inspect(fn.Body, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.ReturnStmt:
+			if len(node.Results) != 3 {
+				statuses[http.StatusOK] = true
+				return false
+			}
+			if tv, ok := pkg.TypesInfo.Types[node.Results[2]]; !ok || !tv.IsNil() {
+				return false
+			}
+			status := http.StatusOK
+			if tv, ok := pkg.TypesInfo.Types[node.Results[0]]; ok && tv.Value != nil {
+				if value, ok := constant.Int64Val(tv.Value); ok && value != 0 {
+					status = int(value)
+				}
+			}
+			statuses[status] = true
+			return false
+		}
+		return true
+	})
+	return slices.Sorted(maps.Keys(statuses))
+}
+
 func isErrorType(t types.Type) bool {
 	named, ok := t.(*types.Named)
 	if !ok {
@@ -1637,6 +3486,43 @@ func isErrorType(t types.Type) bool {
 	return named.Obj().Name() == "error" && named.Obj().Pkg() == nil
 }
 
+// OptionalType returns T if t is zero.Optional[T].
+func OptionalType(t types.Type) (types.Type, bool) {
+	return zeroWrapperType(t, "Optional")
+}
+
+// LazyType returns T if t is zero.Lazy[T].
+func LazyType(t types.Type) (types.Type, bool) {
+	return zeroWrapperType(t, "Lazy")
+}
+
+// zeroWrapperType returns T if t is the single-parameter generic type zero.<name>[T].
+func zeroWrapperType(t types.Type, name string) (types.Type, bool) {
+	named, ok := t.(*types.Named)
+	if !ok {
+		return nil, false
+	}
+	obj := named.Obj()
+	if obj.Name() != name || obj.Pkg() == nil || obj.Pkg().Path() != "github.com/alecthomas/zero" {
+		return nil, false
+	}
+	if named.TypeArgs().Len() != 1 {
+		return nil, false
+	}
+	return named.TypeArgs().At(0), true
+}
+
+// UnwrapDependency returns T if t is zero.Optional[T] or zero.Lazy[T], or t otherwise.
+func UnwrapDependency(t types.Type) types.Type {
+	if elem, ok := OptionalType(t); ok {
+		return elem
+	}
+	if elem, ok := LazyType(t); ok {
+		return elem
+	}
+	return t
+}
+
 func isContextType(t types.Type) bool {
 	named, ok := t.(*types.Named)
 	if !ok {
@@ -1657,10 +3543,23 @@ func findMissingDependencies(graph *Graph) {
 	for key := range graph.Configs {
 		provided[key] = true
 	}
+	for key := range graph.Groups {
+		provided[key] = true
+	}
 
-	for _, providers := range graph.Providers {
+	for key, providers := range graph.Providers {
 		for _, provider := range providers {
+			// Uninstantiated generic providers are only stored for lookup, and only their instantiations are
+			// constructed.
+			if provider.IsGeneric && key == getBaseTypeName(provider.Provides) {
+				continue
+			}
 			for _, required := range provider.Requires {
+				// Optional dependencies are never missing
+				if _, ok := OptionalType(required); ok {
+					continue
+				}
+				required = UnwrapDependency(required)
 				key := types.TypeString(required, nil)
 				if !provided[key] && !isProvidedByConfig(required, graph) && !canBeProvidedByGeneric(required, graph) {
 					// Check for duplicates before adding
@@ -1685,9 +3584,12 @@ func findMissingDependencies(graph *Graph) {
 		checkReceiverDependency(api.Function, provided, graph)
 	}
 
-	// Check receiver types for CronJobs
+	// Check receiver types and parameter sets for CronJobs
 	for _, cron := range graph.CronJobs {
 		checkReceiverDependency(cron.Function, provided, graph)
+		if cron.Params != nil && !provided[types.TypeString(cron.Params, nil)] {
+			graph.Missing[cron.Function] = append(graph.Missing[cron.Function], cron.Params)
+		}
 	}
 
 	// Check receiver types for Subscriptions
@@ -1695,6 +3597,16 @@ func findMissingDependencies(graph *Graph) {
 		checkReceiverDependency(subscription.Function, provided, graph)
 	}
 
+	// Check receiver types for workflow Steps
+	for _, step := range graph.Steps {
+		checkReceiverDependency(step.Function, provided, graph)
+	}
+
+	// Check receiver types for queue Workers
+	for _, worker := range graph.Workers {
+		checkReceiverDependency(worker.Function, provided, graph)
+	}
+
 	// Check middleware dependencies
 	for _, middleware := range graph.Middleware {
 		for _, required := range middleware.Requires {
@@ -1794,6 +3706,9 @@ func canBeProvidedByGenericConfig(requiredType types.Type, graph *Graph) bool {
 	}
 
 	// For generic types, we need to check if the type arguments satisfy the constraints
+	if ptr, ok := requiredType.(*types.Pointer); ok {
+		requiredType = ptr.Elem()
+	}
 	namedType, ok := requiredType.(*types.Named)
 	if !ok {
 		return false
@@ -1828,15 +3743,9 @@ func importPathForDir(dir string) (string, error) {
 		return "", errors.Errorf("failed to get absolute path for directory %s: %w", dir, err)
 	}
 	dir = root
-	// Search up directories for go.mod file
-	for {
-		if _, err := os.Stat(filepath.Join(root, "go.mod")); err == nil {
-			break
-		}
-		if root == "/" {
-			return "", errors.Errorf("couldn't find a go.mod file above %s", dir)
-		}
-		root = filepath.Dir(root)
+	root = findUp(dir, "go.mod")
+	if root == "" {
+		return "", errors.Errorf("couldn't find a go.mod file above %s", dir)
 	}
 	dir, err = filepath.Rel(root, dir)
 	if err != nil {
@@ -1857,10 +3766,25 @@ func importPathForDir(dir string) (string, error) {
 // Types used internally by Zero's generated API handling code.
 var internalAPITypes = []string{
 	"*github.com/alecthomas/zero/providers/dashboard.Dashboard",
+	"github.com/alecthomas/zero.ContentEncodings",
 	"github.com/alecthomas/zero.ErrorEncoder",
 	"github.com/alecthomas/zero.ResponseEncoder",
 }
 
+// libraryRoots returns the types provided by the non-generic providers declared in the package, sorted.
+func libraryRoots(providers map[string][]*Provider, pkgPath string) []string {
+	roots := []string{}
+	for key, candidates := range providers {
+		if slices.ContainsFunc(candidates, func(provider *Provider) bool {
+			return !provider.IsGeneric && provider.Package.PkgPath == pkgPath
+		}) {
+			roots = append(roots, key)
+		}
+	}
+	slices.Sort(roots)
+	return roots
+}
+
 // pruneUnreferencedTypes removes providers and configs that are not transitively referenced from the given roots
 func pruneUnreferencedTypes(graph *Graph, roots []string, providers map[string][]*Provider, pick []string, excludedProviders map[string]bool) error {
 	referenced := map[string]bool{}
@@ -1903,6 +3827,14 @@ func initializeToProcess(graph *Graph, roots []string) []string {
 			toProcess = append(toProcess, eventTypeStr)
 		}
 	}
+	for _, worker := range graph.Workers {
+		toProcess = append(toProcess, types.TypeString(worker.QueueType, nil))
+	}
+	for _, cron := range graph.CronJobs {
+		if cron.Params != nil {
+			toProcess = append(toProcess, types.TypeString(cron.Params, nil))
+		}
+	}
 	return toProcess
 }
 
@@ -1982,7 +3914,7 @@ func buildFuncToProviderMapping(providers map[string][]*Provider) map[string]*Pr
 	funcNameToProvider := map[string]*Provider{}
 	for _, providerList := range providers {
 		for _, p := range providerList {
-			funcKey := p.Package.PkgPath + "." + p.Function.Name()
+			funcKey := p.FullName()
 			funcNameToProvider[funcKey] = p
 		}
 	}
@@ -1998,7 +3930,7 @@ func processDirectiveRequirements(providers map[string][]*Provider, funcNameToPr
 				if _, exists := funcNameToProvider[requiredFuncKey]; exists {
 					explicitlyRequired[requiredFuncKey] = true
 				} else {
-					return nil, errors.Errorf("provider %s requires %s, but it is not a valid provider function", p.Function.FullName(), requiredFuncName)
+					return nil, errors.Errorf("provider %s requires %s, but it is not a valid provider function", p.FullName(), requiredFuncName)
 				}
 			}
 		}
@@ -2024,7 +3956,11 @@ func processReferencedTypes(graph *Graph, providers map[string][]*Provider, pick
 			continue
 		}
 		referenced[current] = true
-		if providerList, exists := providers[current]; exists {
+		if group, exists := graph.Groups[current]; exists {
+			for _, member := range group.Members {
+				addRequirementsToProcess([]types.Type{member.Provides}, referenced, toProcess)
+			}
+		} else if providerList, exists := providers[current]; exists {
 			processExistingProviders(graph, current, providerList, pick, referenced, toProcess, funcNameToProvider, explicitlyRequired, ambiguousProviders, excludedProviders)
 		} else {
 			processGenericProviders(graph, current, pick, referenced, toProcess, funcNameToProvider, ambiguousProviders, excludedProviders)
@@ -2044,7 +3980,7 @@ func processExistingProviders(graph *Graph, current string, providers []*Provide
 func processMultiProviders(graph *Graph, current string, providers []*Provider, referenced map[string]bool, toProcess *[]string, funcNameToProvider map[string]*Provider, explicitlyRequired map[string]bool, excludedProviders map[string]bool) {
 	var includedProviders []*Provider
 	for _, p := range providers {
-		funcKey := p.Function.FullName()
+		funcKey := p.FullName()
 		// Skip excluded providers
 		if excludedProviders[funcKey] {
 			continue
@@ -2069,7 +4005,7 @@ func processSingleProvider(graph *Graph, current string, providers []*Provider,
 	// Filter out excluded providers
 	var filteredProviders []*Provider
 	for _, p := range providers {
-		if !excludedProviders[p.Function.FullName()] {
+		if !excludedProviders[p.FullName()] {
 			filteredProviders = append(filteredProviders, p)
 		}
 	}
@@ -2079,7 +4015,7 @@ func processSingleProvider(graph *Graph, current string, providers []*Provider,
 		return
 	}
 
-	provider := pickProvider(filteredProviders, pick)
+	provider := pickProvider(filteredProviders, picksForType(pick, current))
 	if provider == nil {
 		ambiguousProviders[current] = filteredProviders
 	} else {
@@ -2109,6 +4045,7 @@ func findConcreteType(graph *Graph, current string) types.Type {
 	for _, providers := range graph.Providers {
 		for _, provider := range providers {
 			for _, req := range provider.Requires {
+				req = UnwrapDependency(req)
 				if types.TypeString(req, nil) == current {
 					return req
 				}
@@ -2134,6 +4071,13 @@ func findConcreteType(graph *Graph, current string) types.Type {
 			}
 		}
 	}
+
+	// Check queues processed by workers
+	for _, worker := range graph.Workers {
+		if types.TypeString(worker.QueueType, nil) == current {
+			return worker.QueueType
+		}
+	}
 	return nil
 }
 
@@ -2155,7 +4099,7 @@ func checkAmbiguousGenericProviders(current string, concreteType types.Type, gra
 		var validProviders []*Provider
 		for _, genericProvider := range genericProviders {
 			// Skip excluded providers
-			if excludedProviders[genericProvider.Function.FullName()] {
+			if excludedProviders[genericProvider.FullName()] {
 				continue
 			}
 			if canProvideConcreteTypeWithConstraints(concreteType, genericProvider) {
@@ -2170,7 +4114,7 @@ func checkAmbiguousGenericProviders(current string, concreteType types.Type, gra
 
 func addRequirementsToProcess(requires []types.Type, referenced map[string]bool, toProcess *[]string) {
 	for _, req := range requires {
-		reqKey := types.TypeString(req, nil)
+		reqKey := types.TypeString(UnwrapDependency(req), nil)
 		if !referenced[reqKey] {
 			*toProcess = append(*toProcess, reqKey)
 		}
@@ -2196,14 +4140,11 @@ func checkAmbiguousProviders(ambiguousProviders map[string][]*Provider, referenc
 		}
 	}
 
-	for key, providers := range ambiguousProviders {
-		var providerKeys []string
-		for _, provider := range providers {
-			providerKeys = append(providerKeys, provider.Function.FullName())
-		}
-		return fmt.Errorf("ambiguous providers for type %s: %s", key, strings.Join(providerKeys, ", "))
+	var errs []error
+	for _, key := range slices.Sorted(maps.Keys(ambiguousProviders)) {
+		errs = append(errs, newAmbiguousProvidersError(key, ambiguousProviders[key]))
 	}
-	return nil
+	return errors.Join(errs...)
 }
 
 func cleanupUnreferencedResources(graph *Graph, providers map[string][]*Provider, referenced map[string]bool) {
@@ -2220,6 +4161,12 @@ func cleanupUnreferencedResources(graph *Graph, providers map[string][]*Provider
 		}
 	}
 
+	for key := range graph.Groups {
+		if !referenced[key] {
+			delete(graph.Groups, key)
+		}
+	}
+
 	// Remove unreferenced configs
 	for key := range graph.Configs {
 		if !isConfigReferenced(key, referenced) {
@@ -2241,11 +4188,31 @@ func cleanupUnreferencedResources(graph *Graph, providers map[string][]*Provider
 		}
 	}
 
-	// Remove unused middleware
+	// Remove unused middleware, retaining job middleware applied to cron jobs or subscribers
+	var jobMiddleware []*Middleware
+	graph.Middleware = slices.DeleteFunc(graph.Middleware, func(mw *Middleware) bool {
+		if mw.Job && graph.usesJobMiddleware(mw) {
+			jobMiddleware = append(jobMiddleware, mw)
+		}
+		return mw.Job
+	})
 	if len(graph.APIs) > 0 {
 		usedLabels := collectUsedLabels(graph.APIs)
 		graph.Middleware = filterMiddleware(graph.Middleware, usedLabels)
+	} else {
+		// Labelled middleware from other packages, such as Zero's own providers, is only ever applied to APIs.
+		graph.Middleware = slices.DeleteFunc(graph.Middleware, func(mw *Middleware) bool {
+			return len(mw.Directive.Labels) > 0 && mw.Package.Types != graph.Dest
+		})
 	}
+	graph.Middleware = append(graph.Middleware, jobMiddleware...)
+}
+
+// usesJobMiddleware returns true if the middleware applies to any cron job, subscriber or worker.
+func (g *Graph) usesJobMiddleware(mw *Middleware) bool {
+	return slices.ContainsFunc(g.CronJobs, func(cron *CronJob) bool { return mw.MatchJob(cron.Schedule.Labels) }) ||
+		slices.ContainsFunc(g.Subscriptions, func(subscription *Subscription) bool { return mw.MatchJob(subscription.Labels) }) ||
+		slices.ContainsFunc(g.Workers, func(worker *Worker) bool { return mw.MatchJob(worker.Labels) })
 }
 
 func collectUsedLabels(apis []*API) map[string]bool {
@@ -2283,6 +4250,39 @@ func isConfigReferenced(configKey string, referenced map[string]bool) bool {
 	return referenced[configKey] || referenced["*"+configKey]
 }
 
+// picksForType returns the picks applicable to the given type key.
+//
+// A pick may be scoped to a single type, eg. a generic instantiation, with "<type>=<provider>", in which case it takes
+// precedence over unscoped picks for that type and is ignored for all other types.
+func picksForType(pick []string, typeKey string) []string {
+	var scoped, unscoped []string
+	for _, item := range pick {
+		if typ, provider, ok := strings.Cut(item, "="); ok {
+			if typ == typeKey {
+				scoped = append(scoped, provider)
+			}
+			continue
+		}
+		unscoped = append(unscoped, item)
+	}
+	if len(scoped) > 0 {
+		return scoped
+	}
+	return unscoped
+}
+
+// pickedProviders returns the provider names from picks, stripping any type scope.
+func pickedProviders(pick []string) []string {
+	out := make([]string, len(pick))
+	for i, item := range pick {
+		if _, provider, ok := strings.Cut(item, "="); ok {
+			item = provider
+		}
+		out[i] = item
+	}
+	return out
+}
+
 // Picks a single provider from a list of providers.
 //
 // Disambiguates through two mechanisms:
@@ -2305,7 +4305,7 @@ func pickProvider(providers []*Provider, pick []string) *Provider {
 		if !provider.Directive.Weak {
 			strong = append(strong, provider)
 		}
-		key := provider.Function.FullName()
+		key := provider.FullName()
 		if slices.Contains(pick, key) {
 			return provider
 		}
@@ -2339,10 +4339,7 @@ func validateMultiProviderConstraints(typeKey string, providers []*Provider) err
 		var nonMultiProviders []string
 
 		for _, provider := range providers {
-			funcName := "unknown"
-			if provider.Function != nil {
-				funcName = provider.Function.FullName()
-			}
+			funcName := provider.FullName()
 
 			if provider.Directive.Multi {
 				multiProviders = append(multiProviders, funcName)
@@ -2416,6 +4413,9 @@ func canBeProvidedByGeneric(requiredType types.Type, graph *Graph) bool { //noli
 	}
 
 	// For generic types, we need to check if the type arguments satisfy the constraints
+	if ptr, ok := requiredType.(*types.Pointer); ok {
+		requiredType = ptr.Elem()
+	}
 	namedType, ok := requiredType.(*types.Named)
 	if !ok {
 		return false
@@ -2445,6 +4445,7 @@ func canBeProvidedByGeneric(requiredType types.Type, graph *Graph) bool { //noli
 // resolveGenericProviderWithType finds a suitable generic provider for a concrete type,
 // applying the same weak provider logic as regular providers
 func resolveGenericProviderWithType(graph *Graph, concreteType types.Type, pick []string, excludedProviders map[string]bool) *Provider {
+	pick = picksForType(pick, types.TypeString(concreteType, nil))
 	baseType := getBaseTypeName(concreteType)
 	genericProviders, exists := graph.Providers[baseType]
 	if !exists || len(genericProviders) == 0 {
@@ -2455,7 +4456,7 @@ func resolveGenericProviderWithType(graph *Graph, concreteType types.Type, pick
 	validProviders := make([]*Provider, 0)
 	for _, genericProvider := range genericProviders {
 		// Skip excluded providers
-		if excludedProviders != nil && excludedProviders[genericProvider.Function.FullName()] {
+		if excludedProviders != nil && excludedProviders[genericProvider.FullName()] {
 			continue
 		}
 		if canProvideConcreteTypeWithConstraints(concreteType, genericProvider) {
@@ -2474,7 +4475,7 @@ func resolveGenericProviderWithType(graph *Graph, concreteType types.Type, pick
 	} else {
 		// Check for explicit picks first - if something is explicitly picked, use it
 		for _, provider := range validProviders {
-			key := provider.Function.FullName()
+			key := provider.FullName()
 			if slices.Contains(pick, key) {
 				selectedGenericProvider = provider
 				break
@@ -2521,14 +4522,16 @@ func resolveGenericProviderWithType(graph *Graph, concreteType types.Type, pick
 	}
 
 	concreteProvider := &Provider{
-		Position:   selectedGenericProvider.Position,
-		Directive:  selectedGenericProvider.Directive,
-		Function:   selectedGenericProvider.Function,
-		Package:    selectedGenericProvider.Package,
-		Provides:   concreteType,
-		Requires:   resolvedRequires,
-		IsGeneric:  true, // Keep this flag to indicate it needs type instantiation
-		TypeParams: selectedGenericProvider.TypeParams,
+		Position:      selectedGenericProvider.Position,
+		Directive:     selectedGenericProvider.Directive,
+		Function:      selectedGenericProvider.Function,
+		Package:       selectedGenericProvider.Package,
+		Module:        selectedGenericProvider.Module,
+		Documentation: selectedGenericProvider.Documentation,
+		Provides:      concreteType,
+		Requires:      resolvedRequires,
+		IsGeneric:     true, // Keep this flag to indicate it needs type instantiation
+		TypeParams:    selectedGenericProvider.TypeParams,
 	}
 
 	return concreteProvider
@@ -2549,7 +4552,10 @@ func canProvideConcreteTypeWithConstraints(concreteType types.Type, genericProvi
 		return false
 	}
 
-	// Extract type arguments from the concrete type
+	// Extract type arguments from the concrete type, eg. *Queue[T] from a provider returning a pointer
+	if ptr, ok := concreteType.(*types.Pointer); ok {
+		concreteType = ptr.Elem()
+	}
 	namedType, ok := concreteType.(*types.Named)
 	if !ok {
 		return false
@@ -2774,6 +4780,9 @@ func checkForMissingRoots(graph *Graph, roots []string) error {
 		config := configs[0]
 		collected[normaliseType(config.Type)] = true
 	}
+	for key := range graph.Groups {
+		collected[key] = true
+	}
 	for key, providers := range graph.Providers {
 		for _, provider := range providers {
 			collected[normaliseType(provider.Provides)] = true
@@ -2785,13 +4794,105 @@ func checkForMissingRoots(graph *Graph, roots []string) error {
 	}
 
 	for _, root := range roots {
-		if !collected[root] {
-			return fmt.Errorf("requested root %q not found in discovered provided types: %s", root, strings.Join(slices.Collect(maps.Keys(collected)), ", "))
+		if collected[root] {
+			continue
+		}
+		// Roots inferred from receivers are most commonly missing because the receiver is the pointer form of a
+		// provided value type, or vice versa, so suggest a fix.
+		if value, ok := strings.CutPrefix(root, "*"); ok && len(graph.candidates[value]) > 0 {
+			return fmt.Errorf("requested root %q not found, but %s is provided, return %s from its provider or use a value receiver", root, value, root)
+		}
+		if len(graph.candidates["*"+root]) > 0 {
+			return fmt.Errorf("requested root %q not found, but *%s is provided, use a pointer receiver or provide %s", root, root, root)
 		}
+		return fmt.Errorf("requested root %q not found in discovered provided types: %s", root, strings.Join(slices.Collect(maps.Keys(collected)), ", "))
 	}
 	return nil
 }
 
+// dependencyEdges returns the types each provided type or group depends on when constructed, excluding lazy
+// dependencies, which are constructed on first use.
+func dependencyEdges(graph *Graph) map[string][]string {
+	edges := map[string][]string{}
+	for key, providers := range graph.Providers {
+		for _, provider := range providers {
+			for _, req := range provider.Requires {
+				if _, ok := LazyType(req); ok {
+					continue
+				}
+				edges[key] = append(edges[key], types.TypeString(UnwrapDependency(req), nil))
+			}
+		}
+	}
+	for key, group := range graph.Groups {
+		for _, member := range group.Members {
+			edges[key] = append(edges[key], types.TypeString(member.Provides, nil))
+		}
+	}
+	return edges
+}
+
+// checkForCycles returns an error describing the full path of the first dependency cycle found between providers,
+// as the generated constructors would otherwise recurse infinitely. Cycles may be broken by injecting zero.Lazy[T].
+func checkForCycles(graph *Graph) error {
+	edges := dependencyEdges(graph)
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := map[string]int{}
+	var path []string
+	var visit func(key string) error
+	visit = func(key string) error {
+		switch state[key] {
+		case visited:
+			return nil
+		case visiting:
+			start := slices.Index(path, key)
+			return errors.Errorf("dependency cycle detected: %s", describeCycle(graph, append(path[start:], key)))
+		}
+		state[key] = visiting
+		path = append(path, key)
+		for _, dep := range edges[key] {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[key] = visited
+		return nil
+	}
+	for _, key := range slices.Sorted(maps.Keys(edges)) {
+		if err := visit(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// describeCycle formats a cycle of type keys along with the providers that construct them, eg.
+//
+//	*test.A (test.NewA at a.go:10:1) -> *test.B (test.NewB at b.go:5:1) -> *test.A
+func describeCycle(graph *Graph, cycle []string) string {
+	parts := make([]string, len(cycle))
+	for i, key := range cycle {
+		parts[i] = key
+		if i == len(cycle)-1 {
+			break
+		}
+		if providers := graph.Providers[key]; len(providers) > 0 {
+			names := make([]string, len(providers))
+			for j, provider := range providers {
+				names[j] = fmt.Sprintf("%s at %s", provider.FullName(), provider.Position)
+			}
+			parts[i] += " (" + strings.Join(names, ", ") + ")"
+		}
+	}
+	return strings.Join(parts, " -> ")
+}
+
 func checkForMissingProviders(graph *Graph, pick []string) error {
 	if len(pick) == 0 {
 		return nil
@@ -2801,11 +4902,11 @@ func checkForMissingProviders(graph *Graph, pick []string) error {
 	// Collect all provider function names
 	for _, providers := range graph.Providers {
 		for _, provider := range providers {
-			collected[provider.Function.FullName()] = true
+			collected[provider.FullName()] = true
 		}
 	}
 
-	for _, providerRef := range pick {
+	for _, providerRef := range pickedProviders(pick) {
 		if !collected[providerRef] {
 			return fmt.Errorf("requested provider %q not found in discovered provider functions: %s", providerRef, strings.Join(slices.Collect(maps.Keys(collected)), ", "))
 		}
@@ -2830,7 +4931,7 @@ func pruneWeakProviderAPIs(graph *Graph, providers map[string][]*Provider, pick
 	// First pass: Mark non-weak providers as active
 	for _, providerList := range providers {
 		for _, provider := range providerList {
-			funcKey := provider.Function.FullName()
+			funcKey := provider.FullName()
 			if !provider.Directive.Weak {
 				activeProviders[funcKey] = true
 			}
@@ -2838,7 +4939,7 @@ func pruneWeakProviderAPIs(graph *Graph, providers map[string][]*Provider, pick
 	}
 
 	// Add explicitly picked providers as active
-	for _, pickItem := range pick {
+	for _, pickItem := range pickedProviders(pick) {
 		activeProviders[pickItem] = true
 	}
 
@@ -2866,7 +4967,7 @@ func pruneWeakProviderAPIs(graph *Graph, providers map[string][]*Provider, pick
 		// Check if any of the Components providers are active
 		hasActiveComponentProvider := false
 		for _, provider := range componentProviders {
-			if activeProviders[provider.Function.FullName()] {
+			if activeProviders[provider.FullName()] {
 				hasActiveComponentProvider = true
 				break
 			}
@@ -2896,14 +4997,14 @@ func pruneWeakProviderAPIs(graph *Graph, providers map[string][]*Provider, pick
 			if providerList, exists := providers[receiverTypeStr]; exists {
 				// Track all providers for this API
 				for _, provider := range providerList {
-					funcKey := provider.Function.FullName()
+					funcKey := provider.FullName()
 					apiProviders = append(apiProviders, funcKey)
 					providerAPICounts[funcKey]++
 				}
 
 				// Keep the API if at least one provider for its receiver type is active
 				for _, provider := range providerList {
-					funcKey := provider.Function.FullName()
+					funcKey := provider.FullName()
 					if activeProviders[funcKey] {
 						shouldKeep = true
 						break
@@ -2973,6 +5074,9 @@ func normaliseType(t types.Type) string {
 	case *types.Slice:
 		return "[]" + normaliseType(t.Elem())
 
+	case *types.Signature:
+		return types.TypeString(t, nil)
+
 	default:
 		panic(fmt.Sprintf("unknown type %T", t))
 	}
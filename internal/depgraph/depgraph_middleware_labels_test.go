@@ -288,3 +288,146 @@ func CacheMiddleware(wrongName int) func(http.Handler) http.Handler {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "parameter wrongName of type int in middleware CacheMiddleware must match a label name")
 }
+
+func TestMiddlewareExclusion(t *testing.T) {
+	t.Parallel()
+	testCode := `
+package main
+
+import "net/http"
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service { return &Service{} }
+
+//zero:middleware
+func Logging(next http.Handler) http.Handler { return next }
+
+//zero:middleware
+func Metrics(next http.Handler) http.Handler { return next }
+
+//zero:middleware authenticated
+func Auth(next http.Handler) http.Handler { return next }
+
+//zero:api GET /users authenticated
+func (s *Service) Users() {}
+
+//zero:api GET /healthz no-middleware
+func (s *Service) Health() {}
+
+//zero:api GET /metrics skip=Logging
+func (s *Service) Metrics() {}
+
+//zero:api GET /admin authenticated no-middleware skip=test.Auth
+func (s *Service) Admin() {}
+`
+	graph := analyseTestCode(t, testCode)
+	applied := map[string][]string{}
+	for _, route := range graph.Routes() {
+		applied[route.Pattern] = route.Middleware
+	}
+	assert.Equal(t, map[string][]string{
+		"/users":   {"test.Logging", "test.Metrics", "test.Auth"},
+		"/healthz": nil,
+		"/metrics": {"test.Metrics"},
+		"/admin":   nil,
+	}, applied)
+
+	_, err := analyseTestCodeWithError(t, testCode+`
+//zero:api GET /other skip=Tracing
+func (s *Service) Other() {}
+`)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "main.go:33:1: skip=Tracing does not match any middleware")
+}
+
+func TestJobMiddleware(t *testing.T) {
+	t.Parallel()
+	testCode := `
+package main
+
+import (
+	"context"
+
+	"github.com/alecthomas/zero"
+)
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service { return &Service{} }
+
+//zero:middleware traced
+func Trace(next zero.JobFunc) zero.JobFunc { return next }
+
+//zero:middleware tenant
+func Tenant(next zero.JobFunc) zero.JobFunc { return next }
+
+//zero:cron 1h traced
+func (s *Service) Cleanup(ctx context.Context) error { return nil }
+`
+	options := []Option{WithProviders(
+		"github.com/alecthomas/zero/providers/cron.NewScheduler",
+		"github.com/alecthomas/zero/providers/leases.NewMemoryLeaser",
+	)}
+	graph := analyseTestCode(t, testCode, options...)
+	assert.Equal(t, 1, len(graph.Middleware))
+	assert.Equal(t, "Trace", graph.Middleware[0].Function.Name())
+	assert.True(t, graph.Middleware[0].Job)
+	assert.True(t, graph.Middleware[0].MatchJob(graph.CronJobs[0].Schedule.Labels))
+
+	_, err := analyseTestCodeWithError(t, testCode+`
+//zero:middleware traced
+func Labelled(traced string) zero.JobMiddleware { return nil }
+`, options...)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "parameter traced of type string in job middleware Labelled must be a dependency")
+}
+
+func TestLogBodyRedactedFields(t *testing.T) {
+	t.Parallel()
+	graph := analyseTestCode(t, `
+package test
+
+import "context"
+
+type Credentials struct {
+	Username string `+"`json:\"username\"`"+`
+	Password string `+"`json:\"password\" log:\"-\"`"+`
+}
+
+type Login struct {
+	Credentials Credentials
+	OTP         string `+"`log:\"-\"`"+`
+}
+
+type Session struct {
+	Token string `+"`json:\"token\" log:\"-\"`"+`
+	User  *Session
+}
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service { return &Service{} }
+
+//zero:api POST /login logbody=ssn
+func (s *Service) Login(ctx context.Context, login Login) (Session, error) { return Session{}, nil }
+
+//zero:api POST /logout
+func (s *Service) Logout(ctx context.Context, session Session) error { return nil }
+`)
+	login := findAPI(t, graph.APIs, "POST", "", "/login")
+	assert.Equal(t, "ssn", login.Label("logbody"))
+	assert.Equal(t, []string{"OTP", "password", "token"}, login.RedactedFields)
+	logout := findAPI(t, graph.APIs, "POST", "", "/logout")
+	_, ok := logout.Pattern.Label("logbody")
+	assert.False(t, ok)
+	assert.Equal(t, []string{"token"}, logout.RedactedFields)
+	var middleware []string
+	for _, mw := range graph.Middleware {
+		middleware = append(middleware, mw.Function.FullName())
+	}
+	assert.SliceContains(t, middleware, "github.com/alecthomas/zero/providers/logging.BodyMiddleware")
+}
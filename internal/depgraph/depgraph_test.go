@@ -1,13 +1,20 @@
 package depgraph
 
 import (
+	"encoding/json"
+	"fmt"
 	"go/types"
 	"maps"
 	"net/http"
+	"os"
+	"path/filepath"
 	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/alecthomas/assert/v2"
+	"github.com/alecthomas/errors"
 	"github.com/alecthomas/zero/internal/buildtesting"
 	"github.com/alecthomas/zero/internal/directiveparser"
 )
@@ -133,81 +140,1063 @@ func NewConfig() *Config {
 }
 
 //zero:provider
-func NewLogger() *log.Logger {
+func NewLogger() *log.Logger {
+	return nil
+}
+
+//zero:provider
+func NewDB(cfg *Config, logger *log.Logger) (*sql.DB, error) {
+	return nil, nil
+}
+`
+	graph := analyseTestCode(t, testCode, WithRoots("*database/sql.DB"))
+	assert.Equal(t, 3, len(graph.Providers))
+	assert.Equal(t, 0, len(graph.Missing))
+
+	dbProviders, ok := graph.Providers["*database/sql.DB"]
+	assert.True(t, ok)
+	assert.Equal(t, 1, len(dbProviders))
+	assert.Equal(t, 2, len(dbProviders[0].Requires))
+}
+
+func TestAnalyseInvalidProvider(t *testing.T) {
+	t.Parallel()
+	testCode := `
+package main
+
+//zero:provider
+func InvalidProvider() {
+}
+`
+	_, err := analyseTestCodeWithError(t, testCode, WithRoots("*test.Service"))
+	assert.Error(t, err)
+	assert.EqualError(t, err, "provider function InvalidProvider must return (T) or (T, error)")
+}
+
+func TestAnalyseInvalidErrorReturn(t *testing.T) {
+	t.Parallel()
+	testCode := `
+package main
+
+//zero:provider
+func InvalidProvider() (string, string) {
+	return "", ""
+}
+`
+	_, err := analyseTestCodeWithError(t, testCode, WithRoots("*test.Service"))
+	assert.Error(t, err)
+	assert.EqualError(t, err, "provider function InvalidProvider second return value must be error")
+}
+
+func TestAnalyseETagRequiresResponse(t *testing.T) {
+	t.Parallel()
+	testCode := `
+package main
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service {
+	return &Service{}
+}
+
+//zero:api GET /users etag
+func (s *Service) ListUsers() error {
+	return nil
+}
+`
+	_, err := analyseTestCodeWithError(t, testCode)
+	assert.EqualError(t, err, "API method ListUsers must return a response to use etag")
+}
+
+func TestAnalyseStatusResponse(t *testing.T) {
+	t.Parallel()
+	testCode := `
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/alecthomas/zero"
+)
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service {
+	return &Service{}
+}
+
+type User struct {
+	Name string
+}
+
+//zero:api PUT /users/{id}
+func (s *Service) PutUser(id string, user User) (zero.Status, User, error) {
+	if id == "" {
+		return 0, User{}, zero.APIErrorf(http.StatusBadRequest, "missing id")
+	}
+	if id == "new" {
+		return http.StatusCreated, user, nil
+	}
+	return http.StatusOK, user, nil
+}
+
+//zero:api POST /jobs
+func (s *Service) CreateJob() (int, string, error) {
+	return http.StatusAccepted, "queued", nil
+}
+`
+	graph := analyseTestCode(t, testCode)
+	swagger := graph.GenerateOpenAPISpec("Test API", "1.0.0")
+	put := swagger.Paths.Paths["/users/{id}"].Put
+	assert.Equal(t, []int{200, 201, 400, 500}, slices.Sorted(maps.Keys(put.Responses.StatusCodeResponses)))
+	assert.Equal(t, "Created", put.Responses.StatusCodeResponses[201].Description)
+	assert.Equal(t, "#/definitions/main.User", put.Responses.StatusCodeResponses[201].Schema.Ref.String())
+	post := swagger.Paths.Paths["/jobs"].Post
+	assert.Equal(t, []int{202, 400, 500}, slices.Sorted(maps.Keys(post.Responses.StatusCodeResponses)))
+	assert.Equal(t, "Accepted", post.Responses.StatusCodeResponses[202].Description)
+}
+
+func TestAnalyseInvalidStatusResponse(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		results string
+		err     string
+	}{
+		{"NotStatus", "(string, string, error)", "function CreateUser first return value must be an int or zero.Status when returning three values"},
+		{"NotError", "(int, string, string)", "function CreateUser third return value must be error"},
+		{"TooMany", "(int, string, string, error)", "function CreateUser can only return one or two values, or a status, response and error"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			testCode := `
+package main
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service {
+	return &Service{}
+}
+
+//zero:api POST /users
+func (s *Service) CreateUser() ` + test.results + ` {
+	panic("not implemented")
+}
+`
+			_, err := analyseTestCodeWithError(t, testCode)
+			assert.EqualError(t, err, test.err)
+		})
+	}
+}
+
+func TestAnalyseHeaderParameters(t *testing.T) {
+	t.Parallel()
+	testCode := `
+package main
+
+import "net/netip"
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service {
+	return &Service{}
+}
+
+type User struct {
+	Name string
+}
+
+//zero:api GET /users header=apiKey:X-Api-Key,requestID,client cookie=session:sid,theme
+func (s *Service) ListUsers(apiKey string, requestID *int, client netip.Addr, session string, theme *string) ([]User, error) {
+	return nil, nil
+}
+`
+	graph := analyseTestCode(t, testCode)
+	api := graph.APIs[0]
+	assert.Equal(t, map[int]*HeaderParameter{
+		0: {In: "header", Name: "X-Api-Key"},
+		1: {In: "header", Name: "Request-Id"},
+		2: {In: "header", Name: "Client"},
+		3: {In: "cookie", Name: "sid"},
+		4: {In: "cookie", Name: "theme"},
+	}, api.Headers)
+	swagger := graph.GenerateOpenAPISpec("Test API", "1.0.0")
+	operation := swagger.Paths.Paths["/users"].Get
+	var params []string
+	for _, param := range operation.Parameters {
+		params = append(params, fmt.Sprintf("%s:%s:%s:%v", param.In, param.Name, param.Type, param.Required))
+	}
+	assert.Equal(t, []string{
+		"header:X-Api-Key:string:true",
+		"header:Request-Id:integer:false",
+		"header:Client:string:true",
+		"header:Cookie:string:true",
+	}, params)
+	assert.Equal(t, "Must include the cookies: sid, theme.", operation.Parameters[3].Description)
+}
+
+func TestAnalyseInvalidHeaderParameters(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name   string
+		labels string
+		params string
+		err    string
+	}{
+		{"UnknownParameter", "header=apiKey", "", "API method ListUsers has no parameter apiKey to bind to the header Api-Key"},
+		{"InvalidType", "cookie=filter", "filter []string", "API method ListUsers parameter filter of type []string cannot be bound to a cookie"},
+		{"HeaderAndCookie", "header=session cookie=session", "session string", "API method ListUsers: parameter session cannot be bound to both a header and a cookie"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			testCode := `
+package main
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service {
+	return &Service{}
+}
+
+//zero:api GET /users ` + test.labels + `
+func (s *Service) ListUsers(` + test.params + `) error {
+	return nil
+}
+`
+			_, err := analyseTestCodeWithError(t, testCode)
+			assert.EqualError(t, err, test.err)
+		})
+	}
+}
+
+func TestAnalyseInvalidRequestLabels(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name   string
+		labels string
+		err    string
+	}{
+		{"Timeout", "timeout=5", `invalid timeout "5"`},
+		{"MaxBody", "maxbody=lots", `invalid maxbody "lots"`},
+		{"Idempotent", "idempotent=1hh", `invalid idempotent "1hh"`},
+		{"IdempotentNegative", "idempotent=-1h", `invalid idempotent "-1h"`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			testCode := `
+package main
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service {
+	return &Service{}
+}
+
+//zero:api POST /payments ` + test.labels + `
+func (s *Service) CreatePayment() error {
+	return nil
+}
+`
+			_, err := analyseTestCodeWithError(t, testCode)
+			assert.Error(t, err)
+			assert.True(t, strings.HasSuffix(err.Error(), "main.go:12:1: "+test.err), "%s", err)
+		})
+	}
+}
+
+func TestAnalyseParamsStruct(t *testing.T) {
+	t.Parallel()
+	testCode := `
+package main
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service {
+	return &Service{}
+}
+
+type Paging struct {
+	Limit int ` + "`query:\"limit\"`" + `
+}
+
+type ListPostsParams struct {
+	Paging
+	UserID string   ` + "`path:\"id\"`" + `
+	Tags   []string ` + "`query:\"tag\"`" + `
+	Draft  *bool    ` + "`query:\"draft\"`" + `
+}
+
+type Post struct {
+	Title string
+}
+
+//zero:api GET /users/{id}/posts
+func (s *Service) ListPosts(params ListPostsParams) ([]Post, error) {
+	return nil, nil
+}
+`
+	graph := analyseTestCode(t, testCode)
+	var fields []string
+	for _, field := range graph.APIs[0].Params[0] {
+		fields = append(fields, field.In+":"+field.Name+":"+field.Field.Name())
+	}
+	assert.Equal(t, []string{"query:limit:Limit", "path:id:UserID", "query:tag:Tags", "query:draft:Draft"}, fields)
+	swagger := graph.GenerateOpenAPISpec("Test API", "1.0.0")
+	operation := swagger.Paths.Paths["/users/{id}/posts"].Get
+	var params []string
+	for _, param := range operation.Parameters {
+		params = append(params, fmt.Sprintf("%s:%s:%s:%v", param.In, param.Name, param.Type, param.Required))
+	}
+	assert.Equal(t, []string{
+		"query:limit:integer:false",
+		"path:id:string:true",
+		"query:tag:array:false",
+		"query:draft:boolean:false",
+	}, params)
+	assert.Equal(t, "string", operation.Parameters[2].Items.Type)
+}
+
+func TestAnalyseInvalidParamsStruct(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name   string
+		fields string
+		params string
+		err    string
+	}{
+		{"UnknownWildcard", "ID string `path:\"userID\"`", "params Params", `API method ListPosts parameter params: field ID is bound to unknown path wildcard "userID"`},
+		{"OptionalPath", "ID *string `path:\"id\"`", "params Params", "API method ListPosts parameter params: field ID of type *string cannot be bound to a path wildcard"},
+		{"InvalidQueryType", "ID string `path:\"id\"`; Filter map[string]string `query:\"filter\"`", "params Params", "API method ListPosts parameter params: field Filter of type map[string]string cannot be bound to a query parameter"},
+		{"UntaggedField", "ID string `path:\"id\"`; Limit int", "params Params", "API method ListPosts parameter params: field Limit must have a path or query tag"},
+		{"MultipleParamsStructs", "ID string `path:\"id\"`", "params Params, other Params", "API method ListPosts can only have one params struct parameter"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			testCode := `
+package main
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service {
+	return &Service{}
+}
+
+type Params struct { ` + test.fields + ` }
+
+//zero:api GET /users/{id}/posts
+func (s *Service) ListPosts(` + test.params + `) error {
+	return nil
+}
+`
+			_, err := analyseTestCodeWithError(t, testCode)
+			assert.EqualError(t, err, test.err)
+		})
+	}
+}
+
+func TestAnalyseOpenAPIDocumentation(t *testing.T) {
+	t.Parallel()
+	testCode := `
+package main
+
+type UserService struct{}
+
+// NewUserService creates the user service.
+//
+//zero:provider
+func NewUserService() *UserService {
+	return &UserService{}
+}
+
+// GetUser returns a user by ID, including
+// their profile. Deleted users are not returned.
+//
+// Requires the user to be visible to the caller.
+//
+//zero:api GET /users/{id}
+func (s *UserService) GetUser(id string) (string, error) {
+	return id, nil
+}
+
+// Health reports the health of the service
+//
+//zero:api /health
+func (s *UserService) Health() string {
+	return "OK"
+}
+`
+	graph := analyseTestCode(t, testCode)
+	assert.Equal(t, "NewUserService creates the user service.", graph.Providers["*test.UserService"][0].Documentation)
+	swagger := graph.GenerateOpenAPISpec("Test API", "1.0.0")
+	get := swagger.Paths.Paths["/users/{id}"].Get
+	assert.Equal(t, "UserServiceGetUser", get.ID)
+	assert.Equal(t, "GetUser returns a user by ID, including their profile.", get.Summary)
+	assert.Equal(t, "Deleted users are not returned.\n\nRequires the user to be visible to the caller.", get.Description)
+	health := swagger.Paths.Paths["/health"]
+	assert.Equal(t, "UserServiceHealthGet", health.Get.ID)
+	assert.Equal(t, "UserServiceHealthPost", health.Post.ID)
+	assert.Equal(t, "Health reports the health of the service", health.Get.Summary)
+	assert.Equal(t, "", health.Get.Description)
+}
+
+func TestAnalysePagination(t *testing.T) {
+	t.Parallel()
+	testCode := `
+package main
+
+import "github.com/alecthomas/zero"
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service {
+	return &Service{}
+}
+
+type User struct {
+	Name string
+}
+
+//zero:api GET /users
+func (s *Service) ListUsers(page zero.PageRequest) (zero.Page[User], error) {
+	return zero.Page[User]{}, nil
+}
+`
+	graph := analyseTestCode(t, testCode)
+	swagger := graph.GenerateOpenAPISpec("Test API", "1.0.0")
+	operation := swagger.Paths.Paths["/users"].Get
+	var names []string
+	for _, param := range operation.Parameters {
+		names = append(names, param.In+":"+param.Name)
+	}
+	assert.Equal(t, []string{"query:limit", "query:cursor"}, names)
+	response := operation.Responses.StatusCodeResponses[200]
+	assert.Equal(t, "#/definitions/zero.Page-main.User", response.Schema.Ref.String())
+	assert.Equal(t, "string", response.Headers["Link"].Type)
+	page := swagger.Definitions["zero.Page-main.User"]
+	assert.Equal(t, []string{"items", "nextCursor"}, slices.Sorted(maps.Keys(page.Properties)))
+}
+
+func TestAnalyseValueProviders(t *testing.T) {
+	t.Parallel()
+	testCode := `
+package main
+
+import "time"
+
+type Options struct {
+	Retries int
+}
+
+//zero:provider
+const DefaultTimeout time.Duration = 30 * time.Second
+
+//zero:provider
+var DefaultOptions = &Options{Retries: 3}
+
+var (
+	//zero:provider multi
+	DefaultTags = []string{"a", "b"}
+)
+
+type Service struct{}
+
+//zero:provider
+func NewService(timeout time.Duration, options *Options, tags []string) *Service {
+	return &Service{}
+}
+`
+	graph := analyseTestCode(t, testCode, WithRoots("*test.Service"))
+	assert.Equal(t, []string{"*test.Options", "*test.Service", "[]string", "time.Duration"}, stableKeys(graph.Providers))
+	assert.Equal(t, 0, len(graph.Missing))
+
+	timeoutProviders := graph.Providers["time.Duration"]
+	assert.Equal(t, 1, len(timeoutProviders))
+	assert.Zero(t, timeoutProviders[0].Function)
+	assert.Equal(t, "test.DefaultTimeout", timeoutProviders[0].FullName())
+	assert.Equal(t, "test.DefaultOptions", graph.Providers["*test.Options"][0].FullName())
+	assert.True(t, graph.Providers["[]string"][0].Directive.Multi)
+}
+
+func TestAnalyseUntypedConstValueProvider(t *testing.T) {
+	t.Parallel()
+	testCode := `
+package main
+
+//zero:provider
+const DefaultRetries = 3
+`
+	_, err := analyseTestCodeWithError(t, testCode, WithRoots("int"))
+	assert.Error(t, err)
+	assert.True(t, strings.HasSuffix(err.Error(), "main.go:5:7: value provider DefaultRetries must have an explicit type"), err.Error())
+}
+
+func TestAnalyseModuleSelection(t *testing.T) {
+	t.Parallel()
+	testCode := `
+//zero:module local
+package main
+
+import (
+	"github.com/alecthomas/zero/providers/leases"
+)
+
+type Service struct{}
+
+//zero:provider weak
+func NewLeaser() leases.Leaser {
+	return nil
+}
+
+//zero:provider
+func NewService(leaser leases.Leaser) *Service {
+	return &Service{}
+}
+`
+	tmpDir := buildtesting.Prepare(t, testCode)
+	_, err := Analyse(t.Context(), tmpDir, WithRoots("*test.Service"))
+	assert.Error(t, err)
+
+	graph, err := Analyse(t.Context(), tmpDir, WithRoots("*test.Service"), WithModules("local"))
+	assert.NoError(t, err)
+	providers := graph.Providers["github.com/alecthomas/zero/providers/leases.Leaser"]
+	assert.Equal(t, 1, len(providers))
+	assert.Equal(t, "test.NewLeaser", providers[0].FullName())
+	assert.Equal(t, "local", providers[0].Module)
+
+	_, err = Analyse(t.Context(), tmpDir, WithRoots("*test.Service"), WithModules("remote"))
+	assert.EqualError(t, err, `requested module "remote" not found in discovered modules: local`)
+}
+
+func TestAnalyseOptionalDependencies(t *testing.T) {
+	t.Parallel()
+	testCode := `
+package main
+
+import "github.com/alecthomas/zero"
+
+type Tracer struct{}
+
+type Metrics struct{}
+
+//zero:provider
+func NewTracer() *Tracer {
+	return &Tracer{}
+}
+
+type Service struct{}
+
+//zero:provider
+func NewService(tracer zero.Optional[*Tracer], metrics zero.Optional[*Metrics]) *Service {
+	return &Service{}
+}
+`
+	graph := analyseTestCode(t, testCode, WithRoots("*test.Service"))
+	assert.Equal(t, []string{"*test.Service", "*test.Tracer"}, stableKeys(graph.Providers))
+	assert.Equal(t, 0, len(graph.Missing))
+	assert.Equal(t, []string{"*Tracer", "*Metrics"}, graph.Graph()["*test.Service"])
+}
+
+func TestAnalyseGroups(t *testing.T) {
+	t.Parallel()
+	testCode := `
+package main
+
+type HealthCheck interface {
+	Check() error
+}
+
+type DBCheck struct{}
+
+func (*DBCheck) Check() error { return nil }
+
+type CacheCheck struct{}
+
+func (*CacheCheck) Check() error { return nil }
+
+type DiskCheck struct{}
+
+func (*DiskCheck) Check() error { return nil }
+
+//zero:provider group=HealthCheck
+func NewDBCheck() *DBCheck { return &DBCheck{} }
+
+//zero:provider group=HealthCheck
+func NewCacheCheck() *CacheCheck { return &CacheCheck{} }
+
+//zero:provider weak group=HealthCheck
+func NewDiskCheck() *DiskCheck { return &DiskCheck{} }
+
+type Service struct{}
+
+//zero:provider
+func NewService(checks []HealthCheck) *Service {
+	return &Service{}
+}
+`
+	graph := analyseTestCode(t, testCode, WithRoots("*test.Service"))
+	assert.Equal(t, []string{"*test.CacheCheck", "*test.DBCheck", "*test.Service"}, stableKeys(graph.Providers))
+	assert.Equal(t, []string{"[]test.HealthCheck"}, stableKeys(graph.Groups))
+	assert.Equal(t, 0, len(graph.Missing))
+	members := []string{}
+	for _, member := range graph.Groups["[]test.HealthCheck"].Members {
+		members = append(members, member.FullName())
+	}
+	assert.Equal(t, []string{"test.NewCacheCheck", "test.NewDBCheck"}, members)
+}
+
+func TestAnalyseGroupNotImplemented(t *testing.T) {
+	t.Parallel()
+	testCode := `
+package main
+
+type HealthCheck interface {
+	Check() error
+}
+
+type DBCheck struct{}
+
+//zero:provider group=HealthCheck
+func NewDBCheck() *DBCheck { return &DBCheck{} }
+`
+	_, err := analyseTestCodeWithError(t, testCode)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "*test.DBCheck does not implement group interface test.HealthCheck")
+}
+
+func TestAnalyseMethodProviders(t *testing.T) {
+	t.Parallel()
+	testCode := `
+package main
+
+type Config struct {
+	URL string
+}
+
+type Factory struct{}
+
+//zero:provider
+func NewFactory() *Factory {
+	return &Factory{}
+}
+
+type Client struct{}
+
+//zero:provider
+func (f *Factory) NewClient(config Config) *Client {
+	return &Client{}
+}
+`
+	graph := analyseTestCode(t, testCode, WithRoots("*test.Client"))
+	assert.Equal(t, []string{"*test.Client", "*test.Factory"}, stableKeys(graph.Providers))
+	client := graph.Providers["*test.Client"][0]
+	assert.Equal(t, "*test.Factory", types.TypeString(client.Receiver, nil))
+	assert.Equal(t, "(*test.Factory).NewClient", client.FullName())
+	assert.Equal(t, 1, len(graph.Missing))
+	for _, missing := range graph.Missing {
+		assert.Equal(t, "test.Config", types.TypeString(missing[0], nil))
+	}
+}
+
+func TestAnalyseProfileProviders(t *testing.T) {
+	t.Parallel()
+	testCode := `
+package main
+
+type Store interface {
+	Get(key string) string
+}
+
+//zero:provider profile=dev,test
+func NewMemoryStore() Store {
+	return nil
+}
+
+//zero:provider profile=prod
+func NewPostgresStore() Store {
+	return nil
+}
+
+type Service struct{}
+
+//zero:provider
+func NewService(store Store) *Service {
+	return &Service{}
+}
+`
+	tmpDir := buildtesting.Prepare(t, testCode)
+	graph, err := Analyse(t.Context(), tmpDir, WithRoots("*test.Service"), WithProfiles("test"))
+	assert.NoError(t, err)
+	assert.Equal(t, "test.NewMemoryStore", graph.Providers["test.Store"][0].FullName())
+
+	graph, err = Analyse(t.Context(), tmpDir, WithRoots("*test.Service"), WithProfiles("prod"))
+	assert.NoError(t, err)
+	assert.Equal(t, "test.NewPostgresStore", graph.Providers["test.Store"][0].FullName())
+
+	graph, err = Analyse(t.Context(), tmpDir, WithRoots("*test.Service"))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(graph.Missing))
+	assert.Equal(t, []string{"dev", "prod", "test"}, graph.Profiles())
+}
+
+func TestAnalyseTestProviders(t *testing.T) {
+	t.Parallel()
+	testCode := `
+package main
+
+type Store interface {
+	Get(key string) string
+}
+
+//zero:provider
+func NewPostgresStore() Store {
+	return nil
+}
+
+type Clock interface {
+	Now() int64
+}
+
+//zero:provider test
+func NewFakeClock(store Store) Clock {
+	return nil
+}
+
+type Service struct{}
+
+//zero:provider
+func NewService(store Store) *Service {
+	return &Service{}
+}
+
+func main() {}
+`
+	tmpDir := buildtesting.Prepare(t, testCode)
+	testFile := filepath.Join(tmpDir, "main_test.go")
+	t.Cleanup(func() { _ = os.Remove(testFile) })
+	//nolint
+	err := os.WriteFile(testFile, []byte(`package main
+
+//zero:provider
+func NewMemoryStore() Store {
+	return nil
+}
+`), 0600)
+	assert.NoError(t, err)
+
+	graph, err := Analyse(t.Context(), tmpDir, WithRoots("*test.Service"))
+	assert.NoError(t, err)
+	assert.Zero(t, graph.TestProviders)
+	assert.Equal(t, 1, len(graph.Providers["test.Store"]))
+	assert.Equal(t, 0, len(graph.Providers["test.Clock"]))
+
+	graph, err = Analyse(t.Context(), tmpDir, WithRoots("*test.Service"), WithTests(true))
+	assert.NoError(t, err)
+	names := []string{}
+	for _, provider := range graph.TestProviders {
+		names = append(names, provider.FullName())
+	}
+	assert.Equal(t, []string{"test.NewMemoryStore", "test.NewFakeClock"}, names)
+	assert.Equal(t, 1, len(graph.Providers["test.Store"]))
+	assert.Equal(t, "test.NewPostgresStore", graph.Providers["test.Store"][0].FullName())
+
+	//nolint
+	err = os.WriteFile(testFile, []byte(`package main
+
+//zero:provider
+func NewMemoryStore() Store {
+	return nil
+}
+
+//zero:provider test
+func NewOtherStore() Store {
+	return nil
+}
+`), 0600)
+	assert.NoError(t, err)
+	_, err = Analyse(t.Context(), tmpDir, WithRoots("*test.Service"), WithTests(true))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "multiple test providers for test.Store")
+}
+
+func TestGraphJSON(t *testing.T) {
+	t.Parallel()
+	testCode := `
+package main
+
+//zero:config
+type Config struct {
+	URL string
+}
+
+type DB struct{}
+
+//zero:provider weak
+func NewDB(config Config) *DB {
+	return nil
+}
+
+type Service struct{}
+
+//zero:provider
+func NewService(db *DB) *Service {
+	return &Service{}
+}
+`
+	graph := analyseTestCode(t, testCode, WithRoots("*test.Service"))
+	out := graph.JSON()
+	nodes := []string{}
+	for _, node := range out.Nodes {
+		nodes = append(nodes, string(node.Kind)+" "+node.ID+" "+node.Type)
+		assert.NotZero(t, node.Position)
+	}
+	assert.Equal(t, []string{
+		"config test.Config test.Config",
+		"provider test.NewDB *test.DB",
+		"provider test.NewService *test.Service",
+	}, nodes)
+	assert.True(t, out.Nodes[1].Weak)
+	assert.Equal(t, []JSONEdge{
+		{From: "test.NewDB", To: "test.Config"},
+		{From: "test.NewService", To: "*test.DB"},
+	}, out.Edges)
+
+	data, err := json.Marshal(graph)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"kind":"provider"`)
+}
+
+// featureFlagPlugin requires the parameters of functions annotated with //zero:featureflag.
+type featureFlagPlugin struct{}
+
+func (featureFlagPlugin) Directive() string { return "featureflag" }
+
+func (featureFlagPlugin) Analyse(annotation *Annotation) ([]types.Type, error) {
+	fn, ok := annotation.Object.(*types.Func)
+	if !ok {
+		return nil, errors.Errorf("only valid on functions")
+	}
+	requires := []types.Type{}
+	for param := range fn.Signature().Params().Variables() {
+		requires = append(requires, param.Type())
+	}
+	return requires, nil
+}
+
+func TestAnalysePlugins(t *testing.T) {
+	t.Parallel()
+	testCode := `
+package main
+
+type Flags struct{}
+
+//zero:provider
+func NewFlags() *Flags {
+	return &Flags{}
+}
+
+type Unused struct{}
+
+//zero:provider
+func NewUnused() *Unused {
+	return &Unused{}
+}
+
+//zero:featureflag default=false
+func NewCheckout(flags *Flags) bool {
+	return false
+}
+`
+	tmpDir := buildtesting.Prepare(t, testCode)
+	graph, err := Analyse(t.Context(), tmpDir, WithPlugins(featureFlagPlugin{}))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"*test.Flags"}, stableKeys(graph.Providers))
+	annotations := graph.Annotations["featureflag"]
+	assert.Equal(t, 1, len(annotations))
+	assert.Equal(t, "default=false", annotations[0].Args)
+	assert.Equal(t, "test.NewCheckout", annotations[0].Object.(*types.Func).FullName())
+
+	_, err = Analyse(t.Context(), tmpDir)
+	assert.Error(t, err)
+}
+
+func TestAnalysePluginWithBuiltinDirective(t *testing.T) {
+	t.Parallel()
+	testCode := `
+package main
+
+type Flags struct{}
+
+//zero:provider
+func NewFlags() *Flags {
+	return &Flags{}
+}
+
+type Checkout struct{}
+
+//zero:featureflag default=true
+//zero:provider
+func NewCheckout(flags *Flags) *Checkout {
+	return &Checkout{}
+}
+
+//zero:featureflag
+//zero:api POST /checkout
+func (c *Checkout) Submit() error {
+	return nil
+}
+`
+	tmpDir := buildtesting.Prepare(t, testCode)
+	graph, err := Analyse(t.Context(), tmpDir, WithPlugins(featureFlagPlugin{}))
+	assert.NoError(t, err)
+	_, ok := graph.Providers["*test.Checkout"]
+	assert.True(t, ok)
+	assert.Equal(t, 1, len(graph.APIs))
+	assert.Equal(t, "POST /checkout", graph.APIs[0].Pattern.Pattern())
+	annotations := graph.Annotations["featureflag"]
+	assert.Equal(t, 2, len(annotations))
+	assert.Equal(t, "test.NewCheckout", annotations[0].Object.(*types.Func).FullName())
+	assert.Equal(t, "(*test.Checkout).Submit", annotations[1].Object.(*types.Func).FullName())
+}
+
+type providerPlugin struct{ featureFlagPlugin }
+
+func (providerPlugin) Directive() string { return "provider" }
+
+func TestAnalysePluginConflictsWithBuiltin(t *testing.T) {
+	t.Parallel()
+	_, err := Analyse(t.Context(), ".", WithPlugins(providerPlugin{}))
+	assert.EqualError(t, err, `plugin directive "provider" conflicts with a builtin directive`)
+}
+
+func TestAnalyseNonProviderFunction(t *testing.T) {
+	t.Parallel()
+	testCode := `
+package test
+
+type Service struct{}
+
+func RegularFunction() string {
+	return ""
+}
+
+//zero:provider
+func NewService() *Service {
+	return nil
+}
+`
+	graph := analyseTestCode(t, testCode, WithRoots("*test.Service"))
+	assert.Equal(t, 1, len(graph.Providers))
+}
+
+func TestAnalyseCircularDependencies(t *testing.T) {
+	t.Parallel()
+	testCode := `
+package test
+
+type A struct{}
+type B struct{}
+
+//zero:provider
+func NewA(b *B) *A {
 	return nil
 }
 
 //zero:provider
-func NewDB(cfg *Config, logger *log.Logger) (*sql.DB, error) {
-	return nil, nil
+func NewB(a *A) *B {
+	return nil
 }
 `
-	graph := analyseTestCode(t, testCode, WithRoots("*database/sql.DB"))
-	assert.Equal(t, 3, len(graph.Providers))
-	assert.Equal(t, 0, len(graph.Missing))
-
-	dbProviders, ok := graph.Providers["*database/sql.DB"]
-	assert.True(t, ok)
-	assert.Equal(t, 1, len(dbProviders))
-	assert.Equal(t, 2, len(dbProviders[0].Requires))
+	_, err := analyseTestCodeWithError(t, testCode, WithRoots("*test.A", "*test.B"))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "dependency cycle detected: *test.A (test.NewA at ")
+	assert.Contains(t, err.Error(), ") -> *test.B (test.NewB at ")
+	assert.True(t, strings.HasSuffix(err.Error(), ") -> *test.A"), err.Error())
 }
 
-func TestAnalyseInvalidProvider(t *testing.T) {
+func TestAnalyseLazyBreaksCycle(t *testing.T) {
 	t.Parallel()
 	testCode := `
-package main
+package test
+
+import "github.com/alecthomas/zero"
+
+type A struct{}
+type B struct{}
+type Model struct{}
 
 //zero:provider
-func InvalidProvider() {
-}
-`
-	_, err := analyseTestCodeWithError(t, testCode, WithRoots("*test.Service"))
-	assert.Error(t, err)
-	assert.EqualError(t, err, "provider function InvalidProvider must return (T) or (T, error)")
+func NewA(b *B) *A {
+	return nil
 }
 
-func TestAnalyseInvalidErrorReturn(t *testing.T) {
-	t.Parallel()
-	testCode := `
-package main
-
 //zero:provider
-func InvalidProvider() (string, string) {
-	return "", ""
+func NewB(a zero.Lazy[*A], model zero.Lazy[*Model]) *B {
+	return nil
 }
 `
-	_, err := analyseTestCodeWithError(t, testCode, WithRoots("*test.Service"))
-	assert.Error(t, err)
-	assert.EqualError(t, err, "provider function InvalidProvider second return value must be error")
+	graph := analyseTestCode(t, testCode, WithRoots("*test.A"))
+	assert.Equal(t, []string{"*test.A", "*test.B"}, stableKeys(graph.Providers))
+	assert.Equal(t, 1, len(graph.Missing))
+	for _, missing := range graph.Missing {
+		assert.Equal(t, "*test.Model", types.TypeString(missing[0], nil))
+	}
 }
 
-func TestAnalyseNonProviderFunction(t *testing.T) {
+func TestAnalyseFuncProvider(t *testing.T) {
 	t.Parallel()
 	testCode := `
 package test
 
+import "time"
+
 type Service struct{}
 
-func RegularFunction() string {
-	return ""
+//zero:provider
+func NewNow() func() time.Time {
+	return time.Now
 }
 
 //zero:provider
-func NewService() *Service {
+func NewService(now func() time.Time) *Service {
 	return nil
 }
 `
 	graph := analyseTestCode(t, testCode, WithRoots("*test.Service"))
-	assert.Equal(t, 1, len(graph.Providers))
+	assert.Equal(t, []string{"*test.Service", "func() time.Time"}, stableKeys(graph.Providers))
+	assert.Equal(t, 0, len(graph.Missing))
+	// A func() T dependency is satisfied by its provider, rather than deferring construction of T.
+	assert.Equal(t, map[string][]string{"*test.Service": {"func() time.Time"}}, dependencyEdges(graph))
 }
 
-func TestAnalyseCircularDependencies(t *testing.T) {
+func TestAnalyseIndirectCircularDependencies(t *testing.T) {
 	t.Parallel()
 	testCode := `
 package test
 
 type A struct{}
 type B struct{}
+type C struct{}
+type Root struct{}
+
+//zero:provider
+func NewRoot(a *A) *Root {
+	return nil
+}
 
 //zero:provider
 func NewA(b *B) *A {
@@ -215,13 +1204,21 @@ func NewA(b *B) *A {
 }
 
 //zero:provider
-func NewB(a *A) *B {
+func NewB(c *C) *B {
+	return nil
+}
+
+//zero:provider
+func NewC(b *B) *C {
 	return nil
 }
 `
-	graph := analyseTestCode(t, testCode, WithRoots("*test.A", "*test.B"))
-	assert.Equal(t, 2, len(graph.Providers))
-	assert.Equal(t, 0, len(graph.Missing))
+	_, err := analyseTestCodeWithError(t, testCode, WithRoots("*test.Root"))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "dependency cycle detected: *test.B (test.NewB at ")
+	assert.Contains(t, err.Error(), ") -> *test.C (test.NewC at ")
+	assert.True(t, strings.HasSuffix(err.Error(), ") -> *test.B"), err.Error())
+	assert.NotContains(t, err.Error(), "*test.A")
 }
 
 func TestAnalyseInvalidProviderReference(t *testing.T) {
@@ -450,6 +1447,8 @@ package main
 import (
 	"context"
 	"net/http"
+	"os"
+	"path/filepath"
 )
 
 type CreateUserRequest struct {
@@ -681,10 +1680,12 @@ func (s *UserService) CreateUser(req CreateUserRequest) string {
 	// Check that we have the expected providers (user-defined + Zero infrastructure for APIs)
 	expectedProviders := []string{
 		"*database/sql.DB",
+		"*github.com/alecthomas/zero.Readiness",
 		"*log/slog.Logger",
 		"*net/http.ServeMux",
 		"*net/http.Server",
 		"*test.UserService",
+		"github.com/alecthomas/zero.ContentEncodings",
 		"github.com/alecthomas/zero.ErrorEncoder",
 		"github.com/alecthomas/zero.ResponseEncoder",
 	}
@@ -765,9 +1766,11 @@ func (s *UserService) CreateUser(ctx context.Context, req CreateUserRequest) (*s
 `
 	graph := analyseTestCode(t, testCode, WithRoots("*net/http.Server"))
 	assert.Equal(t, []string{
+		"*github.com/alecthomas/zero.Readiness",
 		"*log/slog.Logger",
 		"*net/http.ServeMux",
 		"*net/http.Server",
+		"github.com/alecthomas/zero.ContentEncodings",
 		"github.com/alecthomas/zero.ErrorEncoder",
 		"github.com/alecthomas/zero.ResponseEncoder",
 	}, stableKeys(graph.Providers))
@@ -813,10 +1816,12 @@ func (s *UserService) CreateUser(ctx context.Context, req CreateUserRequest) (*s
 `
 	graph := analyseTestCode(t, testCode, WithRoots("*test.UserService"))
 	expectedProviders := []string{
+		"*github.com/alecthomas/zero.Readiness",
 		"*log/slog.Logger",
 		"*net/http.ServeMux",
 		"*net/http.Server",
 		"*test.UserService",
+		"github.com/alecthomas/zero.ContentEncodings",
 		"github.com/alecthomas/zero.ErrorEncoder",
 		"github.com/alecthomas/zero.ResponseEncoder",
 	}
@@ -1266,6 +2271,8 @@ import (
 	"encoding"
 	"io"
 	"net/http"
+	"os"
+	"path/filepath"
 	"time"
 )
 
@@ -1496,6 +2503,29 @@ func (s *UserService) ComplexCreate(ctx context.Context, req1 CreateUserRequest,
 `,
 			expectedErr: "API method ComplexCreate can only have one struct parameter for request body/query parameters",
 		},
+		{
+			name: "ReaderWithBodyStruct",
+			code: `
+package main
+
+import (
+	"context"
+	"io"
+)
+
+type CreateUserRequest struct {
+	Name string
+}
+
+type UserService struct{}
+
+//zero:api POST /users/import
+func (s *UserService) Import(ctx context.Context, req CreateUserRequest, body io.Reader) error {
+	return nil
+}
+`,
+			expectedErr: "API method Import can only have one io.Reader parameter, and it cannot be combined with a request body struct",
+		},
 	}
 
 	for _, tt := range tests {
@@ -1766,6 +2796,8 @@ package main
 import (
 	"context"
 	"net/http"
+	"os"
+	"path/filepath"
 )
 
 type CreateUserRequest struct {
@@ -1886,7 +2918,7 @@ func TestRemoveUnusedConfigs(t *testing.T) {
 	code := `
 package main
 
-//zero:config
+//zero:config prefix="used-"
 type UsedConfig struct {
 	Value string
 }
@@ -1896,7 +2928,7 @@ type UnusedConfig struct {
 	Value string
 }
 
-//zero:config
+//zero:config prefix="pointer-used-"
 type PointerUsedConfig struct {
 	Value string
 }
@@ -2044,6 +3076,8 @@ package test
 import (
 	"context"
 	"net/http"
+	"os"
+	"path/filepath"
 )
 
 //zero:provider
@@ -2072,11 +3106,13 @@ func (s *ServiceB) PostOther(ctx context.Context, w http.ResponseWriter, r *http
 	graph, err := analyseCodeString(t, code, WithRoots("*test.ServiceA", "*test.ServiceB"))
 	assert.NoError(t, err)
 	expectedProviders := []string{
+		"*github.com/alecthomas/zero.Readiness",
 		"*log/slog.Logger",
 		"*net/http.ServeMux",
 		"*net/http.Server",
 		"*test.ServiceA",
 		"*test.ServiceB",
+		"github.com/alecthomas/zero.ContentEncodings",
 		"github.com/alecthomas/zero.ErrorEncoder",
 		"github.com/alecthomas/zero.ResponseEncoder",
 	}
@@ -2087,10 +3123,12 @@ func (s *ServiceB) PostOther(ctx context.Context, w http.ResponseWriter, r *http
 	graph, err = analyseCodeString(t, code, WithRoots("*test.ServiceA"))
 	assert.NoError(t, err)
 	expectedProvidersOne := []string{
+		"*github.com/alecthomas/zero.Readiness",
 		"*log/slog.Logger",
 		"*net/http.ServeMux",
 		"*net/http.Server",
 		"*test.ServiceA",
+		"github.com/alecthomas/zero.ContentEncodings",
 		"github.com/alecthomas/zero.ErrorEncoder",
 		"github.com/alecthomas/zero.ResponseEncoder",
 	}
@@ -2161,6 +3199,8 @@ package test
 import (
 	"context"
 	"net/http"
+	"os"
+	"path/filepath"
 )
 
 //zero:provider
@@ -2199,11 +3239,13 @@ func (s *ServiceB) PostOther(ctx context.Context, w http.ResponseWriter, r *http
 	// Only providers for API receivers should be kept (ServiceA and ServiceB)
 	// ServiceC should be pruned since it's not an API receiver
 	expectedProviders := []string{
+		"*github.com/alecthomas/zero.Readiness",
 		"*log/slog.Logger",
 		"*net/http.ServeMux",
 		"*net/http.Server",
 		"*test.ServiceA",
 		"*test.ServiceB",
+		"github.com/alecthomas/zero.ContentEncodings",
 		"github.com/alecthomas/zero.ErrorEncoder",
 		"github.com/alecthomas/zero.ResponseEncoder",
 	}
@@ -2708,6 +3750,51 @@ func StandaloneCronFunction(ctx context.Context) error {
 	assert.EqualError(t, err, "//zero:cron annotation is only valid on methods, not functions: StandaloneCronFunction")
 }
 
+func TestAnalyseParameterisedCron(t *testing.T) {
+	t.Parallel()
+	testCode := `
+package main
+
+import (
+	"context"
+)
+
+type Tenant struct {
+	ID string
+}
+
+//zero:provider
+func NewTenants() []Tenant {
+	return []Tenant{{ID: "acme"}}
+}
+
+type CronService struct{}
+
+//zero:provider
+func NewCronService() *CronService {
+	return &CronService{}
+}
+
+//zero:cron 1h
+func (s *CronService) Vacuum(ctx context.Context, tenant Tenant) error {
+	return nil
+}
+`
+	graph, err := analyseCodeString(t, testCode, WithProviders("github.com/alecthomas/zero/providers/leases.NewMemoryLeaser"))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(graph.CronJobs))
+	assert.Equal(t, "[]test.Tenant", types.TypeString(graph.CronJobs[0].Params, nil))
+	_, ok := graph.Providers["[]test.Tenant"]
+	assert.True(t, ok, "parameter set of the cron job should be provided")
+
+	graph, err = analyseCodeString(t, strings.ReplaceAll(testCode, "//zero:provider\nfunc NewTenants", "func NewTenants"),
+		WithProviders("github.com/alecthomas/zero/providers/leases.NewMemoryLeaser"))
+	assert.NoError(t, err)
+	missing := graph.MissingDependencies()
+	assert.Equal(t, 1, len(missing))
+	assert.Contains(t, missing[0].String(), "parameter tenant of (*test.CronService).Vacuum() is missing a provider for []test.Tenant")
+}
+
 func TestAnalyseCronInvalidSignatureNoParameters(t *testing.T) {
 	t.Parallel()
 	testCode := `
@@ -2721,7 +3808,7 @@ func (s *CronService) InvalidCron() error {
 }
 `
 	_, err := analyseTestCodeWithError(t, testCode)
-	assert.EqualError(t, err, "cron method InvalidCron must have exactly one parameter of type context.Context")
+	assert.EqualError(t, err, "cron method InvalidCron must have one parameter of type context.Context, optionally followed by an element of an injected slice")
 }
 
 func TestAnalyseCronInvalidSignatureTooManyParameters(t *testing.T) {
@@ -2734,12 +3821,12 @@ import "context"
 type CronService struct{}
 
 //zero:cron 1h
-func (s *CronService) InvalidCron(ctx context.Context, extra string) error {
+func (s *CronService) InvalidCron(ctx context.Context, extra string, another int) error {
 	return nil
 }
 `
 	_, err := analyseTestCodeWithError(t, testCode)
-	assert.EqualError(t, err, "cron method InvalidCron must have exactly one parameter of type context.Context")
+	assert.EqualError(t, err, "cron method InvalidCron must have one parameter of type context.Context, optionally followed by an element of an injected slice")
 }
 
 func TestAnalyseCronInvalidSignatureWrongParameterType(t *testing.T) {
@@ -2841,13 +3928,17 @@ func (s *Service) HourlyCleanup(ctx context.Context) error {
 	assert.NoError(t, err)
 
 	expectedProviders := []string{
+		"*github.com/alecthomas/zero.Readiness",
 		"*github.com/alecthomas/zero/providers/cron.Scheduler",
 		"*log/slog.Logger",
 		"*net/http.ServeMux",
 		"*net/http.Server",
 		"*test.Service",
+		"github.com/alecthomas/zero.Clock",
+		"github.com/alecthomas/zero.ContentEncodings",
 		"github.com/alecthomas/zero.ErrorEncoder",
 		"github.com/alecthomas/zero.ResponseEncoder",
+		"github.com/alecthomas/zero/providers/cron.History",
 		"github.com/alecthomas/zero/providers/leases.Leaser",
 	}
 	assert.Equal(t, expectedProviders, stableKeys(graph.Providers))
@@ -2911,6 +4002,176 @@ func (s *SubscriptionService) HandleUserUpdated(ctx context.Context, event pubsu
 	assert.Equal(t, "test.UserCreatedEvent", types.TypeString(subscription2.TopicType, nil))
 }
 
+func TestAnalyseSubscriptionVersions(t *testing.T) {
+	t.Parallel()
+	testCode := `
+package main
+
+import (
+	"context"
+	"github.com/alecthomas/zero/providers/pubsub"
+)
+
+type SubscriptionService struct{}
+
+type UserCreatedEvent struct {
+	UserID string
+}
+
+//zero:subscribe version=1
+func (s *SubscriptionService) HandleUserCreatedV1(ctx context.Context, event pubsub.Event[UserCreatedEvent]) error {
+	return nil
+}
+
+//zero:subscribe version=2
+func (s *SubscriptionService) HandleUserCreated(ctx context.Context, event pubsub.Event[UserCreatedEvent]) error {
+	return nil
+}
+`
+	graph := analyseTestCode(t, testCode, WithRoots("github.com/alecthomas/zero/providers/pubsub.Topic"), WithProviders("github.com/alecthomas/zero/providers/pubsub.NewMemoryTopic"))
+	assert.Equal(t, 2, len(graph.Subscriptions))
+	assert.Equal(t, 1, *graph.Subscriptions[0].Version)
+	assert.Equal(t, 2, *graph.Subscriptions[1].Version)
+
+	_, err := analyseTestCodeWithError(t, strings.ReplaceAll(testCode, "version=2", "version=1"))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "HandleUserCreated: version 1 of test.UserCreatedEvent is already subscribed to by (*test.SubscriptionService).HandleUserCreatedV1")
+}
+
+func TestAnalyseSubscriptionBatch(t *testing.T) {
+	t.Parallel()
+	testCode := `
+package main
+
+import (
+	"context"
+	"github.com/alecthomas/zero/providers/pubsub"
+)
+
+type SubscriptionService struct{}
+
+type PageViewEvent struct {
+	URL string
+}
+
+//zero:subscribe batch=100 window=2s dedupe=1h
+func (s *SubscriptionService) HandlePageViews(ctx context.Context, events []pubsub.Event[PageViewEvent]) error {
+	return nil
+}
+`
+	graph := analyseTestCode(t, testCode, WithRoots("github.com/alecthomas/zero/providers/pubsub.Topic"), WithProviders("github.com/alecthomas/zero/providers/pubsub.NewMemoryTopic"))
+	assert.Equal(t, 1, len(graph.Subscriptions))
+	assert.Equal(t, 100, *graph.Subscriptions[0].Batch)
+	assert.Equal(t, 2*time.Second, graph.Subscriptions[0].Window)
+	assert.Equal(t, time.Hour, graph.Subscriptions[0].Dedupe)
+	assert.Equal(t, "test.PageViewEvent", types.TypeString(graph.Subscriptions[0].TopicType, nil))
+
+	_, err := analyseTestCodeWithError(t, strings.ReplaceAll(testCode, "batch=100 window=2s", ""))
+	assert.EqualError(t, err, "subscription method HandlePageViews receives a batch of events and must set a batch size, eg. //zero:subscribe batch=100")
+
+	_, err = analyseTestCodeWithError(t, strings.ReplaceAll(testCode, "batch=100", "version=1 batch=100"))
+	assert.EqualError(t, err, "subscription method HandlePageViews receives a batch of events and cannot be versioned")
+
+	_, err = analyseTestCodeWithError(t, strings.ReplaceAll(testCode, "[]pubsub.Event", "pubsub.Event"))
+	assert.EqualError(t, err, "subscription method HandlePageViews sets a batch size and must receive a batch of events: []pubsub.Event[T]")
+}
+
+func TestAnalyseWorkflowSteps(t *testing.T) {
+	t.Parallel()
+	testCode := `
+package main
+
+import (
+	"context"
+)
+
+type OrderState struct {
+	Reserved bool
+}
+
+type OrderWorkflow struct{}
+
+//zero:provider
+func NewOrderWorkflow() *OrderWorkflow { return &OrderWorkflow{} }
+
+//zero:step compensate=ReleaseStock
+func (o *OrderWorkflow) ReserveStock(ctx context.Context, state *OrderState) error { return nil }
+
+func (o *OrderWorkflow) ReleaseStock(ctx context.Context, state *OrderState) error { return nil }
+
+//zero:step
+func (o *OrderWorkflow) ChargeCard(ctx context.Context, state *OrderState) error { return nil }
+`
+	graph := analyseTestCode(t, testCode, WithProviders(
+		"github.com/alecthomas/zero/providers/workflow.NewMemoryStore",
+		"github.com/alecthomas/zero/providers/leases.NewMemoryLeaser",
+	))
+	assert.Equal(t, 2, len(graph.Steps))
+	assert.Equal(t, "ReserveStock", graph.Steps[0].Function.Name())
+	assert.Equal(t, "ReleaseStock", graph.Steps[0].Compensate.Name())
+	assert.Equal(t, "ChargeCard", graph.Steps[1].Function.Name())
+	assert.Zero(t, graph.Steps[1].Compensate)
+	assert.Equal(t, "test.OrderState", types.TypeString(graph.Steps[0].StateType, nil))
+	assert.SliceContains(t, graph.Roots, "*github.com/alecthomas/zero/providers/workflow.Engine")
+
+	_, err := analyseTestCodeWithError(t, strings.ReplaceAll(testCode, "compensate=ReleaseStock", "compensate=Refund"))
+	assert.EqualError(t, err, "step method ReserveStock: compensation Refund is not a method of *test.OrderWorkflow")
+
+	_, err = analyseTestCodeWithError(t, strings.ReplaceAll(testCode, "ReleaseStock(ctx context.Context, state *OrderState)", "ReleaseStock(ctx context.Context, state *struct{})"))
+	assert.EqualError(t, err, "step method ReserveStock: compensation ReleaseStock must accept *test.OrderState, got *struct{}")
+
+	_, err = analyseTestCodeWithError(t, strings.ReplaceAll(testCode, "ChargeCard(ctx context.Context, state *OrderState)", "ChargeCard(ctx context.Context, state OrderState)"))
+	assert.EqualError(t, err, "step method ChargeCard second parameter must be a pointer to the workflow state, got test.OrderState")
+
+	_, err = analyseTestCodeWithError(t, strings.ReplaceAll(testCode, "ChargeCard(ctx context.Context, state *OrderState)", "ChargeCard(ctx context.Context, state *struct{})"))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "ChargeCard: workflow state *struct{} differs from *test.OrderState of step")
+}
+
+func TestAnalyseQueueWorkers(t *testing.T) {
+	t.Parallel()
+	testCode := `
+package main
+
+import (
+	"context"
+	"github.com/alecthomas/zero/providers/queue"
+)
+
+type SendEmail struct {
+	To string
+}
+
+type Mailer struct{}
+
+//zero:provider
+func NewMailer() *Mailer { return &Mailer{} }
+
+//zero:worker concurrency=4 attempts=10 backoff=2s visibility=1m traced
+func (m *Mailer) Send(ctx context.Context, job queue.Job[SendEmail]) error { return nil }
+`
+	graph := analyseTestCode(t, testCode, WithProviders("github.com/alecthomas/zero/providers/queue.NewMemoryStore"))
+	assert.Equal(t, 1, len(graph.Workers))
+	worker := graph.Workers[0]
+	assert.Equal(t, "Send", worker.Function.Name())
+	assert.Equal(t, "test.SendEmail", types.TypeString(worker.PayloadType, nil))
+	assert.Equal(t, "*github.com/alecthomas/zero/providers/queue.Queue[test.SendEmail]", types.TypeString(worker.QueueType, nil))
+	assert.Equal(t, 4, *worker.Concurrency)
+	assert.Equal(t, 10, *worker.Attempts)
+	assert.Equal(t, time.Second*2, worker.Backoff)
+	assert.Equal(t, time.Minute, worker.Visibility)
+	assert.Equal(t, []string{"traced"}, worker.Labels)
+	assert.SliceContains(t, graph.Roots, "*test.Mailer")
+	_, ok := graph.Providers["*github.com/alecthomas/zero/providers/queue.Queue[test.SendEmail]"]
+	assert.True(t, ok, "queue of the worker should be provided")
+
+	_, err := analyseTestCodeWithError(t, strings.ReplaceAll(testCode, "job queue.Job[SendEmail]", "job SendEmail"))
+	assert.EqualError(t, err, "worker method Send second parameter must be queue.Job[T], got test.SendEmail")
+
+	_, err = analyseTestCodeWithError(t, strings.ReplaceAll(testCode, "func (m *Mailer) Send(", "func Send("))
+	assert.EqualError(t, err, "//zero:worker annotation is only valid on methods, not functions: Send")
+}
+
 func TestAnalyseSubscriptionAnnotationOnFunction(t *testing.T) {
 	t.Parallel()
 	testCode := `
@@ -3133,10 +4394,13 @@ func (s *Service) HandleEvent(ctx context.Context, event pubsub.Event[Event]) er
 	assert.NoError(t, err)
 
 	expectedProviders := []string{
+		"*github.com/alecthomas/zero.Readiness",
+		"*github.com/alecthomas/zero/providers/pubsub.Subscriptions",
 		"*log/slog.Logger",
 		"*net/http.ServeMux",
 		"*net/http.Server",
 		"*test.Service",
+		"github.com/alecthomas/zero.ContentEncodings",
 		"github.com/alecthomas/zero.ErrorEncoder",
 		"github.com/alecthomas/zero.ResponseEncoder",
 		"github.com/alecthomas/zero/providers/pubsub.Topic",
@@ -3342,6 +4606,81 @@ func NewService(topic Topic[User]) *Service {
 	assert.Equal(t, 0, len(graph.Missing[serviceProviders[0].Function]))
 }
 
+func TestAnalyseGenericProvidersScopedPicks(t *testing.T) {
+	t.Parallel()
+	testCode := `package test
+
+type Store[T any] interface {
+	Get(id string) (T, error)
+}
+
+type User struct{}
+
+type Order struct{}
+
+//zero:provider weak
+func NewMemoryStore[T any]() Store[T] {
+	return nil
+}
+
+//zero:provider weak
+func NewDiskStore[T any]() Store[T] {
+	return nil
+}
+
+type Service struct{}
+
+//zero:provider
+func NewService(users Store[User], orders Store[Order]) *Service {
+	return &Service{}
+}
+`
+	graph := analyseTestCode(t, testCode, WithRoots("*test.Service"), WithProviders(
+		"test.NewMemoryStore",
+		"test.Store[test.Order]=test.NewDiskStore",
+	))
+	assert.Equal(t, "NewMemoryStore", graph.Providers["test.Store[test.User]"][0].Function.Name())
+	assert.Equal(t, "NewDiskStore", graph.Providers["test.Store[test.Order]"][0].Function.Name())
+}
+
+func TestAnalyseGenericProvidersUnselectedDependencies(t *testing.T) {
+	t.Parallel()
+	testCode := `package test
+
+type Cache[K comparable, V any] interface {
+	Get(key K) (V, bool)
+}
+
+type User struct{}
+
+type Client struct{}
+
+//zero:provider weak
+func NewClient() *Client { return &Client{} }
+
+//zero:provider weak
+func NewMemoryCache[K comparable, V any]() Cache[K, V] {
+	return nil
+}
+
+//zero:provider weak
+func NewClientCache[K comparable, V any](client *Client) Cache[K, V] {
+	return nil
+}
+
+type Service struct{}
+
+//zero:provider
+func NewService(users Cache[string, User]) *Service {
+	return &Service{}
+}
+`
+	graph := analyseTestCode(t, testCode, WithRoots("*test.Service"), WithProviders("test.NewMemoryCache"))
+	assert.Equal(t, "NewMemoryCache", graph.Providers["test.Cache[string, test.User]"][0].Function.Name())
+	// The unselected NewClientCache requires *test.Client, which was pruned.
+	assert.Equal(t, 0, len(graph.Missing))
+}
+
 func TestAnalyseGenericProvidersWithConstraints(t *testing.T) {
 	t.Parallel()
 	testCode := `package test
@@ -0,0 +1,70 @@
+package depgraph
+
+import (
+	"go/types"
+	"slices"
+	"strings"
+)
+
+// grpcConfigType is the config for the gRPC server, added as a root if any gRPC services are present.
+const grpcConfigType = "github.com/alecthomas/zero/providers/grpc.Config"
+
+// grpcServerType may be provided to override the default gRPC server.
+const grpcServerType = "*google.golang.org/grpc.Server"
+
+// GRPCService is an API receiver that also implements a protoc-generated gRPC service interface.
+//
+// The receiver is registered with the gRPC server in addition to serving its //zero:api methods over HTTP.
+type GRPCService struct {
+	// Receiver type implementing the service interface.
+	Receiver types.Type
+	// Register is the generated registration function, eg. "pb.RegisterUsersServer".
+	Register *types.Func
+}
+
+// findGRPCServices returns the API receivers implementing a gRPC service interface, identified by a protoc-generated
+// Register<Service>Server function in the receiver's package or one of its imports.
+func findGRPCServices(apis []*API) []*GRPCService {
+	var out []*GRPCService
+	seen := map[string]bool{}
+	for _, api := range apis {
+		receiver := api.Function.Signature().Recv().Type()
+		key := types.TypeString(receiver, nil)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		for _, pkg := range append([]*types.Package{api.Package.Types}, api.Package.Types.Imports()...) {
+			scope := pkg.Scope()
+			for _, name := range scope.Names() {
+				fn, ok := scope.Lookup(name).(*types.Func)
+				if !ok || !strings.HasPrefix(name, "Register") || !strings.HasSuffix(name, "Server") {
+					continue
+				}
+				params := fn.Signature().Params()
+				if params.Len() != 2 || !isGRPCRegistrar(params.At(0).Type()) {
+					continue
+				}
+				iface, ok := params.At(1).Type().Underlying().(*types.Interface)
+				if !ok || !types.Implements(receiver, iface) {
+					continue
+				}
+				out = append(out, &GRPCService{Receiver: receiver, Register: fn})
+			}
+		}
+	}
+	slices.SortStableFunc(out, func(a, b *GRPCService) int {
+		return strings.Compare(a.Register.FullName(), b.Register.FullName())
+	})
+	return out
+}
+
+// isGRPCRegistrar returns true if t is grpc.ServiceRegistrar, or *grpc.Server in older versions of protoc-gen-go-grpc.
+func isGRPCRegistrar(t types.Type) bool {
+	switch types.TypeString(t, nil) {
+	case "google.golang.org/grpc.ServiceRegistrar", grpcServerType:
+		return true
+	default:
+		return false
+	}
+}
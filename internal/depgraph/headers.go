@@ -0,0 +1,108 @@
+package depgraph
+
+import (
+	"go/types"
+	"net/http"
+	"strings"
+
+	"github.com/alecthomas/errors"
+	"github.com/alecthomas/zero/internal/directiveparser"
+	"github.com/go-openapi/spec"
+)
+
+// HeaderParameter is an API parameter bound to a request header or cookie by the header=<param>[:<header>] or
+// cookie=<param>[:<cookie>] label of the API, eg.
+//
+//	//zero:api GET /users header=apiKey:X-Api-Key cookie=session
+//	func (s *Service) ListUsers(apiKey string, session *string) ([]User, error)
+//
+// Pointer parameters are optional, and nil if the header or cookie is absent.
+type HeaderParameter struct {
+	// In is "header" or "cookie".
+	In string
+	// Name of the header or cookie.
+	Name string
+}
+
+// headerParameters returns the parameters bound to headers and cookies by the labels of an API, keyed by parameter name.
+//
+// Header names default to the canonical form of the kebab-cased parameter name, eg. "Request-Id" for requestID, and
+// cookie names to the parameter name.
+func headerParameters(directive *directiveparser.DirectiveAPI) (map[string]*HeaderParameter, error) {
+	headers, err := directive.Headers()
+	if err != nil {
+		return nil, err
+	}
+	cookies, err := directive.Cookies()
+	if err != nil {
+		return nil, err
+	}
+	out := map[string]*HeaderParameter{}
+	for param, name := range headers {
+		if name == "" {
+			name = http.CanonicalHeaderKey(toKebabCase(param))
+		}
+		out[param] = &HeaderParameter{In: "header", Name: name}
+	}
+	for param, name := range cookies {
+		if _, ok := out[param]; ok {
+			return nil, errors.Errorf("parameter %s cannot be bound to both a header and a cookie", param)
+		}
+		if name == "" {
+			name = param
+		}
+		out[param] = &HeaderParameter{In: "cookie", Name: name}
+	}
+	return out, nil
+}
+
+// isHeaderParameterType returns true if t can be decoded from a header or cookie by zero.DecodeHeader and
+// zero.DecodeCookie.
+func isHeaderParameterType(t types.Type) bool {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	if implementsTextUnmarshaler(t) {
+		return true
+	}
+	basic, ok := t.Underlying().(*types.Basic)
+	return ok && basic.Info()&(types.IsString|types.IsInteger|types.IsFloat|types.IsBoolean) != 0
+}
+
+// headerParameterType returns the OpenAPI type of a header or cookie parameter of type t, and whether it is required.
+func headerParameterType(t types.Type) (parameterType string, required bool) {
+	required = true
+	if ptr, ok := t.(*types.Pointer); ok {
+		required = false
+		t = ptr.Elem()
+	}
+	basic, ok := t.Underlying().(*types.Basic)
+	if !ok || implementsTextUnmarshaler(t) {
+		return "string", required
+	}
+	switch info := basic.Info(); {
+	case info&types.IsInteger != 0:
+		return "integer", required
+	case info&types.IsFloat != 0:
+		return "number", required
+	case info&types.IsBoolean != 0:
+		return "boolean", required
+	default:
+		return "string", required
+	}
+}
+
+// cookieParameterSpec returns the OpenAPI parameter documenting the cookies bound to parameters.
+//
+// Swagger 2.0 cannot describe cookies, so they are documented by the description of the Cookie header.
+func cookieParameterSpec(cookies []string, required bool) spec.Parameter {
+	return spec.Parameter{
+		ParamProps: spec.ParamProps{
+			Name:        "Cookie",
+			In:          "header",
+			Description: "Must include the cookies: " + strings.Join(cookies, ", ") + ".",
+			Required:    required,
+		},
+		SimpleSchema: spec.SimpleSchema{Type: "string"},
+	}
+}
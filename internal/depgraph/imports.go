@@ -0,0 +1,194 @@
+package depgraph
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"go/ast"
+	"go/types"
+	"io"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/errors"
+	"golang.org/x/tools/go/packages"
+)
+
+// generatedHeader is the comment starting code generated by Zero.
+const generatedHeader = "// Code generated by zero. DO NOT EDIT."
+
+// LinkedPackage is a non-standard package linked into the service by the graph.
+type LinkedPackage struct {
+	// Imports are the non-standard packages imported directly.
+	Imports []string
+	// Module containing the package, or nil if it isn't in a module.
+	Module *LinkedModule
+}
+
+// LinkedModule is the module containing a [LinkedPackage], as reported by "go list".
+type LinkedModule struct {
+	Path string
+	// Version is empty for main and workspace modules.
+	Version string
+	Main    bool
+	// Replace is the module replacing this one with a replace directive, or nil.
+	Replace *LinkedModule
+}
+
+// Imports returns the non-standard packages linked into the service by the graph, keyed by import path.
+//
+// The closure starts at the destination package and the packages of the providers, configs, handlers and middleware
+// in the graph. Imports of code previously generated by Zero into the destination package are ignored, as it is about
+// to be regenerated from the graph, so the destination package itself isn't listed, only its handwritten imports.
+// Otherwise a stale zero.go importing a package that no longer exists would fail the listing.
+func (g *Graph) Imports(ctx context.Context) (map[string]*LinkedPackage, error) {
+	roots := g.Packages()
+	listed := map[string]*LinkedPackage{}
+	patterns := slices.DeleteFunc(slices.Concat(roots, g.destImports), func(path string) bool { return path == g.Dest.Path() })
+	slices.Sort(patterns)
+	if patterns = slices.Compact(patterns); len(patterns) > 0 {
+		if err := g.listPackages(ctx, slices.Concat([]string{"-deps"}, patterns), listed); err != nil {
+			return nil, err
+		}
+	}
+	// -find lists the module of the destination package without resolving its imports.
+	if err := g.listPackages(ctx, []string{"-find", g.Dest.Path()}, listed); err != nil {
+		return nil, err
+	}
+	if dest, ok := listed[g.Dest.Path()]; ok {
+		dest.Imports = g.destImports
+	}
+
+	// Only packages reachable without the existing generated code are linked.
+	imports := map[string]*LinkedPackage{}
+	queue := slices.Clone(roots)
+	for len(queue) > 0 {
+		path := queue[0]
+		queue = queue[1:]
+		pkg, ok := listed[path]
+		if _, seen := imports[path]; seen || !ok {
+			continue
+		}
+		// Standard library packages were not listed, so are removed from the imports of each package.
+		pkg.Imports = slices.DeleteFunc(slices.Clone(pkg.Imports), func(dep string) bool { _, ok := listed[dep]; return !ok })
+		imports[path] = pkg
+		queue = append(queue, pkg.Imports...)
+	}
+	return imports, nil
+}
+
+// listPackages adds the non-standard packages listed by "go list" with args to listed, keyed by import path.
+func (g *Graph) listPackages(ctx context.Context, args []string, listed map[string]*LinkedPackage) error {
+	args = slices.Concat([]string{"list", "-json=ImportPath,Standard,Imports,Module"}, g.buildFlags, args)
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = g.DestDir
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return errors.Errorf("failed to list the imports of %s: %w: %s", g.Dest.Path(), err, strings.TrimSpace(stderr.String()))
+	}
+	dec := json.NewDecoder(bytes.NewReader(output))
+	for {
+		var pkg struct {
+			ImportPath string
+			Standard   bool
+			Imports    []string
+			Module     *LinkedModule
+		}
+		if err := dec.Decode(&pkg); errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return errors.Errorf("failed to decode the imports of %s: %w", g.Dest.Path(), err)
+		}
+		if !pkg.Standard {
+			listed[pkg.ImportPath] = &LinkedPackage{Imports: pkg.Imports, Module: pkg.Module}
+		}
+	}
+}
+
+// Packages returns the sorted import paths of the packages declaring the providers, configs, handlers and middleware
+// in the graph, and of the destination package.
+func (g *Graph) Packages() []string {
+	pkgs := []string{g.Dest.Path()}
+	for _, providers := range g.Providers {
+		for _, provider := range providers {
+			if provider.Package != nil {
+				pkgs = append(pkgs, provider.Package.PkgPath)
+			}
+		}
+	}
+	for _, group := range g.Groups {
+		for _, member := range group.Members {
+			if member.Package != nil {
+				pkgs = append(pkgs, member.Package.PkgPath)
+			}
+		}
+	}
+	for _, config := range g.Configs {
+		typ := config.Type
+		if ptr, ok := typ.(*types.Pointer); ok {
+			typ = ptr.Elem()
+		}
+		if named, ok := types.Unalias(typ).(*types.Named); ok && named.Obj().Pkg() != nil {
+			pkgs = append(pkgs, named.Obj().Pkg().Path())
+		}
+	}
+	for _, api := range g.APIs {
+		pkgs = append(pkgs, api.Package.PkgPath)
+	}
+	for _, job := range g.CronJobs {
+		pkgs = append(pkgs, job.Package.PkgPath)
+	}
+	for _, subscription := range g.Subscriptions {
+		pkgs = append(pkgs, subscription.Package.PkgPath)
+	}
+	for _, step := range g.Steps {
+		pkgs = append(pkgs, step.Package.PkgPath)
+	}
+	for _, worker := range g.Workers {
+		pkgs = append(pkgs, worker.Package.PkgPath)
+	}
+	for _, middleware := range g.Middleware {
+		pkgs = append(pkgs, middleware.Package.PkgPath)
+	}
+	slices.Sort(pkgs)
+	return slices.Compact(pkgs)
+}
+
+// handwrittenImports returns the sorted imports of the non-test files of pkg that were not generated by Zero.
+func handwrittenImports(pkg *packages.Package) []string {
+	var imports []string
+	for i, file := range pkg.Syntax {
+		if i < len(pkg.CompiledGoFiles) && strings.HasSuffix(pkg.CompiledGoFiles[i], "_test.go") {
+			continue
+		}
+		if isGeneratedByZero(file) {
+			continue
+		}
+		for _, spec := range file.Imports {
+			if path, err := strconv.Unquote(spec.Path.Value); err == nil && path != "C" {
+				imports = append(imports, path)
+			}
+		}
+	}
+	slices.Sort(imports)
+	return slices.Compact(imports)
+}
+
+// isGeneratedByZero reports whether file starts with Zero's generated code header, which may follow build constraints.
+func isGeneratedByZero(file *ast.File) bool {
+	for _, group := range file.Comments {
+		if group.Pos() > file.Package {
+			break
+		}
+		for _, comment := range group.List {
+			if comment.Text == generatedHeader {
+				return true
+			}
+		}
+	}
+	return false
+}
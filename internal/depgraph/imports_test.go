@@ -0,0 +1,83 @@
+package depgraph
+
+import (
+	"go/parser"
+	"go/token"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/alecthomas/zero/internal/buildtesting"
+)
+
+func TestImports(t *testing.T) {
+	t.Parallel()
+	graph := analyseTestCode(t, `
+package test
+
+import (
+	"net/http"
+
+	"github.com/alecthomas/zero"
+)
+
+type Service struct{}
+
+//zero:provider
+func NewService(clock zero.Clock) *Service { return &Service{} }
+
+//zero:api GET /
+func (s *Service) Index(w http.ResponseWriter) {}
+`, WithRoots("*test.Service"))
+	imports, err := graph.Imports(t.Context())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"github.com/alecthomas/zero"}, imports["test"].Imports)
+	assert.True(t, imports["test"].Module.Main)
+	clock := imports["github.com/alecthomas/zero/providers/clock"]
+	assert.True(t, slices.Contains(clock.Imports, "github.com/alecthomas/zero"))
+	assert.Equal(t, "github.com/alecthomas/zero", clock.Module.Path)
+	_, ok := imports["net/http"]
+	assert.False(t, ok, "standard library packages are omitted")
+	assert.True(t, slices.Contains(graph.Packages(), "github.com/alecthomas/zero/providers/clock"))
+}
+
+func TestImportsIgnoresStaleGeneratedCode(t *testing.T) {
+	t.Parallel()
+	tmpDir := buildtesting.Prepare(t, `
+package main
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service { return &Service{} }
+`)
+	stale := filepath.Join(tmpDir, "zero.go")
+	t.Cleanup(func() { _ = os.Remove(stale) })
+	err := os.WriteFile(stale, []byte("// Code generated by zero. DO NOT EDIT.\n\npackage main\n\nimport _ \"example.com/missing\"\n"), 0600)
+	assert.NoError(t, err)
+	graph, err := Analyse(t.Context(), tmpDir, WithRoots("*test.Service"))
+	assert.NoError(t, err)
+	imports, err := graph.Imports(t.Context())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"test"}, slices.Sorted(maps.Keys(imports)))
+	assert.Equal(t, 0, len(imports["test"].Imports))
+	assert.True(t, imports["test"].Module.Main)
+}
+
+func TestIsGeneratedByZero(t *testing.T) {
+	t.Parallel()
+	for src, want := range map[string]bool{
+		"// Code generated by zero. DO NOT EDIT.\n\npackage test\n":                    true,
+		"//go:build prod\n\n// Code generated by zero. DO NOT EDIT.\n\npackage test\n": true,
+		"// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage test\n":           false,
+		"package test\n\n// Code generated by zero. DO NOT EDIT.\n":                    false,
+	} {
+		file, err := parser.ParseFile(token.NewFileSet(), "zero.go", src, parser.ParseComments)
+		assert.NoError(t, err)
+		assert.Equal(t, want, isGeneratedByZero(file), src)
+	}
+}
@@ -0,0 +1,160 @@
+package depgraph
+
+import (
+	"cmp"
+	"encoding/json"
+	"go/token"
+	"go/types"
+	"slices"
+	"strings"
+)
+
+// NodeKind is the kind of a node in the JSON representation of the graph.
+type NodeKind string
+
+const (
+	NodeProvider     NodeKind = "provider"
+	NodeConfig       NodeKind = "config"
+	NodeGroup        NodeKind = "group"
+	NodeAPI          NodeKind = "api"
+	NodeCron         NodeKind = "cron"
+	NodeSubscription NodeKind = "subscription"
+	NodeStep         NodeKind = "step"
+	NodeWorker       NodeKind = "worker"
+	NodeMiddleware   NodeKind = "middleware"
+)
+
+// JSONNode is a node in the JSON representation of the graph.
+type JSONNode struct {
+	// ID uniquely identifies the node, eg. the fully-qualified provider function name or config type.
+	ID   string   `json:"id"`
+	Kind NodeKind `json:"kind"`
+	// Type is the type provided by providers, configs and groups.
+	Type     string `json:"type,omitempty"`
+	Position string `json:"position,omitempty"`
+	Weak     bool   `json:"weak,omitempty"`
+	Multi    bool   `json:"multi,omitempty"`
+}
+
+// JSONEdge is a dependency of a node on a type.
+type JSONEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// JSONGraph is the machine-readable representation of the graph, intended for tooling.
+type JSONGraph struct {
+	Nodes []JSONNode `json:"nodes"`
+	Edges []JSONEdge `json:"edges"`
+}
+
+// JSON returns the machine-readable representation of the graph.
+func (g *Graph) JSON() *JSONGraph {
+	out := &JSONGraph{Nodes: []JSONNode{}, Edges: []JSONEdge{}}
+	addEdges := func(from string, requires ...types.Type) {
+		for _, req := range requires {
+			out.Edges = append(out.Edges, JSONEdge{From: from, To: types.TypeString(UnwrapDependency(req), nil)})
+		}
+	}
+	for key, providers := range g.Providers {
+		for _, provider := range providers {
+			// Skip base generic providers, which are only retained for lookup
+			if provider.IsGeneric && key != types.TypeString(provider.Provides, nil) {
+				continue
+			}
+			id := providerID(provider)
+			out.Nodes = append(out.Nodes, JSONNode{
+				ID:       id,
+				Kind:     NodeProvider,
+				Type:     types.TypeString(provider.Provides, nil),
+				Position: positionString(provider.Position),
+				Weak:     provider.Directive.Weak,
+				Multi:    provider.Directive.Multi,
+			})
+			addEdges(id, provider.Requires...)
+		}
+	}
+	for key, config := range g.Configs {
+		out.Nodes = append(out.Nodes, JSONNode{ID: key, Kind: NodeConfig, Type: key, Position: positionString(config.Position)})
+	}
+	for key, group := range g.Groups {
+		out.Nodes = append(out.Nodes, JSONNode{ID: key, Kind: NodeGroup, Type: key})
+		for _, member := range group.Members {
+			addEdges(key, member.Provides)
+		}
+	}
+	for _, api := range g.APIs {
+		id := api.Function.FullName()
+		out.Nodes = append(out.Nodes, JSONNode{ID: id, Kind: NodeAPI, Position: positionString(api.Position)})
+		addEdges(id, api.Function.Signature().Recv().Type())
+	}
+	for _, cron := range g.CronJobs {
+		id := cron.Function.FullName()
+		out.Nodes = append(out.Nodes, JSONNode{ID: id, Kind: NodeCron, Position: positionString(cron.Position)})
+		addEdges(id, cron.Function.Signature().Recv().Type())
+	}
+	for _, subscription := range g.Subscriptions {
+		id := subscription.Function.FullName()
+		out.Nodes = append(out.Nodes, JSONNode{ID: id, Kind: NodeSubscription, Position: positionString(subscription.Position)})
+		addEdges(id, subscription.Function.Signature().Recv().Type())
+	}
+	for _, step := range g.Steps {
+		id := step.Function.FullName()
+		out.Nodes = append(out.Nodes, JSONNode{ID: id, Kind: NodeStep, Position: positionString(step.Position)})
+		addEdges(id, step.Function.Signature().Recv().Type())
+	}
+	for _, worker := range g.Workers {
+		id := worker.Function.FullName()
+		out.Nodes = append(out.Nodes, JSONNode{ID: id, Kind: NodeWorker, Position: positionString(worker.Position)})
+		addEdges(id, worker.Function.Signature().Recv().Type())
+	}
+	for _, middleware := range g.Middleware {
+		id := middleware.Function.FullName()
+		out.Nodes = append(out.Nodes, JSONNode{ID: id, Kind: NodeMiddleware, Position: positionString(middleware.Position)})
+		addEdges(id, middleware.Requires...)
+	}
+	slices.SortFunc(out.Nodes, func(a, b JSONNode) int {
+		return cmp.Or(cmp.Compare(a.Kind, b.Kind), cmp.Compare(a.ID, b.ID))
+	})
+	slices.SortFunc(out.Edges, func(a, b JSONEdge) int {
+		return cmp.Or(cmp.Compare(a.From, b.From), cmp.Compare(a.To, b.To))
+	})
+	return out
+}
+
+// MarshalJSON implements json.Marshaler.
+func (g *Graph) MarshalJSON() ([]byte, error) {
+	return json.Marshal(g.JSON())
+}
+
+// providerID returns the node ID of a provider, which for generic providers includes the type arguments of the
+// instantiation, eg. "github.com/alecthomas/zero/providers/pubsub.NewMemoryTopic[example.User]"
+func providerID(provider *Provider) string {
+	id := provider.FullName()
+	if !provider.IsGeneric {
+		return id
+	}
+	args := []string{}
+	for _, arg := range extractTypeArgs(provider.Provides) {
+		args = append(args, types.TypeString(arg, nil))
+	}
+	return id + "[" + strings.Join(args, ", ") + "]"
+}
+
+func extractTypeArgs(t types.Type) []types.Type {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, ok := t.(*types.Named)
+	if !ok {
+		return nil
+	}
+	return slices.Collect(named.TypeArgs().Types())
+}
+
+func positionString(pos token.Position) string {
+	if !pos.IsValid() {
+		return ""
+	}
+	return pos.String()
+}
@@ -0,0 +1,223 @@
+package depgraph
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/alecthomas/errors"
+	"golang.org/x/tools/go/packages"
+)
+
+// ManifestName is the name of the manifest file written into a package directory by [WriteManifests].
+const ManifestName = "zero-providers.json"
+
+// Manifest lists the //zero: directives of each file in a package, along with a hash of the file.
+//
+// Provider libraries can ship a manifest in each of their packages so that the bodies of functions without directives
+// need not be type-checked when the package is analysed. Files whose hash doesn't match are analysed in full, so a
+// stale manifest is never trusted.
+type Manifest struct {
+	Version int                     `json:"version"`
+	Package string                  `json:"package"`
+	Files   map[string]ManifestFile `json:"files"`
+}
+
+// ManifestFile is the entry of a single Go file in a [Manifest].
+type ManifestFile struct {
+	SHA256     string              `json:"sha256"`
+	Directives []ManifestDirective `json:"directives,omitempty"`
+}
+
+// ManifestDirective is a //zero: directive in a Go file.
+type ManifestDirective struct {
+	// Decl is the name of the declaration the directive is attached to, eg. NewStore, or Users.List for a method, or
+	// empty for the package clause.
+	Decl string `json:"decl"`
+	// Directive is the text of the directive without the leading //, eg. "zero:provider weak".
+	Directive string `json:"directive"`
+}
+
+const manifestVersion = 1
+
+// WriteManifests writes a [Manifest] into the directory of each package matching patterns, which are resolved relative
+// to dir, returning the paths of the manifests.
+func WriteManifests(ctx context.Context, dir string, patterns []string, tags ...string) ([]string, error) {
+	cfg := &packages.Config{Context: ctx, Dir: dir, Mode: packages.NeedName | packages.NeedFiles}
+	if len(tags) > 0 {
+		cfg.BuildFlags = []string{"-tags=" + strings.Join(tags, ",")}
+	}
+	pkgs, err := packages.Load(cfg, patterns...)
+	if err != nil {
+		return nil, errors.Errorf("failed to load packages: %w", err)
+	}
+	var paths []string
+	for _, pkg := range pkgs {
+		files := slices.Concat(pkg.GoFiles, pkg.IgnoredFiles)
+		files = slices.DeleteFunc(files, func(file string) bool {
+			return !strings.HasSuffix(file, ".go") || strings.HasSuffix(file, "_test.go")
+		})
+		if len(files) == 0 {
+			continue
+		}
+		manifest, err := createManifest(pkg.PkgPath, files)
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.MarshalIndent(manifest, "", "  ")
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		path := filepath.Join(filepath.Dir(files[0]), ManifestName)
+		if err := os.WriteFile(path, append(data, '\n'), 0600); err != nil {
+			return nil, errors.Errorf("failed to write manifest: %w", err)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+func createManifest(pkgPath string, files []string) (*Manifest, error) {
+	manifest := &Manifest{Version: manifestVersion, Package: pkgPath, Files: map[string]ManifestFile{}}
+	fset := token.NewFileSet()
+	for _, filename := range files {
+		src, err := os.ReadFile(filename) //nolint
+		if err != nil {
+			return nil, errors.Errorf("failed to read %s: %w", filename, err)
+		}
+		file, err := parser.ParseFile(fset, filename, src, parser.ParseComments|parser.SkipObjectResolution)
+		if err != nil {
+			return nil, errors.Errorf("failed to parse %s: %w", filename, err)
+		}
+		entry := ManifestFile{SHA256: hashSource(src)}
+		add := func(decl string, doc *ast.CommentGroup) {
+			if doc == nil {
+				return
+			}
+			for _, comment := range doc.List {
+				if strings.HasPrefix(comment.Text, "//zero:") {
+					entry.Directives = append(entry.Directives, ManifestDirective{Decl: decl, Directive: comment.Text[2:]})
+				}
+			}
+		}
+		add("", file.Doc)
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				add(funcDeclName(decl), decl.Doc)
+			case *ast.GenDecl:
+				for i, spec := range decl.Specs {
+					var name string
+					var doc *ast.CommentGroup
+					switch spec := spec.(type) {
+					case *ast.TypeSpec:
+						name, doc = spec.Name.Name, spec.Doc
+					case *ast.ValueSpec:
+						name, doc = spec.Names[0].Name, spec.Doc
+					default:
+						continue
+					}
+					if i == 0 {
+						add(name, decl.Doc)
+					}
+					add(name, doc)
+				}
+			}
+		}
+		manifest.Files[filepath.Base(filename)] = entry
+	}
+	return manifest, nil
+}
+
+// funcDeclName returns the name of a function, or <type>.<name> for a method.
+func funcDeclName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return fn.Name.Name
+	}
+	recv := fn.Recv.List[0].Type
+	for {
+		switch expr := recv.(type) {
+		case *ast.StarExpr:
+			recv = expr.X
+			continue
+		case *ast.IndexExpr:
+			recv = expr.X
+			continue
+		case *ast.IndexListExpr:
+			recv = expr.X
+			continue
+		case *ast.Ident:
+			return expr.Name + "." + fn.Name.Name
+		}
+		return fn.Name.Name
+	}
+}
+
+func hashSource(src []byte) string {
+	sum := sha256.Sum256(src)
+	return hex.EncodeToString(sum[:])
+}
+
+// manifestParser parses Go files for packages.Load, removing the bodies of functions without directives from files
+// listed with a matching hash in the manifest of their package, so that they are not type-checked.
+type manifestParser struct {
+	lock      sync.Mutex
+	manifests map[string]*Manifest // By directory, nil if the directory has no valid manifest
+}
+
+func newManifestParser() *manifestParser {
+	return &manifestParser{manifests: map[string]*Manifest{}}
+}
+
+func (m *manifestParser) parseFile(fset *token.FileSet, filename string, src []byte) (*ast.File, error) {
+	// The same mode as the default parser of packages.Load
+	file, err := parser.ParseFile(fset, filename, src, parser.AllErrors|parser.ParseComments)
+	if err != nil {
+		return file, err //nolint
+	}
+	manifest := m.manifest(filepath.Dir(filename))
+	if manifest == nil {
+		return file, nil
+	}
+	entry, ok := manifest.Files[filepath.Base(filename)]
+	if !ok || entry.SHA256 != hashSource(src) {
+		return file, nil
+	}
+	annotated := map[string]bool{}
+	for _, directive := range entry.Directives {
+		annotated[directive.Decl] = true
+	}
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && !annotated[funcDeclName(fn)] {
+			fn.Body = nil
+		}
+	}
+	return file, nil
+}
+
+// manifest returns the manifest in dir, or nil if there is none or it can't be read.
+func (m *manifestParser) manifest(dir string) *Manifest {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if manifest, ok := m.manifests[dir]; ok {
+		return manifest
+	}
+	var manifest *Manifest
+	if data, err := os.ReadFile(filepath.Join(dir, ManifestName)); err == nil { //nolint
+		manifest = &Manifest{}
+		if err := json.Unmarshal(data, manifest); err != nil || manifest.Version != manifestVersion {
+			manifest = nil
+		}
+	}
+	m.manifests[dir] = manifest
+	return manifest
+}
@@ -0,0 +1,95 @@
+package depgraph
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestManifest(t *testing.T) {
+	t.Parallel()
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+	dir := t.TempDir()
+	source := `package lib
+
+import "strings"
+
+type Store struct{ name string }
+
+//zero:provider
+func NewStore() *Store { return &Store{name: strings.ToUpper("store")} }
+
+//zero:api GET /store
+func (s *Store) Get() string { return s.name }
+
+func helper() string { return strings.TrimSpace(" helper ") }
+`
+	files := map[string]string{
+		"svc/go.mod": "module example.com/svc\n\ngo 1.25\n",
+		"svc/main.go": `package main
+
+import "example.com/lib"
+
+type Service struct{}
+
+//zero:provider
+func NewService(store *lib.Store) *Service { return &Service{} }
+
+//zero:api GET /users
+func (s *Service) List() {}
+
+func main() {}
+`,
+		"lib/go.mod": "module example.com/lib\n\ngo 1.25\n",
+		"lib/lib.go": source,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0750))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	}
+	cmd := exec.CommandContext(t.Context(), "go", "work", "init", "./svc", "./lib", filepath.Join(cwd, "../.."))
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	assert.NoError(t, err, "%s", output)
+
+	paths, err := WriteManifests(t.Context(), filepath.Join(dir, "lib"), []string{"./..."})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "lib", ManifestName)}, paths)
+	data, err := os.ReadFile(paths[0])
+	assert.NoError(t, err)
+	manifest := &Manifest{}
+	assert.NoError(t, json.Unmarshal(data, manifest))
+	assert.Equal(t, "example.com/lib", manifest.Package)
+	assert.Equal(t, []ManifestDirective{
+		{Decl: "NewStore", Directive: "zero:provider"},
+		{Decl: "Store.Get", Directive: "zero:api GET /store"},
+	}, manifest.Files["lib.go"].Directives)
+
+	bodies := func(src string) map[string]bool {
+		file, err := newManifestParser().parseFile(token.NewFileSet(), filepath.Join(dir, "lib", "lib.go"), []byte(src))
+		assert.NoError(t, err)
+		bodies := map[string]bool{}
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok {
+				bodies[funcDeclName(fn)] = fn.Body != nil
+			}
+		}
+		return bodies
+	}
+	assert.Equal(t, map[string]bool{"NewStore": true, "Store.Get": true, "helper": false}, bodies(source))
+	assert.Equal(t, map[string]bool{"NewStore": true, "Store.Get": true, "helper": true}, bodies(source+"\n// changed\n"),
+		"files that differ from the manifest should be parsed in full")
+
+	graph, err := Analyse(t.Context(), filepath.Join(dir, "svc"))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(graph.MissingDependencies()))
+	assert.Equal(t, 2, len(graph.APIs))
+}
@@ -0,0 +1,240 @@
+package depgraph
+
+import (
+	"cmp"
+	"fmt"
+	"go/token"
+	"go/types"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// MissingDependency is a dependency of a function in the graph that has no provider.
+type MissingDependency struct {
+	// Position of the function requiring the dependency.
+	Position token.Position
+	Function *types.Func
+	// Parameter is the name of the parameter requiring the dependency, or empty for the receiver of a method.
+	Parameter string
+	Type      types.Type
+	// Candidates are the fully-qualified names of weak providers of the type that were not selected.
+	Candidates []string
+	// Alternative is a provider of the pointer type of a missing value receiver, or of the value type of a missing
+	// pointer receiver, as the receiver was most likely declared with the wrong form.
+	Alternative *Provider
+}
+
+// String formats the missing dependency like a compiler diagnostic.
+func (m MissingDependency) String() string {
+	w := &strings.Builder{}
+	if m.Position.IsValid() {
+		fmt.Fprintf(w, "%s: ", m.Position)
+	}
+	if m.Parameter == "" {
+		fmt.Fprintf(w, "receiver of %s() is missing a provider for %s", m.Function.FullName(), m.Type)
+	} else {
+		fmt.Fprintf(w, "parameter %s of %s() is missing a provider for %s", m.Parameter, m.Function.FullName(), m.Type)
+	}
+	if len(m.Candidates) > 0 {
+		fmt.Fprintf(w, " (weak providers exist, select one with --resolve: %s)", strings.Join(m.Candidates, ", "))
+	}
+	if m.Alternative != nil {
+		provided := types.TypeString(m.Alternative.Provides, nil)
+		if _, isPointer := m.Type.(*types.Pointer); isPointer {
+			fmt.Fprintf(w, " (%s is provided by %s, return %s from it or use a value receiver)", provided, m.Alternative.FullName(), m.Type)
+		} else {
+			fmt.Fprintf(w, " (%s is provided by %s, use a pointer receiver or provide %s)", provided, m.Alternative.FullName(), m.Type)
+		}
+	}
+	return w.String()
+}
+
+// AmbiguousProvidersError is returned when none of the providers of a type could be selected.
+//
+// It lists each candidate with the flags that would select it, eg.
+//
+//	ambiguous providers for type example.com/service.Store, select one with --resolve or --module:
+//	  - example.com/service.NewMemoryStore (weak) in package example.com/service at store.go:10:1
+//	      --resolve example.com/service.NewMemoryStore
+//	  - example.com/service/redis.New (weak, module redis) in package example.com/service/redis at redis.go:20:1
+//	      --resolve example.com/service/redis.New
+//	      --module redis
+type AmbiguousProvidersError struct {
+	// Type is the type with ambiguous providers, eg. *database/sql.DB
+	Type string
+	// Candidates are the providers of the type, ordered by name.
+	Candidates []*Provider
+}
+
+func newAmbiguousProvidersError(key string, providers []*Provider) *AmbiguousProvidersError {
+	return &AmbiguousProvidersError{
+		Type: key,
+		Candidates: slices.SortedFunc(slices.Values(providers), func(a, b *Provider) int {
+			return strings.Compare(a.FullName(), b.FullName())
+		}),
+	}
+}
+
+// Resolve returns the value of --resolve that selects the candidate.
+//
+// Generic providers are selected for the ambiguous type only, as selecting them for all types would also select them
+// for their other instantiations.
+func (e *AmbiguousProvidersError) Resolve(candidate *Provider) string {
+	if candidate.IsGeneric {
+		return e.Type + "=" + candidate.FullName()
+	}
+	return candidate.FullName()
+}
+
+func (e *AmbiguousProvidersError) Error() string {
+	// --module only selects a candidate if no other candidate is in the same module.
+	modules := map[string]int{}
+	for _, provider := range e.Candidates {
+		if provider.Module != "" {
+			modules[provider.Module]++
+		}
+	}
+	w := &strings.Builder{}
+	fmt.Fprintf(w, "ambiguous providers for type %s, select one with --resolve", e.Type)
+	if slices.Contains(slices.Collect(maps.Values(modules)), 1) {
+		w.WriteString(" or --module")
+	}
+	w.WriteString(":")
+	for _, provider := range e.Candidates {
+		var flags []string
+		if provider.Directive.Weak {
+			flags = append(flags, "weak")
+		}
+		if provider.Directive.Multi {
+			flags = append(flags, "multi")
+		}
+		if provider.IsGeneric {
+			flags = append(flags, "generic")
+		}
+		if provider.Module != "" {
+			flags = append(flags, "module "+provider.Module)
+		}
+		fmt.Fprintf(w, "\n  - %s", provider.FullName())
+		if len(flags) > 0 {
+			fmt.Fprintf(w, " (%s)", strings.Join(flags, ", "))
+		}
+		fmt.Fprintf(w, " in package %s", provider.Package.PkgPath)
+		if provider.Position.IsValid() {
+			fmt.Fprintf(w, " at %s", provider.Position)
+		}
+		if resolve := e.Resolve(provider); strings.ContainsAny(resolve, "=*[") {
+			fmt.Fprintf(w, "\n      --resolve '%s'", resolve)
+		} else {
+			fmt.Fprintf(w, "\n      --resolve %s", resolve)
+		}
+		if modules[provider.Module] == 1 {
+			fmt.Fprintf(w, "\n      --module %s", provider.Module)
+		}
+	}
+	return w.String()
+}
+
+// MissingDependencies returns a diagnostic for every dependency in [Graph.Missing], ordered by position.
+func (g *Graph) MissingDependencies() []MissingDependency {
+	positions := g.functionPositions()
+	out := []MissingDependency{}
+	for fn, missing := range g.Missing {
+		for _, typ := range missing {
+			dep := MissingDependency{
+				Position:  positions[fn],
+				Function:  fn,
+				Parameter: missingParameter(fn, typ),
+				Type:      typ,
+			}
+			for _, provider := range g.discovered[types.TypeString(typ, nil)] {
+				if provider.Directive != nil && provider.Directive.Weak {
+					dep.Candidates = append(dep.Candidates, provider.FullName())
+				}
+			}
+			slices.Sort(dep.Candidates)
+			if dep.Parameter == "" {
+				dep.Alternative = g.alternativeReceiverProvider(typ)
+			}
+			out = append(out, dep)
+		}
+	}
+	// Dependencies of the same function remain in parameter order.
+	slices.SortStableFunc(out, func(a, b MissingDependency) int {
+		return cmp.Or(
+			cmp.Compare(a.Position.Filename, b.Position.Filename),
+			cmp.Compare(a.Position.Line, b.Position.Line),
+			cmp.Compare(a.Position.Column, b.Position.Column),
+			strings.Compare(a.Function.FullName(), b.Function.FullName()),
+		)
+	})
+	return out
+}
+
+// alternativeReceiverProvider returns a provider of *T for a missing receiver of type T, or of T for a missing receiver
+// of type *T, or nil if there is none.
+func (g *Graph) alternativeReceiverProvider(receiver types.Type) *Provider {
+	alternative := types.Type(types.NewPointer(receiver))
+	if ptr, ok := receiver.(*types.Pointer); ok {
+		alternative = ptr.Elem()
+	}
+	for _, provider := range g.candidates[types.TypeString(alternative, nil)] {
+		if !provider.IsGeneric {
+			return provider
+		}
+	}
+	return nil
+}
+
+// functionPositions returns the declaration position of every function in the graph.
+func (g *Graph) functionPositions() map[*types.Func]token.Position {
+	positions := map[*types.Func]token.Position{}
+	for _, providers := range g.Providers {
+		for _, provider := range providers {
+			if provider.Function != nil {
+				positions[provider.Function] = provider.Position
+			}
+		}
+	}
+	for _, api := range g.APIs {
+		positions[api.Function] = api.Position
+	}
+	for _, cron := range g.CronJobs {
+		positions[cron.Function] = cron.Position
+	}
+	for _, subscription := range g.Subscriptions {
+		positions[subscription.Function] = subscription.Position
+	}
+	for _, step := range g.Steps {
+		positions[step.Function] = step.Position
+	}
+	for _, worker := range g.Workers {
+		positions[worker.Function] = worker.Position
+	}
+	for _, middleware := range g.Middleware {
+		positions[middleware.Function] = middleware.Position
+	}
+	return positions
+}
+
+// missingParameter returns the name of the parameter of fn requiring typ, or empty if it is the receiver.
+func missingParameter(fn *types.Func, typ types.Type) string {
+	sig := fn.Signature()
+	if recv := sig.Recv(); recv != nil && types.Identical(recv.Type(), typ) {
+		return ""
+	}
+	params := sig.Params()
+	for i := range params.Len() {
+		param := params.At(i)
+		// The parameter of a parameterised cron job is an element of the missing slice.
+		slice, isSlice := typ.(*types.Slice)
+		if !types.Identical(UnwrapDependency(param.Type()), typ) && (!isSlice || !types.Identical(param.Type(), slice.Elem())) {
+			continue
+		}
+		if param.Name() == "" || param.Name() == "_" {
+			return fmt.Sprintf("#%d", i)
+		}
+		return param.Name()
+	}
+	return ""
+}
@@ -0,0 +1,171 @@
+package depgraph
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/alecthomas/errors"
+)
+
+func TestMissingDependencies(t *testing.T) {
+	t.Parallel()
+	testCode := `
+package main
+
+import "context"
+
+type DB struct{}
+
+// Admin is only provided if selected, as its APIs are otherwise pruned.
+type Admin struct{}
+
+//zero:provider weak
+func NewAdmin() *Admin { return &Admin{} }
+
+//zero:api GET /admin
+func (a *Admin) Index() string { return "" }
+
+type Service struct{}
+
+//zero:provider
+func NewService(db *DB, admin *Admin) *Service { return &Service{} }
+
+type Users struct{}
+
+//zero:api GET /users
+func (u *Users) List(ctx context.Context) ([]string, error) { return nil, nil }
+`
+	graph := analyseTestCode(t, testCode, WithRoots("*test.Service"))
+	missing := graph.MissingDependencies()
+	diagnostics := []string{}
+	for _, dep := range missing {
+		diagnostics = append(diagnostics, strings.TrimPrefix(dep.String(), filepath.Dir(dep.Position.Filename)+"/"))
+	}
+	assert.Equal(t, []string{
+		`main.go:20:1: parameter db of test.NewService() is missing a provider for *test.DB`,
+		`main.go:20:1: parameter admin of test.NewService() is missing a provider for *test.Admin (weak providers exist, select one with --resolve: test.NewAdmin)`,
+		`main.go:25:1: receiver of (*test.Users).List() is missing a provider for *test.Users`,
+	}, diagnostics)
+}
+
+func TestMissingReceiverPointerValueMismatch(t *testing.T) {
+	t.Parallel()
+	testCode := `
+package main
+
+import "context"
+
+type Users struct{}
+
+//zero:provider
+func NewUsers() *Users { return &Users{} }
+
+//zero:api GET /users
+func (u Users) List(ctx context.Context) ([]string, error) { return nil, nil }
+
+type Orders struct{}
+
+//zero:provider
+func NewOrders() Orders { return Orders{} }
+
+//zero:api GET /orders
+func (o *Orders) List(ctx context.Context) ([]string, error) { return nil, nil }
+`
+	graph := analyseTestCode(t, testCode, WithRoots("*test.Users", "test.Orders"))
+	missing := graph.MissingDependencies()
+	diagnostics := []string{}
+	for _, dep := range missing {
+		diagnostics = append(diagnostics, strings.TrimPrefix(dep.String(), filepath.Dir(dep.Position.Filename)+"/"))
+	}
+	assert.Equal(t, []string{
+		`main.go:12:1: receiver of (test.Users).List() is missing a provider for test.Users (*test.Users is provided by test.NewUsers, use a pointer receiver or provide test.Users)`,
+		`main.go:20:1: receiver of (*test.Orders).List() is missing a provider for *test.Orders (test.Orders is provided by test.NewOrders, return *test.Orders from it or use a value receiver)`,
+	}, diagnostics)
+}
+
+func TestInferredRootPointerValueMismatch(t *testing.T) {
+	t.Parallel()
+	valueReceiver := `
+package main
+
+type Users struct{}
+
+//zero:provider
+func NewUsers() *Users { return &Users{} }
+
+//zero:api GET /users
+func (u Users) List() string { return "" }
+`
+	_, err := analyseTestCodeWithError(t, valueReceiver)
+	assert.EqualError(t, err, `requested root "test.Users" not found, but *test.Users is provided, use a pointer receiver or provide test.Users`)
+
+	pointerReceiver := `
+package main
+
+type Users struct{}
+
+//zero:provider
+func NewUsers() Users { return Users{} }
+
+//zero:api GET /users
+func (u *Users) List() string { return "" }
+`
+	_, err = analyseTestCodeWithError(t, pointerReceiver)
+	assert.EqualError(t, err, `requested root "*test.Users" not found, but test.Users is provided, return *test.Users from its provider or use a value receiver`)
+}
+
+func TestAmbiguousProviders(t *testing.T) {
+	t.Parallel()
+	code := `
+package test
+
+type Store interface{ Get() string }
+
+//zero:provider weak
+func NewRedisStore() Store { return nil }
+
+//zero:provider weak
+func NewMemoryStore() Store { return nil }
+
+type Box[T any] struct{ value T }
+
+//zero:provider weak
+func NewBox[T any]() *Box[T] { return &Box[T]{} }
+
+//zero:provider weak
+func NewEmptyBox[T any]() *Box[T] { return nil }
+
+type Service struct{}
+
+//zero:provider
+func NewService(store Store, box *Box[string]) *Service { return &Service{} }
+`
+	_, err := analyseTestCodeWithError(t, code, WithRoots("*test.Service"))
+	assert.Error(t, err)
+	lines := strings.Split(err.Error(), "\n")
+	for i, line := range lines {
+		if _, position, ok := strings.Cut(line, " at "); ok {
+			lines[i] = strings.Replace(line, position, filepath.Base(position), 1)
+		}
+	}
+	assert.Equal(t, `ambiguous providers for type *test.Box[string], select one with --resolve:
+  - test.NewBox (weak, generic) in package test at main.go:15:1
+      --resolve '*test.Box[string]=test.NewBox'
+  - test.NewEmptyBox (weak, generic) in package test at main.go:18:1
+      --resolve '*test.Box[string]=test.NewEmptyBox'
+ambiguous providers for type test.Store, select one with --resolve:
+  - test.NewMemoryStore (weak) in package test at main.go:10:1
+      --resolve test.NewMemoryStore
+  - test.NewRedisStore (weak) in package test at main.go:7:1
+      --resolve test.NewRedisStore`, strings.Join(lines, "\n"))
+
+	var ambiguous *AmbiguousProvidersError
+	assert.True(t, errors.As(err, &ambiguous))
+	assert.Equal(t, "*test.Box[string]=test.NewBox", ambiguous.Resolve(ambiguous.Candidates[0]))
+
+	// The suggested selections resolve the ambiguity.
+	_, err = analyseTestCodeWithError(t, code, WithRoots("*test.Service"), WithProviders("*test.Box[string]=test.NewBox", "test.NewMemoryStore"))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,103 @@
+package depgraph
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/alecthomas/errors"
+	"golang.org/x/mod/modfile"
+)
+
+// findUp searches dir and its parents for a file, returning the directory containing it, or "" if it is not found.
+func findUp(dir, name string) string {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// workspaceModules returns the paths of the modules in the go.work workspace that dir is in, or nil if it is not in a
+// workspace.
+//
+// As with the go command, $GOWORK selects the workspace file, or disables workspace mode if it is "off".
+func workspaceModules(dir string) ([]string, error) {
+	workFile := os.Getenv("GOWORK")
+	switch workFile {
+	case "off":
+		return nil, nil
+	case "":
+		root := findUp(dir, "go.work")
+		if root == "" {
+			return nil, nil
+		}
+		workFile = filepath.Join(root, "go.work")
+	}
+	data, err := os.ReadFile(workFile) //nolint
+	if err != nil {
+		return nil, errors.Errorf("failed to read go.work file: %w", err)
+	}
+	work, err := modfile.ParseWork(workFile, data, nil)
+	if err != nil {
+		return nil, errors.Errorf("failed to parse go.work file: %w", err)
+	}
+	modules := make([]string, 0, len(work.Use))
+	for _, use := range work.Use {
+		modDir := use.Path
+		if !filepath.IsAbs(modDir) {
+			modDir = filepath.Join(filepath.Dir(workFile), modDir)
+		}
+		goModPath := filepath.Join(modDir, "go.mod")
+		data, err := os.ReadFile(goModPath) //nolint
+		if err != nil {
+			return nil, errors.Errorf("%s:%d: failed to read go.mod of workspace module: %w", workFile, use.Syntax.Start.Line, err)
+		}
+		modulePath := modfile.ModulePath(data)
+		if modulePath == "" {
+			return nil, errors.Errorf("%s: missing module path", goModPath)
+		}
+		modules = append(modules, modulePath)
+	}
+	return modules, nil
+}
+
+// inModules returns true if pkgPath is in one of modules.
+func inModules(pkgPath string, modules []string) bool {
+	for _, module := range modules {
+		if pkgPath == module || strings.HasPrefix(pkgPath, module+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// TidyModule runs "go mod tidy" for the module containing dir, followed by "go mod vendor" if the module's
+// dependencies are vendored.
+func TidyModule(ctx context.Context, dir string) error {
+	root := findUp(dir, "go.mod")
+	if root == "" {
+		return errors.Errorf("couldn't find a go.mod file above %s", dir)
+	}
+	commands := [][]string{{"mod", "tidy"}}
+	if _, err := os.Stat(filepath.Join(root, "vendor", "modules.txt")); err == nil {
+		commands = append(commands, []string{"mod", "vendor"})
+	}
+	for _, args := range commands {
+		cmd := exec.CommandContext(ctx, "go", args...)
+		cmd.Dir = root
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return errors.Errorf("failed to run 'go %s' in %s: %w", strings.Join(args, " "), root, err)
+		}
+	}
+	return nil
+}
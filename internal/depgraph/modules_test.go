@@ -0,0 +1,155 @@
+package depgraph
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestAnalyseWorkspace(t *testing.T) {
+	t.Parallel()
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+	dir := t.TempDir()
+	files := map[string]string{
+		"svc/go.mod": "module example.com/svc\n\ngo 1.25\n",
+		"svc/main.go": `package main
+
+import "example.com/lib"
+
+type Service struct{}
+
+//zero:provider
+func NewService(store *lib.Store) *Service { return &Service{} }
+
+//zero:api GET /users
+func (s *Service) List() {}
+
+func main() {}
+`,
+		"lib/go.mod": "module example.com/lib\n\ngo 1.25\n",
+		"lib/lib.go": `package lib
+
+type Store struct{}
+
+//zero:provider
+func NewStore() *Store { return &Store{} }
+`,
+		"lib/cmd/tool/main.go": `package main
+
+type Tool struct{}
+
+//zero:provider
+func NewTool() *Tool { return &Tool{} }
+
+//zero:api GET /tool
+func (t *Tool) Run() {}
+
+func main() {}
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0750))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	}
+	cmd := exec.CommandContext(t.Context(), "go", "work", "init", "./svc", "./lib", filepath.Join(cwd, "../.."))
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	assert.NoError(t, err, "%s", output)
+
+	graph, err := Analyse(t.Context(), filepath.Join(dir, "svc"))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(graph.MissingDependencies()))
+	assert.Equal(t, 1, len(graph.APIs), "main packages of other workspace modules should be ignored")
+	assert.Equal(t, "/users", graph.APIs[0].Pattern.Path())
+	assert.NotZero(t, graph.Providers["*example.com/lib.Store"])
+}
+
+func TestWithMod(t *testing.T) {
+	t.Parallel()
+	opts := &graphOptions{}
+	assert.NoError(t, WithMod("vendor")(opts))
+	assert.NoError(t, WithMod("")(opts))
+	assert.Equal(t, []string{"-mod=vendor"}, opts.buildFlags)
+	assert.EqualError(t, WithMod("bogus")(opts), `invalid -mod "bogus", expected one of mod, readonly or vendor`)
+}
+
+func TestAnalyseAll(t *testing.T) {
+	t.Parallel()
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.25\n",
+		"lib/lib.go": `package lib
+
+type Store struct{}
+
+//zero:provider
+func NewStore() *Store { return &Store{} }
+`,
+		"cmd/users/main.go": `package main
+
+import "example.com/app/lib"
+
+type Users struct{}
+
+//zero:provider
+func NewUsers(store *lib.Store) *Users { return &Users{} }
+
+//zero:api GET /users
+func (u *Users) List() {}
+
+func main() {}
+`,
+		"cmd/billing/main.go": `package main
+
+import "example.com/app/lib"
+
+type Billing struct{}
+
+//zero:provider
+func NewBilling(store *lib.Store) *Billing { return &Billing{} }
+
+//zero:api GET /invoices
+func (b *Billing) List() {}
+
+func main() {}
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0750))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	}
+	cmd := exec.CommandContext(t.Context(), "go", "work", "init", ".", filepath.Join(cwd, "../.."))
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	assert.NoError(t, err, "%s", output)
+
+	graphs, err := AnalyseAll(t.Context(), []Destination{
+		{Dir: filepath.Join(dir, "cmd/users")},
+		{Dir: filepath.Join(dir, "cmd/billing")},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(graphs))
+	for i, expected := range []struct {
+		dir, path, provider, other string
+	}{
+		{"cmd/users", "/users", "*example.com/app/cmd/users.Users", "*example.com/app/cmd/billing.Billing"},
+		{"cmd/billing", "/invoices", "*example.com/app/cmd/billing.Billing", "*example.com/app/cmd/users.Users"},
+	} {
+		graph := graphs[i]
+		assert.Equal(t, filepath.Join(dir, expected.dir), graph.DestDir)
+		assert.Equal(t, 0, len(graph.MissingDependencies()))
+		assert.Equal(t, 1, len(graph.APIs), "APIs of other destinations should be ignored")
+		assert.Equal(t, expected.path, graph.APIs[0].Pattern.Path())
+		assert.NotZero(t, graph.Providers[expected.provider])
+		assert.Zero(t, graph.Providers[expected.other])
+		assert.NotZero(t, graph.Providers["*example.com/app/lib.Store"])
+	}
+}
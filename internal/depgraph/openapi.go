@@ -0,0 +1,259 @@
+package depgraph
+
+import (
+	"go/token"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/alecthomas/errors"
+	"github.com/alecthomas/zero/internal/directiveparser"
+	"github.com/go-openapi/spec"
+	"golang.org/x/tools/go/packages"
+)
+
+// OpenAPIConfig is metadata for the generated OpenAPI specification, from //zero:openapi directives or the [openapi]
+// section of .zero.toml.
+type OpenAPIConfig struct {
+	Title       string `toml:"title" json:"title,omitempty"`
+	Version     string `toml:"version" json:"version,omitempty"`
+	Description string `toml:"description" json:"description,omitempty"`
+	Terms       string `toml:"terms" json:"terms,omitempty"`
+	// Servers the API is served from, as absolute URLs.
+	//
+	// Swagger 2.0 only supports a single host, so the host and base path are taken from the first server, and the
+	// schemes from all servers.
+	Servers []string        `toml:"servers" json:"servers,omitempty"`
+	Contact *OpenAPIContact `toml:"contact" json:"contact,omitempty"`
+	License *OpenAPILicense `toml:"license" json:"license,omitempty"`
+	// Tags maps tag names to their descriptions.
+	Tags map[string]string `toml:"tags" json:"tags,omitempty"`
+	// Security schemes, keyed by name.
+	Security map[string]*OpenAPISecurityScheme `toml:"security" json:"security,omitempty"`
+	// Authenticated is the names of the security schemes, any of which is required by APIs with the "authenticated"
+	// label. Defaults to all security schemes.
+	Authenticated []string `toml:"authenticated" json:"authenticated,omitempty"`
+}
+
+type OpenAPIContact struct {
+	Name  string `toml:"name" json:"name,omitempty"`
+	URL   string `toml:"url" json:"url,omitempty"`
+	Email string `toml:"email" json:"email,omitempty"`
+}
+
+type OpenAPILicense struct {
+	Name string `toml:"name" json:"name,omitempty"`
+	URL  string `toml:"url" json:"url,omitempty"`
+}
+
+// OpenAPISecurityScheme is a Swagger 2.0 security scheme.
+type OpenAPISecurityScheme struct {
+	// Type is one of "basic", "apiKey" or "oauth2".
+	Type        string `toml:"type" json:"type"`
+	Description string `toml:"description" json:"description,omitempty"`
+	// Name of the header or query parameter for "apiKey".
+	Name string `toml:"name" json:"name,omitempty"`
+	// In is "header" or "query" for "apiKey".
+	In string `toml:"in" json:"in,omitempty"`
+	// Flow is one of "implicit", "password", "application" or "accessCode" for "oauth2".
+	Flow             string            `toml:"flow" json:"flow,omitempty"`
+	AuthorizationURL string            `toml:"authorization-url" json:"authorizationUrl,omitempty"`
+	TokenURL         string            `toml:"token-url" json:"tokenUrl,omitempty"`
+	Scopes           map[string]string `toml:"scopes" json:"scopes,omitempty"`
+}
+
+// Merge other into the config, with values set in other taking precedence.
+func (c *OpenAPIConfig) Merge(other OpenAPIConfig) {
+	mergeString(&c.Title, other.Title)
+	mergeString(&c.Version, other.Version)
+	mergeString(&c.Description, other.Description)
+	mergeString(&c.Terms, other.Terms)
+	if other.Contact != nil {
+		mergeString(&c.contact().Name, other.Contact.Name)
+		mergeString(&c.contact().URL, other.Contact.URL)
+		mergeString(&c.contact().Email, other.Contact.Email)
+	}
+	if other.License != nil {
+		mergeString(&c.license().Name, other.License.Name)
+		mergeString(&c.license().URL, other.License.URL)
+	}
+	if len(other.Servers) > 0 {
+		c.Servers = other.Servers
+	}
+	if len(other.Authenticated) > 0 {
+		c.Authenticated = other.Authenticated
+	}
+	if len(other.Tags) > 0 && c.Tags == nil {
+		c.Tags = map[string]string{}
+	}
+	maps.Copy(c.Tags, other.Tags)
+	if len(other.Security) > 0 && c.Security == nil {
+		c.Security = map[string]*OpenAPISecurityScheme{}
+	}
+	maps.Copy(c.Security, other.Security)
+}
+
+// Validate the configuration.
+func (c *OpenAPIConfig) Validate() error {
+	for _, server := range c.Servers {
+		u, err := url.Parse(server)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return errors.Errorf("openapi server %q must be an absolute URL", server)
+		}
+	}
+	for name, scheme := range c.Security {
+		switch scheme.Type {
+		case "basic":
+		case "apiKey":
+			if scheme.Name == "" || (scheme.In != "header" && scheme.In != "query") {
+				return errors.Errorf("openapi security scheme %q: apiKey requires a name and in=header|query", name)
+			}
+		case "oauth2":
+			if !slices.Contains([]string{"implicit", "password", "application", "accessCode"}, scheme.Flow) {
+				return errors.Errorf("openapi security scheme %q: unknown oauth2 flow %q", name, scheme.Flow)
+			}
+		default:
+			return errors.Errorf("openapi security scheme %q: unknown type %q, expected basic, apiKey or oauth2", name, scheme.Type)
+		}
+	}
+	for _, name := range c.Authenticated {
+		if _, ok := c.Security[name]; !ok {
+			return errors.Errorf("openapi authenticated security scheme %q is not defined", name)
+		}
+	}
+	return nil
+}
+
+// apply the configuration to swagger.
+func (c *OpenAPIConfig) apply(swagger *spec.Swagger) {
+	info := &swagger.Info.InfoProps
+	info.Description = c.Description
+	info.TermsOfService = c.Terms
+	if c.Contact != nil {
+		info.Contact = &spec.ContactInfo{ContactInfoProps: spec.ContactInfoProps{Name: c.Contact.Name, URL: c.Contact.URL, Email: c.Contact.Email}}
+	}
+	if c.License != nil {
+		info.License = &spec.License{LicenseProps: spec.LicenseProps{Name: c.License.Name, URL: c.License.URL}}
+	}
+	for i, server := range c.Servers {
+		u, err := url.Parse(server)
+		if err != nil {
+			continue
+		}
+		if i == 0 {
+			swagger.Host = u.Host
+			swagger.BasePath = u.Path
+		}
+		if !slices.Contains(swagger.Schemes, u.Scheme) {
+			swagger.Schemes = append(swagger.Schemes, u.Scheme)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.Tags)) {
+		swagger.Tags = append(swagger.Tags, spec.NewTag(name, c.Tags[name], nil))
+	}
+	if len(c.Security) > 0 {
+		swagger.SecurityDefinitions = spec.SecurityDefinitions{}
+	}
+	for name, scheme := range c.Security {
+		swagger.SecurityDefinitions[name] = &spec.SecurityScheme{SecuritySchemeProps: spec.SecuritySchemeProps{
+			Type:             scheme.Type,
+			Description:      scheme.Description,
+			Name:             scheme.Name,
+			In:               scheme.In,
+			Flow:             scheme.Flow,
+			AuthorizationURL: scheme.AuthorizationURL,
+			TokenURL:         scheme.TokenURL,
+			Scopes:           scheme.Scopes,
+		}}
+	}
+}
+
+// securityRequirements returns the security requirements of APIs with the "authenticated" label, any one of which
+// must be satisfied.
+func (c *OpenAPIConfig) securityRequirements() []map[string][]string {
+	names := c.Authenticated
+	if len(names) == 0 {
+		names = slices.Sorted(maps.Keys(c.Security))
+	}
+	out := make([]map[string][]string, 0, len(names))
+	for _, name := range names {
+		out = append(out, map[string][]string{name: {}})
+	}
+	return out
+}
+
+// packageOpenAPI merges the //zero:openapi directives on the package clause of pkg into config.
+func packageOpenAPI(pkg *packages.Package, fset *token.FileSet, config *OpenAPIConfig) error {
+	for _, file := range pkg.Syntax {
+		if file.Doc == nil {
+			continue
+		}
+		for _, comment := range file.Doc.List {
+			if !strings.HasPrefix(comment.Text, "//zero:openapi") {
+				continue
+			}
+			directive, err := directiveparser.Parse(comment.Text[2:])
+			if err != nil {
+				return errors.Errorf("%s: %w", fset.Position(comment.Pos()), err)
+			}
+			openapi, ok := directive.(*directiveparser.DirectiveOpenAPI)
+			if !ok {
+				continue
+			}
+			var directiveConfig OpenAPIConfig
+			for _, option := range openapi.Options {
+				switch option.Name {
+				case "title":
+					directiveConfig.Title = option.Value
+				case "version":
+					directiveConfig.Version = option.Value
+				case "description":
+					directiveConfig.Description = option.Value
+				case "terms":
+					directiveConfig.Terms = option.Value
+				case "server":
+					config.Servers = append(config.Servers, option.Value)
+				case "contact":
+					directiveConfig.contact().Name = option.Value
+				case "email":
+					directiveConfig.contact().Email = option.Value
+				case "url":
+					directiveConfig.contact().URL = option.Value
+				case "license":
+					directiveConfig.license().Name = option.Value
+				case "license_url":
+					directiveConfig.license().URL = option.Value
+				case "tag":
+					name, description, _ := strings.Cut(option.Value, "=")
+					if directiveConfig.Tags == nil {
+						directiveConfig.Tags = map[string]string{}
+					}
+					directiveConfig.Tags[name] = description
+				}
+			}
+			config.Merge(directiveConfig)
+		}
+	}
+	return nil
+}
+
+func mergeString(dest *string, value string) {
+	if value != "" {
+		*dest = value
+	}
+}
+
+func (c *OpenAPIConfig) contact() *OpenAPIContact {
+	if c.Contact == nil {
+		c.Contact = &OpenAPIContact{}
+	}
+	return c.Contact
+}
+
+func (c *OpenAPIConfig) license() *OpenAPILicense {
+	if c.License == nil {
+		c.License = &OpenAPILicense{}
+	}
+	return c.License
+}
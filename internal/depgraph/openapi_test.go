@@ -130,6 +130,154 @@ func TestAPIGenerateOpenAPIOperation(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:    "ETagGet",
+			funcSig: "GetUser:ctx context.Context,userID string:*User,error",
+			pattern: &directiveparser.DirectiveAPI{
+				Method: "GET",
+				Segments: []directiveparser.Segment{
+					directiveparser.LiteralSegment{Literal: "users"},
+					directiveparser.WildcardSegment{Name: "userID"},
+				},
+				Labels: []*directiveparser.Label{
+					{Name: "etag"},
+				},
+			},
+			expected: &spec.Operation{ //nolint
+				OperationProps: spec.OperationProps{
+					Tags: []string{"test"},
+					Parameters: []spec.Parameter{
+						{
+							ParamProps: spec.ParamProps{
+								Name:     "userID",
+								In:       "path",
+								Required: true,
+							},
+							SimpleSchema: spec.SimpleSchema{
+								Type: "string",
+							},
+						},
+						{
+							ParamProps: spec.ParamProps{
+								Name:        "If-None-Match",
+								In:          "header",
+								Description: "Respond with 304 Not Modified if the response matches one of these ETags.",
+							},
+							SimpleSchema: spec.SimpleSchema{
+								Type: "string",
+							},
+						},
+					},
+					Responses: &spec.Responses{
+						ResponsesProps: spec.ResponsesProps{
+							StatusCodeResponses: map[int]spec.Response{
+								200: {
+									ResponseProps: spec.ResponseProps{
+										Description: "Success",
+										Schema: &spec.Schema{
+											SchemaProps: spec.SchemaProps{
+												Ref: spec.MustCreateRef("#/definitions/test.User"),
+											},
+										},
+										Headers: map[string]spec.Header{
+											"ETag": {SimpleSchema: spec.SimpleSchema{Type: "string"}},
+										},
+									},
+								},
+								304: {
+									ResponseProps: spec.ResponseProps{
+										Description: "Not Modified",
+									},
+								},
+								400: {
+									ResponseProps: spec.ResponseProps{
+										Description: "Bad Request",
+									},
+								},
+								500: {
+									ResponseProps: spec.ResponseProps{
+										Description: "Internal Server Error",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:    "IdempotentPost",
+			funcSig: "CreateUser:ctx context.Context,req CreateUserRequest:*User,error",
+			pattern: &directiveparser.DirectiveAPI{
+				Method: "POST",
+				Segments: []directiveparser.Segment{
+					directiveparser.LiteralSegment{Literal: "users"},
+				},
+				Labels: []*directiveparser.Label{
+					{Name: "idempotent"},
+				},
+			},
+			expected: &spec.Operation{ //nolint
+				OperationProps: spec.OperationProps{
+					Tags: []string{"test"},
+					Parameters: []spec.Parameter{
+						{
+							ParamProps: spec.ParamProps{
+								Name:     "body",
+								In:       "body",
+								Required: true,
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: spec.MustCreateRef("#/definitions/test.CreateUserRequest"),
+									},
+								},
+							},
+						},
+						{
+							ParamProps: spec.ParamProps{
+								Name:        "Idempotency-Key",
+								In:          "header",
+								Description: "Responses to requests with a previously used key are replayed.",
+							},
+							SimpleSchema: spec.SimpleSchema{
+								Type: "string",
+							},
+						},
+					},
+					Responses: &spec.Responses{
+						ResponsesProps: spec.ResponsesProps{
+							StatusCodeResponses: map[int]spec.Response{
+								200: {
+									ResponseProps: spec.ResponseProps{
+										Description: "Success",
+										Schema: &spec.Schema{
+											SchemaProps: spec.SchemaProps{
+												Ref: spec.MustCreateRef("#/definitions/test.User"),
+											},
+										},
+									},
+								},
+								400: {
+									ResponseProps: spec.ResponseProps{
+										Description: "Bad Request",
+									},
+								},
+								409: {
+									ResponseProps: spec.ResponseProps{
+										Description: "Conflict",
+									},
+								},
+								500: {
+									ResponseProps: spec.ResponseProps{
+										Description: "Internal Server Error",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
 		{
 			name:    "DeleteEndpoint",
 			funcSig: "DeleteUser:ctx context.Context,userID string:error",
@@ -344,6 +492,7 @@ func TestGraphGenerateOpenAPISpec(t *testing.T) {
 						PathItemProps: spec.PathItemProps{
 							Get: &spec.Operation{ //nolint
 								OperationProps: spec.OperationProps{
+									ID:   "GetUser",
 									Tags: []string{"test"},
 									Parameters: []spec.Parameter{
 										{
@@ -391,6 +540,7 @@ func TestGraphGenerateOpenAPISpec(t *testing.T) {
 						PathItemProps: spec.PathItemProps{
 							Post: &spec.Operation{ //nolint
 								OperationProps: spec.OperationProps{
+									ID:   "CreateUser",
 									Tags: []string{"test"},
 									Parameters: []spec.Parameter{
 										{
@@ -732,3 +882,149 @@ func TestGraphGenerateOpenAPISpecWithNamedStructDefinitions(t *testing.T) {
 	responseSchema := getOp.Responses.StatusCodeResponses[200].Schema
 	assert.Equal(t, "#/definitions/test.User", responseSchema.Ref.String())
 }
+
+func TestGenerateOpenAPISpecFieldMetadata(t *testing.T) {
+	t.Parallel()
+	graph := analyseTestCode(t, `
+package main
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service {
+	return &Service{}
+}
+
+type User struct {
+	// Name of the user.
+	Name   string   `+"`json:\"name\" required:\"\" example:\"Alice\"`"+`
+	Email  string   `+"`json:\"email\" format:\"email\"`"+` // Email address.
+	Age    int      `+"`json:\"age\" doc:\"Age in years.\" example:\"42\"`"+`
+	Role   string   `+"`json:\"role\" enum:\"admin,user\"`"+`
+	Scopes []string `+"`json:\"scopes\" enum:\"read, write\"`"+`
+}
+
+//zero:api POST /users
+func (s *Service) CreateUser(user User) error {
+	return nil
+}
+`)
+	swagger := graph.GenerateOpenAPISpec("Test API", "1.0.0")
+	user := swagger.Definitions["main.User"]
+	assert.Equal(t, []string{"name"}, user.Required)
+
+	name := user.Properties["name"]
+	assert.Equal(t, "Name of the user.", name.Description)
+	assert.Equal(t, any("Alice"), name.Example)
+
+	email := user.Properties["email"]
+	assert.Equal(t, "Email address.", email.Description)
+	assert.Equal(t, "email", email.Format)
+
+	age := user.Properties["age"]
+	assert.Equal(t, "Age in years.", age.Description)
+	assert.Equal(t, any(int64(42)), age.Example)
+
+	assert.Equal(t, []any{"admin", "user"}, user.Properties["role"].Enum)
+	assert.Equal(t, []any{"read", "write"}, user.Properties["scopes"].Items.Schema.Enum)
+}
+
+func TestGenerateOpenAPISpecStreamingBody(t *testing.T) {
+	t.Parallel()
+	graph := analyseTestCode(t, `
+package main
+
+import "io"
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service {
+	return &Service{}
+}
+
+//zero:api PUT /blobs/{key} maxbody=10MB
+func (s *Service) PutBlob(key string, body io.Reader) error {
+	return nil
+}
+
+//zero:api POST /images consumes=image/png,image/jpeg
+func (s *Service) UploadImage(body io.Reader) error {
+	return nil
+}
+`)
+	swagger := graph.GenerateOpenAPISpec("Test API", "1.0.0")
+	binary := &spec.Schema{SchemaProps: spec.SchemaProps{Type: spec.StringOrArray{"string"}, Format: "binary"}}
+
+	put := swagger.Paths.Paths["/blobs/{key}"].Put
+	assert.Equal(t, []string{"application/octet-stream"}, put.Consumes)
+	assert.Equal(t, 2, len(put.Parameters))
+	assert.Equal(t, "body", put.Parameters[1].In)
+	assert.Equal(t, binary, put.Parameters[1].Schema)
+
+	upload := swagger.Paths.Paths["/images"].Post
+	assert.Equal(t, []string{"image/png", "image/jpeg"}, upload.Consumes)
+	assert.Equal(t, binary, upload.Parameters[0].Schema)
+	assert.Equal(t, "Unsupported Media Type", upload.Responses.StatusCodeResponses[415].Description)
+}
+
+func TestGenerateOpenAPISpecConfig(t *testing.T) {
+	t.Parallel()
+	graph := analyseTestCode(t, `
+//zero:openapi title="Users API" version=1.2.0 server=https://api.example.com/v1
+//zero:openapi contact="API Team" email=api@example.com tag=users="User management"
+package main
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service {
+	return &Service{}
+}
+
+//zero:api GET /users tag=users
+func (s *Service) ListUsers() ([]string, error) {
+	return nil, nil
+}
+
+//zero:api DELETE /users/{id} tag=users authenticated
+func (s *Service) DeleteUser(id string) error {
+	return nil
+}
+`)
+	assert.Equal(t, "Users API", graph.OpenAPI.Title)
+	graph.OpenAPI.Merge(OpenAPIConfig{
+		Version: "2.0.0",
+		Servers: []string{"https://api.example.com/v2", "http://localhost:8080/v2"},
+		Security: map[string]*OpenAPISecurityScheme{
+			"bearer": {Type: "apiKey", Name: "Authorization", In: "header"},
+		},
+	})
+	assert.NoError(t, graph.OpenAPI.Validate())
+
+	swagger := graph.GenerateOpenAPISpec("", "")
+	assert.Equal(t, "Users API", swagger.Info.Title)
+	assert.Equal(t, "2.0.0", swagger.Info.Version)
+	assert.Equal(t, &spec.ContactInfo{ContactInfoProps: spec.ContactInfoProps{Name: "API Team", Email: "api@example.com"}}, swagger.Info.Contact)
+	assert.Equal(t, "api.example.com", swagger.Host)
+	assert.Equal(t, "/v2", swagger.BasePath)
+	assert.Equal(t, []string{"https", "http"}, swagger.Schemes)
+	assert.Equal(t, []spec.Tag{spec.NewTag("users", "User management", nil)}, swagger.Tags)
+	assert.Equal(t, "apiKey", swagger.SecurityDefinitions["bearer"].Type)
+
+	list := swagger.Paths.Paths["/users"].Get
+	assert.Equal(t, 0, len(list.Security))
+	remove := swagger.Paths.Paths["/users/{id}"].Delete
+	assert.Equal(t, []map[string][]string{{"bearer": {}}}, remove.Security)
+	assert.Equal(t, "Unauthorized", remove.Responses.StatusCodeResponses[401].Description)
+}
+
+func TestOpenAPIConfigValidate(t *testing.T) {
+	t.Parallel()
+	config := OpenAPIConfig{Servers: []string{"/v1"}}
+	assert.EqualError(t, config.Validate(), `openapi server "/v1" must be an absolute URL`)
+	config = OpenAPIConfig{Security: map[string]*OpenAPISecurityScheme{"key": {Type: "apiKey"}}}
+	assert.EqualError(t, config.Validate(), `openapi security scheme "key": apiKey requires a name and in=header|query`)
+	config = OpenAPIConfig{Authenticated: []string{"bearer"}}
+	assert.EqualError(t, config.Validate(), `openapi authenticated security scheme "bearer" is not defined`)
+}
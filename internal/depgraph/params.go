@@ -0,0 +1,117 @@
+package depgraph
+
+import (
+	"go/types"
+	"reflect"
+
+	"github.com/alecthomas/errors"
+	"github.com/alecthomas/zero/internal/directiveparser"
+	"github.com/go-openapi/spec"
+)
+
+// ParamsField is a field of a params struct parameter of an API, bound to a path wildcard or query parameter by its
+// `path:"<wildcard>"` or `query:"<name>"` tag, eg.
+//
+//	type ListPostsParams struct {
+//		UserID string `path:"id"`
+//		Limit  int    `query:"limit"`
+//	}
+//
+//	//zero:api GET /users/{id}/posts
+//	func (s *Service) ListPosts(params ListPostsParams) ([]Post, error)
+//
+// Params structs are decoded by zero.DecodeParams.
+type ParamsField struct {
+	// In is "path" or "query".
+	In string
+	// Name of the path wildcard or query parameter.
+	Name string
+	// Field is the struct field.
+	Field *types.Var
+}
+
+// paramsFields returns the fields of t bound to path wildcards and query parameters, or nil if t is not a params struct.
+//
+// A params struct is a struct with at least one field, including fields of exported embedded structs, with a path or
+// query tag.
+func paramsFields(t types.Type) []*ParamsField {
+	if _, ok := t.(*types.Named); !ok {
+		return nil
+	}
+	strct, ok := t.Underlying().(*types.Struct)
+	if !ok {
+		return nil
+	}
+	var fields []*ParamsField
+	for i := range strct.NumFields() {
+		field := strct.Field(i)
+		if !field.Exported() {
+			continue
+		}
+		tag := reflect.StructTag(strct.Tag(i))
+		if name, ok := tag.Lookup("path"); ok {
+			fields = append(fields, &ParamsField{In: "path", Name: name, Field: field})
+		} else if name, ok := tag.Lookup("query"); ok {
+			fields = append(fields, &ParamsField{In: "query", Name: name, Field: field})
+		} else if field.Anonymous() {
+			fields = append(fields, paramsFields(field.Type())...)
+		}
+	}
+	return fields
+}
+
+// checkParamsFields checks that the fields of a params struct are bound to wildcards of the API pattern, and are of
+// types that zero.DecodeParams can decode.
+func checkParamsFields(directive *directiveparser.DirectiveAPI, t types.Type, fields []*ParamsField) error {
+	strct := t.Underlying().(*types.Struct) //nolint
+	for i := range strct.NumFields() {
+		field := strct.Field(i)
+		tag := reflect.StructTag(strct.Tag(i))
+		_, path := tag.Lookup("path")
+		_, query := tag.Lookup("query")
+		if field.Exported() && !field.Anonymous() && !path && !query {
+			return errors.Errorf("field %s must have a path or query tag", field.Name())
+		}
+	}
+	for _, field := range fields {
+		fieldType := field.Field.Type()
+		switch field.In {
+		case "path":
+			if !directive.Wildcard(field.Name) {
+				return errors.Errorf("field %s is bound to unknown path wildcard %q", field.Field.Name(), field.Name)
+			}
+			if _, ok := fieldType.(*types.Pointer); ok || !isHeaderParameterType(fieldType) {
+				return errors.Errorf("field %s of type %s cannot be bound to a path wildcard", field.Field.Name(), types.TypeString(fieldType, nil))
+			}
+		case "query":
+			if slice, ok := fieldType.(*types.Slice); ok && !implementsTextUnmarshaler(fieldType) {
+				fieldType = slice.Elem()
+			}
+			if !isHeaderParameterType(fieldType) {
+				return errors.Errorf("field %s of type %s cannot be bound to a query parameter", field.Field.Name(), types.TypeString(field.Field.Type(), nil))
+			}
+		}
+	}
+	return nil
+}
+
+// paramsFieldSpec returns the OpenAPI parameter for a field of a params struct.
+func paramsFieldSpec(field *ParamsField) spec.Parameter {
+	parameter := spec.Parameter{
+		ParamProps: spec.ParamProps{
+			Name:     field.Name,
+			In:       field.In,
+			Required: field.In == "path",
+		},
+	}
+	fieldType := field.Field.Type()
+	if slice, ok := fieldType.(*types.Slice); ok && !implementsTextUnmarshaler(fieldType) {
+		itemType, _ := headerParameterType(slice.Elem())
+		parameter.Type = "array"
+		parameter.Items = &spec.Items{SimpleSchema: spec.SimpleSchema{Type: itemType}}
+		parameter.CollectionFormat = "multi"
+		return parameter
+	}
+	parameter.Type, _ = headerParameterType(fieldType)
+	return parameter
+}
@@ -0,0 +1,146 @@
+package depgraph
+
+import (
+	"cmp"
+	"go/types"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// PlanAction is what code generation does with a provider.
+type PlanAction string
+
+const (
+	// PlanInclude includes a provider that was explicitly selected, or is strong.
+	PlanInclude PlanAction = "include"
+	// PlanDefault includes a weak provider because there was no alternative, eg. http.DefaultServer.
+	PlanDefault PlanAction = "default"
+	// PlanPrune prunes a provider from the graph.
+	PlanPrune PlanAction = "prune"
+)
+
+// PlanEntry explains why a provider was included in, or pruned from, the graph.
+type PlanEntry struct {
+	Action PlanAction `json:"action"`
+	// Type is the type provided, eg. *database/sql.DB
+	Type string `json:"type"`
+	// Provider is the fully-qualified name of the provider.
+	Provider string `json:"provider"`
+	Weak     bool   `json:"weak,omitempty"`
+	// Reason is a human-readable explanation of the action.
+	Reason string `json:"reason"`
+	// Position is the position of the provider declaration.
+	Position string `json:"position,omitempty"`
+	// RequiredBy is the provider that included this provider with require=<provider>, if any.
+	RequiredBy string `json:"requiredBy,omitempty"`
+}
+
+// Plan returns what code generation will do with every discovered provider, ordered by action, type and provider.
+//
+// Included providers are those that are strong, selected with --resolve or --module, or required by another
+// provider. Weak providers included because there was no alternative are defaults.
+func (g *Graph) Plan() []PlanEntry {
+	picked := pickedProviders(g.pick)
+	required := map[string]string{}
+	included := map[string]bool{}
+	var entries []PlanEntry
+	add := func(typ string, provider *Provider, action PlanAction, reason string) {
+		entries = append(entries, PlanEntry{
+			Action:   action,
+			Type:     typ,
+			Provider: provider.FullName(),
+			Weak:     provider.Directive.Weak,
+			Reason:   reason,
+			Position: positionString(provider.Position),
+		})
+	}
+
+	for key, providers := range g.Providers {
+		for _, provider := range providers {
+			// Unresolved generic providers are keyed by their base type.
+			if key != types.TypeString(provider.Provides, nil) {
+				continue
+			}
+			included[provider.FullName()] = true
+			for _, require := range provider.Directive.Require {
+				required[resolveRequireFunc(provider.Package, require)] = provider.FullName()
+			}
+		}
+	}
+
+	include := func(typ string, provider *Provider, alternatives int) {
+		name := provider.FullName()
+		switch {
+		case slices.Contains(picked, name):
+			add(typ, provider, PlanInclude, "selected with --resolve")
+		case slices.Contains(g.modulePicks, name):
+			add(typ, provider, PlanInclude, "selected with --module "+provider.Module)
+		case !provider.Directive.Weak:
+			add(typ, provider, PlanInclude, "strong provider")
+		case required[name] != "":
+			add(typ, provider, PlanInclude, "required by "+required[name])
+			entries[len(entries)-1].RequiredBy = required[name]
+		case alternatives > 1:
+			add(typ, provider, PlanDefault, "weak provider, no strong provider was available")
+		default:
+			add(typ, provider, PlanDefault, "weak provider, no alternative")
+		}
+	}
+	for key, providers := range g.Providers {
+		for _, provider := range providers {
+			if key == types.TypeString(provider.Provides, nil) {
+				include(key, provider, len(g.candidates[getBaseTypeName(provider.Provides)]))
+			}
+		}
+	}
+	for key, group := range g.Groups {
+		for _, member := range group.Members {
+			included[member.FullName()] = true
+			add(key, member, PlanInclude, "member of group "+types.TypeString(group.Interface, nil))
+		}
+	}
+
+	for key, providers := range g.candidates {
+		for _, provider := range providers {
+			name := provider.FullName()
+			if included[name] {
+				continue
+			}
+			selected := g.Providers[key]
+			switch {
+			case !g.inProfile(provider):
+				add(key, provider, PlanPrune, "requires profile "+strings.Join(provider.Directive.Profile, " or "))
+			case g.replaced[name] != "":
+				add(key, provider, PlanPrune, "replaced by "+g.replaced[name])
+			case g.excluded[name]:
+				add(key, provider, PlanPrune, "weak provider whose APIs were not selected")
+			case len(selected) > 0 && !provider.IsGeneric:
+				add(key, provider, PlanPrune, "overridden by "+selected[0].FullName())
+			case len(provider.Directive.Group) > 0 && provider.Directive.Weak:
+				add(key, provider, PlanPrune, "weak group member, not selected with --resolve")
+			default:
+				add(key, provider, PlanPrune, "not required by any root")
+			}
+		}
+	}
+
+	order := map[PlanAction]int{PlanInclude: 0, PlanDefault: 1, PlanPrune: 2}
+	slices.SortFunc(entries, func(a, b PlanEntry) int {
+		return cmp.Or(
+			cmp.Compare(order[a.Action], order[b.Action]),
+			strings.Compare(a.Type, b.Type),
+			strings.Compare(a.Provider, b.Provider),
+		)
+	})
+	return slices.CompactFunc(entries, func(a, b PlanEntry) bool { return a == b })
+}
+
+// snapshotProviders copies all discovered providers before they are filtered and pruned, for [Graph.Plan].
+func snapshotProviders(providers map[string][]*Provider) map[string][]*Provider {
+	out := maps.Clone(providers)
+	for key, providerList := range out {
+		out[key] = slices.Clone(providerList)
+	}
+	return out
+}
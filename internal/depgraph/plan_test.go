@@ -0,0 +1,67 @@
+package depgraph
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestPlan(t *testing.T) {
+	t.Parallel()
+	graph := analyseTestCode(t, `
+package test
+
+import "net/http"
+
+type Store interface{ Get() string }
+
+type memoryStore struct{}
+
+func (memoryStore) Get() string { return "memory" }
+
+//zero:provider weak
+func NewMemoryStore() Store { return memoryStore{} }
+
+type redisStore struct{}
+
+func (redisStore) Get() string { return "redis" }
+
+//zero:provider weak
+func NewRedisStore() Store { return redisStore{} }
+
+type Clock struct{}
+
+//zero:provider weak
+func NewClock() *Clock { return &Clock{} }
+
+type Debug struct{}
+
+//zero:provider profile=dev
+func NewDebug() *Debug { return &Debug{} }
+
+type Unused struct{}
+
+//zero:provider
+func NewUnused() *Unused { return &Unused{} }
+
+type Service struct{}
+
+//zero:provider
+func NewService(store Store, clock *Clock) *Service { return &Service{} }
+
+//zero:api GET /
+func (s *Service) Index(w http.ResponseWriter) {}
+`, WithProviders("test.NewRedisStore"))
+	entries := map[string]PlanEntry{}
+	for _, entry := range graph.Plan() {
+		entry.Position = ""
+		entries[entry.Provider] = entry
+	}
+	assert.Equal(t, PlanEntry{Action: PlanInclude, Type: "*test.Service", Provider: "test.NewService", Reason: "strong provider"}, entries["test.NewService"])
+	assert.Equal(t, PlanEntry{Action: PlanInclude, Type: "test.Store", Provider: "test.NewRedisStore", Weak: true, Reason: "selected with --resolve"}, entries["test.NewRedisStore"])
+	assert.Equal(t, PlanEntry{Action: PlanDefault, Type: "*test.Clock", Provider: "test.NewClock", Weak: true, Reason: "weak provider, no alternative"}, entries["test.NewClock"])
+	assert.Equal(t, PlanEntry{Action: PlanDefault, Type: "*net/http.Server", Provider: "github.com/alecthomas/zero/providers/http.DefaultServer", Weak: true, Reason: "weak provider, no alternative"}, entries["github.com/alecthomas/zero/providers/http.DefaultServer"])
+	assert.Equal(t, PlanEntry{Action: PlanPrune, Type: "test.Store", Provider: "test.NewMemoryStore", Weak: true, Reason: "overridden by test.NewRedisStore"}, entries["test.NewMemoryStore"])
+	assert.Equal(t, PlanEntry{Action: PlanPrune, Type: "*test.Debug", Provider: "test.NewDebug", Reason: "requires profile dev"}, entries["test.NewDebug"])
+	assert.Equal(t, PlanEntry{Action: PlanPrune, Type: "*test.Unused", Provider: "test.NewUnused", Reason: "not required by any root"}, entries["test.NewUnused"])
+}
@@ -0,0 +1,109 @@
+package depgraph
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"github.com/alecthomas/errors"
+	"golang.org/x/tools/go/packages"
+)
+
+// Plugin handles a custom //zero:<directive> annotation.
+//
+// Plugins are registered with [WithPlugins].
+type Plugin interface {
+	// Directive is the name of the directive handled by the plugin, eg. "featureflag" for //zero:featureflag.
+	Directive() string
+	// Analyse a declaration annotated with the plugin's directive, returning any types that must be constructable by
+	// the injector. These are added to the roots of the graph.
+	Analyse(annotation *Annotation) ([]types.Type, error)
+}
+
+// Annotation is a declaration annotated with a custom directive handled by a [Plugin]. Annotations are annotated
+// like so:
+//
+//	//zero:<directive> [<args>]
+type Annotation struct {
+	// Position is the position of the annotated declaration.
+	Position token.Position
+	// Plugin that handled the annotation.
+	Plugin Plugin
+	// Args is the remainder of the directive following its name.
+	Args string
+	// Object is the annotated function, method, type, variable or constant.
+	Object types.Object
+	// Package is the package that contains the declaration.
+	Package *packages.Package
+	// Requires are the types returned by [Plugin.Analyse].
+	Requires []types.Type
+}
+
+// Parse a custom directive handled by one of plugins from a comment. Will return (nil, "") if none is found.
+func parsePluginDirective(doc *ast.CommentGroup, plugins map[string]Plugin) (Plugin, string) {
+	if doc == nil {
+		return nil, ""
+	}
+	for _, comment := range doc.List {
+		text, ok := strings.CutPrefix(comment.Text, "//zero:")
+		if !ok {
+			continue
+		}
+		name, args, _ := strings.Cut(text, " ")
+		if plugin, ok := plugins[name]; ok {
+			return plugin, strings.TrimSpace(args)
+		}
+	}
+	return nil, ""
+}
+
+// analysePluginDecl passes declarations annotated with a custom directive to its plugin.
+func analysePluginDecl(decl ast.Decl, pkg *packages.Package, graph *Graph, plugins map[string]Plugin, fset *token.FileSet) error {
+	if len(plugins) == 0 {
+		return nil
+	}
+	var (
+		doc   *ast.CommentGroup
+		names []*ast.Ident
+	)
+	switch decl := decl.(type) {
+	case *ast.FuncDecl:
+		doc = decl.Doc
+		names = []*ast.Ident{decl.Name}
+	case *ast.GenDecl:
+		doc = decl.Doc
+		for _, spec := range decl.Specs {
+			switch spec := spec.(type) {
+			case *ast.TypeSpec:
+				names = append(names, spec.Name)
+			case *ast.ValueSpec:
+				names = append(names, spec.Names...)
+			}
+		}
+	}
+	plugin, args := parsePluginDirective(doc, plugins)
+	if plugin == nil {
+		return nil
+	}
+	for _, name := range names {
+		obj := pkg.TypesInfo.Defs[name]
+		if obj == nil {
+			continue
+		}
+		annotation := &Annotation{
+			Position: fset.Position(name.Pos()),
+			Plugin:   plugin,
+			Args:     args,
+			Object:   obj,
+			Package:  pkg,
+		}
+		requires, err := plugin.Analyse(annotation)
+		if err != nil {
+			return errors.Errorf("%s: zero:%s: %w", annotation.Position, plugin.Directive(), err)
+		}
+		annotation.Requires = requires
+		graph.Annotations[plugin.Directive()] = append(graph.Annotations[plugin.Directive()], annotation)
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+package depgraph
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/alecthomas/errors"
+	"github.com/alecthomas/zero/internal/directiveparser"
+)
+
+// Route is an entry in the routing table of the generated service.
+type Route struct {
+	// Method is the HTTP method.
+	Method string `json:"method"`
+	// Pattern is the host and path of the http.ServeMux pattern.
+	Pattern string `json:"pattern"`
+	// Handler is the fully-qualified name of the API method.
+	Handler string `json:"handler"`
+	// Labels are the labels of the API, in the form <name>[=<value>].
+	Labels []string `json:"labels,omitempty"`
+	// Middleware are the fully-qualified names of the middleware applied to the API, innermost first.
+	Middleware []string `json:"middleware,omitempty"`
+	// Position is the position of the API method declaration.
+	Position string `json:"position,omitempty"`
+}
+
+// anyMethods are the methods an API without a method in its pattern is registered for. http.ServeMux also routes HEAD
+// requests to GET handlers, and responds to other methods with 405 Method Not Allowed and an Allow header.
+var anyMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// Patterns returns the http.ServeMux patterns the API is registered with, one for each of its methods.
+//
+// With a trailing slash policy, a path ending in a slash matches only that path, rather than every path it prefixes.
+func (a *API) Patterns() []string {
+	path, _ := a.slashPaths()
+	methods := a.methods()
+	patterns := make([]string, 0, len(methods))
+	for _, method := range methods {
+		patterns = append(patterns, method+" "+a.Pattern.Host+path)
+	}
+	return patterns
+}
+
+// AlternatePatterns returns the http.ServeMux patterns of the API's path with its trailing slash added or removed,
+// one for each of its methods, which are redirected, served or not found according to its trailing slash policy.
+func (a *API) AlternatePatterns() []string {
+	return a.alternatePatterns
+}
+
+// slashPaths returns the path of the API's patterns and the path with its trailing slash added or removed, if it has a
+// trailing slash policy. The root path and paths ending in a catch-all wildcard are unaffected.
+func (a *API) slashPaths() (path, alternate string) {
+	path = a.Pattern.Path()
+	if a.TrailingSlash == "" || path == "/" || len(a.Pattern.Segments) == 0 {
+		return path, ""
+	}
+	switch segment := a.Pattern.Segments[len(a.Pattern.Segments)-1].(type) {
+	case directiveparser.TrailingSegment:
+		return path + "{$}", strings.TrimSuffix(path, "/")
+	case directiveparser.WildcardSegment:
+		if segment.Remainder {
+			return path, ""
+		}
+	}
+	return path, path + "/{$}"
+}
+
+// resolveTrailingSlash sets the trailing slash policy of each API, from its slash=<policy> label or the default, and
+// the alternate patterns it is registered with.
+//
+// http.ServeMux redirects a path without a trailing slash to a pattern with one, so for the strict policy, the path
+// without the slash is registered as not found, unless it is the path of another API.
+func resolveTrailingSlash(graph *Graph, policy string) {
+	registered := map[string]bool{}
+	for _, api := range graph.APIs {
+		if api.Pattern == nil {
+			continue
+		}
+		// Labels are validated during analysis.
+		api.TrailingSlash, _ = api.Pattern.TrailingSlash()
+		if _, ok := api.Pattern.Label("slash"); !ok {
+			api.TrailingSlash = policy
+		}
+		for _, pattern := range api.Patterns() {
+			registered[pattern] = true
+		}
+	}
+	for _, api := range graph.APIs {
+		if api.Pattern == nil {
+			continue
+		}
+		path, alternate := api.slashPaths()
+		if alternate == "" || (api.TrailingSlash == "strict" && !strings.HasSuffix(path, "/{$}")) {
+			continue
+		}
+		for _, method := range api.methods() {
+			pattern := method + " " + api.Pattern.Host + alternate
+			if api.TrailingSlash == "strict" && registered[pattern] {
+				continue
+			}
+			api.alternatePatterns = append(api.alternatePatterns, pattern)
+		}
+	}
+}
+
+// methods returns the methods of the API, defaulting to those of its pattern if they have not been resolved.
+func (a *API) methods() []string {
+	if a.Methods != nil {
+		return a.Methods
+	}
+	if a.Pattern.Method != "" {
+		return []string{a.Pattern.Method}
+	}
+	return anyMethods
+}
+
+// resolveMethods sets the methods of each API. APIs without a method are registered for each of [anyMethods] that is
+// not registered by another API with the same host and path, so that eg. "GET /users" can override "/users".
+func resolveMethods(graph *Graph) error {
+	claimed := map[string]bool{}
+	for _, api := range graph.APIs {
+		if api.Pattern != nil && api.Pattern.Method != "" {
+			claimed[api.Pattern.Pattern()] = true
+		}
+	}
+	for _, api := range graph.APIs {
+		if api.Pattern == nil {
+			continue
+		}
+		if api.Pattern.Method != "" {
+			api.Methods = []string{api.Pattern.Method}
+			continue
+		}
+		api.Methods = []string{}
+		for _, method := range anyMethods {
+			if !claimed[method+" "+api.Pattern.Host+api.Pattern.Path()] {
+				api.Methods = append(api.Methods, method)
+			}
+		}
+		if len(api.Methods) == 0 {
+			return errors.Errorf("%s: %s() route %q is unreachable, as all of its methods are handled by other APIs",
+				api.Position, api.Function.FullName(), api.Pattern.Pattern())
+		}
+	}
+	return nil
+}
+
+// Routes returns the routing table of the generated service, in registration order, with a route for each method of
+// each API.
+func (g *Graph) Routes() []Route {
+	routes := make([]Route, 0, len(g.APIs))
+	for _, api := range g.APIs {
+		var labels, middleware []string
+		for _, label := range api.Pattern.Labels {
+			if label.Value == "" {
+				labels = append(labels, label.Name)
+			} else {
+				labels = append(labels, label.Name+"="+label.Value)
+			}
+		}
+		for _, m := range g.Middleware {
+			if m.Match(api) {
+				middleware = append(middleware, m.Function.FullName())
+			}
+		}
+		for _, method := range api.methods() {
+			routes = append(routes, Route{
+				Method:     method,
+				Pattern:    api.Pattern.Host + api.Pattern.Path(),
+				Handler:    api.Function.FullName(),
+				Labels:     labels,
+				Middleware: middleware,
+				Position:   positionString(api.Position),
+			})
+		}
+	}
+	return routes
+}
+
+// checkForConflictingRoutes returns an error for each pair of API endpoints whose patterns conflict, as the generated
+// http.ServeMux would otherwise panic at startup.
+//
+// Conflicts are detected by registering the patterns with an http.ServeMux, so they follow its precedence rules
+// exactly: patterns conflict if they match a common request and neither is more specific than the other.
+func checkForConflictingRoutes(graph *Graph) error {
+	var errs []error
+	mux := http.NewServeMux()
+	registered := []*API{}
+	for _, api := range graph.APIs {
+		if api.Pattern == nil {
+			continue
+		}
+		err := registerRoute(mux, api)
+		if err == nil {
+			registered = append(registered, api)
+			continue
+		}
+		conflicted := false
+		for _, prev := range registered {
+			pair := http.NewServeMux()
+			if registerRoute(pair, prev) != nil {
+				continue
+			}
+			if err := registerRoute(pair, api); err != nil {
+				errs = append(errs, errors.Errorf("%s: %s() route %q conflicts with %s() route %q at %s: %s",
+					api.Position, api.Function.FullName(), api.Pattern.Pattern(),
+					prev.Function.FullName(), prev.Pattern.Pattern(), prev.Position, describeConflict(err)))
+				conflicted = true
+			}
+		}
+		if !conflicted {
+			errs = append(errs, errors.Errorf("%s: %s() route %q is invalid: %w", api.Position, api.Function.FullName(), api.Pattern.Pattern(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// registerRoute registers the pattern of an API with mux, converting a registration panic into an error.
+func registerRoute(mux *http.ServeMux, api *API) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Errorf("%v", r)
+		}
+	}()
+	for _, pattern := range slices.Concat(api.Patterns(), api.AlternatePatterns()) {
+		mux.Handle(pattern, http.NotFoundHandler())
+	}
+	return nil
+}
+
+// describeConflict extracts the explanation of a conflict from an http.ServeMux registration panic.
+func describeConflict(err error) string {
+	msg := err.Error()
+	if _, description, ok := strings.Cut(msg, ":\n"); ok {
+		msg = description
+	}
+	return strings.Join(strings.Fields(msg), " ")
+}
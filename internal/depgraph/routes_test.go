@@ -0,0 +1,198 @@
+package depgraph
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestConflictingRoutes(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		patterns []string
+		err      string
+	}{
+		{"Distinct", []string{"GET /users", "POST /users"}, ""},
+		{"MoreSpecific", []string{"GET /users/{id}", "GET /users/me", "/users/{path...}"}, ""},
+		{"Identical", []string{"GET /users", "GET /users"}, `route "GET /users" conflicts with (*test.Service).Route0() route "GET /users" at `},
+		{"WildcardNames", []string{"GET /users/{id}", "GET /users/{name}"}, `route "GET /users/{name}" conflicts with (*test.Service).Route0() route "GET /users/{id}" at `},
+		{"Overlap", []string{"GET /users/{id}/posts", "GET /users/me/{resource}"}, `neither is more specific than the other`},
+		{"HostTakesPrecedence", []string{"example.com/users", "GET /users"}, ""},
+		{"MethodAndPath", []string{"GET /users/{id}", "/users/me"}, ""},
+		{"MethodOverride", []string{"/users", "GET /users"}, ""},
+		{"AnyMethod", []string{"ANY /users", "/users"}, `route "/users" conflicts with (*test.Service).Route0() route "/users" at `},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			testCode := `
+package test
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service { return &Service{} }
+`
+			for i, pattern := range test.patterns {
+				testCode += fmt.Sprintf("\n//zero:api %s\nfunc (s *Service) Route%d() {}\n", pattern, i)
+			}
+			_, err := analyseTestCodeWithError(t, testCode)
+			if test.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), test.err)
+				assert.Contains(t, err.Error(), "main.go:13:1: (*test.Service).Route1()")
+				assert.Contains(t, err.Error(), "main.go:10:1: ")
+			}
+		})
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	t.Parallel()
+	testCode := `
+package test
+
+import "net/http"
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service { return &Service{} }
+
+//zero:middleware authenticated
+func Auth(next http.Handler) http.Handler { return next }
+
+//zero:middleware
+func Logging(next http.Handler) http.Handler { return next }
+
+//zero:api GET /users
+func (s *Service) List() {}
+
+//zero:api POST example.com/users authenticated role=admin
+func (s *Service) Create() {}
+`
+	graph := analyseTestCode(t, testCode)
+	routes := graph.Routes()
+	for i := range routes {
+		routes[i].Position = filepath.Base(routes[i].Position)
+	}
+	assert.Equal(t, []Route{
+		{Method: "GET", Pattern: "/users", Handler: "(*test.Service).List", Middleware: []string{"test.Logging"}, Position: "main.go:18:1"},
+		{Method: "POST", Pattern: "example.com/users", Handler: "(*test.Service).Create", Labels: []string{"authenticated", "role=admin"}, Middleware: []string{"test.Auth", "test.Logging"}, Position: "main.go:21:1"},
+	}, routes)
+}
+
+func TestMethodlessRoutes(t *testing.T) {
+	t.Parallel()
+	graph := analyseTestCode(t, `
+package test
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service { return &Service{} }
+
+//zero:api /users
+func (s *Service) Users() {}
+
+//zero:api GET /users
+func (s *Service) List() {}
+`)
+	var routes []string
+	for _, route := range graph.Routes() {
+		routes = append(routes, route.Method+" "+route.Pattern+" "+route.Handler)
+	}
+	assert.Equal(t, []string{
+		"POST /users (*test.Service).Users",
+		"PUT /users (*test.Service).Users",
+		"PATCH /users (*test.Service).Users",
+		"DELETE /users (*test.Service).Users",
+		"GET /users (*test.Service).List",
+	}, routes)
+
+	swagger := graph.GenerateOpenAPISpec("Test", "1.0")
+	users := swagger.Paths.Paths["/users"]
+	assert.NotZero(t, users.Get)
+	assert.NotZero(t, users.Post)
+	assert.NotZero(t, users.Put)
+	assert.NotZero(t, users.Patch)
+	assert.NotZero(t, users.Delete)
+	assert.Zero(t, users.Head)
+}
+
+func TestMethodlessRouteUnreachable(t *testing.T) {
+	t.Parallel()
+	testCode := `
+package test
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service { return &Service{} }
+
+//zero:api /users
+func (s *Service) Users() {}
+`
+	for i, method := range []string{"GET", "POST", "PUT", "PATCH", "DELETE"} {
+		testCode += fmt.Sprintf("\n//zero:api %s /users\nfunc (s *Service) Route%d() {}\n", method, i)
+	}
+	_, err := analyseTestCodeWithError(t, testCode)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `main.go:10:1: (*test.Service).Users() route "/users" is unreachable, as all of its methods are handled by other APIs`)
+}
+
+func TestTrailingSlashPatterns(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		policy    string
+		patterns  []string
+		expected  []string
+		alternate []string
+		err       string
+	}{
+		{name: "Default", patterns: []string{"GET /users", "GET /items/"}, expected: []string{"GET /users", "GET /items/"}},
+		{name: "Redirect", policy: "redirect", patterns: []string{"GET /users", "GET /items/"}, expected: []string{"GET /users", "GET /items/{$}"}, alternate: []string{"GET /users/{$}", "GET /items"}},
+		{name: "Ignore", policy: "ignore", patterns: []string{"GET /users/{id}"}, expected: []string{"GET /users/{id}"}, alternate: []string{"GET /users/{id}/{$}"}},
+		{name: "Strict", policy: "strict", patterns: []string{"GET /users", "GET /items/"}, expected: []string{"GET /users", "GET /items/{$}"}, alternate: []string{"GET /items"}},
+		{name: "StrictDistinct", policy: "strict", patterns: []string{"GET /users", "GET /users/"}, expected: []string{"GET /users", "GET /users/{$}"}},
+		{name: "Label", patterns: []string{"GET /users slash=redirect", "GET /items/"}, expected: []string{"GET /users", "GET /items/"}, alternate: []string{"GET /users/{$}"}},
+		{name: "Unaffected", policy: "redirect", patterns: []string{"GET /", "GET /files/{path...}"}, expected: []string{"GET /", "GET /files/{path...}"}},
+		{name: "Conflict", policy: "redirect", patterns: []string{"GET /users", "GET /users/"}, err: `GET /users/{$} matches the same requests as GET /users/{$}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			testCode := `
+package test
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service { return &Service{} }
+`
+			for i, pattern := range test.patterns {
+				testCode += fmt.Sprintf("\n//zero:api %s\nfunc (s *Service) Route%d() {}\n", pattern, i)
+			}
+			graph, err := analyseTestCodeWithError(t, testCode, WithTrailingSlash(test.policy))
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), test.err)
+				return
+			}
+			assert.NoError(t, err)
+			var patterns, alternate []string
+			for _, api := range graph.APIs {
+				patterns = append(patterns, api.Patterns()...)
+				alternate = append(alternate, api.AlternatePatterns()...)
+			}
+			assert.Equal(t, test.expected, patterns)
+			assert.Equal(t, test.alternate, alternate)
+		})
+	}
+}
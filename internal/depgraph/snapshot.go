@@ -0,0 +1,84 @@
+package depgraph
+
+import (
+	"go/types"
+	"slices"
+	"strings"
+)
+
+// Snapshot returns a canonical text serialisation of the graph, with one line for each root, provider, group, config,
+// API route, cron job, subscription, worker and middleware, suitable for committing and comparing with
+// [DiffSnapshots].
+//
+// Types and functions are fully qualified, and positions are omitted, so that the snapshot only changes when the graph
+// does, eg. when a provider from a new package is added.
+func Snapshot(g *Graph) string {
+	var lines []string
+	for _, root := range g.Roots {
+		lines = append(lines, "root "+root)
+	}
+	for key, providers := range g.Providers {
+		for _, provider := range providers {
+			// Unresolved generic providers are keyed by their base type.
+			if key != types.TypeString(provider.Provides, nil) {
+				continue
+			}
+			requires := make([]string, len(provider.Requires))
+			for i, require := range provider.Requires {
+				requires[i] = types.TypeString(require, nil)
+			}
+			lines = append(lines, "provider "+key+" "+provider.FullName()+"("+strings.Join(requires, ", ")+")")
+		}
+	}
+	for key, group := range g.Groups {
+		members := make([]string, len(group.Members))
+		for i, member := range group.Members {
+			members[i] = member.FullName()
+		}
+		lines = append(lines, "group "+key+" "+strings.Join(members, ", "))
+	}
+	for key := range g.Configs {
+		lines = append(lines, "config "+key)
+	}
+	for _, route := range g.Routes() {
+		line := "api " + route.Method + " " + route.Pattern + " " + route.Handler
+		if len(route.Middleware) > 0 {
+			line += " " + strings.Join(route.Middleware, ", ")
+		}
+		lines = append(lines, line)
+	}
+	for _, job := range g.CronJobs {
+		lines = append(lines, "cron "+job.Function.FullName())
+	}
+	for _, subscription := range g.Subscriptions {
+		lines = append(lines, "subscription "+types.TypeString(subscription.TopicType, nil)+" "+subscription.Function.FullName())
+	}
+	for _, worker := range g.Workers {
+		lines = append(lines, "worker "+types.TypeString(worker.PayloadType, nil)+" "+worker.Function.FullName())
+	}
+	for _, middleware := range g.Middleware {
+		lines = append(lines, "middleware "+middleware.Function.FullName())
+	}
+	slices.Sort(lines)
+	lines = slices.Compact(lines)
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// DiffSnapshots returns the lines of actual missing from expected, prefixed with "+", and the lines of expected missing
+// from actual, prefixed with "-", both in the order of the snapshot.
+func DiffSnapshots(expected, actual string) []string {
+	expectedLines := strings.Split(strings.TrimSpace(expected), "\n")
+	actualLines := strings.Split(strings.TrimSpace(actual), "\n")
+	var diff []string
+	for _, line := range slices.Compact(slices.Sorted(slices.Values(slices.Concat(expectedLines, actualLines)))) {
+		inExpected, inActual := slices.Contains(expectedLines, line), slices.Contains(actualLines, line)
+		switch {
+		case line == "":
+		case inExpected && !inActual:
+			diff = append(diff, "- "+line)
+		case inActual && !inExpected:
+			diff = append(diff, "+ "+line)
+		}
+	}
+	return diff
+}
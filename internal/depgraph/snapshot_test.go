@@ -0,0 +1,62 @@
+package depgraph
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestSnapshot(t *testing.T) {
+	t.Parallel()
+	code := `
+package test
+
+import "net/http"
+
+type DB struct{}
+
+//zero:provider
+func NewDB() *DB { return &DB{} }
+
+type Unused struct{}
+
+//zero:provider
+func NewUnused() *Unused { return &Unused{} }
+
+//zero:config prefix="service-"
+type Config struct {
+	Name string
+}
+
+type Service struct{}
+
+//zero:provider
+func NewService(db *DB, config Config) *Service { return &Service{} }
+
+//zero:api GET /users
+func (s *Service) ListUsers(w http.ResponseWriter) {}
+`
+	graph := analyseTestCode(t, code)
+	snapshot := Snapshot(graph)
+	assert.Equal(t, snapshot, Snapshot(graph), "snapshots are deterministic")
+	for _, line := range []string{
+		"api GET /users (*test.Service).ListUsers\n",
+		"config test.Config\n",
+		"provider *test.DB test.NewDB()\n",
+		"provider *test.Service test.NewService(*test.DB, test.Config)\n",
+		"root *test.Service\n",
+	} {
+		assert.Contains(t, snapshot, line)
+	}
+	assert.NotContains(t, snapshot, "test.NewUnused")
+	assert.NotContains(t, snapshot, "main.go", "positions are omitted")
+
+	graph = analyseTestCode(t, strings.ReplaceAll(code, "db *DB, config Config", "db *DB, config Config, unused *Unused"))
+	assert.Equal(t, []string{
+		"- provider *test.Service test.NewService(*test.DB, test.Config)",
+		"+ provider *test.Service test.NewService(*test.DB, test.Config, *test.Unused)",
+		"+ provider *test.Unused test.NewUnused()",
+	}, DiffSnapshots(snapshot, Snapshot(graph)))
+	assert.Equal(t, []string(nil), DiffSnapshots(snapshot, snapshot))
+}
@@ -0,0 +1,109 @@
+package depgraph
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// PhaseTiming is the duration of a phase of generation, eg. loading packages.
+type PhaseTiming struct {
+	Phase    string        `json:"phase"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Stats summarises the size of the graph and the time taken to build it.
+type Stats struct {
+	// Phases of analysis, in order: load, analyse and prune.
+	Phases []PhaseTiming `json:"phases"`
+	// Packages is the number of packages loaded.
+	Packages int `json:"packages"`
+	// Discovered is the number of providers found, before pruning.
+	Discovered int `json:"discovered"`
+	// Included is the number of providers in the graph, including defaults.
+	Included int `json:"included"`
+	// Pruned is the number of providers pruned from the graph.
+	Pruned        int `json:"pruned"`
+	Configs       int `json:"configs"`
+	APIs          int `json:"apis"`
+	CronJobs      int `json:"cron_jobs"`
+	Subscriptions int `json:"subscriptions"`
+	Middleware    int `json:"middleware"`
+	// Chains are the longest chains of dependencies from the roots of the graph, longest first.
+	Chains [][]string `json:"chains"`
+}
+
+// maxChains is the number of dependency chains returned by [Graph.Stats].
+const maxChains = 5
+
+// Stats returns statistics about the graph.
+func (g *Graph) Stats() Stats {
+	stats := Stats{
+		Phases:        slices.Clone(g.phases),
+		Packages:      g.packages,
+		Configs:       len(g.Configs),
+		APIs:          len(g.APIs),
+		CronJobs:      len(g.CronJobs),
+		Subscriptions: len(g.Subscriptions),
+		Middleware:    len(g.Middleware),
+	}
+	for _, entry := range g.Plan() {
+		stats.Discovered++
+		if entry.Action == PlanPrune {
+			stats.Pruned++
+		} else {
+			stats.Included++
+		}
+	}
+	stats.Chains = g.longestChains(maxChains)
+	return stats
+}
+
+// longestChains returns up to n of the longest dependency chains starting at the roots of the graph.
+func (g *Graph) longestChains(n int) [][]string {
+	edges := dependencyEdges(g)
+	// The longest chain from each type, memoised. The graph is acyclic, as cycles fail analysis.
+	longest := map[string][]string{}
+	var chain func(key string) []string
+	chain = func(key string) []string {
+		if out, ok := longest[key]; ok {
+			return out
+		}
+		var deepest []string
+		for _, dep := range edges[key] {
+			if next := chain(dep); len(next) > len(deepest) {
+				deepest = next
+			}
+		}
+		out := append([]string{key}, deepest...)
+		longest[key] = out
+		return out
+	}
+	var chains [][]string
+	for _, root := range g.Roots {
+		if _, ok := edges[root]; ok {
+			chains = append(chains, chain(root))
+		}
+	}
+	slices.SortStableFunc(chains, func(a, b []string) int {
+		return cmp.Or(cmp.Compare(len(b), len(a)), strings.Compare(a[0], b[0]))
+	})
+	return chains[:min(n, len(chains))]
+}
+
+// startPhase starts timing a phase of analysis for [Graph.Stats], ending the current phase if any.
+func (g *Graph) startPhase(name string) {
+	g.endPhase()
+	g.phases = append(g.phases, PhaseTiming{Phase: name})
+	g.phaseStart = time.Now()
+}
+
+// endPhase ends the current phase of analysis, if any.
+func (g *Graph) endPhase() {
+	if g.phaseStart.IsZero() {
+		return
+	}
+	g.phases[len(g.phases)-1].Duration = time.Since(g.phaseStart)
+	g.phaseStart = time.Time{}
+}
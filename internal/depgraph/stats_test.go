@@ -0,0 +1,57 @@
+package depgraph
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestStats(t *testing.T) {
+	t.Parallel()
+	graph := analyseTestCode(t, `
+package test
+
+import "net/http"
+
+type DB struct{}
+
+//zero:provider
+func NewDB() *DB { return &DB{} }
+
+type Store struct{}
+
+//zero:provider
+func NewStore(db *DB) *Store { return &Store{} }
+
+type Unused struct{}
+
+//zero:provider
+func NewUnused() *Unused { return &Unused{} }
+
+type Service struct{}
+
+//zero:provider
+func NewService(store *Store) *Service { return &Service{} }
+
+//zero:api GET /
+func (s *Service) Index(w http.ResponseWriter) {}
+`)
+	stats := graph.Stats()
+	var phases []string
+	for _, phase := range stats.Phases {
+		phases = append(phases, phase.Phase)
+		assert.True(t, phase.Duration > 0, phase.Phase)
+	}
+	assert.Equal(t, []string{"load", "analyse", "prune"}, phases)
+	assert.True(t, stats.Packages > 1)
+	assert.Equal(t, 1, stats.APIs)
+	assert.Equal(t, stats.Discovered, stats.Included+stats.Pruned)
+	assert.True(t, stats.Pruned > 0)
+	assert.True(t, slices.ContainsFunc(stats.Chains, func(chain []string) bool {
+		return slices.Equal(chain, []string{"*test.Service", "*test.Store", "*test.DB"})
+	}), "%v", stats.Chains)
+	for i := 1; i < len(stats.Chains); i++ {
+		assert.True(t, len(stats.Chains[i-1]) >= len(stats.Chains[i]))
+	}
+}
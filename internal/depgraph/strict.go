@@ -0,0 +1,32 @@
+package depgraph
+
+import (
+	"strings"
+
+	"github.com/alecthomas/errors"
+)
+
+// strictErrors returns an error for each part of the graph that was selected implicitly, for [WithStrict].
+//
+// inferred are the roots inferred from receivers, or from the providers of a library, if none were given.
+func strictErrors(graph *Graph, inferred []string) []error {
+	var errs []error
+	if len(inferred) > 0 {
+		errs = append(errs, errors.Errorf("roots were inferred, select them explicitly with --root: %s", strings.Join(inferred, ", ")))
+	}
+	for _, entry := range graph.Plan() {
+		position := entry.Position
+		if position != "" {
+			position += ": "
+		}
+		switch {
+		case entry.Action == PlanDefault:
+			errs = append(errs, errors.Errorf("%sweak provider %s of %s was included by default, select it explicitly with --resolve or --module",
+				position, entry.Provider, entry.Type))
+		case entry.Weak && entry.RequiredBy != "":
+			errs = append(errs, errors.Errorf("%sweak provider %s of %s was promoted by require= on %s, select it explicitly with --resolve or --module",
+				position, entry.Provider, entry.Type, entry.RequiredBy))
+		}
+	}
+	return errs
+}
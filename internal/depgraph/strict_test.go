@@ -0,0 +1,54 @@
+package depgraph
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+const strictTestCode = `
+package test
+
+import "net/http"
+
+type Clock struct{}
+
+//zero:provider weak
+func NewClock() *Clock { return &Clock{} }
+
+type Audit struct{}
+
+//zero:provider weak
+func NewAudit() *Audit { return &Audit{} }
+
+type Service struct{}
+
+//zero:provider require=NewAudit
+func NewService(clock *Clock) *Service { return &Service{} }
+
+//zero:api GET /
+func (s *Service) Index(w http.ResponseWriter) {}
+`
+
+func TestStrict(t *testing.T) {
+	t.Parallel()
+	_, err := analyseTestCodeWithError(t, strictTestCode, WithStrict(true))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "roots were inferred, select them explicitly with --root: *test.Service\n")
+	assert.Contains(t, err.Error(), "main.go:9:1: weak provider test.NewClock of *test.Clock was included by default, select it explicitly with --resolve or --module\n")
+	assert.Contains(t, err.Error(), "main.go:14:1: weak provider test.NewAudit of *test.Audit was promoted by require= on test.NewService, select it explicitly with --resolve or --module\n")
+	assert.Contains(t, err.Error(), "weak provider github.com/alecthomas/zero/providers/http.DefaultServer of *net/http.Server was included by default")
+
+	_, err = analyseTestCodeWithError(t, strictTestCode, WithStrict(true), WithRoots("*test.Service"), WithProviders(
+		"test.NewClock",
+		"test.NewAudit",
+		"github.com/alecthomas/zero/providers/http.DefaultServer",
+		"github.com/alecthomas/zero/providers/http.DefaultServeMux",
+		"github.com/alecthomas/zero/providers/http.DefaultReadiness",
+		"github.com/alecthomas/zero/providers/http.DefaultContentEncodings",
+		"github.com/alecthomas/zero/providers/http.DefaultErrorEncoder",
+		"github.com/alecthomas/zero/providers/http.DefaultResponseEncoder",
+		"github.com/alecthomas/zero/providers/logging.ProvideLogger",
+	))
+	assert.NoError(t, err)
+}
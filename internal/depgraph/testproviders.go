@@ -0,0 +1,103 @@
+package depgraph
+
+import (
+	"go/types"
+	"slices"
+	"strings"
+
+	"github.com/alecthomas/errors"
+	"golang.org/x/tools/go/packages"
+)
+
+// isTestProvider returns true if provider is only used by the test harness, either because it is annotated with
+// //zero:provider test, or because it is declared in a _test.go file.
+func isTestProvider(provider *Provider) bool {
+	return provider.Directive.Test || strings.HasSuffix(provider.Position.Filename, "_test.go")
+}
+
+// extractTestProviders removes test providers from providers, returning them ordered by position.
+func extractTestProviders(providers map[string][]*Provider) []*Provider {
+	var testProviders []*Provider
+	for key, providerList := range providers {
+		remaining := slices.DeleteFunc(slices.Clone(providerList), isTestProvider)
+		if len(remaining) == len(providerList) {
+			continue
+		}
+		for _, provider := range providerList {
+			if isTestProvider(provider) {
+				testProviders = append(testProviders, provider)
+			}
+		}
+		if len(remaining) == 0 {
+			delete(providers, key)
+		} else {
+			providers[key] = remaining
+		}
+	}
+	slices.SortFunc(testProviders, func(a, b *Provider) int { return strings.Compare(a.Position.String(), b.Position.String()) })
+	return testProviders
+}
+
+// orderTestProviders orders test providers so that each is constructed after the test providers it requires, and
+// checks that each type has at most one test provider.
+func orderTestProviders(testProviders []*Provider) ([]*Provider, error) {
+	byType := map[string]*Provider{}
+	for _, provider := range testProviders {
+		if provider.IsGeneric {
+			return nil, errors.Errorf("%s: test provider %s cannot be generic", provider.Position, provider.FullName())
+		}
+		key := types.TypeString(provider.Provides, nil)
+		if existing, ok := byType[key]; ok {
+			return nil, errors.Errorf("%s: multiple test providers for %s, also provided at %s", provider.Position, key, existing.Position)
+		}
+		byType[key] = provider
+	}
+	ordered := make([]*Provider, 0, len(testProviders))
+	state := map[*Provider]int{} // 1 while visiting, 2 once ordered
+	var visit func(provider *Provider) error
+	visit = func(provider *Provider) error {
+		switch state[provider] {
+		case 1:
+			return errors.Errorf("%s: test provider %s requires itself", provider.Position, provider.FullName())
+		case 2:
+			return nil
+		}
+		state[provider] = 1
+		for _, require := range provider.Requires {
+			if _, ok := LazyType(require); ok {
+				// Lazy dependencies are constructed after all test providers.
+				continue
+			}
+			if dep, ok := byType[types.TypeString(UnwrapDependency(require), nil)]; ok {
+				if err := visit(dep); err != nil {
+					return err
+				}
+			}
+		}
+		state[provider] = 2
+		ordered = append(ordered, provider)
+		return nil
+	}
+	for _, provider := range testProviders {
+		if err := visit(provider); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+// selectTestVariants replaces the destination package with its test variant, which also contains its _test.go files,
+// and drops all other test packages loaded by [packages.Config.Tests].
+func selectTestVariants(pkgs []*packages.Package, destImport string) []*packages.Package {
+	// Test variants have IDs of the form "<path> [<path>.test]".
+	isVariant := func(pkg *packages.Package) bool { return pkg.ID != pkg.PkgPath }
+	hasVariant := slices.ContainsFunc(pkgs, func(pkg *packages.Package) bool {
+		return pkg.PkgPath == destImport && isVariant(pkg)
+	})
+	return slices.DeleteFunc(pkgs, func(pkg *packages.Package) bool {
+		if pkg.PkgPath == destImport {
+			return isVariant(pkg) != hasVariant
+		}
+		return isVariant(pkg)
+	})
+}
@@ -0,0 +1,169 @@
+package depgraph
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/errors"
+	"github.com/alecthomas/zero/internal/directiveparser"
+	"golang.org/x/tools/go/packages"
+)
+
+// Diagnostic is an invalid annotation reported by Vet.
+type Diagnostic struct {
+	Pos     token.Pos
+	Message string
+}
+
+// Vet validates the annotations of a single type-checked package without building the graph, returning a diagnostic
+// for each invalid annotation.
+//
+// This covers the checks that only depend on the annotated declaration: directive syntax, the signatures of providers,
+// APIs, cron jobs, subscriptions and middleware, and the tags of config structs. Checks that require the whole graph,
+// such as missing providers, are not performed.
+//
+// contextKeys are the //zero:contextkey types of imported packages, and are extended with those declared by pkg.
+// Directives named in custom are handled by plugins and are not reported.
+func Vet(pkg *packages.Package, contextKeys map[string]*ContextKey, custom ...string) []Diagnostic {
+	fset := pkg.Fset
+	var diagnostics []Diagnostic
+	report := func(pos token.Pos, err error) {
+		diagnostics = append(diagnostics, diagnosticFromError(pkg, pos, err))
+	}
+	packagePos := token.NoPos
+	if len(pkg.Syntax) > 0 {
+		packagePos = pkg.Syntax[0].Package
+	}
+	if _, err := packageModule(pkg, fset); err != nil {
+		report(packagePos, err)
+	}
+	if err := packageOpenAPI(pkg, fset, &OpenAPIConfig{}); err != nil {
+		report(packagePos, err)
+	}
+	groups, err := packageAPIGroups(pkg, fset)
+	if err != nil {
+		report(packagePos, err)
+	}
+	if contextKeys == nil {
+		contextKeys = map[string]*ContextKey{}
+	}
+	graph := &Graph{ContextKeys: contextKeys}
+	if err := collectContextKeys([]*packages.Package{pkg}, graph, fset, custom...); err != nil {
+		report(packagePos, err)
+	}
+	flags := map[string]configFlag{}
+	for _, file := range pkg.Syntax {
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				directive, err := parseDirective(decl.Doc, custom...)
+				if err != nil {
+					report(directivePos(decl.Doc), err)
+					continue
+				}
+				if err := vetFunc(decl, pkg, directive, groups, graph.ContextKeys); err != nil {
+					report(decl.Name.Pos(), err)
+				}
+
+			case *ast.GenDecl:
+				if decl.Tok == token.VAR || decl.Tok == token.CONST {
+					if err := analyseValueDecl(decl, pkg, "", map[string][]*Provider{}, fset, custom...); err != nil {
+						report(decl.Pos(), err)
+					}
+					continue
+				}
+				directive, err := parseDirective(decl.Doc, custom...)
+				if err != nil {
+					report(directivePos(decl.Doc), err)
+					continue
+				}
+				var config *directiveparser.DirectiveConfig
+				switch directive := directive.(type) {
+				case nil, *directiveparser.DirectiveContextKey, *directiveparser.DirectiveAPIGroup:
+					continue
+				case *directiveparser.DirectiveConfig:
+					config = directive
+				default:
+					report(directivePos(decl.Doc), errors.Errorf("%s: unknown directive type", directive))
+					continue
+				}
+				for _, spec := range decl.Specs {
+					typeSpec, ok := spec.(*ast.TypeSpec)
+					if !ok {
+						continue
+					}
+					configType := pkg.TypesInfo.TypeOf(typeSpec.Name)
+					if configType == nil {
+						continue
+					}
+					name := types.TypeString(configType, nil)
+					var renames []ConfigRename
+					if err := checkConfigFields(configType, name, config.Prefix, nil, &renames, flags, fset); err != nil {
+						report(typeSpec.Name.Pos(), err)
+					}
+				}
+			}
+		}
+	}
+	return diagnostics
+}
+
+// vetFunc validates a function annotated with directive.
+func vetFunc(fn *ast.FuncDecl, pkg *packages.Package, directive directiveparser.Directive, groups apiGroups, contextKeys map[string]*ContextKey) error {
+	var err error
+	switch directive := directive.(type) {
+	case *directiveparser.DirectiveProvider:
+		_, err = createProvider(fn, pkg, directive, pkg.Fset)
+	case *directiveparser.DirectiveAPI:
+		_, err = createAPI(fn, pkg, directive, groups, contextKeys, pkg.Fset)
+	case *directiveparser.DirectiveCron:
+		_, err = createCron(fn, pkg, directive, pkg.Fset)
+	case *directiveparser.DirectiveMiddleware:
+		_, err = createMiddleware(fn, pkg, directive, pkg.Fset)
+	case *directiveparser.DirectiveSubscribe:
+		_, err = createSubscription(fn, pkg, directive, pkg.Fset)
+	case *directiveparser.DirectiveStep:
+		_, err = createStep(fn, pkg, directive, pkg.Fset)
+	case *directiveparser.DirectiveWorker:
+		_, err = createWorker(fn, pkg, directive, pkg.Fset)
+	}
+	return err
+}
+
+// directivePos returns the position of the first directive in doc.
+func directivePos(doc *ast.CommentGroup) token.Pos {
+	for _, comment := range doc.List {
+		if strings.HasPrefix(comment.Text, "//zero:") {
+			return comment.Pos()
+		}
+	}
+	return doc.Pos()
+}
+
+var errorPositionRe = regexp.MustCompile(`^(.+?):(\d+):(\d+): `)
+
+// diagnosticFromError creates a diagnostic for err at pos.
+//
+// Many errors are prefixed with the position of the declaration they refer to, in which case the prefix is removed
+// from the message and the diagnostic is reported at that position instead.
+func diagnosticFromError(pkg *packages.Package, pos token.Pos, err error) Diagnostic {
+	message := err.Error()
+	match := errorPositionRe.FindStringSubmatch(message)
+	if match == nil {
+		return Diagnostic{Pos: pos, Message: message}
+	}
+	line, _ := strconv.Atoi(match[2])
+	column, _ := strconv.Atoi(match[3])
+	for _, file := range pkg.Syntax {
+		tf := pkg.Fset.File(file.Pos())
+		if tf == nil || tf.Name() != match[1] || line > tf.LineCount() {
+			continue
+		}
+		return Diagnostic{Pos: tf.LineStart(line) + token.Pos(column-1), Message: message[len(match[0]):]}
+	}
+	return Diagnostic{Pos: pos, Message: message}
+}
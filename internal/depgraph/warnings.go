@@ -0,0 +1,112 @@
+package depgraph
+
+import (
+	"cmp"
+	"fmt"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// WarningKind identifies a class of [Warning].
+type WarningKind string
+
+const (
+	// WarningUnreachableProvider is a weak provider of a type that is not required by any root.
+	WarningUnreachableProvider WarningKind = "unreachable-provider"
+	// WarningUnusedMiddleware is labelled middleware whose labels match no API, cron job or subscriber.
+	WarningUnusedMiddleware WarningKind = "unused-middleware"
+	// WarningUnusedConfig is a config struct that was pruned because no provider requires it.
+	WarningUnusedConfig WarningKind = "unused-config"
+	// WarningUnneededPick is a provider selected with --resolve for a type that has no alternative providers.
+	WarningUnneededPick WarningKind = "unneeded-pick"
+	// WarningDeprecatedProvider is a provider annotated with deprecated="<message>" that is included in the graph.
+	WarningDeprecatedProvider WarningKind = "deprecated-provider"
+)
+
+// WarningKinds lists every [WarningKind].
+var WarningKinds = []WarningKind{WarningUnreachableProvider, WarningUnusedMiddleware, WarningUnusedConfig, WarningUnneededPick, WarningDeprecatedProvider}
+
+// Warning is an annotation that has no effect on the generated code.
+type Warning struct {
+	Position token.Position
+	Kind     WarningKind
+	Message  string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("%s: %s (%s)", w.Position, w.Message, w.Kind)
+}
+
+// Warnings returns the annotations in the module of the destination package that have no effect, ordered by position.
+//
+// Annotations in other modules, such as Zero's builtin providers, are expected to go unused by most services and are
+// not reported, but deprecated providers included in the graph are reported wherever they are declared.
+func (g *Graph) Warnings() []Warning {
+	var warnings []Warning
+	root := findUp(g.DestDir, "go.mod")
+	add := func(position token.Position, kind WarningKind, format string, args ...any) {
+		if root == "" || !strings.HasPrefix(position.Filename, root+string(filepath.Separator)) {
+			return
+		}
+		warnings = append(warnings, Warning{Position: position, Kind: kind, Message: fmt.Sprintf(format, args...)})
+	}
+
+	picked := pickedProviders(g.pick)
+	for key, providers := range g.candidates {
+		for _, provider := range providers {
+			name := provider.FullName()
+			switch {
+			case slices.Contains(picked, name) && len(providers) == 1 && !provider.IsGeneric:
+				add(provider.Position, WarningUnneededPick, "--resolve %s is not needed, as it is the only provider of %s", name, key)
+			case provider.Directive.Weak && len(g.Providers[key]) == 0 && !provider.IsGeneric && !g.excluded[name] &&
+				len(provider.Directive.Group) == 0 && g.inProfile(provider):
+				add(provider.Position, WarningUnreachableProvider, "weak provider %s is not required by any root", name)
+			}
+		}
+	}
+
+	for _, middleware := range g.discoveredMiddleware {
+		if len(middleware.Directive.Labels) == 0 {
+			continue
+		}
+		if slices.ContainsFunc(g.APIs, middleware.Match) || g.usesJobMiddleware(middleware) {
+			continue
+		}
+		add(middleware.Position, WarningUnusedMiddleware, "middleware %s matches no labels (%s)", middleware.Function.FullName(),
+			strings.Join(middleware.Directive.Labels, ", "))
+	}
+
+	for key, config := range g.discoveredConfigs {
+		if _, ok := g.Configs[key]; !ok {
+			add(config.Position, WarningUnusedConfig, "config %s is not required by any provider", types.TypeString(config.Type, nil))
+		}
+	}
+
+	for _, providers := range g.Providers {
+		for _, provider := range providers {
+			if provider.Directive.Deprecated != "" {
+				warnings = append(warnings, Warning{Position: provider.Position, Kind: WarningDeprecatedProvider,
+					Message: fmt.Sprintf("provider %s is deprecated: %s", provider.FullName(), provider.Directive.Deprecated)})
+			}
+		}
+	}
+
+	slices.SortFunc(warnings, func(a, b Warning) int {
+		return cmp.Or(
+			strings.Compare(a.Position.Filename, b.Position.Filename),
+			cmp.Compare(a.Position.Line, b.Position.Line),
+			strings.Compare(a.Message, b.Message),
+		)
+	})
+	return warnings
+}
+
+// inProfile returns true if the provider is not restricted to profiles, or one of its profiles is enabled.
+func (g *Graph) inProfile(provider *Provider) bool {
+	return len(provider.Directive.Profile) == 0 || slices.ContainsFunc(provider.Directive.Profile, func(profile string) bool {
+		return slices.Contains(g.profiles, profile)
+	})
+}
@@ -0,0 +1,118 @@
+package depgraph
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestWarnings(t *testing.T) {
+	t.Parallel()
+	graph := analyseTestCode(t, `
+package test
+
+import "net/http"
+
+type Clock struct{}
+
+//zero:provider weak
+func NewClock() *Clock { return &Clock{} }
+
+type Debug struct{}
+
+//zero:provider weak profile=dev
+func NewDebug() *Debug { return &Debug{} }
+
+type Store interface{ Get() string }
+
+type memoryStore struct{}
+
+func (memoryStore) Get() string { return "memory" }
+
+//zero:provider weak
+func NewMemoryStore() Store { return memoryStore{} }
+
+//zero:config prefix="unused-"
+type UnusedConfig struct {
+	Value string
+}
+
+//zero:config prefix="used-"
+type UsedConfig struct {
+	Value string
+}
+
+//zero:middleware admin
+func Admin(next http.Handler) http.Handler { return next }
+
+//zero:middleware authenticated
+func Authenticate(next http.Handler) http.Handler { return next }
+
+type Service struct{}
+
+//zero:provider
+func NewService(store Store, config UsedConfig) *Service { return &Service{} }
+
+//zero:api GET / authenticated
+func (s *Service) Index(w http.ResponseWriter) {}
+`, WithProviders("test.NewMemoryStore"))
+	var warnings []string
+	for _, warning := range graph.Warnings() {
+		warnings = append(warnings, filepath.Base(warning.Position.String())+": "+warning.Message+" ("+string(warning.Kind)+")")
+	}
+	assert.Equal(t, []string{
+		"main.go:9:1: weak provider test.NewClock is not required by any root (unreachable-provider)",
+		"main.go:23:1: --resolve test.NewMemoryStore is not needed, as it is the only provider of test.Store (unneeded-pick)",
+		"main.go:26:6: config test.UnusedConfig is not required by any provider (unused-config)",
+		"main.go:36:1: middleware test.Admin matches no labels (admin) (unused-middleware)",
+	}, warnings)
+}
+
+func TestDeprecatedProviders(t *testing.T) {
+	t.Parallel()
+	code := `
+package test
+
+import "net/http"
+
+type Store interface{ Get() string }
+
+type memoryStore struct{}
+
+func (memoryStore) Get() string { return "memory" }
+
+//zero:provider deprecated="use NewStore"
+func NewMemoryStore() Store { return memoryStore{} }
+
+//zero:provider replaces=NewMemoryStore
+func NewStore() Store { return memoryStore{} }
+
+type Service struct{}
+
+//zero:provider
+func NewService(store Store) *Service { return &Service{} }
+
+//zero:api GET /
+func (s *Service) Index(w http.ResponseWriter) {}
+`
+	graph := analyseTestCode(t, code)
+	entries := map[string]PlanEntry{}
+	for _, entry := range graph.Plan() {
+		entry.Position = ""
+		entries[entry.Provider] = entry
+	}
+	assert.Equal(t, PlanEntry{Action: PlanInclude, Type: "test.Store", Provider: "test.NewStore", Reason: "strong provider"}, entries["test.NewStore"])
+	assert.Equal(t, PlanEntry{Action: PlanPrune, Type: "test.Store", Provider: "test.NewMemoryStore", Reason: "replaced by test.NewStore"}, entries["test.NewMemoryStore"])
+	assert.Equal(t, 0, len(graph.Warnings()), "replaced providers are not reported as deprecated")
+
+	// A replaced provider can still be selected explicitly, but is then reported as deprecated.
+	graph = analyseTestCode(t, code, WithProviders("test.NewMemoryStore"))
+	var warnings []string
+	for _, warning := range graph.Warnings() {
+		warnings = append(warnings, filepath.Base(warning.Position.String())+": "+warning.Message+" ("+string(warning.Kind)+")")
+	}
+	assert.Equal(t, []string{
+		"main.go:13:1: provider test.NewMemoryStore is deprecated: use NewStore (deprecated-provider)",
+	}, warnings)
+}
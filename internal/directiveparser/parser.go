@@ -3,6 +3,9 @@ package directiveparser
 
 import (
 	"fmt"
+	"mime"
+	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -10,18 +13,22 @@ import (
 	"github.com/alecthomas/errors"
 	"github.com/alecthomas/participle/v2"
 	"github.com/alecthomas/participle/v2/lexer"
+	"github.com/alecthomas/zero"
 )
 
 var (
-	annotationParser = participle.MustBuild[annotation](
+	parserOptions = []participle.Option{
 		participle.Lexer(patternLexer),
-		participle.Union[Directive](&DirectiveAPI{}, &DirectiveProvider{}, &DirectiveConfig{}, &DirectiveMiddleware{}, &DirectiveCron{}, &DirectiveSubscribe{}),
+		participle.Union[Directive](&DirectiveAPIGroup{}, &DirectiveAPI{}, &DirectiveProvider{}, &DirectiveConfig{}, &DirectiveMiddleware{}, &DirectiveCron{}, &DirectiveSubscribe{}, &DirectiveStep{}, &DirectiveWorker{}, &DirectiveModule{}, &DirectiveOpenAPI{}, &DirectiveContextKey{}),
 		participle.Union[Segment](WildcardSegment{}, LiteralSegment{}, TrailingSegment{}),
 		participle.Elide("Whitespace"),
 		participle.CaseInsensitive("Method"),
 		participle.Unquote("String"),
-	)
-	patternLexer = lexer.MustSimple([]lexer.SimpleRule{
+	}
+	annotationParser = participle.MustBuild[annotation](parserOptions...)
+	// //zero:api-labels can't be distinguished from //zero:api-group with a single token of lookahead.
+	apiLabelsParser = participle.MustBuild[apiLabelsAnnotation](parserOptions...)
+	patternLexer    = lexer.MustSimple([]lexer.SimpleRule{
 		{"Method", `GET|POST|PUT|DELETE|PATCH|HEAD|OPTIONS|TRACE|CONNECT|ANY`},
 		{"Number", `[0-9]+`},
 		{"Ident", `[a-zA-Z_][a-zA-Z0-9_]*`},
@@ -33,10 +40,17 @@ var (
 	})
 )
 
+// Builtins are the names of the builtin directives, eg. "provider" for //zero:provider.
+var Builtins = []string{"api", "api-group", "api-labels", "provider", "config", "middleware", "cron", "subscribe", "step", "worker", "module", "openapi", "contextkey"}
+
 type annotation struct {
 	Directive Directive `parser:"'zero' ':' @@"`
 }
 
+type apiLabelsAnnotation struct {
+	Directive *DirectiveAPILabels `parser:"'zero' ':' @@"`
+}
+
 type Directive interface {
 	directive()
 	// Validate the directive.
@@ -45,9 +59,14 @@ type Directive interface {
 }
 
 type DirectiveProvider struct {
-	Weak    bool     `parser:"'provider' (  @'weak'"`
-	Multi   bool     `parser:"            | @'multi'"`
-	Require []string `parser:"            | 'require' '=' (@Ident | @String) (',' (@Ident | @String))*)*"`
+	Weak       bool     `parser:"'provider' (  @'weak'"`
+	Multi      bool     `parser:"            | @'multi'"`
+	Test       bool     `parser:"            | @'test'"`
+	Require    []string `parser:"            | 'require' '=' (@Ident | @String) (',' (@Ident | @String))*"`
+	Group      []string `parser:"            | 'group' '=' (@Ident | @String) (',' (@Ident | @String))*"`
+	Profile    []string `parser:"            | 'profile' '=' @Ident (',' @Ident)*"`
+	Deprecated string   `parser:"            | 'deprecated' '=' @String"`
+	Replaces   []string `parser:"            | 'replaces' '=' (@Ident | @String) (',' (@Ident | @String))*)*"`
 }
 
 func (p *DirectiveProvider) directive() {}
@@ -59,12 +78,32 @@ func (p *DirectiveProvider) String() string {
 	if p.Multi {
 		out += " multi"
 	}
+	if p.Test {
+		out += " test"
+	}
 	if len(p.Require) > 0 {
 		out += " require=" + strings.Join(p.Require, ",")
 	}
+	if len(p.Group) > 0 {
+		out += " group=" + strings.Join(p.Group, ",")
+	}
+	if len(p.Profile) > 0 {
+		out += " profile=" + strings.Join(p.Profile, ",")
+	}
+	if p.Deprecated != "" {
+		out += " deprecated=" + strconv.Quote(p.Deprecated)
+	}
+	if len(p.Replaces) > 0 {
+		out += " replaces=" + strings.Join(p.Replaces, ",")
+	}
 	return out
 }
-func (p *DirectiveProvider) Validate() error { return nil }
+func (p *DirectiveProvider) Validate() error {
+	if p.Test && p.Multi {
+		return errors.Errorf("test providers cannot be multi-providers")
+	}
+	return nil
+}
 
 type DirectiveConfig struct {
 	Prefix string `parser:"'config' ('prefix' '=' @String)?"`
@@ -90,11 +129,17 @@ func (d *DirectiveMiddleware) Validate() error { return nil }
 
 type DirectiveCron struct {
 	Schedule string `parser:"'cron' @(Number ('h' | 'H' | 'm' | 'm' | 's' | 'S' | 'd' | 'D' | 'w' | 'W'))"`
+	// Labels select the job middleware applied to the cron job.
+	Labels []string `parser:"@Ident*"`
 }
 
 func (d *DirectiveCron) directive() {}
 func (d *DirectiveCron) String() string {
-	return "zero:cron " + d.Schedule
+	result := "zero:cron " + d.Schedule
+	if len(d.Labels) > 0 {
+		result += " " + strings.Join(d.Labels, " ")
+	}
+	return result
 }
 func (d *DirectiveCron) Duration() (time.Duration, error) {
 	// time.ParseDuration doesn't support "d" or "w" so we roll our own
@@ -125,11 +170,351 @@ func (d *DirectiveCron) Validate() error {
 
 type DirectiveSubscribe struct {
 	Subscribe bool `parser:"'subscribe'"`
+	// Version restricts the subscription to events with the given payload schema version, with 0 being unversioned
+	// events.
+	Version *int `parser:"('version' '=' @Number)?"`
+	// Concurrency is the maximum number of events processed by the subscriber at once, overriding the topic's default.
+	Concurrency *int `parser:"('concurrency' '=' @Number)?"`
+	// Prefetch is the number of events claimed ahead of the subscriber's workers, overriding the topic's default.
+	Prefetch *int `parser:"('prefetch' '=' @Number)?"`
+	// Batch is the maximum number of events delivered at once to a subscriber of a slice of events.
+	Batch *int `parser:"('batch' '=' @Number)?"`
+	// Window is the maximum time to wait for a batch to fill, eg. 1s.
+	Window string `parser:"('window' '=' @(Number Ident))?"`
+	// Dedupe is the window within which deliveries of an event with the same ID as one already handled are
+	// suppressed, eg. 5m.
+	Dedupe string `parser:"('dedupe' '=' @(Number Ident))?"`
+	// Labels select the job middleware applied to the subscriber.
+	Labels []string `parser:"@Ident*"`
+}
+
+func (d *DirectiveSubscribe) directive() {}
+func (d *DirectiveSubscribe) String() string {
+	result := "zero:subscribe"
+	if d.Version != nil {
+		result += fmt.Sprintf(" version=%d", *d.Version)
+	}
+	if d.Concurrency != nil {
+		result += fmt.Sprintf(" concurrency=%d", *d.Concurrency)
+	}
+	if d.Prefetch != nil {
+		result += fmt.Sprintf(" prefetch=%d", *d.Prefetch)
+	}
+	if d.Batch != nil {
+		result += fmt.Sprintf(" batch=%d", *d.Batch)
+	}
+	if d.Window != "" {
+		result += " window=" + d.Window
+	}
+	if d.Dedupe != "" {
+		result += " dedupe=" + d.Dedupe
+	}
+	if len(d.Labels) > 0 {
+		result += " " + strings.Join(d.Labels, " ")
+	}
+	return result
+}
+func (d *DirectiveSubscribe) Validate() error {
+	if d.Concurrency != nil && *d.Concurrency < 1 {
+		return errors.Errorf("subscription concurrency must be at least 1")
+	}
+	if d.Batch != nil && *d.Batch < 1 {
+		return errors.Errorf("subscription batch size must be at least 1")
+	}
+	if d.Window != "" {
+		if d.Batch == nil {
+			return errors.Errorf("subscription window requires a batch size")
+		}
+		if _, err := d.WindowDuration(); err != nil {
+			return err
+		}
+	}
+	_, err := d.DedupeDuration()
+	return err
+}
+
+// WindowDuration returns the parsed batch window, or 0 if none is set.
+func (d *DirectiveSubscribe) WindowDuration() (time.Duration, error) {
+	return parseSubscribeDuration("window", d.Window)
+}
+
+// DedupeDuration returns the parsed deduplication window, or 0 if none is set.
+func (d *DirectiveSubscribe) DedupeDuration() (time.Duration, error) {
+	return parseSubscribeDuration("dedupe", d.Dedupe)
+}
+
+func parseSubscribeDuration(label, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, errors.Errorf("invalid subscription %s %q: %w", label, value, err)
+	}
+	if duration <= 0 {
+		return 0, errors.Errorf("subscription %s must be positive", label)
+	}
+	return duration, nil
+}
+
+// DirectiveStep represents a //zero:step directive on a method.
+//
+// The annotated methods of a type form the steps of a durable workflow, run in source order.
+type DirectiveStep struct {
+	Step bool `parser:"'step'"`
+	// Compensate is the method of the same type that undoes the step if a later step fails.
+	Compensate string `parser:"('compensate' '=' @Ident)?"`
+}
+
+func (d *DirectiveStep) directive() {}
+func (d *DirectiveStep) String() string {
+	result := "zero:step"
+	if d.Compensate != "" {
+		result += " compensate=" + d.Compensate
+	}
+	return result
+}
+func (d *DirectiveStep) Validate() error { return nil }
+
+// DirectiveWorker represents a //zero:worker directive on a method.
+//
+// The annotated method processes the jobs of the queue of its payload type.
+type DirectiveWorker struct {
+	Worker bool `parser:"'worker'"`
+	// Concurrency is the maximum number of jobs processed by the worker at once.
+	Concurrency *int `parser:"('concurrency' '=' @Number)?"`
+	// Attempts is the number of times a job is attempted before it is moved to the dead jobs.
+	Attempts *int `parser:"('attempts' '=' @Number)?"`
+	// Backoff is the delay before the first retry of a failed job, eg. 1s.
+	Backoff string `parser:"('backoff' '=' @(Number Ident))?"`
+	// Visibility is how long a claimed job is hidden from other workers, eg. 30s.
+	Visibility string `parser:"('visibility' '=' @(Number Ident))?"`
+	// Labels select the job middleware applied to the worker.
+	Labels []string `parser:"@Ident*"`
+}
+
+func (d *DirectiveWorker) directive() {}
+func (d *DirectiveWorker) String() string {
+	result := "zero:worker"
+	if d.Concurrency != nil {
+		result += fmt.Sprintf(" concurrency=%d", *d.Concurrency)
+	}
+	if d.Attempts != nil {
+		result += fmt.Sprintf(" attempts=%d", *d.Attempts)
+	}
+	if d.Backoff != "" {
+		result += " backoff=" + d.Backoff
+	}
+	if d.Visibility != "" {
+		result += " visibility=" + d.Visibility
+	}
+	if len(d.Labels) > 0 {
+		result += " " + strings.Join(d.Labels, " ")
+	}
+	return result
+}
+func (d *DirectiveWorker) Validate() error {
+	if d.Concurrency != nil && *d.Concurrency < 1 {
+		return errors.Errorf("worker concurrency must be at least 1")
+	}
+	if d.Attempts != nil && *d.Attempts < 1 {
+		return errors.Errorf("worker attempts must be at least 1")
+	}
+	if _, err := d.BackoffDuration(); err != nil {
+		return err
+	}
+	_, err := d.VisibilityDuration()
+	return err
+}
+
+// BackoffDuration returns the parsed retry backoff, or 0 if none is set.
+func (d *DirectiveWorker) BackoffDuration() (time.Duration, error) {
+	return parseWorkerDuration("backoff", d.Backoff)
+}
+
+// VisibilityDuration returns the parsed visibility timeout, or 0 if none is set.
+func (d *DirectiveWorker) VisibilityDuration() (time.Duration, error) {
+	return parseWorkerDuration("visibility", d.Visibility)
+}
+
+func parseWorkerDuration(label, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, errors.Errorf("invalid worker %s %q: %w", label, value, err)
+	}
+	if duration <= 0 {
+		return 0, errors.Errorf("worker %s must be positive", label)
+	}
+	return duration, nil
+}
+
+// DirectiveContextKey represents a //zero:contextkey directive on a type.
+//
+// Values of the type are set in the request context by middleware with the label, and injected into API methods with
+// the label as parameters.
+type DirectiveContextKey struct {
+	Label string `parser:"'contextkey' @Ident"`
+}
+
+func (d *DirectiveContextKey) directive()      {}
+func (d *DirectiveContextKey) String() string  { return "zero:contextkey " + d.Label }
+func (d *DirectiveContextKey) Validate() error { return nil }
+
+// DirectiveModule represents a //zero:module directive on a package clause.
+//
+// All providers in the package are members of the named module.
+type DirectiveModule struct {
+	Name string `parser:"'module' (@Ident | @String)"`
+}
+
+func (d *DirectiveModule) directive()      {}
+func (d *DirectiveModule) String() string  { return "zero:module " + d.Name }
+func (d *DirectiveModule) Validate() error { return nil }
+
+// DirectiveOpenAPI represents a //zero:openapi directive on a package clause, configuring metadata for the generated
+// OpenAPI specification, eg.
+//
+//	//zero:openapi title="Users API" version=1.2.0 server=https://api.example.com/v1
+type DirectiveOpenAPI struct {
+	Options []*Label `parser:"'openapi' @@+"`
+}
+
+// OpenAPIOptions are the keys supported by //zero:openapi.
+var OpenAPIOptions = []string{"title", "version", "description", "terms", "server", "contact", "email", "url", "license", "license_url", "tag"}
+
+func (d *DirectiveOpenAPI) directive() {}
+func (d *DirectiveOpenAPI) String() string {
+	out := "zero:openapi"
+	for _, option := range d.Options {
+		value := option.Value
+		if strings.ContainsAny(value, " \t\"") {
+			value = strconv.Quote(value)
+		}
+		out += " " + option.Name + "=" + value
+	}
+	return out
+}
+func (d *DirectiveOpenAPI) Validate() error {
+	for _, option := range d.Options {
+		if !slices.Contains(OpenAPIOptions, option.Name) {
+			return errors.Errorf("unknown openapi option %q, expected one of %s", option.Name, strings.Join(OpenAPIOptions, ", "))
+		}
+		if option.Value == "" {
+			return errors.Errorf("openapi option %q requires a value", option.Name)
+		}
+		switch option.Name {
+		case "server":
+			u, err := url.Parse(option.Value)
+			if err != nil || u.Scheme == "" || u.Host == "" {
+				return errors.Errorf("openapi server %q must be an absolute URL", option.Value)
+			}
+		case "tag":
+			if name, _, ok := strings.Cut(option.Value, "="); !ok || name == "" {
+				return errors.Errorf("openapi tag %q must be in the form <name>=<description>", option.Value)
+			}
+		}
+	}
+	return nil
+}
+
+// DirectiveAPIGroup represents a //zero:api-group directive on a package clause or API receiver type, prefixing the
+// paths of its APIs and grouping them under an OpenAPI tag, eg.
+//
+//	//zero:api-group prefix=/v2 tag=v2
+type DirectiveAPIGroup struct {
+	Options []*Label `parser:"'api' '-' 'group' @@+"`
 }
 
-func (d *DirectiveSubscribe) directive()      {}
-func (d *DirectiveSubscribe) String() string  { return "zero:subscribe" }
-func (d *DirectiveSubscribe) Validate() error { return nil }
+// APIGroupOptions are the keys supported by //zero:api-group.
+var APIGroupOptions = []string{"prefix", "tag"}
+
+func (d *DirectiveAPIGroup) directive() {}
+func (d *DirectiveAPIGroup) String() string {
+	out := "zero:api-group"
+	for _, option := range d.Options {
+		out += " " + option.Name + "=" + option.Value
+	}
+	return out
+}
+func (d *DirectiveAPIGroup) Validate() error {
+	for _, option := range d.Options {
+		if !slices.Contains(APIGroupOptions, option.Name) {
+			return errors.Errorf("unknown api-group option %q, expected one of %s", option.Name, strings.Join(APIGroupOptions, ", "))
+		}
+		if option.Value == "" {
+			return errors.Errorf("api-group option %q requires a value", option.Name)
+		}
+	}
+	_, err := d.Prefix()
+	return err
+}
+
+// Prefix returns the parsed path segments of the prefix=<path> option, if any.
+//
+// The prefix may contain wildcards, but not a trailing slash or catch-all.
+func (d *DirectiveAPIGroup) Prefix() ([]Segment, error) {
+	prefix := d.option("prefix")
+	if prefix == "" {
+		return nil, nil
+	}
+	result, err := annotationParser.ParseString("", "zero:api "+prefix)
+	if err != nil {
+		return nil, errors.Errorf("invalid api-group prefix %q: %w", prefix, err)
+	}
+	api, ok := result.Directive.(*DirectiveAPI)
+	if !ok || api.Method != "" || api.Host != "" || len(api.Labels) > 0 {
+		return nil, errors.Errorf("api-group prefix %q must be a path, eg. prefix=/v2", prefix)
+	}
+	for _, segment := range api.Segments {
+		switch segment := segment.(type) {
+		case TrailingSegment:
+			return nil, errors.Errorf("api-group prefix %q must not end with /", prefix)
+		case WildcardSegment:
+			if segment.Remainder {
+				return nil, errors.Errorf("api-group prefix %q must not contain a catch-all", prefix)
+			}
+		}
+	}
+	return api.Segments, nil
+}
+
+// Tag returns the value of the tag=<name> option, or "" if it is not present.
+func (d *DirectiveAPIGroup) Tag() string { return d.option("tag") }
+
+func (d *DirectiveAPIGroup) option(name string) string {
+	for _, option := range d.Options {
+		if option.Name == name {
+			return option.Value
+		}
+	}
+	return ""
+}
+
+// DirectiveAPILabels represents a //zero:api-labels directive on an API receiver type or its provider, whose labels
+// are inherited by every API on the receiver, eg.
+//
+//	//zero:api-labels authenticated tenant
+type DirectiveAPILabels struct {
+	Labels []*Label `parser:"'api' '-' 'labels' @@+"`
+}
+
+func (d *DirectiveAPILabels) directive() {}
+func (d *DirectiveAPILabels) String() string {
+	out := "zero:api-labels"
+	for _, label := range d.Labels {
+		out += " " + label.Name
+		if label.Value != "" {
+			out += "=" + label.Value
+		}
+	}
+	return out
+}
+func (d *DirectiveAPILabels) Validate() error {
+	return errors.WithStack((&DirectiveAPI{Labels: d.Labels}).Validate())
+}
 
 // DirectiveAPI represents a //zero:api directive
 type DirectiveAPI struct {
@@ -152,6 +537,9 @@ func (p *DirectiveAPI) Wildcard(name string) bool {
 }
 func (p *DirectiveAPI) Validate() error {
 	p.Method = strings.ToUpper(p.Method)
+	if p.Method == "ANY" {
+		p.Method = ""
+	}
 	for i, segment := range p.Segments {
 		switch segment := segment.(type) {
 		case TrailingSegment:
@@ -165,11 +553,206 @@ func (p *DirectiveAPI) Validate() error {
 		}
 
 	}
+	if _, err := p.Timeout(); err != nil {
+		return err
+	}
+	if _, err := p.MaxBody(); err != nil {
+		return err
+	}
+	if _, err := p.Compress(); err != nil {
+		return err
+	}
+	if _, err := p.TrailingSlash(); err != nil {
+		return err
+	}
+	if _, err := p.Idempotent(); err != nil {
+		return err
+	}
+	if _, err := p.Consumes(); err != nil {
+		return err
+	}
+	if _, err := p.Flag(); err != nil {
+		return err
+	}
+	if _, err := p.Headers(); err != nil {
+		return err
+	}
+	if _, err := p.Cookies(); err != nil {
+		return err
+	}
+	if _, ok := p.Label("etag"); ok && p.Method != "" && p.Method != "GET" {
+		return errors.Errorf("etag is only supported for GET, not %s", p.Method)
+	}
 	return nil
 }
 
+// Label returns the value of the named label, and whether it is present.
+func (p *DirectiveAPI) Label(name string) (string, bool) {
+	for _, label := range p.Labels {
+		if label.Name == name {
+			return label.Value, true
+		}
+	}
+	return "", false
+}
+
+// Timeout returns the value of the timeout=<duration> label, or 0 if it is not present.
+func (p *DirectiveAPI) Timeout() (time.Duration, error) {
+	value, ok := p.Label("timeout")
+	if !ok {
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		return 0, errors.Errorf("invalid timeout %q", value)
+	}
+	return timeout, nil
+}
+
+// MaxBody returns the value of the maxbody=<size> label, eg. "1MB", or 0 if it is not present.
+func (p *DirectiveAPI) MaxBody() (zero.ByteSize, error) {
+	value, ok := p.Label("maxbody")
+	if !ok {
+		return 0, nil
+	}
+	size, err := zero.ParseByteSize(value)
+	if err != nil || size <= 0 {
+		return 0, errors.Errorf("invalid maxbody %q", value)
+	}
+	return size, nil
+}
+
+// Compress returns the minimum size of compressed responses in the compress[=<size>] label, which may be omitted, or 0
+// if it is not present.
+func (p *DirectiveAPI) Compress() (zero.ByteSize, error) {
+	value, ok := p.Label("compress")
+	if !ok || value == "" {
+		return 0, nil
+	}
+	size, err := zero.ParseByteSize(value)
+	if err != nil || size < 0 {
+		return 0, errors.Errorf("invalid compress %q", value)
+	}
+	return size, nil
+}
+
+// TrailingSlashPolicies are the policies of the slash=<policy> label for requests whose path differs from the
+// pattern of an API only by a trailing slash, eg. "/users/" for "/users".
+//
+//   - strict: the paths are distinct, and only the path of the pattern is served.
+//   - redirect: the other path is permanently redirected to the path of the pattern.
+//   - ignore: both paths are served by the API.
+var TrailingSlashPolicies = []string{"strict", "redirect", "ignore"}
+
+// TrailingSlash returns the trailing slash policy in the slash=<policy> label, one of [TrailingSlashPolicies], or ""
+// if it is not present.
+func (p *DirectiveAPI) TrailingSlash() (string, error) {
+	value, ok := p.Label("slash")
+	if !ok {
+		return "", nil
+	}
+	if !slices.Contains(TrailingSlashPolicies, value) {
+		return "", errors.Errorf("invalid slash %q, must be one of %s", value, strings.Join(TrailingSlashPolicies, ", "))
+	}
+	return value, nil
+}
+
+// Consumes returns the media types accepted by the consumes=<media type>[,<media type>...] label, eg.
+// "image/png,image/*", or nil if it is not present.
+func (p *DirectiveAPI) Consumes() ([]string, error) {
+	value, ok := p.Label("consumes")
+	if !ok {
+		return nil, nil
+	}
+	if value == "" {
+		return nil, errors.Errorf("consumes requires a media type, eg. consumes=application/pdf")
+	}
+	mediaTypes := strings.Split(value, ",")
+	for _, mediaType := range mediaTypes {
+		if _, _, err := mime.ParseMediaType(mediaType); err != nil || !strings.Contains(mediaType, "/") {
+			return nil, errors.Errorf("invalid consumes media type %q", mediaType)
+		}
+	}
+	return mediaTypes, nil
+}
+
+// SkipMiddleware returns the names of middleware excluded by the skip=<name>[,<name>...] label.
+func (p *DirectiveAPI) SkipMiddleware() []string {
+	value, ok := p.Label("skip")
+	if !ok || value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// Idempotent returns the value of the idempotent[=<duration>] label, which may be empty, or 0 if it is not present.
+func (p *DirectiveAPI) Idempotent() (time.Duration, error) {
+	value, ok := p.Label("idempotent")
+	if !ok {
+		return 0, nil
+	}
+	switch p.Method {
+	case "GET", "HEAD", "OPTIONS":
+		return 0, errors.Errorf("idempotent is not supported for %s", p.Method)
+	}
+	if value == "" {
+		return 0, nil
+	}
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl <= 0 {
+		return 0, errors.Errorf("invalid idempotent %q", value)
+	}
+	return ttl, nil
+}
+
+// Flag returns the name of the feature flag in the flag=<name> label, or "" if it is not present.
+func (p *DirectiveAPI) Flag() (string, error) {
+	value, ok := p.Label("flag")
+	if !ok {
+		return "", nil
+	}
+	if value == "" {
+		return "", errors.Errorf("flag requires a feature flag name, eg. flag=new-checkout")
+	}
+	return value, nil
+}
+
+// Headers returns the names of the request headers bound to parameters by the header=<param>[:<header>][,...] label,
+// keyed by parameter name. The name is empty if it is not given.
+func (p *DirectiveAPI) Headers() (map[string]string, error) {
+	return p.parameterBindings("header")
+}
+
+// Cookies returns the names of the request cookies bound to parameters by the cookie=<param>[:<cookie>][,...] label,
+// keyed by parameter name. The name is empty if it is not given.
+func (p *DirectiveAPI) Cookies() (map[string]string, error) {
+	return p.parameterBindings("cookie")
+}
+
+func (p *DirectiveAPI) parameterBindings(label string) (map[string]string, error) {
+	value, ok := p.Label(label)
+	if !ok {
+		return nil, nil
+	}
+	if value == "" {
+		return nil, errors.Errorf("%s requires a parameter, eg. %s=apiKey:X-Api-Key", label, label)
+	}
+	bindings := map[string]string{}
+	for binding := range strings.SplitSeq(value, ",") {
+		param, name, _ := strings.Cut(binding, ":")
+		if param == "" {
+			return nil, errors.Errorf("invalid %s %q", label, binding)
+		}
+		if _, ok := bindings[param]; ok {
+			return nil, errors.Errorf("duplicate %s parameter %q", label, param)
+		}
+		bindings[param] = name
+	}
+	return bindings, nil
+}
+
 type Label struct {
-	Name  string `parser:"@(Ident | Method)"`
+	Name  string `parser:"@((Ident | Method) ('-' (Ident | Method))*)"`
 	Value string `parser:"('=' @~(Whitespace | EOF)+)?"`
 }
 
@@ -212,16 +795,25 @@ func Parse(pattern string) (Directive, error) {
 		return nil, errors.Errorf("empty pattern")
 	}
 
-	result, err := annotationParser.ParseString("", pattern)
-	if err != nil {
-		return nil, errors.Errorf("failed to parse pattern: %w", err)
+	var directive Directive
+	if strings.HasPrefix(pattern, "zero:api-labels") {
+		result, err := apiLabelsParser.ParseString("", pattern)
+		if err != nil {
+			return nil, errors.Errorf("failed to parse pattern: %w", err)
+		}
+		directive = result.Directive
+	} else {
+		result, err := annotationParser.ParseString("", pattern)
+		if err != nil {
+			return nil, errors.Errorf("failed to parse pattern: %w", err)
+		}
+		directive = result.Directive
 	}
-	directive := result.Directive
 	if err := directive.Validate(); err != nil {
 		return nil, errors.WithStack(err)
 	}
 
-	return result.Directive, nil
+	return directive, nil
 }
 
 // Pattern returns the http.ServeMux-compatible pattern.
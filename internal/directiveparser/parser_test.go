@@ -2,8 +2,10 @@ package directiveparser
 
 import (
 	"testing"
+	"time"
 
 	"github.com/alecthomas/assert/v2"
+	"github.com/alecthomas/zero"
 )
 
 func TestParse(t *testing.T) {
@@ -34,6 +36,24 @@ func TestParse(t *testing.T) {
 				Schedule: "1w",
 			},
 		},
+		{
+			name:    "CronWithLabels",
+			pattern: "zero:cron 5m traced recovered",
+			want: &DirectiveCron{
+				Schedule: "5m",
+				Labels:   []string{"traced", "recovered"},
+			},
+		},
+		{
+			name:    "ContextKey",
+			pattern: "zero:contextkey authenticated",
+			want:    &DirectiveContextKey{Label: "authenticated"},
+		},
+		{
+			name:    "ContextKeyMissingLabel",
+			pattern: "zero:contextkey",
+			wantErr: true,
+		},
 		{
 			name:    "CronInvalid",
 			pattern: "zero:cron 1y",
@@ -196,6 +216,75 @@ func TestParse(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:    "LimitLabels",
+			pattern: "zero:api POST /upload timeout=5s maxbody=1MB",
+			want: &DirectiveAPI{
+				Method: "POST",
+				Segments: []Segment{
+					LiteralSegment{Literal: "upload"},
+				},
+				Labels: []*Label{
+					{Name: "timeout", Value: "5s"},
+					{Name: "maxbody", Value: "1MB"},
+				},
+			},
+		},
+		{
+			name:    "InvalidTimeout",
+			pattern: "zero:api /upload timeout=soon",
+			wantErr: true,
+		},
+		{
+			name:    "InvalidMaxBody",
+			pattern: "zero:api /upload maxbody=lots",
+			wantErr: true,
+		},
+		{
+			name:    "InvalidCompress",
+			pattern: "zero:api GET /reports compress=big",
+			wantErr: true,
+		},
+		{
+			name:    "InvalidSlash",
+			pattern: "zero:api GET /users slash=loose",
+			wantErr: true,
+		},
+		{
+			name:    "InvalidConsumes",
+			pattern: "zero:api POST /upload consumes=pdf",
+			wantErr: true,
+		},
+		{
+			name:    "InvalidIdempotent",
+			pattern: "zero:api POST /payments idempotent=forever",
+			wantErr: true,
+		},
+		{
+			name:    "IdempotentGet",
+			pattern: "zero:api GET /payments idempotent",
+			wantErr: true,
+		},
+		{
+			name:    "FlagWithoutName",
+			pattern: "zero:api GET /checkout flag",
+			wantErr: true,
+		},
+		{
+			name:    "HeaderWithoutParameter",
+			pattern: "zero:api GET /users header",
+			wantErr: true,
+		},
+		{
+			name:    "DuplicateCookie",
+			pattern: "zero:api GET /users cookie=session,session:sid",
+			wantErr: true,
+		},
+		{
+			name:    "ETagPost",
+			pattern: "zero:api POST /payments etag",
+			wantErr: true,
+		},
 		{
 			name:    "CatchAllNotAtEnd",
 			pattern: "zero:api /users/{path...}/posts",
@@ -224,6 +313,19 @@ func TestParse(t *testing.T) {
 				Require: []string{"first", "second", "third"},
 			},
 		},
+		{
+			name:    "ProviderTest",
+			pattern: "zero:provider test weak",
+			want: &DirectiveProvider{
+				Weak: true,
+				Test: true,
+			},
+		},
+		{
+			name:    "ProviderTestMulti",
+			pattern: "zero:provider test multi",
+			wantErr: true,
+		},
 		{
 			name:    "ProviderWithStringRequire",
 			pattern: `zero:provider require="github.com/example/pkg/ExternalProvider"`,
@@ -238,6 +340,30 @@ func TestParse(t *testing.T) {
 				Require: []string{"LocalProvider", "github.com/example/pkg/ExternalProvider"},
 			},
 		},
+		{
+			name:    "ProviderProfile",
+			pattern: "zero:provider weak profile=dev,test",
+			want: &DirectiveProvider{
+				Weak:    true,
+				Profile: []string{"dev", "test"},
+			},
+		},
+		{
+			name:    "ProviderGroup",
+			pattern: `zero:provider group=HealthCheck,"github.com/example/pkg.Migrator"`,
+			want: &DirectiveProvider{
+				Group: []string{"HealthCheck", "github.com/example/pkg.Migrator"},
+			},
+		},
+		{
+			name:    "ProviderDeprecated",
+			pattern: `zero:provider weak deprecated="use NewV2 instead" replaces=NewV0,"github.com/example/pkg.Old"`,
+			want: &DirectiveProvider{
+				Weak:       true,
+				Deprecated: "use NewV2 instead",
+				Replaces:   []string{"NewV0", "github.com/example/pkg.Old"},
+			},
+		},
 		{
 			name:    "Config",
 			pattern: "zero:config",
@@ -279,6 +405,163 @@ func TestParse(t *testing.T) {
 			pattern: "zero:subscribe",
 			want:    &DirectiveSubscribe{},
 		},
+		{
+			name:    "SubscribeWithVersion",
+			pattern: "zero:subscribe version=2",
+			want:    &DirectiveSubscribe{Version: ptr(2)},
+		},
+		{
+			name:    "SubscribeWithLabels",
+			pattern: "zero:subscribe version=2 traced",
+			want:    &DirectiveSubscribe{Version: ptr(2), Labels: []string{"traced"}},
+		},
+		{
+			name:    "SubscribeWithConcurrency",
+			pattern: "zero:subscribe concurrency=8 prefetch=16 traced",
+			want:    &DirectiveSubscribe{Concurrency: ptr(8), Prefetch: ptr(16), Labels: []string{"traced"}},
+		},
+		{
+			name:    "SubscribeWithBatch",
+			pattern: "zero:subscribe batch=100 window=1m30s traced",
+			want:    &DirectiveSubscribe{Batch: ptr(100), Window: "1m30s", Labels: []string{"traced"}},
+		},
+		{
+			name:    "SubscribeWithDedupe",
+			pattern: "zero:subscribe concurrency=2 dedupe=5m traced",
+			want:    &DirectiveSubscribe{Concurrency: ptr(2), Dedupe: "5m", Labels: []string{"traced"}},
+		},
+		{
+			name:    "SubscribeInvalidDedupe",
+			pattern: "zero:subscribe dedupe=5x",
+			wantErr: true,
+		},
+		{
+			name:    "SubscribeWindowWithoutBatch",
+			pattern: "zero:subscribe window=1s",
+			wantErr: true,
+		},
+		{
+			name:    "SubscribeZeroBatch",
+			pattern: "zero:subscribe batch=0",
+			wantErr: true,
+		},
+		{
+			name:    "SubscribeZeroConcurrency",
+			pattern: "zero:subscribe concurrency=0",
+			wantErr: true,
+		},
+		{
+			name:    "Step",
+			pattern: "zero:step",
+			want:    &DirectiveStep{},
+		},
+		{
+			name:    "StepWithCompensation",
+			pattern: "zero:step compensate=ReleaseStock",
+			want:    &DirectiveStep{Compensate: "ReleaseStock"},
+		},
+		{
+			name:    "StepMissingCompensation",
+			pattern: "zero:step compensate=",
+			wantErr: true,
+		},
+		{
+			name:    "Worker",
+			pattern: "zero:worker",
+			want:    &DirectiveWorker{},
+		},
+		{
+			name:    "WorkerWithOptions",
+			pattern: "zero:worker concurrency=4 attempts=10 backoff=2s visibility=1m30s traced",
+			want: &DirectiveWorker{Concurrency: ptr(4), Attempts: ptr(10), Backoff: "2s", Visibility: "1m30s",
+				Labels: []string{"traced"}},
+		},
+		{
+			name:    "WorkerZeroAttempts",
+			pattern: "zero:worker attempts=0",
+			wantErr: true,
+		},
+		{
+			name:    "WorkerInvalidBackoff",
+			pattern: "zero:worker backoff=2x",
+			wantErr: true,
+		},
+		{
+			name:    "Module",
+			pattern: "zero:module observability",
+			want:    &DirectiveModule{Name: "observability"},
+		},
+		{
+			name:    "ModuleMissingName",
+			pattern: "zero:module",
+			wantErr: true,
+		},
+		{
+			name:    "OpenAPI",
+			pattern: `zero:openapi title="Users API" version=1.2.0 server=https://api.example.com/v1 tag=users="User management"`,
+			want: &DirectiveOpenAPI{Options: []*Label{
+				{Name: "title", Value: "Users API"},
+				{Name: "version", Value: "1.2.0"},
+				{Name: "server", Value: "https://api.example.com/v1"},
+				{Name: "tag", Value: "users=User management"},
+			}},
+		},
+		{
+			name:    "OpenAPIUnknownOption",
+			pattern: "zero:openapi colour=blue",
+			wantErr: true,
+		},
+		{
+			name:    "OpenAPIRelativeServer",
+			pattern: "zero:openapi server=/v1",
+			wantErr: true,
+		},
+		{
+			name:    "OpenAPITagWithoutDescription",
+			pattern: "zero:openapi tag=users",
+			wantErr: true,
+		},
+		{
+			name:    "APIGroup",
+			pattern: "zero:api-group prefix=/v2 tag=v2",
+			want: &DirectiveAPIGroup{Options: []*Label{
+				{Name: "prefix", Value: "/v2"},
+				{Name: "tag", Value: "v2"},
+			}},
+		},
+		{
+			name:    "APIGroupUnknownOption",
+			pattern: "zero:api-group version=2",
+			wantErr: true,
+		},
+		{
+			name:    "APIGroupTrailingSlash",
+			pattern: "zero:api-group prefix=/v2/",
+			wantErr: true,
+		},
+		{
+			name:    "APIGroupCatchAll",
+			pattern: "zero:api-group prefix=/files/{path...}",
+			wantErr: true,
+		},
+		{
+			name:    "APIGroupMethod",
+			pattern: "zero:api-group prefix=GET",
+			wantErr: true,
+		},
+		{
+			name:    "APILabels",
+			pattern: "zero:api-labels authenticated role=admin",
+			want: &DirectiveAPILabels{Labels: []*Label{
+				{Name: "authenticated"},
+				{Name: "role", Value: "admin"},
+			}},
+		},
+		{
+			name:    "APILabelsInvalid",
+			pattern: "zero:api-labels timeout=soon",
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -294,6 +577,111 @@ func TestParse(t *testing.T) {
 	}
 }
 
+func TestAPILimits(t *testing.T) {
+	directive, err := Parse("zero:api POST /upload timeout=1m30s maxbody=512KB")
+	assert.NoError(t, err)
+	api := directive.(*DirectiveAPI)
+	timeout, err := api.Timeout()
+	assert.NoError(t, err)
+	assert.Equal(t, 90*time.Second, timeout)
+	maxBody, err := api.MaxBody()
+	assert.NoError(t, err)
+	assert.Equal(t, zero.ByteSize(512<<10), maxBody)
+}
+
+func TestAPICompress(t *testing.T) {
+	directive, err := Parse("zero:api GET /reports compress=4KB")
+	assert.NoError(t, err)
+	minSize, err := directive.(*DirectiveAPI).Compress()
+	assert.NoError(t, err)
+	assert.Equal(t, zero.ByteSize(4<<10), minSize)
+}
+
+func TestAPITrailingSlash(t *testing.T) {
+	directive, err := Parse("zero:api GET /users/ slash=redirect")
+	assert.NoError(t, err)
+	policy, err := directive.(*DirectiveAPI).TrailingSlash()
+	assert.NoError(t, err)
+	assert.Equal(t, "redirect", policy)
+}
+
+func TestAPIConsumes(t *testing.T) {
+	directive, err := Parse("zero:api POST /upload consumes=image/png,image/*")
+	assert.NoError(t, err)
+	mediaTypes, err := directive.(*DirectiveAPI).Consumes()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"image/png", "image/*"}, mediaTypes)
+}
+
+func TestAPIIdempotent(t *testing.T) {
+	directive, err := Parse("zero:api POST /payments idempotent=1h")
+	assert.NoError(t, err)
+	ttl, err := directive.(*DirectiveAPI).Idempotent()
+	assert.NoError(t, err)
+	assert.Equal(t, time.Hour, ttl)
+
+	directive, err = Parse("zero:api POST /payments idempotent")
+	assert.NoError(t, err)
+	ttl, err = directive.(*DirectiveAPI).Idempotent()
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), ttl)
+}
+
+func TestAPISkipMiddleware(t *testing.T) {
+	directive, err := Parse("zero:api GET /healthz no-middleware skip=Logging,example.com/service.Auth")
+	assert.NoError(t, err)
+	api := directive.(*DirectiveAPI)
+	_, ok := api.Label("no-middleware")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"Logging", "example.com/service.Auth"}, api.SkipMiddleware())
+
+	directive, err = Parse("zero:api GET /healthz")
+	assert.NoError(t, err)
+	assert.Equal(t, nil, directive.(*DirectiveAPI).SkipMiddleware())
+}
+
+func TestAPIFlag(t *testing.T) {
+	directive, err := Parse("zero:api GET /checkout flag=new-checkout")
+	assert.NoError(t, err)
+	flag, err := directive.(*DirectiveAPI).Flag()
+	assert.NoError(t, err)
+	assert.Equal(t, "new-checkout", flag)
+
+	directive, err = Parse("zero:api GET /checkout")
+	assert.NoError(t, err)
+	flag, err = directive.(*DirectiveAPI).Flag()
+	assert.NoError(t, err)
+	assert.Equal(t, "", flag)
+}
+
+func TestAPIParameterBindings(t *testing.T) {
+	directive, err := Parse("zero:api GET /users header=apiKey:X-Api-Key,requestID cookie=session:sid")
+	assert.NoError(t, err)
+	api := directive.(*DirectiveAPI)
+	headers, err := api.Headers()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"apiKey": "X-Api-Key", "requestID": ""}, headers)
+	cookies, err := api.Cookies()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"session": "sid"}, cookies)
+
+	directive, err = Parse("zero:api GET /users")
+	assert.NoError(t, err)
+	headers, err = directive.(*DirectiveAPI).Headers()
+	assert.NoError(t, err)
+	assert.Equal(t, nil, headers)
+}
+
+func TestAPIGroupPrefix(t *testing.T) {
+	directive, err := Parse("zero:api-group prefix=/tenants/{tenant}/v2")
+	assert.NoError(t, err)
+	group := directive.(*DirectiveAPIGroup)
+	prefix, err := group.Prefix()
+	assert.NoError(t, err)
+	assert.Equal(t, []Segment{LiteralSegment{Literal: "tenants"}, WildcardSegment{Name: "tenant"}, LiteralSegment{Literal: "v2"}}, prefix)
+	assert.Equal(t, "", group.Tag())
+}
+
 func TestPatternString(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -331,6 +719,50 @@ func TestPatternString(t *testing.T) {
 			name:    "Subscribe",
 			pattern: "zero:subscribe",
 		},
+		{
+			name:    "SubscribeWithVersion",
+			pattern: "zero:subscribe version=0",
+		},
+		{
+			name:    "SubscribeWithLabels",
+			pattern: "zero:subscribe traced",
+		},
+		{
+			name:    "SubscribeWithConcurrency",
+			pattern: "zero:subscribe version=1 concurrency=4 prefetch=8 traced",
+		},
+		{
+			name:    "SubscribeWithBatch",
+			pattern: "zero:subscribe concurrency=2 batch=100 window=500ms",
+		},
+		{
+			name:    "SubscribeWithDedupe",
+			pattern: "zero:subscribe batch=10 dedupe=1h",
+		},
+		{
+			name:    "ContextKey",
+			pattern: "zero:contextkey authenticated",
+		},
+		{
+			name:    "CronWithLabels",
+			pattern: "zero:cron 1h traced",
+		},
+		{
+			name:    "Module",
+			pattern: "zero:module observability",
+		},
+		{
+			name:    "OpenAPI",
+			pattern: `zero:openapi title="Users API" version=1.2.0`,
+		},
+		{
+			name:    "APIGroup",
+			pattern: "zero:api-group prefix=/v2 tag=v2",
+		},
+		{
+			name:    "APILabels",
+			pattern: "zero:api-labels authenticated role=admin",
+		},
 	}
 
 	for _, tt := range tests {
@@ -341,3 +773,5 @@ func TestPatternString(t *testing.T) {
 		})
 	}
 }
+
+func ptr[T any](v T) *T { return &v }
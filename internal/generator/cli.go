@@ -0,0 +1,411 @@
+package generator
+
+import (
+	"cmp"
+	"fmt"
+	"go/types"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/alecthomas/zero/cli"
+	"github.com/alecthomas/zero/internal/codewriter"
+	"github.com/alecthomas/zero/internal/depgraph"
+)
+
+// sqlConfigType is the configuration of the SQL provider, whose Migrate flag is set by the migrate command.
+const sqlConfigType = "github.com/alecthomas/zero/providers/sql.Config"
+
+// writeCLI writes the ZeroCLI Kong command-line interface, with subcommands for serving and operating the service.
+//
+// Subcommands are only generated for capabilities the service has, eg. migrate requires the SQL provider.
+func writeCLI(w *codewriter.Writer, graph *depgraph.Graph, openAPI []byte) {
+	_, hasSQL := graph.Configs[sqlConfigType]
+	hasSQL = hasSQL && len(graph.Providers["*database/sql.DB"]) > 0
+	topics := graph.Topics()
+
+	w.L("// ZeroCLI is a Kong command-line interface for the service, with subcommands for serving and operating it.")
+	w.L("//")
+	w.L("// eg.")
+	w.L("//")
+	w.L("//	var cli ZeroCLI")
+	w.L("//")
+	w.L("//	func main() {")
+	w.L("//		kctx := kong.Parse(&cli)")
+	w.L("//		kctx.FatalIfErrorf(kctx.Run())")
+	w.L("//	}")
+	w.L("type ZeroCLI struct {")
+	w.In(func(w *codewriter.Writer) {
+		w.L("ZeroConfig")
+		w.L("")
+		w.L("Serve ZeroServeCmd `cmd:\"\" default:\"1\" help:\"Run the service (default).\"`")
+		w.L("Config ZeroConfigCmd `cmd:\"\" help:\"Print the effective configuration, with secrets masked.\"`")
+		w.L("Completions ZeroCompletionsCmd `cmd:\"\" help:\"Print a shell completion script.\"`")
+		w.L("Man ZeroManCmd `cmd:\"\" help:\"Print a man page.\"`")
+		if hasSQL {
+			w.L("Migrate ZeroMigrateCmd `cmd:\"\" help:\"Apply SQL migrations.\"`")
+		}
+		if len(graph.APIs) > 0 {
+			w.L("Routes ZeroRoutesCmd `cmd:\"\" help:\"Print the routing table.\"`")
+			w.L("OpenAPI ZeroOpenAPICmd `cmd:\"\" name:\"openapi\" help:\"Print the OpenAPI specification.\"`")
+		}
+		if len(graph.CronJobs) > 0 {
+			w.L("Cron ZeroCronCmd `cmd:\"\" help:\"Manage cron jobs.\"`")
+		}
+		if len(topics) > 0 {
+			w.L("Publish ZeroPublishCmd `cmd:\"\" help:\"Publish an event to a PubSub topic.\"`")
+		}
+	})
+	w.L("}")
+	w.L("")
+
+	w.Import("os", "os/signal", "syscall")
+	w.L("// ProvideContext provides commands with a context that is cancelled on SIGINT or SIGTERM.")
+	w.L("func (c *ZeroCLI) ProvideContext() context.Context {")
+	w.In(func(w *codewriter.Writer) {
+		w.L("ctx, _ := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)")
+		w.L("return ctx")
+	})
+	w.L("}")
+	w.L("")
+	w.L("// ProvideConfig provides commands with the parsed configuration.")
+	w.L("func (c *ZeroCLI) ProvideConfig() ZeroConfig { return c.ZeroConfig }")
+	w.L("")
+
+	w.L("// ZeroServeCmd runs the service.")
+	w.L("type ZeroServeCmd struct{}")
+	w.L("")
+	w.L("func (ZeroServeCmd) Run(ctx context.Context, config ZeroConfig) error { return Run(ctx, config) }")
+
+	writeConfigCmd(w, graph)
+	writeCompletionsCmd(w, graph)
+
+	if hasSQL {
+		w.L("")
+		w.L("// ZeroMigrateCmd applies SQL migrations by connecting to the database with --sql-migrate.")
+		w.L("type ZeroMigrateCmd struct{}")
+		w.L("")
+		w.L("func (ZeroMigrateCmd) Run(ctx context.Context, config ZeroConfig) error {")
+		w.In(func(w *codewriter.Writer) {
+			w.L("config.Config%s.Migrate = true", hash(sqlConfigType))
+			w.L("injector := NewInjector(ctx, config)")
+			writeZeroConstructSingletonByName(w, graph, "db", "*database/sql.DB", "")
+			w.L("return db.Close()")
+		})
+		w.L("}")
+	}
+
+	if len(graph.APIs) > 0 {
+		writeRoutesCmd(w, graph)
+		w.L("")
+		w.L("// ZeroOpenAPICmd prints the OpenAPI specification.")
+		w.L("type ZeroOpenAPICmd struct{}")
+		w.L("")
+		w.L("func (ZeroOpenAPICmd) Run() error {")
+		w.In(func(w *codewriter.Writer) {
+			w.Import("fmt")
+			w.L("_, err := fmt.Fprintln(os.Stdout, %q)", strings.TrimSpace(string(openAPI)))
+			w.L("return err")
+		})
+		w.L("}")
+	}
+
+	if len(graph.CronJobs) > 0 {
+		writeCronCmd(w, graph)
+	}
+
+	if len(topics) > 0 {
+		writePublishCmd(w, graph, topics)
+	}
+}
+
+// writeRoutesCmd writes a command printing the routing table, which is computed during generation.
+func writeRoutesCmd(w *codewriter.Writer, graph *depgraph.Graph) {
+	w.L("")
+	w.L("// ZeroRoutesCmd prints the routing table, with the labels and middleware applied to each route.")
+	w.L("type ZeroRoutesCmd struct{}")
+	w.L("")
+	w.L("func (ZeroRoutesCmd) Run() error {")
+	w.In(func(w *codewriter.Writer) {
+		w.Import("fmt", "text/tabwriter")
+		w.L("tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)")
+		w.L(`fmt.Fprintln(tw, "METHOD\tPATTERN\tHANDLER\tLABELS\tMIDDLEWARE")`)
+		for _, route := range graph.Routes() {
+			line := strings.Join([]string{
+				route.Method, route.Pattern, route.Handler,
+				cmp.Or(strings.Join(route.Labels, " "), "-"), cmp.Or(strings.Join(route.Middleware, " "), "-"),
+			}, "\t")
+			w.L("fmt.Fprintln(tw, %q)", line)
+		}
+		w.L("return tw.Flush()")
+	})
+	w.L("}")
+}
+
+// writeConfigCmd writes a command printing the value of every flag once flags, environment variables and config files
+// have been merged, to debug which configuration a deployed service actually receives.
+//
+// Values of fields tagged secret:"" are masked, except for the user and host of URLs such as DSNs.
+func writeConfigCmd(w *codewriter.Writer, graph *depgraph.Graph) {
+	w.Import("fmt", "reflect", "slices", "strings", "text/tabwriter", "github.com/alecthomas/kong")
+	w.L("")
+	w.L("// ZeroConfigCmd prints the effective configuration, after merging flags, environment variables and config files.")
+	w.L("type ZeroConfigCmd struct{}")
+	w.L("")
+	w.L("func (ZeroConfigCmd) Run(kctx *kong.Context) error {")
+	w.In(func(w *codewriter.Writer) {
+		if hasRenames(graph) {
+			w.L("// Values given under the former names of renamed flags take precedence, as they do in the injector.")
+			w.L("formerNames := map[string]string{")
+			w.In(func(w *codewriter.Writer) {
+				for _, config := range stableMapIter(graph.Configs) {
+					for _, rename := range config.Renames {
+						w.L("%q: %q,", strings.TrimPrefix(rename.Flags[0], "--"), strings.TrimPrefix(rename.Flag, "--"))
+					}
+				}
+			})
+			w.L("}")
+			w.L("renamed := map[string]*kong.Flag{}")
+			w.L("for _, flag := range kctx.Flags() {")
+			w.In(func(w *codewriter.Writer) {
+				w.L("if current, ok := formerNames[flag.Name]; ok && !flag.Target.IsNil() {")
+				w.In(func(w *codewriter.Writer) {
+					w.L("renamed[current] = flag")
+				})
+				w.L("}")
+			})
+			w.L("}")
+		}
+		w.L("flags := slices.SortedFunc(slices.Values(kctx.Flags()), func(a, b *kong.Flag) int { return strings.Compare(a.Name, b.Name) })")
+		w.L("tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)")
+		w.L(`fmt.Fprintln(tw, "FLAG\tVALUE")`)
+		w.L("for _, flag := range flags {")
+		w.In(func(w *codewriter.Writer) {
+			w.L(`if flag.Hidden || flag.Name == "help" {`)
+			w.In(func(w *codewriter.Writer) {
+				w.L("continue")
+			})
+			w.L("}")
+			w.L("value := flag.Target")
+			if hasRenames(graph) {
+				w.L("if former, ok := renamed[flag.Name]; ok {")
+				w.In(func(w *codewriter.Writer) {
+					w.L("value = former.Target")
+				})
+				w.L("}")
+			}
+			w.L(`fmt.Fprintf(tw, "--%%s\t%%s\n", flag.Name, zeroConfigValue(value, flag.Tag))`)
+		})
+		w.L("}")
+		w.L("return tw.Flush()")
+	})
+	w.L("}")
+	w.L("")
+
+	w.Import("net/url")
+	w.L(`// zeroConfigValue formats the value of a config flag, masking it if the flag is tagged secret:"".`)
+	w.L("func zeroConfigValue(value reflect.Value, tag *kong.Tag) string {")
+	w.In(func(w *codewriter.Writer) {
+		w.L("formatted := zeroFormatConfigValue(value, tag)")
+		w.L(`if !tag.Has("secret") || formatted == "" {`)
+		w.In(func(w *codewriter.Writer) {
+			w.L("return formatted")
+		})
+		w.L("}")
+		w.L("// Only the password of URLs such as DSNs is masked, so that the host they refer to remains visible.")
+		w.L("if u, err := url.Parse(formatted); err == nil && u.User != nil {")
+		w.In(func(w *codewriter.Writer) {
+			w.L("if _, ok := u.User.Password(); ok {")
+			w.In(func(w *codewriter.Writer) {
+				w.L("return u.Redacted()")
+			})
+			w.L("}")
+		})
+		w.L("}")
+		w.L(`return "********"`)
+	})
+	w.L("}")
+	w.L("")
+
+	w.Import("encoding")
+	w.L("// zeroFormatConfigValue formats a config value as it would be given on the command line.")
+	w.L("func zeroFormatConfigValue(value reflect.Value, tag *kong.Tag) string {")
+	w.In(func(w *codewriter.Writer) {
+		w.L("for value.Kind() == reflect.Pointer {")
+		w.In(func(w *codewriter.Writer) {
+			w.L("if value.IsNil() {")
+			w.In(func(w *codewriter.Writer) {
+				w.L(`return ""`)
+			})
+			w.L("}")
+			w.L("value = value.Elem()")
+		})
+		w.L("}")
+		w.L("v := value.Interface()")
+		w.L("if value.CanAddr() {")
+		w.In(func(w *codewriter.Writer) {
+			w.L("v = value.Addr().Interface()")
+		})
+		w.L("}")
+		w.L("switch v := v.(type) {")
+		w.L("case fmt.Stringer:")
+		w.In(func(w *codewriter.Writer) {
+			w.L("return v.String()")
+		})
+		w.L("case encoding.TextMarshaler:")
+		w.In(func(w *codewriter.Writer) {
+			w.L("if text, err := v.MarshalText(); err == nil {")
+			w.In(func(w *codewriter.Writer) {
+				w.L("return string(text)")
+			})
+			w.L("}")
+		})
+		w.L("}")
+		w.L(`sep, mapSep := ",", ";"`)
+		w.L("if tag.Sep >= 0 {")
+		w.In(func(w *codewriter.Writer) {
+			w.L("sep = string(tag.Sep)")
+		})
+		w.L("}")
+		w.L("if tag.MapSep >= 0 {")
+		w.In(func(w *codewriter.Writer) {
+			w.L("mapSep = string(tag.MapSep)")
+		})
+		w.L("}")
+		w.L("switch value.Kind() {")
+		w.L("case reflect.Slice, reflect.Array:")
+		w.In(func(w *codewriter.Writer) {
+			w.L("elements := make([]string, value.Len())")
+			w.L("for i := range elements {")
+			w.In(func(w *codewriter.Writer) {
+				w.L("elements[i] = zeroFormatConfigValue(value.Index(i), tag)")
+			})
+			w.L("}")
+			w.L("return strings.Join(elements, sep)")
+		})
+		w.L("case reflect.Map:")
+		w.In(func(w *codewriter.Writer) {
+			w.L("entries := make([]string, 0, value.Len())")
+			w.L("for iter := value.MapRange(); iter.Next(); {")
+			w.In(func(w *codewriter.Writer) {
+				w.L(`entries = append(entries, zeroFormatConfigValue(iter.Key(), tag)+"="+zeroFormatConfigValue(iter.Value(), tag))`)
+			})
+			w.L("}")
+			w.L("slices.Sort(entries)")
+			w.L("return strings.Join(entries, mapSep)")
+		})
+		w.L("}")
+		w.L("return fmt.Sprint(value.Interface())")
+	})
+	w.L("}")
+}
+
+// writeCompletionsCmd writes commands printing shell completion scripts and a man page, generated from the Kong model
+// at runtime so that they include any flags and commands of the application embedding ZeroCLI.
+func writeCompletionsCmd(w *codewriter.Writer, graph *depgraph.Graph) {
+	const pkg = "github.com/alecthomas/zero/cli"
+	alias := graph.ImportAlias(pkg)
+	w.Import(fmt.Sprintf("%s %q", alias, pkg), "github.com/alecthomas/kong")
+	w.L("")
+	w.L("// ZeroCompletionsCmd prints a shell completion script, eg. source <(service completions bash).")
+	w.L("type ZeroCompletionsCmd struct {")
+	w.In(func(w *codewriter.Writer) {
+		w.L("Shell string `arg:\"\" enum:%q help:\"Shell to print the completion script for (${enum}).\"`", strings.Join(cli.Shells, ","))
+	})
+	w.L("}")
+	w.L("")
+	w.L("func (c *ZeroCompletionsCmd) Run(kctx *kong.Context) error {")
+	w.In(func(w *codewriter.Writer) {
+		w.L("return %s.Completion(os.Stdout, kctx.Model, c.Shell)", alias)
+	})
+	w.L("}")
+	w.L("")
+	w.L("// ZeroManCmd prints a man page in roff format.")
+	w.L("type ZeroManCmd struct{}")
+	w.L("")
+	w.L("func (ZeroManCmd) Run(kctx *kong.Context) error {")
+	w.In(func(w *codewriter.Writer) {
+		w.L("return %s.ManPage(os.Stdout, kctx.Model)", alias)
+	})
+	w.L("}")
+}
+
+// writeCronCmd writes a command running a single cron job once, with its job middleware.
+func writeCronCmd(w *codewriter.Writer, graph *depgraph.Graph) {
+	names := make([]string, len(graph.CronJobs))
+	for i, cronJob := range graph.CronJobs {
+		names[i] = cronJobName(graph, cronJob)
+	}
+	w.L("")
+	w.L("// ZeroCronCmd manages cron jobs.")
+	w.L("type ZeroCronCmd struct {")
+	w.In(func(w *codewriter.Writer) {
+		w.L("Run ZeroCronRunCmd `cmd:\"\" help:\"Run a cron job once.\"`")
+	})
+	w.L("}")
+	w.L("")
+	w.L("// ZeroCronRunCmd runs a cron job once, without acquiring its lease.")
+	w.L("type ZeroCronRunCmd struct {")
+	w.In(func(w *codewriter.Writer) {
+		w.L("Job string `arg:\"\" enum:%q help:\"Name of the cron job to run (${enum}).\"`", strings.Join(names, ","))
+	})
+	w.L("}")
+	w.L("")
+	w.L("func (c *ZeroCronRunCmd) Run(ctx context.Context, config ZeroConfig) error {")
+	w.In(func(w *codewriter.Writer) {
+		w.L("injector := NewInjector(ctx, config)")
+		w.L("switch c.Job {")
+		for ji, cronJob := range graph.CronJobs {
+			w.L("case %q:", names[ji])
+			w.In(func(w *codewriter.Writer) {
+				receiver := graph.TypeRef(cronJob.Function.Signature().Recv().Type())
+				writeZeroConstructSingletonByName(w, graph, "r", receiver.String(), "")
+				job := writeJobMiddleware(w, graph, cronJob.Schedule.Labels, fmt.Sprintf("j%d", ji), "r."+cronJob.Function.Name())
+				w.L("return %s(ctx)", job)
+			})
+		}
+		w.L("}")
+		w.Import("fmt")
+		w.L(`return fmt.Errorf("unknown cron job %%q", c.Job)`)
+	})
+	w.L("}")
+}
+
+// writePublishCmd writes a command publishing a JSON payload as an event to a topic.
+func writePublishCmd(w *codewriter.Writer, graph *depgraph.Graph, topics map[string]types.Type) {
+	names := slices.Sorted(maps.Keys(topics))
+	w.L("")
+	w.L("// ZeroPublishCmd publishes an event to a PubSub topic.")
+	w.L("type ZeroPublishCmd struct {")
+	w.In(func(w *codewriter.Writer) {
+		w.L("Topic string `arg:\"\" enum:%q help:\"Name of the topic (${enum}).\"`", strings.Join(names, ","))
+		w.L("Payload string `arg:\"\" help:\"JSON payload of the event.\"`")
+	})
+	w.L("}")
+	w.L("")
+	w.L("func (c *ZeroPublishCmd) Run(ctx context.Context, config ZeroConfig) error {")
+	w.In(func(w *codewriter.Writer) {
+		w.Import("encoding/json", "fmt")
+		w.L("injector := NewInjector(ctx, config)")
+		w.L("switch c.Topic {")
+		for _, name := range names {
+			w.L("case %q:", name)
+			w.In(func(w *codewriter.Writer) {
+				payloadRef := graph.TypeRef(topics[name])
+				w.Import(payloadRef.Imports()...)
+				w.L("var payload %s", payloadRef.Ref)
+				w.L("if err := json.Unmarshal([]byte(c.Payload), &payload); err != nil {")
+				w.In(func(w *codewriter.Writer) {
+					w.L(`return fmt.Errorf("invalid payload for topic %s: %%w", err)`, name)
+				})
+				w.L("}")
+				writeZeroConstructSingletonByName(w, graph, "topic", fmt.Sprintf("github.com/alecthomas/zero/providers/pubsub.Topic[%s]", payloadRef.Ref), "")
+				w.Import("github.com/alecthomas/zero/providers/pubsub")
+				w.L("return topic.Publish(ctx, pubsub.NewEvent(payload))")
+			})
+		}
+		w.L("}")
+		w.L(`return fmt.Errorf("unknown topic %%q", c.Topic)`)
+	})
+	w.L("}")
+}
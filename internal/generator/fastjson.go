@@ -0,0 +1,590 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/types"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/alecthomas/zero/internal/codewriter"
+	"github.com/alecthomas/zero/internal/depgraph"
+)
+
+// jsonCodecs generates JSON encoders for API response types and decoders for API request types, which encode and
+// decode with zero.JSONEncoder and zero.JSONDecoder rather than reflection.
+//
+// Encoders and decoders are generated for structs, and for pointers to and slices of types with encoders or decoders.
+// Basic types are encoded inline, and any other type, including those with their own JSON or text marshalling
+// methods, is encoded with encoding/json.
+type jsonCodecs struct {
+	graph *depgraph.Graph
+	// names of the generated functions by type, without the zeroEncodeJSON/zeroDecodeJSON prefix.
+	names     map[string]string
+	taken     map[string]bool
+	supported map[string]bool
+	encoders  jsonCodecQueue
+	decoders  jsonCodecQueue
+}
+
+// jsonCodecQueue is the types requiring a generated function, in the order they were discovered.
+type jsonCodecQueue struct {
+	types   []types.Type
+	queued  map[string]bool
+	written int
+}
+
+func (q *jsonCodecQueue) add(t types.Type) {
+	key := types.TypeString(t, nil)
+	if q.queued == nil {
+		q.queued = map[string]bool{}
+	}
+	if !q.queued[key] {
+		q.queued[key] = true
+		q.types = append(q.types, t)
+	}
+}
+
+func newJSONCodecs(graph *depgraph.Graph) *jsonCodecs {
+	return &jsonCodecs{
+		graph:     graph,
+		names:     map[string]string{},
+		taken:     map[string]bool{},
+		supported: map[string]bool{},
+	}
+}
+
+// responseEncoder returns the name of the generated encoder for an API response of type t, or false if the response
+// is encoded by zero.EncodeResponse.
+func (c *jsonCodecs) responseEncoder(t types.Type) (string, bool) {
+	if c == nil || !c.coded(t) || hasMethod(t, "ServeHTTP", "Read", "StatusCode") || types.TypeString(t, nil) == "*net/http.Response" {
+		return "", false
+	}
+	c.encoders.add(t)
+	return "zeroEncodeJSON" + c.name(t), true
+}
+
+// requestDecoder returns the name of the generated decoder for an API request body of type t, or false if the request
+// is decoded by zero.DecodeRequest.
+func (c *jsonCodecs) requestDecoder(t types.Type, method string) (string, bool) {
+	if c == nil || !hasRequestBody(method) || !c.coded(t) {
+		return "", false
+	}
+	c.decoders.add(t)
+	return "zeroDecodeJSON" + c.name(t), true
+}
+
+// coded returns true if a function is generated to encode and decode t.
+func (c *jsonCodecs) coded(t types.Type) bool {
+	key := types.TypeString(t, nil)
+	if supported, ok := c.supported[key]; ok {
+		return supported
+	}
+	// Assume recursive types are supported while checking them.
+	c.supported[key] = true
+	supported := c.checkCoded(t)
+	c.supported[key] = supported
+	return supported
+}
+
+func (c *jsonCodecs) checkCoded(t types.Type) bool {
+	if hasMethod(t, "MarshalJSON", "UnmarshalJSON", "MarshalText", "UnmarshalText") || !c.accessible(t) {
+		return false
+	}
+	switch t := t.(type) {
+	case *types.Pointer:
+		_, basic := jsonBasic(t.Elem())
+		return basic || c.coded(t.Elem())
+	case *types.Slice:
+		// []byte is base64 encoded.
+		if basic, ok := t.Elem().Underlying().(*types.Basic); ok && basic.Kind() == types.Byte {
+			return false
+		}
+		return true
+	case *types.Named:
+		if t.TypeArgs().Len() > 0 {
+			return false
+		}
+		strct, ok := t.Underlying().(*types.Struct)
+		if !ok {
+			return false
+		}
+		_, ok = jsonFields(strct)
+		return ok
+	default:
+		return false
+	}
+}
+
+// accessible returns true if t can be referred to from the destination package.
+func (c *jsonCodecs) accessible(t types.Type) bool {
+	switch t := t.(type) {
+	case *types.Named:
+		obj := t.Obj()
+		return obj.Pkg() == nil || obj.Pkg() == c.graph.Dest || (obj.Exported() && obj.Pkg().Name() != "main")
+	case *types.Pointer:
+		return c.accessible(t.Elem())
+	case *types.Slice:
+		return c.accessible(t.Elem())
+	case *types.Basic:
+		return true
+	default:
+		return false
+	}
+}
+
+// name returns the name of the generated functions for t, eg. "User" for zeroEncodeJSONUser.
+func (c *jsonCodecs) name(t types.Type) string {
+	key := types.TypeString(t, nil)
+	if name, ok := c.names[key]; ok {
+		return name
+	}
+	var name string
+	switch t := t.(type) {
+	case *types.Pointer:
+		name = c.elemName(t.Elem()) + "Ptr"
+	case *types.Slice:
+		name = c.elemName(t.Elem()) + "Slice"
+	case *types.Named:
+		name = t.Obj().Name()
+		if c.taken[name] && t.Obj().Pkg() != c.graph.Dest {
+			name = upperFirst(t.Obj().Pkg().Name()) + name
+		}
+	}
+	base := name
+	for i := 2; c.taken[name]; i++ {
+		name = base + strconv.Itoa(i)
+	}
+	c.taken[name] = true
+	c.names[key] = name
+	return name
+}
+
+func (c *jsonCodecs) elemName(t types.Type) string {
+	if c.coded(t) {
+		return c.name(t)
+	}
+	if named, ok := t.(*types.Named); ok {
+		return upperFirst(named.Obj().Name())
+	}
+	if basic, ok := t.(*types.Basic); ok {
+		return upperFirst(basic.Name())
+	}
+	return "Value"
+}
+
+// write the generated encoders and decoders, including those of the types they refer to.
+func (c *jsonCodecs) write(w *codewriter.Writer) {
+	if c == nil {
+		return
+	}
+	for c.encoders.written < len(c.encoders.types) || c.decoders.written < len(c.decoders.types) {
+		for ; c.encoders.written < len(c.encoders.types); c.encoders.written++ {
+			c.writeEncoder(w, c.encoders.types[c.encoders.written])
+		}
+		for ; c.decoders.written < len(c.decoders.types); c.decoders.written++ {
+			c.writeDecoder(w, c.decoders.types[c.decoders.written])
+		}
+	}
+}
+
+func (c *jsonCodecs) writeEncoder(w *codewriter.Writer, t types.Type) {
+	w.Import("github.com/alecthomas/zero")
+	name := c.name(t)
+	w.L("")
+	w.L("// zeroEncodeJSON%s encodes %s as JSON.", name, types.TypeString(t, nil))
+	w.L("func zeroEncodeJSON%s(e *zero.JSONEncoder, v *%s) {", name, c.typeRef(w, t))
+	w.In(func(w *codewriter.Writer) {
+		switch t := t.(type) {
+		case *types.Pointer:
+			w.L("if *v == nil {")
+			w.In(func(w *codewriter.Writer) {
+				w.L("e.Null()")
+				w.L("return")
+			})
+			w.L("}")
+			c.writeEncodeValue(w, t.Elem(), "*v")
+		case *types.Slice:
+			w.L("if *v == nil {")
+			w.In(func(w *codewriter.Writer) {
+				w.L("e.Null()")
+				w.L("return")
+			})
+			w.L("}")
+			w.L("e.BeginArray()")
+			w.L("for i := range *v {")
+			w.In(func(w *codewriter.Writer) {
+				w.L("e.Next()")
+				c.writeEncodeValue(w, t.Elem(), "&(*v)[i]")
+			})
+			w.L("}")
+			w.L("e.EndArray()")
+		case *types.Named:
+			fields, _ := jsonFields(t.Underlying().(*types.Struct)) //nolint
+			w.L("e.BeginObject()")
+			for _, field := range fields {
+				ref := "v." + field.field.Name()
+				key, _ := json.Marshal(field.name) //nolint:errchkjson
+				if field.omitEmpty {
+					w.L("if %s {", nonEmpty(field.field.Type(), ref))
+					w.In(func(w *codewriter.Writer) {
+						w.L("e.Key(%s)", rawString(string(key)+":"))
+						c.writeEncodeValue(w, field.field.Type(), "&"+ref)
+					})
+					w.L("}")
+				} else {
+					w.L("e.Key(%s)", rawString(string(key)+":"))
+					c.writeEncodeValue(w, field.field.Type(), "&"+ref)
+				}
+			}
+			w.L("e.EndObject()")
+		}
+	})
+	w.L("}")
+}
+
+// writeEncodeValue writes code encoding the value of type t pointed to by ptr.
+func (c *jsonCodecs) writeEncodeValue(w *codewriter.Writer, t types.Type, ptr string) {
+	value := deref(ptr)
+	if basic, ok := jsonBasic(t); ok {
+		info := basic.Info()
+		switch {
+		case info&types.IsBoolean != 0:
+			w.L("e.Bool(%s)", convert(t, "bool", value))
+		case info&types.IsUnsigned != 0:
+			w.L("e.Uint(%s)", convert(t, "uint64", value))
+		case info&types.IsInteger != 0:
+			w.L("e.Int(%s)", convert(t, "int64", value))
+		case basic.Kind() == types.Float32:
+			w.L("e.Float(float64(%s), 32)", value)
+		case info&types.IsFloat != 0:
+			w.L("e.Float(%s, 64)", convert(t, "float64", value))
+		default:
+			w.L("e.String(%s)", convert(t, "string", value))
+		}
+		return
+	}
+	if c.coded(t) {
+		c.encoders.add(t)
+		w.L("zeroEncodeJSON%s(e, %s)", c.name(t), ptr)
+		return
+	}
+	// As with encoding/json, methods with pointer receivers are called on fields and elements.
+	w.L("e.Value(%s)", ptr)
+}
+
+func (c *jsonCodecs) writeDecoder(w *codewriter.Writer, t types.Type) {
+	w.Import("github.com/alecthomas/zero")
+	name := c.name(t)
+	w.L("")
+	w.L("// zeroDecodeJSON%s decodes JSON into %s.", name, types.TypeString(t, nil))
+	w.L("func zeroDecodeJSON%s(d *zero.JSONDecoder, v *%s) {", name, c.typeRef(w, t))
+	w.In(func(w *codewriter.Writer) {
+		switch t := t.(type) {
+		case *types.Pointer:
+			w.L("if d.Null() {")
+			w.In(func(w *codewriter.Writer) {
+				w.L("*v = nil")
+				w.L("return")
+			})
+			w.L("}")
+			if decode, ok := c.decodeBasic(w, t.Elem()); ok {
+				w.L("*v = new(%s)", c.typeRef(w, t.Elem()))
+				w.L("**v = %s", decode)
+				return
+			}
+			w.L("if *v == nil {")
+			w.In(func(w *codewriter.Writer) {
+				w.L("*v = new(%s)", c.typeRef(w, t.Elem()))
+			})
+			w.L("}")
+			c.writeDecodeValue(w, t.Elem(), "*v")
+		case *types.Slice:
+			w.L("if d.Null() {")
+			w.In(func(w *codewriter.Writer) {
+				w.L("*v = nil")
+				w.L("return")
+			})
+			w.L("}")
+			w.L("out := %s{}", c.typeRef(w, t))
+			w.L("d.BeginArray()")
+			w.L("for d.More(']') {")
+			w.In(func(w *codewriter.Writer) {
+				w.L("var elem %s", c.typeRef(w, t.Elem()))
+				c.writeDecodeValue(w, t.Elem(), "&elem")
+				w.L("out = append(out, elem)")
+			})
+			w.L("}")
+			w.L("*v = out")
+		case *types.Named:
+			fields, _ := jsonFields(t.Underlying().(*types.Struct)) //nolint
+			w.L("if d.Null() {")
+			w.In(func(w *codewriter.Writer) {
+				w.L("return")
+			})
+			w.L("}")
+			w.L("d.BeginObject()")
+			w.L("for d.More('}') {")
+			w.In(func(w *codewriter.Writer) {
+				names := make([]string, 0, len(fields))
+				for _, field := range fields {
+					names = append(names, strconv.Quote(field.name))
+				}
+				w.L("switch d.Key(%s) {", strings.Join(names, ", "))
+				for i, field := range fields {
+					w.L("case %d:", i)
+					w.In(func(w *codewriter.Writer) {
+						c.writeDecodeValue(w, field.field.Type(), "&v."+field.field.Name())
+					})
+				}
+				w.L("default:")
+				w.In(func(w *codewriter.Writer) {
+					w.L("d.Skip()")
+				})
+				w.L("}")
+			})
+			w.L("}")
+		}
+	})
+	w.L("}")
+}
+
+// writeDecodeValue writes code decoding into the value of type t pointed to by ptr.
+func (c *jsonCodecs) writeDecodeValue(w *codewriter.Writer, t types.Type, ptr string) {
+	if decode, ok := c.decodeBasic(w, t); ok {
+		w.L("if !d.Null() {")
+		w.In(func(w *codewriter.Writer) {
+			w.L("%s = %s", deref(ptr), decode)
+		})
+		w.L("}")
+		return
+	}
+	if c.coded(t) {
+		c.decoders.add(t)
+		w.L("zeroDecodeJSON%s(d, %s)", c.name(t), ptr)
+		return
+	}
+	w.L("d.Value(%s)", ptr)
+}
+
+// decodeBasic returns an expression decoding a value of type t, if t is a basic type that is decoded inline.
+func (c *jsonCodecs) decodeBasic(w *codewriter.Writer, t types.Type) (string, bool) {
+	basic, ok := jsonBasic(t)
+	if !ok || !c.accessible(t) {
+		return "", false
+	}
+	info := basic.Info()
+	var decode string
+	switch {
+	case info&types.IsBoolean != 0:
+		decode = "d.Bool()"
+	case info&types.IsUnsigned != 0:
+		decode = fmt.Sprintf("d.Uint(%d)", intBits(basic))
+	case info&types.IsInteger != 0:
+		decode = fmt.Sprintf("d.Int(%d)", intBits(basic))
+	case basic.Kind() == types.Float32:
+		decode = "d.Float(32)"
+	case info&types.IsFloat != 0:
+		decode = "d.Float(64)"
+	default:
+		decode = "d.String()"
+	}
+	if !types.Identical(t, resultType(basic)) {
+		decode = fmt.Sprintf("%s(%s)", c.typeRef(w, t), decode)
+	}
+	return decode, true
+}
+
+// typeRef returns a reference to t from the destination package, importing the packages it refers to.
+func (c *jsonCodecs) typeRef(w *codewriter.Writer, t types.Type) string {
+	return types.TypeString(t, func(pkg *types.Package) string {
+		if pkg == c.graph.Dest {
+			return ""
+		}
+		alias := c.graph.ImportAlias(pkg.Path())
+		if alias == "" {
+			w.Import(pkg.Path())
+			return pkg.Name()
+		}
+		w.Import(fmt.Sprintf("%s %q", alias, pkg.Path()))
+		return alias
+	})
+}
+
+// jsonField is a field of a struct encoded as a key of a JSON object.
+type jsonField struct {
+	field     *types.Var
+	name      string
+	omitEmpty bool
+}
+
+// jsonFields returns the fields of strct encoded by encoding/json, or false if the struct has fields that the
+// generated encoders don't handle, such as embedded fields or fields with the string or omitzero options.
+func jsonFields(strct *types.Struct) ([]jsonField, bool) {
+	var fields []jsonField
+	names := map[string]bool{}
+	for i := range strct.NumFields() {
+		field := strct.Field(i)
+		if field.Embedded() {
+			return nil, false
+		}
+		if !field.Exported() {
+			continue
+		}
+		tag, ok := reflect.StructTag(strct.Tag(i)).Lookup("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if !ok || name == "" || !validJSONName(name) {
+			name = field.Name()
+		}
+		out := jsonField{field: field, name: name}
+		for option := range strings.SplitSeq(options, ",") {
+			switch option {
+			case "":
+			case "omitempty":
+				out.omitEmpty = true
+			default:
+				return nil, false
+			}
+		}
+		if names[name] {
+			return nil, false
+		}
+		names[name] = true
+		fields = append(fields, out)
+	}
+	return fields, true
+}
+
+// validJSONName returns true if name is a valid JSON object key in a struct tag, as in encoding/json.
+func validJSONName(name string) bool {
+	for _, c := range name {
+		switch {
+		case strings.ContainsRune("!#$%&()*+-./:;<=>?@[]^_{|}~ ", c):
+		case !unicode.IsLetter(c) && !unicode.IsDigit(c):
+			return false
+		}
+	}
+	return true
+}
+
+// jsonBasic returns the underlying basic type of t if it is encoded inline.
+func jsonBasic(t types.Type) (*types.Basic, bool) {
+	if hasMethod(t, "MarshalJSON", "UnmarshalJSON", "MarshalText", "UnmarshalText") {
+		return nil, false
+	}
+	if named, ok := t.(*types.Named); ok && named.TypeArgs().Len() > 0 {
+		return nil, false
+	}
+	basic, ok := t.Underlying().(*types.Basic)
+	if !ok || basic.Info()&(types.IsBoolean|types.IsInteger|types.IsFloat|types.IsString) == 0 || basic.Kind() == types.UnsafePointer {
+		return nil, false
+	}
+	return basic, true
+}
+
+// resultType returns the type returned by the zero.JSONDecoder method decoding basic.
+func resultType(basic *types.Basic) types.Type {
+	info := basic.Info()
+	switch {
+	case info&types.IsBoolean != 0:
+		return types.Typ[types.Bool]
+	case info&types.IsUnsigned != 0:
+		return types.Typ[types.Uint64]
+	case info&types.IsInteger != 0:
+		return types.Typ[types.Int64]
+	case info&types.IsFloat != 0:
+		return types.Typ[types.Float64]
+	default:
+		return types.Typ[types.String]
+	}
+}
+
+// intBits returns the bit size of an integer type, or 0 for int, uint and uintptr.
+func intBits(basic *types.Basic) int {
+	switch basic.Kind() {
+	case types.Int8, types.Uint8:
+		return 8
+	case types.Int16, types.Uint16:
+		return 16
+	case types.Int32, types.Uint32:
+		return 32
+	case types.Int64, types.Uint64:
+		return 64
+	default:
+		return 0
+	}
+}
+
+// convert returns value converted to basic if its type t isn't already basic.
+func convert(t types.Type, basic, value string) string {
+	if types.TypeString(t, nil) == basic {
+		return value
+	}
+	return basic + "(" + value + ")"
+}
+
+// nonEmpty returns an expression that is true if value of type t is not empty, as defined by the omitempty option of
+// encoding/json.
+func nonEmpty(t types.Type, value string) string {
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case u.Info()&types.IsBoolean != 0:
+			return value
+		case u.Info()&types.IsString != 0:
+			return value + ` != ""`
+		default:
+			return value + " != 0"
+		}
+	case *types.Slice, *types.Map, *types.Array:
+		return "len(" + value + ") > 0"
+	case *types.Pointer, *types.Interface, *types.Chan, *types.Signature:
+		return value + " != nil"
+	default:
+		return "true"
+	}
+}
+
+// deref returns the value pointed to by ptr.
+func deref(ptr string) string {
+	if value, ok := strings.CutPrefix(ptr, "&"); ok {
+		return value
+	}
+	return "*" + ptr
+}
+
+// rawString returns s as a Go string literal, preferring a raw string.
+func rawString(s string) string {
+	if strings.ContainsAny(s, "`\r") {
+		return strconv.Quote(s)
+	}
+	return "`" + s + "`"
+}
+
+// hasMethod returns true if t or *t has any of the named methods.
+func hasMethod(t types.Type, names ...string) bool {
+	if _, ok := t.(*types.Pointer); !ok {
+		if _, ok := t.Underlying().(*types.Interface); !ok {
+			t = types.NewPointer(t)
+		}
+	}
+	for _, name := range names {
+		if obj, _, _ := types.LookupFieldOrMethod(t, true, nil, name); obj != nil {
+			if _, ok := obj.(*types.Func); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func upperFirst(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[size:]
+}
@@ -2,34 +2,131 @@
 package generator
 
 import (
+	"bytes"
 	"cmp"
 	"fmt"
+	"go/token"
 	"go/types"
 	"hash/fnv"
 	"io"
 	"iter"
 	"maps"
+	"path/filepath"
 	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/alecthomas/errors"
 	"github.com/alecthomas/zero/internal/codewriter"
 	"github.com/alecthomas/zero/internal/depgraph"
+	"github.com/alecthomas/zero/internal/directiveparser"
 )
 
 type generateOptions struct {
-	tags []string
+	tags        []string
+	templates   Templates
+	runtimes    []Runtime
+	cli         bool
+	openAPI     []byte
+	benchmarks  bool
+	fastJSON    bool
+	cloudEvents bool
+	// Labels of the CloudEvents endpoints.
+	cloudEventsLabels string
+	parallel          bool
+	trace             bool
 }
 
 type Option func(*generateOptions)
 
+// Plugin is implemented by a [depgraph.Plugin] that generates code for its annotations.
+//
+// The code is appended to the generated file, and may construct dependencies with
+// ZeroConstructSingletons[T](ctx, injector).
+type Plugin interface {
+	Generate(w *codewriter.Writer, graph *depgraph.Graph, annotations []*depgraph.Annotation) error
+}
+
 // WithTags sets the list of //go:build tags to include in the generated code.
+//
+// Each tag may be a build constraint expression, eg. "!dev", and all of them must be satisfied.
 func WithTags(tags ...string) Option {
 	return func(o *generateOptions) {
 		o.tags = tags
 	}
 }
 
+// WithTemplates merges user-supplied template fragments with the generated code.
+func WithTemplates(templates Templates) Option {
+	return func(o *generateOptions) {
+		o.templates = templates
+	}
+}
+
+// WithRuntimes generates alternate entrypoints to Run for the given runtimes.
+func WithRuntimes(runtimes ...Runtime) Option {
+	return func(o *generateOptions) {
+		o.runtimes = runtimes
+	}
+}
+
+// WithCLI generates the ZeroCLI Kong command-line interface, embedding the OpenAPI specification for its openapi
+// command.
+func WithCLI(openAPI []byte) Option {
+	return func(o *generateOptions) {
+		o.cli = true
+		o.openAPI = openAPI
+	}
+}
+
+// WithBenchmarks generates BenchmarkZeroRoutes in the test harness written by [GenerateTest].
+func WithBenchmarks(enable bool) Option {
+	return func(o *generateOptions) {
+		o.benchmarks = enable
+	}
+}
+
+// WithFastJSON generates JSON encoders for API response types and decoders for API request body types, which avoid
+// the reflection and allocations of encoding/json.
+func WithFastJSON(enable bool) Option {
+	return func(o *generateOptions) {
+		o.fastJSON = enable
+	}
+}
+
+// WithCloudEvents generates a POST /events/<topic> endpoint for each PubSub topic, publishing CloudEvents received
+// over HTTP to the topic.
+func WithCloudEvents(enable bool) Option {
+	return func(o *generateOptions) {
+		o.cloudEvents = enable
+	}
+}
+
+// WithCloudEventsLabels applies labels to the endpoints generated by [WithCloudEvents], selecting their middleware and
+// request limits as for an API, eg. "authenticated maxbody=1MB".
+func WithCloudEventsLabels(labels string) Option {
+	return func(o *generateOptions) {
+		o.cloudEventsLabels = labels
+	}
+}
+
+// WithParallelConstruction generates a Run that constructs independent providers concurrently before serving, level by
+// level in the order of their dependencies.
+func WithParallelConstruction(enable bool) Option {
+	return func(o *generateOptions) {
+		o.parallel = enable
+	}
+}
+
+// WithConstructionTracing generates construction code that logs each provider as it starts and completes at debug
+// level, and fails providers that take longer than the --construction-timeout configured in ZeroConfig.
+func WithConstructionTracing(enable bool) Option {
+	return func(o *generateOptions) {
+		o.trace = enable
+	}
+}
+
 // Generate Zero's bootstrap code.
 func Generate(out io.Writer, graph *depgraph.Graph, options ...Option) error {
 	opts := &generateOptions{}
@@ -37,12 +134,55 @@ func Generate(out io.Writer, graph *depgraph.Graph, options ...Option) error {
 		option(opts)
 	}
 
+	templates, err := opts.templates.parse()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	file := fileData{Package: graph.Dest.Name()}
+
 	w := codewriter.New(graph.Dest.Name())
 	if len(opts.tags) > 0 {
 		pw := w.Prelude()
-		pw.L("//go:build %s", strings.Join(opts.tags, " "))
+		pw.L("//go:build %s", buildConstraint(opts.tags))
+		pw.L("")
+	}
+	if templates.header != nil {
+		header, err := render(templates.header, file)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		pw := w.Prelude()
+		pw.L("%s", header)
 		pw.L("")
 	}
+	w.Import(opts.templates.Imports...)
+
+	var codecs *jsonCodecs
+	if opts.fastJSON {
+		codecs = newJSONCodecs(graph)
+	}
+
+	// Render handler wrappers up front, as errors can't be returned while writing.
+	handlerWrappers := make([][2]string, len(graph.APIs))
+	for i, api := range graph.APIs {
+		prefix, suffix, err := templates.wrapHandler(handlerData{
+			Pattern:  api.Pattern.Pattern(),
+			Method:   api.Pattern.Method,
+			Path:     api.Pattern.Path(),
+			Function: api.Function.FullName(),
+		})
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		handlerWrappers[i] = [2]string{prefix, suffix}
+	}
+	var cloudEvents map[string]*depgraph.API
+	if opts.cloudEvents {
+		cloudEvents, err = cloudEventsAPIs(graph, opts.cloudEventsLabels)
+		if err != nil {
+			return err
+		}
+	}
 
 	w.Import("context")
 	w.L("// Config contains combined Kong configuration for all types constructable by the [Injector].")
@@ -51,34 +191,343 @@ func Generate(out io.Writer, graph *depgraph.Graph, options ...Option) error {
 		for key, config := range stableMapIter(graph.Configs) {
 			alias := "Config" + hash(key)
 			ref := graph.TypeRef(config.Type)
-			w.Import(ref.Import)
+			w.Import(ref.Imports()...)
 			prefix := ""
 			if config.Directive.Prefix != "" {
 				prefix = fmt.Sprintf(" prefix:%q", config.Directive.Prefix)
 			}
 			w.L("%s %s `embed:\"\"%s`", alias, ref.Ref, prefix)
+			for _, rename := range config.Renames {
+				ref := graph.TypeRef(rename.Type)
+				w.Import(ref.Imports()...)
+				tags := fmt.Sprintf("name:%q", strings.TrimPrefix(rename.Flags[0], "--"))
+				if len(rename.Flags) > 1 {
+					aliases := make([]string, 0, len(rename.Flags)-1)
+					for _, flag := range rename.Flags[1:] {
+						aliases = append(aliases, strings.TrimPrefix(flag, "--"))
+					}
+					tags += fmt.Sprintf(" aliases:%q", strings.Join(aliases, ","))
+				}
+				if len(rename.Envs) > 0 {
+					tags += fmt.Sprintf(" env:%q", strings.Join(rename.Envs, ","))
+				}
+				if rename.Tags != "" {
+					tags += " " + rename.Tags
+				}
+				w.L("%s *%s `%s hidden:\"\"` // Former name of %s.", renamedField(key, rename), ref.Ref, tags, rename.Flag)
+			}
+		}
+		if opts.trace {
+			w.Import("time")
+			w.L("ConstructionTimeout time.Duration `name:\"construction-timeout\" help:\"Maximum time to wait for each provider to be constructed, or 0 for no limit.\" default:\"1m\"`")
 		}
 	})
 	w.L("}")
 	w.L("")
+	writeApplyRenames(w, graph)
+	writeValidateConfig(w, graph)
 
 	w.L("// Injector contains the constructed dependency graph.")
 	w.L("type Injector struct {")
 	w.In(func(w *codewriter.Writer) {
 		w.L("config     ZeroConfig")
 		w.L("singletons map[reflect.Type]any")
+		w.L("failures   map[reflect.Type]error")
+		if concurrentConstruction(graph, opts) {
+			w.Import("sync")
+			w.L("mu         sync.Mutex // Guards singletons, failures and pending during concurrent construction.")
+			w.L("pending    map[reflect.Type]chan struct{} // Closed when the type being constructed is stored.")
+		}
+		if hasValidators(graph) {
+			w.L("invalid    error      // Error validating the config, returned instead of constructing anything.")
+		}
 	})
 	w.L("}")
+	if concurrentConstruction(graph, opts) {
+		w.L("")
+		w.L("// zeroConstruction is a type being constructed, carried by the context passed to the providers of its dependencies.")
+		w.L("type zeroConstruction struct {")
+		w.In(func(w *codewriter.Writer) {
+			w.L("typ    reflect.Type")
+			w.L("parent *zeroConstruction")
+		})
+		w.L("}")
+		w.L("")
+		w.L("type zeroConstructionKey struct{}")
+	}
 
 	w.L("")
 	w.L("// NewInjector creates a new Injector with the given context and configuration.")
 	w.L("func NewInjector(ctx context.Context, config ZeroConfig) *Injector {")
 	w.In(func(w *codewriter.Writer) {
-		w.L("return &Injector{config: config, singletons: map[reflect.Type]any{}}")
+		if hasRenames(graph) {
+			w.L("config.applyRenames()")
+		}
+		fields := "config: config, "
+		if hasValidators(graph) {
+			fields += "invalid: config.Validate(), "
+		}
+		fields += "singletons: map[reflect.Type]any{}, failures: map[reflect.Type]error{}"
+		if concurrentConstruction(graph, opts) {
+			fields += ", pending: map[reflect.Type]chan struct{}{}"
+		}
+		w.L("return &Injector{%s}", fields)
+	})
+	w.L("}")
+	w.L("")
+
+	// Libraries only include the injector, as their handlers and jobs are registered by the services importing them.
+	if !graph.Library {
+		writeRegisterHandlers(w, graph, codecs, handlerWrappers, cloudEvents)
+		w.L("")
+		writeRegisterSubscribers(w, graph)
+		if err := writeEntrypoints(w, graph, templates, file, opts); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	w.L("// Construct an instance of T.")
+	w.L("func ZeroConstruct[T any](ctx context.Context, config ZeroConfig) (out T, err error) {")
+	w.In(func(w *codewriter.Writer) {
+		w.Import("reflect")
+		w.L("injector := NewInjector(ctx, config)")
+		w.L("return ZeroConstructSingletons[T](ctx, injector)")
 	})
 	w.L("}")
 	w.L("")
+	w.L("// ZeroConstructSingletons constructs a new instance of T, or returns an instance of T from the injector if already constructed.")
+	w.L("func ZeroConstructSingletons[T any](ctx context.Context, injector *Injector) (out T, err error) {")
+	w.In(func(w *codewriter.Writer) {
+		if hasValidators(graph) {
+			w.L("if injector.invalid != nil {")
+			w.In(func(w *codewriter.Writer) {
+				w.L("return out, injector.invalid")
+			})
+			w.L("}")
+		}
+		if concurrentConstruction(graph, opts) {
+			// The lock is not held during construction, so that providers constructed concurrently, either by Run or
+			// by zero.Lazy, don't block each other, and so that a provider may get a zero.Lazy dependency while being
+			// constructed. Construction of a type already in progress is waited for, so that it is constructed once,
+			// unless it is being constructed by the caller itself, which would never finish.
+			w.Import("fmt")
+			w.L("parent, _ := ctx.Value(zeroConstructionKey{}).(*zeroConstruction)")
+			w.L("injector.mu.Lock()")
+			w.L("for {")
+			w.In(func(w *codewriter.Writer) {
+				w.L("if singleton, ok := injector.singletons[reflect.TypeFor[T]()]; ok {")
+				w.In(func(w *codewriter.Writer) {
+					w.L("injector.mu.Unlock()")
+					w.L("return singleton.(T), nil")
+				})
+				w.L("}")
+				w.L("if err, ok := injector.failures[reflect.TypeFor[T]()]; ok {")
+				w.In(func(w *codewriter.Writer) {
+					w.L("injector.mu.Unlock()")
+					w.L("return out, err")
+				})
+				w.L("}")
+				w.L("pending, ok := injector.pending[reflect.TypeFor[T]()]")
+				w.L("if !ok {")
+				w.In(func(w *codewriter.Writer) {
+					w.L("break")
+				})
+				w.L("}")
+				w.L("for construction := parent; construction != nil; construction = construction.parent {")
+				w.In(func(w *codewriter.Writer) {
+					w.L("if construction.typ == reflect.TypeFor[T]() {")
+					w.In(func(w *codewriter.Writer) {
+						w.L("injector.mu.Unlock()")
+						w.L(`return out, fmt.Errorf("dependency cycle: %%s is already being constructed", construction.typ)`)
+					})
+					w.L("}")
+				})
+				w.L("}")
+				w.L("injector.mu.Unlock()")
+				w.L("<-pending")
+				w.L("injector.mu.Lock()")
+			})
+			w.L("}")
+			w.L("done := make(chan struct{})")
+			w.L("injector.pending[reflect.TypeFor[T]()] = done")
+			w.L("injector.mu.Unlock()")
+			w.L("ctx = context.WithValue(ctx, zeroConstructionKey{}, &zeroConstruction{typ: reflect.TypeFor[T](), parent: parent})")
+		} else {
+			w.L("if singleton, ok := injector.singletons[reflect.TypeFor[T]()]; ok {")
+			w.In(func(w *codewriter.Writer) {
+				w.L("return singleton.(T), nil")
+			})
+			w.L("}")
+			// Failures are cached so that dependents of a failed provider see its error rather than a zero value.
+			w.L("if err, ok := injector.failures[reflect.TypeFor[T]()]; ok {")
+			w.In(func(w *codewriter.Writer) {
+				w.L("return out, err")
+			})
+			w.L("}")
+		}
+		w.L("defer func() {")
+		w.In(func(w *codewriter.Writer) {
+			if concurrentConstruction(graph, opts) {
+				w.L("injector.mu.Lock()")
+				w.L("defer injector.mu.Unlock()")
+				w.L("delete(injector.pending, reflect.TypeFor[T]())")
+				w.L("defer close(done)")
+			}
+			w.L("if err != nil {")
+			w.In(func(w *codewriter.Writer) {
+				w.L("injector.failures[reflect.TypeFor[T]()] = err")
+				w.L("return")
+			})
+			w.L("}")
+			w.L("injector.singletons[reflect.TypeFor[T]()] = out")
+		})
+		w.L("}()")
+		w.Import("reflect")
+		w.L("switch reflect.TypeOf((*T)(nil)).Elem() {")
+		w.L("case reflect.TypeOf((*context.Context)(nil)).Elem():")
+		w.In(func(w *codewriter.Writer) {
+			w.L("return any(ctx).(T), nil")
+		})
+		w.W("\n")
+
+		for key, config := range stableMapIter(graph.Configs) {
+			alias := "Config" + hash(key)
+			ref := graph.TypeRef(config.Type)
+			w.Import(ref.Imports()...)
+			w.L("case reflect.TypeOf((**%s)(nil)).Elem(): // Handle pointer to config.", ref.Ref)
+			w.In(func(w *codewriter.Writer) {
+				w.L("return any(&injector.config.%s).(T), nil", alias)
+			})
+			w.W("\n")
+			w.L("case reflect.TypeOf((*%s)(nil)).Elem():", ref.Ref)
+			w.In(func(w *codewriter.Writer) {
+				w.L("return any(injector.config.%s).(T), nil", alias)
+			})
+			w.W("\n")
+		}
+
+		untraced := untracedTypes(graph)
+		for key, providers := range stableMapIter(graph.Providers) {
+			if len(providers) == 0 {
+				continue
+			}
+
+			// Skip base generic providers - only generate code for concrete types
+			if len(providers) > 0 && providers[0].IsGeneric && !isInstantiated(providers[0].Provides) {
+				continue
+			}
+
+			// For single providers, generate direct case
+			if len(providers) == 1 {
+				provider := providers[0]
+				ref := graph.TypeRef(provider.Provides)
+				w.Import(ref.Imports()...)
+				writeDocComment(w, provider.Documentation)
+				w.L("case reflect.TypeOf((*%s)(nil)).Elem():", ref.Ref)
+				w.In(func(w *codewriter.Writer) {
+					writeProviderCall(w, graph, provider, "p", "o", opts.trace && !untraced[key])
+					w.L("return any(o).(T), nil")
+				})
+				w.W("\n")
+				continue
+			}
+			// For multi-providers, handle as before
+			ref := graph.TypeRef(providers[0].Provides)
+			w.Import(ref.Imports()...)
+			w.L("case reflect.TypeOf((*%s)(nil)).Elem():", ref.Ref)
+			w.In(func(w *codewriter.Writer) {
+				// Construct all provider results
+				for pi, provider := range providers {
+					writeDocComment(w, provider.Documentation)
+					writeProviderCall(w, graph, provider, fmt.Sprintf("p%d_", pi), fmt.Sprintf("r%d", pi), opts.trace && !untraced[key])
+				}
+
+				// Determine if it's a map, slice or iterator and merge accordingly
+				providedType := providers[0].Provides.Underlying()
+				if args := iterSeqArgs(providers[0].Provides); args != nil {
+					writeIterSeqConcat(w, graph, ref, args, len(providers))
+					w.L("return any(result).(T), nil")
+					return
+				}
+				switch t := providedType.(type) {
+				case *types.Map:
+					w.Import("maps")
+					// Map merging
+					w.L("result := make(%s)", ref.Ref)
+					for pi := range providers {
+						w.L("maps.Copy(result, r%d)", pi)
+					}
+				case *types.Slice:
+					// Slice appending
+					w.L("var result %s", ref.Ref)
+					for pi := range providers {
+						w.L("result = append(result, r%d...)", pi)
+					}
+				default:
+					_ = t
+					w.L(`return out, fmt.Errorf("multi-provider type %s must be a map or slice", "%s")`, ref.Ref)
+				}
+				w.L("return any(result).(T), nil")
+			})
+			w.W("\n")
+		}
+
+		for _, group := range stableMapIter(graph.Groups) {
+			ifaceRef := graph.TypeRef(group.Interface)
+			w.Import(ifaceRef.Imports()...)
+			w.L("case reflect.TypeOf((*[]%s)(nil)).Elem():", ifaceRef.Ref)
+			w.In(func(w *codewriter.Writer) {
+				members := make([]string, len(group.Members))
+				for i, member := range group.Members {
+					members[i] = fmt.Sprintf("g%d", i)
+					writeZeroConstructSingleton(w, graph, members[i], member.Provides, "[]"+constructionName(graph, group.Interface)+" -> ")
+				}
+				w.L("return any([]%s{%s}).(T), nil", ifaceRef.Ref, strings.Join(members, ", "))
+			})
+			w.W("\n")
+		}
+
+		w.W("\n")
+
+		w.L("}")
+		w.Import("fmt")
+		w.L(`return out, fmt.Errorf("don't know how to construct %%s", reflect.TypeFor[T]())`)
+	})
+	w.L("}")
+
+	if opts.trace {
+		writeConstructTraced(w, graph)
+	}
+	if usesProtoMessages(graph) {
+		writeProtoHelpers(w)
+	}
+	if len(graph.GRPCServices) > 0 {
+		writeGRPCHelpers(w)
+	}
+	codecs.write(w)
+
+	for directive, annotations := range stableMapIter(graph.Annotations) {
+		if len(annotations) == 0 {
+			continue
+		}
+		plugin, ok := annotations[0].Plugin.(Plugin)
+		if !ok {
+			continue
+		}
+		w.L("")
+		if err := plugin.Generate(w, graph, annotations); err != nil {
+			return errors.Errorf("zero:%s: %w", directive, err)
+		}
+	}
+	_, err = out.Write(resolveLineDirectives(w.Bytes()))
+	if err != nil {
+		return errors.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
 
+// writeRegisterHandlers writes RegisterHandlers, which registers the handlers of all APIs with the mux.
+func writeRegisterHandlers(w *codewriter.Writer, graph *depgraph.Graph, codecs *jsonCodecs, handlerWrappers [][2]string, cloudEvents map[string]*depgraph.API) {
 	w.L("// RegisterHandlers registers all Zero handlers with the injector's [http.ServeMux].")
 	w.L("func RegisterHandlers(ctx context.Context, injector *Injector) error {")
 	w.In(func(w *codewriter.Writer) {
@@ -87,7 +536,7 @@ func Generate(out io.Writer, graph *depgraph.Graph, options ...Option) error {
 		for _, api := range graph.APIs {
 			receiver := api.Function.Signature().Recv().Type()
 			ref := graph.TypeRef(receiver)
-			w.Import(ref.Import)
+			w.Import(ref.Imports()...)
 			if _, ok := receivers[ref]; !ok {
 				receivers[ref] = receiverIndex
 				receiverIndex++
@@ -109,32 +558,55 @@ func Generate(out io.Writer, graph *depgraph.Graph, options ...Option) error {
 		writeZeroConstructSingletonByName(w, graph, "encodeResponse", "github.com/alecthomas/zero.ResponseEncoder", "")
 		w.L("_ = encodeError")
 		w.L("_ = encodeResponse")
-		for _, api := range graph.APIs {
+		_, hasServerConfig := graph.Configs[serverConfigType]
+		if hasServerConfig {
+			writeZeroConstructSingletonByName(w, graph, "serverConfig", serverConfigType, "")
+			w.L("_ = serverConfig")
+		}
+		_, hasContentEncodings := graph.Providers[contentEncodingsType]
+		hasContentEncodings = hasContentEncodings && slices.ContainsFunc(graph.APIs, func(api *depgraph.API) bool {
+			_, ok := api.Pattern.Label("compress")
+			return ok
+		})
+		if hasContentEncodings {
+			writeZeroConstructSingletonByName(w, graph, "contentEncodings", contentEncodingsType, "")
+		}
+		for ai, api := range graph.APIs {
+			writeDocComment(w, api.Documentation)
 			handler := "http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {"
 			closing := ""
-			for mi, middleware := range graph.Middleware {
-				if !middleware.Match(api) {
-					continue
-				}
-				ref := graph.FunctionRef(middleware.Function)
-				w.Import(ref.Import)
-				if middleware.Factory {
-					args := []string{}
-					params := middleware.Function.Signature().Params()
-					w.L("// Parameters for the %s middleware", ref.Ref)
-					for i := range params.Len() {
-						args = append(args, fmt.Sprintf("m%dp%d", mi, i))
-						paramType := params.At(i).Type()
-						paramName := params.At(i).Name()
-						writeParameterConstruction(w, graph, paramType, api.Label(paramName), fmt.Sprintf("m%dp", mi), i, true, "")
-					}
-					handler = fmt.Sprintf("%s(%s)(%s", ref.Ref, strings.Join(args, ", "), handler)
-				} else {
-					handler = fmt.Sprintf("%s(%s", ref.Ref, handler)
+			if _, ok := api.Pattern.Label("etag"); ok {
+				w.Import("github.com/alecthomas/zero")
+				handler = "zero.ETag(" + handler
+				closing += ")"
+			}
+			if _, ok := api.Pattern.Label("compress"); ok {
+				w.Import("github.com/alecthomas/zero")
+				encodings := ""
+				if hasContentEncodings {
+					encodings = ", contentEncodings..."
 				}
+				handler = fmt.Sprintf("zero.Compress(%s%s)(%s", compressMinSize(api, hasServerConfig), encodings, handler)
+				closing += ")"
+			}
+			handler, wrapped := writeMiddleware(w, graph, codecs, graph.Middleware, api, fmt.Sprintf("a%d", ai), handler)
+			closing += wrapped
+			handler = handlerWrappers[ai][0] + handler
+			closing += handlerWrappers[ai][1]
+			// Limits are outermost, so that middleware reading the body or taking its time is also limited.
+			if timeout, maxBody := requestLimits(w, api, hasServerConfig); timeout != "0" || maxBody != "0" {
+				w.Import("github.com/alecthomas/zero")
+				handler = fmt.Sprintf("zero.RequestLimits(logger, encodeError, %s, %s)(%s", timeout, maxBody, handler)
 				closing += ")"
 			}
-			w.L("mux.Handle(%q, %s", api.Pattern.Pattern(), handler)
+			// APIs without a method are registered for each of their methods with the same handler, and APIs with a
+			// trailing slash policy are also registered for the path with the trailing slash added or removed.
+			shared := api.Pattern.Method == "" || api.TrailingSlash != ""
+			if shared {
+				w.L("a%dHandler := %s", ai, handler)
+			} else {
+				w.L("mux.Handle(%q, %s", api.Pattern.Pattern(), handler)
+			}
 			w.In(func(w *codewriter.Writer) {
 				signature := api.Function.Signature()
 
@@ -142,18 +614,40 @@ func Generate(out io.Writer, graph *depgraph.Graph, options ...Option) error {
 				receiverIndex := receivers[ref]
 				params := signature.Params()
 
+				// Labels are validated during analysis.
+				if mediaTypes, _ := api.Pattern.Consumes(); mediaTypes != nil {
+					w.Import("github.com/alecthomas/zero")
+					quoted := make([]string, len(mediaTypes))
+					for i, mediaType := range mediaTypes {
+						quoted[i] = strconv.Quote(mediaType)
+					}
+					w.L("if err := zero.CheckContentType(r, %s); err != nil {", strings.Join(quoted, ", "))
+					w.In(func(w *codewriter.Writer) {
+						w.L("encodeError(logger, w, err.Error(), http.StatusUnsupportedMediaType)")
+						w.L("return")
+					})
+					w.L("}")
+				}
+
 				// First pass, decode any parameters from the Request
 				for i := range params.Len() {
 					paramType := params.At(i).Type()
 					paramName := params.At(i).Name()
 					typeName := types.TypeString(paramType, nil)
 					// Skip builtin types that are handled in the call site
-					if typeName != "*net/http.Request" && typeName != "net/http.ResponseWriter" && typeName != "context.Context" {
-						writeParameterConstruction(w, graph, paramType, paramName, "p", i, false, api.Pattern.Method)
+					if key := api.ContextKeys[i]; key != nil {
+						writeContextValue(w, graph, key, fmt.Sprintf("p%d", i))
+					} else if header := api.Headers[i]; header != nil {
+						writeHeaderParameter(w, graph, header, paramType, fmt.Sprintf("p%d", i))
+					} else if api.Params[i] != nil {
+						writeParamsStruct(w, graph, paramType, fmt.Sprintf("p%d", i))
+					} else if typeName != "*net/http.Request" && typeName != "net/http.ResponseWriter" && typeName != "context.Context" && typeName != "io.Reader" {
+						writeParameterConstruction(w, graph, codecs, paramType, paramName, "p", i, false, api.Pattern.Method)
 					}
 				}
 
 				// Second pass, construct the request.
+				mapped := writeLineDirective(w, graph, api.Position)
 				w.Indent()
 				results := signature.Results()
 				var responseType types.Type
@@ -172,6 +666,9 @@ func Generate(out io.Writer, graph *depgraph.Graph, options ...Option) error {
 				case 2: // Always (T, error)
 					w.W("out, herr := ")
 					responseType = results.At(0).Type()
+				case 3: // Always (status, T, error)
+					w.W("hstatus, out, herr := ")
+					responseType = results.At(1).Type()
 				}
 				w.W("r%d.%s(", receiverIndex, api.Function.Name())
 				for i := range params.Len() {
@@ -182,26 +679,77 @@ func Generate(out io.Writer, graph *depgraph.Graph, options ...Option) error {
 					writeParameterCall(w, paramType, "p", i)
 				}
 				w.W(")\n")
+				if mapped {
+					w.W("%s\n", lineDirectiveReset)
+				}
 				errorValue := "nil"
 				if hasError {
 					errorValue = "herr"
+					if isGRPCReceiver(graph, signature.Recv().Type()) {
+						errorValue = "zeroGRPCError(herr)"
+					}
 				}
 				w.Import("github.com/alecthomas/zero")
+				if results.Len() == 3 {
+					w.L("if herr == nil {")
+					w.In(func(w *codewriter.Writer) {
+						w.L("w = zero.WithStatus(w, int(hstatus))")
+					})
+					w.L("}")
+				}
+				if responseType != nil && isPageType(responseType) {
+					if hasError {
+						w.L("if herr == nil {")
+						w.In(func(w *codewriter.Writer) {
+							w.L("out.Prepare(w, r)")
+						})
+						w.L("}")
+					} else {
+						w.L("out.Prepare(w, r)")
+					}
+				}
 				if responseType != nil {
 					ref := graph.TypeRef(responseType)
-					w.Import(ref.Import)
-					w.L(`encodeResponse(logger, r, w, encodeError, out, %s)`, errorValue)
+					w.Import(ref.Imports()...)
+					response := "out"
+					if isProtoMessage(responseType) {
+						response = "zeroProtoJSON{out}"
+					} else if encoder, ok := codecs.responseEncoder(responseType); ok {
+						response = fmt.Sprintf("zero.JSONResponse[%s]{Value: out, Encode: %s}", codecs.typeRef(w, responseType), encoder)
+					}
+					w.L(`encodeResponse(logger, r, w, encodeError, %s, %s)`, response, errorValue)
 				} else if hasError {
 					w.L(`encodeResponse(logger, r, w, encodeError, nil, %s)`, errorValue)
 				}
 			})
-			w.L("}))%s", closing)
-		}
-		w.L("return nil")
-	})
-	w.L("}")
+			if shared {
+				w.L("})%s", closing)
+				for _, pattern := range api.Patterns() {
+					w.L("mux.Handle(%q, a%dHandler)", pattern, ai)
+				}
+				for _, pattern := range api.AlternatePatterns() {
+					switch api.TrailingSlash {
+					case "redirect":
+						w.Import("github.com/alecthomas/zero")
+						w.L("mux.HandleFunc(%q, zero.RedirectTrailingSlash)", pattern)
+					case "ignore":
+						w.L("mux.Handle(%q, a%dHandler)", pattern, ai)
+					default:
+						w.L("mux.Handle(%q, http.NotFoundHandler())", pattern)
+					}
+				}
+			} else {
+				w.L("})%s)", closing)
+			}
+		}
+		writeCloudEventsIngress(w, graph, codecs, cloudEvents, hasServerConfig)
+		w.L("return nil")
+	})
+	w.L("}")
+}
 
-	w.L("")
+// writeRegisterSubscribers writes RegisterSubscribers, which subscribes all subscriptions to their topics.
+func writeRegisterSubscribers(w *codewriter.Writer, graph *depgraph.Graph) {
 	w.L("// RegisterSubscribers registers all Zero PubSub subscribers with their topics.")
 	w.L("func RegisterSubscribers(ctx context.Context, injector *Injector) error {")
 	w.In(func(w *codewriter.Writer) {
@@ -214,7 +762,7 @@ func Generate(out io.Writer, graph *depgraph.Graph, options ...Option) error {
 			for _, subscription := range graph.Subscriptions {
 				receiver := subscription.Function.Signature().Recv().Type()
 				key := graph.TypeRef(receiver)
-				w.Import(key.Import)
+				w.Import(key.Imports()...)
 				if _, ok := receivers[key]; !ok {
 					receivers[key] = receiverIndex
 					receiverIndex++
@@ -227,22 +775,94 @@ func Generate(out io.Writer, graph *depgraph.Graph, options ...Option) error {
 				writeZeroConstructSingletonByName(w, graph, fmt.Sprintf("r%d", index), ref.String(), ref.String())
 			}
 
+			// Wrap the subscribers with any job middleware, then track them so that in-flight events are drained on
+			// shutdown.
+			writeZeroConstructSingletonByName(w, graph, "subscriptions", subscriptionsType, "")
+			handlers := map[*depgraph.Subscription]string{}
+			for si, subscription := range graph.Subscriptions {
+				// Batch subscribers receive []pubsub.Event[T] and are tracked with TrackBatch.
+				param, paramType, track := "event", "%s", "Track"
+				if subscription.Batch != nil {
+					param, paramType, track = "events", "[]%s", "TrackBatch"
+				}
+				trackRef := graph.ParseTypeRef("github.com/alecthomas/zero/providers/pubsub." + track)
+				w.Import(trackRef.Imports()...)
+				name := subscription.Function.Name()
+				handler := fmt.Sprintf("r%d.%s", receivers[graph.TypeRef(subscription.Function.Signature().Recv().Type())], name)
+				inner := fmt.Sprintf("func(ctx context.Context) error { return %s(ctx, %s) }", handler, param)
+				if job := writeJobMiddleware(w, graph, subscription.Labels, fmt.Sprintf("s%d", si), inner); job != inner {
+					eventRef := graph.ParseTypeRef(fmt.Sprintf("github.com/alecthomas/zero/providers/pubsub.Event[%s]", graph.TypeRef(subscription.TopicType).Ref))
+					w.Import(eventRef.Imports()...)
+					handler = fmt.Sprintf("func(ctx context.Context, %s %s) error { return %s(ctx) }", param, fmt.Sprintf(paramType, eventRef.Ref), job)
+				}
+				if recv := subscription.Function.Signature().Recv(); recv != nil {
+					name = strings.TrimPrefix(types.TypeString(recv.Type(), func(pkg *types.Package) string { return pkg.Name() }), "*") + "." + name
+				}
+				handlers[subscription] = fmt.Sprintf("%s(subscriptions, %q, %s)", trackRef.Ref, name, handler)
+			}
+
 			// Register the subscribers with their topics
+			topics := map[string]bool{}
+			routed := map[string]bool{}
 			for _, subscription := range graph.Subscriptions {
-				ref := graph.TypeRef(subscription.Function.Signature().Recv().Type())
-				receiverIndex := receivers[ref]
 
 				// Get the topic type for this subscription
 				topicRef := graph.TypeRef(subscription.TopicType)
-				w.Import(topicRef.Import)
+				w.Import(topicRef.Imports()...)
+				topicVar := fmt.Sprintf("topic%s", hash(topicRef.Ref))
 
 				// Construct the topic
-				writeZeroConstructSingletonByName(w, graph, fmt.Sprintf("topic%s", hash(topicRef.Ref)), fmt.Sprintf("github.com/alecthomas/zero/providers/pubsub.Topic[%s]", topicRef.Ref), "")
+				if !topics[topicVar] {
+					topics[topicVar] = true
+					writeZeroConstructSingletonByName(w, graph, topicVar, fmt.Sprintf("github.com/alecthomas/zero/providers/pubsub.Topic[%s]", topicRef.Ref), "")
+				}
 
-				// Subscribe to the topic
-				w.L("if err := topic%s.Subscribe(ctx, r%d.%s); err != nil {", hash(topicRef.Ref), receiverIndex, subscription.Function.Name())
+				// Subscribe to the topic, in batches if the subscriber receives them
+				if subscription.Batch != nil {
+					subscribeRef := graph.ParseTypeRef("github.com/alecthomas/zero/providers/pubsub.SubscribeBatch")
+					optionsRef := graph.ParseTypeRef("github.com/alecthomas/zero/providers/pubsub.BatchOptions")
+					w.Import(subscribeRef.Imports()...)
+					options := fmt.Sprintf("Size: %d", *subscription.Batch)
+					if subscription.Window != 0 {
+						w.Import("time")
+						options += fmt.Sprintf(", Window: time.Duration(%d)", subscription.Window.Nanoseconds())
+					}
+					w.L("if err := %s(%s, %s, %s{%s}, %s); err != nil {", subscribeRef.Ref, subscribeContext(w, graph, subscription),
+						topicVar, optionsRef.Ref, options, handlers[subscription])
+					w.In(func(w *codewriter.Writer) {
+						w.L(`return fmt.Errorf("failed to subscribe to topic for %s: %%w", err)`, subscription.Function.Name())
+					})
+					w.L("}")
+					continue
+				}
+				if subscription.Version == nil {
+					w.L("if err := %s.Subscribe(%s, %s); err != nil {", topicVar, subscribeContext(w, graph, subscription), handlers[subscription])
+					w.In(func(w *codewriter.Writer) {
+						w.L(`return fmt.Errorf("failed to subscribe to topic for %s: %%w", err)`, subscription.Function.Name())
+					})
+					w.L("}")
+					continue
+				}
+
+				// All versioned subscriptions to a topic are routed by a single subscriber.
+				if routed[topicVar] {
+					continue
+				}
+				routed[topicVar] = true
+				routerRef := graph.ParseTypeRef(fmt.Sprintf("github.com/alecthomas/zero/providers/pubsub.VersionRouter[%s]", topicRef.Ref))
+				w.Import(routerRef.Imports()...)
+				versions := slices.DeleteFunc(slices.Clone(graph.Subscriptions), func(versioned *depgraph.Subscription) bool {
+					return versioned.Version == nil || !types.Identical(versioned.TopicType, subscription.TopicType)
+				})
+				w.L("if err := %s.Subscribe(%s, %s{", topicVar, subscribeContext(w, graph, versions...), routerRef.Ref)
 				w.In(func(w *codewriter.Writer) {
-					w.L(`return fmt.Errorf("failed to subscribe to topic for %s: %%w", err)`, subscription.Function.Name())
+					for _, versioned := range versions {
+						w.L("%d: %s,", *versioned.Version, handlers[versioned])
+					}
+				})
+				w.L("}.Handle); err != nil {")
+				w.In(func(w *codewriter.Writer) {
+					w.L(`return fmt.Errorf("failed to subscribe to versions of topic %s: %%w", err)`, topicRef.Ref)
 				})
 				w.L("}")
 			}
@@ -250,180 +870,504 @@ func Generate(out io.Writer, graph *depgraph.Graph, options ...Option) error {
 		}
 	})
 	w.L("}")
+}
+
+// writeEntrypoints writes the registration of workflows and workers, and the Run function, runtimes and CLI that start
+// the server container.
+func writeEntrypoints(w *codewriter.Writer, graph *depgraph.Graph, templates *parsedTemplates, file fileData, opts *generateOptions) error {
+	if len(graph.Steps) > 0 {
+		w.L("")
+		writeWorkflowRegistration(w, graph)
+	}
+
+	if len(graph.Workers) > 0 {
+		w.L("")
+		writeWorkerRegistration(w, graph)
+	}
 
 	w.Import("net/http")
+	if templates.run != nil {
+		run, err := render(templates.run, file)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		w.L("%s", run)
+	} else {
+		writeRun(w, graph, opts)
+	}
+	w.L("")
+	for _, runtime := range opts.runtimes {
+		if err := writeRuntime(w, graph, runtime, opts); err != nil {
+			return errors.WithStack(err)
+		}
+		w.L("")
+	}
+	if opts.cli {
+		writeCLI(w, graph, opts.openAPI)
+		w.L("")
+	}
+	return nil
+}
+
+// isPageType returns true if t is zero.Page[T] or a pointer to it.
+func isPageType(t types.Type) bool {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Origin().Obj()
+	return obj.Name() == "Page" && obj.Pkg() != nil && obj.Pkg().Path() == "github.com/alecthomas/zero"
+}
+
+// writeMiddleware wraps handler with the middleware applied to api, returning the wrapped handler and the parentheses
+// closing it.
+//
+// The parameters of middleware factories are named with prefix, as the same middleware may be applied to multiple APIs.
+func writeMiddleware(w *codewriter.Writer, graph *depgraph.Graph, codecs *jsonCodecs, middleware []*depgraph.Middleware, api *depgraph.API, prefix, handler string) (string, string) {
+	closing := ""
+	for mi, middleware := range middleware {
+		if !middleware.Match(api) {
+			continue
+		}
+		ref := graph.FunctionRef(middleware.Function)
+		w.Import(ref.Imports()...)
+		if middleware.Factory {
+			args := []string{}
+			params := middleware.Function.Signature().Params()
+			w.L("// Parameters for the %s middleware", ref.Ref)
+			prefix := fmt.Sprintf("%sm%dp", prefix, mi)
+			for i := range params.Len() {
+				args = append(args, fmt.Sprintf("%s%d", prefix, i))
+				paramType := params.At(i).Type()
+				paramName := params.At(i).Name()
+				if depgraph.IsRedactedFieldsType(paramType) {
+					w.Import("github.com/alecthomas/zero")
+					quoted := make([]string, len(api.RedactedFields))
+					for j, field := range api.RedactedFields {
+						quoted[j] = strconv.Quote(field)
+					}
+					w.L("%s%d := zero.RedactedFields{%s}", prefix, i, strings.Join(quoted, ", "))
+					continue
+				}
+				writeParameterConstruction(w, graph, codecs, paramType, api.Label(paramName), prefix, i, true, "")
+			}
+			handler = fmt.Sprintf("%s(%s)(%s", ref.Ref, strings.Join(args, ", "), handler)
+		} else {
+			handler = fmt.Sprintf("%s(%s", ref.Ref, handler)
+		}
+		closing += ")"
+	}
+	return handler, closing
+}
+
+// serverConfigType is the config containing the default request limits.
+const serverConfigType = "github.com/alecthomas/zero/providers/http.Config"
+
+// requestLimits returns the timeout and maximum body size expressions for an API, from its timeout=<duration> and
+// maxbody=<size> labels, falling back to the defaults in the server config.
+func requestLimits(w *codewriter.Writer, api *depgraph.API, hasServerConfig bool) (timeout, maxBody string) {
+	timeout, maxBody = "0", "0"
+	if hasServerConfig {
+		timeout, maxBody = "serverConfig.RequestTimeout", "serverConfig.MaxBodySize"
+	}
+	// Labels are validated during analysis.
+	if value, _ := api.Pattern.Timeout(); value > 0 {
+		w.Import("time")
+		timeout = fmt.Sprintf("time.Duration(%d)", value)
+	}
+	if value, _ := api.Pattern.MaxBody(); value > 0 {
+		maxBody = fmt.Sprintf("%d", value)
+	}
+	return timeout, maxBody
+}
+
+// contentEncodingsType is the type of the content codings used to compress responses.
+const contentEncodingsType = "github.com/alecthomas/zero.ContentEncodings"
+
+// compressMinSize returns the expression for the minimum size of compressed responses of an API, from its
+// compress=<size> label, falling back to the default in the server config.
+func compressMinSize(api *depgraph.API, hasServerConfig bool) string {
+	// Labels are validated during analysis.
+	if label, _ := api.Pattern.Label("compress"); label != "" {
+		value, _ := api.Pattern.Compress()
+		return fmt.Sprintf("%d", value)
+	}
+	if hasServerConfig {
+		return "serverConfig.CompressMinSize"
+	}
+	return "0"
+}
+
+// writeRun writes the builtin Run function.
+func writeRun(w *codewriter.Writer, graph *depgraph.Graph, opts *generateOptions) {
 	w.L("// Run the Zero server container.")
 	w.L("//")
 	w.L("// This registers all request handlers, cron jobs, PubSub subscribers, etc.")
 	w.L("func Run(ctx context.Context, config ZeroConfig) error {")
 	w.In(func(w *codewriter.Writer) {
-		w.L("injector := NewInjector(ctx, config)")
-		w.Import("net/http")
-		w.L("if err := RegisterHandlers(ctx, injector); err != nil {")
+		writeServe(w, graph, opts, true, serveHTTP("server.ListenAndServe()"))
+	})
+	w.L("}")
+}
+
+// serveHTTP returns a serving step for writeServe that serves HTTP with the serve expression.
+func serveHTTP(serve string) func(w *codewriter.Writer) {
+	return func(w *codewriter.Writer) {
+		w.L(`logger.Info("Server starting", "bind", server.Addr)`)
+		w.L("wg.Go(func() error { return %s })", serve)
+		w.L("return wg.Wait()")
+	}
+}
+
+// writeServe writes the body of a function running the server container.
+//
+// All roots are constructed and registered before the serve step writes the code serving requests and returning from
+// the function, with "server", "logger", "ctx" and the errgroup "wg" in scope.
+//
+// With parallel construction, independent providers are constructed concurrently before the roots are.
+//
+// Cron jobs, PubSub subscribers and queue workers are only run with background, which is false for runtimes serving
+// a single request per process.
+func writeServe(w *codewriter.Writer, graph *depgraph.Graph, opts *generateOptions, background bool, serve func(w *codewriter.Writer)) {
+	w.L("injector := NewInjector(ctx, config)")
+	// Report invalid config once, rather than as the failure of every root.
+	if hasValidators(graph) {
+		w.L("if injector.invalid != nil {")
 		w.In(func(w *codewriter.Writer) {
-			w.L(`return fmt.Errorf("failed to register handlers: %%w", err)`)
+			w.L("return injector.invalid")
 		})
 		w.L("}")
-		w.L("if err := RegisterSubscribers(ctx, injector); err != nil {")
+	}
+	w.Import("net/http")
+	if opts.parallel {
+		writeParallelConstruction(w, graph, opts.trace)
+	}
+	writeRootConstruction(w, graph)
+	// Workflows are registered first, so that handlers and subscribers can start them.
+	if len(graph.Steps) > 0 {
+		w.L("if err := RegisterWorkflows(ctx, injector); err != nil {")
 		w.In(func(w *codewriter.Writer) {
-			w.L(`return fmt.Errorf("failed to register subscribers: %%w", err)`)
+			w.L(`return fmt.Errorf("failed to register workflows: %%w", err)`)
 		})
 		w.L("}")
-		writeZeroConstructSingletonByName(w, graph, "server", "*net/http.Server", "")
-
-		if len(graph.CronJobs) > 0 {
-			writeZeroConstructSingletonByName(w, graph, "cron", "*github.com/alecthomas/zero/providers/cron.Scheduler", "")
-			writeCronJobRegistration(w, graph)
-		}
-
-		w.Import("golang.org/x/sync/errgroup")
-		w.L("wg, ctx := errgroup.WithContext(ctx)")
-		writeZeroConstructSingletonByName(w, graph, "logger", "*log/slog.Logger", "")
-		w.L(`logger.Info("Server starting", "bind", server.Addr)`)
-		w.L("wg.Go(func() error { return server.ListenAndServe() })")
-		w.L("return wg.Wait()")
-	})
-	w.L("}")
-	w.L("")
-
-	w.L("// Construct an instance of T.")
-	w.L("func ZeroConstruct[T any](ctx context.Context, config ZeroConfig) (out T, err error) {")
+	}
+	w.L("if err := RegisterHandlers(ctx, injector); err != nil {")
 	w.In(func(w *codewriter.Writer) {
-		w.Import("reflect")
-		w.L("injector := NewInjector(ctx, config)")
-		w.L("return ZeroConstructSingletons[T](ctx, injector)")
+		w.L(`return fmt.Errorf("failed to register handlers: %%w", err)`)
 	})
 	w.L("}")
-	w.L("")
-	w.L("// ZeroConstructSingletons constructs a new instance of T, or returns an instance of T from the injector if already constructed.")
-	w.L("func ZeroConstructSingletons[T any](ctx context.Context, injector *Injector) (out T, err error) {")
-	w.In(func(w *codewriter.Writer) {
-		w.L("if singleton, ok := injector.singletons[reflect.TypeFor[T]()]; ok {")
+	if background {
+		w.L("if err := RegisterSubscribers(ctx, injector); err != nil {")
 		w.In(func(w *codewriter.Writer) {
-			w.L("return singleton.(T), nil")
+			w.L(`return fmt.Errorf("failed to register subscribers: %%w", err)`)
 		})
 		w.L("}")
-		w.L("defer func() { injector.singletons[reflect.TypeFor[T]()] = out }()")
-		w.Import("reflect")
-		w.L("switch reflect.TypeOf((*T)(nil)).Elem() {")
-		w.L("case reflect.TypeOf((*context.Context)(nil)).Elem():")
+	}
+	if background && len(graph.Workers) > 0 {
+		w.L("if err := RegisterWorkers(ctx, injector); err != nil {")
 		w.In(func(w *codewriter.Writer) {
-			w.L("return any(ctx).(T), nil")
+			w.L(`return fmt.Errorf("failed to register workers: %%w", err)`)
 		})
-		w.W("\n")
+		w.L("}")
+	}
+	writeZeroConstructSingletonByName(w, graph, "server", "*net/http.Server", "")
 
-		for key, config := range stableMapIter(graph.Configs) {
-			alias := "Config" + hash(key)
-			ref := graph.TypeRef(config.Type)
-			w.Import(ref.Import)
-			w.L("case reflect.TypeOf((**%s)(nil)).Elem(): // Handle pointer to config.", ref.Ref)
-			w.In(func(w *codewriter.Writer) {
-				w.L("return any(&injector.config.%s).(T), nil", alias)
-			})
-			w.W("\n")
-			w.L("case reflect.TypeOf((*%s)(nil)).Elem():", ref.Ref)
-			w.In(func(w *codewriter.Writer) {
-				w.L("return any(injector.config.%s).(T), nil", alias)
-			})
-			w.W("\n")
+	if background && len(graph.CronJobs) > 0 {
+		writeZeroConstructSingletonByName(w, graph, "cron", "*github.com/alecthomas/zero/providers/cron.Scheduler", "")
+		writeCronJobRegistration(w, graph)
+	}
+
+	if _, ok := graph.Providers[readinessType]; ok {
+		writeZeroConstructSingletonByName(w, graph, "readiness", readinessType, "")
+		w.L("readiness.SetReady(true)")
+	}
+
+	w.Import("golang.org/x/sync/errgroup")
+	w.L("wg, ctx := errgroup.WithContext(ctx)")
+	if background && len(graph.Subscriptions) > 0 {
+		writeZeroConstructSingletonByName(w, graph, "subscriptions", subscriptionsType, "")
+		w.L("wg.Go(func() error { return subscriptions.Run(ctx) })")
+	}
+	writeZeroConstructSingletonByName(w, graph, "logger", "*log/slog.Logger", "")
+	if len(graph.GRPCServices) > 0 {
+		writeGRPCServe(w, graph)
+	}
+	serve(w)
+}
+
+// cloudEventsAPIs returns the API of the CloudEvents endpoint of each topic, keyed by topic name, with labels selecting
+// its middleware and request limits.
+func cloudEventsAPIs(graph *depgraph.Graph, labels string) (map[string]*depgraph.API, error) {
+	apis := map[string]*depgraph.API{}
+	for name := range graph.Topics() {
+		directive, err := directiveparser.Parse(strings.TrimSpace("zero:api POST /events/" + name + " " + labels))
+		if err != nil {
+			return nil, errors.Errorf("invalid CloudEvents labels %q: %w", labels, err)
 		}
+		apis[name] = &depgraph.API{Pattern: directive.(*directiveparser.DirectiveAPI)} //nolint:forcetypeassert
+	}
+	return apis, nil
+}
 
-		for _, providers := range stableMapIter(graph.Providers) {
-			if len(providers) == 0 {
-				continue
-			}
+// writeCloudEventsIngress writes the registration of the CloudEvents endpoint of each topic.
+func writeCloudEventsIngress(w *codewriter.Writer, graph *depgraph.Graph, codecs *jsonCodecs, apis map[string]*depgraph.API, hasServerConfig bool) {
+	if len(apis) == 0 {
+		return
+	}
+	// Middleware must also be applied to an API, as the dependencies of other middleware aren't constructed.
+	middleware := slices.DeleteFunc(slices.Clone(graph.Middleware), func(m *depgraph.Middleware) bool {
+		return !slices.ContainsFunc(graph.APIs, m.Match)
+	})
+	topics := graph.Topics()
+	handlerRef := graph.ParseTypeRef("github.com/alecthomas/zero/providers/pubsub/cloudevents.Handler")
+	w.Import(handlerRef.Imports()...)
+	for i, name := range slices.Sorted(maps.Keys(apis)) {
+		api := apis[name]
+		topicRef := graph.TypeRef(topics[name])
+		w.Import(topicRef.Imports()...)
+		topicVar := fmt.Sprintf("topic%s", hash(topicRef.Ref))
+		// Publish through the validated topic if there is one, so that its schema is enforced.
+		topicType := fmt.Sprintf("github.com/alecthomas/zero/providers/pubsub.Topic[%s]", topicRef.Ref)
+		if _, ok := graph.Providers[fmt.Sprintf("*github.com/alecthomas/zero/providers/pubsub.ValidatedTopic[%s]", types.TypeString(topics[name], nil))]; ok {
+			topicType = fmt.Sprintf("*github.com/alecthomas/zero/providers/pubsub.ValidatedTopic[%s]", topicRef.Ref)
+		}
+		writeZeroConstructSingletonByName(w, graph, topicVar, topicType, "")
+		handler, closing := writeMiddleware(w, graph, codecs, middleware, api, fmt.Sprintf("e%d", i), fmt.Sprintf("%s[%s](logger, encodeError, %s)", handlerRef.Ref, topicRef.Ref, topicVar))
+		if timeout, maxBody := requestLimits(w, api, hasServerConfig); timeout != "0" || maxBody != "0" {
+			w.Import("github.com/alecthomas/zero")
+			handler = fmt.Sprintf("zero.RequestLimits(logger, encodeError, %s, %s)(%s", timeout, maxBody, handler)
+			closing += ")"
+		}
+		w.L("mux.Handle(%q, %s%s)", api.Pattern.Pattern(), handler, closing)
+	}
+}
 
-			// Skip base generic providers - only generate code for concrete types
-			if len(providers) > 0 && providers[0].IsGeneric {
-				// Check if this is a base generic provider (stored for lookup only)
-				firstProviderType := types.TypeString(providers[0].Provides, nil)
-				if strings.Contains(firstProviderType, "[T]") || strings.Contains(firstProviderType, "[T ") {
-					continue // Skip base generic providers
-				}
-			}
+// subscriptionsType tracks subscribers so that in-flight events are drained on shutdown.
+const subscriptionsType = "*github.com/alecthomas/zero/providers/pubsub.Subscriptions"
 
-			// For single providers, generate direct case
-			if len(providers) == 1 {
-				provider := providers[0]
-				ref := graph.TypeRef(provider.Provides)
-				w.Import(ref.Import)
-				w.L("case reflect.TypeOf((*%s)(nil)).Elem():", ref.Ref)
-				w.In(func(w *codewriter.Writer) {
-					writeProviderCall(w, graph, provider, "p", "o")
-					w.L("return any(o).(T), nil")
-				})
-				w.W("\n")
-				continue
-			}
-			// For multi-providers, handle as before
-			ref := graph.TypeRef(providers[0].Provides)
-			w.Import(ref.Import)
-			w.L("case reflect.TypeOf((*%s)(nil)).Elem():", ref.Ref)
-			w.In(func(w *codewriter.Writer) {
-				// Construct all provider results
-				for pi, provider := range providers {
-					writeProviderCall(w, graph, provider, fmt.Sprintf("p%d_", pi), fmt.Sprintf("r%d", pi))
-				}
+// loggerType is used to trace construction of providers.
+const loggerType = "*log/slog.Logger"
 
-				// Determine if it's a map or slice and merge accordingly
-				providedType := providers[0].Provides.Underlying()
-				switch t := providedType.(type) {
-				case *types.Map:
-					w.Import("maps")
-					// Map merging
-					w.L("result := make(%s)", ref.Ref)
-					for pi := range providers {
-						w.L("maps.Copy(result, r%d)", pi)
+// readinessType is flagged as ready once the server container has started.
+const readinessType = "*github.com/alecthomas/zero.Readiness"
+
+// constructedRoots returns the types of the roots of the graph that are constructed before anything is served.
+func constructedRoots(graph *depgraph.Graph) []types.Type {
+	var roots []types.Type
+	for _, root := range graph.Roots {
+		if providers := graph.Providers[root]; len(providers) > 0 && types.TypeString(providers[0].Provides, nil) == root {
+			roots = append(roots, providers[0].Provides)
+		}
+	}
+	return roots
+}
+
+// writeRootConstruction writes code constructing the roots of the graph before anything is served, so that
+// infrastructure such as databases, migrations, leases and topics is ready up front. Errors from all roots are
+// reported together.
+func writeRootConstruction(w *codewriter.Writer, graph *depgraph.Graph) {
+	roots := constructedRoots(graph)
+	if len(roots) == 0 {
+		return
+	}
+	w.Import("errors")
+	w.Import("fmt")
+	w.L("var errs []error")
+	for _, root := range roots {
+		ref := graph.TypeRef(root)
+		w.Import(ref.Imports()...)
+		// Construction errors are already prefixed with the root type.
+		w.L("if _, err := ZeroConstructSingletons[%s](ctx, injector); err != nil {", ref.Ref)
+		w.In(func(w *codewriter.Writer) {
+			w.L(`errs = append(errs, err)`)
+		})
+		w.L("}")
+	}
+	w.L("if err := errors.Join(errs...); err != nil {")
+	w.In(func(w *codewriter.Writer) {
+		w.L(`return fmt.Errorf("failed to construct service: %%w", err)`)
+	})
+	w.L("}")
+}
+
+// constructionLevels returns the provided types reachable from roots, grouped into levels that only depend on types in
+// earlier levels, so that each level can be constructed concurrently. Types are sorted within each level.
+//
+// Configs and the context are not included, as they are cheap to construct, and zero.Lazy dependencies are not
+// included as they are constructed on first use.
+func constructionLevels(graph *depgraph.Graph, roots []types.Type) [][]types.Type {
+	levels := map[string]int{}
+	provided := map[string]types.Type{}
+	var visit func(typ types.Type) int
+	visit = func(typ types.Type) int {
+		key := types.TypeString(typ, nil)
+		if level, ok := levels[key]; ok {
+			return level
+		}
+		var deps []types.Type
+		if providers := graph.Providers[key]; len(providers) > 0 {
+			for _, provider := range providers {
+				for _, require := range provider.Requires {
+					if _, ok := depgraph.LazyType(require); ok {
+						continue
 					}
-				case *types.Slice:
-					// Slice appending
-					w.L("var result %s", ref.Ref)
-					for pi := range providers {
-						w.L("result = append(result, r%d...)", pi)
+					if elem, ok := depgraph.OptionalType(require); ok {
+						require = elem
 					}
-				default:
-					_ = t
-					w.L(`return out, fmt.Errorf("multi-provider type %s must be a map or slice", "%s")`, ref.Ref)
+					deps = append(deps, require)
 				}
-				w.L("return any(result).(T), nil")
-			})
-			w.W("\n")
+			}
+		} else if group := graph.Groups[key]; group != nil {
+			for _, member := range group.Members {
+				deps = append(deps, member.Provides)
+			}
+		} else {
+			return -1
 		}
+		level := 0
+		for _, dep := range deps {
+			level = max(level, visit(dep)+1)
+		}
+		levels[key] = level
+		provided[key] = typ
+		return level
+	}
+	for _, root := range roots {
+		visit(root)
+	}
+	var out [][]types.Type
+	for _, key := range slices.Sorted(maps.Keys(levels)) {
+		level := levels[key]
+		if level < 0 {
+			continue
+		}
+		for len(out) <= level {
+			out = append(out, nil)
+		}
+		out[level] = append(out[level], provided[key])
+	}
+	return out
+}
 
-		w.W("\n")
-
+// writeParallelConstruction writes code constructing the dependencies of the roots concurrently, one level at a time.
+//
+// Errors are not reported here, but are cached by the injector and reported by the subsequent root construction, so
+// that they are reported in the same order as when constructing sequentially.
+//
+// When tracing, the logger is constructed first, as tracing every other provider uses it.
+func writeParallelConstruction(w *codewriter.Writer, graph *depgraph.Graph, trace bool) {
+	levels := constructionLevels(graph, constructedRoots(graph))
+	if len(levels) == 0 {
+		return
+	}
+	if _, ok := graph.Providers[loggerType]; ok && trace {
+		writeZeroConstructSingletonByName(w, graph, "_", loggerType, "failed to construct service")
+	}
+	w.Import("golang.org/x/sync/errgroup")
+	w.L("// Construct independent providers concurrently, in levels ordered by their dependencies.")
+	w.L("for _, level := range [][]func() error{")
+	w.In(func(w *codewriter.Writer) {
+		for _, level := range levels {
+			w.L("{")
+			w.In(func(w *codewriter.Writer) {
+				for _, typ := range level {
+					ref := graph.TypeRef(typ)
+					w.Import(ref.Imports()...)
+					w.L("func() error { _, err := ZeroConstructSingletons[%s](ctx, injector); return err },", ref.Ref)
+				}
+			})
+			w.L("},")
+		}
+	})
+	w.L("} {")
+	w.In(func(w *codewriter.Writer) {
+		w.L("var wg errgroup.Group")
+		w.L("for _, construct := range level {")
+		w.In(func(w *codewriter.Writer) {
+			w.L("wg.Go(construct)")
+		})
+		w.L("}")
+		w.L("if wg.Wait() != nil {")
+		w.In(func(w *codewriter.Writer) {
+			w.L("break")
+		})
 		w.L("}")
-		w.Import("fmt")
-		w.L(`return out, fmt.Errorf("don't know how to construct %%s", reflect.TypeFor[T]())`)
 	})
 	w.L("}")
-	_, err := out.Write(w.Bytes())
-	if err != nil {
-		return errors.Errorf("failed to write file: %w", err)
+}
+
+// writeContextValue generates code to retrieve a //zero:contextkey value set by middleware from the request context.
+//
+// A missing value is a server error, as the analyser has already checked that middleware setting it is applied.
+func writeContextValue(w *codewriter.Writer, graph *depgraph.Graph, key *depgraph.ContextKey, varName string) {
+	ref := graph.TypeRef(key.Type)
+	w.Import(ref.Imports()...)
+	w.Import("github.com/alecthomas/zero")
+	w.L("%s, ok := zero.ContextValue[%s](r.Context())", varName, ref.Ref)
+	w.L("if !ok {")
+	typeName := types.TypeString(key.Type, func(pkg *types.Package) string { return pkg.Name() })
+	w.In(func(w *codewriter.Writer) {
+		w.L(`encodeError(logger, w, "%s was not set in the request context by %s middleware", http.StatusInternalServerError)`, typeName, key.Label)
+		w.L("return")
+	})
+	w.L("}")
+}
+
+// writeHeaderParameter generates code to decode a parameter bound to a request header or cookie.
+func writeHeaderParameter(w *codewriter.Writer, graph *depgraph.Graph, header *depgraph.HeaderParameter, paramType types.Type, varName string) {
+	ref := graph.TypeRef(paramType)
+	w.Import(ref.Imports()...)
+	w.Import("github.com/alecthomas/zero")
+	decode := "DecodeHeader"
+	if header.In == "cookie" {
+		decode = "DecodeCookie"
 	}
-	return nil
+	w.L("%s, err := zero.%s[%s](r, %q)", varName, decode, ref.Ref, header.Name)
+	w.L("if err != nil {")
+	w.In(func(w *codewriter.Writer) {
+		w.L(`encodeError(logger, w, err.Error(), http.StatusBadRequest)`)
+		w.L("return")
+	})
+	w.L("}")
+}
+
+// writeParamsStruct generates code to decode a params struct from the path wildcards and query parameters of a request.
+func writeParamsStruct(w *codewriter.Writer, graph *depgraph.Graph, paramType types.Type, varName string) {
+	ref := graph.TypeRef(paramType)
+	w.Import(ref.Imports()...)
+	w.Import("github.com/alecthomas/zero")
+	w.L("%s, err := zero.DecodeParams[%s](r)", varName, ref.Ref)
+	w.L("if err != nil {")
+	w.In(func(w *codewriter.Writer) {
+		w.L(`encodeError(logger, w, err.Error(), http.StatusBadRequest)`)
+		w.L("return")
+	})
+	w.L("}")
 }
 
 // writeParameterConstruction generates code to construct a parameter of the given type.
 // Returns the variable name that holds the constructed parameter.
-func writeParameterConstruction(w *codewriter.Writer, graph *depgraph.Graph, paramType types.Type, paramName string, varPrefix string, index int, isMiddleware bool, httpMethod string) {
+func writeParameterConstruction(w *codewriter.Writer, graph *depgraph.Graph, codecs *jsonCodecs, paramType types.Type, paramName string, varPrefix string, index int, isMiddleware bool, httpMethod string) {
 	ref := graph.TypeRef(paramType)
-	w.Import(ref.Import)
+	w.Import(ref.Imports()...)
 	typeName := types.TypeString(paramType, nil)
 	varName := fmt.Sprintf("%s%d", varPrefix, index)
 
 	switch typeName {
 	case "int":
+		w.Import("strconv")
 		if isMiddleware {
-			w.L(`%s, err := strconv.Itoa(%q)`, varName, paramName) // For middleware, paramName is the label value
+			w.L(`%s, err := strconv.Atoi(%q)`, varName, paramName) // For middleware, paramName is the label value
 		} else {
 			w.L(`%s, err := strconv.Atoi(r.PathValue("%s"))`, varName, paramName)
 		}
 		w.L("if err != nil {")
 		w.In(func(w *codewriter.Writer) {
 			if isMiddleware {
-				w.Import("fmt")
-				w.L(`return out, err`)
+				w.L(`return err`)
 			} else {
 				w.L(`encodeError(logger, w, fmt.Sprintf("path parameter %s must be a valid integer: %%s", err), http.StatusBadRequest)`, paramName)
 				w.L("return")
@@ -438,18 +1382,48 @@ func writeParameterConstruction(w *codewriter.Writer, graph *depgraph.Graph, par
 		}
 	case "*net/http.Request", "net/http.ResponseWriter", "context.Context":
 		// These are handled specially in the call site, no construction needed
+	case "github.com/alecthomas/zero.PageRequest":
+		w.Import("github.com/alecthomas/zero")
+		w.L(`%s, err := zero.DecodePageRequest(r)`, varName)
+		w.L("if err != nil {")
+		w.In(func(w *codewriter.Writer) {
+			w.L(`encodeError(logger, w, err.Error(), http.StatusBadRequest)`)
+			w.L("return")
+		})
+		w.L("}")
 	default:
 		if isMiddleware {
 			w.L("%s, err := ZeroConstructSingletons[%s](ctx, injector)", varName, ref.Ref)
 			w.L("if err != nil {")
 			w.In(func(w *codewriter.Writer) {
-				w.Import("fmt")
-				w.L(`return out, err`)
+				w.L(`return err`)
+			})
+			w.L("}")
+		} else if ptr, ok := paramType.(*types.Pointer); ok && isProtoMessage(paramType) && hasRequestBody(httpMethod) {
+			elem := graph.TypeRef(ptr.Elem())
+			w.L("%s := &%s{}", varName, elem.Ref)
+			w.L("if err := zeroDecodeProtoJSON(r, %s); err != nil {", varName)
+			w.In(func(w *codewriter.Writer) {
+				w.L(`encodeError(logger, w, fmt.Sprintf("invalid request: %%s", err), http.StatusBadRequest)`)
+				w.L("return")
+			})
+			w.L("}")
+		} else if decoder, ok := codecs.requestDecoder(paramType, httpMethod); ok {
+			w.Import("github.com/alecthomas/zero")
+			w.L(`%s, err := zero.DecodeJSONRequest(r, %s)`, varName, decoder)
+			w.L("if err != nil {")
+			w.In(func(w *codewriter.Writer) {
+				w.L(`encodeError(logger, w, fmt.Sprintf("invalid request: %%s", err), http.StatusBadRequest)`)
+				w.L("return")
 			})
 			w.L("}")
 		} else {
 			w.Import("github.com/alecthomas/zero")
-			w.L(`%s, err := zero.DecodeRequest[%s]("%s", r)`, varName, ref.Ref, httpMethod)
+			method := strconv.Quote(httpMethod)
+			if httpMethod == "" {
+				method = "r.Method"
+			}
+			w.L(`%s, err := zero.DecodeRequest[%s](%s, r)`, varName, ref.Ref, method)
 			w.L("if err != nil {")
 			w.In(func(w *codewriter.Writer) {
 				w.L(`encodeError(logger, w, fmt.Sprintf("invalid request: %%s", err), http.StatusBadRequest)`)
@@ -470,23 +1444,75 @@ func writeParameterCall(w *codewriter.Writer, paramType types.Type, varPrefix st
 		w.W("r")
 	case "net/http.ResponseWriter":
 		w.W("w")
+	case "io.Reader":
+		// The body is limited by zero.RequestLimits if the API has a maximum body size.
+		w.W("r.Body")
 	default:
 		w.W("%s%d", varPrefix, index)
 	}
 }
 
+// iterSeqArgs returns the type arguments of an iter.Seq or iter.Seq2, or nil if t is neither.
+func iterSeqArgs(t types.Type) []types.Type {
+	named, ok := types.Unalias(t).(*types.Named)
+	if !ok || named.Obj().Pkg() == nil || named.Obj().Pkg().Path() != "iter" {
+		return nil
+	}
+	if name := named.Obj().Name(); name != "Seq" && name != "Seq2" {
+		return nil
+	}
+	args := make([]types.Type, named.TypeArgs().Len())
+	for i := range args {
+		args[i] = named.TypeArgs().At(i)
+	}
+	return args
+}
+
+// writeIterSeqConcat writes code concatenating the iterators r0..rN of multi-providers into "result", so that
+// streaming providers can contribute to a single sequence like slices do.
+func writeIterSeqConcat(w *codewriter.Writer, graph *depgraph.Graph, ref depgraph.Ref, args []types.Type, count int) {
+	params := make([]string, len(args))
+	vars := []string{"v", "k, v"}[len(args)-1]
+	for i, arg := range args {
+		argRef := graph.TypeRef(arg)
+		w.Import(argRef.Imports()...)
+		params[i] = argRef.Ref
+	}
+	seqs := make([]string, count)
+	for i := range seqs {
+		seqs[i] = fmt.Sprintf("r%d", i)
+	}
+	w.L("var result %s = func(yield func(%s) bool) {", ref.Ref, strings.Join(params, ", "))
+	w.In(func(w *codewriter.Writer) {
+		w.L("for _, seq := range []%s{%s} {", ref.Ref, strings.Join(seqs, ", "))
+		w.In(func(w *codewriter.Writer) {
+			w.L("for %s := range seq {", vars)
+			w.In(func(w *codewriter.Writer) {
+				w.L("if !yield(%s) {", vars)
+				w.In(func(w *codewriter.Writer) {
+					w.L("return")
+				})
+				w.L("}")
+			})
+			w.L("}")
+		})
+		w.L("}")
+	})
+	w.L("}")
+}
+
 // writeZeroConstructSingleton writes code to construct a dependency using ZeroConstructSingletons.
-func writeZeroConstructSingleton(w *codewriter.Writer, graph *depgraph.Graph, varName string, depType types.Type, errorWrapper string) {
+//
+// A non-empty errorPrefix is prepended to errors, eg. "*Service -> ".
+func writeZeroConstructSingleton(w *codewriter.Writer, graph *depgraph.Graph, varName string, depType types.Type, errorPrefix string) {
 	ref := graph.TypeRef(depType)
-	if ref.Import != "" {
-		w.Import(ref.Import)
-	}
+	w.Import(ref.Imports()...)
 	w.L("%s, err := ZeroConstructSingletons[%s](ctx, injector)", varName, ref.Ref)
 	w.L("if err != nil {")
 	w.In(func(w *codewriter.Writer) {
-		if errorWrapper != "" {
+		if errorPrefix != "" {
 			w.Import("fmt")
-			w.L(`return out, fmt.Errorf("%s: %%w", err)`, errorWrapper)
+			w.L(`return out, fmt.Errorf("%s%%w", err)`, errorPrefix)
 		} else {
 			w.L(`return out, err`)
 		}
@@ -494,14 +1520,56 @@ func writeZeroConstructSingleton(w *codewriter.Writer, graph *depgraph.Graph, va
 	w.L("}")
 }
 
+// writeOptionalConstruction writes code to construct a zero.Optional[T], which is empty if T is not provided.
+func writeOptionalConstruction(w *codewriter.Writer, graph *depgraph.Graph, varName string, elem types.Type, errorPrefix string) {
+	w.Import("github.com/alecthomas/zero")
+	if !graph.IsProvided(elem) {
+		ref := graph.TypeRef(elem)
+		w.Import(ref.Imports()...)
+		w.L("%s := zero.None[%s]()", varName, ref.Ref)
+		return
+	}
+	writeZeroConstructSingleton(w, graph, varName+"v", elem, errorPrefix)
+	w.L("%s := zero.Some(%sv)", varName, varName)
+}
+
+// writeLazyConstruction writes code to construct a zero.Lazy[T], which constructs T on first use.
+func writeLazyConstruction(w *codewriter.Writer, graph *depgraph.Graph, varName string, elem types.Type) {
+	w.Import("github.com/alecthomas/zero")
+	ref := graph.TypeRef(elem)
+	w.Import(ref.Imports()...)
+	w.L("%s := zero.NewLazy(func() (%s, error) {", varName, ref.Ref)
+	w.In(func(w *codewriter.Writer) {
+		w.L("return ZeroConstructSingletons[%s](ctx, injector)", ref.Ref)
+	})
+	w.L("})")
+}
+
+// concurrentConstruction returns true if providers may be constructed concurrently, either in parallel by Run, or by
+// zero.Lazy dependencies, which may be got from any goroutine.
+func concurrentConstruction(graph *depgraph.Graph, opts *generateOptions) bool {
+	if opts.parallel {
+		return true
+	}
+	for _, providers := range graph.Providers {
+		for _, provider := range providers {
+			if slices.ContainsFunc(provider.Requires, func(require types.Type) bool {
+				_, ok := depgraph.LazyType(require)
+				return ok
+			}) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // writeZeroConstructSingletonByName writes code to construct a dependency using ZeroConstructSingletons by fully-qualified type reference.
 //
 // It also adds imports for the specified type.
 func writeZeroConstructSingletonByName(w *codewriter.Writer, g *depgraph.Graph, varName string, typeRef string, errorWrapper string) {
 	ref := g.ParseTypeRef(typeRef)
-	if ref.Import != "" {
-		w.Import(ref.Import)
-	}
+	w.Import(ref.Imports()...)
 	w.L("%s, err := ZeroConstructSingletons[%s](ctx, injector)", varName, ref.Ref)
 	w.L("if err != nil {")
 	w.In(func(w *codewriter.Writer) {
@@ -515,24 +1583,94 @@ func writeZeroConstructSingletonByName(w *codewriter.Writer, g *depgraph.Graph,
 	w.L("}")
 }
 
+// writeDocComment writes the doc comment of a provider or API above the code generated for it.
+func writeDocComment(w *codewriter.Writer, doc string) {
+	if doc == "" {
+		return
+	}
+	for line := range strings.SplitSeq(doc, "\n") {
+		w.L("%s", strings.TrimRight("// "+line, " "))
+	}
+}
+
+// lineDirectiveReset is replaced by resolveLineDirectives with a //line directive restoring the position of the
+// following line in zero.go.
+const lineDirectiveReset = "//line zero.go:0"
+
+// writeLineDirective maps the next line to the position of an annotated declaration with a //line directive, so that
+// stack traces and debuggers report the declaration rather than zero.go. The mapping must be ended by writing
+// lineDirectiveReset.
+//
+// Returns false if the declaration has no position relative to the destination directory.
+func writeLineDirective(w *codewriter.Writer, graph *depgraph.Graph, position token.Position) bool {
+	if graph.DestDir == "" || !filepath.IsAbs(position.Filename) {
+		return false
+	}
+	filename, err := filepath.Rel(graph.DestDir, position.Filename)
+	if err != nil {
+		return false
+	}
+	// Directives must start at the beginning of a line.
+	w.W("//line %s:%d\n", filepath.ToSlash(filename), position.Line)
+	return true
+}
+
+// resolveLineDirectives replaces each lineDirectiveReset in code with a //line directive for the following line.
+func resolveLineDirectives(code []byte) []byte {
+	if !bytes.Contains(code, []byte(lineDirectiveReset)) {
+		return code
+	}
+	lines := bytes.Split(code, []byte("\n"))
+	for i, line := range lines {
+		if string(line) == lineDirectiveReset {
+			// Lines are numbered from 1, so the following line is i+2.
+			lines[i] = fmt.Appendf(nil, "//line zero.go:%d", i+2)
+		}
+	}
+	return bytes.Join(lines, []byte("\n"))
+}
+
 // writeProviderCall generates code to call a provider function with its dependencies.
-func writeProviderCall(w *codewriter.Writer, graph *depgraph.Graph, provider *depgraph.Provider, depVarPrefix string, resultVar string) {
-	// Construct all dependencies
+//
+// If traced is true, the call is made via zeroConstructTraced, which logs its progress and enforces a timeout.
+func writeProviderCall(w *codewriter.Writer, graph *depgraph.Graph, provider *depgraph.Provider, depVarPrefix string, resultVar string, traced bool) {
+	// Value providers are referenced directly
+	if provider.Value != nil {
+		valueRef := graph.ValueRef(provider.Value)
+		w.Import(valueRef.Imports()...)
+		w.L("%s := %s", resultVar, valueRef.Ref)
+		return
+	}
+
+	// Construct all dependencies, prefixing their errors with the provided type so that a failure reports the chain of
+	// types being constructed, eg. "*http.Server -> *Service -> *DAL: dial tcp ...".
+	provides := constructionName(graph, provider.Provides)
 	for i, require := range provider.Requires {
-		writeZeroConstructSingleton(w, graph, fmt.Sprintf("%s%d", depVarPrefix, i), require, "")
+		varName := fmt.Sprintf("%s%d", depVarPrefix, i)
+		if elem, ok := depgraph.OptionalType(require); ok {
+			writeOptionalConstruction(w, graph, varName, elem, provides+" -> ")
+			continue
+		}
+		if elem, ok := depgraph.LazyType(require); ok {
+			writeLazyConstruction(w, graph, varName, elem)
+			continue
+		}
+		writeZeroConstructSingleton(w, graph, varName, require, provides+" -> ")
 	}
 
-	// Get function reference and call it
-	functionRef := graph.FunctionRef(provider.Function)
-	if functionRef.Import != "" {
-		w.Import(functionRef.Import)
+	// Get function reference and call it, or for method providers call the method on the constructed receiver
+	args := make([]string, len(provider.Requires))
+	for i := range provider.Requires {
+		args[i] = fmt.Sprintf("%s%d", depVarPrefix, i)
 	}
-	returnsErr := provider.Function.Signature().Results().Len() == 2
-	w.Indent()
-	if returnsErr {
-		w.W("%s, err := %s", resultVar, functionRef.Ref)
+	var call string
+	if provider.Receiver != nil {
+		call = args[0] + "." + provider.Function.Name()
+		args = args[1:]
 	} else {
-		w.W("%s := %s", resultVar, functionRef.Ref)
+		functionRef := graph.FunctionRef(provider.Function)
+		w.Import(functionRef.Imports()...)
+		call = functionRef.Ref
 	}
 
 	// Add type instantiation for generic providers
@@ -540,41 +1678,182 @@ func writeProviderCall(w *codewriter.Writer, graph *depgraph.Graph, provider *de
 		// Extract type arguments from the concrete type that this provider provides
 		typeArgs := extractTypeArguments(provider.Provides)
 		if len(typeArgs) > 0 {
-			w.W("[")
+			refs := make([]string, len(typeArgs))
 			for i, typeArg := range typeArgs {
 				argRef := graph.TypeRef(typeArg)
-				if argRef.Import != "" {
-					w.Import(argRef.Import)
-				}
-				w.W("%s", argRef.Ref)
-				if i < len(typeArgs)-1 {
-					w.W(", ")
-				}
+				w.Import(argRef.Imports()...)
+				refs[i] = argRef.Ref
 			}
-			w.W("]")
+			call += "[" + strings.Join(refs, ", ") + "]"
 		}
 	}
+	call += "(" + strings.Join(args, ", ") + ")"
 
-	w.W("(")
-	for i := range len(provider.Requires) {
-		w.W("%s%d", depVarPrefix, i)
-		if i < len(provider.Requires)-1 {
-			w.W(", ")
-		}
+	returnsErr := provider.Function.Signature().Results().Len() == 2
+	switch {
+	case traced:
+		ref := graph.TypeRef(provider.Provides)
+		w.Import(ref.Imports()...)
+		w.L("%s, err := zeroConstructTraced(ctx, injector, %q, %q, func() (%s, error) {", resultVar, provides, providerName(graph, provider), ref.Ref)
+		w.In(func(w *codewriter.Writer) {
+			if returnsErr {
+				w.L("return %s", call)
+			} else {
+				w.L("return %s, nil", call)
+			}
+		})
+		w.L("})")
+		returnsErr = true
+	case returnsErr:
+		w.L("%s, err := %s", resultVar, call)
+	default:
+		w.L("%s := %s", resultVar, call)
 	}
-	w.W(")\n")
 	if returnsErr {
-		ref := graph.TypeRef(provider.Provides)
 		w.L("if err != nil {")
 		w.In(func(w *codewriter.Writer) {
-			w.L(`return out, fmt.Errorf("%s: %%w", err)`, ref.Ref)
+			w.L(`return out, fmt.Errorf("%s: %%w", err)`, provides)
+		})
+		w.L("}")
+	}
+}
+
+// providerName returns the name and position of a provider function in construction logs and errors, eg.
+// "NewDAL (dal.go:12)".
+func providerName(graph *depgraph.Graph, provider *depgraph.Provider) string {
+	name := provider.Function.Name()
+	if recv := provider.Function.Signature().Recv(); recv != nil {
+		name = strings.TrimPrefix(constructionName(graph, recv.Type()), "*") + "." + name
+	} else if pkg := provider.Function.Pkg(); pkg != graph.Dest {
+		name = pkg.Name() + "." + name
+	}
+	return fmt.Sprintf("%s (%s:%d)", name, filepath.Base(provider.Position.Filename), provider.Position.Line)
+}
+
+// untracedTypes returns the logger and the types it depends on, which are not traced as tracing uses the logger.
+func untracedTypes(graph *depgraph.Graph) map[string]bool {
+	out := map[string]bool{}
+	var visit func(key string)
+	visit = func(key string) {
+		if out[key] {
+			return
+		}
+		out[key] = true
+		for _, provider := range graph.Providers[key] {
+			for _, require := range provider.Requires {
+				if elem, ok := depgraph.OptionalType(require); ok {
+					require = elem
+				}
+				visit(types.TypeString(require, nil))
+			}
+		}
+		if group := graph.Groups[key]; group != nil {
+			for _, member := range group.Members {
+				visit(types.TypeString(member.Provides, nil))
+			}
+		}
+	}
+	visit(loggerType)
+	return out
+}
+
+// writeConstructTraced writes zeroConstructTraced, which logs the construction of a provider at debug level and fails
+// if it does not return within the configured construction timeout.
+func writeConstructTraced(w *codewriter.Writer, graph *depgraph.Graph) {
+	w.Import("context", "fmt", "log/slog", "time")
+	w.L("")
+	w.L("// zeroConstructTraced calls construct, logging its progress at debug level, and fails if it does not return within")
+	w.L("// the configured construction timeout.")
+	w.L("func zeroConstructTraced[T any](ctx context.Context, injector *Injector, typeName, provider string, construct func() (T, error)) (out T, err error) {")
+	w.In(func(w *codewriter.Writer) {
+		if _, ok := graph.Providers[loggerType]; ok {
+			w.L("logger, err := ZeroConstructSingletons[*slog.Logger](ctx, injector)")
+			w.L("if err != nil {")
+			w.In(func(w *codewriter.Writer) {
+				w.L("return out, err")
+			})
+			w.L("}")
+		} else {
+			w.L("logger := slog.Default()")
+		}
+		w.L(`logger.DebugContext(ctx, "Constructing", "type", typeName, "provider", provider)`)
+		w.L("start := time.Now()")
+		w.L("timeout := injector.config.ConstructionTimeout")
+		w.L("if timeout <= 0 {")
+		w.In(func(w *codewriter.Writer) {
+			w.L("out, err = construct()")
+		})
+		w.L("} else {")
+		w.In(func(w *codewriter.Writer) {
+			w.L("type result struct {")
+			w.In(func(w *codewriter.Writer) {
+				w.L("out T")
+				w.L("err error")
+			})
+			w.L("}")
+			w.L("done := make(chan result, 1)")
+			w.L("go func() {")
+			w.In(func(w *codewriter.Writer) {
+				w.L("out, err := construct()")
+				w.L("done <- result{out, err}")
+			})
+			w.L("}()")
+			w.L("timer := time.NewTimer(timeout)")
+			w.L("defer timer.Stop()")
+			w.L("select {")
+			w.L("case result := <-done:")
+			w.In(func(w *codewriter.Writer) {
+				w.L("out, err = result.out, result.err")
+			})
+			w.L("case <-timer.C:")
+			w.In(func(w *codewriter.Writer) {
+				w.L("// Providers can't be cancelled, so the call is abandoned.")
+				w.L(`err = fmt.Errorf("%%s did not return within %%s", provider, timeout)`)
+			})
+			w.L("}")
+		})
+		w.L("}")
+		w.L("if err != nil {")
+		w.In(func(w *codewriter.Writer) {
+			w.L(`logger.DebugContext(ctx, "Construction failed", "type", typeName, "provider", provider, "elapsed", time.Since(start), "error", err)`)
+			w.L("return out, err")
 		})
 		w.L("}")
+		w.L(`logger.DebugContext(ctx, "Constructed", "type", typeName, "provider", provider, "elapsed", time.Since(start))`)
+		w.L("return out, nil")
+	})
+	w.L("}")
+}
+
+// constructionName returns the name of a type in construction errors, qualified by package name rather than import
+// alias, and unqualified in the destination package.
+func constructionName(graph *depgraph.Graph, t types.Type) string {
+	return types.TypeString(t, func(pkg *types.Package) string {
+		if pkg == graph.Dest {
+			return ""
+		}
+		return pkg.Name()
+	})
+}
+
+// isInstantiated returns true if t is not a generic type with type parameters, eg. Cache[K, V].
+func isInstantiated(t types.Type) bool {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	for _, typeArg := range extractTypeArguments(t) {
+		if _, ok := typeArg.(*types.TypeParam); ok {
+			return false
+		}
 	}
+	return true
 }
 
-// extractTypeArguments extracts type arguments from a concrete generic type
+// extractTypeArguments extracts type arguments from a concrete generic type, or a pointer to one
 func extractTypeArguments(t types.Type) []types.Type {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
 	if named, ok := t.(*types.Named); ok {
 		if typeArgs := named.TypeArgs(); typeArgs != nil {
 			result := make([]types.Type, typeArgs.Len())
@@ -587,6 +1866,94 @@ func extractTypeArguments(t types.Type) []types.Type {
 	return nil
 }
 
+// renamedField returns the name of the ZeroConfig field holding the value of a renamed config field given under its
+// former names.
+func renamedField(configKey string, rename depgraph.ConfigRename) string {
+	return "Renamed" + hash(configKey+"."+strings.Join(rename.Path, "."))
+}
+
+func hasRenames(graph *depgraph.Graph) bool {
+	for _, config := range graph.Configs {
+		if len(config.Renames) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// writeApplyRenames writes the ZeroConfig method applying the values of renamed config fields given under their former
+// names, so that renaming a flag does not break existing deployments.
+func writeApplyRenames(w *codewriter.Writer, graph *depgraph.Graph) {
+	if !hasRenames(graph) {
+		return
+	}
+	w.Import("log/slog")
+	w.L("// applyRenames applies config given under the former names of renamed flags, warning that they are deprecated.")
+	w.L("func (c *ZeroConfig) applyRenames() {")
+	w.In(func(w *codewriter.Writer) {
+		for key, config := range stableMapIter(graph.Configs) {
+			for _, rename := range config.Renames {
+				field := renamedField(key, rename)
+				former := strings.Join(rename.Flags, ", ")
+				for _, env := range rename.Envs {
+					former += ", $" + env
+				}
+				w.L("if c.%s != nil {", field)
+				w.In(func(w *codewriter.Writer) {
+					w.L("slog.Warn(%q)", fmt.Sprintf("%s is deprecated, use %s instead", former, rename.Flag))
+					w.L("c.Config%s.%s = *c.%s", hash(key), strings.Join(rename.Path, "."), field)
+				})
+				w.L("}")
+			}
+		}
+	})
+	w.L("}")
+	w.L("")
+}
+
+func hasValidators(graph *depgraph.Graph) bool {
+	for _, config := range graph.Configs {
+		if config.Validates {
+			return true
+		}
+	}
+	return false
+}
+
+// writeValidateConfig writes the ZeroConfig method calling the Validate() method of each config that has one, so that
+// all invalid config is reported together at startup rather than by whichever provider happens to be constructed first.
+//
+// Kong also calls it while parsing the command line if ZeroConfig is embedded in the CLI struct.
+func writeValidateConfig(w *codewriter.Writer, graph *depgraph.Graph) {
+	if !hasValidators(graph) {
+		return
+	}
+	w.Import("errors", "fmt")
+	w.L("// Validate the config, returning the errors of all invalid configs.")
+	w.L("func (c *ZeroConfig) Validate() error {")
+	w.In(func(w *codewriter.Writer) {
+		w.L("var errs []error")
+		for key, config := range stableMapIter(graph.Configs) {
+			if !config.Validates {
+				continue
+			}
+			w.L("if err := c.Config%s.Validate(); err != nil {", hash(key))
+			w.In(func(w *codewriter.Writer) {
+				w.L(`errs = append(errs, fmt.Errorf("%s: %%w", err))`, key)
+			})
+			w.L("}")
+		}
+		w.L("if err := errors.Join(errs...); err != nil {")
+		w.In(func(w *codewriter.Writer) {
+			w.L(`return fmt.Errorf("invalid configuration: %%w", err)`)
+		})
+		w.L("}")
+		w.L("return nil")
+	})
+	w.L("}")
+	w.L("")
+}
+
 func hash(s string) string {
 	h := fnv.New64a()
 	h.Write([]byte(s))
@@ -600,7 +1967,7 @@ func writeCronJobRegistration(w *codewriter.Writer, graph *depgraph.Graph) {
 	for _, cronJob := range graph.CronJobs {
 		receiver := cronJob.Function.Signature().Recv().Type()
 		key := graph.TypeRef(receiver)
-		w.Import(key.Import)
+		w.Import(key.Imports()...)
 		if _, ok := receivers[key]; !ok {
 			receivers[key] = receiverIndex
 			receiverIndex++
@@ -614,13 +1981,11 @@ func writeCronJobRegistration(w *codewriter.Writer, graph *depgraph.Graph) {
 	}
 
 	// Register each cron job
-	for _, cronJob := range graph.CronJobs {
+	for ji, cronJob := range graph.CronJobs {
 		receiver := cronJob.Function.Signature().Recv().Type()
 		ref := graph.TypeRef(receiver)
 		receiverIndex := receivers[ref]
-
-		// Create the job name from the full type signature
-		jobName := fmt.Sprintf("%s.%s", ref, cronJob.Function.Name())
+		jobName := cronJobName(graph, cronJob)
 
 		// Get the schedule duration at generation time
 		schedule, scheduleErr := cronJob.Schedule.Duration()
@@ -631,7 +1996,26 @@ func writeCronJobRegistration(w *codewriter.Writer, graph *depgraph.Graph) {
 
 		// Register the job
 		w.Import("time")
-		w.L("err = cron.Register(%q, time.Duration(%d), r%d.%s)", jobName, schedule.Nanoseconds(), receiverIndex, cronJob.Function.Name())
+		if cronJob.Params != nil {
+			// Parameterised jobs are fanned out, with any job middleware applied to the run for each element.
+			params := fmt.Sprintf("j%dparams", ji)
+			paramRef := graph.TypeRef(cronJob.Params.(*types.Slice).Elem())
+			w.Import(paramRef.Imports()...)
+			w.L("%s, err := ZeroConstructSingletons[[]%s](ctx, injector)", params, paramRef.Ref)
+			w.L("if err != nil {")
+			w.In(func(w *codewriter.Writer) { w.L("return err") })
+			w.L("}")
+			inner := fmt.Sprintf("func(ctx context.Context) error { return r%d.%s(ctx, param) }", receiverIndex, cronJob.Function.Name())
+			job := writeJobMiddleware(w, graph, cronJob.Schedule.Labels, fmt.Sprintf("j%d", ji), inner)
+			fanOutRef := graph.ParseTypeRef("github.com/alecthomas/zero/providers/cron.FanOut")
+			jobRef := graph.ParseTypeRef("github.com/alecthomas/zero/providers/cron.Job")
+			w.Import(fanOutRef.Imports()...)
+			w.L("err = cron.Register(%q, time.Duration(%d), %s(%s, func(param %s) %s { return %s }))", jobName, schedule.Nanoseconds(),
+				fanOutRef.Ref, params, paramRef.Ref, jobRef.Ref, job)
+		} else {
+			job := writeJobMiddleware(w, graph, cronJob.Schedule.Labels, fmt.Sprintf("j%d", ji), fmt.Sprintf("r%d.%s", receiverIndex, cronJob.Function.Name()))
+			w.L("err = cron.Register(%q, time.Duration(%d), %s)", jobName, schedule.Nanoseconds(), job)
+		}
 		w.L("if err != nil {")
 		w.In(func(w *codewriter.Writer) {
 			w.Import("fmt")
@@ -641,6 +2025,198 @@ func writeCronJobRegistration(w *codewriter.Writer, graph *depgraph.Graph) {
 	}
 }
 
+// writeWorkflowRegistration writes RegisterWorkflows, registering the steps of each receiver type as a workflow
+// named after the type.
+func writeWorkflowRegistration(w *codewriter.Writer, graph *depgraph.Graph) {
+	w.L("// RegisterWorkflows registers all Zero workflows with the workflow engine.")
+	w.L("func RegisterWorkflows(ctx context.Context, injector *Injector) error {")
+	w.In(func(w *codewriter.Writer) {
+		workflows := map[depgraph.Ref][]*depgraph.Step{}
+		for _, step := range graph.Steps {
+			ref := graph.TypeRef(step.Function.Signature().Recv().Type())
+			w.Import(ref.Imports()...)
+			workflows[ref] = append(workflows[ref], step)
+		}
+		writeZeroConstructSingletonByName(w, graph, "workflows", "*github.com/alecthomas/zero/providers/workflow.Engine", "")
+		registerRef := graph.ParseTypeRef("github.com/alecthomas/zero/providers/workflow.Register")
+		w.Import(registerRef.Imports()...)
+		for index, ref := range slices.SortedStableFunc(maps.Keys(workflows), func(a, b depgraph.Ref) int {
+			return strings.Compare(a.String(), b.String())
+		}) {
+			steps := workflows[ref]
+			receiver := fmt.Sprintf("r%d", index)
+			writeZeroConstructSingletonByName(w, graph, receiver, ref.String(), ref.String())
+			stateRef := graph.TypeRef(steps[0].StateType)
+			w.Import(stateRef.Imports()...)
+			stepRef := graph.ParseTypeRef(fmt.Sprintf("github.com/alecthomas/zero/providers/workflow.Step[%s]", stateRef.Ref))
+			name := workflowName(steps[0])
+			w.L("if err := %s(workflows, %q, []%s{", registerRef.Ref, name, stepRef.Ref)
+			w.In(func(w *codewriter.Writer) {
+				for _, step := range steps {
+					if step.Compensate != nil {
+						w.L("{Name: %q, Run: %s.%s, Compensate: %s.%s},", step.Function.Name(), receiver, step.Function.Name(), receiver, step.Compensate.Name())
+					} else {
+						w.L("{Name: %q, Run: %s.%s},", step.Function.Name(), receiver, step.Function.Name())
+					}
+				}
+			})
+			w.L("}); err != nil {")
+			w.In(func(w *codewriter.Writer) {
+				w.Import("fmt")
+				w.L(`return fmt.Errorf("failed to register workflow %s: %%w", err)`, name)
+			})
+			w.L("}")
+		}
+		w.L("return nil")
+	})
+	w.L("}")
+}
+
+// workflowName returns the name a workflow is registered with, its receiver type qualified by package name, as for
+// subscribers.
+func workflowName(step *depgraph.Step) string {
+	return strings.TrimPrefix(types.TypeString(step.Function.Signature().Recv().Type(), func(pkg *types.Package) string { return pkg.Name() }), "*")
+}
+
+// writeWorkerRegistration writes RegisterWorkers, starting each worker processing the jobs of its queue.
+func writeWorkerRegistration(w *codewriter.Writer, graph *depgraph.Graph) {
+	w.L("// RegisterWorkers starts all Zero queue workers processing jobs.")
+	w.L("func RegisterWorkers(ctx context.Context, injector *Injector) error {")
+	w.In(func(w *codewriter.Writer) {
+		receivers := map[depgraph.Ref]int{}
+		for _, worker := range graph.Workers {
+			ref := graph.TypeRef(worker.Function.Signature().Recv().Type())
+			w.Import(ref.Imports()...)
+			if _, ok := receivers[ref]; !ok {
+				receivers[ref] = len(receivers)
+			}
+		}
+		for _, ref := range slices.SortedStableFunc(maps.Keys(receivers), func(a, b depgraph.Ref) int {
+			return strings.Compare(a.String(), b.String())
+		}) {
+			writeZeroConstructSingletonByName(w, graph, fmt.Sprintf("r%d", receivers[ref]), ref.String(), ref.String())
+		}
+		optionsRef := graph.ParseTypeRef("github.com/alecthomas/zero/providers/queue.WorkerOptions")
+		retryRef := graph.ParseTypeRef("github.com/alecthomas/zero/providers/queue.RetryPolicy")
+		w.Import(optionsRef.Imports()...)
+		queues := map[string]bool{}
+		for wi, worker := range graph.Workers {
+			payloadRef := graph.TypeRef(worker.PayloadType)
+			w.Import(payloadRef.Imports()...)
+			queueVar := fmt.Sprintf("queue%s", hash(payloadRef.Ref))
+			if !queues[queueVar] {
+				queues[queueVar] = true
+				writeZeroConstructSingletonByName(w, graph, queueVar, fmt.Sprintf("*github.com/alecthomas/zero/providers/queue.Queue[%s]", payloadRef.Ref), "")
+			}
+
+			// Wrap the worker with any job middleware.
+			handler := fmt.Sprintf("r%d.%s", receivers[graph.TypeRef(worker.Function.Signature().Recv().Type())], worker.Function.Name())
+			inner := fmt.Sprintf("func(ctx context.Context) error { return %s(ctx, job) }", handler)
+			if job := writeJobMiddleware(w, graph, worker.Labels, fmt.Sprintf("w%d", wi), inner); job != inner {
+				jobRef := graph.ParseTypeRef(fmt.Sprintf("github.com/alecthomas/zero/providers/queue.Job[%s]", payloadRef.Ref))
+				handler = fmt.Sprintf("func(ctx context.Context, job %s) error { return %s(ctx) }", jobRef.Ref, job)
+			}
+
+			var options, retry []string
+			if worker.Concurrency != nil {
+				options = append(options, fmt.Sprintf("Concurrency: %d", *worker.Concurrency))
+			}
+			if worker.Visibility != 0 {
+				w.Import("time")
+				options = append(options, fmt.Sprintf("Visibility: time.Duration(%d)", worker.Visibility.Nanoseconds()))
+			}
+			if worker.Attempts != nil {
+				retry = append(retry, fmt.Sprintf("MaxAttempts: %d", *worker.Attempts))
+			}
+			if worker.Backoff != 0 {
+				w.Import("time")
+				retry = append(retry, fmt.Sprintf("Backoff: time.Duration(%d)", worker.Backoff.Nanoseconds()))
+			}
+			if len(retry) > 0 {
+				options = append(options, fmt.Sprintf("Retry: %s{%s}", retryRef.Ref, strings.Join(retry, ", ")))
+			}
+			w.L("if err := %s.Work(ctx, %s{%s}, %s); err != nil {", queueVar, optionsRef.Ref, strings.Join(options, ", "), handler)
+			w.In(func(w *codewriter.Writer) {
+				w.Import("fmt")
+				w.L(`return fmt.Errorf("failed to start worker %s: %%w", err)`, worker.Function.Name())
+			})
+			w.L("}")
+		}
+		w.L("return nil")
+	})
+	w.L("}")
+}
+
+// cronJobName returns the name a cron job is registered with, derived from the full type signature.
+func cronJobName(graph *depgraph.Graph, cronJob *depgraph.CronJob) string {
+	return fmt.Sprintf("%s.%s", graph.TypeRef(cronJob.Function.Signature().Recv().Type()), cronJob.Function.Name())
+}
+
+// subscribeContext returns the context to subscribe to a topic with, carrying the concurrency, prefetch and dedupe
+// window of subscriptions if any set them. Subscriptions sharing a subscriber, such as versions routed by a single router, use
+// the largest of each.
+func subscribeContext(w *codewriter.Writer, graph *depgraph.Graph, subscriptions ...*depgraph.Subscription) string {
+	var options []string
+	for _, option := range []struct {
+		name  string
+		value func(*depgraph.Subscription) *int
+	}{
+		{"Concurrency", func(s *depgraph.Subscription) *int { return s.Concurrency }},
+		{"Prefetch", func(s *depgraph.Subscription) *int { return s.Prefetch }},
+	} {
+		largest := -1
+		for _, subscription := range subscriptions {
+			if value := option.value(subscription); value != nil {
+				largest = max(largest, *value)
+			}
+		}
+		if largest >= 0 {
+			options = append(options, fmt.Sprintf("%s: %d", option.name, largest))
+		}
+	}
+	var dedupe time.Duration
+	for _, subscription := range subscriptions {
+		dedupe = max(dedupe, subscription.Dedupe)
+	}
+	if dedupe > 0 {
+		w.Import("time")
+		options = append(options, fmt.Sprintf("Dedupe: time.Duration(%d)", dedupe.Nanoseconds()))
+	}
+	if len(options) == 0 {
+		return "ctx"
+	}
+	withRef := graph.ParseTypeRef("github.com/alecthomas/zero/providers/pubsub.WithSubscribeOptions")
+	optionsRef := graph.ParseTypeRef("github.com/alecthomas/zero/providers/pubsub.SubscribeOptions")
+	w.Import(withRef.Imports()...)
+	return fmt.Sprintf("%s(ctx, %s{%s})", withRef.Ref, optionsRef.Ref, strings.Join(options, ", "))
+}
+
+// writeJobMiddleware writes the construction of the dependencies of any job middleware matching labels, and returns
+// job wrapped with the middleware, innermost first.
+func writeJobMiddleware(w *codewriter.Writer, graph *depgraph.Graph, labels []string, prefix string, job string) string {
+	for mi, middleware := range graph.Middleware {
+		if !middleware.MatchJob(labels) {
+			continue
+		}
+		ref := graph.FunctionRef(middleware.Function)
+		w.Import(ref.Imports()...)
+		if !middleware.Factory {
+			job = fmt.Sprintf("%s(%s)", ref.Ref, job)
+			continue
+		}
+		w.L("// Parameters for the %s middleware", ref.Ref)
+		args := []string{}
+		params := middleware.Function.Signature().Params()
+		for i := range params.Len() {
+			arg := fmt.Sprintf("%sm%dp%d", prefix, mi, i)
+			args = append(args, arg)
+			writeZeroConstructSingletonByName(w, graph, arg, types.TypeString(params.At(i).Type(), nil), "")
+		}
+		job = fmt.Sprintf("%s(%s)(%s)", ref.Ref, strings.Join(args, ", "), job)
+	}
+	return job
+}
+
 func stableMapIter[K cmp.Ordered, V any](m map[K]V) iter.Seq2[K, V] {
 	return func(yield func(K, V) bool) {
 		for _, key := range slices.Sorted(maps.Keys(m)) {
@@ -650,3 +2226,19 @@ func stableMapIter[K cmp.Ordered, V any](m map[K]V) iter.Seq2[K, V] {
 		}
 	}
 }
+
+// buildConstraint returns a //go:build expression satisfied when all of tags are, eg. "prod && (linux || darwin)".
+func buildConstraint(tags []string) string {
+	if len(tags) == 1 {
+		return tags[0]
+	}
+	terms := make([]string, len(tags))
+	for i, tag := range tags {
+		// && binds more tightly than ||, so only disjunctions need parentheses.
+		if strings.Contains(tag, "||") {
+			tag = "(" + tag + ")"
+		}
+		terms[i] = tag
+	}
+	return strings.Join(terms, " && ")
+}
@@ -2,6 +2,7 @@ package generator
 
 import (
 	"fmt"
+	"go/types"
 	"io"
 	"maps"
 	"os"
@@ -12,6 +13,7 @@ import (
 	"testing"
 
 	"github.com/alecthomas/assert/v2"
+	"github.com/alecthomas/zero/internal/codewriter"
 	"github.com/alecthomas/zero/internal/depgraph"
 )
 
@@ -148,6 +150,102 @@ func TestMultiProvider(t *testing.T) {
 	assert.NoError(t, err, "Generated code should compile and run:\n%s", generatedCode)
 }
 
+func TestRunConstructsRootsBeforeServing(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	copyFile(t, "testdata/main.go", filepath.Join(dir, "main.go"))
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".", depgraph.WithProviders(
+		"github.com/alecthomas/zero/providers/sql.New",
+		"github.com/alecthomas/zero/providers/cron.NewScheduler",
+		"github.com/alecthomas/zero/providers/cron.NewMemoryHistory",
+		"github.com/alecthomas/zero/providers/leases.NewMemoryLeaser",
+	))
+	assert.NoError(t, err)
+	assert.True(t, slices.Contains(graph.Roots, "*net/http.Server"), "%v", graph.Roots)
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph)
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	generatedCode := readFile(t)
+	assert.Contains(t, generatedCode, "if _, err := ZeroConstructSingletons[*http.Server](ctx, injector); err != nil {")
+	// Construction errors are prefixed with the chain of types being constructed, starting from the root.
+	assert.Contains(t, generatedCode, "errs = append(errs, err)")
+	assert.Contains(t, generatedCode, `return out, fmt.Errorf("*http.Server -> %w", err)`)
+	assert.Contains(t, generatedCode, `return fmt.Errorf("failed to construct service: %w", err)`)
+	assert.Contains(t, generatedCode, "readiness.SetReady(true)")
+	assert.Contains(t, generatedCode, "injector.failures[reflect.TypeFor[T]()] = err")
+	// Roots are constructed before handlers are registered, and readiness is flagged before serving.
+	assert.True(t, strings.Index(generatedCode, "failed to construct service") < strings.Index(generatedCode, "RegisterHandlers(ctx, injector); err != nil"))
+	assert.True(t, strings.Index(generatedCode, "readiness.SetReady(true)") < strings.Index(generatedCode, "server.ListenAndServe()"))
+
+	goModTidy(t, dir)
+
+	cmd := exec.CommandContext(t.Context(), "go", "build", ".")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	assert.NoError(t, err, "Generated code should compile:\n%s", generatedCode)
+}
+
+func TestGenericRootConstruction(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+type Topic[T any] struct{}
+
+type User struct{}
+
+//zero:provider
+func NewTopic[T any]() Topic[T] { return Topic[T]{} }
+
+type Service struct{}
+
+//zero:provider
+func NewService(topic Topic[User]) *Service { return &Service{} }
+
+func main() {}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".", depgraph.WithRoots("*test.Service", "test.Topic[test.User]"))
+	assert.NoError(t, err)
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph)
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	generatedCode := readFile(t)
+	assert.Contains(t, generatedCode, "if _, err := ZeroConstructSingletons[Topic[User]](ctx, injector); err != nil {")
+
+	goModTidy(t, dir)
+
+	cmd := exec.CommandContext(t.Context(), "go", "build", ".")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	assert.NoError(t, err, "Generated code should compile:\n%s", generatedCode)
+}
+
 func TestCronJobGeneration(t *testing.T) {
 	cwd, err := os.Getwd()
 	assert.NoError(t, err)
@@ -162,6 +260,7 @@ func TestCronJobGeneration(t *testing.T) {
 	graph, err := depgraph.Analyse(t.Context(), ".", depgraph.WithProviders(
 		"github.com/alecthomas/zero/providers/sql.New",
 		"github.com/alecthomas/zero/providers/cron.NewScheduler",
+		"github.com/alecthomas/zero/providers/cron.NewMemoryHistory",
 		"github.com/alecthomas/zero/providers/leases.NewMemoryLeaser",
 	))
 	assert.NoError(t, err)
@@ -236,6 +335,7 @@ func main() {}
 
 	graph, err := depgraph.Analyse(t.Context(), ".", depgraph.WithProviders(
 		"github.com/alecthomas/zero/providers/cron.NewScheduler",
+		"github.com/alecthomas/zero/providers/cron.NewMemoryHistory",
 		"github.com/alecthomas/zero/providers/leases.NewMemoryLeaser",
 	))
 	assert.NoError(t, err)
@@ -270,6 +370,99 @@ func main() {}
 	assert.NoError(t, err, "Generated code should compile")
 }
 
+func TestJobMiddlewareGeneration(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/alecthomas/zero"
+	"github.com/alecthomas/zero/providers/pubsub"
+)
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service {
+	return &Service{}
+}
+
+type UserCreated struct {
+	Name string
+}
+
+//zero:middleware
+func Recover(next zero.JobFunc) zero.JobFunc {
+	return next
+}
+
+//zero:middleware traced
+func Trace(logger *slog.Logger) zero.JobMiddleware {
+	return func(next zero.JobFunc) zero.JobFunc { return next }
+}
+
+//zero:cron 5m traced
+func (s *Service) Cleanup(ctx context.Context) error {
+	return nil
+}
+
+//zero:subscribe version=1 traced
+func (s *Service) OnUserCreatedV1(ctx context.Context, event pubsub.Event[UserCreated]) error {
+	return nil
+}
+
+//zero:subscribe
+func (s *Service) AuditUserCreated(ctx context.Context, event pubsub.Event[UserCreated]) error {
+	return nil
+}
+
+var cli struct {
+	ZeroConfig
+}
+
+func main() {}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".", depgraph.WithProviders(
+		"github.com/alecthomas/zero/providers/cron.NewScheduler",
+		"github.com/alecthomas/zero/providers/cron.NewMemoryHistory",
+		"github.com/alecthomas/zero/providers/leases.NewMemoryLeaser",
+	))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(graph.Middleware))
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph)
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	generatedCode := readFile(t)
+	assert.Contains(t, generatedCode, `cron.Register("*test.Service.Cleanup", time.Duration(300000000000), Trace(j0m1p0)(Recover(r0.Cleanup)))`)
+	assert.Contains(t, generatedCode, `1: imp57144815321973d3.Track(subscriptions, "main.Service.OnUserCreatedV1", func(ctx context.Context, event imp57144815321973d3.Event[UserCreated]) error {`)
+	assert.Contains(t, generatedCode, `return Trace(s0m1p0)(Recover(func(ctx context.Context) error { return r0.OnUserCreatedV1(ctx, event) }))(ctx)`)
+	assert.Contains(t, generatedCode, `return Recover(func(ctx context.Context) error { return r0.AuditUserCreated(ctx, event) })(ctx)`)
+
+	goModTidy(t, dir)
+
+	cmd := exec.CommandContext(t.Context(), "go", "build", ".")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	assert.NoError(t, err, "Generated code should compile:\n%s", generatedCode)
+}
+
 func TestGenericProviderGeneration(t *testing.T) {
 	cwd, err := os.Getwd()
 	assert.NoError(t, err)
@@ -521,6 +714,7 @@ func main() {}
 
 	graph, err := depgraph.Analyse(t.Context(), ".", depgraph.WithProviders(
 		"github.com/alecthomas/zero/providers/cron.NewScheduler",
+		"github.com/alecthomas/zero/providers/cron.NewMemoryHistory",
 		"github.com/alecthomas/zero/providers/leases.NewMemoryLeaser",
 	))
 	assert.NoError(t, err)
@@ -555,6 +749,3940 @@ func main() {}
 	assert.NoError(t, err, "Generated code should compile")
 }
 
-func stableKeys[V any](m map[string]V) []string {
-	return slices.Sorted(maps.Keys(m))
+func TestParameterisedCronGeneration(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+import (
+	"context"
+
+	"github.com/alecthomas/zero"
+)
+
+type Tenant struct {
+	ID string
+}
+
+//zero:provider
+func NewTenants() []Tenant {
+	return []Tenant{{ID: "acme"}, {ID: "globex"}}
+}
+
+type Maintenance struct{}
+
+//zero:provider
+func NewMaintenance() *Maintenance {
+	return &Maintenance{}
+}
+
+//zero:middleware traced
+func Trace(next zero.JobFunc) zero.JobFunc { return next }
+
+//zero:cron 1h traced
+func (m *Maintenance) Vacuum(ctx context.Context, tenant Tenant) error {
+	return nil
+}
+
+var cli struct {
+	ZeroConfig
+}
+
+func main() {}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".", depgraph.WithProviders(
+		"github.com/alecthomas/zero/providers/cron.NewMemoryHistory",
+		"github.com/alecthomas/zero/providers/leases.NewMemoryLeaser",
+	))
+	assert.NoError(t, err)
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph)
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	generatedCode := readFile(t)
+	assert.Contains(t, generatedCode, "j0params, err := ZeroConstructSingletons[[]Tenant](ctx, injector)")
+	assert.Contains(t, generatedCode, `.FanOut(j0params, func(param Tenant) `)
+	assert.Contains(t, generatedCode, `{ return Trace(func(ctx context.Context) error { return r0.Vacuum(ctx, param) }) }))`)
+
+	goModTidy(t, dir)
+
+	cmd := exec.CommandContext(t.Context(), "go", "build", ".")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	assert.NoError(t, err, "Generated code should compile:\n%s", generatedCode)
+}
+
+func TestValueProviderGeneration(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+import (
+	"time"
+)
+
+type Options struct {
+	Retries int
+}
+
+//zero:provider
+const DefaultTimeout time.Duration = 30 * time.Second
+
+//zero:provider
+var DefaultOptions = &Options{Retries: 3}
+
+type Service struct {
+	timeout time.Duration
+	options *Options
+}
+
+//zero:provider
+func NewService(timeout time.Duration, options *Options) *Service {
+	return &Service{timeout: timeout, options: options}
+}
+
+var cli struct {
+	ZeroConfig
+}
+
+func main() {}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".", depgraph.WithRoots("*test.Service"))
+	assert.NoError(t, err)
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph)
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	generatedCode := readFile(t)
+	assert.Contains(t, generatedCode, "o := DefaultTimeout")
+	assert.Contains(t, generatedCode, "o := DefaultOptions")
+
+	goModTidy(t, dir)
+
+	cmd := exec.CommandContext(t.Context(), "go", "build", ".")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	assert.NoError(t, err, "Generated code should compile:\n%s", generatedCode)
+}
+
+func TestOptionalDependencyGeneration(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+import (
+	"github.com/alecthomas/zero"
+)
+
+type Tracer struct{}
+
+type Metrics struct{}
+
+//zero:provider
+func NewTracer() *Tracer {
+	return &Tracer{}
+}
+
+type Service struct {
+	tracer  zero.Optional[*Tracer]
+	metrics zero.Optional[*Metrics]
+}
+
+//zero:provider
+func NewService(tracer zero.Optional[*Tracer], metrics zero.Optional[*Metrics]) *Service {
+	return &Service{tracer: tracer, metrics: metrics}
+}
+
+var cli struct {
+	ZeroConfig
+}
+
+func main() {}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".", depgraph.WithRoots("*test.Service"))
+	assert.NoError(t, err)
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph)
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	generatedCode := readFile(t)
+	assert.Contains(t, generatedCode, "p0 := zero.Some(p0v)")
+	assert.Contains(t, generatedCode, "p1 := zero.None[*Metrics]()")
+
+	goModTidy(t, dir)
+
+	cmd := exec.CommandContext(t.Context(), "go", "build", ".")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	assert.NoError(t, err, "Generated code should compile:\n%s", generatedCode)
+}
+
+func TestGroupGeneration(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+type HealthCheck interface {
+	Check() error
+}
+
+type DBCheck struct{}
+
+func (*DBCheck) Check() error { return nil }
+
+type CacheCheck struct{}
+
+func (*CacheCheck) Check() error { return nil }
+
+//zero:provider group=HealthCheck
+func NewDBCheck() *DBCheck { return &DBCheck{} }
+
+//zero:provider group=HealthCheck
+func NewCacheCheck() *CacheCheck { return &CacheCheck{} }
+
+type Service struct {
+	checks []HealthCheck
+}
+
+//zero:provider
+func NewService(checks []HealthCheck) *Service {
+	return &Service{checks: checks}
+}
+
+var cli struct {
+	ZeroConfig
+}
+
+func main() {}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".", depgraph.WithRoots("*test.Service"))
+	assert.NoError(t, err)
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph)
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	generatedCode := readFile(t)
+	assert.Contains(t, generatedCode, "case reflect.TypeOf((*[]HealthCheck)(nil)).Elem():")
+	assert.Contains(t, generatedCode, "return any([]HealthCheck{g0, g1}).(T), nil")
+
+	goModTidy(t, dir)
+
+	cmd := exec.CommandContext(t.Context(), "go", "build", ".")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	assert.NoError(t, err, "Generated code should compile:\n%s", generatedCode)
+}
+
+func TestLazyDependencyGeneration(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+import (
+	"github.com/alecthomas/zero"
+)
+
+type Model struct{}
+
+//zero:provider
+func LoadModel() (*Model, error) {
+	return &Model{}, nil
+}
+
+type Service struct {
+	model zero.Lazy[*Model]
+}
+
+//zero:provider
+func NewService(model zero.Lazy[*Model]) *Service {
+	return &Service{model: model}
+}
+
+var cli struct {
+	ZeroConfig
+}
+
+func main() {}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".", depgraph.WithRoots("*test.Service"))
+	assert.NoError(t, err)
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph)
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	generatedCode := readFile(t)
+	assert.Contains(t, generatedCode, "p0 := zero.NewLazy(func() (*Model, error) {")
+	assert.Contains(t, generatedCode, "return ZeroConstructSingletons[*Model](ctx, injector)")
+
+	goModTidy(t, dir)
+
+	cmd := exec.CommandContext(t.Context(), "go", "build", ".")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	assert.NoError(t, err, "Generated code should compile:\n%s", generatedCode)
+}
+
+func TestLazyDependencyGetDuringConstruction(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+import (
+	"github.com/alecthomas/zero"
+)
+
+type Index struct{}
+
+//zero:provider
+func NewIndex() *Index {
+	return &Index{}
+}
+
+type Model struct {
+	index *Index
+}
+
+// LoadModel is constructed through zero.Lazy, and gets its own zero.Lazy dependency while being constructed.
+//
+//zero:provider
+func LoadModel(index zero.Lazy[*Index]) (*Model, error) {
+	i, err := index.Get()
+	if err != nil {
+		return nil, err
+	}
+	return &Model{index: i}, nil
+}
+
+type Service struct {
+	model zero.Lazy[*Model]
+}
+
+//zero:provider
+func NewService(model zero.Lazy[*Model]) *Service {
+	return &Service{model: model}
+}
+
+type Other struct {
+	model zero.Lazy[*Model]
+}
+
+//zero:provider
+func NewOther(model zero.Lazy[*Model]) *Other {
+	return &Other{model: model}
+}
+
+var cli struct {
+	ZeroConfig
+}
+
+func main() {}
+`), 0644)
+	assert.NoError(t, err)
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main_test.go"), []byte(`package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLazyGet(t *testing.T) {
+	ctx := t.Context()
+	injector := NewInjector(ctx, ZeroConfig{})
+	service, err := ZeroConstructSingletons[*Service](ctx, injector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := ZeroConstructSingletons[*Other](ctx, injector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	models := make([]*Model, 8)
+	var wg sync.WaitGroup
+	for i := range models {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			get := service.model.Get
+			if i%2 == 1 {
+				get = other.model.Get
+			}
+			model, err := get()
+			if err != nil {
+				t.Error(err)
+			}
+			models[i] = model
+		}()
+	}
+	done := make(chan struct{})
+	go func() { wg.Wait(); close(done) }()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("deadlock getting lazy dependencies")
+	}
+	for _, model := range models {
+		if model == nil || model != models[0] || model.index == nil {
+			t.Fatalf("expected a single model with an index, got %v", models)
+		}
+	}
+}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".", depgraph.WithRoots("*test.Service", "*test.Other"))
+	assert.NoError(t, err)
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph)
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	code := readFile(t)
+
+	goModTidy(t, dir)
+
+	cmd := exec.CommandContext(t.Context(), "go", "test", ".")
+	output, err := cmd.CombinedOutput()
+	assert.NoError(t, err, "Lazy dependencies should be got during construction without deadlocking:\n%s\n%s", output, code)
+}
+
+func TestLazyDependencyCycle(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+import (
+	"github.com/alecthomas/zero"
+)
+
+type Cache struct {
+	store *Store
+}
+
+// NewCache gets its zero.Lazy dependency while being constructed, but the store depends on the cache.
+//
+//zero:provider
+func NewCache(store zero.Lazy[*Store]) (*Cache, error) {
+	s, err := store.Get()
+	if err != nil {
+		return nil, err
+	}
+	return &Cache{store: s}, nil
+}
+
+type Store struct {
+	cache *Cache
+}
+
+//zero:provider
+func NewStore(cache *Cache) *Store {
+	return &Store{cache: cache}
+}
+
+var cli struct {
+	ZeroConfig
+}
+
+func main() {}
+`), 0644)
+	assert.NoError(t, err)
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main_test.go"), []byte(`package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLazyCycle(t *testing.T) {
+	ctx := t.Context()
+	injector := NewInjector(ctx, ZeroConfig{})
+	errs := make(chan error, 1)
+	go func() {
+		_, err := ZeroConstructSingletons[*Cache](ctx, injector)
+		errs <- err
+	}()
+	select {
+	case err := <-errs:
+		if err == nil || !strings.Contains(err.Error(), "dependency cycle: *main.Cache is already being constructed") {
+			t.Fatalf("expected a dependency cycle error, got %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("deadlock getting a lazy dependency that depends on the type being constructed")
+	}
+}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".", depgraph.WithRoots("*test.Cache"))
+	assert.NoError(t, err)
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph)
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	code := readFile(t)
+
+	goModTidy(t, dir)
+
+	cmd := exec.CommandContext(t.Context(), "go", "test", ".")
+	output, err := cmd.CombinedOutput()
+	assert.NoError(t, err, "Getting a lazy dependency that depends on the type being constructed should fail:\n%s\n%s", output, code)
+}
+
+func TestMethodProviderGeneration(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+import (
+	"time"
+)
+
+type Factory struct {
+	timeout time.Duration
+}
+
+//zero:provider
+func NewFactory() *Factory {
+	return &Factory{timeout: time.Second}
+}
+
+type Client struct {
+	timeout time.Duration
+}
+
+//zero:provider
+func (f *Factory) NewClient() (*Client, error) {
+	return &Client{timeout: f.timeout}, nil
+}
+
+var cli struct {
+	ZeroConfig
+}
+
+func main() {}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".", depgraph.WithRoots("*test.Client"))
+	assert.NoError(t, err)
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph)
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	generatedCode := readFile(t)
+	assert.Contains(t, generatedCode, "o, err := p0.NewClient()")
+
+	goModTidy(t, dir)
+
+	cmd := exec.CommandContext(t.Context(), "go", "build", ".")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	assert.NoError(t, err, "Generated code should compile:\n%s", generatedCode)
+}
+
+// featureFlagPlugin generates a FeatureFlags function evaluating every function annotated with //zero:featureflag.
+type featureFlagPlugin struct{}
+
+func (featureFlagPlugin) Directive() string { return "featureflag" }
+
+func (featureFlagPlugin) Analyse(annotation *depgraph.Annotation) ([]types.Type, error) {
+	requires := []types.Type{}
+	for param := range annotation.Object.(*types.Func).Signature().Params().Variables() {
+		requires = append(requires, param.Type())
+	}
+	return requires, nil
+}
+
+func (featureFlagPlugin) Generate(w *codewriter.Writer, graph *depgraph.Graph, annotations []*depgraph.Annotation) error {
+	w.L("// FeatureFlags evaluates all feature flags.")
+	w.L("func FeatureFlags(ctx context.Context, injector *Injector) (map[string]bool, error) {")
+	w.In(func(w *codewriter.Writer) {
+		w.L("out := map[string]bool{}")
+		for i, annotation := range annotations {
+			fn := annotation.Object.(*types.Func)
+			args := []string{}
+			for j, req := range annotation.Requires {
+				arg := fmt.Sprintf("f%dp%d", i, j)
+				args = append(args, arg)
+				writeZeroConstructSingleton(w, graph, arg, req, "")
+			}
+			ref := graph.FunctionRef(fn)
+			w.Import(ref.Import)
+			w.L("out[%q] = %s(%s)", fn.Name(), ref.Ref, strings.Join(args, ", "))
+		}
+		w.L("return out, nil")
+	})
+	w.L("}")
+	return nil
+}
+
+func TestPluginGeneration(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+type Flags struct{}
+
+//zero:provider
+func NewFlags() *Flags {
+	return &Flags{}
+}
+
+//zero:featureflag
+func NewCheckout(flags *Flags) bool {
+	return true
+}
+
+var cli struct {
+	ZeroConfig
+}
+
+func main() {}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".", depgraph.WithPlugins(featureFlagPlugin{}))
+	assert.NoError(t, err)
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph)
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	generatedCode := readFile(t)
+	assert.Contains(t, generatedCode, "func FeatureFlags(ctx context.Context, injector *Injector) (map[string]bool, error) {")
+	assert.Contains(t, generatedCode, `out["NewCheckout"] = NewCheckout(f0p0)`)
+
+	goModTidy(t, dir)
+
+	cmd := exec.CommandContext(t.Context(), "go", "build", ".")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	assert.NoError(t, err, "Generated code should compile:\n%s", generatedCode)
+}
+
+func TestTemplateGeneration(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+import (
+	"net/http"
+)
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service {
+	return &Service{}
+}
+
+type User struct {
+	Name string
+}
+
+//zero:api POST /users
+func (s *Service) CreateUser(user User) error {
+	return nil
+}
+
+func instrument(handler http.Handler, pattern string) http.Handler {
+	return handler
+}
+
+var cli struct {
+	ZeroConfig
+}
+
+func main() {}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".")
+	assert.NoError(t, err)
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph, WithTemplates(Templates{
+		Header:  "// Copyright Example Corp. Package {{.Package}}.",
+		Handler: `instrument({{.Handler}}, {{printf "%q" .Pattern}})`,
+		Run: `// Run the service.
+func Run(ctx context.Context, config ZeroConfig) error {
+	injector := NewInjector(ctx, config)
+	if err := RegisterHandlers(ctx, injector); err != nil {
+		return err
+	}
+	server, err := ZeroConstructSingletons[*http.Server](ctx, injector)
+	if err != nil {
+		return err
+	}
+	return server.ListenAndServe()
+}`,
+	}))
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	generatedCode := readFile(t)
+	assert.Contains(t, generatedCode, "001: // Copyright Example Corp. Package main.")
+	assert.Contains(t, generatedCode, `mux.Handle("POST /users", zero.RequestLimits(logger, encodeError, serverConfig.RequestTimeout, serverConfig.MaxBodySize)(instrument(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {`)
+	assert.Contains(t, generatedCode, `}), "POST /users")))`)
+	assert.Contains(t, generatedCode, "// Run the service.")
+	assert.NotContains(t, generatedCode, "errgroup")
+
+	goModTidy(t, dir)
+
+	cmd := exec.CommandContext(t.Context(), "go", "build", ".")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	assert.NoError(t, err, "Generated code should compile:\n%s", generatedCode)
+
+	err = Generate(io.Discard, graph, WithTemplates(Templates{Handler: "instrument()"}))
+	assert.EqualError(t, err, "handler template must contain {{.Handler}}")
+}
+
+func TestRuntimeGeneration(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service {
+	return &Service{}
+}
+
+type User struct {
+	Name string
+}
+
+//zero:api POST /users
+func (s *Service) CreateUser(user User) error {
+	return nil
+}
+
+var cli struct {
+	ZeroConfig
+}
+
+func main() {}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".")
+	assert.NoError(t, err)
+
+	lambda := &strings.Builder{}
+	err = Generate(lambda, graph, WithRuntimes(RuntimeLambda))
+	assert.NoError(t, err)
+	assert.Contains(t, lambda.String(), "func RunLambda(ctx context.Context, config ZeroConfig) error {")
+	assert.Contains(t, lambda.String(), "lambda.StartWithOptions(httpadapter.New(server.Handler).ProxyWithContext, lambda.WithContext(ctx))")
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph, WithRuntimes(RuntimeCGI, RuntimeFCGI, RuntimeSystemd))
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	generatedCode := readFile(t)
+	assert.Contains(t, generatedCode, "if err := cgi.Serve(server.Handler); err != nil {")
+	assert.Contains(t, generatedCode, "wg.Go(func() error { return fcgi.Serve(nil, server.Handler) })")
+	assert.Contains(t, generatedCode, "wg.Go(func() error { return server.Serve(listener) })")
+	// Every runtime shares Run's construction and startup, but CGI doesn't run subscribers in each request.
+	assert.Equal(t, 3, strings.Count(generatedCode, "if err := RegisterSubscribers(ctx, injector); err != nil {"))
+	assert.Equal(t, 4, strings.Count(generatedCode, "readiness.SetReady(true)"))
+	// The CGI response is written to stdout, so nothing else may be.
+	assert.Contains(t, generatedCode, "os.Stdout = os.Stderr")
+
+	goModTidy(t, dir)
+
+	cmd := exec.CommandContext(t.Context(), "go", "build", ".")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	assert.NoError(t, err, "Generated code should compile:\n%s", generatedCode)
+
+	err = Generate(io.Discard, graph, WithRuntimes("bogus"))
+	assert.EqualError(t, err, `unknown runtime "bogus"`)
+}
+
+func TestRequestLimitsGeneration(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+import "net/http"
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service {
+	return &Service{}
+}
+
+type Upload struct {
+	Data []byte
+}
+
+//zero:api POST /uploads timeout=5s maxbody=1MB audited
+func (s *Service) CreateUpload(upload Upload) error {
+	return nil
+}
+
+//zero:middleware audited
+func Audit(next http.Handler) http.Handler {
+	return next
+}
+
+//zero:api POST /reports timeout=1m
+func (s *Service) CreateReport(upload Upload) error {
+	return nil
+}
+
+var cli struct {
+	ZeroConfig
+}
+
+func main() {}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".")
+	assert.NoError(t, err)
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph)
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	generatedCode := readFile(t)
+	// Limits wrap labelled middleware too, as it may read the body.
+	assert.Contains(t, generatedCode, `mux.Handle("POST /uploads", zero.RequestLimits(logger, encodeError, time.Duration(5000000000), 1048576)(Audit(http.HandlerFunc(`)
+	assert.Contains(t, generatedCode, `mux.Handle("POST /reports", zero.RequestLimits(logger, encodeError, time.Duration(60000000000), serverConfig.MaxBodySize)(http.HandlerFunc(`)
+
+	goModTidy(t, dir)
+
+	cmd := exec.CommandContext(t.Context(), "go", "build", ".")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	assert.NoError(t, err, "Generated code should compile:\n%s", generatedCode)
+}
+
+func TestStreamingBodyGeneration(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+import "io"
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service {
+	return &Service{}
+}
+
+//zero:api PUT /blobs/{key} maxbody=8B
+func (s *Service) PutBlob(key string, body io.Reader) (string, error) {
+	data, err := io.ReadAll(body)
+	return key + ":" + string(data), err
+}
+
+//zero:api POST /images consumes=image/*
+func (s *Service) UploadImage(body io.Reader) (int64, error) {
+	return io.Copy(io.Discard, body)
+}
+
+var cli struct {
+	ZeroConfig
+}
+
+func main() {}
+`), 0644)
+	assert.NoError(t, err)
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main_test.go"), []byte(`package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func serve(t *testing.T, method, path, contentType, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	ctx := t.Context()
+	injector := NewInjector(ctx, ZeroConfig{})
+	if err := RegisterHandlers(ctx, injector); err != nil {
+		t.Fatal(err)
+	}
+	mux, err := ZeroConstructSingletons[*http.ServeMux](ctx, injector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	r.ContentLength = -1
+	r.Header.Set("Content-Type", contentType)
+	mux.ServeHTTP(w, r)
+	return w
+}
+
+func TestPutBlob(t *testing.T) {
+	if w := serve(t, "PUT", "/blobs/a", "application/octet-stream", "data"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "a:data") {
+		t.Fatalf("unexpected response %d %s", w.Code, w.Body)
+	}
+	if w := serve(t, "PUT", "/blobs/a", "application/octet-stream", "too much data"); w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413, got %d %s", w.Code, w.Body)
+	}
+}
+
+func TestUploadImage(t *testing.T) {
+	if w := serve(t, "POST", "/images", "image/png", "png"); w.Code != http.StatusOK || w.Body.String() != "3\n" {
+		t.Fatalf("unexpected response %d %s", w.Code, w.Body)
+	}
+	if w := serve(t, "POST", "/images", "text/plain", "png"); w.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("expected 415, got %d %s", w.Code, w.Body)
+	}
+}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".")
+	assert.NoError(t, err)
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph)
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	code := readFile(t)
+	assert.Contains(t, code, `mux.Handle("PUT /blobs/{key}", zero.RequestLimits(logger, encodeError, serverConfig.RequestTimeout, 8)(http.HandlerFunc(`)
+	assert.Contains(t, code, `if err := zero.CheckContentType(r, "image/*"); err != nil {`)
+	assert.Contains(t, code, "out, herr := r0.PutBlob(p0, r.Body)")
+	assert.Contains(t, code, "out, herr := r0.UploadImage(r.Body)")
+
+	goModTidy(t, dir)
+
+	cmd := exec.CommandContext(t.Context(), "go", "test", ".")
+	output, err := cmd.CombinedOutput()
+	assert.NoError(t, err, "Streaming request bodies should be limited:\n%s\n%s", output, code)
+}
+
+func TestCompressGeneration(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+import "strings"
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service {
+	return &Service{}
+}
+
+//zero:api GET /report compress=64B
+func (s *Service) Report() string {
+	return strings.Repeat("report ", 100)
+}
+
+//zero:api GET /summary compress etag
+func (s *Service) Summary() string {
+	return "summary"
+}
+
+var cli struct {
+	ZeroConfig
+}
+
+func main() {}
+`), 0644)
+	assert.NoError(t, err)
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main_test.go"), []byte(`package main
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReport(t *testing.T) {
+	ctx := t.Context()
+	injector := NewInjector(ctx, ZeroConfig{})
+	if err := RegisterHandlers(ctx, injector); err != nil {
+		t.Fatal(err)
+	}
+	mux, err := ZeroConstructSingletons[*http.ServeMux](ctx, injector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/report", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	mux.ServeHTTP(w, r)
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected gzip response, got %v", w.Header())
+	}
+	reader, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(body), "report report") {
+		t.Fatalf("unexpected response %s", body)
+	}
+}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".")
+	assert.NoError(t, err)
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph)
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	code := readFile(t)
+	assert.Contains(t, code, "contentEncodings, err := ZeroConstructSingletons[imp9c34c006eb3c10fa.ContentEncodings](ctx, injector)")
+	assert.Contains(t, code, `(zero.Compress(64, contentEncodings...)(http.HandlerFunc(`)
+	assert.Contains(t, code, `(zero.Compress(serverConfig.CompressMinSize, contentEncodings...)(zero.ETag(http.HandlerFunc(`)
+
+	goModTidy(t, dir)
+
+	cmd := exec.CommandContext(t.Context(), "go", "test", ".")
+	output, err := cmd.CombinedOutput()
+	assert.NoError(t, err, "Responses should be compressed:\n%s\n%s", output, code)
+}
+
+func TestTrailingSlashGeneration(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service {
+	return &Service{}
+}
+
+//zero:api GET /users slash=redirect
+func (s *Service) Users() string {
+	return "users"
+}
+
+//zero:api GET /tags slash=ignore
+func (s *Service) Tags() string {
+	return "tags"
+}
+
+//zero:api GET /items/ slash=strict
+func (s *Service) Items() string {
+	return "items"
+}
+
+var cli struct {
+	ZeroConfig
+}
+
+func main() {}
+`), 0644)
+	assert.NoError(t, err)
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main_test.go"), []byte(`package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTrailingSlash(t *testing.T) {
+	ctx := t.Context()
+	injector := NewInjector(ctx, ZeroConfig{})
+	if err := RegisterHandlers(ctx, injector); err != nil {
+		t.Fatal(err)
+	}
+	mux, err := ZeroConstructSingletons[*http.ServeMux](ctx, injector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		target   string
+		status   int
+		location string
+	}{
+		{"/users", http.StatusOK, ""},
+		{"/users/?q=1", http.StatusMovedPermanently, "/users?q=1"},
+		{"/tags", http.StatusOK, ""},
+		{"/tags/", http.StatusOK, ""},
+		{"/items/", http.StatusOK, ""},
+		{"/items", http.StatusNotFound, ""},
+		{"/items/other", http.StatusNotFound, ""},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", test.target, nil))
+		if w.Code != test.status || w.Header().Get("Location") != test.location {
+			t.Errorf("%s: expected %d %q, got %d %q", test.target, test.status, test.location, w.Code, w.Header().Get("Location"))
+		}
+	}
+}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".")
+	assert.NoError(t, err)
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph)
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	code := readFile(t)
+	assert.Contains(t, code, `mux.HandleFunc("GET /users/{$}", zero.RedirectTrailingSlash)`)
+	assert.Contains(t, code, `mux.Handle("GET /items", http.NotFoundHandler())`)
+
+	goModTidy(t, dir)
+
+	cmd := exec.CommandContext(t.Context(), "go", "test", ".")
+	output, err := cmd.CombinedOutput()
+	assert.NoError(t, err, "Trailing slashes should follow each API's policy:\n%s\n%s", output, code)
+}
+
+func TestMethodlessRouteGeneration(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service {
+	return &Service{}
+}
+
+//zero:api /health
+func (s *Service) Health() string {
+	return "OK"
+}
+
+//zero:api GET /health
+func (s *Service) GetHealth() string {
+	return "OK"
+}
+
+var cli struct {
+	ZeroConfig
+}
+
+func main() {}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".")
+	assert.NoError(t, err)
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph)
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	generatedCode := readFile(t)
+	for _, method := range []string{"POST", "PUT", "PATCH", "DELETE"} {
+		assert.Contains(t, generatedCode, `mux.Handle("`+method+` /health", a0Handler)`)
+	}
+	assert.NotContains(t, generatedCode, `mux.Handle("GET /health", a0Handler)`)
+	assert.Contains(t, generatedCode, `mux.Handle("GET /health", `)
+
+	goModTidy(t, dir)
+
+	cmd := exec.CommandContext(t.Context(), "go", "build", ".")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	assert.NoError(t, err, "Generated code should compile:\n%s", generatedCode)
+}
+
+func TestStatusResponseGeneration(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+import (
+	"net/http"
+
+	"github.com/alecthomas/zero"
+)
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service {
+	return &Service{}
+}
+
+type User struct {
+	Name string
+}
+
+//zero:api POST /users
+func (s *Service) CreateUser(user User) (zero.Status, User, error) {
+	return http.StatusCreated, user, nil
+}
+
+//zero:api POST /jobs
+func (s *Service) CreateJob() (int, string, error) {
+	return http.StatusAccepted, "queued", nil
+}
+
+var cli struct {
+	ZeroConfig
+}
+
+func main() {}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".")
+	assert.NoError(t, err)
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph)
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	generatedCode := readFile(t)
+	assert.Contains(t, generatedCode, "hstatus, out, herr := r0.CreateUser(p0)")
+	assert.Contains(t, generatedCode, "w = zero.WithStatus(w, int(hstatus))")
+
+	goModTidy(t, dir)
+
+	cmd := exec.CommandContext(t.Context(), "go", "build", ".")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	assert.NoError(t, err, "Generated code should compile:\n%s", generatedCode)
+}
+
+func TestHeaderParameterGeneration(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service {
+	return &Service{}
+}
+
+//zero:api GET /users/{id} header=apiKey:X-Api-Key,retries cookie=session
+func (s *Service) GetUser(id string, apiKey string, retries *int, session string) (string, error) {
+	return id, nil
+}
+
+var cli struct {
+	ZeroConfig
+}
+
+func main() {}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".")
+	assert.NoError(t, err)
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph)
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	generatedCode := readFile(t)
+	assert.Contains(t, generatedCode, `p1, err := zero.DecodeHeader[string](r, "X-Api-Key")`)
+	assert.Contains(t, generatedCode, `p2, err := zero.DecodeHeader[*int](r, "Retries")`)
+	assert.Contains(t, generatedCode, `p3, err := zero.DecodeCookie[string](r, "session")`)
+
+	goModTidy(t, dir)
+
+	cmd := exec.CommandContext(t.Context(), "go", "build", ".")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	assert.NoError(t, err, "Generated code should compile:\n%s", generatedCode)
+}
+
+func TestParamsStructGeneration(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service {
+	return &Service{}
+}
+
+type ListPostsParams struct {
+	UserID int      `+"`path:\"id\"`"+`
+	Limit  int      `+"`query:\"limit\"`"+`
+	Tags   []string `+"`query:\"tag\"`"+`
+}
+
+//zero:api GET /users/{id}/posts
+func (s *Service) ListPosts(params ListPostsParams) ([]string, error) {
+	return params.Tags, nil
+}
+
+var cli struct {
+	ZeroConfig
+}
+
+func main() {}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".")
+	assert.NoError(t, err)
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph)
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	generatedCode := readFile(t)
+	assert.Contains(t, generatedCode, "p0, err := zero.DecodeParams[ListPostsParams](r)")
+
+	goModTidy(t, dir)
+
+	cmd := exec.CommandContext(t.Context(), "go", "build", ".")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	assert.NoError(t, err, "Generated code should compile:\n%s", generatedCode)
+}
+
+func TestDocCommentGeneration(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+type Service struct{}
+
+// NewService creates the service.
+//
+//zero:provider
+func NewService() *Service {
+	return &Service{}
+}
+
+// ListUsers lists users.
+//
+// Users are sorted by name.
+//
+//zero:api GET /users
+func (s *Service) ListUsers() ([]string, error) {
+	return nil, nil
+}
+
+var cli struct {
+	ZeroConfig
+}
+
+func main() {}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".")
+	assert.NoError(t, err)
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph)
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	generatedCode := readFile(t)
+	source, err := os.ReadFile("zero.go")
+	assert.NoError(t, err)
+	assert.Contains(t, string(source), "// NewService creates the service.\n\tcase reflect.TypeOf((**Service)(nil)).Elem():")
+	assert.Contains(t, string(source), "// ListUsers lists users.\n\t//\n\t// Users are sorted by name.\n\tmux.Handle(\"GET /users\", ")
+
+	goModTidy(t, dir)
+
+	cmd := exec.CommandContext(t.Context(), "go", "build", ".")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	assert.NoError(t, err, "Generated code should compile:\n%s", generatedCode)
+}
+
+func TestLineDirectiveGeneration(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service {
+	return &Service{}
+}
+
+//zero:api GET /users/{id}
+func (s *Service) GetUser(id string) (string, error) {
+	return id, nil
+}
+
+var cli struct {
+	ZeroConfig
+}
+
+func main() {}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".")
+	assert.NoError(t, err)
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph)
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	generatedCode := readFile(t)
+	source, err := os.ReadFile("zero.go")
+	assert.NoError(t, err)
+	assert.Contains(t, string(source), "\n//line main.go:11\n\t\tout, herr := r0.GetUser(p0)\n//line zero.go:")
+	// The position in zero.go must be restored to the line following the directive.
+	lines := strings.Split(string(source), "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, "//line zero.go:") {
+			assert.Equal(t, fmt.Sprintf("//line zero.go:%d", i+2), line)
+		}
+	}
+
+	goModTidy(t, dir)
+
+	cmd := exec.CommandContext(t.Context(), "go", "build", ".")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	assert.NoError(t, err, "Generated code should compile:\n%s", generatedCode)
+}
+
+func TestTestHarnessGeneration(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+type Store interface {
+	Name(id string) string
+}
+
+type realStore struct{}
+
+func (realStore) Name(id string) string { return "real " + id }
+
+//zero:provider
+func NewStore() Store {
+	return realStore{}
+}
+
+type Service struct {
+	store Store
+}
+
+//zero:provider
+func NewService(store Store) *Service {
+	return &Service{store: store}
+}
+
+//zero:api GET /users/{id}
+func (s *Service) GetUser(id string) (string, error) {
+	return s.store.Name(id), nil
+}
+
+var cli struct {
+	ZeroConfig
+}
+
+func main() {}
+`), 0644)
+	assert.NoError(t, err)
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main_test.go"), []byte(`package main
+
+import (
+	"io"
+	"net/http"
+	"testing"
+)
+
+type fakeStore struct{}
+
+func (fakeStore) Name(id string) string { return "fake " + id }
+
+//zero:provider
+func NewFakeStore() (Store, error) {
+	return fakeStore{}, nil
+}
+
+func TestGetUser(t *testing.T) {
+	server := ZeroTestServer(t, ZeroConfig{})
+	resp, err := http.Get(server.URL + "/users/bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != "fake bob" {
+		t.Fatalf("unexpected response %q", body)
+	}
+}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".", depgraph.WithTests(true))
+	assert.NoError(t, err)
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph)
+	_ = w.Close()
+	assert.NoError(t, err)
+	assert.NotContains(t, readFile(t), "NewFakeStore")
+
+	w, err = os.Create("zero_test.go")
+	assert.NoError(t, err)
+	err = GenerateTest(w, graph)
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	harness, err := os.ReadFile("zero_test.go")
+	assert.NoError(t, err)
+	assert.Contains(t, string(harness), "o, err := NewFakeStore()")
+	assert.Contains(t, string(harness), "injector.singletons[reflect.TypeFor[Store]()] = o")
+
+	goModTidy(t, dir)
+
+	cmd := exec.CommandContext(t.Context(), "go", "test", ".")
+	output, err := cmd.CombinedOutput()
+	assert.NoError(t, err, "Test harness should pass:\n%s\n%s", output, harness)
+}
+
+func TestBenchmarkGeneration(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+import "fmt"
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service {
+	return &Service{}
+}
+
+//zero:api GET /users/{id}
+func (s *Service) GetUser(id int) (string, error) {
+	return fmt.Sprintf("user %d", id), nil
+}
+
+type ListPostsParams struct {
+	UserID string   `+"`path:\"user\"`"+`
+	Limit  int      `+"`query:\"limit\"`"+`
+	Tags   []string `+"`query:\"tag\"`"+`
+}
+
+//zero:api GET /users/{user}/posts
+func (s *Service) ListPosts(params ListPostsParams) ([]string, error) {
+	return params.Tags, nil
+}
+
+//zero:api POST /users
+func (s *Service) CreateUser() error {
+	return nil
+}
+
+var cli struct {
+	ZeroConfig
+}
+
+func main() {}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".", depgraph.WithTests(true))
+	assert.NoError(t, err)
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph)
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	w, err = os.Create("zero_test.go")
+	assert.NoError(t, err)
+	err = GenerateTest(w, graph, WithBenchmarks(true))
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	harness, err := os.ReadFile("zero_test.go")
+	assert.NoError(t, err)
+	assert.Contains(t, string(harness), `{"GET /users/{id}", "/users/1"},`)
+	assert.Contains(t, string(harness), `{"GET /users/{user}/posts", "/users/example/posts?limit=1&tag=example"},`)
+	assert.NotContains(t, string(harness), `"POST /users"`)
+
+	goModTidy(t, dir)
+
+	cmd := exec.CommandContext(t.Context(), "go", "test", "-run", "^$", "-bench", "BenchmarkZeroRoutes", "-benchtime", "10x", ".")
+	output, err := cmd.CombinedOutput()
+	assert.NoError(t, err, "Benchmarks should pass:\n%s\n%s", output, harness)
+	assert.Contains(t, string(output), "BenchmarkZeroRoutes/GET_/users/{id}")
+}
+
+func TestFastJSONGeneration(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+import "time"
+
+type Status string
+
+type Address struct {
+	Street string  `+"`json:\"street\"`"+`
+	Unit   *string `+"`json:\"unit,omitempty\"`"+`
+}
+
+type CreateUserRequest struct {
+	Name    string            `+"`json:\"name\"`"+`
+	Age     int               `+"`json:\"age,omitempty\"`"+`
+	Score   float32
+	Admin   bool              `+"`json:\"admin\"`"+`
+	Status  Status            `+"`json:\"status\"`"+`
+	Tags    []string          `+"`json:\"tags\"`"+`
+	Address *Address          `+"`json:\"address\"`"+`
+	Created time.Time         `+"`json:\"created\"`"+`
+	Meta    map[string]string `+"`json:\"meta,omitempty\"`"+`
+	Secret  string            `+"`json:\"-\"`"+`
+	private string
+}
+
+type Base struct {
+	Kind string
+}
+
+// Extra embeds Base, so is encoded with encoding/json.
+type Extra struct {
+	Base
+	Note string `+"`json:\"note\"`"+`
+}
+
+type User struct {
+	ID      int               `+"`json:\"id\"`"+`
+	Request CreateUserRequest `+"`json:\"request\"`"+`
+	Friends []*User           `+"`json:\"friends\"`"+`
+	Extra   Extra             `+"`json:\"extra\"`"+`
+}
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service {
+	return &Service{}
+}
+
+//zero:api POST /users
+func (s *Service) CreateUser(req CreateUserRequest) (*User, error) {
+	return &User{ID: 1, Request: req, Friends: []*User{{ID: 2}, nil}, Extra: Extra{Base: Base{Kind: "k"}}}, nil
+}
+
+//zero:api PUT /users/{id}/address
+func (s *Service) SetAddress(id int, address *Address) ([]Address, error) {
+	return []Address{*address}, nil
+}
+
+var cli struct {
+	ZeroConfig
+}
+
+func main() {}
+`), 0644)
+	assert.NoError(t, err)
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main_test.go"), []byte(`package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func serve(t *testing.T, method, path, body string) string {
+	t.Helper()
+	ctx := t.Context()
+	injector := NewInjector(ctx, ZeroConfig{})
+	if err := RegisterHandlers(ctx, injector); err != nil {
+		t.Fatal(err)
+	}
+	mux, err := ZeroConstructSingletons[*http.ServeMux](ctx, injector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return w.Body.String()
+}
+
+func TestCreateUser(t *testing.T) {
+	body := `+"`"+`{"name":"<bob>","AGE":42,"Score":1.5,"admin":true,"status":"active","tags":["a",null],"address":{"street":"x","unit":"2"},"created":"2024-01-02T03:04:05Z","meta":{"k":"v"},"Secret":"s","unknown":[1,{}]}`+"`"+`
+	var req CreateUserRequest
+	if err := json.Unmarshal([]byte(body), &req); err != nil {
+		t.Fatal(err)
+	}
+	user, _ := (&Service{}).CreateUser(req)
+	expected, err := json.Marshal(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := serve(t, "POST", "/users", body); actual != string(expected)+"\n" {
+		t.Fatalf("expected %s, got %s", expected, actual)
+	}
+}
+
+func TestSetAddress(t *testing.T) {
+	if actual := serve(t, "PUT", "/users/1/address", `+"`"+`{"street":"x"}`+"`"+`); actual != `+"`"+`[{"street":"x"}]`+"`"+`+"\n" {
+		t.Fatalf("unexpected response %s", actual)
+	}
+	if actual := serve(t, "PUT", "/users/1/address", `+"`"+`{"street":1}`+"`"+`); !strings.Contains(actual, "failed to decode JSON request body") {
+		t.Fatalf("unexpected response %s", actual)
+	}
+}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".")
+	assert.NoError(t, err)
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph, WithFastJSON(true))
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	code := readFile(t)
+	assert.Contains(t, code, "p0, err := zero.DecodeJSONRequest(r, zeroDecodeJSONCreateUserRequest)")
+	assert.Contains(t, code, "zero.JSONResponse[*User]{Value: out, Encode: zeroEncodeJSONUserPtr}")
+	assert.Contains(t, code, "zero.JSONResponse[[]Address]{Value: out, Encode: zeroEncodeJSONAddressSlice}")
+	assert.Contains(t, code, "func zeroDecodeJSONAddressPtr(d *zero.JSONDecoder, v **Address) {")
+	assert.Contains(t, code, "v.Status = Status(d.String())")
+	assert.Contains(t, code, "d.Value(&v.Created)")
+	assert.Contains(t, code, "e.Key(`\"request\":`)")
+	assert.Contains(t, code, "e.Value(&v.Extra)")
+	assert.NotContains(t, code, "zeroDecodeJSONUser(")
+
+	goModTidy(t, dir)
+
+	cmd := exec.CommandContext(t.Context(), "go", "test", ".")
+	output, err := cmd.CombinedOutput()
+	assert.NoError(t, err, "Fast JSON should match encoding/json:\n%s\n%s", output, code)
+}
+
+func TestIdempotencyGeneration(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service {
+	return &Service{}
+}
+
+type Payment struct {
+	Amount int
+}
+
+//zero:api POST /payments idempotent
+func (s *Service) CreatePayment(payment Payment) error {
+	return nil
+}
+
+//zero:api POST /refunds idempotent=1h
+func (s *Service) CreateRefund(payment Payment) error {
+	return nil
+}
+
+var cli struct {
+	ZeroConfig
+}
+
+func main() {}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".", depgraph.WithProviders("github.com/alecthomas/zero/providers/idempotency.NewMemoryStore"))
+	assert.NoError(t, err)
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph)
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	generatedCode := readFile(t)
+	assert.Contains(t, generatedCode, `a0m0p0 := ""`)
+	assert.Contains(t, generatedCode, `a1m0p0 := "1h"`)
+	_, wrapped, ok := strings.Cut(generatedCode, `zero.RequestLimits(logger, encodeError, serverConfig.RequestTimeout, serverConfig.MaxBodySize)(`)
+	assert.True(t, ok)
+	wrapped, _, _ = strings.Cut(wrapped, "\n")
+	assert.Contains(t, wrapped, `.Middleware(a1m0p0, a1m0p1, a1m0p2, a1m0p3)(`)
+
+	goModTidy(t, dir)
+
+	cmd := exec.CommandContext(t.Context(), "go", "build", ".")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	assert.NoError(t, err, "Generated code should compile:\n%s", generatedCode)
+}
+
+func TestETagGeneration(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service {
+	return &Service{}
+}
+
+type User struct {
+	Name string
+}
+
+//zero:api GET /users etag timeout=5s
+func (s *Service) ListUsers() ([]User, error) {
+	return nil, nil
+}
+
+var cli struct {
+	ZeroConfig
+}
+
+func main() {}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".")
+	assert.NoError(t, err)
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph)
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	generatedCode := readFile(t)
+	assert.Contains(t, generatedCode, `mux.Handle("GET /users", zero.RequestLimits(logger, encodeError, time.Duration(5000000000), serverConfig.MaxBodySize)(zero.ETag(http.HandlerFunc(`)
+
+	goModTidy(t, dir)
+
+	cmd := exec.CommandContext(t.Context(), "go", "build", ".")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	assert.NoError(t, err, "Generated code should compile:\n%s", generatedCode)
+}
+
+func TestPaginationGeneration(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+import "github.com/alecthomas/zero"
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service {
+	return &Service{}
+}
+
+type User struct {
+	Name string
+}
+
+//zero:api GET /users
+func (s *Service) ListUsers(page zero.PageRequest) (zero.Page[User], error) {
+	return zero.Page[User]{}, nil
+}
+
+var cli struct {
+	ZeroConfig
+}
+
+func main() {}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".")
+	assert.NoError(t, err)
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph)
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	generatedCode := readFile(t)
+	assert.Contains(t, generatedCode, `p0, err := zero.DecodePageRequest(r)`)
+	assert.Contains(t, generatedCode, "if herr == nil {\n\t\t\tout.Prepare(w, r)\n\t\t}\n\t\tencodeResponse(logger, r, w, encodeError, out, herr)")
+
+	goModTidy(t, dir)
+
+	cmd := exec.CommandContext(t.Context(), "go", "build", ".")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	assert.NoError(t, err, "Generated code should compile:\n%s", generatedCode)
+}
+
+func TestContextKeyGeneration(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+import (
+	"net/http"
+
+	"github.com/alecthomas/zero"
+)
+
+//zero:contextkey authenticated
+type Subject string
+
+//zero:middleware authenticated
+func Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(zero.WithContextValue(r.Context(), Subject(r.Header.Get("X-Subject")))))
+	})
+}
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service {
+	return &Service{}
+}
+
+//zero:api GET /users/{id} authenticated
+func (s *Service) GetUser(id string, subject Subject) (string, error) {
+	return string(subject), nil
+}
+
+var cli struct {
+	ZeroConfig
+}
+
+func main() {}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".")
+	assert.NoError(t, err)
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph)
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	generatedCode := readFile(t)
+	assert.Contains(t, generatedCode, `p1, ok := zero.ContextValue[Subject](r.Context())`)
+	assert.Contains(t, generatedCode, `encodeError(logger, w, "main.Subject was not set in the request context by authenticated middleware", http.StatusInternalServerError)`)
+	assert.Contains(t, generatedCode, `r0.GetUser(p0, p1)`)
+
+	goModTidy(t, dir)
+
+	cmd := exec.CommandContext(t.Context(), "go", "build", ".")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	assert.NoError(t, err, "Generated code should compile:\n%s", generatedCode)
+}
+
+func TestVersionedSubscriptionGeneration(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+import (
+	"context"
+
+	"github.com/alecthomas/zero/providers/pubsub"
+)
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service {
+	return &Service{}
+}
+
+type UserCreated struct {
+	Name string
+}
+
+//zero:subscribe version=1
+func (s *Service) OnUserCreatedV1(ctx context.Context, event pubsub.Event[UserCreated]) error {
+	return nil
+}
+
+//zero:subscribe version=2
+func (s *Service) OnUserCreated(ctx context.Context, event pubsub.Event[UserCreated]) error {
+	return nil
+}
+
+//zero:subscribe
+func (s *Service) AuditUserCreated(ctx context.Context, event pubsub.Event[UserCreated]) error {
+	return nil
+}
+
+var cli struct {
+	ZeroConfig
+}
+
+func main() {}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".")
+	assert.NoError(t, err)
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph)
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	generatedCode := readFile(t)
+	assert.Contains(t, generatedCode, `.Subscribe(ctx, imp57144815321973d3.VersionRouter[UserCreated]{`)
+	assert.Contains(t, generatedCode, `1: imp57144815321973d3.Track(subscriptions, "main.Service.OnUserCreatedV1", r0.OnUserCreatedV1),`)
+	assert.Contains(t, generatedCode, `2: imp57144815321973d3.Track(subscriptions, "main.Service.OnUserCreated", r0.OnUserCreated),`)
+	assert.Contains(t, generatedCode, `.Subscribe(ctx, imp57144815321973d3.Track(subscriptions, "main.Service.AuditUserCreated", r0.AuditUserCreated)); err != nil {`)
+
+	goModTidy(t, dir)
+
+	cmd := exec.CommandContext(t.Context(), "go", "build", ".")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	assert.NoError(t, err, "Generated code should compile:\n%s", generatedCode)
+}
+
+func TestSubscriptionConcurrencyGeneration(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+import (
+	"context"
+
+	"github.com/alecthomas/zero/providers/pubsub"
+)
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service {
+	return &Service{}
+}
+
+type UserCreated struct {
+	Name string
+}
+
+//zero:subscribe version=1 concurrency=2
+func (s *Service) OnUserCreatedV1(ctx context.Context, event pubsub.Event[UserCreated]) error {
+	return nil
+}
+
+//zero:subscribe version=2 concurrency=4 prefetch=8
+func (s *Service) OnUserCreated(ctx context.Context, event pubsub.Event[UserCreated]) error {
+	return nil
+}
+
+//zero:subscribe concurrency=3 dedupe=5m
+func (s *Service) AuditUserCreated(ctx context.Context, event pubsub.Event[UserCreated]) error {
+	return nil
+}
+
+type UserDeleted struct {
+	Name string
+}
+
+//zero:subscribe
+func (s *Service) OnUserDeleted(ctx context.Context, event pubsub.Event[UserDeleted]) error {
+	return nil
+}
+
+var cli struct {
+	ZeroConfig
+}
+
+func main() {}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".")
+	assert.NoError(t, err)
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph)
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	generatedCode := readFile(t)
+	assert.Contains(t, generatedCode, `.Subscribe(imp57144815321973d3.WithSubscribeOptions(ctx, imp57144815321973d3.SubscribeOptions{Concurrency: 4, Prefetch: 8}), imp57144815321973d3.VersionRouter[UserCreated]{`)
+	assert.Contains(t, generatedCode, `.Subscribe(imp57144815321973d3.WithSubscribeOptions(ctx, imp57144815321973d3.SubscribeOptions{Concurrency: 3, Dedupe: time.Duration(300000000000)}), imp57144815321973d3.Track(subscriptions, "main.Service.AuditUserCreated", r0.AuditUserCreated)); err != nil {`)
+	assert.Contains(t, generatedCode, `.Subscribe(ctx, imp57144815321973d3.Track(subscriptions, "main.Service.OnUserDeleted", r0.OnUserDeleted)); err != nil {`)
+
+	goModTidy(t, dir)
+
+	cmd := exec.CommandContext(t.Context(), "go", "build", ".")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	assert.NoError(t, err, "Generated code should compile:\n%s", generatedCode)
+}
+
+func TestBatchSubscriptionGeneration(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+import (
+	"context"
+
+	"github.com/alecthomas/zero"
+	"github.com/alecthomas/zero/providers/pubsub"
+)
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service {
+	return &Service{}
+}
+
+type PageView struct {
+	URL string
+}
+
+//zero:middleware traced
+func Trace(next zero.JobFunc) zero.JobFunc { return next }
+
+//zero:subscribe batch=100 window=2s
+func (s *Service) RecordPageViews(ctx context.Context, events []pubsub.Event[PageView]) error {
+	return nil
+}
+
+//zero:subscribe concurrency=2 batch=10 traced
+func (s *Service) AuditPageViews(ctx context.Context, events []pubsub.Event[PageView]) error {
+	return nil
+}
+
+var cli struct {
+	ZeroConfig
+}
+
+func main() {}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".")
+	assert.NoError(t, err)
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph)
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	generatedCode := readFile(t)
+	assert.Contains(t, generatedCode, `imp57144815321973d3.SubscribeBatch(ctx, topic`)
+	assert.Contains(t, generatedCode, `imp57144815321973d3.BatchOptions{Size: 100, Window: time.Duration(2000000000)}, imp57144815321973d3.TrackBatch(subscriptions, "main.Service.RecordPageViews", r0.RecordPageViews)); err != nil {`)
+	assert.Contains(t, generatedCode, `imp57144815321973d3.SubscribeBatch(imp57144815321973d3.WithSubscribeOptions(ctx, imp57144815321973d3.SubscribeOptions{Concurrency: 2}), topic`)
+	assert.Contains(t, generatedCode, `imp57144815321973d3.BatchOptions{Size: 10}, imp57144815321973d3.TrackBatch(subscriptions, "main.Service.AuditPageViews", func(ctx context.Context, events []imp57144815321973d3.Event[PageView]) error {`)
+
+	goModTidy(t, dir)
+
+	cmd := exec.CommandContext(t.Context(), "go", "build", ".")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	assert.NoError(t, err, "Generated code should compile:\n%s", generatedCode)
+}
+
+func TestCloudEventsGeneration(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/alecthomas/zero/providers/pubsub"
+)
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service {
+	return &Service{}
+}
+
+type UserCreated struct {
+	Name string
+}
+
+//zero:middleware authenticated
+func Auth(next http.Handler) http.Handler {
+	return next
+}
+
+//zero:api GET /users authenticated
+func (s *Service) Users() string {
+	return ""
+}
+
+//zero:subscribe
+func (s *Service) OnUserCreated(ctx context.Context, event pubsub.Event[UserCreated]) error {
+	return nil
+}
+
+var cli struct {
+	ZeroConfig
+}
+
+func main() {}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".")
+	assert.NoError(t, err)
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph, WithCloudEvents(true), WithCloudEventsLabels("authenticated maxbody=1KB"))
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	generatedCode := readFile(t)
+	_, handler, ok := strings.Cut(generatedCode, `mux.Handle("POST /events/user_created", `)
+	assert.True(t, ok, "CloudEvents endpoint should be registered:\n%s", generatedCode)
+	handler, _, _ = strings.Cut(handler, "\n")
+	assert.Contains(t, handler, `, 1024)(Auth(`, "Labels should select the middleware and request limits")
+	assert.Contains(t, handler, `.Handler[UserCreated](logger, encodeError, `)
+
+	goModTidy(t, dir)
+
+	cmd := exec.CommandContext(t.Context(), "go", "build", ".")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	assert.NoError(t, err, "Generated code should compile:\n%s", generatedCode)
+}
+
+func TestWorkflowGeneration(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+import (
+	"context"
+)
+
+type OrderState struct {
+	Order    string
+	Reserved bool
+}
+
+type OrderWorkflow struct{}
+
+//zero:provider
+func NewOrderWorkflow() *OrderWorkflow {
+	return &OrderWorkflow{}
+}
+
+//zero:step compensate=ReleaseStock
+func (o *OrderWorkflow) ReserveStock(ctx context.Context, state *OrderState) error {
+	state.Reserved = true
+	return nil
+}
+
+func (o *OrderWorkflow) ReleaseStock(ctx context.Context, state *OrderState) error {
+	state.Reserved = false
+	return nil
+}
+
+//zero:step
+func (o *OrderWorkflow) ChargeCard(ctx context.Context, state *OrderState) error {
+	return nil
+}
+
+var cli struct {
+	ZeroConfig
+}
+
+func main() {}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".", depgraph.WithProviders(
+		"github.com/alecthomas/zero/providers/workflow.NewMemoryStore",
+		"github.com/alecthomas/zero/providers/leases.NewMemoryLeaser",
+	))
+	assert.NoError(t, err)
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph)
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	generatedCode := readFile(t)
+	assert.Contains(t, generatedCode, `if err := RegisterWorkflows(ctx, injector); err != nil {`)
+	assert.Contains(t, generatedCode, `{Name: "ReserveStock", Run: r0.ReserveStock, Compensate: r0.ReleaseStock},`)
+	assert.Contains(t, generatedCode, `{Name: "ChargeCard", Run: r0.ChargeCard},`)
+
+	goModTidy(t, dir)
+
+	cmd := exec.CommandContext(t.Context(), "go", "build", ".")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	assert.NoError(t, err, "Generated code should compile:\n%s", generatedCode)
+}
+
+func TestWorkerGeneration(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+import (
+	"context"
+
+	"github.com/alecthomas/zero"
+	"github.com/alecthomas/zero/providers/queue"
+)
+
+type SendEmail struct {
+	To string
+}
+
+type Mailer struct{}
+
+//zero:provider
+func NewMailer() *Mailer {
+	return &Mailer{}
+}
+
+//zero:middleware traced
+func Trace(next zero.JobFunc) zero.JobFunc { return next }
+
+//zero:worker concurrency=4 attempts=10 backoff=2s visibility=1m traced
+func (m *Mailer) Send(ctx context.Context, job queue.Job[SendEmail]) error {
+	return nil
+}
+
+var cli struct {
+	ZeroConfig
+}
+
+func main() {}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".", depgraph.WithProviders("github.com/alecthomas/zero/providers/queue.NewMemoryStore"))
+	assert.NoError(t, err)
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph)
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	generatedCode := readFile(t)
+	assert.Contains(t, generatedCode, `if err := RegisterWorkers(ctx, injector); err != nil {`)
+	assert.Contains(t, generatedCode, `.WorkerOptions{Concurrency: 4, Visibility: time.Duration(60000000000), Retry: `)
+	assert.Contains(t, generatedCode, `.RetryPolicy{MaxAttempts: 10, Backoff: time.Duration(2000000000)}}`)
+	assert.Contains(t, generatedCode, `return Trace(func(ctx context.Context) error { return r0.Send(ctx, job) })(ctx)`)
+
+	goModTidy(t, dir)
+
+	cmd := exec.CommandContext(t.Context(), "go", "build", ".")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	assert.NoError(t, err, "Generated code should compile:\n%s", generatedCode)
+}
+
+func TestGRPCGeneration(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// GreeterServer is in the form generated by protoc-gen-go-grpc.
+type GreeterServer interface {
+	Greet(context.Context, *wrapperspb.StringValue) (*wrapperspb.StringValue, error)
+	mustEmbedUnimplementedGreeterServer()
+}
+
+type UnimplementedGreeterServer struct{}
+
+func (UnimplementedGreeterServer) Greet(context.Context, *wrapperspb.StringValue) (*wrapperspb.StringValue, error) {
+	return nil, status.Error(codes.Unimplemented, "method Greet not implemented")
+}
+func (UnimplementedGreeterServer) mustEmbedUnimplementedGreeterServer() {}
+
+var Greeter_ServiceDesc = grpc.ServiceDesc{ServiceName: "test.Greeter", HandlerType: (*GreeterServer)(nil)}
+
+func RegisterGreeterServer(s grpc.ServiceRegistrar, srv GreeterServer) {
+	s.RegisterService(&Greeter_ServiceDesc, srv)
+}
+
+type Service struct {
+	UnimplementedGreeterServer
+}
+
+//zero:provider
+func NewService() *Service {
+	return &Service{}
+}
+
+//zero:api POST /greet
+func (s *Service) Greet(ctx context.Context, name *wrapperspb.StringValue) (*wrapperspb.StringValue, error) {
+	return wrapperspb.String("Hello " + name.Value), nil
+}
+
+var cli struct {
+	ZeroConfig
+}
+
+func main() {}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(graph.GRPCServices))
+	assert.Equal(t, "test.RegisterGreeterServer", graph.GRPCServices[0].Register.FullName())
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph)
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	generatedCode := readFile(t)
+	assert.Contains(t, generatedCode, `if err := zeroDecodeProtoJSON(r, p1); err != nil {`)
+	assert.Contains(t, generatedCode, `encodeResponse(logger, r, w, encodeError, zeroProtoJSON{out}, zeroGRPCError(herr))`)
+	assert.Contains(t, generatedCode, `RegisterGreeterServer(grpcServer, grpcService0)`)
+
+	goModTidy(t, dir)
+
+	cmd := exec.CommandContext(t.Context(), "go", "build", ".")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	assert.NoError(t, err, "Generated code should compile:\n%s", generatedCode)
+}
+
+func stableKeys[V any](m map[string]V) []string {
+	return slices.Sorted(maps.Keys(m))
+}
+
+func TestTenantGeneration(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+import (
+	"github.com/alecthomas/zero/providers/tenant"
+)
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service {
+	return &Service{}
+}
+
+//zero:api GET /projects tenant
+func (s *Service) Projects(id tenant.ID) (string, error) {
+	return string(id), nil
+}
+
+var cli struct {
+	ZeroConfig
+}
+
+func main() {}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".")
+	assert.NoError(t, err)
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph)
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	generatedCode := readFile(t)
+	assert.Contains(t, generatedCode, `.NewResolver(p0)`)
+	assert.Contains(t, generatedCode, `.Middleware(a0m0p0, a0m0p1, a0m0p2)`)
+	assert.Contains(t, generatedCode, `"tenant.ID was not set in the request context by tenant middleware"`)
+
+	goModTidy(t, dir)
+
+	cmd := exec.CommandContext(t.Context(), "go", "build", ".")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	assert.NoError(t, err, "Generated code should compile:\n%s", generatedCode)
+}
+
+func TestFeatureFlagGeneration(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service {
+	return &Service{}
+}
+
+//zero:api POST /checkout flag=new-checkout
+func (s *Service) Checkout() error {
+	return nil
+}
+
+var cli struct {
+	ZeroConfig
+}
+
+func main() {}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".", depgraph.WithProviders("github.com/alecthomas/zero/providers/flags.NewStaticClient"))
+	assert.NoError(t, err)
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph)
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	generatedCode := readFile(t)
+	assert.Contains(t, generatedCode, `a0m0p0 := "new-checkout"`)
+	assert.Contains(t, generatedCode, `.Middleware(a0m0p0, a0m0p1, a0m0p2, a0m0p3)`)
+	assert.Contains(t, generatedCode, `.NewStaticClient(p0)`)
+
+	goModTidy(t, dir)
+
+	cmd := exec.CommandContext(t.Context(), "go", "build", ".")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	assert.NoError(t, err, "Generated code should compile:\n%s", generatedCode)
+}
+
+func TestLogBodyGeneration(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+type Login struct {
+	Username string
+	Password string `+"`json:\"password\" log:\"-\"`"+`
+}
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service {
+	return &Service{}
+}
+
+//zero:api POST /login logbody
+func (s *Service) Login(login Login) error {
+	return nil
+}
+
+var cli struct {
+	ZeroConfig
+}
+
+func main() {}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".")
+	assert.NoError(t, err)
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph)
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	generatedCode := readFile(t)
+	assert.Contains(t, generatedCode, `a0m0p0 := ""`)
+	assert.Contains(t, generatedCode, `a0m0p1 := zero.RedactedFields{"password"}`)
+	assert.Contains(t, generatedCode, `.BodyMiddleware(a0m0p0, a0m0p1, a0m0p2, a0m0p3)`)
+
+	goModTidy(t, dir)
+
+	cmd := exec.CommandContext(t.Context(), "go", "build", ".")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	assert.NoError(t, err, "Generated code should compile:\n%s", generatedCode)
+}
+
+func TestCLIGeneration(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecthomas/kong"
+	"github.com/alecthomas/zero"
+	"github.com/alecthomas/zero/providers/pubsub"
+)
+
+type Service struct {
+	topic pubsub.Topic[UserCreated]
+}
+
+//zero:provider
+func NewService(topic pubsub.Topic[UserCreated]) *Service {
+	return &Service{topic: topic}
+}
+
+type UserCreated struct {
+	Name string
+}
+
+//zero:middleware traced
+func Trace(next zero.JobFunc) zero.JobFunc {
+	return func(ctx context.Context) error {
+		fmt.Println("traced")
+		return next(ctx)
+	}
+}
+
+//zero:api GET /users
+func (s *Service) ListUsers() ([]string, error) {
+	return nil, nil
+}
+
+//zero:cron 5m traced
+func (s *Service) Cleanup(ctx context.Context) error {
+	fmt.Println("cleaned up")
+	return nil
+}
+
+var cli ZeroCLI
+
+func main() {
+	kctx := kong.Parse(&cli)
+	kctx.FatalIfErrorf(kctx.Run())
+}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".", depgraph.WithProviders(
+		"github.com/alecthomas/zero/providers/cron.NewScheduler",
+		"github.com/alecthomas/zero/providers/cron.NewMemoryHistory",
+		"github.com/alecthomas/zero/providers/leases.NewMemoryLeaser",
+	))
+	assert.NoError(t, err)
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph, WithCLI([]byte(`{"swagger": "2.0"}`)))
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	generatedCode := readFile(t)
+	assert.Contains(t, generatedCode, "type ZeroCLI struct {")
+	assert.NotContains(t, generatedCode, "ZeroMigrateCmd")
+
+	goModTidy(t, dir)
+
+	cmd := exec.CommandContext(t.Context(), "go", "build", "-o", "service", ".")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	assert.NoError(t, err, "Generated code should compile:\n%s", generatedCode)
+
+	run := func(args ...string) string {
+		t.Helper()
+		output, err := exec.CommandContext(t.Context(), "./service", args...).CombinedOutput()
+		assert.NoError(t, err, "%s", output)
+		return string(output)
+	}
+	assert.Equal(t, "METHOD  PATTERN  HANDLER                    LABELS  MIDDLEWARE\nGET     /users   (*test.Service).ListUsers  -       -\n", run("routes"))
+	assert.Equal(t, "{\"swagger\": \"2.0\"}\n", run("openapi"))
+	assert.Equal(t, "traced\ncleaned up\n", run("cron", "run", "*test.Service.Cleanup"))
+	assert.Contains(t, run("completions", "bash"), "'cron:run') cmdpath='cron run' ;;")
+	assert.Contains(t, run("completions", "fish"), "-a '*test.Service.Cleanup'")
+	assert.Contains(t, run("man"), ".TH SERVICE 1\n")
+	run("publish", "user_created", `{"Name": "Bob"}`)
+	output, err := exec.CommandContext(t.Context(), "./service", "publish", "user_created", `{`).CombinedOutput()
+	assert.Error(t, err)
+	assert.Contains(t, string(output), "invalid payload for topic user_created")
+}
+
+func TestCLIConfigGeneration(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+import (
+	"time"
+
+	"github.com/alecthomas/kong"
+)
+
+//zero:config prefix="app-"
+type Config struct {
+	DSN     string            `+"`"+`secret:"" env:"APP_DSN"`+"`"+`
+	Token   string            `+"`"+`secret:"" rename:"key"`+"`"+`
+	Unset   string            `+"`"+`secret:""`+"`"+`
+	Hosts   []string          `+"`"+`default:"a,b"`+"`"+`
+	Labels  map[string]string `+"`"+`default:"x=1;w=2"`+"`"+`
+	Timeout time.Duration     `+"`"+`default:"5s"`+"`"+`
+}
+
+type Service struct{}
+
+//zero:provider
+func NewService(config Config) *Service {
+	return &Service{}
+}
+
+var cli ZeroCLI
+
+func main() {
+	kctx := kong.Parse(&cli)
+	kctx.FatalIfErrorf(kctx.Run())
+}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".", depgraph.WithRoots("*test.Service"))
+	assert.NoError(t, err)
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph, WithCLI(nil))
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	generatedCode := readFile(t)
+	assert.Contains(t, generatedCode, "type ZeroConfigCmd struct{}")
+
+	goModTidy(t, dir)
+
+	cmd := exec.CommandContext(t.Context(), "go", "build", "-o", "service", ".")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	assert.NoError(t, err, "Generated code should compile:\n%s", generatedCode)
+
+	cmd = exec.CommandContext(t.Context(), "./service", "--app-key=hunter2", "--app-hosts=c,d", "config")
+	cmd.Env = append(os.Environ(), "APP_DSN=postgres://user:password@db:5432/app")
+	output, err := cmd.Output()
+	assert.NoError(t, err)
+	assert.Equal(t, ""+
+		"FLAG           VALUE\n"+
+		"--app-dsn      postgres://user:xxxxx@db:5432/app\n"+
+		"--app-hosts    c,d\n"+
+		"--app-labels   w=2;x=1\n"+
+		"--app-timeout  5s\n"+
+		"--app-token    ********\n"+
+		"--app-unset    \n", string(output))
+}
+
+func TestRenamedConfigGeneration(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/alecthomas/kong"
+)
+
+type Network struct {
+	Hosts []string `+"`"+`sep:";" rename:"host"`+"`"+`
+}
+
+//zero:config prefix="server-"
+type Config struct {
+	Network `+"`"+`embed:"" prefix:"net-"`+"`"+`
+	Bind string `+"`"+`default:"localhost:8080" env:"SERVER_BIND" rename:"listen,addr" renameenv:"SERVER_LISTEN"`+"`"+`
+}
+
+type Service struct {
+	config Config
+}
+
+//zero:provider
+func NewService(config Config) *Service {
+	return &Service{config: config}
+}
+
+var cli struct {
+	ZeroConfig
+}
+
+func main() {
+	kong.Parse(&cli)
+	service, err := ZeroConstruct[*Service](context.Background(), cli.ZeroConfig)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(service.config.Bind, strings.Join(service.config.Hosts, "|"))
+}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".", depgraph.WithRoots("*test.Service"))
+	assert.NoError(t, err)
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph)
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	generatedCode := readFile(t)
+	assert.Contains(t, generatedCode, "`name:\"server-listen\" aliases:\"server-addr\" env:\"SERVER_LISTEN\" hidden:\"\"` // Former name of --server-bind.")
+	assert.Contains(t, generatedCode, "`name:\"server-net-host\" sep:\";\" hidden:\"\"` // Former name of --server-net-hosts.")
+	assert.Contains(t, generatedCode, "config.applyRenames()")
+
+	goModTidy(t, dir)
+
+	cmd := exec.CommandContext(t.Context(), "go", "build", "-o", "service", ".")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	assert.NoError(t, err, "Generated code should compile:\n%s", generatedCode)
+
+	run := func(env []string, args ...string) (string, string) {
+		t.Helper()
+		cmd := exec.CommandContext(t.Context(), "./service", args...)
+		cmd.Env = append(os.Environ(), env...)
+		var stderr strings.Builder
+		cmd.Stderr = &stderr
+		output, err := cmd.Output()
+		assert.NoError(t, err, "%s", stderr.String())
+		return string(output), stderr.String()
+	}
+	output, warnings := run(nil)
+	assert.Equal(t, "localhost:8080 \n", output)
+	assert.Equal(t, "", warnings)
+
+	output, warnings = run(nil, "--server-bind=:80", "--server-net-hosts=a;b")
+	assert.Equal(t, ":80 a|b\n", output)
+	assert.Equal(t, "", warnings)
+
+	output, warnings = run(nil, "--server-listen=:81", "--server-net-host=c;d")
+	assert.Equal(t, ":81 c|d\n", output)
+	assert.Contains(t, warnings, "--server-listen, --server-addr, $SERVER_LISTEN is deprecated, use --server-bind instead")
+	assert.Contains(t, warnings, "--server-net-host is deprecated, use --server-net-hosts instead")
+
+	output, _ = run(nil, "--server-addr=:82")
+	assert.Equal(t, ":82 \n", output)
+
+	output, warnings = run([]string{"SERVER_LISTEN=:83"})
+	assert.Equal(t, ":83 \n", output)
+	assert.Contains(t, warnings, "is deprecated, use --server-bind instead")
+}
+
+func TestValidateConfigGeneration(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/alecthomas/kong"
+)
+
+//zero:config prefix="server-"
+type ServerConfig struct {
+	Bind string `+"`"+`default:"localhost:8080"`+"`"+`
+}
+
+func (s ServerConfig) Validate() error {
+	if s.Bind == "" {
+		return errors.New("--server-bind is required")
+	}
+	return nil
+}
+
+//zero:config prefix="db-"
+type DBConfig struct {
+	Pool int `+"`"+`default:"1"`+"`"+`
+}
+
+func (d *DBConfig) Validate() error {
+	if d.Pool < 1 {
+		return errors.New("--db-pool must be positive")
+	}
+	return nil
+}
+
+type Service struct {
+	server ServerConfig
+	db     DBConfig
+}
+
+//zero:provider
+func NewService(server ServerConfig, db DBConfig) *Service {
+	return &Service{server: server, db: db}
+}
+
+var cli struct {
+	// Not embedded, so that Kong does not validate the config while parsing.
+	Config ZeroConfig `+"`"+`embed:""`+"`"+`
+}
+
+func main() {
+	kong.Parse(&cli)
+	service, err := ZeroConstruct[*Service](context.Background(), cli.Config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Println(service.server.Bind, service.db.Pool)
+}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".", depgraph.WithRoots("*test.Service"))
+	assert.NoError(t, err)
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph)
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	generatedCode := readFile(t)
+	assert.Contains(t, generatedCode, "func (c *ZeroConfig) Validate() error {")
+
+	goModTidy(t, dir)
+
+	cmd := exec.CommandContext(t.Context(), "go", "build", "-o", "service", ".")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	assert.NoError(t, err, "Generated code should compile:\n%s", generatedCode)
+
+	output, err := exec.CommandContext(t.Context(), "./service", "--server-bind=:80", "--db-pool=4").CombinedOutput()
+	assert.NoError(t, err, "%s", output)
+	assert.Equal(t, ":80 4\n", string(output))
+
+	// Every invalid config is reported, before any provider is called.
+	output, err = exec.CommandContext(t.Context(), "./service", "--server-bind=", "--db-pool=0").CombinedOutput()
+	assert.Error(t, err)
+	assert.Equal(t, "invalid configuration: test.DBConfig: --db-pool must be positive\n"+
+		"test.ServerConfig: --server-bind is required\n", string(output))
+}
+
+func TestIterSeqGeneration(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+)
+
+type Entry struct{ Key, Value string }
+
+//zero:provider multi
+func BuiltinEntries() iter.Seq[Entry] {
+	return slices.Values([]Entry{{"a", "1"}})
+}
+
+//zero:provider multi
+func ExtraEntries() iter.Seq[Entry] {
+	return slices.Values([]Entry{{"b", "2"}})
+}
+
+//zero:provider
+func Names() []string { return []string{"x", "y"} }
+
+//zero:provider
+func Values[T any](items []T) iter.Seq[T] {
+	return slices.Values(items)
+}
+
+//zero:provider
+func Logger() *slog.Logger { return slog.Default() }
+
+//zero:provider
+func Loggers(logger *slog.Logger) iter.Seq[*slog.Logger] {
+	return slices.Values([]*slog.Logger{logger})
+}
+
+//zero:provider
+func Ports() iter.Seq2[string, int] {
+	return maps.All(map[string]int{"http": 80})
+}
+
+type Service struct{ summary string }
+
+//zero:provider
+func NewService(entries iter.Seq[Entry], names iter.Seq[string], ports iter.Seq2[string, int], loggers iter.Seq[*slog.Logger]) *Service {
+	var parts []string
+	for entry := range entries {
+		parts = append(parts, entry.Key+"="+entry.Value)
+	}
+	parts = append(parts, slices.Collect(names)...)
+	for name, port := range ports {
+		parts = append(parts, fmt.Sprintf("%s:%d", name, port))
+	}
+	parts = append(parts, fmt.Sprintf("loggers=%d", len(slices.Collect(loggers))))
+	slices.Sort(parts)
+	return &Service{summary: strings.Join(parts, " ")}
+}
+
+func main() {
+	service, err := ZeroConstruct[*Service](context.Background(), ZeroConfig{})
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(service.summary)
+}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".", depgraph.WithRoots("*test.Service"))
+	assert.NoError(t, err)
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph)
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	generatedCode := readFile(t)
+	// Type arguments from other packages are qualified by package name, not path.
+	assert.Contains(t, generatedCode, "iter.Seq[*slog.Logger]")
+	assert.NotContains(t, generatedCode, "log/slog.Logger")
+
+	goModTidy(t, dir)
+
+	output, err := exec.CommandContext(t.Context(), "go", "run", ".").CombinedOutput()
+	assert.NoError(t, err, "%s\n%s", output, generatedCode)
+	assert.Equal(t, "a=1 b=2 http:80 loggers=1 x y\n", string(output))
+}
+
+func TestConstructionErrorChain(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+type DAL struct{}
+
+//zero:provider
+func NewDAL() (*DAL, error) {
+	return nil, errors.New("dial tcp: connection refused")
+}
+
+//zero:provider
+func NewLogger(dal *DAL) *slog.Logger { return slog.Default() }
+
+type Service struct{}
+
+//zero:provider
+func NewService(logger *slog.Logger) *Service { return &Service{} }
+
+type Server struct{}
+
+//zero:provider
+func NewServer(service *Service) *Server { return &Server{} }
+
+func main() {
+	_, err := ZeroConstruct[*Server](context.Background(), ZeroConfig{})
+	fmt.Fprintln(os.Stderr, err)
+}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".", depgraph.WithRoots("*test.Server"))
+	assert.NoError(t, err)
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph)
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	generatedCode := readFile(t)
+	goModTidy(t, dir)
+
+	output, err := exec.CommandContext(t.Context(), "go", "run", ".").CombinedOutput()
+	assert.NoError(t, err, "%s\n%s", output, generatedCode)
+	assert.Equal(t, "*Server -> *Service -> *slog.Logger -> *DAL: dial tcp: connection refused\n", string(output))
+}
+
+func TestParallelConstructionGeneration(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Both providers must be waiting on the barrier at the same time to proceed.
+var barrier sync.WaitGroup
+
+func await() error {
+	barrier.Done()
+	done := make(chan struct{})
+	go func() { barrier.Wait(); close(done) }()
+	select {
+	case <-done:
+		return nil
+	case <-time.After(10 * time.Second):
+		return errors.New("not constructed concurrently")
+	}
+}
+
+type DB struct{}
+
+//zero:provider
+func NewDB() (*DB, error) { return &DB{}, await() }
+
+type Topic[T any] struct{}
+
+//zero:provider
+func NewTopic[T any]() (*Topic[T], error) { return &Topic[T]{}, await() }
+
+type User struct{}
+
+type Service struct{}
+
+//zero:provider
+func NewService(db *DB, topic *Topic[User]) (*Service, error) {
+	return nil, errors.New("constructed after its dependencies")
+}
+
+func main() {
+	barrier.Add(2)
+	err := Run(context.Background(), ZeroConfig{})
+	fmt.Fprintln(os.Stderr, err)
+}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".", depgraph.WithRoots("*test.Service"))
+	assert.NoError(t, err)
+	assert.Equal(t, "[[*test.DB *test.Topic[test.User]] [*test.Service]]", fmt.Sprint(constructionLevels(graph, constructedRoots(graph))))
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph, WithParallelConstruction(true))
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	generatedCode := readFile(t)
+	assert.Contains(t, generatedCode, "func() error { _, err := ZeroConstructSingletons[*Topic[User]](ctx, injector); return err },")
+	assert.Contains(t, generatedCode, "mu         sync.Mutex")
+
+	goModTidy(t, dir)
+
+	output, err := exec.CommandContext(t.Context(), "go", "run", ".").CombinedOutput()
+	assert.NoError(t, err, "%s\n%s", output, generatedCode)
+	assert.Equal(t, "failed to construct service: *Service: constructed after its dependencies\n", string(output))
+}
+
+func TestConstructionTracingGeneration(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+)
+
+//zero:provider
+func Logger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+			if attr.Key == slog.TimeKey || attr.Key == "elapsed" {
+				return slog.Attr{}
+			}
+			return attr
+		},
+	}))
+}
+
+type Cache struct{}
+
+//zero:provider
+func NewCache() *Cache { return &Cache{} }
+
+type DAL struct{}
+
+//zero:provider
+func NewDAL(cache *Cache) (*DAL, error) {
+	time.Sleep(time.Minute)
+	return &DAL{}, nil
+}
+
+type Service struct{}
+
+//zero:provider
+func NewService(logger *slog.Logger, dal *DAL) *Service { return &Service{} }
+
+func main() {
+	_, err := ZeroConstruct[*Service](context.Background(), ZeroConfig{ConstructionTimeout: 100 * time.Millisecond})
+	fmt.Println(err)
+}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".", depgraph.WithRoots("*test.Service"))
+	assert.NoError(t, err)
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph, WithConstructionTracing(true))
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	generatedCode := readFile(t)
+	goModTidy(t, dir)
+
+	output, err := exec.CommandContext(t.Context(), "go", "run", ".").CombinedOutput()
+	assert.NoError(t, err, "%s\n%s", output, generatedCode)
+	// The logger is not traced, as tracing uses it.
+	assert.Equal(t, `level=DEBUG msg=Constructing type=*Cache provider="NewCache (main.go:27)"
+level=DEBUG msg=Constructed type=*Cache provider="NewCache (main.go:27)"
+level=DEBUG msg=Constructing type=*DAL provider="NewDAL (main.go:32)"
+level=DEBUG msg="Construction failed" type=*DAL provider="NewDAL (main.go:32)" error="NewDAL (main.go:32) did not return within 100ms"
+*Service -> *DAL: NewDAL (main.go:32) did not return within 100ms
+`, string(output))
+}
+
+func TestLibraryGeneration(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "store.go"), []byte(`package store
+
+import "context"
+
+//zero:config prefix="store-"
+type Config struct {
+	Table string `+"`default:\"users\"`"+`
+}
+
+type Store struct{ table string }
+
+//zero:provider
+func NewStore(config Config) *Store { return &Store{table: config.Table} }
+
+type Cache struct{ store *Store }
+
+//zero:provider
+func NewCache(store *Store) *Cache { return &Cache{store: store} }
+
+func (c *Cache) Table() string { return c.store.table }
+
+// Handlers of a library are registered by the services importing it.
+//
+//zero:api GET /users
+func (s *Store) List(ctx context.Context) ([]string, error) { return nil, nil }
+`), 0644)
+	assert.NoError(t, err)
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "cmd", "app"), 0750))
+	//nolint
+	err = os.WriteFile(filepath.Join(dir, "cmd", "app", "main.go"), []byte(`package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecthomas/kong"
+
+	store "test"
+)
+
+func main() {
+	var config store.ZeroConfig
+	if _, err := kong.Must(&config).Parse([]string{"--store-table=accounts"}); err != nil {
+		panic(err)
+	}
+	cache, err := store.ZeroConstruct[*store.Cache](context.Background(), config)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(cache.Table())
+}
+`), 0644)
+	assert.NoError(t, err)
+
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".", depgraph.WithLibrary(true))
+	assert.NoError(t, err)
+	assert.True(t, graph.Library)
+	assert.Equal(t, []string{"*test.Cache", "*test.Store"}, graph.Roots)
+	assert.Equal(t, 0, len(graph.APIs))
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph, WithCLI(nil))
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	generatedCode := readFile(t)
+	assert.Contains(t, generatedCode, "func ZeroConstructSingletons[T any](ctx context.Context, injector *Injector) (out T, err error) {")
+	assert.NotContains(t, generatedCode, "func RegisterHandlers(")
+	assert.NotContains(t, generatedCode, "func Run(")
+	assert.NotContains(t, generatedCode, "ZeroCLI")
+	assert.NotContains(t, generatedCode, "net/http")
+	goModTidy(t, dir)
+
+	output, err := exec.CommandContext(t.Context(), "go", "run", "./cmd/app").CombinedOutput()
+	assert.NoError(t, err, "%s\n%s", output, generatedCode)
+	assert.Equal(t, "accounts\n", string(output))
+}
+
+func TestCLIMigrateGeneration(t *testing.T) {
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	copyFile(t, "testdata/main.go", filepath.Join(dir, "main.go"))
+	createGoMod(t, filepath.Join(cwd, "../.."), dir)
+
+	t.Chdir(dir)
+
+	graph, err := depgraph.Analyse(t.Context(), ".", depgraph.WithProviders(
+		"github.com/alecthomas/zero/providers/sql.New",
+		"github.com/alecthomas/zero/providers/leases.NewMemoryLeaser",
+	))
+	assert.NoError(t, err)
+
+	w, err := os.Create("zero.go")
+	assert.NoError(t, err)
+	err = Generate(w, graph, WithCLI(nil))
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	generatedCode := readFile(t)
+	assert.Contains(t, generatedCode, "Migrate ZeroMigrateCmd `cmd:\"\" help:\"Apply SQL migrations.\"`")
+	assert.Contains(t, generatedCode, ".Migrate = true")
+	assert.Contains(t, generatedCode, "db, err := ZeroConstructSingletons[*sql.DB](ctx, injector)")
+
+	goModTidy(t, dir)
+
+	cmd := exec.CommandContext(t.Context(), "go", "build", ".")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	assert.NoError(t, err, "Generated code should compile:\n%s", generatedCode)
+}
+
+func TestMerge(t *testing.T) {
+	existing := `// Code generated by zero. DO NOT EDIT.
+package main
+
+import (
+  "context"
+  "log/slog"
+  impaaaa "example.com/old"
+)
+
+// ZeroConfig contains combined Kong configuration for all types constructable by the [Injector].
+type ZeroConfig struct {
+	Config impaaaa.Config
+}
+
+func NewInjector(ctx context.Context, config ZeroConfig) *Injector {
+	return &Injector{config: config}
+}
+
+func RegisterHandlers(ctx context.Context, injector *Injector) error {
+	slog.Info("old")
+//line main.go:10
+	handler()
+//line zero.go:23
+	return nil
+}
+
+func Run(ctx context.Context, config ZeroConfig) error {
+	return nil
+}
+`
+	generated := `// Code generated by zero. DO NOT EDIT.
+package main
+
+import (
+  "context"
+  "fmt"
+  impbbbb "example.com/new"
+)
+
+// ZeroConfig contains combined Kong configuration for all types constructable by the [Injector].
+type ZeroConfig struct {
+	Config impbbbb.Config
+}
+
+func NewInjector(ctx context.Context, config ZeroConfig) *Injector {
+	return &Injector{config: config}
+}
+
+// RegisterHandlers registers all APIs.
+func RegisterHandlers(ctx context.Context, injector *Injector) error {
+	fmt.Println("new")
+	fmt.Println("new")
+//line main.go:12
+	handler()
+//line zero.go:26
+	return nil
+}
+
+func Run(ctx context.Context, config ZeroConfig) error {
+	return fmt.Errorf("new")
+}
+`
+	merged, err := Merge([]byte(existing), []byte(generated), SectionHandlers, SectionInjector, SectionOpenAPI)
+	assert.NoError(t, err)
+	assert.Equal(t, generated, string(merged))
+
+	merged, err = Merge([]byte(existing), []byte(generated), SectionInjector)
+	assert.NoError(t, err)
+	assert.Equal(t, `// Code generated by zero. DO NOT EDIT.
+package main
+
+import (
+  "context"
+  "fmt"
+  impbbbb "example.com/new"
+  "log/slog"
+)
+
+// ZeroConfig contains combined Kong configuration for all types constructable by the [Injector].
+type ZeroConfig struct {
+	Config impbbbb.Config
+}
+
+func NewInjector(ctx context.Context, config ZeroConfig) *Injector {
+	return &Injector{config: config}
+}
+
+func RegisterHandlers(ctx context.Context, injector *Injector) error {
+	slog.Info("old")
+//line main.go:10
+	handler()
+//line zero.go:25
+	return nil
+}
+
+func Run(ctx context.Context, config ZeroConfig) error {
+	return fmt.Errorf("new")
+}
+`, string(merged))
+
+	merged, err = Merge([]byte(existing), []byte(generated), SectionHandlers)
+	assert.NoError(t, err)
+	assert.Contains(t, string(merged), "  impaaaa \"example.com/old\"\n")
+	assert.NotContains(t, string(merged), "example.com/new")
+	assert.Contains(t, string(merged), "\tConfig impaaaa.Config\n")
+
+	_, err = Merge([]byte("package"), []byte(generated), SectionHandlers)
+	assert.EqualError(t, err, "failed to parse existing generated code: existing.go:1:8: expected 'IDENT', found 'EOF'")
+}
+
+func TestBuildConstraint(t *testing.T) {
+	assert.Equal(t, "prod", buildConstraint([]string{"prod"}))
+	assert.Equal(t, "prod && !dev", buildConstraint([]string{"prod", "!dev"}))
+	assert.Equal(t, "prod && (linux || darwin)", buildConstraint([]string{"prod", "linux || darwin"}))
 }
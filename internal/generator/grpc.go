@@ -0,0 +1,185 @@
+package generator
+
+import (
+	"fmt"
+	"go/types"
+
+	"github.com/alecthomas/zero/internal/codewriter"
+	"github.com/alecthomas/zero/internal/depgraph"
+)
+
+const (
+	grpcConfigType = "github.com/alecthomas/zero/providers/grpc.Config"
+	grpcServerType = "*google.golang.org/grpc.Server"
+)
+
+// grpcHTTPStatus maps gRPC status codes to HTTP status codes in the same way as grpc-gateway, with all other codes
+// mapping to 500.
+var grpcHTTPStatus = []struct {
+	code   string
+	status string
+}{
+	{"Canceled", "499"},
+	{"InvalidArgument", "http.StatusBadRequest"},
+	{"DeadlineExceeded", "http.StatusGatewayTimeout"},
+	{"NotFound", "http.StatusNotFound"},
+	{"AlreadyExists", "http.StatusConflict"},
+	{"PermissionDenied", "http.StatusForbidden"},
+	{"Unauthenticated", "http.StatusUnauthorized"},
+	{"ResourceExhausted", "http.StatusTooManyRequests"},
+	{"FailedPrecondition", "http.StatusBadRequest"},
+	{"Aborted", "http.StatusConflict"},
+	{"OutOfRange", "http.StatusBadRequest"},
+	{"Unimplemented", "http.StatusNotImplemented"},
+	{"Unavailable", "http.StatusServiceUnavailable"},
+}
+
+// isProtoMessage returns true if t is a pointer to a protoc-generated message, which is transcoded with protojson.
+func isProtoMessage(t types.Type) bool {
+	ptr, ok := t.(*types.Pointer)
+	if !ok {
+		return false
+	}
+	if _, ok := ptr.Elem().Underlying().(*types.Struct); !ok {
+		return false
+	}
+	obj, _, _ := types.LookupFieldOrMethod(t, true, nil, "ProtoReflect")
+	fn, ok := obj.(*types.Func)
+	if !ok {
+		return false
+	}
+	results := fn.Signature().Results()
+	return results.Len() == 1 && types.TypeString(results.At(0).Type(), nil) == "google.golang.org/protobuf/reflect/protoreflect.Message"
+}
+
+// hasRequestBody returns true if the request body is decoded for method, as in zero.DecodeRequest.
+func hasRequestBody(method string) bool {
+	switch method {
+	case "PATCH", "POST", "PUT":
+		return true
+	default:
+		return false
+	}
+}
+
+// isGRPCReceiver returns true if the receiver of an API is also a gRPC service.
+func isGRPCReceiver(graph *depgraph.Graph, receiver types.Type) bool {
+	for _, service := range graph.GRPCServices {
+		if types.Identical(service.Receiver, receiver) {
+			return true
+		}
+	}
+	return false
+}
+
+// usesProtoMessages returns true if any API accepts or returns a protobuf message.
+func usesProtoMessages(graph *depgraph.Graph) bool {
+	for _, api := range graph.APIs {
+		signature := api.Function.Signature()
+		for i := range signature.Params().Len() {
+			if isProtoMessage(signature.Params().At(i).Type()) {
+				return true
+			}
+		}
+		if results := signature.Results(); results.Len() > 0 && isProtoMessage(results.At(0).Type()) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeGRPCServe writes code registering the gRPC services and serving them alongside the HTTP server.
+func writeGRPCServe(w *codewriter.Writer, graph *depgraph.Graph) {
+	writeZeroConstructSingletonByName(w, graph, "grpcConfig", grpcConfigType, "")
+	if _, ok := graph.Providers[grpcServerType]; ok {
+		writeZeroConstructSingletonByName(w, graph, "grpcServer", grpcServerType, "")
+	} else {
+		w.Import("google.golang.org/grpc")
+		w.L("grpcServer := grpc.NewServer()")
+	}
+	for i, service := range graph.GRPCServices {
+		name := fmt.Sprintf("grpcService%d", i)
+		writeZeroConstructSingletonByName(w, graph, name, service.Receiver.String(), "")
+		ref := graph.FunctionRef(service.Register)
+		w.Import(ref.Imports()...)
+		w.L("%s(grpcServer, %s)", ref.Ref, name)
+	}
+	w.Import("net", "fmt")
+	w.L(`grpcListener, err := net.Listen("tcp", grpcConfig.Bind)`)
+	w.L("if err != nil {")
+	w.In(func(w *codewriter.Writer) {
+		w.L(`return fmt.Errorf("failed to listen for gRPC: %%w", err)`)
+	})
+	w.L("}")
+	w.L(`logger.Info("gRPC server starting", "bind", grpcConfig.Bind)`)
+	w.L("wg.Go(func() error { return grpcServer.Serve(grpcListener) })")
+	w.L("wg.Go(func() error {")
+	w.In(func(w *codewriter.Writer) {
+		w.L("<-ctx.Done()")
+		w.L("grpcServer.GracefulStop()")
+		w.L("return nil")
+	})
+	w.L("})")
+}
+
+// writeProtoHelpers writes helpers for transcoding protobuf messages to and from JSON.
+func writeProtoHelpers(w *codewriter.Writer) {
+	w.Import("io", "net/http", "google.golang.org/protobuf/proto", "google.golang.org/protobuf/encoding/protojson")
+	w.L("")
+	w.L("// zeroProtoJSON encodes a protobuf message response as JSON.")
+	w.L("type zeroProtoJSON struct{ msg proto.Message }")
+	w.L("")
+	w.L("func (z zeroProtoJSON) ServeHTTP(w http.ResponseWriter, r *http.Request) {")
+	w.In(func(w *codewriter.Writer) {
+		w.L("data, err := protojson.Marshal(z.msg)")
+		w.L("if err != nil {")
+		w.In(func(w *codewriter.Writer) {
+			w.L("http.Error(w, err.Error(), http.StatusInternalServerError)")
+			w.L("return")
+		})
+		w.L("}")
+		w.L(`w.Header().Set("Content-Type", "application/json; charset=utf-8")`)
+		w.L("_, _ = w.Write(data)")
+	})
+	w.L("}")
+	w.L("")
+	w.L("// zeroDecodeProtoJSON decodes a JSON request body into a protobuf message.")
+	w.L("func zeroDecodeProtoJSON(r *http.Request, msg proto.Message) error {")
+	w.In(func(w *codewriter.Writer) {
+		w.L("data, err := io.ReadAll(r.Body)")
+		w.L("if err != nil {")
+		w.In(func(w *codewriter.Writer) {
+			w.L("return err")
+		})
+		w.L("}")
+		w.L("return protojson.Unmarshal(data, msg)")
+	})
+	w.L("}")
+}
+
+// writeGRPCHelpers writes helpers for mapping gRPC errors to HTTP errors.
+func writeGRPCHelpers(w *codewriter.Writer) {
+	w.Import("net/http", "github.com/alecthomas/zero", "google.golang.org/grpc/codes", "google.golang.org/grpc/status")
+	w.L("")
+	w.L("// zeroGRPCError maps gRPC status errors returned by gRPC services to HTTP errors.")
+	w.L("func zeroGRPCError(err error) error {")
+	w.In(func(w *codewriter.Writer) {
+		w.L("st, ok := status.FromError(err)")
+		w.L("if err == nil || !ok {")
+		w.In(func(w *codewriter.Writer) {
+			w.L("return err")
+		})
+		w.L("}")
+		w.L("code := http.StatusInternalServerError")
+		w.L("switch st.Code() {")
+		for _, mapping := range grpcHTTPStatus {
+			w.L("case codes.%s:", mapping.code)
+			w.In(func(w *codewriter.Writer) {
+				w.L("code = %s", mapping.status)
+			})
+		}
+		w.L("}")
+		w.L(`return zero.APIErrorf(code, "%%s", st.Message())`)
+	})
+	w.L("}")
+}
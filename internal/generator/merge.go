@@ -0,0 +1,237 @@
+package generator
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/errors"
+	"github.com/alecthomas/zero/internal/codewriter"
+)
+
+// Section is a part of the generated code that can be regenerated independently with [Merge].
+type Section string
+
+const (
+	// SectionHandlers is the registration of API handlers and subscribers, along with their JSON codecs.
+	SectionHandlers Section = "handlers"
+	// SectionInjector is the configuration and dependency injector.
+	SectionInjector Section = "injector"
+	// SectionOpenAPI is the OpenAPI specification embedded in the generated CLI.
+	SectionOpenAPI Section = "openapi"
+)
+
+// Sections lists every [Section].
+var Sections = []Section{SectionHandlers, SectionInjector, SectionOpenAPI}
+
+// sectionOf returns the section a top-level declaration of the generated code belongs to, or "" if it isn't part of a
+// section and is always regenerated.
+func sectionOf(decl string) Section {
+	switch decl {
+	case "ZeroConfig", "Injector", "NewInjector", "ZeroConstruct", "ZeroConstructSingletons":
+		return SectionInjector
+	case "RegisterHandlers", "RegisterSubscribers", "zeroDecodeProtoJSON", "zeroGRPCError":
+		return SectionHandlers
+	case "ZeroOpenAPICmd.Run":
+		return SectionOpenAPI
+	}
+	switch {
+	case strings.HasPrefix(decl, "Injector."):
+		return SectionInjector
+	case strings.HasPrefix(decl, "zeroEncodeJSON"), strings.HasPrefix(decl, "zeroDecodeJSON"),
+		strings.HasPrefix(decl, "zeroProtoJSON"):
+		return SectionHandlers
+	}
+	return ""
+}
+
+var lineDirectiveRe = regexp.MustCompile(`(?m)^//line zero\.go:\d+$`)
+
+// Merge regenerates only the given sections of existing generated code.
+//
+// The result is the freshly generated code, except that declarations in sections that were not selected are copied
+// verbatim from existing. Declarations that aren't part of any section, such as Run and the CLI, are always taken from
+// generated, as are declarations that are missing from existing. Imports are merged and pruned to those in use.
+//
+// Keeping a section while regenerating another is only valid if the kept section is still consistent with the code,
+// eg. regenerating handlers for a new API that requires a dependency the existing injector can't construct will not
+// compile.
+func Merge(existing, generated []byte, sections ...Section) ([]byte, error) {
+	regenerate := map[Section]bool{}
+	for _, section := range sections {
+		regenerate[section] = true
+	}
+	fset := token.NewFileSet()
+	oldFile, err := parser.ParseFile(fset, "existing.go", existing, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, errors.Errorf("failed to parse existing generated code: %w", err)
+	}
+	newFile, err := parser.ParseFile(fset, "generated.go", generated, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, errors.Errorf("failed to parse generated code: %w", err)
+	}
+
+	oldDecls := map[string][]byte{}
+	for _, decl := range oldFile.Decls {
+		if name := declName(decl); name != "" {
+			oldDecls[name] = declSource(fset, existing, decl)
+		}
+	}
+
+	// Assemble the declarations in generated order, tracking where the import declaration goes.
+	var header []byte
+	body := &bytes.Buffer{}
+	cursor := -1
+	for _, decl := range newFile.Decls {
+		start, end := declRange(fset, decl)
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+			if header == nil {
+				header = generated[:start]
+			}
+			cursor = -1
+			continue
+		}
+		if header == nil {
+			header = generated[:start]
+		} else if cursor >= 0 {
+			body.Write(generated[cursor:start])
+		}
+		cursor = end
+		name := declName(decl)
+		if section := sectionOf(name); section != "" && !regenerate[section] {
+			if source, ok := oldDecls[name]; ok {
+				body.Write(source)
+				continue
+			}
+		}
+		body.Write(generated[start:end])
+	}
+	if cursor >= 0 {
+		body.Write(generated[cursor:])
+	} else if header == nil {
+		return generated, nil
+	}
+
+	imports, err := usedImports(body.Bytes(), newFile, oldFile)
+	if err != nil {
+		return nil, err
+	}
+	out := &bytes.Buffer{}
+	out.Write(header)
+	out.WriteString(codewriter.ImportBlock(imports))
+	out.Write(body.Bytes())
+	code := lineDirectiveRe.ReplaceAll(out.Bytes(), []byte(lineDirectiveReset))
+	return resolveLineDirectives(code), nil
+}
+
+// usedImports returns the union of the imports of files that are used by body, formatted for
+// [codewriter.ImportBlock].
+func usedImports(body []byte, files ...*ast.File) ([]string, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), "merged.go", append([]byte("package merged\n"), body...), parser.SkipObjectResolution)
+	if err != nil {
+		return nil, errors.Errorf("failed to parse merged code: %w", err)
+	}
+	used := map[string]bool{}
+	ast.Inspect(file, func(node ast.Node) bool {
+		if sel, ok := node.(*ast.SelectorExpr); ok {
+			if ident, ok := sel.X.(*ast.Ident); ok {
+				used[ident.Name] = true
+			}
+		}
+		return true
+	})
+	var imports []string
+	for _, file := range files {
+		for _, spec := range file.Imports {
+			importPath, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			name := assumedPackageName(importPath)
+			if spec.Name != nil {
+				name = spec.Name.Name
+			}
+			if name != "_" && name != "." && !used[name] {
+				continue
+			}
+			if spec.Name != nil {
+				imports = append(imports, spec.Name.Name+" "+strconv.Quote(importPath))
+			} else {
+				imports = append(imports, importPath)
+			}
+		}
+	}
+	return imports, nil
+}
+
+// assumedPackageName returns the conventional name of the package at importPath, eg. "yaml" for gopkg.in/yaml.v3 and
+// "rand" for math/rand/v2.
+func assumedPackageName(importPath string) string {
+	base := path.Base(importPath)
+	if strings.HasPrefix(base, "v") && len(base) > 1 && strings.Trim(base[1:], "0123456789") == "" {
+		if dir := path.Dir(importPath); dir != "." {
+			base = path.Base(dir)
+		}
+	}
+	base = strings.TrimPrefix(base, "go-")
+	if i := strings.IndexAny(base, ".-"); i >= 0 {
+		base = base[:i]
+	}
+	return base
+}
+
+// declName returns the name of a top-level declaration, <type>.<name> for a method, or "" for import declarations.
+func declName(decl ast.Decl) string {
+	switch decl := decl.(type) {
+	case *ast.FuncDecl:
+		if decl.Recv == nil || len(decl.Recv.List) == 0 {
+			return decl.Name.Name
+		}
+		recv := decl.Recv.List[0].Type
+		if star, ok := recv.(*ast.StarExpr); ok {
+			recv = star.X
+		}
+		if ident, ok := recv.(*ast.Ident); ok {
+			return ident.Name + "." + decl.Name.Name
+		}
+		return decl.Name.Name
+	case *ast.GenDecl:
+		if len(decl.Specs) == 0 {
+			return ""
+		}
+		switch spec := decl.Specs[0].(type) {
+		case *ast.TypeSpec:
+			return spec.Name.Name
+		case *ast.ValueSpec:
+			return spec.Names[0].Name
+		}
+	}
+	return ""
+}
+
+// declRange returns the byte offsets of a declaration, including its doc comment.
+func declRange(fset *token.FileSet, decl ast.Decl) (start, end int) {
+	pos := decl.Pos()
+	switch decl := decl.(type) {
+	case *ast.FuncDecl:
+		if decl.Doc != nil {
+			pos = decl.Doc.Pos()
+		}
+	case *ast.GenDecl:
+		if decl.Doc != nil {
+			pos = decl.Doc.Pos()
+		}
+	}
+	file := fset.File(pos)
+	return file.Offset(pos), file.Offset(decl.End())
+}
+
+func declSource(fset *token.FileSet, src []byte, decl ast.Decl) []byte {
+	start, end := declRange(fset, decl)
+	return src[start:end]
+}
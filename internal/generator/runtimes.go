@@ -0,0 +1,128 @@
+package generator
+
+import (
+	"github.com/alecthomas/errors"
+	"github.com/alecthomas/zero/internal/codewriter"
+	"github.com/alecthomas/zero/internal/depgraph"
+)
+
+// Runtime is an alternate entrypoint to Run, adapting the generated handlers to a different deployment environment.
+type Runtime string
+
+const (
+	// RuntimeLambda generates RunLambda, serving requests from AWS Lambda via aws-lambda-go-api-proxy.
+	RuntimeLambda Runtime = "lambda"
+	// RuntimeCGI generates RunCGI, serving a single CGI request.
+	RuntimeCGI Runtime = "cgi"
+	// RuntimeFCGI generates RunFCGI, serving FastCGI requests from stdin.
+	RuntimeFCGI Runtime = "fcgi"
+	// RuntimeSystemd generates RunSystemd, serving HTTP on a socket passed by systemd socket activation.
+	RuntimeSystemd Runtime = "systemd"
+)
+
+func writeRuntime(w *codewriter.Writer, graph *depgraph.Graph, runtime Runtime, opts *generateOptions) error {
+	switch runtime {
+	case RuntimeLambda:
+		w.L("// RunLambda runs the Zero server container, serving requests from AWS Lambda (API Gateway proxy events).")
+		w.L("//")
+		w.L("// This registers all request handlers, cron jobs, PubSub subscribers, etc.")
+		w.L("func RunLambda(ctx context.Context, config ZeroConfig) error {")
+		w.In(func(w *codewriter.Writer) {
+			writeServe(w, graph, opts, true, func(w *codewriter.Writer) {
+				w.Import("github.com/aws/aws-lambda-go/lambda", "github.com/awslabs/aws-lambda-go-api-proxy/httpadapter")
+				w.L(`logger.Info("Server starting", "runtime", "lambda")`)
+				w.L("wg.Go(func() error {")
+				w.In(func(w *codewriter.Writer) {
+					w.L("lambda.StartWithOptions(httpadapter.New(server.Handler).ProxyWithContext, lambda.WithContext(ctx))")
+					w.L("return nil")
+				})
+				w.L("})")
+				w.L("return wg.Wait()")
+			})
+		})
+		w.L("}")
+
+	case RuntimeCGI:
+		w.L("// RunCGI runs the Zero server container, serving a single request as a CGI script.")
+		w.L("//")
+		w.L("// This registers all request handlers, and stops once the request has been served. Cron jobs, PubSub")
+		w.L("// subscribers and queue workers are not run, as each request is served by a new process.")
+		w.L("//")
+		w.L("// The response is written to stdout, so the container is constructed with os.Stdout redirected to")
+		w.L("// os.Stderr, eg. for loggers. Nothing else may write to os.Stdout while the request is served.")
+		w.L("func RunCGI(ctx context.Context, config ZeroConfig) error {")
+		w.In(func(w *codewriter.Writer) {
+			w.Import("os")
+			w.L("stdout := os.Stdout")
+			w.L("os.Stdout = os.Stderr")
+			w.L("defer func() { os.Stdout = stdout }()")
+			writeServe(w, graph, opts, false, func(w *codewriter.Writer) {
+				w.Import("errors", "net/http/cgi")
+				w.L(`logger.Debug("Server starting", "runtime", "cgi")`)
+				w.L(`errServed := errors.New("served")`)
+				w.L("os.Stdout = stdout")
+				w.L("wg.Go(func() error {")
+				w.In(func(w *codewriter.Writer) {
+					w.L("if err := cgi.Serve(server.Handler); err != nil {")
+					w.In(func(w *codewriter.Writer) {
+						w.L("return err")
+					})
+					w.L("}")
+					w.L("// Cancel the context to stop background work.")
+					w.L("return errServed")
+				})
+				w.L("})")
+				w.L("if err := wg.Wait(); !errors.Is(err, errServed) {")
+				w.In(func(w *codewriter.Writer) {
+					w.L("return err")
+				})
+				w.L("}")
+				w.L("return nil")
+			})
+		})
+		w.L("}")
+
+	case RuntimeFCGI:
+		w.L("// RunFCGI runs the Zero server container, serving FastCGI requests from stdin, as when spawned by a FastCGI")
+		w.L("// web server.")
+		w.L("//")
+		w.L("// This registers all request handlers, cron jobs, PubSub subscribers, etc.")
+		w.L("func RunFCGI(ctx context.Context, config ZeroConfig) error {")
+		w.In(func(w *codewriter.Writer) {
+			writeServe(w, graph, opts, true, func(w *codewriter.Writer) {
+				w.Import("net/http/fcgi")
+				w.L(`logger.Info("Server starting", "runtime", "fcgi")`)
+				w.L("wg.Go(func() error { return fcgi.Serve(nil, server.Handler) })")
+				w.L("return wg.Wait()")
+			})
+		})
+		w.L("}")
+
+	case RuntimeSystemd:
+		w.L("// RunSystemd runs the Zero server container, serving HTTP on the socket passed by systemd socket activation.")
+		w.L("//")
+		w.L("// This registers all request handlers, cron jobs, PubSub subscribers, etc.")
+		w.L("func RunSystemd(ctx context.Context, config ZeroConfig) error {")
+		w.In(func(w *codewriter.Writer) {
+			w.Import("os", "strconv", "net")
+			w.L(`if os.Getenv("LISTEN_PID") != strconv.Itoa(os.Getpid()) || os.Getenv("LISTEN_FDS") != "1" {`)
+			w.In(func(w *codewriter.Writer) {
+				w.L(`return fmt.Errorf("expected a single socket from systemd socket activation")`)
+			})
+			w.L("}")
+			w.L("// The first passed file descriptor is always 3.")
+			w.L(`listener, err := net.FileListener(os.NewFile(3, "systemd"))`)
+			w.L("if err != nil {")
+			w.In(func(w *codewriter.Writer) {
+				w.L(`return fmt.Errorf("failed to listen on systemd socket: %%w", err)`)
+			})
+			w.L("}")
+			writeServe(w, graph, opts, true, serveHTTP("server.Serve(listener)"))
+		})
+		w.L("}")
+
+	default:
+		return errors.Errorf("unknown runtime %q", runtime)
+	}
+	return nil
+}
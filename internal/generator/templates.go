@@ -0,0 +1,97 @@
+package generator
+
+import (
+	"strings"
+	"text/template"
+
+	"github.com/alecthomas/errors"
+)
+
+// Templates are user-supplied text/template fragments merged with the generated code.
+type Templates struct {
+	// Header is written at the top of the generated file, eg. a copyright notice.
+	Header string `toml:"header"`
+	// Handler wraps each HTTP handler. It must contain {{.Handler}}, and may refer to {{.Pattern}}, {{.Method}},
+	// {{.Path}} and {{.Function}}, eg.
+	//
+	//	otelhttp.NewHandler({{.Handler}}, {{printf "%q" .Pattern}})
+	Handler string `toml:"handler"`
+	// Run replaces the generated Run function, and must declare
+	//
+	//	func Run(ctx context.Context, config ZeroConfig) error
+	Run string `toml:"run"`
+	// Imports required by the templates.
+	Imports []string `toml:"imports"`
+}
+
+// fileData is passed to the Header and Run templates.
+type fileData struct {
+	Package string
+}
+
+// handlerData is passed to the Handler template.
+type handlerData struct {
+	Handler  string
+	Pattern  string
+	Method   string
+	Path     string
+	Function string
+}
+
+// handlerPlaceholder marks the position of the wrapped handler in the rendered Handler template.
+const handlerPlaceholder = "\x00handler\x00"
+
+// parsedTemplates are the parsed [Templates], nil where not configured.
+type parsedTemplates struct {
+	header  *template.Template
+	handler *template.Template
+	run     *template.Template
+}
+
+func (t Templates) parse() (*parsedTemplates, error) {
+	out := &parsedTemplates{}
+	for _, tmpl := range []struct {
+		name   string
+		source string
+		dest   **template.Template
+	}{
+		{"header", t.Header, &out.header},
+		{"handler", t.Handler, &out.handler},
+		{"run", t.Run, &out.run},
+	} {
+		if tmpl.source == "" {
+			continue
+		}
+		parsed, err := template.New(tmpl.name).Option("missingkey=error").Parse(tmpl.source)
+		if err != nil {
+			return nil, errors.Errorf("invalid %s template: %w", tmpl.name, err)
+		}
+		*tmpl.dest = parsed
+	}
+	return out, nil
+}
+
+// wrapHandler renders the handler template, returning the code to write before and after the wrapped handler.
+func (p *parsedTemplates) wrapHandler(data handlerData) (prefix, suffix string, err error) {
+	if p.handler == nil {
+		return "", "", nil
+	}
+	data.Handler = handlerPlaceholder
+	rendered, err := render(p.handler, data)
+	if err != nil {
+		return "", "", err
+	}
+	prefix, suffix, ok := strings.Cut(rendered, handlerPlaceholder)
+	if !ok {
+		return "", "", errors.Errorf("handler template must contain {{.Handler}}")
+	}
+	return prefix, suffix, nil
+}
+
+func render(tmpl *template.Template, data any) (string, error) {
+	out := &strings.Builder{}
+	if err := tmpl.Execute(out, data); err != nil {
+		return "", errors.Errorf("failed to render %s template: %w", tmpl.Name(), err)
+	}
+	return strings.TrimSpace(out.String()), nil
+}
@@ -0,0 +1,238 @@
+package generator
+
+import (
+	"fmt"
+	"go/types"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/alecthomas/errors"
+	"github.com/alecthomas/zero/internal/codewriter"
+	"github.com/alecthomas/zero/internal/depgraph"
+	"github.com/alecthomas/zero/internal/directiveparser"
+)
+
+// GenerateTest writes the test harness for graph, to be written to zero_test.go alongside the zero.go generated by
+// [Generate].
+//
+// The harness constructs an Injector in which the types provided by the graph's test providers are replaced by their
+// fakes, and if the graph has APIs, starts an httptest.Server serving them. [WithBenchmarks] additionally generates
+// BenchmarkZeroRoutes.
+func GenerateTest(out io.Writer, graph *depgraph.Graph, options ...Option) error {
+	opts := &generateOptions{}
+	for _, option := range options {
+		option(opts)
+	}
+	w := codewriter.New(graph.Dest.Name())
+	if len(opts.tags) > 0 {
+		pw := w.Prelude()
+		pw.L("//go:build %s", buildConstraint(opts.tags))
+		pw.L("")
+	}
+
+	w.Import("context")
+	w.L("// ZeroTestInjector creates an [Injector] for tests, in which types provided by test providers are replaced by")
+	w.L("// their fakes.")
+	w.L("func ZeroTestInjector(ctx context.Context, config ZeroConfig) (out *Injector, err error) {")
+	w.In(func(w *codewriter.Writer) {
+		w.L("injector := NewInjector(ctx, config)")
+		for _, provider := range graph.TestProviders {
+			ref := graph.TypeRef(provider.Provides)
+			w.Import(append(ref.Imports(), "reflect")...)
+			writeDocComment(w, provider.Documentation)
+			w.L("{")
+			w.In(func(w *codewriter.Writer) {
+				if provider.Function != nil && provider.Function.Signature().Results().Len() == 2 {
+					w.Import("fmt")
+				}
+				writeProviderCall(w, graph, provider, "p", "o", false)
+				w.L("injector.singletons[reflect.TypeFor[%s]()] = o", ref.Ref)
+			})
+			w.L("}")
+		}
+		w.L("return injector, nil")
+	})
+	w.L("}")
+
+	if len(graph.APIs) > 0 {
+		w.Import("net/http", "net/http/httptest", "testing")
+		w.L("")
+		w.L("// ZeroTestServer starts an [httptest.Server] serving the service's handlers, in which types provided by test")
+		w.L("// providers are replaced by their fakes. The server is closed when the test completes.")
+		w.L("func ZeroTestServer(t testing.TB, config ZeroConfig) *httptest.Server {")
+		w.In(func(w *codewriter.Writer) {
+			w.L("t.Helper()")
+			w.L("ctx := t.Context()")
+			w.L("injector, err := ZeroTestInjector(ctx, config)")
+			w.L("if err != nil {")
+			w.In(func(w *codewriter.Writer) {
+				w.L(`t.Fatalf("failed to construct test injector: %%s", err)`)
+			})
+			w.L("}")
+			w.L("if err := RegisterHandlers(ctx, injector); err != nil {")
+			w.In(func(w *codewriter.Writer) {
+				w.L(`t.Fatalf("failed to register handlers: %%s", err)`)
+			})
+			w.L("}")
+			w.L("mux, err := ZeroConstructSingletons[*http.ServeMux](ctx, injector)")
+			w.L("if err != nil {")
+			w.In(func(w *codewriter.Writer) {
+				w.L(`t.Fatalf("failed to construct *net/http.ServeMux: %%s", err)`)
+			})
+			w.L("}")
+			w.L("server := httptest.NewServer(mux)")
+			w.L("t.Cleanup(server.Close)")
+			w.L("return server")
+		})
+		w.L("}")
+	}
+
+	if opts.benchmarks {
+		writeBenchmarks(w, graph)
+	}
+
+	if _, err := out.Write(w.Bytes()); err != nil {
+		return errors.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
+
+// writeBenchmarks generates BenchmarkZeroRoutes, which serves a request to each GET endpoint through the mux with
+// httptest, with representative values for its path wildcards and query parameters.
+func writeBenchmarks(w *codewriter.Writer, graph *depgraph.Graph) {
+	type route struct{ name, target string }
+	var routes []route
+	for _, api := range graph.APIs {
+		if !slices.Contains(api.Methods, http.MethodGet) {
+			continue
+		}
+		path := api.Pattern.Host + api.Pattern.Path()
+		routes = append(routes, route{name: http.MethodGet + " " + path, target: benchmarkTarget(api)})
+	}
+	if len(routes) == 0 {
+		return
+	}
+	w.Import("net/http", "net/http/httptest", "testing")
+	w.L("")
+	w.L("// BenchmarkZeroRoutes benchmarks each GET endpoint through the service's mux, with representative values for its")
+	w.L("// path wildcards and query parameters. Types provided by test providers are replaced by their fakes.")
+	w.L("func BenchmarkZeroRoutes(b *testing.B) {")
+	w.In(func(w *codewriter.Writer) {
+		w.L("ctx := b.Context()")
+		w.L("injector, err := ZeroTestInjector(ctx, ZeroConfig{})")
+		w.L("if err != nil {")
+		w.In(func(w *codewriter.Writer) {
+			w.L(`b.Fatalf("failed to construct test injector: %%s", err)`)
+		})
+		w.L("}")
+		w.L("if err := RegisterHandlers(ctx, injector); err != nil {")
+		w.In(func(w *codewriter.Writer) {
+			w.L(`b.Fatalf("failed to register handlers: %%s", err)`)
+		})
+		w.L("}")
+		w.L("mux, err := ZeroConstructSingletons[*http.ServeMux](ctx, injector)")
+		w.L("if err != nil {")
+		w.In(func(w *codewriter.Writer) {
+			w.L(`b.Fatalf("failed to construct *net/http.ServeMux: %%s", err)`)
+		})
+		w.L("}")
+		w.L("routes := []struct{ name, target string }{")
+		w.In(func(w *codewriter.Writer) {
+			for _, route := range routes {
+				w.L("{%q, %q},", route.name, route.target)
+			}
+		})
+		w.L("}")
+		w.L("for _, route := range routes {")
+		w.In(func(w *codewriter.Writer) {
+			w.L("b.Run(route.name, func(b *testing.B) {")
+			w.In(func(w *codewriter.Writer) {
+				w.L("b.ReportAllocs()")
+				w.L("for b.Loop() {")
+				w.In(func(w *codewriter.Writer) {
+					w.L("w := httptest.NewRecorder()")
+					w.L("mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, route.target, nil))")
+					w.L("if w.Code >= http.StatusInternalServerError {")
+					w.In(func(w *codewriter.Writer) {
+						w.L(`b.Fatalf("%%s returned %%d: %%s", route.name, w.Code, w.Body)`)
+					})
+					w.L("}")
+				})
+				w.L("}")
+			})
+			w.L("})")
+		})
+		w.L("}")
+	})
+	w.L("}")
+}
+
+// benchmarkTarget returns the request target of a benchmark of api, with representative values for its path wildcards
+// and the query parameters of its params struct.
+func benchmarkTarget(api *depgraph.API) string {
+	wildcards := map[string]types.Type{}
+	query := url.Values{}
+	sig := api.Function.Signature()
+	for i := range sig.Params().Len() {
+		param := sig.Params().At(i)
+		if api.Pattern.Wildcard(param.Name()) {
+			wildcards[param.Name()] = param.Type()
+		}
+		for _, field := range api.Params[i] {
+			if field.In == "path" {
+				wildcards[field.Name] = field.Field.Type()
+			} else {
+				query.Add(field.Name, fixtureValue(field.Field.Type()))
+			}
+		}
+	}
+	target := &strings.Builder{}
+	if api.Pattern.Host != "" {
+		fmt.Fprintf(target, "http://%s", api.Pattern.Host)
+	}
+	for _, segment := range api.Pattern.Segments {
+		wildcard, ok := segment.(directiveparser.WildcardSegment)
+		if !ok {
+			target.WriteString(segment.String())
+			continue
+		}
+		target.WriteString("/" + url.PathEscape(fixtureValue(wildcards[wildcard.Name])))
+	}
+	if len(query) > 0 {
+		target.WriteString("?" + query.Encode())
+	}
+	return target.String()
+}
+
+// fixtureValue returns a representative value of a path wildcard or query parameter of type t.
+func fixtureValue(t types.Type) string {
+	if t == nil {
+		return "example"
+	}
+	switch t := t.(type) {
+	case *types.Pointer:
+		return fixtureValue(t.Elem())
+	case *types.Slice:
+		return fixtureValue(t.Elem())
+	}
+	if types.TypeString(t, nil) == "time.Time" {
+		return "2006-01-02T15:04:05Z"
+	}
+	basic, ok := t.Underlying().(*types.Basic)
+	if !ok {
+		return "example"
+	}
+	switch {
+	case basic.Info()&types.IsBoolean != 0:
+		return "true"
+	case basic.Info()&types.IsInteger != 0:
+		return "1"
+	case basic.Info()&types.IsFloat != 0:
+		return "1.5"
+	default:
+		return "example"
+	}
+}
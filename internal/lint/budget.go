@@ -0,0 +1,114 @@
+package lint
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/alecthomas/zero/internal/depgraph"
+)
+
+// Budget limits the size of the code linked into a service by its dependency graph, after pruning.
+//
+// Forbidden imports are import path prefixes, which match the package itself and all of its sub-packages, eg.
+// "github.com/aws" matches "github.com/aws/aws-sdk-go-v2/service/s3" but not "github.com/awslabs/smithy-go". A "/..."
+// suffix, as in other package patterns, is accepted and has the same effect.
+type Budget struct {
+	// MaxProviders is the maximum number of providers included in the graph, or 0 for no limit.
+	MaxProviders int `toml:"max-providers"`
+	// MaxPackages is the maximum number of non-standard packages linked into the service, or 0 for no limit.
+	MaxPackages int `toml:"max-packages"`
+	// DenyImports forbids linking packages with these import path prefixes into the service, directly or transitively.
+	DenyImports []string `toml:"deny-imports"`
+}
+
+// CheckBudget checks the graph against budget, returning a violation for each limit exceeded.
+//
+// Package limits are checked against the transitive imports of the graph, listed with the go command.
+func CheckBudget(ctx context.Context, graph *depgraph.Graph, budget Budget) ([]Violation, error) {
+	var violations []Violation
+	if budget.MaxProviders > 0 {
+		included := 0
+		for _, entry := range graph.Plan() {
+			if entry.Action != depgraph.PlanPrune {
+				included++
+			}
+		}
+		if included > budget.MaxProviders {
+			violations = append(violations, Violation{
+				Rule:    "max-providers",
+				Message: fmt.Sprintf("graph includes %d providers, exceeding the budget of %d", included, budget.MaxProviders),
+			})
+		}
+	}
+	if budget.MaxPackages == 0 && len(budget.DenyImports) == 0 {
+		return violations, nil
+	}
+	imports, err := graph.Imports(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if budget.MaxPackages > 0 && len(imports) > budget.MaxPackages {
+		violations = append(violations, Violation{
+			Rule:    "max-packages",
+			Message: fmt.Sprintf("graph links %d non-standard packages, exceeding the budget of %d", len(imports), budget.MaxPackages),
+		})
+	}
+	if len(budget.DenyImports) == 0 {
+		return violations, nil
+	}
+	chains := importChains(imports, graph.Packages())
+	declarations := nodes(graph)
+	slices.SortFunc(declarations, func(a, b node) int { return strings.Compare(a.name, b.name) })
+	for _, pkg := range slices.Sorted(maps.Keys(imports)) {
+		if !slices.ContainsFunc(budget.DenyImports, func(prefix string) bool { return matchPrefix(prefix, pkg) }) {
+			continue
+		}
+		chain, ok := chains[pkg]
+		if !ok {
+			chain = []string{pkg}
+		}
+		violation := Violation{Rule: "deny-imports", Message: "forbidden package " + pkg + " is linked into the service"}
+		if len(chain) > 1 {
+			violation.Message = fmt.Sprintf("forbidden package %s is imported by %s", pkg, strings.Join(chain[:len(chain)-1], " -> "))
+		}
+		// Attribute the import to the first declaration in the graph from the package importing it, if any.
+		if i := slices.IndexFunc(declarations, func(n node) bool { return n.pkg == chain[0] }); i >= 0 {
+			violation.Position = declarations[i].position
+			violation.Message += " (from " + declarations[i].name + ")"
+		}
+		violations = append(violations, violation)
+	}
+	return violations, nil
+}
+
+// matchPrefix returns true if pkg is the package at the import path prefix, or one of its sub-packages.
+func matchPrefix(prefix, pkg string) bool {
+	prefix = strings.TrimSuffix(strings.TrimSuffix(prefix, "/..."), "/")
+	return pkg == prefix || strings.HasPrefix(pkg, prefix+"/")
+}
+
+// importChains returns the shortest chain of imports from one of roots to each package in imports.
+func importChains(imports map[string]*depgraph.LinkedPackage, roots []string) map[string][]string {
+	chains := map[string][]string{}
+	queue := []string{}
+	for _, root := range roots {
+		if _, ok := imports[root]; ok {
+			chains[root] = []string{root}
+			queue = append(queue, root)
+		}
+	}
+	for len(queue) > 0 {
+		pkg := queue[0]
+		queue = queue[1:]
+		for _, dep := range imports[pkg].Imports {
+			if _, ok := chains[dep]; !ok {
+				chains[dep] = append(slices.Clone(chains[pkg]), dep)
+				queue = append(queue, dep)
+			}
+		}
+	}
+	return chains
+}
@@ -0,0 +1,73 @@
+package lint
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/alecthomas/zero/internal/buildtesting"
+	"github.com/alecthomas/zero/internal/depgraph"
+)
+
+func TestCheckBudget(t *testing.T) {
+	t.Parallel()
+	testCode := `
+package main
+
+import "github.com/alecthomas/zero"
+
+type DB struct{}
+
+//zero:provider
+func NewDB() *DB {
+	return &DB{}
+}
+
+type Service struct{}
+
+//zero:provider
+func NewService(db *DB, clock zero.Clock) *Service {
+	return &Service{}
+}
+`
+	tmpDir := buildtesting.Prepare(t, testCode)
+	graph, err := depgraph.Analyse(t.Context(), tmpDir, depgraph.WithRoots("*test.Service"))
+	assert.NoError(t, err)
+
+	violations, err := CheckBudget(t.Context(), graph, Budget{})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(violations))
+
+	violations, err = CheckBudget(t.Context(), graph, Budget{MaxProviders: 100, MaxPackages: 1000, DenyImports: []string{"example.com/..."}})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(violations))
+
+	violations, err = CheckBudget(t.Context(), graph, Budget{MaxProviders: 1, MaxPackages: 1, DenyImports: []string{"github.com/alecthomas/zero"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(violations))
+	assert.Equal(t, `graph includes 3 providers, exceeding the budget of 1 (rule "max-providers")`, violations[0].String())
+	assert.Equal(t, "max-packages", violations[1].Rule)
+	// Forbidden imports are prefixes, so match sub-packages too.
+	assert.Equal(t, "forbidden package github.com/alecthomas/zero is imported by github.com/alecthomas/zero/providers/clock "+
+		"(from github.com/alecthomas/zero/providers/clock.Real)", violations[2].Message)
+	assert.True(t, violations[2].Position.IsValid())
+	assert.Equal(t, "forbidden package github.com/alecthomas/zero/providers/clock is linked into the service "+
+		"(from github.com/alecthomas/zero/providers/clock.Real)", violations[3].Message)
+}
+
+func TestMatchPrefix(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		prefix, pkg string
+		expected    bool
+	}{
+		{"github.com/aws", "github.com/aws", true},
+		{"github.com/aws", "github.com/aws/aws-sdk-go-v2/service/s3", true},
+		{"github.com/aws/", "github.com/aws/aws-sdk-go-v2", true},
+		{"github.com/aws/...", "github.com/aws/aws-sdk-go-v2", true},
+		{"github.com/aws", "github.com/awslabs/smithy-go", false},
+		{"github.com/aws/aws-sdk-go-v2", "github.com/aws", false},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, matchPrefix(test.prefix, test.pkg), "%s %s", test.prefix, test.pkg)
+	}
+}
@@ -0,0 +1,152 @@
+// Package lint checks user-defined architecture rules against the dependency graph.
+package lint
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"slices"
+	"strings"
+
+	"github.com/alecthomas/zero/internal/depgraph"
+)
+
+// Rule restricts the direct dependencies of providers, APIs, cron jobs, subscriptions and middleware.
+//
+// Package patterns are import paths, optionally suffixed with "/..." to match all sub-packages.
+type Rule struct {
+	// Name of the rule, included in violations.
+	Name string `toml:"name"`
+	// From restricts the rule to packages matching these patterns. If empty the rule applies to all packages.
+	From []string `toml:"from"`
+	// Except exempts packages matching these patterns from the rule.
+	Except []string `toml:"except"`
+	// DenyPackages forbids requiring types provided by providers in packages matching these patterns.
+	DenyPackages []string `toml:"deny-packages"`
+	// DenyTypes forbids requiring these types directly, eg. "*database/sql.DB".
+	DenyTypes []string `toml:"deny-types"`
+}
+
+// Violation of a Rule.
+type Violation struct {
+	Position token.Position
+	Rule     string
+	Message  string
+}
+
+func (v Violation) String() string {
+	if !v.Position.IsValid() {
+		return fmt.Sprintf("%s (rule %q)", v.Message, v.Rule)
+	}
+	return fmt.Sprintf("%s: %s (rule %q)", v.Position, v.Message, v.Rule)
+}
+
+// Check the graph against rules, returning all violations ordered by position.
+func Check(graph *depgraph.Graph, rules []Rule) []Violation {
+	var violations []Violation
+	for _, node := range nodes(graph) {
+		for _, rule := range rules {
+			if !rule.appliesTo(node.pkg) {
+				continue
+			}
+			for _, req := range node.requires {
+				key := types.TypeString(depgraph.UnwrapDependency(req), nil)
+				if slices.Contains(rule.DenyTypes, key) {
+					violations = append(violations, Violation{
+						Position: node.position,
+						Rule:     rule.Name,
+						Message:  fmt.Sprintf("%s requires %s", node.name, key),
+					})
+				}
+				for _, provider := range providersOf(graph, key) {
+					if provider.Package != nil && matchAny(rule.DenyPackages, provider.Package.PkgPath) {
+						violations = append(violations, Violation{
+							Position: node.position,
+							Rule:     rule.Name,
+							Message:  fmt.Sprintf("%s requires %s provided by %s", node.name, key, provider.FullName()),
+						})
+					}
+				}
+			}
+		}
+	}
+	slices.SortStableFunc(violations, func(a, b Violation) int {
+		if a.Position.Filename != b.Position.Filename {
+			return strings.Compare(a.Position.Filename, b.Position.Filename)
+		}
+		return a.Position.Line - b.Position.Line
+	})
+	return violations
+}
+
+func (r Rule) appliesTo(pkg string) bool {
+	if len(r.From) > 0 && !matchAny(r.From, pkg) {
+		return false
+	}
+	return !matchAny(r.Except, pkg)
+}
+
+// node is anything in the graph with dependencies.
+type node struct {
+	name     string
+	pkg      string
+	position token.Position
+	requires []types.Type
+}
+
+func nodes(graph *depgraph.Graph) []node {
+	var out []node
+	for _, providers := range graph.Providers {
+		for _, provider := range providers {
+			if provider.Package == nil {
+				continue
+			}
+			out = append(out, node{provider.FullName(), provider.Package.PkgPath, provider.Position, provider.Requires})
+		}
+	}
+	receiver := func(fn *types.Func) []types.Type { return []types.Type{fn.Signature().Recv().Type()} }
+	for _, api := range graph.APIs {
+		out = append(out, node{api.Function.FullName(), api.Package.PkgPath, api.Position, receiver(api.Function)})
+	}
+	for _, cron := range graph.CronJobs {
+		out = append(out, node{cron.Function.FullName(), cron.Package.PkgPath, cron.Position, receiver(cron.Function)})
+	}
+	for _, subscription := range graph.Subscriptions {
+		out = append(out, node{subscription.Function.FullName(), subscription.Package.PkgPath, subscription.Position, receiver(subscription.Function)})
+	}
+	for _, step := range graph.Steps {
+		out = append(out, node{step.Function.FullName(), step.Package.PkgPath, step.Position, receiver(step.Function)})
+	}
+	for _, worker := range graph.Workers {
+		out = append(out, node{worker.Function.FullName(), worker.Package.PkgPath, worker.Position, receiver(worker.Function)})
+	}
+	for _, middleware := range graph.Middleware {
+		out = append(out, node{middleware.Function.FullName(), middleware.Package.PkgPath, middleware.Position, middleware.Requires})
+	}
+	return out
+}
+
+// providersOf returns the providers of the type key, including members of groups.
+func providersOf(graph *depgraph.Graph, key string) []*depgraph.Provider {
+	if group, ok := graph.Groups[key]; ok {
+		return group.Members
+	}
+	return graph.Providers[key]
+}
+
+func matchAny(patterns []string, pkg string) bool {
+	for _, pattern := range patterns {
+		if match(pattern, pkg) {
+			return true
+		}
+	}
+	return false
+}
+
+// match a package against a pattern in the same form as "go list", eg. "example.com/service/..."
+func match(pattern, pkg string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "/..."); ok {
+		return pkg == prefix || strings.HasPrefix(pkg, prefix+"/")
+	}
+	return pattern == pkg
+}
@@ -0,0 +1,90 @@
+package lint
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/alecthomas/zero/internal/buildtesting"
+	"github.com/alecthomas/zero/internal/depgraph"
+)
+
+func TestCheck(t *testing.T) {
+	t.Parallel()
+	testCode := `
+package main
+
+import "github.com/alecthomas/zero"
+
+type DB struct{}
+
+//zero:provider
+func NewDB() *DB {
+	return &DB{}
+}
+
+type Repo struct{}
+
+//zero:provider
+func NewRepo(db *DB) *Repo {
+	return &Repo{}
+}
+
+type Service struct{}
+
+//zero:provider
+func NewService(repo *Repo, db zero.Optional[*DB]) *Service {
+	return &Service{}
+}
+`
+	tmpDir := buildtesting.Prepare(t, testCode)
+	graph, err := depgraph.Analyse(t.Context(), tmpDir, depgraph.WithRoots("*test.Service"))
+	assert.NoError(t, err)
+
+	violations := Check(graph, []Rule{{Name: "no-db", DenyTypes: []string{"*test.DB"}}})
+	assert.Equal(t, []string{
+		"test.NewRepo requires *test.DB",
+		"test.NewService requires *test.DB",
+	}, messages(violations))
+	assert.Equal(t, "no-db", violations[0].Rule)
+	assert.True(t, violations[0].Position.IsValid())
+
+	violations = Check(graph, []Rule{{Name: "no-test", DenyPackages: []string{"test"}}})
+	assert.Equal(t, []string{
+		"test.NewRepo requires *test.DB provided by test.NewDB",
+		"test.NewService requires *test.Repo provided by test.NewRepo",
+		"test.NewService requires *test.DB provided by test.NewDB",
+	}, messages(violations))
+
+	violations = Check(graph, []Rule{{Name: "no-db", Except: []string{"test"}, DenyTypes: []string{"*test.DB"}}})
+	assert.Equal(t, 0, len(violations))
+
+	violations = Check(graph, []Rule{{Name: "no-db", From: []string{"example.com/..."}, DenyTypes: []string{"*test.DB"}}})
+	assert.Equal(t, 0, len(violations))
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		pkg     string
+		want    bool
+	}{
+		{"example.com/app", "example.com/app", true},
+		{"example.com/app", "example.com/app/db", false},
+		{"example.com/app/...", "example.com/app", true},
+		{"example.com/app/...", "example.com/app/db", true},
+		{"example.com/app/...", "example.com/application", false},
+	}
+	for _, test := range tests {
+		t.Run(test.pattern+" "+test.pkg, func(t *testing.T) {
+			assert.Equal(t, test.want, match(test.pattern, test.pkg))
+		})
+	}
+}
+
+func messages(violations []Violation) []string {
+	out := make([]string, 0, len(violations))
+	for _, violation := range violations {
+		out = append(out, violation.Message)
+	}
+	return out
+}
@@ -0,0 +1,9 @@
+package mocks
+
+import (
+	"testing"
+
+	"github.com/alecthomas/zero/internal/buildtesting"
+)
+
+func TestMain(m *testing.M) { buildtesting.Run(m) }
@@ -0,0 +1,310 @@
+// Package mocks generates recording fakes for the interfaces required by providers in a dependency graph.
+package mocks
+
+import (
+	"fmt"
+	"go/types"
+	"io"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/alecthomas/errors"
+	"github.com/alecthomas/zero/internal/codewriter"
+	"github.com/alecthomas/zero/internal/depgraph"
+)
+
+// Package is the name of the package that fakes are generated into.
+const Package = "zeromocks"
+
+// Generate writes a recording fake for each interface required by providers in graph to w, as a package named
+// [Package].
+//
+// Each fake records the arguments of calls to each method in <Method>Calls, and delegates to <Method>Func if set,
+// otherwise returning zero values. Interfaces that can't be implemented outside their package, such as those with
+// unexported methods or those declared in a main package, are skipped.
+func Generate(w io.Writer, graph *depgraph.Graph) error {
+	cw := codewriter.New(Package)
+	q := &qualifier{graph: graph, w: cw}
+	var fakes []*fake
+	names := map[string]int{}
+	for _, iface := range graph.RequiredInterfaces() {
+		if !implementable(iface) {
+			continue
+		}
+		f := &fake{iface: iface, name: iface.Obj().Name()}
+		fakes = append(fakes, f)
+		names[f.name]++
+	}
+	// Fakes of interfaces with the same name are prefixed with their package name.
+	for _, f := range fakes {
+		if names[f.name] > 1 {
+			f.name = upperFirst(f.iface.Obj().Pkg().Name()) + f.name
+		}
+	}
+	for i, f := range fakes {
+		if i > 0 {
+			cw.L("")
+		}
+		f.write(cw, q)
+	}
+	if _, err := w.Write(cw.Bytes()); err != nil {
+		return errors.Errorf("failed to write fakes: %w", err)
+	}
+	return nil
+}
+
+// fake of an interface.
+type fake struct {
+	iface *types.Named
+	name  string
+}
+
+func (f *fake) write(w *codewriter.Writer, q *qualifier) {
+	iface := f.iface.Underlying().(*types.Interface) //nolint
+	typeParams, typeArgs := "", ""
+	if tparams := f.iface.TypeParams(); tparams.Len() > 0 {
+		params, args := []string{}, []string{}
+		for tparam := range tparams.TypeParams() {
+			params = append(params, tparam.Obj().Name()+" "+types.TypeString(tparam.Constraint(), q.qualify))
+			args = append(args, tparam.Obj().Name())
+		}
+		typeParams = "[" + strings.Join(params, ", ") + "]"
+		typeArgs = "[" + strings.Join(args, ", ") + "]"
+	}
+	ifaceRef := types.TypeString(f.iface, q.qualify)
+
+	methods := []*method{}
+	for fn := range iface.Methods() {
+		methods = append(methods, newMethod(fn, q))
+	}
+
+	w.Import("sync")
+	w.L("// %s is a recording fake of %s.", f.name, ifaceRef)
+	w.L("type %s%s struct {", f.name, typeParams)
+	w.In(func(w *codewriter.Writer) {
+		w.L("mu sync.Mutex")
+		for _, m := range methods {
+			w.L("// %sFunc is called by %s if set, otherwise %s returns zero values.", m.name, m.name, m.name)
+			w.L("%sFunc func(%s)%s", m.name, m.paramList(), m.resultList())
+			w.L("// %sCalls records the arguments of each call to %s.", m.name, m.name)
+			w.L("%sCalls []%s%sCall%s", m.name, f.name, m.name, typeArgs)
+		}
+	})
+	w.L("}")
+	if typeParams == "" {
+		w.L("")
+		w.L("var _ %s = (*%s)(nil)", ifaceRef, f.name)
+	}
+
+	for _, m := range methods {
+		call := f.name + m.name + "Call"
+		w.L("")
+		w.L("// %s records the arguments of a call to %s.%s.", call, f.name, m.name)
+		w.L("type %s%s struct {", call, typeParams)
+		w.In(func(w *codewriter.Writer) {
+			for _, p := range m.params {
+				w.L("%s %s", p.field, p.fieldType)
+			}
+		})
+		w.L("}")
+		w.L("")
+		w.L("func (fake *%s%s) %s(%s)%s {", f.name, typeArgs, m.name, m.paramList(), m.namedResultList())
+		w.In(func(w *codewriter.Writer) {
+			fields := []string{}
+			args := []string{}
+			for _, p := range m.params {
+				fields = append(fields, p.field+": "+p.name)
+				args = append(args, p.name)
+			}
+			if m.variadic {
+				args[len(args)-1] += "..."
+			}
+			w.L("fake.mu.Lock()")
+			w.L("fake.%sCalls = append(fake.%sCalls, %s%s{%s})", m.name, m.name, call, typeArgs, strings.Join(fields, ", "))
+			w.L("fn := fake.%sFunc", m.name)
+			w.L("fake.mu.Unlock()")
+			w.L("if fn != nil {")
+			w.In(func(w *codewriter.Writer) {
+				if len(m.results) > 0 {
+					w.L("return fn(%s)", strings.Join(args, ", "))
+				} else {
+					w.L("fn(%s)", strings.Join(args, ", "))
+					w.L("return")
+				}
+			})
+			w.L("}")
+			w.L("return")
+		})
+		w.L("}")
+	}
+}
+
+// method of a fake.
+type method struct {
+	name     string
+	params   []param
+	results  []string
+	variadic bool
+}
+
+type param struct {
+	name string
+	// typ is the type of the parameter, prefixed with ... if variadic.
+	typ       string
+	field     string
+	fieldType string
+}
+
+func newMethod(fn *types.Func, q *qualifier) *method {
+	sig := fn.Signature()
+	m := &method{name: fn.Name(), variadic: sig.Variadic()}
+	for i := range sig.Results().Len() {
+		m.results = append(m.results, types.TypeString(sig.Results().At(i).Type(), q.qualify))
+	}
+	// Parameters are named after those of the interface where possible, avoiding the names used by the fake itself.
+	reserved := map[string]bool{"fake": true, "fn": true}
+	for i := range len(m.results) {
+		reserved[fmt.Sprintf("r%d", i)] = true
+	}
+	for i := range sig.Params().Len() {
+		v := sig.Params().At(i)
+		name := v.Name()
+		if name == "" || name == "_" || reserved[name] || slices.ContainsFunc(m.params, func(p param) bool { return strings.EqualFold(p.name, name) }) {
+			name = fmt.Sprintf("p%d", i)
+		}
+		typ := types.TypeString(v.Type(), q.qualify)
+		p := param{name: name, typ: typ, field: upperFirst(name), fieldType: typ}
+		if m.variadic && i == sig.Params().Len()-1 {
+			elem := v.Type().(*types.Slice).Elem() //nolint
+			p.typ = "..." + types.TypeString(elem, q.qualify)
+		}
+		m.params = append(m.params, p)
+	}
+	return m
+}
+
+func (m *method) paramList() string {
+	params := []string{}
+	for _, p := range m.params {
+		params = append(params, p.name+" "+p.typ)
+	}
+	return strings.Join(params, ", ")
+}
+
+func (m *method) resultList() string {
+	switch len(m.results) {
+	case 0:
+		return ""
+	case 1:
+		return " " + m.results[0]
+	default:
+		return " (" + strings.Join(m.results, ", ") + ")"
+	}
+}
+
+// namedResultList returns the results named r0, r1, etc., so that a bare return returns zero values.
+func (m *method) namedResultList() string {
+	if len(m.results) == 0 {
+		return ""
+	}
+	results := []string{}
+	for i, result := range m.results {
+		results = append(results, fmt.Sprintf("r%d %s", i, result))
+	}
+	return " (" + strings.Join(results, ", ") + ")"
+}
+
+// qualifier qualifies types in other packages with the same import aliases as zero.go, importing them into the fakes.
+type qualifier struct {
+	graph *depgraph.Graph
+	w     *codewriter.Writer
+}
+
+func (q *qualifier) qualify(pkg *types.Package) string {
+	alias := q.graph.ImportAlias(pkg.Path())
+	if alias == "" {
+		// The standard library and the destination package are not aliased.
+		q.w.Import(pkg.Path())
+		return pkg.Name()
+	}
+	q.w.Import(fmt.Sprintf("%s %q", alias, pkg.Path()))
+	return alias
+}
+
+// implementable returns true if a fake of iface can be declared in another package.
+func implementable(iface *types.Named) bool {
+	if iface.Obj().Pkg().Name() == "main" {
+		return false
+	}
+	for fn := range iface.Underlying().(*types.Interface).Methods() { //nolint
+		if !fn.Exported() || !accessible(fn.Type(), map[types.Type]bool{}) {
+			return false
+		}
+	}
+	return true
+}
+
+// accessible returns true if t can be referred to from another package.
+func accessible(t types.Type, seen map[types.Type]bool) bool {
+	if seen[t] {
+		return true
+	}
+	seen[t] = true
+	switch t := t.(type) {
+	case *types.Named:
+		obj := t.Obj()
+		if obj.Pkg() != nil && (!obj.Exported() || obj.Pkg().Name() == "main") {
+			return false
+		}
+		for arg := range t.TypeArgs().Types() {
+			if !accessible(arg, seen) {
+				return false
+			}
+		}
+		return true
+	case *types.Alias:
+		return accessible(types.Unalias(t), seen)
+	case *types.Pointer:
+		return accessible(t.Elem(), seen)
+	case *types.Slice:
+		return accessible(t.Elem(), seen)
+	case *types.Array:
+		return accessible(t.Elem(), seen)
+	case *types.Chan:
+		return accessible(t.Elem(), seen)
+	case *types.Map:
+		return accessible(t.Key(), seen) && accessible(t.Elem(), seen)
+	case *types.Signature:
+		return accessible(t.Params(), seen) && accessible(t.Results(), seen)
+	case *types.Tuple:
+		for v := range t.Variables() {
+			if !accessible(v.Type(), seen) {
+				return false
+			}
+		}
+		return true
+	case *types.Struct:
+		for field := range t.Fields() {
+			if !field.Exported() || !accessible(field.Type(), seen) {
+				return false
+			}
+		}
+		return true
+	case *types.Interface:
+		for fn := range t.Methods() {
+			if !fn.Exported() || !accessible(fn.Type(), seen) {
+				return false
+			}
+		}
+		return true
+	default:
+		return true
+	}
+}
+
+func upperFirst(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[size:]
+}
@@ -0,0 +1,89 @@
+package mocks
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/alecthomas/zero/internal/buildtesting"
+	"github.com/alecthomas/zero/internal/depgraph"
+)
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+	testCode := `
+package main
+
+import (
+	"io"
+
+	"test/store"
+)
+
+type Service struct{}
+
+//zero:provider
+func NewService(s store.Store, q store.Queue[string], sealed store.Sealed, w io.Writer) *Service {
+	return &Service{}
+}
+
+func main() {}
+`
+	tmpDir := buildtesting.Prepare(t, testCode)
+	storeDir := filepath.Join(tmpDir, "store")
+	mocksDir := filepath.Join(tmpDir, Package)
+	t.Cleanup(func() {
+		_ = os.RemoveAll(storeDir)
+		_ = os.RemoveAll(mocksDir)
+	})
+	assert.NoError(t, os.MkdirAll(storeDir, 0750))
+	assert.NoError(t, os.MkdirAll(mocksDir, 0750))
+	//nolint
+	err := os.WriteFile(filepath.Join(storeDir, "store.go"), []byte(`package store
+
+import "context"
+
+type Store interface {
+	Get(ctx context.Context, key string) (string, error)
+	Put(ctx context.Context, key, value string) error
+	Keys(prefixes ...string) []string
+}
+
+type Queue[T any] interface {
+	Push(item T)
+}
+
+type Sealed interface {
+	seal()
+}
+`), 0600)
+	assert.NoError(t, err)
+
+	graph, err := depgraph.Analyse(t.Context(), tmpDir, depgraph.WithRoots("*test.Service"))
+	assert.NoError(t, err)
+
+	w := &strings.Builder{}
+	err = Generate(w, graph)
+	assert.NoError(t, err)
+	code := w.String()
+	assert.Contains(t, code, "type Store struct {")
+	assert.Contains(t, code, "GetFunc func(ctx context.Context, key string) (string, error)")
+	assert.Contains(t, code, "GetCalls []StoreGetCall")
+	assert.Contains(t, code, "func (fake *Store) Keys(prefixes ...string) (r0 []string) {")
+	assert.Contains(t, code, "return fn(prefixes...)")
+	assert.Contains(t, code, ".Store = (*Store)(nil)")
+	assert.Contains(t, code, "type Queue[T any] struct {")
+	assert.Contains(t, code, "func (fake *Queue[T]) Push(item T) {")
+	assert.NotContains(t, code, "Sealed")
+	assert.NotContains(t, code, "Writer")
+
+	err = os.WriteFile(filepath.Join(mocksDir, Package+".go"), []byte(code), 0600)
+	assert.NoError(t, err)
+	cmd := exec.CommandContext(t.Context(), "go", "vet", "./"+Package)
+	cmd.Dir = tmpDir
+	output, err := cmd.CombinedOutput()
+	assert.NoError(t, err, "Fakes should compile:\n%s\n%s", output, code)
+}
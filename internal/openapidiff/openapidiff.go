@@ -0,0 +1,243 @@
+// Package openapidiff detects breaking changes between two OpenAPI specifications.
+package openapidiff
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/go-openapi/spec"
+)
+
+// Change is a breaking change between two specifications.
+type Change struct {
+	// Location of the change, eg. "GET /users/{id} response 200 .name".
+	Location string
+	Message  string
+}
+
+func (c Change) String() string { return c.Location + ": " + c.Message }
+
+// direction of data flow, which determines whether a schema change is breaking.
+//
+// Clients must continue to be able to send the same requests, so request schemas may only be widened. Conversely,
+// clients must continue to be able to decode responses, so response schemas may only be narrowed.
+type direction int
+
+const (
+	request direction = iota
+	response
+)
+
+// Diff returns the breaking changes from before to after, ordered by path.
+func Diff(before, after *spec.Swagger) []Change {
+	d := &differ{before: before, after: after}
+	for _, path := range slices.Sorted(maps.Keys(paths(before))) {
+		beforeItem := paths(before)[path]
+		afterItem, ok := paths(after)[path]
+		if !ok {
+			d.report(path, "path removed")
+			continue
+		}
+		afterOperations := operations(afterItem)
+		for method, beforeOperation := range operations(beforeItem) {
+			location := method + " " + path
+			afterOperation, ok := afterOperations[method]
+			if !ok {
+				d.report(location, "operation removed")
+				continue
+			}
+			d.operation(location, beforeOperation, afterOperation)
+		}
+	}
+	slices.SortStableFunc(d.changes, func(a, b Change) int { return strings.Compare(a.Location, b.Location) })
+	return d.changes
+}
+
+type differ struct {
+	before, after *spec.Swagger
+	changes       []Change
+}
+
+func (d *differ) report(location, format string, args ...any) {
+	d.changes = append(d.changes, Change{Location: location, Message: fmt.Sprintf(format, args...)})
+}
+
+func (d *differ) operation(location string, before, after *spec.Operation) {
+	beforeParams := map[string]spec.Parameter{}
+	for _, param := range before.Parameters {
+		beforeParams[param.In+":"+param.Name] = param
+	}
+	for _, param := range after.Parameters {
+		beforeParam, existed := beforeParams[param.In+":"+param.Name]
+		if param.In == "body" {
+			if existed {
+				d.schema(location+" request body", beforeParam.Schema, param.Schema, request, map[string]bool{})
+			} else if param.Required {
+				d.report(location, "new required request body")
+			}
+			continue
+		}
+		paramLocation := fmt.Sprintf("%s %s parameter %q", location, param.In, param.Name)
+		if param.Required && (!existed || !beforeParam.Required) {
+			d.report(paramLocation, "new required parameter")
+		}
+		if existed {
+			d.types(paramLocation, beforeParam.Type, param.Type, request)
+			d.enum(paramLocation, beforeParam.Enum, param.Enum, request)
+		}
+	}
+	if before.Responses == nil || after.Responses == nil {
+		return
+	}
+	for _, code := range slices.Sorted(maps.Keys(before.Responses.StatusCodeResponses)) {
+		beforeResponse := before.Responses.StatusCodeResponses[code]
+		if beforeResponse.Schema == nil {
+			continue
+		}
+		responseLocation := fmt.Sprintf("%s response %d", location, code)
+		afterResponse, ok := after.Responses.StatusCodeResponses[code]
+		if !ok {
+			if code >= 200 && code < 300 {
+				d.report(responseLocation, "response removed")
+			}
+			continue
+		}
+		d.schema(responseLocation, beforeResponse.Schema, afterResponse.Schema, response, map[string]bool{})
+	}
+}
+
+// schema compares two schemas, tracking the definitions being compared by ancestors in seen to avoid recursing
+// infinitely into recursive definitions.
+func (d *differ) schema(location string, before, after *spec.Schema, dir direction, seen map[string]bool) {
+	if before == nil || after == nil {
+		return
+	}
+	if before.Ref.String() != "" || after.Ref.String() != "" {
+		key := before.Ref.String() + " " + after.Ref.String()
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		defer delete(seen, key)
+	}
+	before, after = resolve(d.before, before), resolve(d.after, after)
+
+	d.types(location, schemaType(before), schemaType(after), dir)
+	d.enum(location, before.Enum, after.Enum, dir)
+	switch dir {
+	case request:
+		for _, name := range after.Required {
+			if !slices.Contains(before.Required, name) {
+				d.report(location+" ."+name, "new required property")
+			}
+		}
+	case response:
+		for _, name := range slices.Sorted(maps.Keys(before.Properties)) {
+			if _, ok := after.Properties[name]; !ok {
+				d.report(location+" ."+name, "property removed")
+			}
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(before.Properties)) {
+		if afterProperty, ok := after.Properties[name]; ok {
+			beforeProperty := before.Properties[name]
+			d.schema(location+" ."+name, &beforeProperty, &afterProperty, dir, seen)
+		}
+	}
+	if before.Items != nil && after.Items != nil {
+		d.schema(location+"[]", before.Items.Schema, after.Items.Schema, dir, seen)
+	}
+}
+
+// types reports a change of type, other than widening integers to numbers in requests, or narrowing numbers to
+// integers in responses.
+func (d *differ) types(location, before, after string, dir direction) {
+	if before == "" || after == "" || before == after {
+		return
+	}
+	if dir == request && before == "integer" && after == "number" {
+		return
+	}
+	if dir == response && before == "number" && after == "integer" {
+		return
+	}
+	d.report(location, "type changed from %s to %s", before, after)
+}
+
+// enum reports enum values removed from requests, or added to responses.
+func (d *differ) enum(location string, before, after []any, dir direction) {
+	switch dir {
+	case request:
+		if len(after) == 0 {
+			return
+		}
+		if len(before) == 0 {
+			d.report(location, "values restricted to %v", after)
+			return
+		}
+		for _, value := range before {
+			if !containsValue(after, value) {
+				d.report(location, "enum value %v removed", value)
+			}
+		}
+	case response:
+		if len(before) == 0 {
+			return
+		}
+		for _, value := range after {
+			if !containsValue(before, value) {
+				d.report(location, "enum value %v added", value)
+			}
+		}
+	}
+}
+
+func containsValue(values []any, value any) bool {
+	return slices.ContainsFunc(values, func(v any) bool { return fmt.Sprint(v) == fmt.Sprint(value) })
+}
+
+// resolve a local "#/definitions/<name>" reference.
+func resolve(swagger *spec.Swagger, schema *spec.Schema) *spec.Schema {
+	for range 32 {
+		ref := schema.Ref.String()
+		name, ok := strings.CutPrefix(ref, "#/definitions/")
+		if !ok {
+			return schema
+		}
+		definition, ok := swagger.Definitions[name]
+		if !ok {
+			return schema
+		}
+		schema = &definition
+	}
+	return schema
+}
+
+func schemaType(schema *spec.Schema) string {
+	if len(schema.Type) == 0 {
+		return ""
+	}
+	return schema.Type[0]
+}
+
+func paths(swagger *spec.Swagger) map[string]spec.PathItem {
+	if swagger.Paths == nil {
+		return nil
+	}
+	return swagger.Paths.Paths
+}
+
+func operations(item spec.PathItem) map[string]*spec.Operation {
+	out := map[string]*spec.Operation{}
+	for method, operation := range map[string]*spec.Operation{
+		"GET": item.Get, "PUT": item.Put, "POST": item.Post, "DELETE": item.Delete,
+		"OPTIONS": item.Options, "HEAD": item.Head, "PATCH": item.Patch,
+	} {
+		if operation != nil {
+			out[method] = operation
+		}
+	}
+	return out
+}
@@ -0,0 +1,106 @@
+package openapidiff
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/go-openapi/spec"
+)
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+	before := load(t, `{
+  "swagger": "2.0",
+  "paths": {
+    "/users": {
+      "get": {
+        "parameters": [{"name": "limit", "in": "query", "type": "integer"}],
+        "responses": {"200": {"schema": {"type": "array", "items": {"$ref": "#/definitions/main.User"}}}}
+      },
+      "post": {
+        "parameters": [{"name": "body", "in": "body", "required": true, "schema": {"$ref": "#/definitions/main.User"}}],
+        "responses": {"204": {"description": "No Content"}}
+      }
+    },
+    "/users/{id}": {
+      "delete": {"responses": {"204": {"description": "No Content"}}}
+    },
+    "/health": {
+      "get": {"responses": {"200": {"schema": {"type": "string"}}}}
+    }
+  },
+  "definitions": {
+    "main.User": {
+      "type": "object",
+      "properties": {
+        "name": {"type": "string"},
+        "age": {"type": "integer"},
+        "role": {"type": "string", "enum": ["admin", "user"]},
+        "manager": {"$ref": "#/definitions/main.User"}
+      }
+    }
+  }
+}`)
+	after := load(t, `{
+  "swagger": "2.0",
+  "paths": {
+    "/users": {
+      "get": {
+        "parameters": [
+          {"name": "limit", "in": "query", "type": "number"},
+          {"name": "org", "in": "query", "type": "string", "required": true}
+        ],
+        "responses": {"200": {"schema": {"type": "array", "items": {"$ref": "#/definitions/main.User"}}}}
+      },
+      "post": {
+        "parameters": [{"name": "body", "in": "body", "required": true, "schema": {"$ref": "#/definitions/main.User"}}],
+        "responses": {"204": {"description": "No Content"}}
+      }
+    },
+    "/users/{id}": {},
+    "/status": {
+      "get": {"responses": {"200": {"schema": {"type": "string"}}}}
+    }
+  },
+  "definitions": {
+    "main.User": {
+      "type": "object",
+      "required": ["email"],
+      "properties": {
+        "name": {"type": "string"},
+        "email": {"type": "string"},
+        "role": {"type": "string", "enum": ["admin"]},
+        "manager": {"$ref": "#/definitions/main.User"}
+      }
+    }
+  }
+}`)
+	var actual []string
+	for _, change := range Diff(before, after) {
+		actual = append(actual, change.String())
+	}
+	assert.Equal(t, []string{
+		`/health: path removed`,
+		`DELETE /users/{id}: operation removed`,
+		`GET /users query parameter "org": new required parameter`,
+		`GET /users response 200[] .age: property removed`,
+		`POST /users request body .email: new required property`,
+		`POST /users request body .role: enum value user removed`,
+	}, actual)
+}
+
+func TestDiffUnchanged(t *testing.T) {
+	t.Parallel()
+	swagger := load(t, `{
+  "swagger": "2.0",
+  "paths": {"/users": {"get": {"responses": {"200": {"schema": {"type": "integer", "enum": [1, 2]}}}}}}
+}`)
+	assert.Equal(t, 0, len(Diff(swagger, swagger)))
+}
+
+func load(t *testing.T, data string) *spec.Swagger {
+	t.Helper()
+	swagger := &spec.Swagger{}
+	assert.NoError(t, swagger.UnmarshalJSON([]byte(data)))
+	return swagger
+}
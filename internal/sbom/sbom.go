@@ -0,0 +1,159 @@
+// Package sbom generates a CycloneDX software bill of materials for the code linked into a Zero service by its pruned
+// dependency graph, rather than for every requirement of its go.mod.
+package sbom
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/alecthomas/zero/internal/depgraph"
+)
+
+// SpecVersion is the version of the CycloneDX specification generated.
+const SpecVersion = "1.5"
+
+// BOM is a CycloneDX JSON document.
+type BOM struct {
+	BOMFormat    string       `json:"bomFormat"`
+	SpecVersion  string       `json:"specVersion"`
+	Version      int          `json:"version"`
+	Metadata     Metadata     `json:"metadata"`
+	Components   []Component  `json:"components"`
+	Dependencies []Dependency `json:"dependencies"`
+}
+
+// Metadata describes the service the BOM is for, and the tool that generated it.
+type Metadata struct {
+	Tools     Tools     `json:"tools"`
+	Component Component `json:"component"`
+}
+
+// Tools used to generate the BOM.
+type Tools struct {
+	Components []Component `json:"components"`
+}
+
+// Component is a module, or a package of a module nested in its components.
+type Component struct {
+	Type       string      `json:"type"`
+	BOMRef     string      `json:"bom-ref,omitempty"`
+	Name       string      `json:"name"`
+	Version    string      `json:"version,omitempty"`
+	PURL       string      `json:"purl,omitempty"`
+	Components []Component `json:"components,omitempty"`
+}
+
+// Dependency lists the modules a module imports packages from, by bom-ref.
+type Dependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn,omitempty"`
+}
+
+// module is a module linked into the service, and the packages linked from it.
+type module struct {
+	path, version string
+	packages      []string
+	dependsOn     map[string]bool
+}
+
+// Generate returns a BOM of the non-standard modules linked into the service by graph, each with the packages linked
+// from it, as listed by [depgraph.Graph.Imports].
+//
+// The module of the destination package is the subject of the BOM, and zeroVersion is the version of Zero recorded as
+// the tool generating it.
+func Generate(ctx context.Context, graph *depgraph.Graph, zeroVersion string) (*BOM, error) {
+	imports, err := graph.Imports(ctx)
+	if err != nil {
+		return nil, err
+	}
+	modules := map[string]*module{}
+	moduleOf := func(pkg string) *module {
+		path, version := modulePath(pkg, imports[pkg].Module)
+		m, ok := modules[path]
+		if !ok {
+			m = &module{path: path, version: version, dependsOn: map[string]bool{}}
+			modules[path] = m
+		}
+		return m
+	}
+	for _, pkg := range slices.Sorted(maps.Keys(imports)) {
+		m := moduleOf(pkg)
+		m.packages = append(m.packages, pkg)
+		for _, dep := range imports[pkg].Imports {
+			if depModule := moduleOf(dep); depModule != m {
+				m.dependsOn[purl(depModule.path, depModule.version, "")] = true
+			}
+		}
+	}
+
+	dest := moduleOf(graph.Dest.Path())
+	bom := &BOM{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: SpecVersion,
+		Version:     1,
+		Metadata: Metadata{
+			Tools: Tools{Components: []Component{{
+				Type:    "application",
+				Name:    "github.com/alecthomas/zero",
+				Version: zeroVersion,
+				PURL:    purl("github.com/alecthomas/zero/cmd/zero", zeroVersion, ""),
+			}}},
+			Component: component("application", dest),
+		},
+		Components:   []Component{},
+		Dependencies: []Dependency{},
+	}
+	for _, path := range slices.Sorted(maps.Keys(modules)) {
+		m := modules[path]
+		if m != dest {
+			bom.Components = append(bom.Components, component("library", m))
+		}
+		bom.Dependencies = append(bom.Dependencies, Dependency{
+			Ref:       purl(m.path, m.version, ""),
+			DependsOn: slices.Sorted(maps.Keys(m.dependsOn)),
+		})
+	}
+	return bom, nil
+}
+
+// modulePath returns the path and version of the module containing pkg, following replace directives to other
+// module versions. Modules replaced by a local directory have no version, and packages outside modules are their own
+// module.
+func modulePath(pkg string, m *depgraph.LinkedModule) (path, version string) {
+	switch {
+	case m == nil:
+		return pkg, ""
+	case m.Replace != nil && m.Replace.Version != "":
+		return m.Replace.Path, m.Replace.Version
+	case m.Replace != nil:
+		return m.Path, ""
+	default:
+		return m.Path, m.Version
+	}
+}
+
+// component returns the component of a module, with its packages as nested components.
+func component(typ string, m *module) Component {
+	out := Component{Type: typ, BOMRef: purl(m.path, m.version, ""), Name: m.path, Version: m.version, PURL: purl(m.path, m.version, "")}
+	for _, pkg := range m.packages {
+		subpath := strings.TrimPrefix(strings.TrimPrefix(pkg, m.path), "/")
+		// The purl of a module's root package is that of the module, so packages are referred to by import path.
+		out.Components = append(out.Components, Component{Type: "library", BOMRef: pkg, Name: pkg, Version: m.version, PURL: purl(m.path, m.version, subpath)})
+	}
+	return out
+}
+
+// purl returns the package URL of a Go module, or of a package within it if subpath is not empty, eg.
+// "pkg:golang/github.com/alecthomas/kong@v1.12.1".
+func purl(path, version, subpath string) string {
+	out := "pkg:golang/" + path
+	if version != "" {
+		out += "@" + strings.ReplaceAll(version, "+", "%2B")
+	}
+	if subpath != "" {
+		out += "#" + subpath
+	}
+	return out
+}
@@ -0,0 +1,60 @@
+package sbom
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/alecthomas/zero/internal/buildtesting"
+	"github.com/alecthomas/zero/internal/depgraph"
+)
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+	testCode := `
+package main
+
+import "github.com/alecthomas/zero"
+
+type Service struct{}
+
+//zero:provider
+func NewService(clock zero.Clock) *Service {
+	return &Service{}
+}
+`
+	tmpDir := buildtesting.Prepare(t, testCode)
+	graph, err := depgraph.Analyse(t.Context(), tmpDir, depgraph.WithRoots("*test.Service"))
+	assert.NoError(t, err)
+	bom, err := Generate(t.Context(), graph, "v1.2.3")
+	assert.NoError(t, err)
+	assert.Equal(t, "CycloneDX", bom.BOMFormat)
+	assert.Equal(t, "v1.2.3", bom.Metadata.Tools.Components[0].Version)
+	assert.Equal(t, "test", bom.Metadata.Component.Name)
+
+	components := map[string]Component{}
+	for _, component := range bom.Components {
+		components[component.Name] = component
+	}
+	zero, ok := components["github.com/alecthomas/zero"]
+	assert.True(t, ok)
+	var packages []string
+	for _, pkg := range zero.Components {
+		packages = append(packages, pkg.Name)
+	}
+	assert.Equal(t, []string{"github.com/alecthomas/zero", "github.com/alecthomas/zero/providers/clock"}, packages,
+		"only linked packages are included")
+	_, ok = components["github.com/alecthomas/kong"]
+	assert.False(t, ok, "modules required by go.mod but not linked are excluded")
+
+	assert.Equal(t, Dependency{Ref: "pkg:golang/test", DependsOn: []string{zero.BOMRef}}, bom.Dependencies[len(bom.Dependencies)-1])
+
+	_, err = json.Marshal(bom)
+	assert.NoError(t, err)
+}
+
+func TestPURL(t *testing.T) {
+	assert.Equal(t, "pkg:golang/github.com/alecthomas/kong@v1.12.1", purl("github.com/alecthomas/kong", "v1.12.1", ""))
+	assert.Equal(t, "pkg:golang/example.com/app@v2.0.0%2Bincompatible#internal/db", purl("example.com/app", "v2.0.0+incompatible", "internal/db"))
+	assert.Equal(t, "pkg:golang/test", purl("test", "", ""))
+}
@@ -0,0 +1,73 @@
+package scaffold
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/alecthomas/zero/internal/buildtesting"
+	"github.com/alecthomas/zero/internal/depgraph"
+)
+
+func TestAnalyse(t *testing.T) {
+	t.Parallel()
+	testCode := `
+package main
+
+type Service struct{}
+
+//zero:provider
+func NewService() *Service {
+	return &Service{}
+}
+
+//zero:api GET /users
+func (s *Service) ListUsers() []string {
+	return nil
+}
+`
+	tmpDir := buildtesting.Prepare(t, testCode)
+	graph, err := depgraph.Analyse(t.Context(), tmpDir)
+	assert.NoError(t, err)
+
+	service, err := Analyse(graph, tmpDir, "APP_")
+	assert.NoError(t, err)
+	assert.Equal(t, "8080", service.Port)
+	assert.Equal(t, ".", service.Package)
+	assert.False(t, service.Postgres)
+	assert.Equal(t, []Env{
+		{Name: "APP_LOG_JSON", Value: ""},
+		{Name: "APP_LOG_LEVEL", Value: "info"},
+		{Name: "APP_SERVER_BIND", Value: "0.0.0.0:8080"},
+		{Name: "APP_SERVER_COMPRESS", Value: "true"},
+		{Name: "APP_SERVER_COMPRESS_MIN_SIZE", Value: "1KB"},
+		{Name: "APP_SERVER_MAX_BODY_SIZE", Value: "0"},
+		{Name: "APP_SERVER_READINESS_PATH", Value: "/_readyz"},
+		{Name: "APP_SERVER_REQUEST_TIMEOUT", Value: "0s"},
+	}, service.Env)
+
+	files, err := service.Files()
+	assert.NoError(t, err)
+	assert.Contains(t, string(files["Dockerfile"]), "EXPOSE 8080")
+	assert.Contains(t, string(files["k8s.yaml"]), "- name: APP_SERVER_BIND\n              value: \"0.0.0.0:8080\"")
+	assert.Contains(t, string(files["k8s.yaml"]), "readinessProbe:\n            httpGet:\n              path: /_readyz\n              port: 8080")
+	assert.Contains(t, string(files["docker-compose.yml"]), "- 8080:8080")
+	assert.NotContains(t, string(files["docker-compose.yml"]), "postgres")
+}
+
+func TestFlagName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Bind", "bind"},
+		{"DSN", "dsn"},
+		{"JSON", "json"},
+		{"SQLDumpMigrations", "sql-dump-migrations"},
+		{"MaxConns", "max-conns"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, flagName(test.name))
+		})
+	}
+}
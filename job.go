@@ -0,0 +1,12 @@
+package zero
+
+import "context"
+
+// JobFunc is a non-HTTP entrypoint, such as a cron job or a PubSub subscriber.
+type JobFunc func(ctx context.Context) error
+
+// JobMiddleware is a convenience type for Zero middleware applied to cron jobs and PubSub subscribers.
+//
+// Middleware of this shape is applied to //zero:cron and //zero:subscribe methods with a matching label, or to all of
+// them if it has no labels, rather than to APIs.
+type JobMiddleware func(next JobFunc) JobFunc
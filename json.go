@@ -0,0 +1,700 @@
+package zero
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/alecthomas/errors"
+)
+
+// JSONEncodable is implemented by responses that encode themselves with a [JSONEncoder], which [EncodeResponse]
+// writes as JSON without reflection.
+type JSONEncodable interface {
+	EncodeJSON(e *JSONEncoder)
+}
+
+// JSONResponse is a response encoded by a JSON encoder generated by `zero --fast-json`.
+//
+// It also implements [json.Marshaler], so custom [ResponseEncoder]s that encode responses with encoding/json produce
+// the same output. Custom encoders that inspect the response should use Value.
+type JSONResponse[T any] struct {
+	Value  T
+	Encode func(e *JSONEncoder, v *T)
+}
+
+var _ JSONEncodable = JSONResponse[int]{}
+
+func (j JSONResponse[T]) EncodeJSON(e *JSONEncoder) { j.Encode(e, &j.Value) }
+
+func (j JSONResponse[T]) MarshalJSON() ([]byte, error) {
+	e := &JSONEncoder{}
+	j.EncodeJSON(e)
+	return e.Bytes(), e.Err()
+}
+
+var jsonEncoderPool = sync.Pool{New: func() any { return &JSONEncoder{} }}
+
+// encodeJSONResponse writes a response that encodes itself, encoding it before writing the status so that encoding
+// errors can be reported.
+func encodeJSONResponse(logger *slog.Logger, w http.ResponseWriter, errorEncoder ErrorEncoder, data JSONEncodable, statusCode int) {
+	e := jsonEncoderPool.Get().(*JSONEncoder) //nolint
+	defer func() {
+		if cap(e.buf) <= 1<<20 {
+			jsonEncoderPool.Put(e)
+		}
+	}()
+	e.Reset()
+	data.EncodeJSON(e)
+	if err := e.Err(); err != nil {
+		logger.Error("Failed to encode response", "error", err)
+		errorEncoder(logger, w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	e.buf = append(e.buf, '\n')
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(statusCode)
+	if _, err := w.Write(e.buf); err != nil {
+		logger.Error("Failed to write response", "error", err)
+	}
+}
+
+// JSONEncoder appends JSON to a buffer, producing the same output as encoding/json. It is used by the encoders
+// generated by `zero --fast-json`.
+//
+// Errors are sticky: the first error is returned by [JSONEncoder.Err], and the encoded output should be discarded.
+type JSONEncoder struct {
+	buf []byte
+	err error
+}
+
+// Bytes returns the encoded JSON.
+func (e *JSONEncoder) Bytes() []byte { return e.buf }
+
+// Err returns the first error encountered while encoding.
+func (e *JSONEncoder) Err() error { return e.err }
+
+// Reset the encoder for reuse, retaining its buffer.
+func (e *JSONEncoder) Reset() {
+	e.buf = e.buf[:0]
+	e.err = nil
+}
+
+// Raw appends pre-encoded JSON.
+func (e *JSONEncoder) Raw(s string) { e.buf = append(e.buf, s...) }
+
+// Null appends null.
+func (e *JSONEncoder) Null() { e.buf = append(e.buf, "null"...) }
+
+// BeginObject appends the start of an object.
+func (e *JSONEncoder) BeginObject() { e.buf = append(e.buf, '{') }
+
+// Key appends a pre-encoded object key and colon, eg. `"name":`, preceded by a comma if it is not the first key of
+// the object.
+func (e *JSONEncoder) Key(key string) {
+	if n := len(e.buf); n > 0 && e.buf[n-1] != '{' {
+		e.buf = append(e.buf, ',')
+	}
+	e.buf = append(e.buf, key...)
+}
+
+// EndObject appends the end of an object.
+func (e *JSONEncoder) EndObject() { e.buf = append(e.buf, '}') }
+
+// BeginArray appends the start of an array.
+func (e *JSONEncoder) BeginArray() { e.buf = append(e.buf, '[') }
+
+// Next appends a comma if the next element is not the first of the array.
+func (e *JSONEncoder) Next() {
+	if n := len(e.buf); n > 0 && e.buf[n-1] != '[' {
+		e.buf = append(e.buf, ',')
+	}
+}
+
+// EndArray appends the end of an array.
+func (e *JSONEncoder) EndArray() { e.buf = append(e.buf, ']') }
+
+// String appends s as a JSON string, escaping HTML characters as encoding/json does.
+func (e *JSONEncoder) String(s string) {
+	const hex = "0123456789abcdef"
+	e.buf = append(e.buf, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			if b >= 0x20 && b != '"' && b != '\\' && b != '<' && b != '>' && b != '&' {
+				i++
+				continue
+			}
+			e.buf = append(e.buf, s[start:i]...)
+			switch b {
+			case '\\', '"':
+				e.buf = append(e.buf, '\\', b)
+			case '\b':
+				e.buf = append(e.buf, '\\', 'b')
+			case '\f':
+				e.buf = append(e.buf, '\\', 'f')
+			case '\n':
+				e.buf = append(e.buf, '\\', 'n')
+			case '\r':
+				e.buf = append(e.buf, '\\', 'r')
+			case '\t':
+				e.buf = append(e.buf, '\\', 't')
+			default:
+				e.buf = append(e.buf, '\\', 'u', '0', '0', hex[b>>4], hex[b&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			e.buf = append(e.buf, s[start:i]...)
+			e.buf = append(e.buf, `\ufffd`...)
+			i += size
+			start = i
+			continue
+		}
+		// U+2028 and U+2029 are valid JSON but not valid JavaScript.
+		if r == '\u2028' || r == '\u2029' {
+			e.buf = append(e.buf, s[start:i]...)
+			e.buf = append(e.buf, '\\', 'u', '2', '0', '2', hex[r&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	e.buf = append(e.buf, s[start:]...)
+	e.buf = append(e.buf, '"')
+}
+
+// Int appends an integer.
+func (e *JSONEncoder) Int(i int64) { e.buf = strconv.AppendInt(e.buf, i, 10) }
+
+// Uint appends an unsigned integer.
+func (e *JSONEncoder) Uint(u uint64) { e.buf = strconv.AppendUint(e.buf, u, 10) }
+
+// Bool appends a boolean.
+func (e *JSONEncoder) Bool(b bool) { e.buf = strconv.AppendBool(e.buf, b) }
+
+// Float appends a float of the given bit size, formatted as encoding/json does. NaN and infinities are errors.
+func (e *JSONEncoder) Float(f float64, bits int) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		e.fail(errors.Errorf("json: unsupported value: %s", strconv.FormatFloat(f, 'g', -1, bits)))
+		e.Null()
+		return
+	}
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+	e.buf = strconv.AppendFloat(e.buf, f, format, -1, bits)
+	if format == 'e' {
+		// Clean up e-09 to e-9.
+		if n := len(e.buf); n >= 4 && e.buf[n-4] == 'e' && e.buf[n-3] == '-' && e.buf[n-2] == '0' {
+			e.buf[n-2] = e.buf[n-1]
+			e.buf = e.buf[:n-1]
+		}
+	}
+}
+
+// Value appends v encoded with encoding/json, for types that the generated encoders don't handle.
+func (e *JSONEncoder) Value(v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		e.fail(err)
+		e.Null()
+		return
+	}
+	e.buf = append(e.buf, data...)
+}
+
+func (e *JSONEncoder) fail(err error) {
+	if e.err == nil {
+		e.err = err
+	}
+}
+
+// maxJSONDepth is the maximum nesting depth of decoded JSON, as in encoding/json.
+const maxJSONDepth = 10000
+
+// JSONDecoder decodes JSON with the same semantics as encoding/json. It is used by the decoders generated by
+// `zero --fast-json`.
+//
+// Errors are sticky: after the first error, [JSONDecoder.Null] returns true and [JSONDecoder.More] returns false so
+// that decoding stops, and the error is returned by [JSONDecoder.Err].
+type JSONDecoder struct {
+	data    []byte
+	pos     int
+	depth   int
+	first   bool
+	err     error
+	scratch []byte
+}
+
+// NewJSONDecoder creates a decoder for a single JSON value in data.
+func NewJSONDecoder(data []byte) *JSONDecoder {
+	return &JSONDecoder{data: data}
+}
+
+// Err returns the first error encountered while decoding.
+func (d *JSONDecoder) Err() error { return d.err }
+
+// Null consumes a null, returning true if the next value was null or an error has occurred.
+func (d *JSONDecoder) Null() bool {
+	if d.err != nil {
+		return true
+	}
+	d.skipSpace()
+	if bytes.HasPrefix(d.data[d.pos:], []byte("null")) {
+		d.pos += 4
+		return true
+	}
+	return false
+}
+
+// BeginObject consumes the start of an object, whose keys are then read while [JSONDecoder.More]('}') returns true.
+func (d *JSONDecoder) BeginObject() { d.begin('{', "object") }
+
+// BeginArray consumes the start of an array, whose elements are then read while [JSONDecoder.More](']') returns true.
+func (d *JSONDecoder) BeginArray() { d.begin('[', "array") }
+
+func (d *JSONDecoder) begin(open byte, kind string) {
+	if d.expect(open, kind) {
+		d.depth++
+		if d.depth > maxJSONDepth {
+			d.fail("exceeded max depth")
+			return
+		}
+		d.first = true
+	}
+}
+
+// More returns true if the object or array being decoded has another key or element, consuming the separating
+// comma, or consumes close and returns false if it has ended.
+func (d *JSONDecoder) More(close byte) bool {
+	if d.err != nil {
+		return false
+	}
+	d.skipSpace()
+	if d.pos >= len(d.data) {
+		d.eof()
+		return false
+	}
+	c := d.data[d.pos]
+	if c == close {
+		d.pos++
+		d.depth--
+		d.first = false
+		return false
+	}
+	if d.first {
+		d.first = false
+		return true
+	}
+	if c != ',' {
+		d.fail("expected , or %c", close)
+		return false
+	}
+	d.pos++
+	return true
+}
+
+// Key consumes an object key and its colon, returning the index of the name it matches, or -1.
+//
+// As with encoding/json, an exact match is preferred, otherwise keys are matched case-insensitively.
+func (d *JSONDecoder) Key(names ...string) int {
+	if !d.expectString("object key") {
+		return -1
+	}
+	d.scratch = d.readString(d.scratch[:0])
+	if !d.expect(':', "colon") {
+		return -1
+	}
+	for i, name := range names {
+		if string(d.scratch) == name {
+			return i
+		}
+	}
+	for i, name := range names {
+		if bytes.EqualFold(d.scratch, []byte(name)) {
+			return i
+		}
+	}
+	return -1
+}
+
+// String decodes a string.
+func (d *JSONDecoder) String() string {
+	if !d.expectString("string") {
+		return ""
+	}
+	d.scratch = d.readString(d.scratch[:0])
+	return string(d.scratch)
+}
+
+// Bool decodes a boolean.
+func (d *JSONDecoder) Bool() bool {
+	if d.err != nil {
+		return false
+	}
+	d.skipSpace()
+	switch {
+	case bytes.HasPrefix(d.data[d.pos:], []byte("true")):
+		d.pos += 4
+		return true
+	case bytes.HasPrefix(d.data[d.pos:], []byte("false")):
+		d.pos += 5
+		return false
+	default:
+		d.unexpected("boolean")
+		return false
+	}
+}
+
+// Int decodes an integer that fits in a signed integer of the given bit size, where 0 is the size of int.
+func (d *JSONDecoder) Int(bits int) int64 {
+	number, negative, ok := d.integer()
+	if !ok {
+		return 0
+	}
+	if bits == 0 {
+		bits = strconv.IntSize
+	}
+	limit := uint64(1) << (bits - 1)
+	if negative && number <= limit {
+		return -int64(number) //nolint:gosec
+	}
+	if !negative && number < limit {
+		return int64(number) //nolint:gosec
+	}
+	d.fail("number out of range for int%d", bits)
+	return 0
+}
+
+// Uint decodes an integer that fits in an unsigned integer of the given bit size, where 0 is the size of uint.
+func (d *JSONDecoder) Uint(bits int) uint64 {
+	number, negative, ok := d.integer()
+	if !ok {
+		return 0
+	}
+	if bits == 0 {
+		bits = strconv.IntSize
+	}
+	if negative || (bits < 64 && number >= uint64(1)<<bits) {
+		d.fail("number out of range for uint%d", bits)
+		return 0
+	}
+	return number
+}
+
+// Float decodes a number as a float of the given bit size.
+func (d *JSONDecoder) Float(bits int) float64 {
+	number := d.readNumber()
+	if d.err != nil {
+		return 0
+	}
+	f, err := strconv.ParseFloat(string(number), bits)
+	if err != nil {
+		d.fail("number %s out of range for float%d", number, bits)
+		return 0
+	}
+	return f
+}
+
+// Value decodes the next value into v with encoding/json, for types that the generated decoders don't handle.
+func (d *JSONDecoder) Value(v any) {
+	if d.err != nil {
+		return
+	}
+	d.skipSpace()
+	start := d.pos
+	d.Skip()
+	if d.err != nil {
+		return
+	}
+	if err := json.Unmarshal(d.data[start:d.pos], v); err != nil {
+		d.err = errors.Errorf("offset %d: %w", start, err)
+	}
+}
+
+// Skip the next value.
+func (d *JSONDecoder) Skip() {
+	if d.Null() {
+		return
+	}
+	if d.pos >= len(d.data) {
+		d.eof()
+		return
+	}
+	switch d.data[d.pos] {
+	case '{':
+		d.BeginObject()
+		for d.More('}') {
+			d.Key()
+			d.Skip()
+		}
+	case '[':
+		d.BeginArray()
+		for d.More(']') {
+			d.Skip()
+		}
+	case '"':
+		d.scratch = d.readString(d.scratch[:0])
+	case 't', 'f':
+		d.Bool()
+	default:
+		d.readNumber()
+	}
+}
+
+// integer reads a number, which must be an integer, returning its magnitude and sign.
+func (d *JSONDecoder) integer() (uint64, bool, bool) {
+	number := d.readNumber()
+	if d.err != nil {
+		return 0, false, false
+	}
+	negative := number[0] == '-'
+	if negative {
+		number = number[1:]
+	}
+	var out uint64
+	for _, c := range number {
+		if c < '0' || c > '9' {
+			d.fail("expected integer")
+			return 0, false, false
+		}
+		if out > (math.MaxUint64-uint64(c-'0'))/10 {
+			d.fail("number out of range")
+			return 0, false, false
+		}
+		out = out*10 + uint64(c-'0')
+	}
+	return out, negative, true
+}
+
+// readNumber reads a number, validating its syntax.
+func (d *JSONDecoder) readNumber() []byte {
+	if d.err != nil {
+		return nil
+	}
+	d.skipSpace()
+	start := d.pos
+	digits := func() int {
+		n := 0
+		for d.pos < len(d.data) && d.data[d.pos] >= '0' && d.data[d.pos] <= '9' {
+			d.pos++
+			n++
+		}
+		return n
+	}
+	if d.pos < len(d.data) && d.data[d.pos] == '-' {
+		d.pos++
+	}
+	switch {
+	case d.pos < len(d.data) && d.data[d.pos] == '0':
+		d.pos++
+	case digits() == 0:
+		d.unexpected("value")
+		return nil
+	}
+	if d.pos < len(d.data) && d.data[d.pos] == '.' {
+		d.pos++
+		if digits() == 0 {
+			d.fail("invalid number")
+			return nil
+		}
+	}
+	if d.pos < len(d.data) && (d.data[d.pos] == 'e' || d.data[d.pos] == 'E') {
+		d.pos++
+		if d.pos < len(d.data) && (d.data[d.pos] == '+' || d.data[d.pos] == '-') {
+			d.pos++
+		}
+		if digits() == 0 {
+			d.fail("invalid number")
+			return nil
+		}
+	}
+	return d.data[start:d.pos]
+}
+
+// readString reads a string whose opening quote has been checked, appending its unescaped contents to dst. Invalid
+// UTF-8 and unpaired surrogates are replaced with U+FFFD, as in encoding/json.
+func (d *JSONDecoder) readString(dst []byte) []byte {
+	d.pos++
+	for d.pos < len(d.data) {
+		c := d.data[d.pos]
+		switch {
+		case c == '"':
+			d.pos++
+			return dst
+		case c < 0x20:
+			d.fail("invalid character in string")
+			return dst
+		case c == '\\':
+			d.pos++
+			if d.pos >= len(d.data) {
+				break
+			}
+			escape := d.data[d.pos]
+			d.pos++
+			switch escape {
+			case '"', '\\', '/':
+				dst = append(dst, escape)
+			case 'b':
+				dst = append(dst, '\b')
+			case 'f':
+				dst = append(dst, '\f')
+			case 'n':
+				dst = append(dst, '\n')
+			case 'r':
+				dst = append(dst, '\r')
+			case 't':
+				dst = append(dst, '\t')
+			case 'u':
+				r, ok := d.readHex()
+				if !ok {
+					return dst
+				}
+				if utf16.IsSurrogate(r) {
+					r2 := utf8.RuneError
+					if bytes.HasPrefix(d.data[d.pos:], []byte(`\u`)) {
+						saved := d.pos
+						d.pos += 2
+						if r2, ok = d.readHex(); !ok {
+							return dst
+						}
+						if r = utf16.DecodeRune(r, r2); r == utf8.RuneError {
+							d.pos = saved
+						}
+					} else {
+						r = r2
+					}
+				}
+				dst = utf8.AppendRune(dst, r)
+			default:
+				d.fail("invalid escape in string")
+				return dst
+			}
+		case c < utf8.RuneSelf:
+			dst = append(dst, c)
+			d.pos++
+		default:
+			r, size := utf8.DecodeRune(d.data[d.pos:])
+			if r == utf8.RuneError && size == 1 {
+				dst = utf8.AppendRune(dst, utf8.RuneError)
+			} else {
+				dst = append(dst, d.data[d.pos:d.pos+size]...)
+			}
+			d.pos += size
+		}
+	}
+	d.eof()
+	return dst
+}
+
+func (d *JSONDecoder) readHex() (rune, bool) {
+	if d.pos+4 > len(d.data) {
+		d.eof()
+		return 0, false
+	}
+	r, err := strconv.ParseUint(string(d.data[d.pos:d.pos+4]), 16, 32)
+	if err != nil {
+		d.fail("invalid unicode escape in string")
+		return 0, false
+	}
+	d.pos += 4
+	return rune(r), true
+}
+
+func (d *JSONDecoder) expectString(kind string) bool {
+	if d.err != nil {
+		return false
+	}
+	d.skipSpace()
+	if d.pos >= len(d.data) || d.data[d.pos] != '"' {
+		d.unexpected(kind)
+		return false
+	}
+	return true
+}
+
+func (d *JSONDecoder) expect(c byte, kind string) bool {
+	if d.err != nil {
+		return false
+	}
+	d.skipSpace()
+	if d.pos >= len(d.data) || d.data[d.pos] != c {
+		d.unexpected(kind)
+		return false
+	}
+	d.pos++
+	return true
+}
+
+func (d *JSONDecoder) skipSpace() {
+	for d.pos < len(d.data) {
+		switch d.data[d.pos] {
+		case ' ', '\t', '\n', '\r':
+			d.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (d *JSONDecoder) fail(format string, args ...any) {
+	if d.err == nil {
+		d.err = errors.Errorf("offset %d: "+format, append([]any{d.pos}, args...)...)
+	}
+}
+
+// unexpected fails with an error for a missing value of the given kind.
+func (d *JSONDecoder) unexpected(kind string) {
+	if d.pos >= len(d.data) {
+		d.eof()
+		return
+	}
+	d.fail("expected %s", kind)
+}
+
+func (d *JSONDecoder) eof() {
+	if d.err == nil {
+		d.err = errors.New("unexpected end of JSON input")
+	}
+}
+
+var jsonBufferPool = sync.Pool{New: func() any { return &bytes.Buffer{} }}
+
+// DecodeJSONRequest decodes the JSON request body into T with a decoder generated by `zero --fast-json`, with the
+// same errors as [DecodeRequest].
+func DecodeJSONRequest[T any](r *http.Request, decode func(d *JSONDecoder, v *T)) (T, error) {
+	var out T
+	buf := jsonBufferPool.Get().(*bytes.Buffer) //nolint
+	defer func() {
+		if buf.Cap() <= 1<<20 {
+			jsonBufferPool.Put(buf)
+		}
+	}()
+	buf.Reset()
+	if _, err := buf.ReadFrom(r.Body); err != nil {
+		if maxBytesErr := (*http.MaxBytesError)(nil); errors.As(err, &maxBytesErr) {
+			return out, APIErrorf(http.StatusRequestEntityTooLarge, "request body too large: %w", err)
+		}
+		return out, APIErrorf(http.StatusBadRequest, "failed to decode JSON request body: %w", err)
+	}
+	d := JSONDecoder{data: buf.Bytes()}
+	decode(&d, &out)
+	if err := d.Err(); err != nil {
+		return out, APIErrorf(http.StatusBadRequest, "failed to decode JSON request body: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,244 @@
+package zero_test
+
+import (
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/alecthomas/zero"
+)
+
+type jsonUser struct {
+	Name  string   `json:"name"`
+	Age   int      `json:"age,omitempty"`
+	Score float64  `json:"score"`
+	Tags  []string `json:"tags"`
+}
+
+func encodeJSONUser(e *zero.JSONEncoder, v *jsonUser) {
+	e.BeginObject()
+	e.Key(`"name":`)
+	e.String(v.Name)
+	if v.Age != 0 {
+		e.Key(`"age":`)
+		e.Int(int64(v.Age))
+	}
+	e.Key(`"score":`)
+	e.Float(v.Score, 64)
+	e.Key(`"tags":`)
+	if v.Tags == nil {
+		e.Null()
+	} else {
+		e.BeginArray()
+		for _, tag := range v.Tags {
+			e.Next()
+			e.String(tag)
+		}
+		e.EndArray()
+	}
+	e.EndObject()
+}
+
+func decodeJSONUser(d *zero.JSONDecoder, v *jsonUser) {
+	if d.Null() {
+		return
+	}
+	d.BeginObject()
+	for d.More('}') {
+		switch d.Key("name", "age", "score", "tags") {
+		case 0:
+			if !d.Null() {
+				v.Name = d.String()
+			}
+		case 1:
+			if !d.Null() {
+				v.Age = int(d.Int(0))
+			}
+		case 2:
+			if !d.Null() {
+				v.Score = d.Float(64)
+			}
+		case 3:
+			if d.Null() {
+				v.Tags = nil
+				continue
+			}
+			d.BeginArray()
+			v.Tags = v.Tags[:0]
+			if v.Tags == nil {
+				v.Tags = []string{}
+			}
+			for d.More(']') {
+				var tag string
+				if !d.Null() {
+					tag = d.String()
+				}
+				v.Tags = append(v.Tags, tag)
+			}
+		default:
+			d.Skip()
+		}
+	}
+}
+
+func TestJSONEncoder(t *testing.T) {
+	strs := []string{"", "hello", `"quoted" \ back`, "<html> & </html>", "\b\f\n\r\t\x00\x1f", "日本語", "\u2028\u2029", "bad \xff utf8"}
+	for _, str := range strs {
+		e := &zero.JSONEncoder{}
+		e.String(str)
+		expected, err := json.Marshal(str)
+		assert.NoError(t, err)
+		assert.Equal(t, string(expected), string(e.Bytes()), "%q", str)
+	}
+
+	floats := []float64{0, 1, -1.5, 1e-7, 1e21, 123456789.125, math.SmallestNonzeroFloat64, math.MaxFloat64}
+	for _, f := range floats {
+		e := &zero.JSONEncoder{}
+		e.Float(f, 64)
+		expected, err := json.Marshal(f)
+		assert.NoError(t, err)
+		assert.Equal(t, string(expected), string(e.Bytes()))
+
+		if math.IsInf(float64(float32(f)), 0) {
+			continue
+		}
+		e = &zero.JSONEncoder{}
+		e.Float(float64(float32(f)), 32)
+		expected, err = json.Marshal(float32(f))
+		assert.NoError(t, err)
+		assert.Equal(t, string(expected), string(e.Bytes()))
+	}
+
+	e := &zero.JSONEncoder{}
+	e.Float(math.NaN(), 64)
+	assert.EqualError(t, e.Err(), "json: unsupported value: NaN")
+
+	users := []jsonUser{{}, {Name: "bob", Age: 42, Score: 1.5, Tags: []string{"a", "b"}}, {Tags: []string{}}}
+	for _, user := range users {
+		e := &zero.JSONEncoder{}
+		encodeJSONUser(e, &user)
+		expected, err := json.Marshal(user)
+		assert.NoError(t, err)
+		assert.Equal(t, string(expected), string(e.Bytes()))
+	}
+}
+
+func TestJSONDecoder(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected jsonUser
+		err      string
+	}{
+		{name: "Object", input: `{"name":"bob","age":42,"score":1.5,"tags":["a","b"]}`, expected: jsonUser{Name: "bob", Age: 42, Score: 1.5, Tags: []string{"a", "b"}}},
+		{name: "Whitespace", input: " {\n\t\"name\" : \"bob\" , \"tags\" : [ ] } ", expected: jsonUser{Name: "bob", Tags: []string{}}},
+		{name: "CaseInsensitiveKeys", input: `{"NAME":"bob","Age":1}`, expected: jsonUser{Name: "bob", Age: 1}},
+		{name: "UnknownKeys", input: `{"other":{"a":[1,true,null,"x"]},"name":"bob"}`, expected: jsonUser{Name: "bob"}},
+		{name: "Nulls", input: `{"name":null,"tags":null}`, expected: jsonUser{}},
+		{name: "Null", input: `null`, expected: jsonUser{}},
+		{name: "Escapes", input: `{"name":"a\"b\\c\/\né😀\ud800"}`, expected: jsonUser{Name: "a\"b\\c/\né😀\ufffd"}},
+		{name: "Exponent", input: `{"score":-1.5e3}`, expected: jsonUser{Score: -1500}},
+		{name: "FloatIntoInt", input: `{"age":1.5}`, err: "offset 10: expected integer"},
+		{name: "IntOverflow", input: `{"age":99999999999999999999}`, err: "offset 27: number out of range"},
+		{name: "StringIntoInt", input: `{"age":"1"}`, err: "offset 7: expected value"},
+		{name: "MissingComma", input: `{"name":"bob" "age":1}`, err: "offset 14: expected , or }"},
+		{name: "TrailingComma", input: `{"name":"bob",}`, err: "offset 14: expected object key"},
+		{name: "Truncated", input: `{"name":"bob"`, err: "unexpected end of JSON input"},
+		{name: "Empty", input: ``, err: "unexpected end of JSON input"},
+		{name: "NotObject", input: `[]`, err: "offset 0: expected object"},
+		{name: "ControlCharacter", input: "{\"name\":\"\x01\"}", err: "offset 9: invalid character in string"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var actual jsonUser
+			d := zero.NewJSONDecoder([]byte(test.input))
+			decodeJSONUser(d, &actual)
+			if test.err != "" {
+				assert.EqualError(t, d.Err(), test.err)
+				return
+			}
+			assert.NoError(t, d.Err())
+			assert.Equal(t, test.expected, actual)
+
+			var expected jsonUser
+			assert.NoError(t, json.Unmarshal([]byte(test.input), &expected))
+			assert.Equal(t, expected, actual, "should match encoding/json")
+		})
+	}
+}
+
+func TestJSONDecoderIntRange(t *testing.T) {
+	d := zero.NewJSONDecoder([]byte(`127`))
+	assert.Equal(t, int64(127), d.Int(8))
+	assert.NoError(t, d.Err())
+	d = zero.NewJSONDecoder([]byte(`-128`))
+	assert.Equal(t, int64(-128), d.Int(8))
+	assert.NoError(t, d.Err())
+	d = zero.NewJSONDecoder([]byte(`128`))
+	d.Int(8)
+	assert.EqualError(t, d.Err(), "offset 3: number out of range for int8")
+	d = zero.NewJSONDecoder([]byte(`-1 `))
+	d.Uint(8)
+	assert.EqualError(t, d.Err(), "offset 2: number out of range for uint8")
+}
+
+func TestJSONDecoderValue(t *testing.T) {
+	var actual struct {
+		Values map[string]int
+	}
+	d := zero.NewJSONDecoder([]byte(`{"values":{"a":1}}`))
+	d.BeginObject()
+	for d.More('}') {
+		switch d.Key("Values") {
+		case 0:
+			d.Value(&actual.Values)
+		default:
+			d.Skip()
+		}
+	}
+	assert.NoError(t, d.Err())
+	assert.Equal(t, map[string]int{"a": 1}, actual.Values)
+}
+
+func TestDecodeJSONRequest(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"bob"}`))
+	user, err := zero.DecodeJSONRequest(r, decodeJSONUser)
+	assert.NoError(t, err)
+	assert.Equal(t, jsonUser{Name: "bob"}, user)
+
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":1}`))
+	_, err = zero.DecodeJSONRequest(r, decodeJSONUser)
+	assert.EqualError(t, err, "400: failed to decode JSON request body: offset 8: expected string")
+
+	w := httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"bob"}`))
+	r.Body = http.MaxBytesReader(w, r.Body, 4)
+	_, err = zero.DecodeJSONRequest(r, decodeJSONUser)
+	assert.EqualError(t, err, "413: request body too large: http: request body too large")
+}
+
+func TestEncodeJSONResponse(t *testing.T) {
+	user := jsonUser{Name: "<bob>", Tags: []string{"a"}}
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	zero.EncodeResponse(slog.Default(), r, w, zero.EncodeError, zero.JSONResponse[jsonUser]{Value: user, Encode: encodeJSONUser}, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+
+	expected := httptest.NewRecorder()
+	zero.EncodeResponse(slog.Default(), r, expected, zero.EncodeError, user, nil)
+	assert.Equal(t, expected.Body.String(), w.Body.String())
+
+	data, err := json.Marshal(zero.JSONResponse[jsonUser]{Value: user, Encode: encodeJSONUser})
+	assert.NoError(t, err)
+	assert.Equal(t, strings.TrimSpace(expected.Body.String()), string(data))
+
+	w = httptest.NewRecorder()
+	zero.EncodeResponse(slog.Default(), r, w, zero.EncodeError, zero.JSONResponse[jsonUser]{Value: jsonUser{Score: math.Inf(1)}, Encode: encodeJSONUser}, nil)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
@@ -0,0 +1,18 @@
+package zero
+
+import "sync"
+
+// Lazy is a dependency of a provider whose construction is deferred until [Lazy.Get] is first called.
+//
+// Get is safe to call concurrently, and T will be constructed at most once.
+type Lazy[T any] struct {
+	get func() (T, error)
+}
+
+// NewLazy returns a Lazy that calls construct on first use.
+func NewLazy[T any](construct func() (T, error)) Lazy[T] {
+	return Lazy[T]{get: sync.OnceValues(construct)}
+}
+
+// Get returns T, constructing it on first use.
+func (l Lazy[T]) Get() (T, error) { return l.get() }
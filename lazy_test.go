@@ -0,0 +1,39 @@
+package zero_test
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/alecthomas/zero"
+)
+
+func TestLazy(t *testing.T) {
+	var calls atomic.Int32
+	lazy := zero.NewLazy(func() (string, error) {
+		calls.Add(1)
+		return "hello", nil
+	})
+	assert.Equal(t, int32(0), calls.Load())
+
+	wg := sync.WaitGroup{}
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := lazy.Get()
+			assert.NoError(t, err)
+			assert.Equal(t, "hello", value)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestLazyError(t *testing.T) {
+	lazy := zero.NewLazy(func() (string, error) { return "", errors.New("failed") })
+	_, err := lazy.Get()
+	assert.EqualError(t, err, "failed")
+}
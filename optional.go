@@ -0,0 +1,30 @@
+package zero
+
+// Optional is an optional dependency of a provider.
+//
+// If a provider for T exists the Optional will contain the constructed value, otherwise it will be empty rather than
+// the dependency being reported as missing.
+type Optional[T any] struct {
+	value T
+	ok    bool
+}
+
+// Some returns an Optional containing value.
+func Some[T any](value T) Optional[T] { return Optional[T]{value: value, ok: true} }
+
+// None returns an empty Optional.
+func None[T any]() Optional[T] { return Optional[T]{} }
+
+// Get returns the value and true if present, or the zero value of T and false otherwise.
+func (o Optional[T]) Get() (T, bool) { return o.value, o.ok }
+
+// Ok returns true if the Optional contains a value.
+func (o Optional[T]) Ok() bool { return o.ok }
+
+// Default returns the value if present, or def otherwise.
+func (o Optional[T]) Default(def T) T {
+	if o.ok {
+		return o.value
+	}
+	return def
+}
@@ -0,0 +1,23 @@
+package zero_test
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/alecthomas/zero"
+)
+
+func TestOptional(t *testing.T) {
+	some := zero.Some("hello")
+	value, ok := some.Get()
+	assert.True(t, ok)
+	assert.Equal(t, "hello", value)
+	assert.Equal(t, "hello", some.Default("world"))
+
+	none := zero.None[string]()
+	value, ok = none.Get()
+	assert.False(t, ok)
+	assert.Equal(t, "", value)
+	assert.False(t, none.Ok())
+	assert.Equal(t, "world", none.Default("world"))
+}
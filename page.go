@@ -0,0 +1,75 @@
+package zero
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// DefaultPageLimit is the number of items requested if the "limit" query parameter is not provided.
+const DefaultPageLimit = 50
+
+// MaxPageLimit is the maximum number of items that may be requested in a single page.
+const MaxPageLimit = 1000
+
+// PageRequest is a request for a page of results, decoded from the "limit" and "cursor" query parameters.
+//
+// API methods accepting a PageRequest should return a [Page].
+type PageRequest struct {
+	// Limit is the maximum number of items to return, between 1 and [MaxPageLimit].
+	Limit int
+	// Cursor is the opaque cursor returned in [Page.NextCursor] of the previous page, or empty for the first page.
+	Cursor string
+}
+
+// DecodePageRequest decodes a [PageRequest] from the query parameters of r.
+func DecodePageRequest(r *http.Request) (PageRequest, error) {
+	query := r.URL.Query()
+	out := PageRequest{Limit: DefaultPageLimit, Cursor: query.Get("cursor")}
+	if limit := query.Get("limit"); limit != "" {
+		var err error
+		out.Limit, err = strconv.Atoi(limit)
+		if err != nil {
+			return out, APIErrorf(http.StatusBadRequest, "limit must be a valid integer: %w", err)
+		}
+		if out.Limit < 1 || out.Limit > MaxPageLimit {
+			return out, APIErrorf(http.StatusBadRequest, "limit must be between 1 and %d", MaxPageLimit)
+		}
+	}
+	return out, nil
+}
+
+// Page is a page of results returned by an API method accepting a [PageRequest].
+//
+// It is encoded as a JSON envelope of the form:
+//
+//	{
+//	  "items": [...],
+//	  "nextCursor": "..."
+//	}
+//
+// If there are further results, a "Link" header with rel="next" is also set.
+type Page[T any] struct {
+	Items []T `json:"items"`
+	// NextCursor is the cursor for the next page of results, or empty if this is the last page.
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// Prepare readies p to be encoded as the response to r, replacing nil Items with an empty slice and setting the
+// "Link" header of w to the next page, if any.
+//
+// Generated handlers call it before encoding a returned Page with the configured [ResponseEncoder].
+func (p *Page[T]) Prepare(w http.ResponseWriter, r *http.Request) {
+	if p == nil {
+		return
+	}
+	if p.Items == nil {
+		p.Items = []T{}
+	}
+	if p.NextCursor != "" {
+		next := *r.URL
+		query := next.Query()
+		query.Set("cursor", p.NextCursor)
+		next.RawQuery = query.Encode()
+		w.Header().Set("Link", "<"+next.RequestURI()+`>; rel="next"`)
+	}
+}
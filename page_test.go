@@ -0,0 +1,60 @@
+package zero_test
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/alecthomas/zero"
+)
+
+func TestDecodePageRequest(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		query    string
+		expected zero.PageRequest
+		err      string
+	}{
+		{"Defaults", "", zero.PageRequest{Limit: zero.DefaultPageLimit}, ""},
+		{"LimitAndCursor", "?limit=10&cursor=abc", zero.PageRequest{Limit: 10, Cursor: "abc"}, ""},
+		{"InvalidLimit", "?limit=ten", zero.PageRequest{}, "limit must be a valid integer"},
+		{"ZeroLimit", "?limit=0", zero.PageRequest{}, "limit must be between 1 and 1000"},
+		{"LimitTooLarge", "?limit=1001", zero.PageRequest{}, "limit must be between 1 and 1000"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			r := httptest.NewRequest(http.MethodGet, "/users"+test.query, nil)
+			actual, err := zero.DecodePageRequest(r)
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), test.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestPage(t *testing.T) {
+	t.Parallel()
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/users?limit=2&cursor=a", nil)
+	page := zero.Page[string]{Items: []string{"alice", "bob"}, NextCursor: "b"}
+	page.Prepare(w, r)
+	zero.EncodeResponse(slog.Default(), r, w, zero.EncodeError, page, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"items":["alice","bob"],"nextCursor":"b"}`+"\n", w.Body.String())
+	assert.Equal(t, `</users?cursor=b&limit=2>; rel="next"`, w.Header().Get("Link"))
+
+	w = httptest.NewRecorder()
+	page = zero.Page[string]{}
+	page.Prepare(w, r)
+	zero.EncodeResponse(slog.Default(), r, w, zero.EncodeError, page, nil)
+	assert.Equal(t, `{"items":[]}`+"\n", w.Body.String())
+	assert.Equal(t, "", w.Header().Get("Link"))
+}
@@ -0,0 +1,90 @@
+package zero
+
+import (
+	"encoding"
+	"net/http"
+	"reflect"
+
+	"github.com/alecthomas/errors"
+)
+
+// DecodeParams decodes the path wildcards and query parameters of a request into the fields of the params struct T
+// tagged with `path:"<wildcard>"` and `query:"<name>"` respectively, eg.
+//
+//	type ListPostsParams struct {
+//		UserID string   `path:"id"`
+//		Limit  int      `query:"limit"`
+//		Tags   []string `query:"tag"`
+//		Before *string  `query:"before"`
+//	}
+//
+// Fields may be strings, integers, floats, bools or implement [encoding.TextUnmarshaler]. Query fields may also be
+// slices of those, which receive every value of the parameter, or pointers, which are nil if the parameter is absent.
+// Other query fields are left as their zero value if absent. Invalid values result in an [APIError] with a 400 status.
+func DecodeParams[T any](r *http.Request) (T, error) {
+	var out T
+	target := reflect.ValueOf(&out).Elem()
+	if target.Kind() != reflect.Struct {
+		return out, errors.Errorf("params type %s must be a struct", target.Type())
+	}
+	if err := decodeParamsStruct(r, target); err != nil {
+		return out, err
+	}
+	return out, nil
+}
+
+func decodeParamsStruct(r *http.Request, target reflect.Value) error {
+	query := r.URL.Query()
+	for i := range target.NumField() {
+		field := target.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		value := target.Field(i)
+		if name, ok := field.Tag.Lookup("path"); ok {
+			if err := decodeParameterValue(value, r.PathValue(name)); err != nil {
+				return APIErrorf(http.StatusBadRequest, "invalid path parameter %s: %w", name, err)
+			}
+			continue
+		}
+		name, ok := field.Tag.Lookup("query")
+		if !ok {
+			if field.Anonymous && value.Kind() == reflect.Struct {
+				if err := decodeParamsStruct(r, value); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		values := query[name]
+		if len(values) == 0 {
+			continue
+		}
+		if err := decodeQueryValues(value, values); err != nil {
+			return APIErrorf(http.StatusBadRequest, "invalid query parameter %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func decodeQueryValues(target reflect.Value, values []string) error {
+	switch target.Kind() {
+	case reflect.Pointer:
+		target.Set(reflect.New(target.Type().Elem()))
+		return decodeParameterValue(target.Elem(), values[0])
+	case reflect.Slice:
+		if _, ok := target.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return decodeParameterValue(target, values[0])
+		}
+		slice := reflect.MakeSlice(target.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := decodeParameterValue(slice.Index(i), value); err != nil {
+				return err
+			}
+		}
+		target.Set(slice)
+		return nil
+	default:
+		return decodeParameterValue(target, values[0])
+	}
+}
@@ -0,0 +1,56 @@
+package zero_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/alecthomas/zero"
+)
+
+type PageParams struct {
+	Limit int `query:"limit"`
+}
+
+type listPostsParams struct {
+	PageParams
+	UserID int      `path:"id"`
+	Tags   []string `query:"tag"`
+	Before *string  `query:"before"`
+	Draft  bool     `query:"draft"`
+}
+
+func TestDecodeParams(t *testing.T) {
+	decode := func(t *testing.T, url string) (listPostsParams, error) {
+		t.Helper()
+		var params listPostsParams
+		var err error
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /users/{id}/posts", func(w http.ResponseWriter, r *http.Request) {
+			params, err = zero.DecodeParams[listPostsParams](r)
+		})
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, url, nil))
+		return params, err
+	}
+
+	params, err := decode(t, "/users/42/posts?limit=10&tag=go&tag=http&draft=true")
+	assert.NoError(t, err)
+	assert.Equal(t, listPostsParams{
+		PageParams: PageParams{Limit: 10},
+		UserID:     42,
+		Tags:       []string{"go", "http"},
+		Draft:      true,
+	}, params)
+
+	params, err = decode(t, "/users/42/posts?before=abc")
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", *params.Before)
+	assert.Equal(t, 0, params.Limit)
+
+	_, err = decode(t, "/users/bob/posts")
+	assert.EqualError(t, err, `400: invalid path parameter id: expected an integer: strconv.ParseInt: parsing "bob": invalid syntax`)
+
+	_, err = decode(t, "/users/42/posts?limit=ten")
+	assert.EqualError(t, err, `400: invalid query parameter limit: expected an integer: strconv.ParseInt: parsing "ten": invalid syntax`)
+}
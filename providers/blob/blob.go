@@ -0,0 +1,53 @@
+// Package blob provides a [Store] abstraction over object storage, with S3, GCS and filesystem implementations.
+//
+// Zero includes a weak provider for each implementation, one of which must be selected with eg.
+// `--resolve github.com/alecthomas/zero/providers/blob.NewS3Store`.
+package blob
+
+import (
+	"context"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/errors"
+)
+
+// ErrNotFound is returned by [Store.Get] when there is no object with the key.
+var ErrNotFound = errors.New("blob not found")
+
+// ErrInvalidKey is returned when a key is not a clean, relative, slash-separated path.
+var ErrInvalidKey = errors.New("invalid blob key")
+
+// Object is the content of an object retrieved from a [Store], which must be closed.
+type Object struct {
+	io.ReadCloser
+	// ContentType is the MIME type of the object.
+	ContentType string
+	// Size is the size of the object in bytes, or -1 if unknown.
+	Size int64
+}
+
+// Store is an object store.
+//
+// Keys are slash-separated paths, eg. "avatars/123.png".
+type Store interface {
+	// Get retrieves the object with key, or returns [ErrNotFound].
+	Get(ctx context.Context, key string) (*Object, error)
+	// Put creates or replaces the object with key.
+	Put(ctx context.Context, key string, contentType string, body io.Reader) error
+	// Delete removes the object with key, if it exists.
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a URL that grants the bearer access to the object with key using method, either GET or PUT,
+	// until it expires.
+	SignedURL(ctx context.Context, method, key string, expires time.Duration) (string, error)
+}
+
+// ValidateKey returns [ErrInvalidKey] if key is not a clean, relative, slash-separated path.
+func ValidateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || path.Clean(key) != key || key == ".." || strings.HasPrefix(key, "../") {
+		return errors.Errorf("%q: %w", key, ErrInvalidKey)
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package blob
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestValidateKey(t *testing.T) {
+	for _, valid := range []string{"a", "avatars/123.png", "a/b/c.txt", "..a"} {
+		assert.NoError(t, ValidateKey(valid), valid)
+	}
+	for _, invalid := range []string{"", "/a", "a/", "a//b", "./a", "a/../b", "..", "../a"} {
+		assert.IsError(t, ValidateKey(invalid), ErrInvalidKey, invalid)
+	}
+}
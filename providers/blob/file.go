@@ -0,0 +1,226 @@
+package blob
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/errors"
+)
+
+//zero:config prefix="blob-file-"
+type FileConfig struct {
+	Root   string `help:"Directory to store objects in." default:"blobs"`
+	URL    string `help:"Base URL of the service, used to construct signed URLs." default:"http://127.0.0.1:8080"`
+	Secret string `help:"Secret used to sign URLs (defaults to a random secret, invalidating signed URLs on restart)." secret:""`
+}
+
+// FileStore is a [Store] that stores objects as files in a directory, for development and tests.
+//
+// The content type of objects is derived from the extension of their key. Signed URLs are served by [FileServer].
+type FileStore struct {
+	root   string
+	url    string
+	secret []byte
+	now    func() time.Time
+}
+
+var _ Store = (*FileStore)(nil)
+
+// NewFileStore creates a [Store] that stores objects as files in --blob-file-root.
+//
+//zero:provider weak require=NewFileServer
+func NewFileStore(server *FileServer) Store {
+	return server.store
+}
+
+func newFileStore(config FileConfig) (*FileStore, error) {
+	secret := []byte(config.Secret)
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	if err := os.MkdirAll(config.Root, 0o750); err != nil {
+		return nil, errors.Errorf("failed to create blob root: %w", err)
+	}
+	return &FileStore{root: config.Root, url: strings.TrimSuffix(config.URL, "/"), secret: secret, now: time.Now}, nil
+}
+
+func (f *FileStore) Get(ctx context.Context, key string) (*Object, error) {
+	path, err := f.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, errors.Errorf("%s: %w", key, ErrNotFound)
+	} else if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, errors.WithStack(err)
+	}
+	return &Object{ReadCloser: file, ContentType: contentType(key), Size: info.Size()}, nil
+}
+
+// Put creates or replaces the object with key, atomically replacing any existing file.
+func (f *FileStore) Put(ctx context.Context, key string, contentType string, body io.Reader) error {
+	path, err := f.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return errors.WithStack(err)
+	}
+	file, err := os.CreateTemp(filepath.Dir(path), ".blob-*")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer os.Remove(file.Name())
+	if _, err := io.Copy(file, body); err != nil {
+		_ = file.Close()
+		return errors.Errorf("%s: %w", key, err)
+	}
+	if err := file.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.Rename(file.Name(), path))
+}
+
+func (f *FileStore) Delete(ctx context.Context, key string) error {
+	path, err := f.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// SignedURL returns a URL under /_blob/ that is served by [FileServer].
+func (f *FileStore) SignedURL(ctx context.Context, method, key string, expires time.Duration) (string, error) {
+	if method != http.MethodGet && method != http.MethodPut {
+		return "", errors.Errorf("%s: unsupported method %s for signed URL", key, method)
+	}
+	if err := ValidateKey(key); err != nil {
+		return "", err
+	}
+	expiry := strconv.FormatInt(f.now().Add(expires).Unix(), 10)
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	query := url.Values{"expires": {expiry}, "signature": {f.signature(method, key, expiry)}}
+	return f.url + "/_blob/" + strings.Join(segments, "/") + "?" + query.Encode(), nil
+}
+
+// verify checks the signature of a request for the object with key.
+func (f *FileStore) verify(r *http.Request, key string) bool {
+	expiry := r.URL.Query().Get("expires")
+	expires, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil || f.now().Unix() > expires {
+		return false
+	}
+	signature, err := hex.DecodeString(r.URL.Query().Get("signature"))
+	if err != nil {
+		return false
+	}
+	expected, _ := hex.DecodeString(f.signature(r.Method, key, expiry))
+	return hmac.Equal(signature, expected)
+}
+
+func (f *FileStore) signature(method, key, expiry string) string {
+	h := hmac.New(sha256.New, f.secret)
+	h.Write([]byte(method + "\n" + key + "\n" + expiry))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (f *FileStore) path(key string) (string, error) {
+	if err := ValidateKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(f.root, filepath.FromSlash(key)), nil
+}
+
+func contentType(key string) string {
+	if contentType := mime.TypeByExtension(path.Ext(key)); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
+
+// FileServer serves the signed URLs of a [FileStore] under /_blob/.
+type FileServer struct {
+	store *FileStore
+}
+
+// NewFileServer creates the [FileServer] of a [FileStore], which is required by NewFileStore.
+//
+//zero:provider weak
+func NewFileServer(config FileConfig) (*FileServer, error) {
+	store, err := newFileStore(config)
+	if err != nil {
+		return nil, err
+	}
+	return &FileServer{store: store}, nil
+}
+
+// Download serves an object to a signed GET URL.
+//
+//zero:api GET /_blob/{key...}
+func (f *FileServer) Download(key string, w http.ResponseWriter, r *http.Request) {
+	if !f.store.verify(r, key) {
+		http.Error(w, "invalid or expired signature", http.StatusForbidden)
+		return
+	}
+	object, err := f.store.Get(r.Context(), key)
+	if errors.Is(err, ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer object.Close()
+	w.Header().Set("Content-Type", object.ContentType)
+	if file, ok := object.ReadCloser.(*os.File); ok {
+		if info, err := file.Stat(); err == nil {
+			http.ServeContent(w, r, key, info.ModTime(), file)
+			return
+		}
+	}
+	_, _ = io.Copy(w, object)
+}
+
+// Upload stores the body of a request to a signed PUT URL.
+//
+//zero:api PUT /_blob/{key...}
+func (f *FileServer) Upload(key string, w http.ResponseWriter, r *http.Request) {
+	if !f.store.verify(r, key) {
+		http.Error(w, "invalid or expired signature", http.StatusForbidden)
+		return
+	}
+	if err := f.store.Put(r.Context(), key, r.Header.Get("Content-Type"), r.Body); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
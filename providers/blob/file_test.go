@@ -0,0 +1,78 @@
+package blob
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestFileStore(t *testing.T) {
+	ctx := t.Context()
+	server, err := NewFileServer(FileConfig{Root: t.TempDir(), URL: "http://example.com"})
+	assert.NoError(t, err)
+	store := NewFileStore(server)
+
+	_, err = store.Get(ctx, "avatars/1.png")
+	assert.IsError(t, err, ErrNotFound)
+
+	assert.NoError(t, store.Put(ctx, "avatars/1.png", "image/png", strings.NewReader("png")))
+	object, err := store.Get(ctx, "avatars/1.png")
+	assert.NoError(t, err)
+	data, err := io.ReadAll(object)
+	assert.NoError(t, err)
+	assert.NoError(t, object.Close())
+	assert.Equal(t, "png", string(data))
+	assert.Equal(t, "image/png", object.ContentType)
+	assert.Equal(t, int64(3), object.Size)
+
+	assert.NoError(t, store.Delete(ctx, "avatars/1.png"))
+	_, err = store.Get(ctx, "avatars/1.png")
+	assert.IsError(t, err, ErrNotFound)
+	assert.NoError(t, store.Delete(ctx, "avatars/1.png"))
+
+	assert.IsError(t, store.Put(ctx, "../escape", "", strings.NewReader("")), ErrInvalidKey)
+}
+
+func TestFileServer(t *testing.T) {
+	ctx := t.Context()
+	server, err := NewFileServer(FileConfig{Root: t.TempDir(), URL: "http://example.com/", Secret: "secret"})
+	assert.NoError(t, err)
+	store := NewFileStore(server)
+
+	putURL, err := store.SignedURL(ctx, http.MethodPut, "docs/a b.txt", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(putURL, "http://example.com/_blob/docs/a%20b.txt?"), putURL)
+	w := httptest.NewRecorder()
+	server.Upload("docs/a b.txt", w, httptest.NewRequest(http.MethodPut, putURL, strings.NewReader("hello")))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	getURL, err := store.SignedURL(ctx, http.MethodGet, "docs/a b.txt", time.Minute)
+	assert.NoError(t, err)
+	w = httptest.NewRecorder()
+	server.Download("docs/a b.txt", w, httptest.NewRequest(http.MethodGet, getURL, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "hello", w.Body.String())
+	assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+
+	// A signed GET URL does not grant PUT, or access to another key.
+	w = httptest.NewRecorder()
+	server.Upload("docs/a b.txt", w, httptest.NewRequest(http.MethodPut, getURL, strings.NewReader("overwritten")))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = httptest.NewRecorder()
+	server.Download("docs/other.txt", w, httptest.NewRequest(http.MethodGet, getURL, nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Expired URLs are rejected.
+	server.store.now = func() time.Time { return time.Now().Add(time.Hour) }
+	w = httptest.NewRecorder()
+	server.Download("docs/a b.txt", w, httptest.NewRequest(http.MethodGet, getURL, nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	_, err = store.SignedURL(ctx, http.MethodDelete, "docs/a b.txt", time.Minute)
+	assert.Error(t, err)
+}
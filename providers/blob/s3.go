@@ -0,0 +1,186 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/errors"
+)
+
+//zero:config prefix="blob-s3-"
+type S3Config struct {
+	Bucket          string `help:"S3 bucket to store objects in."`
+	Region          string `help:"AWS region of the bucket." default:"us-east-1" env:"AWS_REGION"`
+	Endpoint        string `help:"URL of an S3-compatible service, eg. MinIO, addressing buckets by path (defaults to AWS)."`
+	AccessKeyID     string `help:"AWS access key ID." env:"AWS_ACCESS_KEY_ID"`
+	SecretAccessKey string `help:"AWS secret access key." env:"AWS_SECRET_ACCESS_KEY" secret:""`
+	SessionToken    string `help:"AWS session token for temporary credentials." env:"AWS_SESSION_TOKEN" secret:""`
+}
+
+//zero:config prefix="blob-gcs-"
+type GCSConfig struct {
+	Bucket   string `help:"GCS bucket to store objects in."`
+	Endpoint string `help:"URL of the GCS XML API." default:"https://storage.googleapis.com"`
+	AccessID string `help:"Access ID of a GCS HMAC key." env:"GCS_HMAC_ACCESS_ID"`
+	Secret   string `help:"Secret of a GCS HMAC key." env:"GCS_HMAC_SECRET" secret:""`
+}
+
+// S3Store is a [Store] backed by S3, or a service implementing the S3 API such as the GCS XML API.
+//
+// Requests are signed with AWS Signature Version 4 using static credentials.
+type S3Store struct {
+	client   *http.Client
+	signer   sigV4
+	endpoint *url.URL
+	bucket   string
+	// pathStyle addresses the bucket in the path rather than the host.
+	pathStyle bool
+}
+
+var _ Store = (*S3Store)(nil)
+
+// NewS3Store creates a [Store] backed by the S3 bucket --blob-s3-bucket.
+//
+//zero:provider weak
+func NewS3Store(config S3Config) (Store, error) {
+	if config.Bucket == "" {
+		return nil, errors.Errorf("--blob-s3-bucket is required")
+	}
+	endpoint := "https://s3." + config.Region + ".amazonaws.com"
+	if config.Endpoint != "" {
+		endpoint = config.Endpoint
+	}
+	return newS3Store(endpoint, config.Bucket, config.Endpoint != "", sigV4{
+		accessKeyID:     config.AccessKeyID,
+		secretAccessKey: config.SecretAccessKey,
+		sessionToken:    config.SessionToken,
+		region:          config.Region,
+		now:             time.Now,
+	})
+}
+
+// NewGCSStore creates a [Store] backed by the GCS bucket --blob-gcs-bucket, using the S3-compatible XML API
+// authenticated with an HMAC key.
+//
+//zero:provider weak
+func NewGCSStore(config GCSConfig) (Store, error) {
+	if config.Bucket == "" {
+		return nil, errors.Errorf("--blob-gcs-bucket is required")
+	}
+	return newS3Store(config.Endpoint, config.Bucket, true, sigV4{
+		accessKeyID:     config.AccessID,
+		secretAccessKey: config.Secret,
+		region:          "auto",
+		now:             time.Now,
+	})
+}
+
+func newS3Store(endpoint, bucket string, pathStyle bool, signer sigV4) (*S3Store, error) {
+	u, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil {
+		return nil, errors.Errorf("invalid endpoint %q: %w", endpoint, err)
+	}
+	return &S3Store{client: http.DefaultClient, signer: signer, endpoint: u, bucket: bucket, pathStyle: pathStyle}, nil
+}
+
+func (s *S3Store) Get(ctx context.Context, key string) (*Object, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Object{ReadCloser: resp.Body, ContentType: resp.Header.Get("Content-Type"), Size: resp.ContentLength}, nil
+}
+
+// Put creates or replaces the object with key.
+//
+// Bodies other than a [bytes.Buffer], [bytes.Reader] or [strings.Reader] are read into memory, as the S3 API requires
+// the length of the object up front.
+func (s *S3Store) Put(ctx context.Context, key string, contentType string, body io.Reader) error {
+	switch body.(type) {
+	case *bytes.Buffer, *bytes.Reader, *strings.Reader:
+	default:
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return errors.Errorf("%s: failed to read body: %w", key, err)
+		}
+		body = bytes.NewReader(data)
+	}
+	resp, err := s.do(ctx, http.MethodPut, key, contentType, body)
+	if err != nil {
+		return err
+	}
+	return errors.WithStack(resp.Body.Close())
+}
+
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, "", nil)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return errors.WithStack(resp.Body.Close())
+}
+
+func (s *S3Store) SignedURL(ctx context.Context, method, key string, expires time.Duration) (string, error) {
+	if method != http.MethodGet && method != http.MethodPut {
+		return "", errors.Errorf("%s: unsupported method %s for signed URL", key, method)
+	}
+	u, err := s.objectURL(key)
+	if err != nil {
+		return "", err
+	}
+	s.signer.presign(method, u, expires)
+	return u.String(), nil
+}
+
+func (s *S3Store) objectURL(key string) (*url.URL, error) {
+	if err := ValidateKey(key); err != nil {
+		return nil, err
+	}
+	u := *s.endpoint
+	if s.pathStyle {
+		u.Path += "/" + s.bucket + "/" + key
+	} else {
+		u.Host = s.bucket + "." + u.Host
+		u.Path += "/" + key
+	}
+	u.RawPath = awsEscape(u.Path, true)
+	return &u, nil
+}
+
+// do sends a signed request for the object with key, returning an error for unsuccessful responses.
+func (s *S3Store) do(ctx context.Context, method, key, contentType string, body io.Reader) (*http.Response, error) {
+	u, err := s.objectURL(key)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	s.signer.sign(req)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, errors.Errorf("%s %s: %w", method, key, err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		_ = resp.Body.Close()
+		return nil, errors.Errorf("%s: %w", key, ErrNotFound)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, errors.Errorf("%s %s: %s", method, key, strings.TrimSpace(fmt.Sprintf("%s %s", resp.Status, detail)))
+	}
+	return resp, nil
+}
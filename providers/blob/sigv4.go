@@ -0,0 +1,133 @@
+package blob
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	sigV4Algorithm  = "AWS4-HMAC-SHA256"
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+// sigV4 signs requests with AWS Signature Version 4, as used by S3 and the GCS XML API.
+type sigV4 struct {
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+	region          string
+	now             func() time.Time
+}
+
+// sign adds the authorization headers of a request with an unsigned payload.
+func (s sigV4) sign(r *http.Request) {
+	now := s.now().UTC()
+	r.Header.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	r.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+	if s.sessionToken != "" {
+		r.Header.Set("X-Amz-Security-Token", s.sessionToken)
+	}
+	headers := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	if s.sessionToken != "" {
+		headers = append(headers, "x-amz-security-token")
+	}
+	scope := s.scope(now)
+	signature := s.signature(now, s.canonicalRequest(r, headers))
+	r.Header.Set("Authorization", sigV4Algorithm+" Credential="+s.accessKeyID+"/"+scope+
+		", SignedHeaders="+strings.Join(headers, ";")+", Signature="+signature)
+}
+
+// presign adds query parameters to u that authorise a request with method until it expires.
+func (s sigV4) presign(method string, u *url.URL, expires time.Duration) {
+	now := s.now().UTC()
+	query := u.Query()
+	query.Set("X-Amz-Algorithm", sigV4Algorithm)
+	query.Set("X-Amz-Credential", s.accessKeyID+"/"+s.scope(now))
+	query.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expires/time.Second)))
+	query.Set("X-Amz-SignedHeaders", "host")
+	if s.sessionToken != "" {
+		query.Set("X-Amz-Security-Token", s.sessionToken)
+	}
+	u.RawQuery = canonicalQuery(query)
+	r := &http.Request{Method: method, URL: u, Host: u.Host, Header: http.Header{}}
+	signature := s.signature(now, s.canonicalRequest(r, []string{"host"}))
+	u.RawQuery += "&X-Amz-Signature=" + signature
+}
+
+func (s sigV4) scope(now time.Time) string {
+	return now.Format("20060102") + "/" + s.region + "/s3/aws4_request"
+}
+
+func (s sigV4) canonicalRequest(r *http.Request, headers []string) string {
+	w := &strings.Builder{}
+	w.WriteString(r.Method + "\n")
+	w.WriteString(awsEscape(r.URL.Path, true) + "\n")
+	w.WriteString(canonicalQuery(r.URL.Query()) + "\n")
+	for _, header := range headers {
+		value := r.Header.Get(header)
+		if header == "host" {
+			value = r.Host
+			if value == "" {
+				value = r.URL.Host
+			}
+		}
+		w.WriteString(header + ":" + strings.TrimSpace(value) + "\n")
+	}
+	w.WriteString("\n" + strings.Join(headers, ";") + "\n")
+	w.WriteString(unsignedPayload)
+	return w.String()
+}
+
+func (s sigV4) signature(now time.Time, canonicalRequest string) string {
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := sigV4Algorithm + "\n" + now.Format("20060102T150405Z") + "\n" + s.scope(now) + "\n" + hex.EncodeToString(hash[:])
+	key := hmacSHA256([]byte("AWS4"+s.secretAccessKey), now.Format("20060102"))
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// canonicalQuery encodes query parameters sorted by key, as required by SigV4.
+func canonicalQuery(query url.Values) string {
+	var parts []string
+	for _, key := range slices.Sorted(maps.Keys(query)) {
+		values := slices.Clone(query[key])
+		slices.Sort(values)
+		for _, value := range values {
+			parts = append(parts, awsEscape(key, false)+"="+awsEscape(value, false))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// awsEscape percent-encodes all but the unreserved characters of s, and optionally slashes.
+func awsEscape(s string, keepSlash bool) string {
+	w := &strings.Builder{}
+	for _, b := range []byte(s) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9', b == '-', b == '.', b == '_', b == '~':
+			w.WriteByte(b)
+		case b == '/' && keepSlash:
+			w.WriteByte(b)
+		default:
+			w.WriteString("%" + strings.ToUpper(hex.EncodeToString([]byte{b})))
+		}
+	}
+	return w.String()
+}
@@ -0,0 +1,79 @@
+// Package breaker provides circuit breakers for outbound dependencies.
+//
+// A [zero.Breaker] is injected for each dependency, and configured by its own flags, eg.
+//
+//	// Configured with --breaker-git-hub-failures, etc.
+//	func NewGitHub(client httpclient.Client[GitHub], breaker zero.Breaker[GitHub]) *GitHub {
+//		client.Transport = breaker.RoundTripper(client.Transport)
+//		...
+//	}
+package breaker
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/alecthomas/errors"
+	"github.com/alecthomas/zero"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Config of the [zero.Breaker] for T.
+//
+//zero:config prefix="breaker-${type}-"
+type Config[T any] struct {
+	zero.BreakerConfig
+	Telemetry bool `help:"Record circuit breaker state changes and rejections with OpenTelemetry metrics." default:"true" negatable:""`
+}
+
+// New creates the [zero.Breaker] for T, logging its state changes.
+//
+//zero:provider weak
+func New[T any](logger *slog.Logger, config Config[T]) (zero.Breaker[T], error) {
+	hooks, err := Hooks(logger, config.Telemetry)
+	if err != nil {
+		return zero.Breaker[T]{}, err
+	}
+	return zero.NewBreaker[T](config.BreakerConfig, hooks), nil
+}
+
+// Hooks returns [zero.BreakerHooks] that log state changes, and if telemetry is true, count state changes and rejected
+// calls with the zero.breaker.transitions and zero.breaker.rejections metrics of the global OpenTelemetry provider.
+func Hooks(logger *slog.Logger, telemetry bool) (zero.BreakerHooks, error) {
+	hooks := zero.BreakerHooks{
+		OnStateChange: func(name string, from, to zero.BreakerState) {
+			level := slog.LevelInfo
+			if to == zero.BreakerOpen {
+				level = slog.LevelWarn
+			}
+			logger.Log(context.Background(), level, "Circuit breaker state changed", "breaker", name, "from", from, "to", to)
+		},
+	}
+	if !telemetry {
+		return hooks, nil
+	}
+	meter := otel.Meter("github.com/alecthomas/zero/providers/breaker")
+	transitions, err := meter.Int64Counter("zero.breaker.transitions", metric.WithDescription("Number of circuit breaker state changes."))
+	if err != nil {
+		return hooks, errors.Errorf("failed to create transitions counter: %w", err)
+	}
+	rejections, err := meter.Int64Counter("zero.breaker.rejections", metric.WithDescription("Number of calls rejected by open circuit breakers."))
+	if err != nil {
+		return hooks, errors.Errorf("failed to create rejections counter: %w", err)
+	}
+	log := hooks.OnStateChange
+	hooks.OnStateChange = func(name string, from, to zero.BreakerState) {
+		log(name, from, to)
+		transitions.Add(context.Background(), 1, metric.WithAttributes(
+			attribute.String("breaker", name),
+			attribute.String("from", from.String()),
+			attribute.String("to", to.String()),
+		))
+	}
+	hooks.OnReject = func(name string) {
+		rejections.Add(context.Background(), 1, metric.WithAttributes(attribute.String("breaker", name)))
+	}
+	return hooks, nil
+}
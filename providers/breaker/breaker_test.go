@@ -0,0 +1,30 @@
+package breaker_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/alecthomas/zero"
+	"github.com/alecthomas/zero/providers/breaker"
+)
+
+type Database struct{}
+
+func TestNew(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewTextHandler(buf, nil))
+	b, err := breaker.New[Database](logger, breaker.Config[Database]{
+		BreakerConfig: zero.BreakerConfig{Failures: 1, Cooldown: time.Minute},
+		Telemetry:     true,
+	})
+	assert.NoError(t, err)
+	_ = b.Do(t.Context(), func(ctx context.Context) error { return errors.New("failed") })
+	err = b.Do(t.Context(), func(ctx context.Context) error { return nil })
+	assert.IsError(t, err, zero.ErrBreakerOpen)
+	assert.Contains(t, buf.String(), `level=WARN msg="Circuit breaker state changed" breaker=breaker_test.Database from=closed to=open`)
+}
@@ -0,0 +1,26 @@
+// Package cache provides a generic key/value cache, with in-memory and Redis implementations.
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+//zero:config prefix="cache-"
+type Config struct {
+	TTL  time.Duration `help:"Default time-to-live of cached values." default:"5m"`
+	Size int           `help:"Maximum number of entries in each in-memory cache." default:"10000"`
+}
+
+// Cache is a cache of values of type V keyed by K.
+//
+// Zero includes weak in-memory and Redis implementations, one of which must be selected with --resolve.
+type Cache[K comparable, V any] interface {
+	// Get returns the cached value for key, and false if there is no value or it has expired.
+	Get(ctx context.Context, key K) (V, bool, error)
+	// Set caches value for key, expiring after ttl, or after the configured default TTL if ttl is
+	// zero. Values set with a negative ttl do not expire.
+	Set(ctx context.Context, key K, value V, ttl time.Duration) error
+	// Delete removes the value for key, if any.
+	Delete(ctx context.Context, key K) error
+}
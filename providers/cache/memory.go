@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/zero"
+)
+
+// MemoryCache is a [Cache] that holds values in memory, evicting the least recently used values once it is full.
+type MemoryCache[K comparable, V any] struct {
+	lock    sync.Mutex
+	ttl     time.Duration
+	size    int
+	entries map[K]*list.Element
+	lru     *list.List // Most recently used first.
+	clock   zero.Clock
+}
+
+type memoryEntry[K comparable, V any] struct {
+	key     K
+	value   V
+	expires time.Time
+}
+
+var _ Cache[string, string] = (*MemoryCache[string, string])(nil)
+
+// NewMemoryCache creates a [Cache] that holds up to --cache-size values in memory.
+//
+// Values are not shared between replicas of a service, which makes it suitable for development and tests. Values are
+// stored as is, so mutating a cached pointer, slice or map mutates the cached value. Values expire according to
+// [zero.Clock].
+//
+//zero:provider weak
+func NewMemoryCache[K comparable, V any](config Config, clock zero.Clock) Cache[K, V] {
+	return &MemoryCache[K, V]{
+		ttl:     config.TTL,
+		size:    config.Size,
+		entries: map[K]*list.Element{},
+		lru:     list.New(),
+		clock:   clock,
+	}
+}
+
+func (m *MemoryCache[K, V]) Get(ctx context.Context, key K) (V, bool, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	element, ok := m.entries[key]
+	if !ok {
+		var zero V
+		return zero, false, nil
+	}
+	entry := element.Value.(*memoryEntry[K, V])
+	if !entry.expires.IsZero() && !m.clock.Now().Before(entry.expires) {
+		m.remove(element)
+		var zero V
+		return zero, false, nil
+	}
+	m.lru.MoveToFront(element)
+	return entry.value, true, nil
+}
+
+func (m *MemoryCache[K, V]) Set(ctx context.Context, key K, value V, ttl time.Duration) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if ttl == 0 {
+		ttl = m.ttl
+	}
+	var expires time.Time
+	if ttl > 0 {
+		expires = m.clock.Now().Add(ttl)
+	}
+	if element, ok := m.entries[key]; ok {
+		entry := element.Value.(*memoryEntry[K, V])
+		entry.value = value
+		entry.expires = expires
+		m.lru.MoveToFront(element)
+		return nil
+	}
+	m.entries[key] = m.lru.PushFront(&memoryEntry[K, V]{key: key, value: value, expires: expires})
+	for m.size > 0 && m.lru.Len() > m.size {
+		m.remove(m.lru.Back())
+	}
+	return nil
+}
+
+func (m *MemoryCache[K, V]) Delete(ctx context.Context, key K) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if element, ok := m.entries[key]; ok {
+		m.remove(element)
+	}
+	return nil
+}
+
+func (m *MemoryCache[K, V]) remove(element *list.Element) {
+	entry := m.lru.Remove(element).(*memoryEntry[K, V])
+	delete(m.entries, entry.key)
+}
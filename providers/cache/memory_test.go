@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/alecthomas/zero"
+	"github.com/alecthomas/zero/providers/clock/clocktest"
+)
+
+type testUser struct {
+	Name string `json:"name"`
+}
+
+// testCache exercises a cache whose clock is advanced by advance.
+func testCache(t *testing.T, cache Cache[string, testUser], advance func(time.Duration)) {
+	t.Helper()
+	ctx := t.Context()
+
+	_, ok, err := cache.Get(ctx, "alice")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.NoError(t, cache.Set(ctx, "alice", testUser{Name: "Alice"}, 0))
+	assert.NoError(t, cache.Set(ctx, "bob", testUser{Name: "Bob"}, time.Second))
+	assert.NoError(t, cache.Set(ctx, "carol", testUser{Name: "Carol"}, -1))
+	user, ok, err := cache.Get(ctx, "alice")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, testUser{Name: "Alice"}, user)
+
+	advance(2 * time.Second)
+	_, ok, err = cache.Get(ctx, "bob")
+	assert.NoError(t, err)
+	assert.False(t, ok, "expected bob to have expired")
+	_, ok, err = cache.Get(ctx, "alice")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	advance(time.Minute)
+	_, ok, err = cache.Get(ctx, "alice")
+	assert.NoError(t, err)
+	assert.False(t, ok, "expected alice to have expired after the default TTL")
+	_, ok, err = cache.Get(ctx, "carol")
+	assert.NoError(t, err)
+	assert.True(t, ok, "expected carol to never expire")
+
+	assert.NoError(t, cache.Delete(ctx, "carol"))
+	_, ok, err = cache.Get(ctx, "carol")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.NoError(t, cache.Delete(ctx, "carol"))
+}
+
+func TestMemoryCache(t *testing.T) {
+	clock := clocktest.NewForTesting(time.Now())
+	cache := NewMemoryCache[string, testUser](Config{TTL: time.Minute, Size: 10}, clock)
+	testCache(t, cache, clock.Advance)
+}
+
+func TestMemoryCacheEviction(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemoryCache[int, string](Config{Size: 2}, zero.RealClock{})
+	assert.NoError(t, cache.Set(ctx, 1, "one", 0))
+	assert.NoError(t, cache.Set(ctx, 2, "two", 0))
+	// Using 1 makes 2 the least recently used.
+	_, ok, err := cache.Get(ctx, 1)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.NoError(t, cache.Set(ctx, 3, "three", 0))
+
+	_, ok, err = cache.Get(ctx, 2)
+	assert.NoError(t, err)
+	assert.False(t, ok, "expected 2 to have been evicted")
+	for _, key := range []int{1, 3} {
+		_, ok, err = cache.Get(ctx, key)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	}
+}
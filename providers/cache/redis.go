@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/alecthomas/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+//zero:config prefix="cache-redis-"
+type RedisConfig struct {
+	Prefix string `help:"Prefix of the keys of cached values." default:"cache:"`
+}
+
+// RedisCache is a [Cache] that holds JSON encoded values in Redis, shared between replicas of a service.
+type RedisCache[K comparable, V any] struct {
+	client *redis.Client
+	ttl    time.Duration
+	prefix string
+}
+
+var _ Cache[string, string] = (*RedisCache[string, string])(nil)
+
+// NewRedisCache creates a [Cache] backed by Redis, using the client from
+// [github.com/alecthomas/zero/providers/redis.New].
+//
+// Keys are formatted with [fmt.Sprint] and namespaced by --cache-redis-prefix and the types of K and V, eg.
+// "cache:int:example.com/service.User:42", so that caches of the same values with different key types don't collide.
+//
+//zero:provider weak
+func NewRedisCache[K comparable, V any](client *redis.Client, config Config, redisConfig RedisConfig) Cache[K, V] {
+	prefix := redisConfig.Prefix + typeName(reflect.TypeFor[K]()) + ":" + typeName(reflect.TypeFor[V]()) + ":"
+	return &RedisCache[K, V]{client: client, ttl: config.TTL, prefix: prefix}
+}
+
+// typeName returns the fully qualified name of t, ignoring pointers.
+func typeName(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.PkgPath() != "" {
+		return t.PkgPath() + "." + t.Name()
+	}
+	return t.String()
+}
+
+func (r *RedisCache[K, V]) Get(ctx context.Context, key K) (V, bool, error) {
+	var value V
+	data, err := r.client.Get(ctx, r.key(key)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return value, false, nil
+	} else if err != nil {
+		return value, false, errors.Errorf("failed to get cached value: %w", err)
+	}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return value, false, errors.Errorf("failed to decode cached value: %w", err)
+	}
+	return value, true, nil
+}
+
+func (r *RedisCache[K, V]) Set(ctx context.Context, key K, value V, ttl time.Duration) error {
+	if ttl == 0 {
+		ttl = r.ttl
+	}
+	if ttl < 0 {
+		// Redis treats a zero expiration as no expiration.
+		ttl = 0
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return errors.Errorf("failed to encode cached value: %w", err)
+	}
+	if err := r.client.Set(ctx, r.key(key), data, ttl).Err(); err != nil {
+		return errors.Errorf("failed to set cached value: %w", err)
+	}
+	return nil
+}
+
+func (r *RedisCache[K, V]) Delete(ctx context.Context, key K) error {
+	if err := r.client.Del(ctx, r.key(key)).Err(); err != nil {
+		return errors.Errorf("failed to delete cached value: %w", err)
+	}
+	return nil
+}
+
+func (r *RedisCache[K, V]) key(key K) string {
+	return r.prefix + fmt.Sprint(key)
+}
@@ -0,0 +1,37 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRedisCache(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	cache := NewRedisCache[string, testUser](client, Config{TTL: time.Minute}, RedisConfig{Prefix: "cache:"})
+	assert.NoError(t, cache.Set(t.Context(), "dave", testUser{Name: "Dave"}, 0))
+	server.CheckGet(t, "cache:string:github.com/alecthomas/zero/providers/cache.testUser:dave", `{"name":"Dave"}`)
+	testCache(t, cache, server.FastForward)
+}
+
+func TestRedisCacheKeyTypes(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	byName := NewRedisCache[string, testUser](client, Config{TTL: time.Minute}, RedisConfig{Prefix: "cache:"})
+	byID := NewRedisCache[int, testUser](client, Config{TTL: time.Minute}, RedisConfig{Prefix: "cache:"})
+	assert.NoError(t, byName.Set(t.Context(), "1", testUser{Name: "Dave"}, 0))
+	_, ok, err := byID.Get(t.Context(), 1)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.NoError(t, byID.Set(t.Context(), 1, testUser{Name: "Alice"}, 0))
+	user, ok, err := byName.Get(t.Context(), "1")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, testUser{Name: "Dave"}, user)
+}
@@ -0,0 +1,12 @@
+// Package clock provides the default [zero.Clock].
+//
+// Tests can override it with a fake, such as [github.com/alecthomas/zero/providers/clock/clocktest.Clock], to control
+// the time seen by cron schedules, leases, pubsub backoff and caches.
+package clock
+
+import "github.com/alecthomas/zero"
+
+// Real returns a [zero.Clock] backed by the system clock.
+//
+//zero:provider weak
+func Real() zero.Clock { return zero.RealClock{} }
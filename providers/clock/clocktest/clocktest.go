@@ -0,0 +1,72 @@
+// Package clocktest provides a fake [zero.Clock] for deterministic tests.
+package clocktest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/alecthomas/zero"
+)
+
+// Clock is a fake [zero.Clock] whose time only moves when advanced.
+type Clock struct {
+	lock    sync.Mutex
+	now     time.Time
+	waiters []waiter
+}
+
+type waiter struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+var _ zero.Clock = (*Clock)(nil)
+
+// NewForTesting creates a fake [zero.Clock] starting at now.
+func NewForTesting(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+func (c *Clock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+// After returns a channel that receives the time once the clock has been advanced by at least d.
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, waiter{deadline: c.now.Add(d), ch: ch})
+	return ch
+}
+
+// Advance the clock by d, firing any channels returned by [Clock.After] that are due.
+func (c *Clock) Advance(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.now = c.now.Add(d)
+	pending := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.deadline.After(c.now) {
+			pending = append(pending, w)
+			continue
+		}
+		w.ch <- c.now
+	}
+	c.waiters = pending
+}
+
+// Waiters returns the number of channels returned by [Clock.After] that have not yet fired.
+//
+// Tests can poll this to wait until the code under test is blocked on the clock before advancing it.
+func (c *Clock) Waiters() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return len(c.waiters)
+}
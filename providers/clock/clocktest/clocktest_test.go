@@ -0,0 +1,34 @@
+package clocktest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestClock(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewForTesting(start)
+	assert.Equal(t, start, clock.Now())
+
+	immediate := clock.After(0)
+	assert.Equal(t, start, <-immediate)
+
+	second := clock.After(time.Second)
+	minute := clock.After(time.Minute)
+	assert.Equal(t, 2, clock.Waiters())
+
+	clock.Advance(time.Second)
+	assert.Equal(t, start.Add(time.Second), <-second)
+	select {
+	case <-minute:
+		t.Fatal("minute should not have fired")
+	default:
+	}
+	assert.Equal(t, 1, clock.Waiters())
+
+	clock.Advance(time.Hour)
+	assert.Equal(t, start.Add(time.Hour+time.Second), <-minute)
+	assert.Equal(t, 0, clock.Waiters())
+}
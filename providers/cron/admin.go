@@ -0,0 +1,142 @@
+package cron
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/alecthomas/errors"
+	"github.com/alecthomas/zero"
+)
+
+// Admin exposes cron job status, history and control as an admin API under /_admin/api/cron.
+//
+// It is not enabled by default, and must be selected with eg.
+// `--resolve github.com/alecthomas/zero/providers/cron.NewAdmin`.
+type Admin struct {
+	scheduler *Scheduler
+	history   History
+}
+
+//zero:provider weak
+func NewAdmin(scheduler *Scheduler, history History) *Admin {
+	return &Admin{scheduler: scheduler, history: history}
+}
+
+// JobDetail is the status of a cron job along with its recent runs.
+type JobDetail struct {
+	JobStatus
+	History []Run `json:"history"`
+}
+
+type HistoryQuery struct {
+	Limit int `qstring:"limit"`
+}
+
+// ListJobs returns the status of all cron jobs.
+//
+//zero:api GET /_admin/api/cron admin
+func (a *Admin) ListJobs() []JobStatus {
+	return a.scheduler.Jobs()
+}
+
+// GetJob returns the status and recent runs of a cron job.
+//
+//zero:api GET /_admin/api/cron/{job} admin
+func (a *Admin) GetJob(ctx context.Context, job string, query HistoryQuery) (JobDetail, error) {
+	status, err := a.scheduler.Job(job)
+	if err != nil {
+		return JobDetail{}, jobError(err)
+	}
+	if query.Limit <= 0 {
+		query.Limit = 20
+	}
+	runs, err := a.history.Runs(ctx, job, query.Limit)
+	if err != nil {
+		return JobDetail{}, errors.Errorf("failed to retrieve history of %s: %w", job, err)
+	}
+	return JobDetail{JobStatus: status, History: runs}, nil
+}
+
+// TriggerJob runs a cron job as soon as possible.
+//
+//zero:api POST /_admin/api/cron/{job}/trigger admin
+func (a *Admin) TriggerJob(job string) error {
+	return jobError(a.scheduler.Trigger(job))
+}
+
+// PauseJob stops a cron job from running on its schedule.
+//
+//zero:api POST /_admin/api/cron/{job}/pause admin
+func (a *Admin) PauseJob(job string) error {
+	return jobError(a.scheduler.Pause(job))
+}
+
+// ResumeJob resumes a paused cron job.
+//
+//zero:api POST /_admin/api/cron/{job}/resume admin
+func (a *Admin) ResumeJob(job string) error {
+	return jobError(a.scheduler.Resume(job))
+}
+
+// Metrics returns cron job metrics in the Prometheus text exposition format.
+//
+//zero:api GET /_admin/metrics/cron admin
+func (a *Admin) Metrics() Metrics {
+	return Metrics(a.scheduler.Jobs())
+}
+
+func jobError(err error) error {
+	if errors.Is(err, ErrUnknownJob) {
+		return zero.APIErrorf(http.StatusNotFound, "%s", err)
+	}
+	return err
+}
+
+// Metrics of cron jobs, served in the Prometheus text exposition format.
+type Metrics []JobStatus
+
+var _ http.Handler = Metrics(nil)
+
+func (m Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	_, _ = w.Write([]byte(m.String()))
+}
+
+func (m Metrics) String() string {
+	w := &strings.Builder{}
+	metric := func(name, kind, help string, value func(job JobStatus) (float64, bool)) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+		for _, job := range m {
+			if v, ok := value(job); ok {
+				fmt.Fprintf(w, "%s{job=%q} %g\n", name, job.Name, v)
+			}
+		}
+	}
+	metric("zero_cron_runs_total", "counter", "Number of runs of the cron job.", func(job JobStatus) (float64, bool) {
+		return float64(job.Runs), true
+	})
+	metric("zero_cron_failures_total", "counter", "Number of failed runs of the cron job.", func(job JobStatus) (float64, bool) {
+		return float64(job.Failures), true
+	})
+	metric("zero_cron_paused", "gauge", "Whether the cron job is paused.", func(job JobStatus) (float64, bool) {
+		if job.Paused {
+			return 1, true
+		}
+		return 0, true
+	})
+	metric("zero_cron_last_duration_seconds", "gauge", "Duration of the last run of the cron job.", func(job JobStatus) (float64, bool) {
+		if job.LastRun == nil {
+			return 0, false
+		}
+		return job.LastRun.Duration.Seconds(), true
+	})
+	metric("zero_cron_last_success_timestamp_seconds", "gauge", "Time of the last successful run of the cron job.", func(job JobStatus) (float64, bool) {
+		if job.LastSuccess.IsZero() {
+			return 0, false
+		}
+		return float64(job.LastSuccess.Unix()), true
+	})
+	return w.String()
+}
@@ -6,18 +6,26 @@ import (
 	"fmt"
 	"log/slog"
 	"slices"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/alecthomas/errors"
+	"github.com/alecthomas/zero"
 	"github.com/alecthomas/zero/providers/leases"
 )
 
 type Schedule struct {
-	name    string
-	lastRun time.Time
-	period  time.Duration
-	run     Job
+	name      string
+	lastRun   time.Time
+	period    time.Duration
+	run       Job
+	paused    bool
+	triggered bool
+	runs      int64
+	failures  int64
+	last      *Run
+	lastOK    time.Time
 }
 
 // NextRun returns the next time the job should run.
@@ -25,27 +33,101 @@ func (s *Schedule) NextRun() time.Time {
 	return nextRun(s.period, s.lastRun)
 }
 
+func (s *Schedule) status() JobStatus {
+	status := JobStatus{
+		Name:        s.name,
+		Schedule:    s.period.String(),
+		NextRun:     s.NextRun(),
+		Paused:      s.paused,
+		Runs:        s.runs,
+		Failures:    s.failures,
+		LastSuccess: s.lastOK,
+	}
+	if s.last != nil {
+		last := *s.last
+		status.LastRun = &last
+	}
+	return status
+}
+
 func (s *Schedule) String() string {
 	return fmt.Sprintf("Schedule(%q, nextRun=%s)", s.name, time.Until(s.NextRun()))
 }
 
+// ErrUnknownJob is returned when a cron job is not registered with the [Scheduler].
+var ErrUnknownJob = errors.New("unknown cron job")
+
 // Job represents a cron job.
-type Job func(ctx context.Context) error
+//
+// The context passed to a job is cancelled with a cause of [leases.ErrLeaseLost] if the job's lease is lost while it
+// is running, and carries the fencing token of the lease, which can be retrieved with [FencingToken].
+//
+// Job is an alias of [zero.JobFunc] so that jobs wrapped with [zero.JobMiddleware] can be registered directly.
+type Job = zero.JobFunc
+
+type fencingTokenKey struct{}
+
+// FencingToken returns the fencing token of the lease held by the cron job running in ctx.
+//
+// Tokens increase monotonically with each run of a job, so resources modified by a job can reject writes carrying a
+// token lower than the highest they have seen, eg. from a replica that has lost its lease.
+func FencingToken(ctx context.Context) (int64, bool) {
+	token, ok := ctx.Value(fencingTokenKey{}).(int64)
+	return token, ok
+}
+
+// FanOut returns a [Job] running job once for each of params in turn, as registered for parameterised cron jobs.
+//
+// A failure for one element does not prevent the job running for the rest, and the failures are joined. The
+// remaining elements are skipped if ctx is cancelled, eg. because the job's lease was lost.
+func FanOut[T any](params []T, job func(param T) Job) Job {
+	return func(ctx context.Context) error {
+		var errs []error
+		for i, param := range params {
+			if ctx.Err() != nil {
+				errs = append(errs, errors.Errorf("skipped %d of %d runs: %w", len(params)-i, len(params), context.Cause(ctx)))
+				break
+			}
+			if err := job(param)(ctx); err != nil {
+				errs = append(errs, errors.Errorf("run %d of %d: %w", i+1, len(params), err))
+			}
+		}
+		return errors.Join(errs...)
+	}
+}
+
+// JobStatus is a snapshot of the state of a cron job.
+type JobStatus struct {
+	Name string `json:"name"`
+	// Schedule is the period of the job, eg. "5s".
+	Schedule string    `json:"schedule"`
+	NextRun  time.Time `json:"nextRun"`
+	Paused   bool      `json:"paused"`
+	// Runs is the number of times the job has run since the process started.
+	Runs     int64 `json:"runs"`
+	Failures int64 `json:"failures"`
+	// LastRun is the most recent run of the job, if any.
+	LastRun     *Run      `json:"lastRun,omitempty"`
+	LastSuccess time.Time `json:"lastSuccess,omitzero"`
+}
 
 type Scheduler struct {
 	lock      sync.Mutex
 	logger    *slog.Logger
 	leaser    leases.Leaser
+	history   History
+	clock     zero.Clock
 	schedules []*Schedule
 }
 
 // NewScheduler creates a new cron scheduler.
 //
-// The [Scheduler] uses [leases.Leaser] to prevent cron jobs from running concurrently.
+// The [Scheduler] uses [leases.Leaser] to prevent cron jobs from running concurrently, and records each run in
+// [History]. Schedules are evaluated against [zero.Clock], so that they can be driven by a fake clock in tests.
 //
 //zero:provider
-func NewScheduler(ctx context.Context, logger *slog.Logger, leaser leases.Leaser) *Scheduler {
-	s := &Scheduler{logger: logger, leaser: leaser}
+func NewScheduler(ctx context.Context, logger *slog.Logger, leaser leases.Leaser, history History, clock zero.Clock) *Scheduler {
+	s := &Scheduler{logger: logger, leaser: leaser, history: history, clock: clock}
 	go s.run(ctx)
 	return s
 }
@@ -57,47 +139,155 @@ func (s *Scheduler) Register(name string, schedule time.Duration, job Job) error
 	}
 	s.lock.Lock()
 	defer s.lock.Unlock()
-	sched := &Schedule{name: name, period: schedule, run: job, lastRun: time.Now()}
+	sched := &Schedule{name: name, period: schedule, run: job, lastRun: s.clock.Now()}
 	s.schedules = append(s.schedules, sched)
 	s.logger.Debug("Scheduled new cron job", "job", sched.name)
 	s.sortSchedulesNoLock()
 	return nil
 }
 
+// Jobs returns the status of all registered cron jobs, ordered by name.
+func (s *Scheduler) Jobs() []JobStatus {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	out := make([]JobStatus, 0, len(s.schedules))
+	for _, schedule := range s.schedules {
+		out = append(out, schedule.status())
+	}
+	slices.SortFunc(out, func(a, b JobStatus) int { return strings.Compare(a.Name, b.Name) })
+	return out
+}
+
+// Job returns the status of a cron job.
+func (s *Scheduler) Job(name string) (JobStatus, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	schedule, err := s.findNoLock(name)
+	if err != nil {
+		return JobStatus{}, err
+	}
+	return schedule.status(), nil
+}
+
+// Pause a cron job, preventing it from running on its schedule until resumed.
+//
+// Pausing only applies to this replica.
+func (s *Scheduler) Pause(name string) error {
+	return s.update(name, func(schedule *Schedule) { schedule.paused = true })
+}
+
+// Resume a paused cron job.
+func (s *Scheduler) Resume(name string) error {
+	return s.update(name, func(schedule *Schedule) { schedule.paused = false })
+}
+
+// Trigger a cron job to run as soon as possible, regardless of its schedule or whether it is paused.
+func (s *Scheduler) Trigger(name string) error {
+	return s.update(name, func(schedule *Schedule) { schedule.triggered = true })
+}
+
+func (s *Scheduler) update(name string, fn func(schedule *Schedule)) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	schedule, err := s.findNoLock(name)
+	if err != nil {
+		return err
+	}
+	fn(schedule)
+	return nil
+}
+
+func (s *Scheduler) findNoLock(name string) (*Schedule, error) {
+	for _, schedule := range s.schedules {
+		if schedule.name == name {
+			return schedule, nil
+		}
+	}
+	return nil, errors.Errorf("%s: %w", name, ErrUnknownJob)
+}
+
 func (s *Scheduler) run(ctx context.Context) {
-	ticker := time.NewTicker(time.Millisecond * 100)
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case <-ticker.C:
+		case <-s.clock.After(time.Millisecond * 100):
 		}
-		now := time.Now()
+		now := s.clock.Now()
 		s.lock.Lock()
+		var due []*Schedule
 		for _, schedule := range s.schedules {
-			if !schedule.NextRun().Before(now) {
-				continue
-			}
-			release, err := s.leaser.Acquire(ctx, "cron/"+schedule.name, schedule.period/2)
-			if err != nil {
-				s.logger.Error("Failed to acquire lease for cron job", "job", schedule.name, "error", err)
-				continue
-			}
-			schedule.lastRun = now
-			if err := schedule.run(ctx); err != nil {
-				s.logger.Error("Cron job failed", "job", schedule.name, "error", err)
-				continue
-			}
-			if err = release(ctx); err != nil {
-				s.logger.Error("Failed to release lease for cron job", "job", schedule.name, "error", err)
+			if !schedule.triggered && (schedule.paused || !schedule.NextRun().Before(now)) {
 				continue
 			}
+			due = append(due, schedule)
+		}
+		s.lock.Unlock()
+		// Jobs are run without holding the lock so that the state of the scheduler can be inspected while they run.
+		for _, schedule := range due {
+			s.runSchedule(ctx, schedule, now)
 		}
+		s.lock.Lock()
 		s.sortSchedulesNoLock()
 		s.lock.Unlock()
 	}
 }
 
+func (s *Scheduler) runSchedule(ctx context.Context, schedule *Schedule, now time.Time) {
+	lease, err := s.leaser.Acquire(ctx, "cron/"+schedule.name, schedule.period/2)
+	if err != nil {
+		s.logger.Error("Failed to acquire lease for cron job", "job", schedule.name, "error", err)
+		return
+	}
+	s.lock.Lock()
+	schedule.lastRun = now
+	schedule.triggered = false
+	s.lock.Unlock()
+
+	// Cancel the job if the lease is lost while it is running.
+	jobCtx, cancel := context.WithCancelCause(context.WithValue(ctx, fencingTokenKey{}, lease.Token))
+	go func() {
+		select {
+		case <-lease.Done:
+			cancel(errors.Errorf("cron job %s: %w", schedule.name, leases.ErrLeaseLost))
+		case <-jobCtx.Done():
+		}
+	}()
+	start := s.clock.Now()
+	err = schedule.run(jobCtx)
+	lost := lease.Lost()
+	cancel(nil)
+	run := Run{Job: schedule.name, Start: start, Duration: s.clock.Now().Sub(start)}
+	if lost && err == nil {
+		err = errors.Errorf("lost lease while running: %w", leases.ErrLeaseLost)
+	}
+	if err != nil {
+		run.Error = err.Error()
+		s.logger.Error("Cron job failed", "job", schedule.name, "error", err)
+	}
+
+	if err := s.history.Record(ctx, run); err != nil {
+		s.logger.Error("Failed to record cron job run", "job", schedule.name, "error", err)
+	}
+
+	s.lock.Lock()
+	schedule.runs++
+	if err != nil {
+		schedule.failures++
+	} else {
+		schedule.lastOK = start
+	}
+	schedule.last = &run
+	s.lock.Unlock()
+
+	if lost {
+		return
+	}
+	if err := lease.Release(ctx); err != nil {
+		s.logger.Error("Failed to release lease for cron job", "job", schedule.name, "error", err)
+	}
+}
+
 func (s *Scheduler) sortSchedulesNoLock() {
 	slices.SortFunc(s.schedules, func(a, b *Schedule) int { return a.NextRun().Compare(b.NextRun()) })
 }
@@ -2,12 +2,16 @@ package cron
 
 import (
 	"context"
+	"errors"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"testing/synctest"
 	"time"
 
 	"github.com/alecthomas/assert/v2"
+	"github.com/alecthomas/zero"
+	"github.com/alecthomas/zero/providers/clock/clocktest"
 	"github.com/alecthomas/zero/providers/leases"
 	"github.com/alecthomas/zero/providers/logging/loggingtest"
 )
@@ -27,6 +31,36 @@ func TestNextRun(t *testing.T) {
 	assert.Equal(t, time.Date(2023, 1, 1, 0, 0, 10, 0, time.UTC), next)
 }
 
+func TestFanOut(t *testing.T) {
+	t.Parallel()
+	var ran []string
+	job := FanOut([]string{"acme", "globex", "initech"}, func(tenant string) Job {
+		return func(ctx context.Context) error {
+			ran = append(ran, tenant)
+			if tenant == "globex" {
+				return errors.New("failed")
+			}
+			return nil
+		}
+	})
+	err := job(t.Context())
+	assert.EqualError(t, err, "run 2 of 3: failed")
+	assert.Equal(t, []string{"acme", "globex", "initech"}, ran)
+
+	ctx, cancel := context.WithCancelCause(t.Context())
+	ran = nil
+	job = FanOut([]string{"acme", "globex"}, func(tenant string) Job {
+		return func(ctx context.Context) error {
+			ran = append(ran, tenant)
+			cancel(leases.ErrLeaseLost)
+			return nil
+		}
+	})
+	err = job(ctx)
+	assert.IsError(t, err, leases.ErrLeaseLost)
+	assert.Equal(t, []string{"acme"}, ran)
+}
+
 func TestScheduler(t *testing.T) {
 	t.Skip("Blocked on https://github.com/golang/go/issues/74837")
 	synctest.Run(func() {
@@ -35,7 +69,7 @@ func TestScheduler(t *testing.T) {
 
 		logger := loggingtest.NewForTesting()
 		leaser := leases.NewMemoryLeaser()
-		s := NewScheduler(ctx, logger, leaser)
+		s := NewScheduler(ctx, logger, leaser, NewMemoryHistory(), zero.RealClock{})
 
 		var aliceRuns atomic.Int32
 		err := s.Register("alice", time.Second*5, func(ctx context.Context) error {
@@ -66,3 +100,188 @@ func TestScheduler(t *testing.T) {
 		t.Log("Finished waiting")
 	})
 }
+
+func TestSchedulerFakeClock(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	clock := clocktest.NewForTesting(time.Date(2025, 1, 1, 0, 0, 1, 0, time.UTC))
+	s := NewScheduler(ctx, loggingtest.NewForTesting(), leases.NewMemoryLeaser(), NewMemoryHistory(), clock)
+	runs := make(chan time.Time, 10)
+	err := s.Register("alice", time.Minute, func(ctx context.Context) error {
+		runs <- clock.Now()
+		return nil
+	})
+	assert.NoError(t, err)
+
+	// Advance the clock while the scheduler is idle, then wait for it to become idle again.
+	waitForScheduler := func() {
+		for clock.Waiters() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	advance := func(d time.Duration) {
+		waitForScheduler()
+		clock.Advance(d)
+		waitForScheduler()
+	}
+
+	advance(time.Second * 30)
+	assert.Equal(t, 0, len(runs))
+
+	advance(time.Second * 30)
+	assert.Equal(t, 1, len(runs))
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 1, 1, 0, time.UTC), <-runs)
+	job, err := s.Job("alice")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), job.Runs)
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 2, 0, 0, time.UTC), job.NextRun)
+
+	advance(time.Minute)
+	assert.Equal(t, 1, len(runs))
+}
+
+func TestSchedulerPauseTrigger(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	history := NewMemoryHistory()
+	s := NewScheduler(ctx, loggingtest.NewForTesting(), leases.NewMemoryLeaser(), history, zero.RealClock{})
+
+	runs := make(chan struct{}, 1)
+	var fail atomic.Bool
+	err := s.Register("alice", time.Hour, func(ctx context.Context) error {
+		defer func() { runs <- struct{}{} }()
+		if fail.Load() {
+			return errors.New("failed")
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, s.Pause("alice"))
+	job, err := s.Job("alice")
+	assert.NoError(t, err)
+	assert.True(t, job.Paused)
+	assert.Equal(t, "1h0m0s", job.Schedule)
+
+	// Triggering runs the job even when paused.
+	assert.NoError(t, s.Trigger("alice"))
+	<-runs
+	fail.Store(true)
+	assert.NoError(t, s.Trigger("alice"))
+	<-runs
+
+	// Run statistics are updated after the job returns.
+	for job.Runs != 2 {
+		time.Sleep(time.Millisecond * 10)
+		job, err = s.Job("alice")
+		assert.NoError(t, err)
+	}
+	jobs := s.Jobs()
+	assert.Equal(t, 1, len(jobs))
+	assert.Equal(t, int64(1), jobs[0].Failures)
+	assert.Equal(t, "failed", jobs[0].LastRun.Error)
+	assert.False(t, jobs[0].LastSuccess.IsZero())
+
+	recorded, err := history.Runs(ctx, "alice", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(recorded))
+	assert.Equal(t, "failed", recorded[0].Error)
+	assert.Equal(t, "", recorded[1].Error)
+
+	metrics := Metrics(jobs).String()
+	assert.Contains(t, metrics, `zero_cron_runs_total{job="alice"} 2`)
+	assert.Contains(t, metrics, `zero_cron_failures_total{job="alice"} 1`)
+	assert.Contains(t, metrics, `zero_cron_paused{job="alice"} 1`)
+
+	assert.IsError(t, s.Pause("bob"), ErrUnknownJob)
+}
+
+func TestMemoryHistory(t *testing.T) {
+	t.Parallel()
+	history := &MemoryHistory{size: 3, runs: map[string][]Run{}}
+	for i := range 5 {
+		assert.NoError(t, history.Record(t.Context(), Run{Job: "alice", Duration: time.Duration(i)}))
+	}
+	runs, err := history.Runs(t.Context(), "alice", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []Run{{Job: "alice", Duration: 4}, {Job: "alice", Duration: 3}}, runs)
+	runs, err = history.Runs(t.Context(), "alice", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(runs))
+}
+
+// expiringLeaser is a [leases.Leaser] whose leases can be expired while held.
+type expiringLeaser struct {
+	lock  sync.Mutex
+	token int64
+	held  map[string]chan struct{}
+}
+
+func (e *expiringLeaser) Acquire(ctx context.Context, key string, timeout time.Duration) (leases.Lease, error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.token++
+	done := make(chan struct{})
+	e.held[key] = done
+	return leases.Lease{Token: e.token, Done: done, Release: func(ctx context.Context) error {
+		e.expire(key)
+		return nil
+	}}, nil
+}
+
+func (e *expiringLeaser) expire(key string) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if done, ok := e.held[key]; ok {
+		close(done)
+		delete(e.held, key)
+	}
+}
+
+func (e *expiringLeaser) Close() error { return nil }
+
+func TestSchedulerLeaseLostMidRun(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	leaser := &expiringLeaser{token: 41, held: map[string]chan struct{}{}}
+	history := NewMemoryHistory()
+	s := NewScheduler(ctx, loggingtest.NewForTesting(), leaser, history, zero.RealClock{})
+
+	started := make(chan int64, 1)
+	causes := make(chan error, 1)
+	err := s.Register("alice", time.Hour, func(ctx context.Context) error {
+		token, ok := FencingToken(ctx)
+		assert.True(t, ok)
+		started <- token
+		<-ctx.Done()
+		causes <- context.Cause(ctx)
+		return nil
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, s.Trigger("alice"))
+	assert.Equal(t, int64(42), <-started)
+	leaser.expire("cron/alice")
+	assert.IsError(t, <-causes, leases.ErrLeaseLost)
+
+	// The run is recorded as failed even though the job itself succeeded.
+	job, err := s.Job("alice")
+	assert.NoError(t, err)
+	for job.Runs != 1 {
+		time.Sleep(time.Millisecond * 10)
+		job, err = s.Job("alice")
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int64(1), job.Failures)
+	assert.Contains(t, job.LastRun.Error, "lease was lost")
+
+	// The next run has a new fencing token.
+	assert.NoError(t, s.Trigger("alice"))
+	assert.Equal(t, int64(43), <-started)
+}
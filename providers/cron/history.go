@@ -0,0 +1,68 @@
+package cron
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Run is a single run of a cron job.
+type Run struct {
+	Job      string        `json:"job"`
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration"`
+	// Error is the error returned by the job, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// History records the runs of cron jobs.
+type History interface {
+	// Record a run of a cron job.
+	Record(ctx context.Context, run Run) error
+	// Runs returns up to limit of the most recent runs of a job, newest first.
+	Runs(ctx context.Context, job string, limit int) ([]Run, error)
+}
+
+// DefaultHistorySize is the number of runs retained per job by [MemoryHistory].
+const DefaultHistorySize = 100
+
+type MemoryHistory struct {
+	lock sync.Mutex
+	size int
+	runs map[string][]Run
+}
+
+var _ History = (*MemoryHistory)(nil)
+
+// NewMemoryHistory creates a [History] that retains the most recent [DefaultHistorySize] runs of each job in memory.
+//
+// Runs are only recorded for jobs run by this replica, and do not survive restarts.
+//
+//zero:provider weak
+func NewMemoryHistory() History {
+	return &MemoryHistory{size: DefaultHistorySize, runs: map[string][]Run{}}
+}
+
+func (m *MemoryHistory) Record(ctx context.Context, run Run) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	runs := append(m.runs[run.Job], run)
+	if len(runs) > m.size {
+		runs = slices.Delete(runs, 0, len(runs)-m.size)
+	}
+	m.runs[run.Job] = runs
+	return nil
+}
+
+func (m *MemoryHistory) Runs(ctx context.Context, job string, limit int) ([]Run, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	runs := m.runs[job]
+	if limit > 0 && len(runs) > limit {
+		runs = runs[len(runs)-limit:]
+	}
+	out := slices.Clone(runs)
+	slices.Reverse(out)
+	return out, nil
+}
@@ -0,0 +1,74 @@
+// Package flags provides feature flag clients and middleware for dark-launching endpoints.
+//
+// The middleware is applied to any API annotated with the "flag" label, and responds with 404 Not Found unless the
+// named flag is enabled, eg.
+//
+//	//zero:api POST /checkout flag=new-checkout
+package flags
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/alecthomas/errors"
+	"github.com/alecthomas/zero"
+	"github.com/alecthomas/zero/providers/tenant"
+)
+
+// ErrFlagNotFound is returned by a [Client] if the flag does not exist.
+var ErrFlagNotFound = errors.New("flag not found")
+
+// Client evaluates feature flags.
+//
+// Implementations are provided for static configuration and for OpenFeature Remote Evaluation Protocol (OFREP)
+// services. Other flag services, such as LaunchDarkly, can be used by providing a Client wrapping their SDK.
+type Client interface {
+	// Enabled returns whether the boolean flag is enabled for the evaluation context in ctx.
+	Enabled(ctx context.Context, flag string) (bool, error)
+}
+
+type targetingKey struct{}
+
+// WithTargetingKey returns a copy of ctx with the key used to target flag evaluation, eg. the ID of the user.
+func WithTargetingKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, targetingKey{}, key)
+}
+
+// TargetingKey returns the key used to target flag evaluation in ctx.
+//
+// This is the key set with [WithTargetingKey] if any, otherwise the tenant in ctx, if any.
+func TargetingKey(ctx context.Context) (string, bool) {
+	if key, ok := ctx.Value(targetingKey{}).(string); ok {
+		return key, true
+	}
+	if id, ok := tenant.FromContext(ctx); ok {
+		return string(id), true
+	}
+	return "", false
+}
+
+// Middleware responds with 404 Not Found to requests for APIs whose feature flag is not enabled.
+//
+// Flags that fail to evaluate are treated as disabled.
+//
+//zero:middleware flag
+func Middleware(flag string, client Client, logger *slog.Logger, encodeError zero.ErrorEncoder) zero.Middleware {
+	if flag == "" {
+		// The label is validated by Zero during code generation.
+		panic("flag requires a feature flag name")
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			enabled, err := client.Enabled(r.Context(), flag)
+			if err != nil {
+				logger.Error("Failed to evaluate feature flag", "flag", flag, "error", err)
+			}
+			if !enabled {
+				encodeError(logger, w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
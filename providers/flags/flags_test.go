@@ -0,0 +1,84 @@
+package flags
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/alecthomas/zero"
+	"github.com/alecthomas/zero/providers/logging/loggingtest"
+	"github.com/alecthomas/zero/providers/tenant"
+)
+
+func TestMiddleware(t *testing.T) {
+	client := NewStaticClient(StaticConfig{}).(*StaticClient)
+	handler := Middleware("new-checkout", client, loggingtest.NewForTesting(), zero.EncodeError)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("checkout"))
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/checkout", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	client.Set("new-checkout", true)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/checkout", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "checkout", w.Body.String())
+}
+
+func TestStaticClient(t *testing.T) {
+	client := NewStaticClient(StaticConfig{Enabled: []string{"a"}})
+	enabled, err := client.Enabled(t.Context(), "a")
+	assert.NoError(t, err)
+	assert.True(t, enabled)
+	enabled, err = client.Enabled(t.Context(), "b")
+	assert.NoError(t, err)
+	assert.False(t, enabled)
+}
+
+func TestOFREPClient(t *testing.T) {
+	var targetingKeys []any
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /ofrep/v1/evaluate/flags/{key}", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		var request ofrepRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		targetingKeys = append(targetingKeys, request.Context["targetingKey"])
+		key := r.PathValue("key")
+		switch key {
+		case "enabled":
+			_ = json.NewEncoder(w).Encode(map[string]any{"key": key, "value": true, "reason": "STATIC"})
+		case "variant":
+			_ = json.NewEncoder(w).Encode(map[string]any{"key": key, "value": "blue", "reason": "STATIC"})
+		case "invalid":
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]any{"key": key, "errorCode": "INVALID_CONTEXT", "errorDetails": "missing targetingKey"})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(map[string]any{"key": key, "errorCode": "FLAG_NOT_FOUND"})
+		}
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	client, err := NewOFREPClient(OFREPConfig{URL: server.URL + "/", Token: "secret"})
+	assert.NoError(t, err)
+
+	enabled, err := client.Enabled(tenant.WithID(t.Context(), "acme"), "enabled")
+	assert.NoError(t, err)
+	assert.True(t, enabled)
+
+	_, err = client.Enabled(WithTargetingKey(t.Context(), "user-1"), "missing")
+	assert.IsError(t, err, ErrFlagNotFound)
+
+	_, err = client.Enabled(t.Context(), "variant")
+	assert.EqualError(t, err, "flag variant: expected a boolean value but got string")
+
+	_, err = client.Enabled(t.Context(), "invalid")
+	assert.EqualError(t, err, "flag invalid: OFREP evaluation failed: 400 Bad Request: INVALID_CONTEXT missing targetingKey")
+
+	assert.Equal(t, []any{"acme", "user-1", nil, nil}, targetingKeys)
+}
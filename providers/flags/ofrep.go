@@ -0,0 +1,87 @@
+package flags
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/alecthomas/errors"
+)
+
+//zero:config prefix="flags-ofrep-"
+type OFREPConfig struct {
+	URL   string `help:"Base URL of the OpenFeature Remote Evaluation Protocol (OFREP) service."`
+	Token string `help:"Bearer token for authenticating with the OFREP service." secret:""`
+}
+
+// OFREPClient evaluates flags with an OpenFeature Remote Evaluation Protocol service.
+//
+// See https://openfeature.dev/specification/appendix-c
+type OFREPClient struct {
+	config OFREPConfig
+	client *http.Client
+}
+
+var _ Client = (*OFREPClient)(nil)
+
+// NewOFREPClient creates a [Client] backed by the OFREP service at --flags-ofrep-url.
+//
+//zero:provider weak
+func NewOFREPClient(config OFREPConfig) (Client, error) {
+	if config.URL == "" {
+		return nil, errors.Errorf("--flags-ofrep-url is required")
+	}
+	return &OFREPClient{config: config, client: http.DefaultClient}, nil
+}
+
+type ofrepRequest struct {
+	Context map[string]any `json:"context"`
+}
+
+type ofrepResponse struct {
+	Key          string `json:"key"`
+	Value        any    `json:"value"`
+	ErrorCode    string `json:"errorCode"`
+	ErrorDetails string `json:"errorDetails"`
+}
+
+func (o *OFREPClient) Enabled(ctx context.Context, flag string) (bool, error) {
+	request := ofrepRequest{Context: map[string]any{}}
+	if key, ok := TargetingKey(ctx); ok {
+		request.Context["targetingKey"] = key
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	endpoint := strings.TrimSuffix(o.config.URL, "/") + "/ofrep/v1/evaluate/flags/" + url.PathEscape(flag)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if o.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+o.config.Token)
+	}
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return false, errors.Errorf("flag %s: OFREP request failed: %w", flag, err)
+	}
+	defer resp.Body.Close()
+	var response ofrepResponse
+	_ = json.NewDecoder(resp.Body).Decode(&response)
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return false, errors.Errorf("flag %s: %w", flag, ErrFlagNotFound)
+	case resp.StatusCode >= 300:
+		return false, errors.Errorf("flag %s: OFREP evaluation failed: %s: %s %s", flag, resp.Status, response.ErrorCode, response.ErrorDetails)
+	}
+	enabled, ok := response.Value.(bool)
+	if !ok {
+		return false, errors.Errorf("flag %s: expected a boolean value but got %T", flag, response.Value)
+	}
+	return enabled, nil
+}
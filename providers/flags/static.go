@@ -0,0 +1,43 @@
+package flags
+
+import (
+	"context"
+	"sync"
+)
+
+//zero:config prefix="flags-"
+type StaticConfig struct {
+	Enabled []string `help:"Feature flags to enable."`
+}
+
+// StaticClient is a [Client] with a fixed set of enabled flags, for development and tests.
+type StaticClient struct {
+	lock    sync.RWMutex
+	enabled map[string]bool
+}
+
+var _ Client = (*StaticClient)(nil)
+
+// NewStaticClient creates a [Client] with the flags enabled by --flags-enabled.
+//
+//zero:provider weak
+func NewStaticClient(config StaticConfig) Client {
+	client := &StaticClient{enabled: map[string]bool{}}
+	for _, flag := range config.Enabled {
+		client.enabled[flag] = true
+	}
+	return client
+}
+
+func (s *StaticClient) Enabled(ctx context.Context, flag string) (bool, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.enabled[flag], nil
+}
+
+// Set enables or disables a flag.
+func (s *StaticClient) Set(flag string, enabled bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.enabled[flag] = enabled
+}
@@ -0,0 +1,10 @@
+// Package grpc contains configuration for serving gRPC services.
+//
+// API receivers implementing a protoc-generated gRPC service interface are registered with a gRPC server, which is
+// served alongside the HTTP server. The default server may be overridden by providing a *grpc.Server.
+package grpc
+
+//zero:config prefix="grpc-"
+type Config struct {
+	Bind string `help:"The address to bind the gRPC server to." default:"127.0.0.1:9090"`
+}
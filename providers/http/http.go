@@ -52,14 +52,40 @@ func DefaultServeMux() *http.ServeMux {
 
 //zero:config prefix="server-"
 type Config struct {
-	Bind string `help:"The address to bind the server to." default:"127.0.0.1:8080"`
+	Bind            string        `help:"The address to bind the server to." default:"127.0.0.1:8080"`
+	RequestTimeout  time.Duration `help:"Default timeout for API requests, overridden by timeout=<duration> (0 for none)." default:"0s"`
+	MaxBodySize     zero.ByteSize `help:"Default maximum API request body size, overridden by maxbody=<size> (0 for unlimited)." default:"0"`
+	ReadinessPath   string        `help:"Path serving 503 until the service is ready, then 200 (empty to disable)." default:"/_readyz"`
+	Compress        bool          `help:"Compress responses of at least --server-compress-min-size if the client accepts it." default:"true" negatable:""`
+	CompressMinSize zero.ByteSize `help:"Minimum size of compressed responses, and the default for the compress label." default:"1KB"`
 }
 
+// DefaultReadiness returns the readiness flag of the service. It can be overridden.
+//
 //zero:provider weak
-func DefaultServer(ctx context.Context, logger *slog.Logger, config Config, mux *http.ServeMux) *http.Server {
+func DefaultReadiness() *zero.Readiness { return &zero.Readiness{} }
+
+// DefaultContentEncodings returns the content codings used to compress responses, gzip only by default. It can be
+// overridden, eg. to prefer brotli.
+//
+//zero:provider weak
+func DefaultContentEncodings() zero.ContentEncodings { return zero.ContentEncodings{zero.Gzip} }
+
+// DefaultServer returns the default [http.Server], serving readiness on the configured path and compressing responses
+// if enabled. It can be overridden.
+//
+//zero:provider weak
+func DefaultServer(ctx context.Context, logger *slog.Logger, config Config, mux *http.ServeMux, readiness *zero.Readiness, encodings zero.ContentEncodings) *http.Server {
+	if config.ReadinessPath != "" {
+		mux.Handle("GET "+config.ReadinessPath, readiness)
+	}
+	var handler http.Handler = mux
+	if config.Compress {
+		handler = zero.Compress(config.CompressMinSize, encodings...)(mux)
+	}
 	return &http.Server{
 		Addr:              config.Bind,
-		Handler:           mux,
+		Handler:           handler,
 		BaseContext:       func(l net.Listener) context.Context { return ctx },
 		ReadTimeout:       time.Second * 10,
 		WriteTimeout:      time.Second * 10,
@@ -0,0 +1,130 @@
+// Package httpclient provides HTTP clients with consistent timeouts, retries, proxy, TLS and telemetry.
+//
+// A default *http.Client is configured with --http-client-* flags, while a [Client] for a specific downstream service
+// is configured by its own flags, eg.
+//
+//	// Configured with --http-client-git-hub-timeout, etc.
+//	func NewGitHub(client httpclient.Client[GitHub]) *GitHub { ... }
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/alecthomas/errors"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// Options for an HTTP client.
+type Options struct {
+	Timeout    time.Duration `help:"Timeout of each request, including retries (0 for none)." default:"30s"`
+	Retries    int           `help:"Maximum number of retries of idempotent requests that fail with a network error, 429, 502, 503 or 504." default:"2"`
+	RetryMin   time.Duration `help:"Minimum backoff between retries." default:"100ms"`
+	RetryMax   time.Duration `help:"Maximum backoff between retries." default:"2s"`
+	Proxy      string        `help:"URL of the proxy for requests (defaults to the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables)." placeholder:"URL"`
+	CACert     string        `help:"Path to PEM encoded CA certificates to trust in addition to the system pool." type:"path" placeholder:"PATH"`
+	ClientCert string        `help:"Path to a PEM encoded client certificate for mutual TLS." type:"path" placeholder:"PATH"`
+	ClientKey  string        `help:"Path to the PEM encoded key of the client certificate." type:"path" placeholder:"PATH"`
+	Insecure   bool          `help:"Skip verification of server certificates. Do not use in production."`
+	Telemetry  bool          `help:"Instrument requests with OpenTelemetry tracing and metrics." default:"true" negatable:""`
+}
+
+//zero:config prefix="http-client-"
+type DefaultConfig struct {
+	Options
+}
+
+// Config of the [Client] for T.
+//
+//zero:config prefix="http-client-${type}-"
+type Config[T any] struct {
+	Options
+}
+
+// Client is an HTTP client for calls to T, typically a type representing a downstream service.
+//
+// Each Client is configured independently, so that eg. timeouts can be tuned per service. To mock calls in tests,
+// construct a Client with an [http.Client] whose Transport returns canned responses.
+type Client[T any] struct {
+	*http.Client
+}
+
+// New creates the [Client] for T.
+//
+//zero:provider weak
+func New[T any](config Config[T]) (Client[T], error) {
+	client, err := NewClient(config.Options)
+	if err != nil {
+		return Client[T]{}, err
+	}
+	return Client[T]{Client: client}, nil
+}
+
+// NewDefault creates the default *http.Client. It can be overridden.
+//
+//zero:provider weak
+func NewDefault(config DefaultConfig) (*http.Client, error) {
+	return NewClient(config.Options)
+}
+
+// NewClient creates an *http.Client with options.
+//
+// Each attempt of a request is traced separately, and retries back off exponentially between RetryMin and RetryMax,
+// honouring Retry-After headers up to RetryMax.
+func NewClient(options Options) (*http.Client, error) {
+	transport, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return nil, errors.Errorf("unexpected default transport %T", http.DefaultTransport)
+	}
+	transport = transport.Clone()
+	if options.Proxy != "" {
+		proxy, err := url.Parse(options.Proxy)
+		if err != nil {
+			return nil, errors.Errorf("invalid proxy URL %q: %w", options.Proxy, err)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+	tlsConfig, err := tlsConfig(options)
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+	var roundTripper http.RoundTripper = transport
+	if options.Telemetry {
+		roundTripper = otelhttp.NewTransport(roundTripper)
+	}
+	if options.Retries > 0 {
+		roundTripper = &retryTransport{next: roundTripper, retries: options.Retries, min: options.RetryMin, max: options.RetryMax}
+	}
+	return &http.Client{Transport: roundTripper, Timeout: options.Timeout}, nil
+}
+
+func tlsConfig(options Options) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: options.Insecure} //nolint
+	if options.CACert != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		pem, err := os.ReadFile(options.CACert)
+		if err != nil {
+			return nil, errors.Errorf("failed to read CA certificates: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("%s: no CA certificates found", options.CACert)
+		}
+		config.RootCAs = pool
+	}
+	if options.ClientCert != "" || options.ClientKey != "" {
+		cert, err := tls.LoadX509KeyPair(options.ClientCert, options.ClientKey)
+		if err != nil {
+			return nil, errors.Errorf("failed to load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
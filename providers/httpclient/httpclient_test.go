@@ -0,0 +1,131 @@
+package httpclient
+
+import (
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func testOptions() Options {
+	return Options{Timeout: 5 * time.Second, Retries: 2, RetryMin: time.Millisecond, RetryMax: 10 * time.Millisecond}
+}
+
+func TestRetry(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		method   string
+		header   http.Header
+		statuses []int
+		status   int
+		attempts int32
+	}{
+		{"Success", http.MethodGet, nil, []int{200}, 200, 1},
+		{"RetriesTransientStatus", http.MethodGet, nil, []int{503, 502, 200}, 200, 3},
+		{"GivesUpAfterRetries", http.MethodGet, nil, []int{503, 503, 503, 200}, 503, 3},
+		{"DoesNotRetryClientErrors", http.MethodGet, nil, []int{404, 200}, 404, 1},
+		{"DoesNotRetryPost", http.MethodPost, nil, []int{503, 200}, 503, 1},
+		{"RetriesPostWithIdempotencyKey", http.MethodPost, http.Header{"Idempotency-Key": {"abc"}}, []int{429, 200}, 200, 2},
+		{"RetriesPutWithBody", http.MethodPut, nil, []int{503, 200}, 200, 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			var attempts atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempt := attempts.Add(1)
+				if r.Method != http.MethodGet {
+					body, err := io.ReadAll(r.Body)
+					assert.NoError(t, err)
+					assert.Equal(t, "hello", string(body))
+				}
+				w.WriteHeader(test.statuses[attempt-1])
+			}))
+			t.Cleanup(server.Close)
+			client, err := NewClient(testOptions())
+			assert.NoError(t, err)
+			body := io.Reader(http.NoBody)
+			if test.method != http.MethodGet {
+				body = strings.NewReader("hello")
+			}
+			req, err := http.NewRequestWithContext(t.Context(), test.method, server.URL, body)
+			assert.NoError(t, err)
+			for key, values := range test.header {
+				req.Header[key] = values
+			}
+			resp, err := client.Do(req)
+			assert.NoError(t, err)
+			_ = resp.Body.Close()
+			assert.Equal(t, test.status, resp.StatusCode)
+			assert.Equal(t, test.attempts, attempts.Load())
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, 2*time.Second, retryAfter(&http.Response{Header: http.Header{"Retry-After": {"2"}}}))
+	assert.Equal(t, time.Duration(0), retryAfter(&http.Response{Header: http.Header{"Retry-After": {"soon"}}}))
+	assert.Equal(t, time.Duration(0), retryAfter(&http.Response{Header: http.Header{}}))
+}
+
+func TestTimeout(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	t.Cleanup(server.Close)
+	options := testOptions()
+	options.Timeout = 50 * time.Millisecond
+	client, err := NewClient(options)
+	assert.NoError(t, err)
+	start := time.Now()
+	_, err = client.Get(server.URL)
+	assert.Error(t, err)
+	assert.True(t, time.Since(start) < time.Second)
+}
+
+func TestCACert(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewClient(testOptions())
+	assert.NoError(t, err)
+	_, err = client.Get(server.URL)
+	assert.Error(t, err)
+
+	caCert := filepath.Join(t.TempDir(), "ca.pem")
+	err = os.WriteFile(caCert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600)
+	assert.NoError(t, err)
+	options := testOptions()
+	options.CACert = caCert
+	client, err = NewClient(options)
+	assert.NoError(t, err)
+	resp, err := client.Get(server.URL)
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestInvalidOptions(t *testing.T) {
+	t.Parallel()
+	_, err := NewClient(Options{Proxy: "://"})
+	assert.Error(t, err)
+	_, err = NewClient(Options{CACert: filepath.Join(t.TempDir(), "missing.pem")})
+	assert.Error(t, err)
+}
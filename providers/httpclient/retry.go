@@ -0,0 +1,106 @@
+package httpclient
+
+import (
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/alecthomas/errors"
+)
+
+// retryTransport retries idempotent requests that fail with a network error or a transient status.
+type retryTransport struct {
+	next    http.RoundTripper
+	retries int
+	min     time.Duration
+	max     time.Duration
+}
+
+func (r *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !retryable(req) {
+		return r.next.RoundTrip(req)
+	}
+	for attempt := 0; ; attempt++ {
+		attemptReq := req
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			attemptReq = req.Clone(req.Context())
+			attemptReq.Body = body
+		}
+		resp, err := r.next.RoundTrip(attemptReq)
+		if attempt >= r.retries || !transient(resp, err) {
+			return resp, err
+		}
+		wait := r.backoff(attempt)
+		if resp != nil {
+			if after := retryAfter(resp); after > 0 {
+				wait = min(after, r.max)
+			}
+			// Drain the body so that the connection can be reused.
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			_ = resp.Body.Close()
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, errors.WithStack(req.Context().Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff returns the delay before retrying attempt, doubling from min, with jitter.
+func (r *retryTransport) backoff(attempt int) time.Duration {
+	delay := r.max
+	if shift := min(attempt, 30); r.min<<shift < r.max && r.min<<shift > 0 {
+		delay = r.min << shift
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return r.min + rand.N(max(delay-r.min, 1)) //nolint
+}
+
+// retryable returns true if req can safely be sent more than once.
+func retryable(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete, http.MethodTrace:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+func transient(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter returns the delay requested by the Retry-After header of resp, if any.
+func retryAfter(resp *http.Response) time.Duration {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return time.Until(at)
+	}
+	return 0
+}
@@ -0,0 +1,187 @@
+// Package idempotency provides middleware for replaying responses to requests with a repeated Idempotency-Key header.
+//
+// The middleware is applied to any API annotated with the "idempotent" label, optionally with the duration responses
+// are retained for, eg.
+//
+//	//zero:api POST /payments idempotent=1h
+//
+// Keys are not scoped to the client, so clients must generate keys that are unique to them, eg. random UUIDs. A
+// request reusing a key with a different body is rejected rather than replayed, but a request from another client
+// with the same key and body is replayed. To scope keys by client, apply middleware before this one that prefixes
+// the header with the authenticated principal.
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/alecthomas/errors"
+	"github.com/alecthomas/zero"
+)
+
+// Header is the request header containing the idempotency key.
+const Header = "Idempotency-Key"
+
+// ReplayedHeader is set on responses that have been replayed from the [Store].
+const ReplayedHeader = "Idempotent-Replayed"
+
+// DefaultTTL is the duration responses are retained for if the "idempotent" label has no value.
+const DefaultTTL = 24 * time.Hour
+
+// ErrInProgress is returned by [Store.Start] when another request with the same key has not yet completed.
+var ErrInProgress = errors.New("request with idempotency key is in progress")
+
+// Response is a recorded HTTP response.
+type Response struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+	// RequestHash is the hex-encoded SHA-256 hash of the body of the request the response was recorded for, so that
+	// a request reusing the key with a different body is rejected rather than replayed. Responses recorded without one
+	// are replayed for any body.
+	RequestHash string `json:"request_hash,omitempty"`
+}
+
+// Store records responses by idempotency key.
+type Store interface {
+	// Start processing the request for key, whose body has the hex-encoded SHA-256 hash requestHash, which expires
+	// after ttl.
+	//
+	// If a response has already been recorded for key it is returned. If another request with the key is still being
+	// processed, [ErrInProgress] is returned along with a Response containing only the RequestHash of that request, if
+	// known. Otherwise a nil response is returned and the caller must subsequently call either Finish or Abort.
+	Start(ctx context.Context, key, requestHash string, ttl time.Duration) (*Response, error)
+	// Finish records the response for a started key.
+	Finish(ctx context.Context, key string, response Response) error
+	// Abort releases a started key without recording a response, allowing the request to be retried.
+	Abort(ctx context.Context, key string) error
+}
+
+// Middleware replays the recorded response for requests with a previously seen Idempotency-Key header.
+//
+// Keys are scoped to the request method and path. Requests without the header are passed through unchanged, and
+// server errors are not recorded so that the request may be retried. A request with a previously seen key but a
+// different body receives a 422, even while the first request is in progress.
+//
+// The body is read in full to fingerprint it, so the API should limit its size with maxbody=<size> or the server
+// default, which are applied before this middleware. A body exceeding the limit receives a 413.
+//
+//zero:middleware idempotent
+func Middleware(idempotent string, logger *slog.Logger, store Store, encodeError zero.ErrorEncoder) zero.Middleware {
+	ttl := DefaultTTL
+	if idempotent != "" {
+		// The label is validated by Zero during analysis, so this is only reached by middleware applied by hand.
+		if parsed, err := time.ParseDuration(idempotent); err != nil || parsed <= 0 {
+			logger.Error("Invalid idempotent duration, using the default", "idempotent", idempotent, "default", DefaultTTL)
+		} else {
+			ttl = parsed
+		}
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := r.Header.Get(Header)
+			if header == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			body, err := io.ReadAll(r.Body)
+			if maxBytesErr := (*http.MaxBytesError)(nil); errors.As(err, &maxBytesErr) {
+				encodeError(logger, w, "request body too large", http.StatusRequestEntityTooLarge)
+				return
+			} else if err != nil {
+				encodeError(logger, w, "failed to read request body", http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			sum := sha256.Sum256(body)
+			requestHash := hex.EncodeToString(sum[:])
+			key := keyFor(r.Method, r.URL.Path, header)
+			response, err := store.Start(r.Context(), key, requestHash, ttl)
+			// A different body is rejected whether or not the request with the key has finished.
+			if response != nil && response.RequestHash != "" && response.RequestHash != requestHash {
+				encodeError(logger, w, "idempotency key was already used for a request with a different body", http.StatusUnprocessableEntity)
+				return
+			} else if errors.Is(err, ErrInProgress) {
+				encodeError(logger, w, "a request with this idempotency key is in progress", http.StatusConflict)
+				return
+			} else if err != nil {
+				logger.Error("Failed to start idempotent request", "error", err)
+				encodeError(logger, w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			} else if response != nil {
+				for name, values := range response.Header {
+					w.Header()[name] = values
+				}
+				w.Header().Set(ReplayedHeader, "true")
+				w.WriteHeader(response.Status)
+				_, _ = w.Write(response.Body)
+				return
+			}
+
+			rec := &recorder{ResponseWriter: w}
+			finished := false
+			defer func() {
+				if finished {
+					return
+				}
+				// Don't use the request context, which may already be cancelled.
+				if err := store.Abort(context.WithoutCancel(r.Context()), key); err != nil {
+					logger.Error("Failed to abort idempotent request", "error", err)
+				}
+			}()
+			next.ServeHTTP(rec, r)
+			if rec.status == 0 {
+				rec.status = http.StatusOK
+			}
+			if rec.status >= http.StatusInternalServerError {
+				return
+			}
+			finished = true
+			err = store.Finish(context.WithoutCancel(r.Context()), key, Response{
+				Status:      rec.status,
+				Header:      w.Header().Clone(),
+				Body:        rec.body.Bytes(),
+				RequestHash: requestHash,
+			})
+			if err != nil {
+				logger.Error("Failed to record idempotent response", "error", err)
+			}
+		})
+	}
+}
+
+// keyFor returns the [Store] key for an Idempotency-Key header, scoped to the request method and path.
+func keyFor(method, path, header string) string {
+	hash := sha256.Sum256([]byte(method + " " + path + " " + header))
+	return hex.EncodeToString(hash[:])
+}
+
+// recorder captures the status and body of a response while writing it through.
+type recorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(data []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *recorder) Unwrap() http.ResponseWriter { return r.ResponseWriter }
@@ -0,0 +1,188 @@
+package idempotency
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/alecthomas/zero"
+	"github.com/alecthomas/zero/providers/logging/loggingtest"
+)
+
+// testStore exercises a store whose keys expire as time is advanced by advance.
+func testStore(t *testing.T, store Store, advance func(time.Duration)) { //nolint
+	t.Run("Replay", func(t *testing.T) {
+		response, err := store.Start(t.Context(), "replay", "hash", time.Minute)
+		assert.NoError(t, err)
+		assert.Zero(t, response)
+		expected := Response{Status: http.StatusCreated, Header: http.Header{"Content-Type": {"text/plain"}}, Body: []byte("created")}
+		assert.NoError(t, store.Finish(t.Context(), "replay", expected))
+		response, err = store.Start(t.Context(), "replay", "hash", time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, &expected, response)
+	})
+
+	t.Run("InProgress", func(t *testing.T) {
+		_, err := store.Start(t.Context(), "in-progress", "hash", time.Minute)
+		assert.NoError(t, err)
+		response, err := store.Start(t.Context(), "in-progress", "other", time.Minute)
+		assert.IsError(t, err, ErrInProgress)
+		// The hash of the request in progress is returned so that a different body can be rejected.
+		assert.Equal(t, &Response{RequestHash: "hash"}, response)
+	})
+
+	t.Run("Abort", func(t *testing.T) {
+		_, err := store.Start(t.Context(), "abort", "hash", time.Minute)
+		assert.NoError(t, err)
+		assert.NoError(t, store.Abort(t.Context(), "abort"))
+		response, err := store.Start(t.Context(), "abort", "hash", time.Minute)
+		assert.NoError(t, err)
+		assert.Zero(t, response)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		_, err := store.Start(t.Context(), "expired", "hash", time.Millisecond)
+		assert.NoError(t, err)
+		assert.NoError(t, store.Finish(t.Context(), "expired", Response{Status: http.StatusOK}))
+		advance(time.Millisecond * 10)
+		response, err := store.Start(t.Context(), "expired", "hash", time.Minute)
+		assert.NoError(t, err)
+		assert.Zero(t, response)
+	})
+}
+
+func TestMiddleware(t *testing.T) {
+	calls := 0
+	status := http.StatusCreated
+	handler := Middleware("1h", loggingtest.NewForTesting(), NewMemoryStore(), zero.EncodeError)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte("payment " + r.URL.Path))
+	}))
+	request := func(path, key string) *httptest.ResponseRecorder {
+		return requestWithBody(handler, path, key, "{}")
+	}
+
+	w := request("/payments", "a")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "", w.Header().Get(ReplayedHeader))
+
+	w = request("/payments", "a")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "payment /payments", w.Body.String())
+	assert.Equal(t, "text/plain", w.Header().Get("Content-Type"))
+	assert.Equal(t, "true", w.Header().Get(ReplayedHeader))
+	assert.Equal(t, 1, calls)
+
+	// Keys are scoped to the path.
+	w = request("/refunds", "a")
+	assert.Equal(t, "payment /refunds", w.Body.String())
+	assert.Equal(t, 2, calls)
+
+	// Requests without a key are never replayed.
+	request("/payments", "")
+	request("/payments", "")
+	assert.Equal(t, 4, calls)
+
+	// Server errors are not recorded.
+	status = http.StatusServiceUnavailable
+	request("/payments", "b")
+	status = http.StatusCreated
+	w = request("/payments", "b")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, 6, calls)
+
+	// Reusing a key with a different body is rejected rather than replayed.
+	w = requestWithBody(handler, "/payments", "a", `{"amount":100}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, "", w.Header().Get(ReplayedHeader))
+	assert.Equal(t, 6, calls)
+}
+
+func TestMiddlewarePassesBodyThrough(t *testing.T) {
+	var received string
+	handler := Middleware("", loggingtest.NewForTesting(), NewMemoryStore(), zero.EncodeError)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		received = string(body)
+	}))
+	w := requestWithBody(handler, "/payments", "a", `{"amount":100}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"amount":100}`, received)
+}
+
+func TestMiddlewareRequestLimits(t *testing.T) {
+	logger := loggingtest.NewForTesting()
+	store := NewMemoryStore()
+	calls := 0
+	// As generated for "idempotent maxbody=16", the limits are applied before the middleware reads the body.
+	handler := zero.RequestLimits(logger, zero.EncodeError, 5*time.Second, 16)(Middleware("", logger, store, zero.EncodeError)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	})))
+	r := httptest.NewRequest(http.MethodPost, "/payments", strings.NewReader(`{"amount":100,"currency":"USD"}`))
+	// A chunked body isn't rejected up front, so is limited while the middleware reads it.
+	r.ContentLength = -1
+	r.Header.Set(Header, "a")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Equal(t, 0, calls)
+
+	// The key isn't started, so the request can be retried with a smaller body.
+	w = requestWithBody(handler, "/payments", "a", `{"amount":100}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, calls)
+}
+
+func TestMiddlewareInvalidTTL(t *testing.T) {
+	handler := Middleware("1hh", loggingtest.NewForTesting(), NewMemoryStore(), zero.EncodeError)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	w := requestWithBody(handler, "/payments", "a", "{}")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func requestWithBody(handler http.Handler, path, key, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	if key != "" {
+		r.Header.Set(Header, key)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestMiddlewareInProgress(t *testing.T) {
+	store := NewMemoryStore()
+	handler := Middleware("", loggingtest.NewForTesting(), store, zero.EncodeError)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	_, err := store.Start(t.Context(), keyFor(http.MethodPost, "/payments", "a"), "", time.Minute)
+	assert.NoError(t, err)
+	r := httptest.NewRequest(http.MethodPost, "/payments", nil)
+	r.Header.Set(Header, "a")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestMiddlewareInProgressWithDifferentBody(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := Middleware("", loggingtest.NewForTesting(), NewMemoryStore(), zero.EncodeError)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	}))
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- requestWithBody(handler, "/payments", "a", `{"amount":100}`) }()
+	<-started
+
+	w := requestWithBody(handler, "/payments", "a", `{"amount":200}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	w = requestWithBody(handler, "/payments", "a", `{"amount":100}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	close(release)
+	assert.Equal(t, http.StatusOK, (<-done).Code)
+}
@@ -0,0 +1,69 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	response    *Response
+	requestHash string
+	expires     time.Time
+}
+
+type MemoryStore struct {
+	lock    sync.Mutex
+	entries map[string]*memoryEntry
+	swept   time.Time
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore creates a [Store] that records responses in an in-memory map.
+//
+// Responses are not shared between replicas of a service, nor do they survive restarts.
+//
+//zero:provider weak
+func NewMemoryStore() Store {
+	return &MemoryStore{entries: map[string]*memoryEntry{}}
+}
+
+func (m *MemoryStore) Start(ctx context.Context, key, requestHash string, ttl time.Duration) (*Response, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	now := time.Now()
+	// Periodically remove expired entries.
+	if now.Sub(m.swept) > time.Minute {
+		m.swept = now
+		for k, entry := range m.entries {
+			if now.After(entry.expires) {
+				delete(m.entries, k)
+			}
+		}
+	}
+	if entry, ok := m.entries[key]; ok && now.Before(entry.expires) {
+		if entry.response == nil {
+			return &Response{RequestHash: entry.requestHash}, ErrInProgress
+		}
+		return entry.response, nil
+	}
+	m.entries[key] = &memoryEntry{requestHash: requestHash, expires: now.Add(ttl)}
+	return nil, nil
+}
+
+func (m *MemoryStore) Finish(ctx context.Context, key string, response Response) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if entry, ok := m.entries[key]; ok {
+		entry.response = &response
+	}
+	return nil
+}
+
+func (m *MemoryStore) Abort(ctx context.Context, key string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(m.entries, key)
+	return nil
+}
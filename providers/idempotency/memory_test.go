@@ -0,0 +1,10 @@
+package idempotency
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore(), time.Sleep)
+}
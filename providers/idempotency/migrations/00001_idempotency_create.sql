@@ -0,0 +1,5 @@
+CREATE TABLE idempotency_keys (
+    idempotency_key CHAR(64) NOT NULL PRIMARY KEY,
+    response TEXT,
+    expires TIMESTAMP NOT NULL
+);
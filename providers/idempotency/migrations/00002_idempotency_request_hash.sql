@@ -0,0 +1 @@
+ALTER TABLE idempotency_keys ADD COLUMN request_hash CHAR(64);
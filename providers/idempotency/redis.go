@@ -0,0 +1,79 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/alecthomas/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+//zero:config prefix="idempotency-redis-"
+type RedisConfig struct {
+	Prefix string `help:"Prefix of the keys of recorded responses." default:"idempotency:"`
+}
+
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+var _ Store = (*RedisStore)(nil)
+
+// NewRedisStore creates a [Store] backed by Redis, using the client from
+// [github.com/alecthomas/zero/providers/redis.New].
+//
+// Each key holds the JSON encoded response, or the hash of the request body while the request is being processed, and
+// expires with the key.
+//
+//zero:provider weak
+func NewRedisStore(client *redis.Client, config RedisConfig) Store {
+	return &RedisStore{client: client, prefix: config.Prefix}
+}
+
+func (r *RedisStore) Start(ctx context.Context, key, requestHash string, ttl time.Duration) (*Response, error) {
+	// If we can set the key, this request is the first with the key.
+	ok, err := r.client.SetNX(ctx, r.prefix+key, requestHash, ttl).Result()
+	if err != nil {
+		return nil, errors.Errorf("failed to start idempotent request: %w", err)
+	} else if ok {
+		return nil, nil
+	}
+	data, err := r.client.Get(ctx, r.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		// The other request was aborted, or the key expired, between our set and get.
+		return nil, errors.Errorf("%s: %w", key, ErrInProgress)
+	} else if err != nil {
+		return nil, errors.Errorf("failed to get recorded response: %w", err)
+	}
+	// Recorded responses are JSON objects, anything else is the hash of a request in progress.
+	if len(data) == 0 || data[0] != '{' {
+		return &Response{RequestHash: string(data)}, errors.Errorf("%s: %w", key, ErrInProgress)
+	}
+	response := &Response{}
+	if err := json.Unmarshal(data, response); err != nil {
+		return nil, errors.Errorf("failed to decode recorded response: %w", err)
+	}
+	return response, nil
+}
+
+func (r *RedisStore) Finish(ctx context.Context, key string, response Response) error {
+	data, err := json.Marshal(response)
+	if err != nil {
+		return errors.Errorf("failed to encode response: %w", err)
+	}
+	// Keep the expiry set by Start, and don't record the response if the key has already expired.
+	err = r.client.SetArgs(ctx, r.prefix+key, data, redis.SetArgs{Mode: "XX", KeepTTL: true}).Err()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return errors.Errorf("failed to record response: %w", err)
+	}
+	return nil
+}
+
+func (r *RedisStore) Abort(ctx context.Context, key string) error {
+	if err := r.client.Del(ctx, r.prefix+key).Err(); err != nil {
+		return errors.Errorf("failed to abort idempotent request: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package idempotency
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRedisStore(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	store := NewRedisStore(client, RedisConfig{Prefix: "idempotency:"})
+	testStore(t, store, server.FastForward)
+
+	_, err := store.Start(t.Context(), "key", "hash", time.Minute)
+	assert.NoError(t, err)
+	// The hash of the request body is held while the request is in progress.
+	server.CheckGet(t, "idempotency:key", "hash")
+	assert.NoError(t, store.Finish(t.Context(), "key", Response{Status: http.StatusOK}))
+	server.CheckGet(t, "idempotency:key", `{"status":200,"header":null,"body":null}`)
+	// The response expires with the key started.
+	assert.Equal(t, time.Minute, server.TTL("idempotency:key"))
+}
@@ -0,0 +1,87 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/alecthomas/errors"
+	zerosql "github.com/alecthomas/zero/providers/sql"
+)
+
+type SQLStore struct {
+	db     *sql.DB
+	q      func(string) string
+	driver zerosql.Driver
+}
+
+var _ Store = (*SQLStore)(nil)
+
+// NewSQLStore creates a [Store] backed by an SQL database.
+//
+//zero:provider weak require="github.com/alecthomas/zero/providers/idempotency/migrations.Migrations"
+func NewSQLStore(driver zerosql.Driver, db *sql.DB) Store {
+	return &SQLStore{db: db, q: driver.Denormalise, driver: driver}
+}
+
+func (s *SQLStore) Start(ctx context.Context, key, requestHash string, ttl time.Duration) (*Response, error) {
+	now := time.Now().UTC()
+	_, err := s.db.ExecContext(ctx, s.q(`
+		DELETE FROM idempotency_keys
+		WHERE idempotency_key = ? AND expires < ?
+	`), key, now)
+	if err != nil {
+		return nil, errors.WithStack(s.driver.TranslateError(err))
+	}
+
+	// If we can insert successfully, this request is the first with the key.
+	_, err = s.db.ExecContext(ctx, s.q(`
+		INSERT INTO idempotency_keys (idempotency_key, request_hash, expires)
+		VALUES (?, ?, ?)
+	`), key, requestHash, now.Add(ttl))
+	if err == nil {
+		return nil, nil
+	}
+	if err = s.driver.TranslateError(err); !errors.Is(err, zerosql.ErrConstraint) {
+		return nil, errors.WithStack(err)
+	}
+
+	var response, pendingHash sql.NullString
+	err = s.db.QueryRowContext(ctx, s.q(`SELECT response, request_hash FROM idempotency_keys WHERE idempotency_key = ?`), key).Scan(&response, &pendingHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		// The other request was aborted between our insert and query.
+		return nil, errors.Errorf("%s: %w", key, ErrInProgress)
+	} else if err != nil {
+		return nil, errors.WithStack(s.driver.TranslateError(err))
+	}
+	if !response.Valid {
+		return &Response{RequestHash: pendingHash.String}, errors.Errorf("%s: %w", key, ErrInProgress)
+	}
+	out := &Response{}
+	if err := json.Unmarshal([]byte(response.String), out); err != nil {
+		return nil, errors.Errorf("%s: failed to decode response: %w", key, err)
+	}
+	return out, nil
+}
+
+func (s *SQLStore) Finish(ctx context.Context, key string, response Response) error {
+	data, err := json.Marshal(response)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	_, err = s.db.ExecContext(ctx, s.q(`
+		UPDATE idempotency_keys
+		SET response = ?
+		WHERE idempotency_key = ?
+	`), string(data), key)
+	return errors.WithStack(s.driver.TranslateError(err))
+}
+
+func (s *SQLStore) Abort(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, s.q(`
+		DELETE FROM idempotency_keys
+		WHERE idempotency_key = ? AND response IS NULL
+	`), key)
+	return errors.WithStack(s.driver.TranslateError(err))
+}
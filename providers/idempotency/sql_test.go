@@ -0,0 +1,14 @@
+package idempotency
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alecthomas/zero/providers/idempotency/migrations"
+	"github.com/alecthomas/zero/providers/sql/sqltest"
+)
+
+func testSQLStore(t *testing.T, dsn string) { //nolint
+	db, driver := sqltest.NewForTesting(t, dsn, migrations.Migrations())
+	testStore(t, NewSQLStore(driver, db), time.Sleep)
+}
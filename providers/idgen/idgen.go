@@ -0,0 +1,12 @@
+// Package idgen provides the default [zero.IDGenerator].
+//
+// Tests can override it with a deterministic sequence, such as
+// [github.com/alecthomas/zero/providers/idgen/idgentest.Sequence].
+package idgen
+
+import "github.com/alecthomas/zero"
+
+// UUIDv7 returns a [zero.IDGenerator] of time-ordered UUIDv7s.
+//
+//zero:provider weak
+func UUIDv7() zero.IDGenerator { return zero.UUIDv7Generator{} }
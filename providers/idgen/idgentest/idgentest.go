@@ -0,0 +1,25 @@
+// Package idgentest provides a deterministic [zero.IDGenerator] for tests.
+package idgentest
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/alecthomas/zero"
+)
+
+// Sequence is a [zero.IDGenerator] of sequential UUIDs, eg. "00000000-0000-7000-8000-000000000001".
+//
+// IDs are valid UUIDv7s, so they are accepted by code that parses them, and sort in the order they were generated.
+type Sequence struct {
+	next atomic.Uint64
+}
+
+var _ zero.IDGenerator = (*Sequence)(nil)
+
+// NewForTesting creates a [Sequence] starting at 1.
+func NewForTesting() *Sequence { return &Sequence{} }
+
+func (s *Sequence) NewID() string {
+	return fmt.Sprintf("00000000-0000-7000-8000-%012x", s.next.Add(1))
+}
@@ -0,0 +1,19 @@
+package idgentest
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/gofrs/uuid/v5"
+)
+
+func TestSequence(t *testing.T) {
+	sequence := NewForTesting()
+	assert.Equal(t, "00000000-0000-7000-8000-000000000001", sequence.NewID())
+	id := sequence.NewID()
+	assert.Equal(t, "00000000-0000-7000-8000-000000000002", id)
+	parsed, err := uuid.FromString(id)
+	assert.NoError(t, err)
+	assert.Equal(t, byte(uuid.V7), parsed.Version())
+	assert.Equal(t, uuid.VariantRFC9562, parsed.Variant())
+}
@@ -13,9 +13,36 @@ var ErrLeaseHeld = errors.New("lease is held")
 // ErrLeaseNotHeld is returned by Release when the lease is not held.
 var ErrLeaseNotHeld = errors.New("lease is not held")
 
+// ErrLeaseLost is the cause of cancellation of contexts derived from a [Lease] that was lost while held.
+var ErrLeaseLost = errors.New("lease was lost")
+
 // Release an acquired lease.
 type Release func(ctx context.Context) error
 
+// Lease is an acquired lease.
+type Lease struct {
+	// Token is a fencing token for the lease.
+	//
+	// Tokens increase monotonically with each acquisition of a lease key, so resources guarded by the lease can reject
+	// writes carrying a token lower than the highest they have seen, eg. from a holder that lost the lease.
+	Token int64
+	// Done is closed once the lease is no longer held, either because it was released or because it was lost, eg.
+	// through expiry.
+	Done <-chan struct{}
+	// Release the lease.
+	Release Release
+}
+
+// Lost returns true if the lease is no longer held.
+func (l Lease) Lost() bool {
+	select {
+	case <-l.Done:
+		return true
+	default:
+		return false
+	}
+}
+
 // Leaser is an interface for lease acquisition and release.
 type Leaser interface {
 	// Acquire acquires a lease for the given key.
@@ -25,7 +52,7 @@ type Leaser interface {
 	//
 	// Leases are automatically renewed, but if renewal fails after a period of retries, the process will be terminated
 	// to avoid split-brain.
-	Acquire(ctx context.Context, key string, timeout time.Duration) (Release, error)
+	Acquire(ctx context.Context, key string, timeout time.Duration) (Lease, error)
 
 	// Close releases all resources associated with the leaser.
 	Close() error
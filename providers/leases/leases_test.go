@@ -10,41 +10,54 @@ import (
 
 func testLeases(t *testing.T, leaser Leaser) { //nolint
 	t.Run("AlreadyHeld", func(t *testing.T) {
-		release, err := leaser.Acquire(t.Context(), "lease", time.Second)
+		lease, err := leaser.Acquire(t.Context(), "lease", time.Second)
 		assert.NoError(t, err)
-		defer release(t.Context()) //nolint
+		defer lease.Release(t.Context()) //nolint
 		_, err = leaser.Acquire(t.Context(), "lease", time.Second)
 		assert.IsError(t, err, ErrLeaseHeld)
 	})
 
 	t.Run("Release", func(t *testing.T) {
-		release, err := leaser.Acquire(t.Context(), "lease", time.Second)
+		lease, err := leaser.Acquire(t.Context(), "lease", time.Second)
 		assert.NoError(t, err)
-		assert.NoError(t, release(t.Context()))
+		assert.False(t, lease.Lost())
+		assert.NoError(t, lease.Release(t.Context()))
+		assert.True(t, lease.Lost())
 	})
 
 	t.Run("ReleaseTwice", func(t *testing.T) {
-		release, err := leaser.Acquire(t.Context(), "lease", time.Second)
+		lease, err := leaser.Acquire(t.Context(), "lease", time.Second)
 		assert.NoError(t, err)
-		assert.NoError(t, release(t.Context()))
-		assert.IsError(t, release(t.Context()), ErrLeaseNotHeld)
+		assert.NoError(t, lease.Release(t.Context()))
+		assert.IsError(t, lease.Release(t.Context()), ErrLeaseNotHeld)
 	})
 
 	t.Run("ReacquireAfterRelease", func(t *testing.T) {
-		release, err := leaser.Acquire(t.Context(), "lease", time.Second)
+		lease, err := leaser.Acquire(t.Context(), "lease", time.Second)
 		assert.NoError(t, err)
-		assert.NoError(t, release(t.Context()))
-		release, err = leaser.Acquire(t.Context(), "lease", time.Second)
+		assert.NoError(t, lease.Release(t.Context()))
+		lease, err = leaser.Acquire(t.Context(), "lease", time.Second)
 		assert.NoError(t, err)
-		assert.NoError(t, release(t.Context()))
+		assert.NoError(t, lease.Release(t.Context()))
+	})
+
+	t.Run("FencingTokenIncreases", func(t *testing.T) {
+		first, err := leaser.Acquire(t.Context(), "token", time.Second)
+		assert.NoError(t, err)
+		assert.NoError(t, first.Release(t.Context()))
+		second, err := leaser.Acquire(t.Context(), "token", time.Second)
+		assert.NoError(t, err)
+		assert.NoError(t, second.Release(t.Context()))
+		assert.True(t, second.Token > first.Token, "%d <= %d", second.Token, first.Token)
 	})
 
 	t.Run("ReleaseWhenContextCancelled", func(t *testing.T) {
 		ctx, cancel := context.WithCancel(t.Context())
-		release, err := leaser.Acquire(ctx, "lease", time.Second)
+		lease, err := leaser.Acquire(ctx, "lease", time.Second)
 		assert.NoError(t, err)
 		cancel()
 		time.Sleep(time.Millisecond * 100)
-		assert.IsError(t, release(t.Context()), ErrLeaseNotHeld)
+		assert.True(t, lease.Lost())
+		assert.IsError(t, lease.Release(t.Context()), ErrLeaseNotHeld)
 	})
 }
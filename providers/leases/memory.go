@@ -9,22 +9,23 @@ import (
 )
 
 type MemoryLeaser struct {
-	lock   sync.RWMutex
-	leases map[string]bool
+	lock   sync.Mutex
+	leases map[string]chan struct{}
+	tokens map[string]int64
 }
 
 var _ Leaser = (*MemoryLeaser)(nil)
 
 // NewMemoryLeaser creates a [Leaser] that holds leases using an in-memory map.
 //
-// On the upside, it can never fail.
+// On the upside, it can never fail. On the downside, leases are not shared between replicas of a service.
 //
 //zero:provider weak
 func NewMemoryLeaser() Leaser {
-	return &MemoryLeaser{leases: make(map[string]bool)}
+	return &MemoryLeaser{leases: map[string]chan struct{}{}, tokens: map[string]int64{}}
 }
 
-func (m *MemoryLeaser) Acquire(ctx context.Context, key string, timeout time.Duration) (Release, error) {
+func (m *MemoryLeaser) Acquire(ctx context.Context, key string, timeout time.Duration) (Lease, error) {
 	timeoutContext, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
@@ -32,64 +33,54 @@ func (m *MemoryLeaser) Acquire(ctx context.Context, key string, timeout time.Dur
 	defer tick.Stop()
 
 	for {
-		m.lock.RLock()
-		ok := m.leases[key]
-		m.lock.RUnlock()
-
-		// Lease is held, sleep then try again.
+		lease, ok := m.tryAcquire(key)
 		if ok {
-			select {
-			case <-tick.C:
-			case <-timeoutContext.Done():
-				return nil, errors.Errorf("%s: %w", key, ErrLeaseHeld)
-			}
-			continue
+			// Release the lease if the context is cancelled.
+			go func() {
+				select {
+				case <-ctx.Done():
+					_ = m.release(key, lease.Done)
+				case <-lease.Done:
+				}
+			}()
+			return lease, nil
 		}
 
-		// Try to acquire the lease
-		m.lock.Lock()
-		ok = m.leases[key]
-		if ok {
-			m.lock.Unlock()
-			continue
+		// Lease is held, sleep then try again.
+		select {
+		case <-tick.C:
+		case <-timeoutContext.Done():
+			return Lease{}, errors.Errorf("%s: %w", key, ErrLeaseHeld)
 		}
-		defer m.lock.Unlock()
+	}
+}
 
-		// We have the lease
-		m.leases[key] = true
-		// Release the lease if the context is cancelled.
-		released := make(chan struct{})
-		go func() {
-			select {
-			case <-ctx.Done():
-				m.lock.Lock()
-				defer m.lock.Unlock()
-				// Check if the lease was already released
-				select {
-				case <-released:
-				default:
-					delete(m.leases, key)
-				}
+func (m *MemoryLeaser) tryAcquire(key string) (Lease, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if _, ok := m.leases[key]; ok {
+		return Lease{}, false
+	}
+	done := make(chan struct{})
+	m.leases[key] = done
+	m.tokens[key]++
+	return Lease{
+		Token:   m.tokens[key],
+		Done:    done,
+		Release: func(ctx context.Context) error { return m.release(key, done) },
+	}, true
+}
 
-			case <-released:
-			}
-		}()
-		return func(ctx context.Context) error {
-			m.lock.Lock()
-			defer m.lock.Unlock()
-			select {
-			case <-released:
-			default:
-				close(released)
-			}
-			held := m.leases[key]
-			if !held {
-				return errors.Errorf("%s: %w", key, ErrLeaseNotHeld)
-			}
-			delete(m.leases, key)
-			return nil
-		}, nil
+func (m *MemoryLeaser) release(key string, done <-chan struct{}) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	held, ok := m.leases[key]
+	if !ok || held != done {
+		return errors.Errorf("%s: %w", key, ErrLeaseNotHeld)
 	}
+	delete(m.leases, key)
+	close(held)
+	return nil
 }
 
 func (m *MemoryLeaser) Close() error { return nil }
@@ -0,0 +1 @@
+ALTER TABLE leases ADD COLUMN token BIGINT NOT NULL DEFAULT 0;
@@ -6,10 +6,13 @@ import (
 	"database/sql"
 	"fmt"
 	"log/slog"
+	"maps"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/alecthomas/errors"
+	"github.com/alecthomas/zero"
 	"github.com/alecthomas/zero/internal"
 	zerosql "github.com/alecthomas/zero/providers/sql"
 )
@@ -21,18 +24,31 @@ type SQLLeaser struct {
 	q      func(string) string
 	driver zerosql.Driver
 	log    *slog.Logger
+	clock  zero.Clock
+	lock   sync.Mutex
+	held   map[string]*sqlLease
+}
+
+// A lease held by this holder.
+type sqlLease struct {
+	token int64
+	done  chan struct{}
 }
 
 var _ Leaser = (*SQLLeaser)(nil)
 
 // NewSQLLeaser creates a [Leaser] backed by an SQL database.
 //
+// Lease expiry and renewal are timed by [zero.Clock], though leases are compared against the expiry times written by
+// every holder, so holders sharing a database must agree on the time.
+//
 //zero:provider weak require="github.com/alecthomas/zero/providers/leases/migrations.Migrations"
 func NewSQLLeaser(
 	ctx context.Context,
 	logger *slog.Logger,
 	driver zerosql.Driver,
 	db *sql.DB,
+	clock zero.Clock,
 ) (Leaser, error) {
 	holder, err := makeID()
 	if err != nil {
@@ -45,6 +61,8 @@ func NewSQLLeaser(
 		q:      driver.Denormalise,
 		driver: driver,
 		log:    logger,
+		clock:  clock,
+		held:   map[string]*sqlLease{},
 	}
 	go s.renewLoop(ctx)
 	return s, nil
@@ -72,7 +90,7 @@ func (s *SQLLeaser) renewLoop(ctx context.Context) {
 			return
 		case <-ctx.Done():
 			return
-		case <-time.After(time.Second * 1):
+		case <-s.clock.After(time.Second * 1):
 			s.renew(ctx)
 		}
 	}
@@ -86,13 +104,14 @@ func (s *SQLLeaser) renew(ctx context.Context) {
 	// Attempt to renew the leases until the context times out, at which point we take the nuclear
 	// option of terminating the process.
 	for {
-		nextExpires := time.Now().UTC().Add(time.Second * 30)
+		nextExpires := s.clock.Now().UTC().Add(time.Second * 30)
 		_, err := s.db.ExecContext(ctx, s.q(`
 			UPDATE leases
 			SET expires = ?
 			WHERE holder = ?
 		`), nextExpires, s.holder)
 		if err == nil {
+			s.checkLost(ctx)
 			return
 		}
 		select {
@@ -108,7 +127,54 @@ func (s *SQLLeaser) renew(ctx context.Context) {
 	}
 }
 
-func (s *SQLLeaser) Acquire(ctx context.Context, key string, timeout time.Duration) (Release, error) {
+// Mark leases that are no longer held by this holder as lost, eg. because they expired and were acquired by another
+// holder while renewals were stalled.
+func (s *SQLLeaser) checkLost(ctx context.Context) {
+	s.lock.Lock()
+	held := maps.Clone(s.held)
+	s.lock.Unlock()
+	if len(held) == 0 {
+		return
+	}
+	rows, err := s.db.QueryContext(ctx, s.q(`SELECT lease, token FROM leases WHERE holder = ?`), s.holder)
+	if err != nil {
+		s.log.Warn("Failed to check held leases", "error", err)
+		return
+	}
+	defer rows.Close()
+	tokens := map[string]int64{}
+	for rows.Next() {
+		var key string
+		var token int64
+		if err := rows.Scan(&key, &token); err != nil {
+			s.log.Warn("Failed to check held leases", "error", err)
+			return
+		}
+		tokens[key] = token
+	}
+	if err := rows.Err(); err != nil {
+		s.log.Warn("Failed to check held leases", "error", err)
+		return
+	}
+	for key, lease := range held {
+		if token, ok := tokens[key]; !ok || token != lease.token {
+			s.log.Warn("Lease lost", "lease", key, "token", lease.token)
+			s.forget(key, lease.token)
+		}
+	}
+}
+
+// Forget a lease held by this holder, closing its Done channel.
+func (s *SQLLeaser) forget(key string, token int64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if lease, ok := s.held[key]; ok && lease.token == token {
+		delete(s.held, key)
+		close(lease.done)
+	}
+}
+
+func (s *SQLLeaser) Acquire(ctx context.Context, key string, timeout time.Duration) (Lease, error) {
 	timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
@@ -120,9 +186,9 @@ retry:
 			if errors.Is(err, context.DeadlineExceeded) {
 				break
 			}
-			return nil, errors.Errorf("lease %s: failed to begin transaction: %w", key, err)
+			return Lease{}, errors.Errorf("lease %s: failed to begin transaction: %w", key, err)
 		}
-		if err := s.acquireTx(timeoutCtx, tx, key); err != nil {
+		if token, err := s.acquireTx(timeoutCtx, tx, key); err != nil {
 			s.log.Debug("Failed to acquire lease, will retry", "error", err)
 			// Failed to acquire lease, rollback and fallthrough to the retry.
 			_ = tx.Rollback()
@@ -130,23 +196,25 @@ retry:
 				break
 			}
 		} else if err := tx.Commit(); err != nil {
-			return nil, errors.WithStack(err)
+			return Lease{}, errors.WithStack(err)
 		} else {
-			released := make(chan struct{})
+			done := make(chan struct{})
+			s.lock.Lock()
+			s.held[key] = &sqlLease{token: token, done: done}
+			s.lock.Unlock()
 			go func() {
 				select {
-				case <-released:
+				case <-done:
 				case <-ctx.Done():
-					_ = s.releaseLease(context.Background(), key) //nolint
+					_ = s.releaseLease(context.Background(), key, token) //nolint
 				}
 			}()
-			return func(ctx context.Context) error {
-				select {
-				case <-released:
-				default:
-					close(released)
-				}
-				return errors.WithStack(s.releaseLease(ctx, key))
+			return Lease{
+				Token: token,
+				Done:  done,
+				Release: func(ctx context.Context) error {
+					return errors.WithStack(s.releaseLease(ctx, key, token))
+				},
 			}, nil
 		}
 
@@ -162,23 +230,26 @@ retry:
 	defer cancel()
 	row := s.db.QueryRowContext(timeoutCtx, s.q(`SELECT holder FROM leases WHERE lease = ?`), key) //nolint
 	if row.Err() != nil {
-		return nil, errors.Errorf("lease %s: failed to query lease holder: %w", key, row.Err())
+		return Lease{}, errors.Errorf("lease %s: failed to query lease holder: %w", key, row.Err())
 	}
 	var holder string
 	if err := row.Scan(&holder); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errors.Errorf("%s: %w: by unknown", key, ErrLeaseHeld)
+			return Lease{}, errors.Errorf("%s: %w: by unknown", key, ErrLeaseHeld)
 		}
-		return nil, errors.WithStack(err)
+		return Lease{}, errors.WithStack(err)
 	}
-	return nil, errors.Errorf("%s: %w: by %s", key, ErrLeaseHeld, holder)
+	return Lease{}, errors.Errorf("%s: %w: by %s", key, ErrLeaseHeld, holder)
 }
 
-func (s *SQLLeaser) releaseLease(ctx context.Context, key string) error {
+func (s *SQLLeaser) releaseLease(ctx context.Context, key string, token int64) error {
+	defer s.forget(key, token)
+	// Released leases are retained, expired, so that their fencing token continues to increase.
 	result, err := s.db.ExecContext(ctx, s.q(`
-		DELETE FROM leases
-		WHERE lease = ? AND holder = ?
-	`), key, s.holder)
+		UPDATE leases
+		SET holder = '', expires = ?
+		WHERE lease = ? AND holder = ? AND token = ?
+	`), s.clock.Now().UTC(), key, s.holder, token)
 	if err != nil {
 		return errors.WithStack(s.driver.TranslateError(err))
 	}
@@ -192,28 +263,42 @@ func (s *SQLLeaser) releaseLease(ctx context.Context, key string) error {
 	return nil
 }
 
-func (s *SQLLeaser) acquireTx(ctx context.Context, tx *sql.Tx, key string) error {
-	// Delete lease if it exists and is expired
-	now := time.Now().UTC()
-	_, err := tx.ExecContext(ctx, s.q(`
-		DELETE FROM leases
-		WHERE lease = ? AND expires < ?;
-	`), key, now)
+// Acquire the lease in tx, returning its fencing token.
+func (s *SQLLeaser) acquireTx(ctx context.Context, tx *sql.Tx, key string) (int64, error) {
+	now := s.clock.Now().UTC()
+	expires := now.Add(time.Second * 5)
+
+	// Take over the lease if it exists and is expired, incrementing its token.
+	result, err := tx.ExecContext(ctx, s.q(`
+		UPDATE leases
+		SET holder = ?, expires = ?, token = token + 1
+		WHERE lease = ? AND expires < ?
+	`), s.holder, expires, key, now)
 	if err != nil {
-		return errors.WithStack(s.driver.TranslateError(err))
+		return 0, errors.WithStack(s.driver.TranslateError(err))
 	}
-
-	// If we can insert successfully, we have the lease
-	expires := time.Now().UTC().Add(time.Second * 5)
-	_, err = tx.ExecContext(ctx, s.q(`
-		INSERT INTO leases (lease, holder, expires)
-		VALUES (?, ?, ?)
-	`), key, s.holder, expires)
+	count, err := result.RowsAffected()
 	if err != nil {
-		return errors.WithStack(s.driver.TranslateError(err))
+		return 0, errors.WithStack(s.driver.TranslateError(err))
 	}
 
-	return nil
+	// Otherwise if we can insert successfully, we have the lease
+	if count == 0 {
+		_, err = tx.ExecContext(ctx, s.q(`
+			INSERT INTO leases (lease, holder, expires, token)
+			VALUES (?, ?, ?, 1)
+		`), key, s.holder, expires)
+		if err != nil {
+			return 0, errors.WithStack(s.driver.TranslateError(err))
+		}
+	}
+
+	var token int64
+	err = tx.QueryRowContext(ctx, s.q(`SELECT token FROM leases WHERE lease = ?`), key).Scan(&token)
+	if err != nil {
+		return 0, errors.WithStack(s.driver.TranslateError(err))
+	}
+	return token, nil
 }
 
 func (s *SQLLeaser) Close() error { close(s.stop); return nil }
@@ -1,9 +1,13 @@
 package leases
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/alecthomas/assert/v2"
+	"github.com/alecthomas/zero"
+	"github.com/alecthomas/zero/providers/clock/clocktest"
 	"github.com/alecthomas/zero/providers/leases/migrations"
 	"github.com/alecthomas/zero/providers/logging/loggingtest"
 	"github.com/alecthomas/zero/providers/sql/sqltest"
@@ -12,7 +16,51 @@ import (
 func testSQLLeaser(t *testing.T, dsn string) { //nolint
 	logger := loggingtest.NewForTesting()
 	db, driver := sqltest.NewForTesting(t, dsn, migrations.Migrations())
-	leaser, err := NewSQLLeaser(t.Context(), logger, driver, db)
+	leaser, err := NewSQLLeaser(t.Context(), logger, driver, db, zero.RealClock{})
 	assert.NoError(t, err)
 	testLeases(t, leaser)
+
+	t.Run("LostOnExpiry", func(t *testing.T) {
+		// Simulate a stalled holder by not renewing its leases.
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+		stalled, err := NewSQLLeaser(ctx, logger, driver, db, zero.RealClock{})
+		assert.NoError(t, err)
+		lease, err := stalled.Acquire(t.Context(), "expiry", time.Second)
+		assert.NoError(t, err)
+
+		_, err = db.ExecContext(t.Context(), driver.Denormalise(`UPDATE leases SET expires = ? WHERE lease = ?`),
+			time.Now().UTC().Add(-time.Minute), "expiry")
+		assert.NoError(t, err)
+		taken, err := leaser.Acquire(t.Context(), "expiry", time.Second*5)
+		assert.NoError(t, err)
+		defer taken.Release(t.Context()) //nolint
+		assert.True(t, taken.Token > lease.Token, "%d <= %d", taken.Token, lease.Token)
+
+		// The stalled holder detects the loss on its next renewal.
+		assert.False(t, lease.Lost())
+		stalled.(*SQLLeaser).renew(t.Context())
+		assert.True(t, lease.Lost())
+		assert.IsError(t, lease.Release(t.Context()), ErrLeaseNotHeld)
+		assert.False(t, taken.Lost())
+	})
+
+	t.Run("ExpiresWithClock", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+		clock := clocktest.NewForTesting(time.Now())
+		stalled, err := NewSQLLeaser(ctx, logger, driver, db, clock)
+		assert.NoError(t, err)
+		lease, err := stalled.Acquire(t.Context(), "clock", time.Second)
+		assert.NoError(t, err)
+
+		// The lease is held until the clock passes its expiry.
+		_, err = stalled.Acquire(t.Context(), "clock", time.Millisecond*100)
+		assert.IsError(t, err, ErrLeaseHeld)
+		clock.Advance(time.Minute)
+		taken, err := stalled.Acquire(t.Context(), "clock", time.Second)
+		assert.NoError(t, err)
+		defer taken.Release(t.Context()) //nolint
+		assert.True(t, taken.Token > lease.Token, "%d <= %d", taken.Token, lease.Token)
+	})
 }
@@ -0,0 +1,214 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/zero"
+)
+
+// Redacted replaces the values of redacted fields in logged bodies.
+const Redacted = "[REDACTED]"
+
+//zero:config prefix="log-body-"
+type BodyConfig struct {
+	Enabled bool          `help:"Log request and response bodies of APIs with the logbody label. Do not enable in production."`
+	Limit   zero.ByteSize `help:"Maximum size of each logged body." default:"4KB"`
+	Redact  []string      `help:"Case-insensitive glob patterns of JSON and form fields whose values are redacted." default:"*password*,*secret*,*token*,authorization,*api_key*,*apikey*"`
+}
+
+// BodyMiddleware logs the request and response bodies of APIs with the "logbody" label, up to a size limit, which is
+// useful for debugging integration environments. It is disabled unless --log-body-enabled is set.
+//
+// The values of JSON and form fields matching the --log-body-redact patterns are redacted, as are the redacted fields
+// of the API, tagged `log:"-"` in its request and response types. Fields may also be redacted explicitly with
+// logbody=<field>,... Bodies of other content types are logged if they are text,
+// and summarised otherwise. Bodies exceeding the limit are truncated, unless they would be redacted, in which case
+// they are summarised.
+//
+//zero:middleware logbody
+func BodyMiddleware(logbody string, redacted zero.RedactedFields, config BodyConfig, logger *slog.Logger) zero.Middleware {
+	if !config.Enabled {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	redact := &redactor{patterns: config.Redact, fields: redacted}
+	for field := range strings.SplitSeq(logbody, ",") {
+		if field != "" {
+			redact.patterns = append(redact.patterns, field)
+		}
+	}
+	limit := int(config.Limit)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			request := &capture{limit: limit}
+			if r.Body != nil {
+				// Capture the body as it is read by the handler, so that streaming is unaffected.
+				r.Body = &captureReader{ReadCloser: r.Body, capture: request}
+			}
+			rec := &captureWriter{ResponseWriter: w, capture: capture{limit: limit}}
+			next.ServeHTTP(rec, r)
+			if rec.status == 0 {
+				rec.status = http.StatusOK
+			}
+			logger.Info("HTTP request",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"status", rec.status,
+				"duration", time.Since(start),
+				"request_body", redact.body(r.Header.Get("Content-Type"), request),
+				"response_body", redact.body(w.Header().Get("Content-Type"), &rec.capture),
+			)
+		})
+	}
+}
+
+// capture records the first limit bytes of a body, and its total size.
+type capture struct {
+	limit int
+	buf   bytes.Buffer
+	size  int
+}
+
+func (c *capture) record(p []byte) {
+	c.size += len(p)
+	if remaining := c.limit - c.buf.Len(); remaining > 0 {
+		c.buf.Write(p[:min(len(p), remaining)])
+	}
+}
+
+func (c *capture) truncated() bool { return c.size > c.buf.Len() }
+
+type captureReader struct {
+	io.ReadCloser
+	capture *capture
+}
+
+func (c *captureReader) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.capture.record(p[:n])
+	return n, err
+}
+
+type captureWriter struct {
+	http.ResponseWriter
+	capture
+	status int
+}
+
+func (c *captureWriter) WriteHeader(status int) {
+	if c.status == 0 {
+		c.status = status
+	}
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *captureWriter) Write(p []byte) (int, error) {
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	c.record(p)
+	return c.ResponseWriter.Write(p)
+}
+
+func (c *captureWriter) Unwrap() http.ResponseWriter { return c.ResponseWriter }
+
+type redactor struct {
+	patterns []string
+	// Fields redacted by name rather than by pattern, ignoring case as encoding/json does.
+	fields []string
+}
+
+// body returns the loggable representation of a captured body.
+func (r *redactor) body(contentType string, body *capture) string {
+	if body.size == 0 {
+		return ""
+	}
+	if contentType == "" {
+		contentType = http.DetectContentType(body.buf.Bytes())
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		if body.truncated() {
+			return summary(mediaType, body)
+		}
+		var value any
+		if err := json.Unmarshal(body.buf.Bytes(), &value); err != nil {
+			return summary(mediaType, body)
+		}
+		data, err := json.Marshal(r.redactJSON(value))
+		if err != nil {
+			return summary(mediaType, body)
+		}
+		return string(data)
+
+	case mediaType == "application/x-www-form-urlencoded":
+		if body.truncated() {
+			return summary(mediaType, body)
+		}
+		values, err := url.ParseQuery(body.buf.String())
+		if err != nil {
+			return summary(mediaType, body)
+		}
+		for key := range values {
+			if r.match(key) {
+				values[key] = []string{Redacted}
+			}
+		}
+		return values.Encode()
+
+	case strings.HasPrefix(mediaType, "text/"):
+		if body.truncated() {
+			return body.buf.String() + "..."
+		}
+		return body.buf.String()
+
+	default:
+		return summary(mediaType, body)
+	}
+}
+
+func (r *redactor) redactJSON(value any) any {
+	switch value := value.(type) {
+	case map[string]any:
+		for key, field := range value {
+			if r.match(key) {
+				value[key] = Redacted
+			} else {
+				value[key] = r.redactJSON(field)
+			}
+		}
+	case []any:
+		for i, element := range value {
+			value[i] = r.redactJSON(element)
+		}
+	}
+	return value
+}
+
+func (r *redactor) match(key string) bool {
+	if slices.ContainsFunc(r.fields, func(field string) bool { return strings.EqualFold(field, key) }) {
+		return true
+	}
+	key = strings.ToLower(key)
+	for _, pattern := range r.patterns {
+		if ok, _ := path.Match(strings.ToLower(pattern), key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func summary(mediaType string, body *capture) string {
+	return "[" + mediaType + " body of " + zero.ByteSize(body.size).String() + "]"
+}
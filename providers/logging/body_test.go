@@ -0,0 +1,83 @@
+package logging_test
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/alecthomas/zero"
+	"github.com/alecthomas/zero/providers/logging"
+)
+
+func TestBodyMiddleware(t *testing.T) {
+	t.Parallel()
+	config := logging.BodyConfig{Enabled: true, Limit: 64, Redact: []string{"*password*"}}
+	tests := []struct {
+		name         string
+		logbody      string
+		redacted     zero.RedactedFields
+		contentType  string
+		request      string
+		response     string
+		requestBody  string
+		responseBody string
+	}{
+		{"JSON", "", nil, "application/json", `{"name":"alice","password":"hunter2"}`, `{"id":1}`,
+			`request_body="{\"name\":\"alice\",\"password\":\"[REDACTED]\"}"`, `response_body="{\"id\":1}"`},
+		{"NestedJSON", "ssn", nil, "application/json", `{"users":[{"name":"alice","ssn":"123"}]}`, `{"SSN":"123"}`,
+			`request_body="{\"users\":[{\"name\":\"alice\",\"ssn\":\"[REDACTED]\"}]}"`, `response_body="{\"SSN\":\"[REDACTED]\"}"`},
+		{"RedactedFields", "", zero.RedactedFields{"otp*"}, "application/json", `{"OTP*":"123","otp_hint":"sms"}`, `{}`,
+			`request_body="{\"OTP*\":\"[REDACTED]\",\"otp_hint\":\"sms\"}"`, `response_body={}`},
+		{"Form", "", nil, "application/x-www-form-urlencoded", `user=alice&Password=hunter2`, `ok`,
+			`request_body="Password=%5BREDACTED%5D&user=alice"`, `response_body=ok`},
+		{"TruncatedJSON", "", nil, "application/json", `{"password":"` + strings.Repeat("x", 100) + `"}`, ``,
+			`request_body="[application/json body of 115B]"`, `response_body=""`},
+		{"TruncatedText", "", nil, "text/plain", strings.Repeat("x", 100), ``,
+			`request_body=` + strings.Repeat("x", 64) + `...`, `response_body=""`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			buf := &bytes.Buffer{}
+			logger := slog.New(slog.NewTextHandler(buf, nil))
+			handler := logging.BodyMiddleware(test.logbody, test.redacted, config, logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				assert.NoError(t, err)
+				// The handler must receive the full, unredacted body.
+				assert.Equal(t, test.request, string(body))
+				if strings.HasPrefix(test.response, "{") {
+					w.Header().Set("Content-Type", "application/json")
+				}
+				w.WriteHeader(http.StatusCreated)
+				_, _ = w.Write([]byte(test.response))
+			}))
+			r := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(test.request))
+			r.Header.Set("Content-Type", test.contentType)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			assert.Equal(t, http.StatusCreated, w.Code)
+			assert.Equal(t, test.response, w.Body.String())
+			assert.Contains(t, buf.String(), "method=POST path=/users status=201")
+			assert.Contains(t, buf.String(), test.requestBody)
+			assert.Contains(t, buf.String(), test.responseBody)
+		})
+	}
+}
+
+func TestBodyMiddlewareDisabled(t *testing.T) {
+	t.Parallel()
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewTextHandler(buf, nil))
+	handler := logging.BodyMiddleware("", nil, logging.BodyConfig{}, logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, "ok", w.Body.String())
+	assert.Equal(t, "", buf.String())
+}
@@ -0,0 +1,109 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultBatchWindow is the [BatchOptions.Window] used if none is set.
+const DefaultBatchWindow = time.Second
+
+// BatchOptions control how events are batched for a batch subscriber.
+type BatchOptions struct {
+	// Size is the maximum number of events in a batch.
+	Size int
+	// Window is the maximum time to wait for a batch to fill before delivering it, measured from the first event in
+	// the batch.
+	Window time.Duration
+}
+
+func (b BatchOptions) withDefaults() BatchOptions {
+	b.Size = max(b.Size, 1)
+	if b.Window <= 0 {
+		b.Window = DefaultBatchWindow
+	}
+	return b
+}
+
+// BatchTopic is implemented by a [Topic] that can deliver events to subscribers in batches natively, eg. by claiming
+// multiple events at once.
+type BatchTopic[T any] interface {
+	Topic[T]
+	// SubscribeBatch subscribes to the topic, delivering events in batches.
+	//
+	// If the handler returns an error every event in the batch fails with it.
+	SubscribeBatch(ctx context.Context, options BatchOptions, handler func(ctx context.Context, events []Event[T]) error) error
+}
+
+// SubscribeBatch subscribes to topic, delivering events to handler in batches of up to [BatchOptions.Size] events,
+// waiting at most [BatchOptions.Window] for a batch to fill.
+//
+// If the topic implements [BatchTopic] batching is delegated to it. Otherwise events are collected from concurrent
+// deliveries of the topic, which are held until their batch has been handled. If the handler returns an error every
+// event in the batch fails with it.
+func SubscribeBatch[T any](ctx context.Context, topic Topic[T], options BatchOptions, handler func(ctx context.Context, events []Event[T]) error) error {
+	options = options.withDefaults()
+	if batched, ok := topic.(BatchTopic[T]); ok {
+		return batched.SubscribeBatch(ctx, options, handler)
+	}
+	// Each event in a batch is held by a delivery until the batch is handled, so the topic must deliver a full batch
+	// at once for each concurrent batch.
+	subscribe := SubscribeOptionsFromContext(ctx, SubscribeOptions{})
+	subscribe.Concurrency *= options.Size
+	b := &batcher[T]{ctx: ctx, options: options, handler: handler}
+	return topic.Subscribe(WithSubscribeOptions(ctx, subscribe), b.deliver)
+}
+
+// batcher collects individually delivered events into batches.
+type batcher[T any] struct {
+	ctx     context.Context
+	options BatchOptions
+	handler func(context.Context, []Event[T]) error
+
+	lock    sync.Mutex
+	current *batch[T]
+}
+
+type batch[T any] struct {
+	events []Event[T]
+	timer  *time.Timer
+	done   chan struct{} // Closed once the batch has been handled
+	err    error
+}
+
+func (b *batcher[T]) deliver(ctx context.Context, event Event[T]) error {
+	b.lock.Lock()
+	current := b.current
+	if current == nil {
+		current = &batch[T]{done: make(chan struct{})}
+		current.timer = time.AfterFunc(b.options.Window, func() { b.flush(current) })
+		b.current = current
+	}
+	current.events = append(current.events, event)
+	full := len(current.events) >= b.options.Size
+	b.lock.Unlock()
+	if full {
+		b.flush(current)
+	}
+	select {
+	case <-current.done:
+		return current.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// flush handles the batch if it is still being collected.
+func (b *batcher[T]) flush(flushing *batch[T]) {
+	b.lock.Lock()
+	if b.current != flushing {
+		b.lock.Unlock()
+		return
+	}
+	b.current = nil
+	b.lock.Unlock()
+	flushing.timer.Stop()
+	flushing.err = b.handler(b.ctx, flushing.events)
+	close(flushing.done)
+}
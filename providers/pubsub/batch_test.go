@@ -0,0 +1,78 @@
+package pubsub_test
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/alecthomas/errors"
+	"github.com/alecthomas/zero/providers/pubsub"
+	"github.com/alecthomas/zero/providers/pubsub/pubsubtest"
+)
+
+func TestSubscribeBatch(t *testing.T) {
+	t.Parallel()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	topic := pubsub.NewMemoryTopic[pubsubtest.User](logger)
+	batches := make(chan []string, 8)
+	err := pubsub.SubscribeBatch(t.Context(), topic, pubsub.BatchOptions{Size: 3, Window: 100 * time.Millisecond},
+		func(ctx context.Context, events []pubsub.Event[pubsubtest.User]) error {
+			var names []string
+			for _, event := range events {
+				names = append(names, event.Payload().Name)
+			}
+			batches <- names
+			return nil
+		})
+	assert.NoError(t, err)
+
+	for _, name := range []string{"a", "b", "c", "d"} {
+		assert.NoError(t, topic.Publish(t.Context(), pubsub.NewEvent(pubsubtest.User{Name: name})))
+	}
+	// The first three events fill a batch, while the fourth is delivered once the window elapses.
+	var sizes []int
+	for range 2 {
+		select {
+		case batch := <-batches:
+			sizes = append(sizes, len(batch))
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for batch")
+		}
+	}
+	assert.Equal(t, []int{3, 1}, sizes)
+}
+
+func TestSubscribeBatchError(t *testing.T) {
+	t.Parallel()
+	topic := &capturingTopic{}
+	err := pubsub.SubscribeBatch(t.Context(), pubsub.Topic[string](topic), pubsub.BatchOptions{Size: 2},
+		func(ctx context.Context, events []pubsub.Event[string]) error {
+			return errors.New("failed")
+		})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, topic.concurrency)
+
+	errs := make(chan error, 2)
+	for _, payload := range []string{"a", "b"} {
+		go func() { errs <- topic.handler(t.Context(), pubsub.NewEvent(payload)) }()
+	}
+	for range 2 {
+		assert.EqualError(t, <-errs, "failed")
+	}
+}
+
+// capturingTopic captures its subscriber, for delivering events directly.
+type capturingTopic struct {
+	pubsub.Topic[string]
+	concurrency int
+	handler     func(context.Context, pubsub.Event[string]) error
+}
+
+func (r *capturingTopic) Subscribe(ctx context.Context, handler func(context.Context, pubsub.Event[string]) error) error {
+	r.concurrency = pubsub.SubscribeOptionsFromContext(ctx, pubsub.SubscribeOptions{}).Concurrency
+	r.handler = handler
+	return nil
+}
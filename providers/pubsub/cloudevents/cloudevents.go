@@ -0,0 +1,235 @@
+// Package cloudevents maps CloudEvents HTTP messages to and from [pubsub.Topic]s, so that Zero services can
+// interoperate with Knative eventing and other CloudEvents-speaking systems.
+//
+// Both structured mode, where the whole event is JSON encoded in the body, and binary mode, where attributes are
+// carried in ce-* headers and the body is the data, are supported. See
+// https://github.com/cloudevents/spec/blob/v1.0.2/cloudevents/bindings/http-protocol-binding.md
+package cloudevents
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/errors"
+	"github.com/alecthomas/zero"
+	"github.com/alecthomas/zero/internal/cloudevent"
+	"github.com/alecthomas/zero/providers/pubsub"
+)
+
+// StructuredContentType is the content type of a CloudEvent in structured mode.
+const StructuredContentType = "application/cloudevents+json"
+
+// maxBodySize is the maximum size of a CloudEvent accepted by [Handler].
+const maxBodySize = 4 << 20
+
+// ErrUnsupportedMedia is returned by [Decode] for a request whose content type is not JSON.
+var ErrUnsupportedMedia = errors.New("unsupported media type")
+
+// Mode is the HTTP content mode in which a CloudEvent is sent.
+type Mode int
+
+const (
+	// Binary mode carries attributes in ce-* headers, with the data as the body.
+	Binary Mode = iota
+	// Structured mode encodes the whole event as JSON in the body.
+	Structured
+)
+
+// Decode a CloudEvent in structured or binary mode from an HTTP request.
+func Decode[T any](r *http.Request) (pubsub.Event[T], error) {
+	var event pubsub.Event[T]
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+	if err != nil {
+		return event, errors.Errorf("failed to read CloudEvent: %w", err)
+	}
+	mediaType := "application/json"
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err = mime.ParseMediaType(contentType)
+		if err != nil {
+			return event, errors.Errorf("%s: %w", contentType, ErrUnsupportedMedia)
+		}
+	}
+	var envelope cloudevent.Event[json.RawMessage]
+	switch {
+	case mediaType == "application/cloudevents-batch+json":
+		return event, errors.Errorf("batched CloudEvents: %w", ErrUnsupportedMedia)
+
+	case mediaType == StructuredContentType:
+		if err := json.Unmarshal(body, &envelope); err != nil {
+			return event, errors.Errorf("invalid structured CloudEvent: %w", err)
+		}
+
+	case mediaType == "application/json" || mediaType == "text/json" || strings.HasSuffix(mediaType, "+json"):
+		envelope, err = binaryEnvelope(r.Header, body)
+		if err != nil {
+			return event, err
+		}
+
+	default:
+		return event, errors.Errorf("%s: %w", mediaType, ErrUnsupportedMedia)
+	}
+	if envelope.SpecVersion != "1.0" {
+		return event, errors.Errorf("unsupported CloudEvents specversion %q", envelope.SpecVersion)
+	}
+	if envelope.ID == "" || envelope.Source == "" || envelope.Type == "" {
+		return event, errors.Errorf("CloudEvent is missing required attributes id, source or type")
+	}
+	// Round-trip through JSON so that the event is decoded exactly as it is from a topic.
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		return event, errors.WithStack(err)
+	}
+	if err := json.Unmarshal(data, &event); err != nil {
+		return event, errors.Errorf("invalid CloudEvent data: %w", err)
+	}
+	return event, nil
+}
+
+func binaryEnvelope(header http.Header, body []byte) (cloudevent.Event[json.RawMessage], error) {
+	envelope := cloudevent.Event[json.RawMessage]{
+		SpecVersion:     header.Get("ce-specversion"),
+		Type:            header.Get("ce-type"),
+		Source:          header.Get("ce-source"),
+		ID:              header.Get("ce-id"),
+		DataContentType: header.Get("Content-Type"),
+		Tenant:          header.Get("ce-tenant"),
+		Data:            body,
+	}
+	if value := header.Get("ce-time"); value != "" {
+		created, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return envelope, errors.Errorf("invalid ce-time %q: %w", value, err)
+		}
+		envelope.Time = created
+	}
+	if value := header.Get("ce-dataversion"); value != "" {
+		version, err := strconv.Atoi(value)
+		if err != nil {
+			return envelope, errors.Errorf("invalid ce-dataversion %q: %w", value, err)
+		}
+		envelope.DataVersion = version
+	}
+	return envelope, nil
+}
+
+// NewRequest creates an HTTP request POSTing the event to url as a CloudEvent in the given mode.
+func NewRequest[T any](ctx context.Context, url string, event pubsub.Event[T], mode Mode) (*http.Request, error) {
+	// Event marshals to a structured CloudEvent.
+	structured, err := json.Marshal(pubsub.PropagateTenant(ctx, event))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to marshal event %s", event.ID())
+	}
+	if mode == Structured {
+		r, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(structured))
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		r.Header.Set("Content-Type", StructuredContentType)
+		return r, nil
+	}
+	var envelope cloudevent.Event[json.RawMessage]
+	if err := json.Unmarshal(structured, &envelope); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(envelope.Data))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	r.Header.Set("Content-Type", envelope.DataContentType)
+	r.Header.Set("ce-specversion", envelope.SpecVersion)
+	r.Header.Set("ce-type", envelope.Type)
+	r.Header.Set("ce-source", envelope.Source)
+	r.Header.Set("ce-id", envelope.ID)
+	r.Header.Set("ce-time", envelope.Time.Format(time.RFC3339Nano))
+	if envelope.DataVersion != 0 {
+		r.Header.Set("ce-dataversion", strconv.Itoa(envelope.DataVersion))
+	}
+	if envelope.Tenant != "" {
+		r.Header.Set("ce-tenant", envelope.Tenant)
+	}
+	return r, nil
+}
+
+// Handler returns an HTTP handler publishing CloudEvents POSTed to it to topic.
+//
+// Events are acknowledged with 202 Accepted once published. Malformed events are rejected with 400 Bad Request, and
+// non-JSON content with 415 Unsupported Media Type, encoded by encodeError.
+func Handler[T any](logger *slog.Logger, encodeError zero.ErrorEncoder, topic pubsub.Topic[T]) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event, err := Decode[T](r)
+		if errors.Is(err, ErrUnsupportedMedia) {
+			encodeError(logger, w, err.Error(), http.StatusUnsupportedMediaType)
+			return
+		} else if err != nil {
+			encodeError(logger, w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := topic.Publish(r.Context(), event); err != nil {
+			logger.Error("Failed to publish CloudEvent", "id", event.ID(), "error", err)
+			encodeError(logger, w, "failed to publish event", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+//zero:config prefix="cloudevents-sink-"
+type SinkConfig struct {
+	URL        string        `help:"URL to send CloudEvents to, eg. a Knative broker."`
+	Structured bool          `help:"Send CloudEvents in structured mode rather than binary mode."`
+	Timeout    time.Duration `help:"Timeout for sending a CloudEvent." default:"10s"`
+}
+
+// Sink sends events as CloudEvents over HTTP.
+//
+// Events are forwarded from a topic by a subscriber calling [Send], eg.
+//
+//	//zero:subscribe
+//	func (f *Forwarder) OnOrderPlaced(ctx context.Context, event pubsub.Event[OrderPlaced]) error {
+//		return cloudevents.Send(ctx, f.sink, event)
+//	}
+type Sink struct {
+	url    string
+	mode   Mode
+	client *http.Client
+}
+
+// NewSink creates a new [Sink].
+//
+//zero:provider weak
+func NewSink(config SinkConfig) *Sink {
+	mode := Binary
+	if config.Structured {
+		mode = Structured
+	}
+	return &Sink{url: config.URL, mode: mode, client: &http.Client{Timeout: config.Timeout}}
+}
+
+// Send the event to the sink, returning an error if it is not accepted with a 2xx response.
+func Send[T any](ctx context.Context, sink *Sink, event pubsub.Event[T]) error {
+	if sink.url == "" {
+		return errors.Errorf("no CloudEvents sink URL configured for event %s", event.ID())
+	}
+	r, err := NewRequest(ctx, sink.url, event, sink.mode)
+	if err != nil {
+		return err
+	}
+	resp, err := sink.client.Do(r)
+	if err != nil {
+		return errors.Errorf("failed to send event %s to %s: %w", event.ID(), sink.url, err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.Errorf("failed to send event %s to %s: %s", event.ID(), sink.url, resp.Status)
+	}
+	return nil
+}
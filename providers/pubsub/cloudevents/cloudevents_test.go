@@ -0,0 +1,111 @@
+package cloudevents_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/alecthomas/zero"
+	"github.com/alecthomas/zero/providers/logging/loggingtest"
+	"github.com/alecthomas/zero/providers/pubsub"
+	"github.com/alecthomas/zero/providers/pubsub/cloudevents"
+	"github.com/alecthomas/zero/providers/pubsub/pubsubtest"
+	"github.com/alecthomas/zero/providers/tenant"
+)
+
+func TestSinkToHandler(t *testing.T) {
+	for _, structured := range []bool{false, true} {
+		name := "Binary"
+		if structured {
+			name = "Structured"
+		}
+		t.Run(name, func(t *testing.T) {
+			logger := loggingtest.NewForTesting()
+			topic := pubsub.NewMemoryTopic[pubsubtest.User](logger)
+			received := make(chan pubsub.Event[pubsubtest.User], 1)
+			err := topic.Subscribe(t.Context(), func(ctx context.Context, event pubsub.Event[pubsubtest.User]) error {
+				received <- event
+				return nil
+			})
+			assert.NoError(t, err)
+			server := httptest.NewServer(cloudevents.Handler(logger, zero.EncodeError, topic))
+			defer server.Close()
+
+			sink := cloudevents.NewSink(cloudevents.SinkConfig{URL: server.URL, Structured: structured, Timeout: time.Second})
+			sent := pubsub.NewEvent(pubsubtest.User{Name: "Bob", Age: 30}).WithVersion(2).WithTenant(tenant.ID("acme"))
+			assert.NoError(t, cloudevents.Send(t.Context(), sink, sent))
+
+			select {
+			case event := <-received:
+				assert.Equal(t, sent.ID(), event.ID())
+				assert.Equal(t, sent.Source(), event.Source())
+				assert.Equal(t, sent.Created(), event.Created())
+				assert.Equal(t, sent.Payload(), event.Payload())
+				assert.Equal(t, 2, event.Version())
+				assert.Equal(t, tenant.ID("acme"), event.Tenant())
+			case <-time.After(time.Second):
+				t.Fatal("timed out waiting for event")
+			}
+		})
+	}
+}
+
+func TestHandlerRejectsInvalidEvents(t *testing.T) {
+	logger := loggingtest.NewForTesting()
+	handler := cloudevents.Handler(logger, zero.EncodeError, pubsub.NewMemoryTopic[pubsubtest.User](logger))
+	tests := []struct {
+		name        string
+		contentType string
+		headers     map[string]string
+		body        string
+		status      int
+	}{
+		{
+			name:        "BinaryMissingID",
+			contentType: "application/json",
+			headers:     map[string]string{"ce-specversion": "1.0", "ce-type": "user", "ce-source": "test"},
+			body:        `{"name":"Bob"}`,
+			status:      http.StatusBadRequest,
+		},
+		{
+			name:        "StructuredWrongSpecVersion",
+			contentType: cloudevents.StructuredContentType,
+			body:        `{"specversion":"0.3","id":"1","type":"user","source":"test","data":{"name":"Bob"}}`,
+			status:      http.StatusBadRequest,
+		},
+		{
+			name:        "NotJSON",
+			contentType: "text/plain",
+			body:        `Bob`,
+			status:      http.StatusUnsupportedMediaType,
+		},
+		{
+			name:        "Batch",
+			contentType: "application/cloudevents-batch+json",
+			body:        `[]`,
+			status:      http.StatusUnsupportedMediaType,
+		},
+		{
+			name:        "Structured",
+			contentType: cloudevents.StructuredContentType,
+			body:        `{"specversion":"1.0","id":"1","type":"user","source":"test","data":{"name":"Bob"}}`,
+			status:      http.StatusAccepted,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/events/user", strings.NewReader(test.body))
+			r.Header.Set("Content-Type", test.contentType)
+			for key, value := range test.headers {
+				r.Header.Set(key, value)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			assert.Equal(t, test.status, w.Code, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package pubsub
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// maxDedupeEntries is the maximum number of event IDs remembered by [Deduplicate], beyond which the oldest are
+// forgotten even if they are within the window.
+const maxDedupeEntries = 10_000
+
+// Deduplicate wraps a subscriber so that deliveries of an event with the ID of one handled within window are
+// acknowledged without calling the subscriber, or returns handler unchanged if window is not positive.
+//
+// Events whose delivery fails are forgotten, so that they can be redelivered. IDs are remembered in memory, so
+// duplicates are only suppressed within a single process.
+//
+// [Topic] implementations that may deliver an event more than once call this with [SubscribeOptions.Dedupe].
+func Deduplicate[T any](window time.Duration, handler func(context.Context, Event[T]) error) func(context.Context, Event[T]) error {
+	if window <= 0 {
+		return handler
+	}
+	seen := newDedupeCache(window, maxDedupeEntries)
+	return func(ctx context.Context, event Event[T]) error {
+		if !seen.claim(event.ID(), time.Now()) {
+			return nil
+		}
+		if err := handler(ctx, event); err != nil {
+			seen.forget(event.ID())
+			return err
+		}
+		return nil
+	}
+}
+
+// dedupeCache is a bounded cache of recently seen event IDs, ordered from newest to oldest.
+type dedupeCache struct {
+	window   time.Duration
+	capacity int
+
+	lock  sync.Mutex
+	order *list.List // Of *dedupeEntry, newest first
+	seen  map[string]*list.Element
+}
+
+type dedupeEntry struct {
+	id   string
+	seen time.Time
+}
+
+func newDedupeCache(window time.Duration, capacity int) *dedupeCache {
+	return &dedupeCache{window: window, capacity: capacity, order: list.New(), seen: map[string]*list.Element{}}
+}
+
+// claim records the ID as seen at now, returning false if it was already seen within the window.
+func (d *dedupeCache) claim(id string, now time.Time) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	for oldest := d.order.Back(); oldest != nil; oldest = d.order.Back() {
+		entry := oldest.Value.(*dedupeEntry) //nolint:forcetypeassert
+		if now.Sub(entry.seen) < d.window && d.order.Len() < d.capacity {
+			break
+		}
+		d.order.Remove(oldest)
+		delete(d.seen, entry.id)
+	}
+	if _, ok := d.seen[id]; ok {
+		return false
+	}
+	d.seen[id] = d.order.PushFront(&dedupeEntry{id: id, seen: now})
+	return true
+}
+
+// forget removes the ID, so that it is no longer considered seen.
+func (d *dedupeCache) forget(id string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if element, ok := d.seen[id]; ok {
+		d.order.Remove(element)
+		delete(d.seen, id)
+	}
+}
@@ -0,0 +1,76 @@
+package pubsub_test
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/alecthomas/errors"
+	"github.com/alecthomas/zero/providers/pubsub"
+	"github.com/alecthomas/zero/providers/pubsub/pubsubtest"
+)
+
+func TestDeduplicate(t *testing.T) {
+	t.Parallel()
+	var handled []string
+	fail := true
+	handler := pubsub.Deduplicate(50*time.Millisecond, func(ctx context.Context, event pubsub.Event[pubsubtest.User]) error {
+		handled = append(handled, event.Payload().Name)
+		if event.Payload().Name == "fails" && fail {
+			fail = false
+			return errors.New("failed")
+		}
+		return nil
+	})
+	bob := pubsub.NewEvent(pubsubtest.User{Name: "Bob"})
+	fails := pubsub.NewEvent(pubsubtest.User{Name: "fails"})
+
+	assert.NoError(t, handler(t.Context(), bob))
+	assert.NoError(t, handler(t.Context(), bob))
+	// Failed deliveries are not remembered, so the redelivery is handled.
+	assert.EqualError(t, handler(t.Context(), fails), "failed")
+	assert.NoError(t, handler(t.Context(), fails))
+	assert.NoError(t, handler(t.Context(), fails))
+	assert.Equal(t, []string{"Bob", "fails", "fails"}, handled)
+
+	// Once the window has elapsed the event is handled again.
+	time.Sleep(60 * time.Millisecond)
+	assert.NoError(t, handler(t.Context(), bob))
+	assert.Equal(t, []string{"Bob", "fails", "fails", "Bob"}, handled)
+}
+
+func TestMemoryPubSubDedupe(t *testing.T) {
+	t.Parallel()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	topic := pubsub.NewMemoryTopic[pubsubtest.User](logger)
+	handled := make(chan string, 4)
+	ctx := pubsub.WithSubscribeOptions(t.Context(), pubsub.SubscribeOptions{Dedupe: time.Minute})
+	err := topic.Subscribe(ctx, func(ctx context.Context, event pubsub.Event[pubsubtest.User]) error {
+		handled <- event.Payload().Name
+		return nil
+	})
+	assert.NoError(t, err)
+
+	bob := pubsub.NewEvent(pubsubtest.User{Name: "Bob"})
+	for _, event := range []pubsub.Event[pubsubtest.User]{bob, bob, pubsub.NewEvent(pubsubtest.User{Name: "Alice"})} {
+		assert.NoError(t, topic.Publish(t.Context(), event))
+	}
+	var names []string
+	for range 2 {
+		select {
+		case name := <-handled:
+			names = append(names, name)
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for events")
+		}
+	}
+	assert.Equal(t, []string{"Bob", "Alice"}, names)
+	select {
+	case name := <-handled:
+		t.Fatalf("unexpected duplicate delivery of %s", name)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
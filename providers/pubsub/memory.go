@@ -26,30 +26,38 @@ var _ Topic[string] = (*InMemoryTopic[string])(nil)
 
 func (i *InMemoryTopic[T]) Publish(ctx context.Context, msg Event[T]) error {
 	select {
-	case i.messages <- msg:
+	case i.messages <- PropagateTenant(ctx, msg):
 		return nil
 	default:
 		return errors.Errorf("failed to publish message, channel full")
 	}
 }
 
+// Subscribe delivers events to handler from [SubscribeOptions.Concurrency] goroutines, suppressing duplicates within
+// [SubscribeOptions.Dedupe]. Prefetch is ignored, as events are not claimed.
 func (i *InMemoryTopic[T]) Subscribe(ctx context.Context, handler func(context.Context, Event[T]) error) error {
-	go func() {
-		for {
-			select {
-			case msg, ok := <-i.messages:
-				if !ok {
-					return
-				}
-				if err := handler(ctx, msg); err != nil {
-					i.logger.Error("Failed to handle message", "error", err)
-				}
-			case <-ctx.Done():
+	options := SubscribeOptionsFromContext(ctx, SubscribeOptions{})
+	handler = Deduplicate(options.Dedupe, handler)
+	for range options.Concurrency {
+		go i.deliver(ctx, handler)
+	}
+	return nil
+}
+
+func (i *InMemoryTopic[T]) deliver(ctx context.Context, handler func(context.Context, Event[T]) error) {
+	for {
+		select {
+		case msg, ok := <-i.messages:
+			if !ok {
 				return
 			}
+			if err := handler(TenantContext(ctx, msg), msg); err != nil {
+				i.logger.Error("Failed to handle message", "error", err)
+			}
+		case <-ctx.Done():
+			return
 		}
-	}()
-	return nil
+	}
 }
 
 func (i *InMemoryTopic[T]) Close() error {
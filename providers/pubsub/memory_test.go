@@ -1,10 +1,13 @@
 package pubsub_test
 
 import (
+	"context"
 	"log/slog"
 	"os"
 	"testing"
+	"time"
 
+	"github.com/alecthomas/assert/v2"
 	"github.com/alecthomas/zero/providers/pubsub"
 	"github.com/alecthomas/zero/providers/pubsub/pubsubtest"
 )
@@ -15,3 +18,39 @@ func TestMemoryPubSub(t *testing.T) {
 	topic := pubsub.NewMemoryTopic[pubsubtest.User](logger)
 	pubsubtest.RunPubSubTest(t, topic)
 }
+
+func TestMemoryPubSubConcurrency(t *testing.T) {
+	t.Parallel()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	topic := pubsub.NewMemoryTopic[pubsubtest.User](logger)
+	ctx := pubsub.WithSubscribeOptions(t.Context(), pubsub.SubscribeOptions{Concurrency: 3})
+	started := make(chan struct{})
+	release := make(chan struct{})
+	err := topic.Subscribe(ctx, func(ctx context.Context, event pubsub.Event[pubsubtest.User]) error {
+		started <- struct{}{}
+		<-release
+		return nil
+	})
+	assert.NoError(t, err)
+	for range 4 {
+		assert.NoError(t, topic.Publish(t.Context(), pubsub.NewEvent(pubsubtest.User{Name: "Bob"})))
+	}
+	for range 3 {
+		select {
+		case <-started:
+		case <-time.After(time.Second):
+			t.Fatal("expected 3 events to be processed concurrently")
+		}
+	}
+	select {
+	case <-started:
+		t.Fatal("expected at most 3 events to be processed concurrently")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("expected the fourth event to be processed")
+	}
+}
@@ -0,0 +1,6 @@
+CREATE TABLE pubsub_schemas (
+    subject VARCHAR(255) NOT NULL,
+    version INTEGER NOT NULL,
+    definition TEXT NOT NULL,
+    PRIMARY KEY (subject, version)
+);
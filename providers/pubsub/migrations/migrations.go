@@ -0,0 +1,16 @@
+// Package migrations contains SQL migrations for the pubsub schema registry.
+package migrations
+
+import (
+	"embed"
+
+	"github.com/alecthomas/zero/providers/sql"
+)
+
+//go:embed *.sql
+var migrations embed.FS
+
+//zero:provider weak multi
+func Migrations() sql.Migrations {
+	return sql.Migrations{migrations}
+}
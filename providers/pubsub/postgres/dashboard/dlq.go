@@ -5,9 +5,14 @@ import (
 	"context"
 	"database/sql"
 	_ "embed"
+	"encoding/json"
 	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/alecthomas/errors"
+	"github.com/alecthomas/zero"
 	"github.com/alecthomas/zero/providers/dashboard"
 	"github.com/alecthomas/zero/providers/pubsub/postgres/internal"
 )
@@ -63,14 +68,114 @@ func (d *Component) Index(ctx context.Context, query DQLListQuery) (string, erro
 	}))
 }
 
+// DeadLetter is a dead-lettered event.
+type DeadLetter struct {
+	// ID is the CloudEvents ID of the event.
+	ID    string `json:"id"`
+	Topic string `json:"topic"`
+	// Error that resulted in the event being dead-lettered.
+	Error        string    `json:"error"`
+	DeadLettered time.Time `json:"deadLettered"`
+	Created      time.Time `json:"created"`
+	// Event is the CloudEvent, including its payload.
+	Event   json.RawMessage `json:"event"`
+	Headers json.RawMessage `json:"headers"`
+}
+
+// PurgeQuery restricts a purge to a single topic.
+type PurgeQuery struct {
+	Topic string `qstring:"topic"`
+}
+
+type PurgeResponse struct {
+	Purged int64 `json:"purged"`
+}
+
+// ListDeadLetters returns a page of dead-lettered events, newest first.
+//
+//zero:api GET /_admin/api/dlq dashboard
+func (d *Component) ListDeadLetters(ctx context.Context, page zero.PageRequest) (zero.Page[DeadLetter], error) {
+	// The cursor is the offset of the next page.
+	var offset int64
+	if page.Cursor != "" {
+		var err error
+		offset, err = strconv.ParseInt(page.Cursor, 10, 32)
+		if err != nil || offset < 0 {
+			return zero.Page[DeadLetter]{}, zero.APIErrorf(http.StatusBadRequest, "invalid cursor %q", page.Cursor)
+		}
+	}
+	// Fetch an extra row to determine whether there is a next page.
+	rows, err := d.queries.ListDeadLetters(ctx, int32(offset), int32(page.Limit+1))
+	if err != nil {
+		return zero.Page[DeadLetter]{}, errors.WithStack(err)
+	}
+	out := zero.Page[DeadLetter]{}
+	if len(rows) > page.Limit {
+		rows = rows[:page.Limit]
+		out.NextCursor = strconv.FormatInt(offset+int64(page.Limit), 10)
+	}
+	for _, row := range rows {
+		out.Items = append(out.Items, toDeadLetter(internal.GetDeadLetterRow(row)))
+	}
+	return out, nil
+}
+
+// GetDeadLetter returns a dead-lettered event, including its payload and error.
+//
+//zero:api GET /_admin/api/dlq/{cloudEventID} dashboard
+func (d *Component) GetDeadLetter(ctx context.Context, cloudEventID string) (DeadLetter, error) {
+	row, err := d.queries.GetDeadLetter(ctx, cloudEventID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return DeadLetter{}, zero.APIErrorf(http.StatusNotFound, "dead-lettered event %q not found", cloudEventID)
+	} else if err != nil {
+		return DeadLetter{}, errors.WithStack(err)
+	}
+	return toDeadLetter(row), nil
+}
+
+// PurgeDeadLetters deletes all dead-lettered events, or only those in the topic given by the "topic" query parameter.
+//
+//zero:api DELETE /_admin/api/dlq dashboard
+func (d *Component) PurgeDeadLetters(ctx context.Context, query PurgeQuery) (PurgeResponse, error) {
+	purged, err := d.queries.PurgeDeadLetters(ctx, query.Topic)
+	if err != nil {
+		return PurgeResponse{}, errors.WithStack(err)
+	}
+	return PurgeResponse{Purged: purged}, nil
+}
+
 //zero:api DELETE /_admin/api/dlq/{cloudEventID} dashboard
 func (d *Component) DeleteDeadLetter(ctx context.Context, cloudEventID string) error {
-	_, err := d.queries.DeleteDeadLetter(ctx, cloudEventID)
-	return errors.WithStack(err)
+	deleted, err := d.queries.DeleteDeadLetter(ctx, cloudEventID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if !deleted {
+		return zero.APIErrorf(http.StatusNotFound, "dead-lettered event %q not found", cloudEventID)
+	}
+	return nil
 }
 
 //zero:api POST /_admin/api/dlq/{cloudEventID}/retry dashboard
 func (d *Component) ReenqueueDeadLetter(ctx context.Context, cloudEventID string) error {
-	_, err := d.queries.RetryDeadLetterEvent(ctx, cloudEventID)
-	return errors.WithStack(err)
+	retried, err := d.queries.RetryDeadLetterEvent(ctx, cloudEventID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if !retried {
+		return zero.APIErrorf(http.StatusNotFound, "dead-lettered event %q not found", cloudEventID)
+	}
+	return nil
+}
+
+func toDeadLetter(row internal.GetDeadLetterRow) DeadLetter {
+	return DeadLetter{
+		ID:           row.CloudeventsID,
+		Topic:        row.TopicName,
+		Error:        row.ErrorMessage,
+		DeadLettered: row.DeadLetterCreatedAt,
+		Created:      row.EventCreatedAt,
+		Event:        row.Message,
+		Headers:      row.Headers,
+	}
 }
@@ -56,6 +56,64 @@ func (q *Queries) ClaimNextEvent(ctx context.Context, topicID int64) (ClaimNextE
 	return i, err
 }
 
+const claimNextEvents = `-- name: ClaimNextEvents :many
+SELECT
+  id::BIGINT,
+  created_at::TIMESTAMP,
+  last_updated::TIMESTAMP,
+  topic_id::BIGINT,
+  state::pubsub_event_state,
+  cloudevents_id::VARCHAR(64),
+  message::JSONB,
+  headers::JSONB
+FROM pubsub_claim_next_events($1, $2)
+`
+
+type ClaimNextEventsRow struct {
+	ID            int64            `json:"id"`
+	CreatedAt     time.Time        `json:"createdAt"`
+	LastUpdated   time.Time        `json:"lastUpdated"`
+	TopicID       int64            `json:"topicId"`
+	State         PubsubEventState `json:"state"`
+	CloudeventsID string           `json:"cloudeventsId"`
+	Message       json.RawMessage  `json:"message"`
+	Headers       json.RawMessage  `json:"headers"`
+}
+
+// ClaimNextEvents atomically claims up to limit_count pending events for processing, oldest first, in the same way
+// as ClaimNextEvent.
+func (q *Queries) ClaimNextEvents(ctx context.Context, topicID int64, limitCount int32) ([]ClaimNextEventsRow, error) {
+	rows, err := q.db.QueryContext(ctx, claimNextEvents, topicID, limitCount)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ClaimNextEventsRow
+	for rows.Next() {
+		var i ClaimNextEventsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.LastUpdated,
+			&i.TopicID,
+			&i.State,
+			&i.CloudeventsID,
+			&i.Message,
+			&i.Headers,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const cleanupOldDeadLetters = `-- name: CleanupOldDeadLetters :exec
 DELETE FROM pubsub_dead_letters
 WHERE id IN (
@@ -214,6 +272,55 @@ func (q *Queries) FailEvent(ctx context.Context, eventID int64, errorMessage str
 	return action_taken, err
 }
 
+const getDeadLetter = `-- name: GetDeadLetter :one
+SELECT
+  dl.id as dead_letter_id,
+  dl.created_at as dead_letter_created_at,
+  dl.error_message,
+  e.id as event_id,
+  e.created_at as event_created_at,
+  e.cloudevents_id,
+  e.message,
+  e.headers,
+  t.name as topic_name
+FROM pubsub_dead_letters dl
+JOIN pubsub_events e ON dl.event_id = e.id
+JOIN pubsub_topics t ON e.topic_id = t.id
+WHERE e.cloudevents_id = $1
+ORDER BY dl.created_at DESC
+LIMIT 1
+`
+
+type GetDeadLetterRow struct {
+	DeadLetterID        int64           `json:"deadLetterId"`
+	DeadLetterCreatedAt time.Time       `json:"deadLetterCreatedAt"`
+	ErrorMessage        string          `json:"errorMessage"`
+	EventID             int64           `json:"eventId"`
+	EventCreatedAt      time.Time       `json:"eventCreatedAt"`
+	CloudeventsID       string          `json:"cloudeventsId"`
+	Message             json.RawMessage `json:"message"`
+	Headers             json.RawMessage `json:"headers"`
+	TopicName           string          `json:"topicName"`
+}
+
+// GetDeadLetter returns a dead-lettered event by its CloudEvents ID.
+func (q *Queries) GetDeadLetter(ctx context.Context, cloudeventsID string) (GetDeadLetterRow, error) {
+	row := q.db.QueryRowContext(ctx, getDeadLetter, cloudeventsID)
+	var i GetDeadLetterRow
+	err := row.Scan(
+		&i.DeadLetterID,
+		&i.DeadLetterCreatedAt,
+		&i.ErrorMessage,
+		&i.EventID,
+		&i.EventCreatedAt,
+		&i.CloudeventsID,
+		&i.Message,
+		&i.Headers,
+		&i.TopicName,
+	)
+	return i, err
+}
+
 const getEventStats = `-- name: GetEventStats :one
 SELECT
   COUNT(*) FILTER (WHERE e.state = 'pending') as pending_count,
@@ -374,6 +481,19 @@ func (q *Queries) PublishEvent(ctx context.Context, topicID int64, cloudeventsID
 	return event_id, err
 }
 
+const purgeDeadLetters = `-- name: PurgeDeadLetters :one
+SELECT pubsub_purge_dead_letters($1) as purged_count
+`
+
+// PurgeDeadLetters deletes all dead-lettered events in a topic, or in all topics if the topic name is empty,
+// returning the number of events deleted.
+func (q *Queries) PurgeDeadLetters(ctx context.Context, topicName string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, purgeDeadLetters, topicName)
+	var purged_count int64
+	err := row.Scan(&purged_count)
+	return purged_count, err
+}
+
 const retryDeadLetterEvent = `-- name: RetryDeadLetterEvent :one
 SELECT pubsub_retry_dead_letter_event($1) as success
 `
@@ -0,0 +1,29 @@
+-- Function to delete all dead-lettered events, optionally restricted to a single topic
+CREATE OR REPLACE FUNCTION pubsub_purge_dead_letters(p_topic_name VARCHAR(255))
+RETURNS BIGINT AS $$
+DECLARE
+  v_event_ids BIGINT[];
+BEGIN
+  -- An empty topic name purges dead letters from all topics
+  SELECT array_agg(DISTINCT dl.event_id) INTO v_event_ids
+  FROM pubsub_dead_letters dl
+  JOIN pubsub_events e ON dl.event_id = e.id
+  JOIN pubsub_topics t ON e.topic_id = t.id
+  WHERE p_topic_name = '' OR t.name = p_topic_name;
+
+  IF v_event_ids IS NULL THEN
+    RETURN 0;
+  END IF;
+
+  -- Delete from dead letter queue
+  DELETE FROM pubsub_dead_letters WHERE event_id = ANY(v_event_ids);
+
+  -- Clear any retry records
+  DELETE FROM pubsub_retries WHERE event_id = ANY(v_event_ids);
+
+  -- Delete the events themselves
+  DELETE FROM pubsub_events WHERE id = ANY(v_event_ids);
+
+  RETURN cardinality(v_event_ids);
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,58 @@
+-- Function to atomically claim up to p_limit events for processing, for batch subscribers
+CREATE OR REPLACE FUNCTION pubsub_claim_next_events(p_topic_id BIGINT, p_limit INT)
+RETURNS TABLE (
+  id BIGINT,
+  created_at TIMESTAMP,
+  last_updated TIMESTAMP,
+  topic_id BIGINT,
+  state pubsub_event_state,
+  cloudevents_id VARCHAR(64),
+  message JSONB,
+  headers JSONB
+) AS $$
+DECLARE
+  v_event_ids BIGINT[];
+BEGIN
+  -- Find and lock the next available events, as in pubsub_claim_next_event
+  SELECT array_agg(claimable.id ORDER BY claimable.created_at) INTO v_event_ids
+  FROM (
+    SELECT e.id, e.created_at
+    FROM pubsub_events e
+    WHERE e.state IN ('pending', 'retry')
+      AND e.topic_id = p_topic_id
+      AND e.id IN (
+        SELECT ev.id
+        FROM pubsub_events ev
+        LEFT JOIN pubsub_retries r ON ev.id = r.event_id
+        WHERE ev.state IN ('pending', 'retry')
+          AND ev.topic_id = p_topic_id
+          AND (r.id IS NULL OR r.next_attempt <= CURRENT_TIMESTAMP)
+      )
+    ORDER BY e.created_at ASC
+    LIMIT p_limit
+    FOR UPDATE SKIP LOCKED
+  ) claimable;
+
+  IF v_event_ids IS NULL THEN
+    RETURN;
+  END IF;
+
+  -- Mark as active
+  UPDATE pubsub_events SET state = 'active' WHERE pubsub_events.id = ANY(v_event_ids);
+
+  -- Return the event data only, oldest first
+  RETURN QUERY
+  SELECT
+    e.id,
+    e.created_at,
+    e.last_updated,
+    e.topic_id,
+    e.state,
+    e.cloudevents_id,
+    e.message,
+    e.headers
+  FROM pubsub_events e
+  WHERE e.id = ANY(v_event_ids)
+  ORDER BY e.created_at ASC;
+END;
+$$ LANGUAGE plpgsql;
@@ -15,6 +15,7 @@ import (
 
 	"github.com/alecthomas/errors"
 	"github.com/alecthomas/kong"
+	"github.com/alecthomas/zero"
 	zerointernal "github.com/alecthomas/zero/internal"
 	"github.com/alecthomas/zero/providers/pubsub"
 	"github.com/alecthomas/zero/providers/pubsub/postgres/internal"
@@ -165,12 +166,19 @@ type DeadLetterConfig struct {
 	Lifetime time.Duration `help:"Maximum age for messages in the dead letter queue." default:"120h"`
 }
 
+// SubscriberConfig is the default [pubsub.SubscribeOptions] for subscribers to a topic.
+type SubscriberConfig struct {
+	Concurrency int `help:"Default maximum number of events processed at once by each subscriber." default:"1"`
+	Prefetch    int `help:"Default number of events claimed by each subscriber ahead of those being processed." default:"0"`
+}
+
 // Config for a Postgres topic.
 //
 //zero:config prefix="topic-${type}-"
 type Config[T any] struct {
 	RetryConfig      `prefix:"backoff-"`
 	DeadLetterConfig `prefix:"dlq-"`
+	SubscriberConfig `prefix:"subscriber-"`
 }
 
 // DefaultConfig creates a default configuration for a Postgres topic.
@@ -189,14 +197,35 @@ type Topic[T any] struct {
 	topicID     int64
 	listener    *Listener
 	queries     *internal.Queries
+	defaults    pubsub.SubscribeOptions
+	clock       zero.Clock
 	lock        sync.RWMutex
-	subscribers []func(context.Context, pubsub.Event[T]) error
+	subscribers []*subscriber[T]
+	// batches is signalled when events are published, waking batch subscribers waiting for events.
+	batches chan struct{}
+}
+
+// subscriber is a pool of workers processing the events claimed for a subscriber.
+type subscriber[T any] struct {
+	handler func(context.Context, pubsub.Event[T]) error
+	// slots limits the number of events claimed for the subscriber and not yet processed to its concurrency plus
+	// prefetch.
+	slots  chan struct{}
+	events chan claimedEvent[T]
+}
+
+type claimedEvent[T any] struct {
+	id    int64
+	event pubsub.Event[T]
 }
 
-var _ pubsub.Topic[string] = (*Topic[string])(nil)
+var _ pubsub.BatchTopic[string] = (*Topic[string])(nil)
 
 // New creates a new [pubsub.Topic] backed by Postgres.
 //
+// Backoff between polls of the backlog and batch windows are timed by [zero.Clock]. Retries of failed events are
+// scheduled by the database.
+//
 //zero:provider weak require="github.com/alecthomas/zero/providers/pubsub/postgres/dashboard.New"
 func New[T any](
 	ctx context.Context,
@@ -204,6 +233,7 @@ func New[T any](
 	listener *Listener,
 	db *sql.DB,
 	config Config[T],
+	clock zero.Clock,
 ) (pubsub.Topic[T], error) {
 	topic := pubsub.TopicName[T]()
 	logger.Debug("Registered topic",
@@ -214,6 +244,8 @@ func New[T any](
 		"backoff-exponent", config.RetryConfig.Exponent,
 		"dlq-enabled", config.DeadLetterConfig.Enabled,
 		"dlq-lifetime", config.DeadLetterConfig.Lifetime,
+		"subscriber-concurrency", config.SubscriberConfig.Concurrency,
+		"subscriber-prefetch", config.SubscriberConfig.Prefetch,
 	)
 	queries := internal.New(db)
 	topicRow, err := queries.CreateTopic(ctx, internal.CreateTopicParams{
@@ -234,6 +266,12 @@ func New[T any](
 		topic:    topic,
 		topicID:  topicRow.ID,
 		listener: listener,
+		batches:  make(chan struct{}, 1),
+		clock:    clock,
+		defaults: pubsub.SubscribeOptions{
+			Concurrency: config.SubscriberConfig.Concurrency,
+			Prefetch:    config.SubscriberConfig.Prefetch,
+		},
 	}
 
 	// Start the listener
@@ -255,12 +293,12 @@ func (t *Topic[T]) processBacklog(ctx context.Context) {
 	for {
 		delay := zerointernal.Jitter(time.Second * 5)
 
-		processed, err := t.processOneBacklogEvent(ctx)
+		processed, err := t.claimEvent(ctx)
 		if err != nil {
 			t.logger.Error("Backlog processing failed", "error", err)
 			delay = retry.Duration()
 		} else if processed {
-			// If we successfully process an event, immediately try to process another one under the assumption
+			// If we successfully claim an event, immediately try to claim another one under the assumption
 			// that there's more in the backlog. Once we hit the end of the backlog the delay will kick in.
 			continue
 		}
@@ -269,69 +307,92 @@ func (t *Topic[T]) processBacklog(ctx context.Context) {
 		case <-ctx.Done():
 			return
 
-		case <-time.After(delay):
+		case <-t.clock.After(delay):
 		}
 	}
 }
 
-func (t *Topic[T]) processOneBacklogEvent(ctx context.Context) (processed bool, err error) {
+// Called when the LISTENER receives a notification
+func (t *Topic[T]) notified(ctx context.Context, notification Notification) error {
+	if notification.Topic != t.topicID {
+		return nil
+	}
+	select {
+	case t.batches <- struct{}{}:
+	default:
+	}
+	_, err := t.claimEvent(ctx)
+	return errors.WithStack(err)
+}
+
+// claimEvent claims the next event and queues it for a random subscriber with a free slot.
+//
+// If every subscriber is busy no event is claimed, leaving it for the backlog to pick up once a slot frees up.
+func (t *Topic[T]) claimEvent(ctx context.Context) (claimed bool, err error) {
+	sub := t.reserveSlot()
+	if sub == nil {
+		return false, nil
+	}
 	eventRow, err := t.queries.ClaimNextEvent(ctx, t.topicID)
 	if err != nil {
+		<-sub.slots
 		if errors.Is(err, sql.ErrNoRows) {
 			return false, nil
 		}
-		return false, errors.Errorf("failed to get pending events for topic %s: %w", t.topic, err)
+		return false, errors.Errorf("failed to claim next event from topic %q: %w", t.topic, err)
 	}
 	var event pubsub.Event[T]
 	if err := json.Unmarshal(eventRow.Message, &event); err != nil {
-		return false, errors.Errorf("failed to unmarshal event %d on topic %s: %w", eventRow.ID, t.topic, err)
-	}
-	if err := t.processEvent(ctx, eventRow.ID, event); err != nil {
-		return false, errors.Errorf("failed to process event %d on topic %s: %w", eventRow.ID, t.topic, err)
+		<-sub.slots
+		return false, errors.Errorf("failed to unmarshal event %d from topic %q: %w", eventRow.ID, t.topic, err)
 	}
+	sub.events <- claimedEvent[T]{id: eventRow.ID, event: event}
 	return true, nil
 }
 
-// Called when the LISTENER receives a notification
-func (t *Topic[T]) notified(ctx context.Context, notification Notification) error {
-	if notification.Topic != t.topicID {
-		return nil
-	}
-	// Pick a random subscriber
+// reserveSlot reserves a slot with a random subscriber that has one free, or returns nil if none do.
+func (t *Topic[T]) reserveSlot() *subscriber[T] {
 	t.lock.RLock()
-	subscribers := len(t.subscribers)
-	t.lock.RUnlock()
-	if subscribers == 0 {
+	defer t.lock.RUnlock()
+	if len(t.subscribers) == 0 {
 		return nil
 	}
-
-	// Claim an event
-	eventRow, err := t.queries.ClaimNextEvent(ctx, t.topicID)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil
+	offset := rand.IntN(len(t.subscribers)) //nolint
+	for i := range t.subscribers {
+		sub := t.subscribers[(offset+i)%len(t.subscribers)]
+		select {
+		case sub.slots <- struct{}{}:
+			return sub
+		default:
 		}
-		return errors.Errorf("failed to claim next event from topic %q: %w", t.topic, err)
 	}
-	var event pubsub.Event[T]
-	err = json.Unmarshal(eventRow.Message, &event)
-	if err != nil {
-		return errors.Errorf("failed to unmarshal event %d from topic %q: %w", eventRow.ID, t.topic, err)
-	}
-	return errors.WithStack(t.processEvent(ctx, eventRow.ID, event))
+	return nil
 }
 
-func (t *Topic[T]) processEvent(ctx context.Context, eventID int64, event pubsub.Event[T]) error {
-	t.lock.RLock()
-	if len(t.subscribers) == 0 {
-		t.lock.RUnlock()
-		return errors.New("no subscribers")
+// work processes the events queued for a subscriber until ctx is cancelled.
+//
+// Events still queued when ctx is cancelled remain claimed, and are released once they are considered stuck.
+func (t *Topic[T]) work(ctx context.Context, sub *subscriber[T]) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case claimed := <-sub.events:
+			if err := t.processEvent(ctx, sub, claimed.id, claimed.event); err != nil {
+				t.logger.Error("Error processing event", "topic", t.topic, "error", err)
+			}
+			<-sub.slots
+		}
 	}
-	subscriber := t.subscribers[rand.IntN(len(t.subscribers))] //nolint
-	t.lock.RUnlock()
+}
+
+func (t *Topic[T]) processEvent(ctx context.Context, sub *subscriber[T], eventID int64, event pubsub.Event[T]) error {
+	return t.settleEvent(ctx, eventID, sub.handler(pubsub.TenantContext(ctx, event), event))
+}
 
-	// Have the event, send it to a subscriber
-	err := subscriber(ctx, event)
+// settleEvent completes, fails, dead-letters or discards a claimed event according to the error returned by its
+// subscriber.
+func (t *Topic[T]) settleEvent(ctx context.Context, eventID int64, err error) error {
 	if err != nil {
 		if errors.Is(err, pubsub.ErrDeadLetter) {
 			// Immediately send to dead letter queue
@@ -364,7 +425,7 @@ func (t *Topic[T]) Close() error {
 }
 
 func (t *Topic[T]) Publish(ctx context.Context, event pubsub.Event[T]) error {
-	data, err := json.Marshal(event)
+	data, err := json.Marshal(pubsub.PropagateTenant(ctx, event))
 	if err != nil {
 		return errors.Wrapf(err, "failed to marshal event %s", event.ID())
 	}
@@ -372,13 +433,114 @@ func (t *Topic[T]) Publish(ctx context.Context, event pubsub.Event[T]) error {
 	return errors.Wrapf(err, "failed to publish event %s to topic %s", event.ID(), t.topic)
 }
 
+// Subscribe processes events with a pool of [pubsub.SubscribeOptions.Concurrency] workers, claiming up to
+// [pubsub.SubscribeOptions.Prefetch] further events ahead of them. Options not set in ctx default to the topic's
+// [SubscriberConfig].
+//
+// [pubsub.SubscribeOptions.Dedupe] is not needed, as event IDs are unique within the database, so an event published
+// twice is rejected rather than delivered twice.
 func (t *Topic[T]) Subscribe(ctx context.Context, handler func(context.Context, pubsub.Event[T]) error) error {
+	options := pubsub.SubscribeOptionsFromContext(ctx, t.defaults)
+	sub := &subscriber[T]{
+		handler: handler,
+		slots:   make(chan struct{}, options.Concurrency+options.Prefetch),
+		events:  make(chan claimedEvent[T], options.Concurrency+options.Prefetch),
+	}
+	for range options.Concurrency {
+		go t.work(ctx, sub)
+	}
 	t.lock.Lock()
 	defer t.lock.Unlock()
-	t.subscribers = append(t.subscribers, handler)
+	t.subscribers = append(t.subscribers, sub)
 	return nil
 }
 
+// SubscribeBatch claims up to [pubsub.BatchOptions.Size] events per query, with
+// [pubsub.SubscribeOptions.Concurrency] batches processed at once.
+func (t *Topic[T]) SubscribeBatch(ctx context.Context, options pubsub.BatchOptions, handler func(context.Context, []pubsub.Event[T]) error) error {
+	subscribe := pubsub.SubscribeOptionsFromContext(ctx, t.defaults)
+	for range subscribe.Concurrency {
+		go t.processBatches(ctx, options, handler)
+	}
+	return nil
+}
+
+// processBatches repeatedly claims a batch of events and sends it to handler, until ctx is cancelled.
+func (t *Topic[T]) processBatches(ctx context.Context, options pubsub.BatchOptions, handler func(context.Context, []pubsub.Event[T]) error) {
+	retry := backoff.Backoff{Min: time.Second * 5, Max: time.Second * 30}
+	for {
+		batch, err := t.claimBatch(ctx, options)
+		if err == nil && len(batch) > 0 {
+			err = t.processBatch(ctx, batch, handler)
+		}
+		delay := zerointernal.Jitter(time.Second * 5)
+		if err != nil {
+			t.logger.Error("Batch processing failed", "topic", t.topic, "error", err)
+			delay = retry.Duration()
+		} else {
+			retry.Reset()
+			if len(batch) > 0 {
+				continue
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.batches:
+		case <-t.clock.After(delay):
+		}
+	}
+}
+
+// claimBatch claims up to a batch of events, waiting for the batch window to elapse from the first claimed event for
+// further events to be published if the batch isn't full.
+func (t *Topic[T]) claimBatch(ctx context.Context, options pubsub.BatchOptions) ([]claimedEvent[T], error) {
+	var batch []claimedEvent[T]
+	var window <-chan time.Time
+	for {
+		rows, err := t.queries.ClaimNextEvents(ctx, t.topicID, int32(options.Size-len(batch))) //nolint:gosec
+		if err != nil {
+			return batch, errors.Errorf("failed to claim events from topic %q: %w", t.topic, err)
+		}
+		for _, row := range rows {
+			var event pubsub.Event[T]
+			if err := json.Unmarshal(row.Message, &event); err != nil {
+				return batch, errors.Errorf("failed to unmarshal event %d from topic %q: %w", row.ID, t.topic, err)
+			}
+			batch = append(batch, claimedEvent[T]{id: row.ID, event: event})
+		}
+		if len(batch) == 0 || len(batch) >= options.Size {
+			return batch, nil
+		}
+		if window == nil {
+			window = t.clock.After(options.Window)
+		}
+		select {
+		case <-ctx.Done():
+			return batch, nil
+		case <-window:
+			return batch, nil
+		case <-t.batches:
+		}
+	}
+}
+
+// processBatch sends a batch of events to handler, settling every event in the batch with its result.
+func (t *Topic[T]) processBatch(ctx context.Context, batch []claimedEvent[T], handler func(context.Context, []pubsub.Event[T]) error) error {
+	events := make([]pubsub.Event[T], len(batch))
+	for i, claimed := range batch {
+		events[i] = claimed.event
+	}
+	result := handler(ctx, events)
+	var errs []error
+	for _, claimed := range batch {
+		if err := t.settleEvent(ctx, claimed.id, result); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
 func (t *Topic[T]) RetryDeadLetter(ctx context.Context, cloudeventsID string) error {
 	success, err := t.queries.RetryDeadLetterEvent(ctx, cloudeventsID)
 	if err != nil {
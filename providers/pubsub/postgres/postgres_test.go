@@ -9,6 +9,7 @@ import (
 
 	"github.com/alecthomas/assert/v2"
 	"github.com/alecthomas/errors"
+	"github.com/alecthomas/zero"
 	"github.com/alecthomas/zero/providers/logging/loggingtest"
 	"github.com/alecthomas/zero/providers/pubsub"
 	"github.com/alecthomas/zero/providers/pubsub/pubsubtest"
@@ -22,7 +23,7 @@ func TestPostgresPubSubBaseline(t *testing.T) {
 	db, _ := sqltest.NewForTesting(t, sqltest.PostgresDSN, Migrations())
 	listener, err := NewListener(t.Context(), logger, db)
 	assert.NoError(t, err)
-	topic, err := New(t.Context(), logger, listener, db, DefaultConfig[pubsubtest.User]())
+	topic, err := New(t.Context(), logger, listener, db, DefaultConfig[pubsubtest.User](), zero.RealClock{})
 	assert.NoError(t, err)
 	pubsubtest.RunPubSubTest(t, topic)
 }
@@ -35,7 +36,7 @@ func TestErrDiscardHandling(t *testing.T) {
 	assert.NoError(t, err)
 	defer listener.listenConn.Close(context.Background())
 
-	topic, err := New(t.Context(), logger, listener, db, DefaultConfig[pubsubtest.User]())
+	topic, err := New(t.Context(), logger, listener, db, DefaultConfig[pubsubtest.User](), zero.RealClock{})
 	assert.NoError(t, err)
 	defer topic.Close()
 
@@ -86,7 +87,7 @@ func TestRetryDeadLetter(t *testing.T) {
 	config.RetryConfig.Retries = 0
 	config.DeadLetterConfig.Enabled = true
 
-	topic, err := New(t.Context(), logger, listener, db, config)
+	topic, err := New(t.Context(), logger, listener, db, config, zero.RealClock{})
 	assert.NoError(t, err)
 	defer topic.Close()
 
@@ -144,3 +145,44 @@ func TestRetryDeadLetter(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "not found or not in dead letter queue")
 }
+
+func TestSubscribeBatch(t *testing.T) {
+	t.Parallel()
+	logger := loggingtest.NewForTesting()
+	db, _ := sqltest.NewForTesting(t, sqltest.PostgresDSN, Migrations())
+	listener, err := NewListener(t.Context(), logger, db)
+	assert.NoError(t, err)
+	defer listener.listenConn.Close(context.Background())
+
+	topic, err := New(t.Context(), logger, listener, db, DefaultConfig[pubsubtest.User](), zero.RealClock{})
+	assert.NoError(t, err)
+	defer topic.Close()
+
+	// Publish before subscribing, so that the first batch is claimed in a single query.
+	for i := range 5 {
+		err = topic.Publish(t.Context(), pubsub.NewEvent(pubsubtest.User{Name: "test", Age: i}))
+		assert.NoError(t, err)
+	}
+
+	batches := make(chan []pubsub.Event[pubsubtest.User], 5)
+	err = pubsub.SubscribeBatch(t.Context(), topic, pubsub.BatchOptions{Size: 3, Window: 100 * time.Millisecond},
+		func(ctx context.Context, events []pubsub.Event[pubsubtest.User]) error {
+			batches <- events
+			return nil
+		})
+	assert.NoError(t, err)
+
+	var ages []int
+	for len(ages) < 5 {
+		select {
+		case batch := <-batches:
+			assert.True(t, len(batch) <= 3, "batch of %d events exceeds the batch size", len(batch))
+			for _, event := range batch {
+				ages = append(ages, event.Payload().Age)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("timed out waiting for batches, received %v", ages)
+		}
+	}
+	assert.Equal(t, []int{0, 1, 2, 3, 4}, ages)
+}
@@ -34,6 +34,20 @@ SELECT
   headers::JSONB
 FROM pubsub_claim_next_event(sqlc.arg(topic_id));
 
+-- ClaimNextEvents atomically claims up to limit_count pending events for processing, oldest first, in the same way
+-- as ClaimNextEvent.
+-- name: ClaimNextEvents :many
+SELECT
+  id::BIGINT,
+  created_at::TIMESTAMP,
+  last_updated::TIMESTAMP,
+  topic_id::BIGINT,
+  state::pubsub_event_state,
+  cloudevents_id::VARCHAR(64),
+  message::JSONB,
+  headers::JSONB
+FROM pubsub_claim_next_events(sqlc.arg(topic_id), sqlc.arg(limit_count));
+
 -- CompleteEvent marks an event as successfully processed.
 -- name: CompleteEvent :one
 SELECT pubsub_complete_event(sqlc.arg(event_id)) as success;
@@ -128,3 +142,27 @@ LIMIT sqlc.arg(limit_count) OFFSET sqlc.arg(offset_count);
 
 -- name: DeadLetterCount :one
 SELECT COUNT(*) as count FROM pubsub_dead_letters;
+
+-- GetDeadLetter returns a dead-lettered event by its CloudEvents ID.
+-- name: GetDeadLetter :one
+SELECT
+  dl.id as dead_letter_id,
+  dl.created_at as dead_letter_created_at,
+  dl.error_message,
+  e.id as event_id,
+  e.created_at as event_created_at,
+  e.cloudevents_id,
+  e.message,
+  e.headers,
+  t.name as topic_name
+FROM pubsub_dead_letters dl
+JOIN pubsub_events e ON dl.event_id = e.id
+JOIN pubsub_topics t ON e.topic_id = t.id
+WHERE e.cloudevents_id = sqlc.arg(cloudevents_id)
+ORDER BY dl.created_at DESC
+LIMIT 1;
+
+-- PurgeDeadLetters deletes all dead-lettered events in a topic, or in all topics if the topic name is empty,
+-- returning the number of events deleted.
+-- name: PurgeDeadLetters :one
+SELECT pubsub_purge_dead_letters(sqlc.arg(topic_name)) as purged_count;
@@ -4,6 +4,7 @@ package postgres
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"testing"
@@ -790,3 +791,82 @@ func TestRetryDeadLetterEvent(t *testing.T) {
 		})
 	}
 }
+
+// deadLetterEvent publishes, claims and fails an event on a DLQ-enabled topic.
+func deadLetterEvent(t *testing.T, queries *internal.Queries, topicID int64, cloudEventsID string) {
+	t.Helper()
+	ctx := context.Background()
+	eventID, err := queries.PublishEvent(ctx, topicID, cloudEventsID, json.RawMessage(`{"test": "data"}`), json.RawMessage(`{}`))
+	assert.NoError(t, err)
+	_, err = queries.ClaimNextEvent(ctx, topicID)
+	assert.NoError(t, err)
+	action, err := queries.FailEvent(ctx, eventID, "failed "+cloudEventsID)
+	assert.NoError(t, err)
+	assert.Equal(t, internal.PubsubFailActionDeadLettered, action)
+}
+
+func createDLQTopic(t *testing.T, queries *internal.Queries, name string) internal.PubsubTopic {
+	t.Helper()
+	topic, err := queries.CreateTopic(context.Background(), internal.CreateTopicParams{
+		Name:              name,
+		MaxRetries:        0,
+		InitialBackoff:    internal.Duration(time.Minute),
+		BackoffMax:        internal.Duration(5 * time.Minute),
+		BackoffMultiplier: 2.0,
+		DlqEnabled:        true,
+		DlqMaxAge:         internal.Duration(7 * 24 * time.Hour),
+	})
+	assert.NoError(t, err)
+	return topic
+}
+
+func TestGetDeadLetter(t *testing.T) {
+	t.Parallel()
+	db, _ := sqltest.NewForTesting(t, sqltest.PostgresDSN, Migrations())
+	queries := internal.New(db)
+	ctx := context.Background()
+
+	topic := createDLQTopic(t, queries, "get-dead-letter-topic")
+	deadLetterEvent(t, queries, topic.ID, "get-dead-letter-event")
+
+	row, err := queries.GetDeadLetter(ctx, "get-dead-letter-event")
+	assert.NoError(t, err)
+	assert.Equal(t, "get-dead-letter-topic", row.TopicName)
+	assert.Equal(t, "failed get-dead-letter-event", row.ErrorMessage)
+	assert.Equal(t, `{"test": "data"}`, string(row.Message))
+
+	_, err = queries.GetDeadLetter(ctx, "missing-event")
+	assert.IsError(t, err, sql.ErrNoRows)
+}
+
+func TestPurgeDeadLetters(t *testing.T) {
+	t.Parallel()
+	db, _ := sqltest.NewForTesting(t, sqltest.PostgresDSN, Migrations())
+	queries := internal.New(db)
+	ctx := context.Background()
+
+	first := createDLQTopic(t, queries, "purge-topic-first")
+	second := createDLQTopic(t, queries, "purge-topic-second")
+	for i := range 3 {
+		deadLetterEvent(t, queries, first.ID, fmt.Sprintf("purge-first-%d", i))
+	}
+	deadLetterEvent(t, queries, second.ID, "purge-second-0")
+
+	purged, err := queries.PurgeDeadLetters(ctx, "purge-topic-first")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), purged)
+	count, err := queries.DeadLetterCount(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	purged, err = queries.PurgeDeadLetters(ctx, "")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+	count, err = queries.DeadLetterCount(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+
+	stats, err := queries.GetEventStats(ctx, internal.Duration(5*time.Minute), first.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), stats.FailedCount)
+}
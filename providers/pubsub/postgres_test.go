@@ -0,0 +1,13 @@
+//go:build postgres
+
+package pubsub_test
+
+import (
+	"testing"
+
+	"github.com/alecthomas/zero/providers/sql/sqltest"
+)
+
+func TestPostgresSchemaRegistry(t *testing.T) {
+	testSQLSchemaRegistry(t, sqltest.PostgresDSN)
+}
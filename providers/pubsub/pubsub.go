@@ -10,8 +10,10 @@ import (
 	"time"
 
 	"github.com/alecthomas/errors"
+	"github.com/alecthomas/zero"
 	"github.com/alecthomas/zero/internal/cloudevent"
 	"github.com/alecthomas/zero/internal/strcase"
+	"github.com/alecthomas/zero/providers/tenant"
 	"go.jetify.com/typeid/v2"
 )
 
@@ -58,6 +60,43 @@ type Topic[T any] interface {
 	Close() error
 }
 
+// SubscribeOptions control how a [Topic] delivers events to a subscriber.
+//
+// They are passed to [Topic.Subscribe] in the context with [WithSubscribeOptions]. Zero values use the topic's
+// defaults.
+type SubscribeOptions struct {
+	// Concurrency is the maximum number of events delivered to the subscriber at once.
+	Concurrency int
+	// Prefetch is the number of events claimed ahead of those being processed, for topics that claim events.
+	Prefetch int
+	// Dedupe is the window within which deliveries of an event with the ID of one already handled are suppressed, for
+	// topics that may deliver an event more than once. See [Deduplicate].
+	Dedupe time.Duration
+}
+
+// WithSubscribeOptions returns a copy of ctx carrying options for [Topic.Subscribe].
+func WithSubscribeOptions(ctx context.Context, options SubscribeOptions) context.Context {
+	return zero.WithContextValue(ctx, options)
+}
+
+// SubscribeOptionsFromContext returns the options set in ctx by [WithSubscribeOptions], with unset options taken from
+// defaults.
+//
+// [Topic] implementations call this when subscribing.
+func SubscribeOptionsFromContext(ctx context.Context, defaults SubscribeOptions) SubscribeOptions {
+	options, _ := zero.ContextValue[SubscribeOptions](ctx)
+	if options.Concurrency <= 0 {
+		options.Concurrency = max(defaults.Concurrency, 1)
+	}
+	if options.Prefetch <= 0 {
+		options.Prefetch = max(defaults.Prefetch, 0)
+	}
+	if options.Dedupe <= 0 {
+		options.Dedupe = max(defaults.Dedupe, 0)
+	}
+	return options
+}
+
 // TopicName returns the name of the topic for a type.
 //
 // The name is a lower_snake_case string derived from the type name.
@@ -94,6 +133,8 @@ type Event[T any] struct {
 	id      string // If the payload implements EventPayload, the ID is taken from the payload, otherwise one will be automatically generated.
 	source  string
 	created time.Time
+	version int
+	tenant  tenant.ID
 	payload T
 }
 
@@ -123,8 +164,50 @@ func (e Event[T]) Source() string     { return e.source }
 func (e Event[T]) Created() time.Time { return e.created }
 func (e Event[T]) Payload() T         { return e.payload }
 
+// Version returns the version of the payload schema, or 0 if the event is unversioned.
+//
+// Versions are assigned by a [SchemaRegistry] when publishing through a [ValidatedTopic].
+func (e Event[T]) Version() int { return e.version }
+
+// WithVersion returns a copy of the event with the given payload schema version.
+func (e Event[T]) WithVersion(version int) Event[T] {
+	e.version = version
+	return e
+}
+
+// Tenant returns the tenant the event was published on behalf of, or "" if none.
+func (e Event[T]) Tenant() tenant.ID { return e.tenant }
+
+// WithTenant returns a copy of the event published on behalf of the given tenant.
+func (e Event[T]) WithTenant(id tenant.ID) Event[T] {
+	e.tenant = id
+	return e
+}
+
+// PropagateTenant returns a copy of the event carrying the tenant in ctx, if the event does not already have one.
+//
+// [Topic] implementations call this when publishing.
+func PropagateTenant[T any](ctx context.Context, event Event[T]) Event[T] {
+	if id, ok := tenant.FromContext(ctx); ok && event.tenant == "" {
+		event.tenant = id
+	}
+	return event
+}
+
+// TenantContext returns a copy of ctx carrying the tenant of the event, if any.
+//
+// [Topic] implementations call this when delivering events to subscribers.
+func TenantContext[T any](ctx context.Context, event Event[T]) context.Context {
+	if event.tenant == "" {
+		return ctx
+	}
+	return tenant.WithID(ctx, event.tenant)
+}
+
 func (e Event[T]) MarshalJSON() ([]byte, error) {
 	cloudEvent := cloudevent.New(e.id, e.source, e.created, e.payload)
+	cloudEvent.DataVersion = e.version
+	cloudEvent.Tenant = string(e.tenant)
 	return errors.WithStack2(json.MarshalIndent(cloudEvent, "", "  "))
 }
 
@@ -134,8 +217,11 @@ func (e *Event[T]) UnmarshalJSON(data []byte) error {
 	if err != nil {
 		return errors.Errorf("failed to unmarshal CloudEvent: %w", err)
 	}
+	e.id = ce.ID
 	e.source = ce.Source
 	e.created = ce.Time
+	e.version = ce.DataVersion
+	e.tenant = tenant.ID(ce.Tenant)
 	e.payload = ce.Data
 	return nil
 }
@@ -10,6 +10,7 @@ import (
 
 	"github.com/alecthomas/assert/v2"
 	"github.com/alecthomas/zero/providers/pubsub"
+	"github.com/alecthomas/zero/providers/tenant"
 )
 
 type User struct {
@@ -26,18 +27,37 @@ func RunPubSubTest(t *testing.T, topic pubsub.Topic[User]) { //nolint
 	})
 	var received0 atomic.Int32
 	var received1 atomic.Int32
+	var tenants atomic.Int32
+	checkTenant := func(ctx context.Context, u pubsub.Event[User]) {
+		id, ok := tenant.FromContext(ctx)
+		if u.Payload().Name == "Alice 0" {
+			assert.True(t, ok, "tenant was not propagated")
+			assert.Equal(t, tenant.ID("acme"), id)
+			assert.Equal(t, tenant.ID("acme"), u.Tenant())
+			tenants.Add(1)
+		} else {
+			assert.False(t, ok, "unexpected tenant %q", id)
+		}
+	}
 	err := topic.Subscribe(t.Context(), func(ctx context.Context, u pubsub.Event[User]) error {
+		checkTenant(ctx, u)
 		received0.Add(1)
 		return nil
 	})
 	assert.NoError(t, err)
 	err = topic.Subscribe(t.Context(), func(ctx context.Context, u pubsub.Event[User]) error {
+		checkTenant(ctx, u)
 		received1.Add(1)
 		return nil
 	})
 	assert.NoError(t, err)
 	for i := range 16 {
-		err = topic.Publish(t.Context(), pubsub.NewEvent(User{
+		ctx := t.Context()
+		if i == 0 {
+			// The tenant of the publisher is propagated to the subscriber.
+			ctx = tenant.WithID(ctx, "acme")
+		}
+		err = topic.Publish(ctx, pubsub.NewEvent(User{
 			Name: fmt.Sprintf("Alice %d", i),
 			Age:  30,
 		}))
@@ -46,6 +66,7 @@ func RunPubSubTest(t *testing.T, topic pubsub.Topic[User]) { //nolint
 
 	for range 16 {
 		if received0.Load()+received1.Load() == 16 {
+			assert.Equal(t, int32(1), tenants.Load())
 			return
 		}
 		time.Sleep(time.Second * 1)
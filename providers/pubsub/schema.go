@@ -0,0 +1,273 @@
+package pubsub
+
+import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/errors"
+)
+
+// SchemaRegistry stores versioned JSON schemas for event payloads.
+//
+// Subjects are topic names, as returned by [TopicName].
+type SchemaRegistry interface {
+	// Register a JSON schema for a subject, returning its version.
+	//
+	// Registering a schema identical to an existing version of the subject returns the existing version.
+	Register(ctx context.Context, subject string, schema []byte) (version int, err error)
+	// Schema returns the JSON schema registered for a subject at version.
+	Schema(ctx context.Context, subject string, version int) ([]byte, error)
+}
+
+// ValidatedTopic is a [Topic] that registers the JSON schema of its payload in a [SchemaRegistry], and validates
+// published events against the registered schema.
+//
+// Published events are stamped with the registered schema version, which subscribers can route on with
+// //zero:subscribe version=N.
+type ValidatedTopic[T any] struct {
+	Topic[T]
+	version int
+	schema  *JSONSchema
+}
+
+// NewValidatedTopic registers the JSON schema of T in the registry and wraps topic with validation.
+//
+//zero:provider weak
+func NewValidatedTopic[T any](ctx context.Context, topic Topic[T], registry SchemaRegistry) (*ValidatedTopic[T], error) {
+	subject := TopicName[T]()
+	data, err := json.Marshal(SchemaFor[T]())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	version, err := registry.Register(ctx, subject, data)
+	if err != nil {
+		return nil, errors.Errorf("failed to register schema for %s: %w", subject, err)
+	}
+	// Validate against the schema as registered, rather than as generated.
+	data, err = registry.Schema(ctx, subject, version)
+	if err != nil {
+		return nil, errors.Errorf("failed to retrieve schema for %s version %d: %w", subject, version, err)
+	}
+	schema := &JSONSchema{}
+	if err := json.Unmarshal(data, schema); err != nil {
+		return nil, errors.Errorf("invalid schema for %s version %d: %w", subject, version, err)
+	}
+	return &ValidatedTopic[T]{Topic: topic, version: version, schema: schema}, nil
+}
+
+// Version returns the registered schema version of the topic payload.
+func (v *ValidatedTopic[T]) Version() int { return v.version }
+
+// Publish validates the event payload against the registered schema before publishing it with the schema version.
+func (v *ValidatedTopic[T]) Publish(ctx context.Context, event Event[T]) error {
+	data, err := json.Marshal(event.Payload())
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := v.schema.Validate(value); err != nil {
+		return errors.Errorf("event %s does not match schema version %d: %w", event.ID(), v.version, err)
+	}
+	return errors.WithStack(v.Topic.Publish(ctx, event.WithVersion(v.version)))
+}
+
+// VersionRouter routes events to handlers by their payload schema version.
+//
+// The handler for version 0 receives unversioned events. Events without a handler for their version are dead-lettered.
+//
+// Zero generates a VersionRouter for each topic with //zero:subscribe version=N subscribers.
+type VersionRouter[T any] map[int]func(ctx context.Context, event Event[T]) error
+
+// Handle an event by passing it to the handler for its version.
+func (v VersionRouter[T]) Handle(ctx context.Context, event Event[T]) error {
+	handler, ok := v[event.Version()]
+	if !ok {
+		return errors.Errorf("event %s: no subscriber for version %d: %w", event.ID(), event.Version(), ErrDeadLetter)
+	}
+	return handler(ctx, event)
+}
+
+// JSONSchema is the subset of JSON Schema used to describe event payloads.
+type JSONSchema struct {
+	// Type is one or more of "null", "boolean", "integer", "number", "string", "array" or "object". Any value is
+	// valid if empty.
+	Type                 []string               `json:"type,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	AdditionalProperties *JSONSchema            `json:"additionalProperties,omitempty"`
+}
+
+// SchemaFor returns the JSON schema of T, as encoded by encoding/json.
+//
+// Struct fields are required unless tagged with omitempty.
+func SchemaFor[T any]() *JSONSchema {
+	return schemaForType(reflect.TypeFor[T](), map[reflect.Type]bool{})
+}
+
+var (
+	timeType          = reflect.TypeFor[time.Time]()
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+func schemaForType(t reflect.Type, seen map[reflect.Type]bool) *JSONSchema {
+	switch {
+	case t == timeType:
+		return &JSONSchema{Type: []string{"string"}, Format: "date-time"}
+	case t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType):
+		return &JSONSchema{}
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		return &JSONSchema{Type: []string{"string"}}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &JSONSchema{Type: []string{"boolean"}}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &JSONSchema{Type: []string{"integer"}}
+	case reflect.Float32, reflect.Float64:
+		return &JSONSchema{Type: []string{"number"}}
+	case reflect.String:
+		return &JSONSchema{Type: []string{"string"}}
+	case reflect.Pointer:
+		return nullable(schemaForType(t.Elem(), seen))
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &JSONSchema{Type: []string{"string", "null"}, Format: "byte"}
+		}
+		return nullable(&JSONSchema{Type: []string{"array"}, Items: schemaForType(t.Elem(), seen)})
+	case reflect.Array:
+		return &JSONSchema{Type: []string{"array"}, Items: schemaForType(t.Elem(), seen)}
+	case reflect.Map:
+		return nullable(&JSONSchema{Type: []string{"object"}, AdditionalProperties: schemaForType(t.Elem(), seen)})
+	case reflect.Struct:
+		// Recursive references are not described.
+		if seen[t] {
+			return &JSONSchema{}
+		}
+		seen[t] = true
+		defer delete(seen, t)
+		schema := &JSONSchema{Type: []string{"object"}, Properties: map[string]*JSONSchema{}}
+		addStructFields(schema, t, seen)
+		return schema
+	default:
+		return &JSONSchema{}
+	}
+}
+
+func addStructFields(schema *JSONSchema, t reflect.Type, seen map[reflect.Type]bool) {
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() && !field.Anonymous {
+			continue
+		}
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				addStructFields(schema, embedded, seen)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		schema.Properties[name] = schemaForType(field.Type, seen)
+		if !slices.Contains(strings.Split(options, ","), "omitempty") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+func nullable(schema *JSONSchema) *JSONSchema {
+	if len(schema.Type) > 0 && !slices.Contains(schema.Type, "null") {
+		schema.Type = append(schema.Type, "null")
+	}
+	return schema
+}
+
+// Validate a value decoded by encoding/json into an "any" against the schema.
+func (s *JSONSchema) Validate(value any) error {
+	return s.validate("$", value)
+}
+
+func (s *JSONSchema) validate(path string, value any) error {
+	if len(s.Type) > 0 && !slices.Contains(s.Type, jsonType(value)) &&
+		!(jsonType(value) == "integer" && slices.Contains(s.Type, "number")) {
+		return errors.Errorf("%s: expected %s but got %s", path, strings.Join(s.Type, " or "), jsonType(value))
+	}
+	switch value := value.(type) {
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := value[name]; !ok {
+				return errors.Errorf("%s: missing required property %q", path, name)
+			}
+		}
+		for name, property := range value {
+			schema, ok := s.Properties[name]
+			if !ok {
+				schema = s.AdditionalProperties
+			}
+			if schema == nil {
+				continue
+			}
+			if err := schema.validate(path+"."+name, property); err != nil {
+				return err
+			}
+		}
+	case []any:
+		if s.Items == nil {
+			return nil
+		}
+		for i, item := range value {
+			if err := s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func jsonType(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if value == math.Trunc(value) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
@@ -0,0 +1,100 @@
+package pubsub
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/alecthomas/errors"
+)
+
+//zero:config prefix="schema-registry-"
+type ConfluentSchemaRegistryConfig struct {
+	URL      string `help:"URL of the Confluent Schema Registry."`
+	Username string `help:"Username for basic authentication with the schema registry."`
+	Password string `help:"Password for basic authentication with the schema registry." secret:""`
+}
+
+type ConfluentSchemaRegistry struct {
+	config ConfluentSchemaRegistryConfig
+	client *http.Client
+}
+
+var _ SchemaRegistry = (*ConfluentSchemaRegistry)(nil)
+
+// NewConfluentSchemaRegistry creates a [SchemaRegistry] backed by a Confluent Schema Registry, registering schemas
+// with the JSON schema type.
+//
+//zero:provider weak
+func NewConfluentSchemaRegistry(config ConfluentSchemaRegistryConfig) (SchemaRegistry, error) {
+	if config.URL == "" {
+		return nil, errors.Errorf("--schema-registry-url is required")
+	}
+	return &ConfluentSchemaRegistry{config: config, client: http.DefaultClient}, nil
+}
+
+type confluentSchema struct {
+	SchemaType string `json:"schemaType,omitempty"`
+	Schema     string `json:"schema"`
+	Version    int    `json:"version,omitempty"`
+}
+
+func (c *ConfluentSchemaRegistry) Register(ctx context.Context, subject string, schema []byte) (int, error) {
+	request := confluentSchema{SchemaType: "JSON", Schema: string(schema)}
+	// Registration only returns the global schema ID, so the version is looked up separately.
+	if err := c.call(ctx, http.MethodPost, "/subjects/"+url.PathEscape(subject)+"/versions", request, nil); err != nil {
+		return 0, err
+	}
+	var response confluentSchema
+	if err := c.call(ctx, http.MethodPost, "/subjects/"+url.PathEscape(subject), request, &response); err != nil {
+		return 0, err
+	}
+	return response.Version, nil
+}
+
+func (c *ConfluentSchemaRegistry) Schema(ctx context.Context, subject string, version int) ([]byte, error) {
+	var response confluentSchema
+	if err := c.call(ctx, http.MethodGet, fmt.Sprintf("/subjects/%s/versions/%d", url.PathEscape(subject), version), nil, &response); err != nil {
+		return nil, err
+	}
+	return []byte(response.Schema), nil
+}
+
+func (c *ConfluentSchemaRegistry) call(ctx context.Context, method, path string, request, response any) error {
+	var body bytes.Buffer
+	if request != nil {
+		if err := json.NewEncoder(&body).Encode(request); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.config.URL, "/")+path, &body)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	req.Header.Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+	req.Header.Set("Accept", "application/vnd.schemaregistry.v1+json")
+	if c.config.Username != "" {
+		req.SetBasicAuth(c.config.Username, c.config.Password)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return errors.Errorf("schema registry request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		var apiError struct {
+			ErrorCode int    `json:"error_code"`
+			Message   string `json:"message"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&apiError)
+		return errors.Errorf("schema registry %s %s failed: %s: %s", method, path, resp.Status, apiError.Message)
+	}
+	if response == nil {
+		return nil
+	}
+	return errors.WithStack(json.NewDecoder(resp.Body).Decode(response))
+}
@@ -0,0 +1,47 @@
+package pubsub
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/alecthomas/errors"
+)
+
+type MemorySchemaRegistry struct {
+	lock     sync.Mutex
+	subjects map[string][][]byte
+}
+
+var _ SchemaRegistry = (*MemorySchemaRegistry)(nil)
+
+// NewMemorySchemaRegistry creates a [SchemaRegistry] that stores schemas in an in-memory map.
+//
+// Schemas are not shared between replicas of a service, nor do they survive restarts.
+//
+//zero:provider weak
+func NewMemorySchemaRegistry() SchemaRegistry {
+	return &MemorySchemaRegistry{subjects: map[string][][]byte{}}
+}
+
+func (m *MemorySchemaRegistry) Register(ctx context.Context, subject string, schema []byte) (int, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	versions := m.subjects[subject]
+	if index := slices.IndexFunc(versions, func(existing []byte) bool { return bytes.Equal(existing, schema) }); index >= 0 {
+		return index + 1, nil
+	}
+	m.subjects[subject] = append(versions, bytes.Clone(schema))
+	return len(versions) + 1, nil
+}
+
+func (m *MemorySchemaRegistry) Schema(ctx context.Context, subject string, version int) ([]byte, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	versions := m.subjects[subject]
+	if version < 1 || version > len(versions) {
+		return nil, errors.Errorf("%s: unknown schema version %d", subject, version)
+	}
+	return versions[version-1], nil
+}
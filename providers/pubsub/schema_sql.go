@@ -0,0 +1,73 @@
+package pubsub
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/alecthomas/errors"
+	zerosql "github.com/alecthomas/zero/providers/sql"
+)
+
+type SQLSchemaRegistry struct {
+	db     *sql.DB
+	q      func(string) string
+	driver zerosql.Driver
+}
+
+var _ SchemaRegistry = (*SQLSchemaRegistry)(nil)
+
+// NewSQLSchemaRegistry creates a [SchemaRegistry] backed by the pubsub_schemas table of an SQL database.
+//
+//zero:provider weak require="github.com/alecthomas/zero/providers/pubsub/migrations.Migrations"
+func NewSQLSchemaRegistry(driver zerosql.Driver, db *sql.DB) SchemaRegistry {
+	return &SQLSchemaRegistry{db: db, q: driver.Denormalise, driver: driver}
+}
+
+func (s *SQLSchemaRegistry) Register(ctx context.Context, subject string, schema []byte) (int, error) {
+	// Retry if a concurrent registration claims the next version first.
+	for range 3 {
+		var version int
+		err := s.db.QueryRowContext(ctx, s.q(`
+			SELECT version FROM pubsub_schemas
+			WHERE subject = ? AND definition = ?
+		`), subject, string(schema)).Scan(&version)
+		if err == nil {
+			return version, nil
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return 0, errors.WithStack(s.driver.TranslateError(err))
+		}
+
+		err = s.db.QueryRowContext(ctx, s.q(`
+			SELECT COALESCE(MAX(version), 0) FROM pubsub_schemas WHERE subject = ?
+		`), subject).Scan(&version)
+		if err != nil {
+			return 0, errors.WithStack(s.driver.TranslateError(err))
+		}
+		version++
+		_, err = s.db.ExecContext(ctx, s.q(`
+			INSERT INTO pubsub_schemas (subject, version, definition)
+			VALUES (?, ?, ?)
+		`), subject, version, string(schema))
+		if err == nil {
+			return version, nil
+		}
+		if err = s.driver.TranslateError(err); !errors.Is(err, zerosql.ErrConstraint) {
+			return 0, errors.WithStack(err)
+		}
+	}
+	return 0, errors.Errorf("%s: failed to register schema due to concurrent registrations", subject)
+}
+
+func (s *SQLSchemaRegistry) Schema(ctx context.Context, subject string, version int) ([]byte, error) {
+	var schema string
+	err := s.db.QueryRowContext(ctx, s.q(`
+		SELECT definition FROM pubsub_schemas
+		WHERE subject = ? AND version = ?
+	`), subject, version).Scan(&schema)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.Errorf("%s: unknown schema version %d", subject, version)
+	} else if err != nil {
+		return nil, errors.WithStack(s.driver.TranslateError(err))
+	}
+	return []byte(schema), nil
+}
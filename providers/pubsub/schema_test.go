@@ -0,0 +1,232 @@
+package pubsub_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/alecthomas/zero/providers/pubsub"
+	"github.com/alecthomas/zero/providers/pubsub/migrations"
+	"github.com/alecthomas/zero/providers/pubsub/pubsubtest"
+	"github.com/alecthomas/zero/providers/sql/sqltest"
+)
+
+type Address struct {
+	Street string `json:"street"`
+}
+
+type Account struct {
+	ID       int64             `json:"id"`
+	Balance  float64           `json:"balance"`
+	Tags     []string          `json:"tags,omitempty"`
+	Address  *Address          `json:"address"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Created  time.Time         `json:"created"`
+	Parent   *Account          `json:"parent,omitempty"`
+	internal string
+}
+
+func TestSchemaFor(t *testing.T) {
+	data, err := json.Marshal(pubsub.SchemaFor[Account]())
+	assert.NoError(t, err)
+	assert.Equal(t, `{"type":["object"],"properties":{"address":{"type":["object","null"],"properties":{"street":{"type":["string"]}},"required":["street"]},"balance":{"type":["number"]},"created":{"type":["string"],"format":"date-time"},"id":{"type":["integer"]},"metadata":{"type":["object","null"],"additionalProperties":{"type":["string"]}},"parent":{},"tags":{"type":["array","null"],"items":{"type":["string"]}}},"required":["id","balance","address","created"]}`, string(data))
+}
+
+func TestSchemaValidate(t *testing.T) {
+	schema := pubsub.SchemaFor[Account]()
+	tests := []struct {
+		name string
+		json string
+		err  string
+	}{
+		{"Valid", `{"id": 1, "balance": 1.5, "address": null, "created": "2000-01-01T00:00:00Z", "tags": ["a"]}`, ""},
+		{"IntegerAsNumber", `{"id": 1, "balance": 1, "address": {"street": "Main"}, "created": "2000-01-01T00:00:00Z"}`, ""},
+		{"MissingRequired", `{"id": 1, "balance": 1.5, "address": null}`, `$: missing required property "created"`},
+		{"WrongType", `{"id": 1.5, "balance": 1.5, "address": null, "created": ""}`, `$.id: expected integer but got number`},
+		{"Nested", `{"id": 1, "balance": 1, "address": {}, "created": ""}`, `$.address: missing required property "street"`},
+		{"Items", `{"id": 1, "balance": 1, "address": null, "created": "", "tags": ["a", 2]}`, `$.tags[1]: expected string but got integer`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var value any
+			assert.NoError(t, json.Unmarshal([]byte(test.json), &value))
+			err := schema.Validate(value)
+			if test.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, test.err)
+			}
+		})
+	}
+}
+
+type recordingTopic[T any] struct {
+	lock      sync.Mutex
+	published []pubsub.Event[T]
+}
+
+func (r *recordingTopic[T]) Publish(ctx context.Context, event pubsub.Event[T]) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.published = append(r.published, event)
+	return nil
+}
+
+func (r *recordingTopic[T]) Subscribe(ctx context.Context, handler func(context.Context, pubsub.Event[T]) error) error {
+	return nil
+}
+
+func (r *recordingTopic[T]) Close() error { return nil }
+
+func TestValidatedTopic(t *testing.T) {
+	registry := pubsub.NewMemorySchemaRegistry()
+	_, err := registry.Register(t.Context(), "user", []byte(`{"type":["object"],"required":["name"]}`))
+	assert.NoError(t, err)
+
+	topic := &recordingTopic[pubsubtest.User]{}
+	validated, err := pubsub.NewValidatedTopic(t.Context(), pubsub.Topic[pubsubtest.User](topic), registry)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, validated.Version())
+
+	err = validated.Publish(t.Context(), pubsub.NewEvent(pubsubtest.User{Name: "Bob", Age: 30}))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(topic.published))
+	assert.Equal(t, 2, topic.published[0].Version())
+
+	data, err := json.Marshal(topic.published[0])
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"dataversion":2`)
+	var decoded pubsub.Event[pubsubtest.User]
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, 2, decoded.Version())
+
+	// Registering the same schema again reuses the version.
+	validated, err = pubsub.NewValidatedTopic(t.Context(), pubsub.Topic[pubsubtest.User](topic), registry)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, validated.Version())
+}
+
+func TestValidatedTopicRejectsInvalidPayload(t *testing.T) {
+	registry := pubsub.NewMemorySchemaRegistry()
+	// Simulate a registered schema that has diverged from the payload type.
+	_, err := registry.Register(t.Context(), "account", []byte(`{"type":["object"],"properties":{"id":{"type":["string"]}}}`))
+	assert.NoError(t, err)
+	topic := &recordingTopic[Account]{}
+	validated, err := pubsub.NewValidatedTopic(t.Context(), pubsub.Topic[Account](topic), &pinnedRegistry{SchemaRegistry: registry, version: 1})
+	assert.NoError(t, err)
+	err = validated.Publish(t.Context(), pubsub.NewEvent(Account{ID: 1}))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "does not match schema version 1: $.id: expected string but got integer")
+	assert.Equal(t, 0, len(topic.published))
+}
+
+// pinnedRegistry always registers schemas as a fixed version.
+type pinnedRegistry struct {
+	pubsub.SchemaRegistry
+	version int
+}
+
+func (p *pinnedRegistry) Register(ctx context.Context, subject string, schema []byte) (int, error) {
+	return p.version, nil
+}
+
+func TestVersionRouter(t *testing.T) {
+	var received []string
+	router := pubsub.VersionRouter[pubsubtest.User]{
+		0: func(ctx context.Context, event pubsub.Event[pubsubtest.User]) error {
+			received = append(received, "unversioned")
+			return nil
+		},
+		2: func(ctx context.Context, event pubsub.Event[pubsubtest.User]) error {
+			received = append(received, "v2")
+			return nil
+		},
+	}
+	event := pubsub.NewEvent(pubsubtest.User{Name: "Bob"})
+	assert.NoError(t, router.Handle(t.Context(), event))
+	assert.NoError(t, router.Handle(t.Context(), event.WithVersion(2)))
+	err := router.Handle(t.Context(), event.WithVersion(3))
+	assert.IsError(t, err, pubsub.ErrDeadLetter)
+	assert.Equal(t, []string{"unversioned", "v2"}, received)
+}
+
+func testSchemaRegistry(t *testing.T, registry pubsub.SchemaRegistry) {
+	t.Helper()
+	version, err := registry.Register(t.Context(), "user", []byte(`{"type":["object"]}`))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, version)
+	version, err = registry.Register(t.Context(), "user", []byte(`{"type":["object"],"required":["name"]}`))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, version)
+	version, err = registry.Register(t.Context(), "user", []byte(`{"type":["object"]}`))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, version)
+	version, err = registry.Register(t.Context(), "account", []byte(`{"type":["object"]}`))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, version)
+
+	schema, err := registry.Schema(t.Context(), "user", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"type":["object"],"required":["name"]}`, string(schema))
+	_, err = registry.Schema(t.Context(), "user", 3)
+	assert.Error(t, err)
+}
+
+func TestMemorySchemaRegistry(t *testing.T) {
+	testSchemaRegistry(t, pubsub.NewMemorySchemaRegistry())
+}
+
+func testSQLSchemaRegistry(t *testing.T, dsn string) { //nolint
+	db, driver := sqltest.NewForTesting(t, dsn, migrations.Migrations())
+	testSchemaRegistry(t, pubsub.NewSQLSchemaRegistry(driver, db))
+}
+
+// fakeConfluent implements the subset of the Confluent Schema Registry API used by ConfluentSchemaRegistry.
+func fakeConfluent(t *testing.T) *httptest.Server {
+	t.Helper()
+	registry := pubsub.NewMemorySchemaRegistry()
+	mux := http.NewServeMux()
+	register := func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			SchemaType string `json:"schemaType"`
+			Schema     string `json:"schema"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		assert.Equal(t, "JSON", request.SchemaType)
+		version, err := registry.Register(r.Context(), r.PathValue("subject"), []byte(request.Schema))
+		assert.NoError(t, err)
+		_ = json.NewEncoder(w).Encode(map[string]any{"id": 100 + version, "version": version})
+	}
+	mux.HandleFunc("POST /subjects/{subject}/versions", register)
+	mux.HandleFunc("POST /subjects/{subject}", register)
+	mux.HandleFunc("GET /subjects/{subject}/versions/{version}", func(w http.ResponseWriter, r *http.Request) {
+		var version int
+		assert.NoError(t, json.Unmarshal([]byte(r.PathValue("version")), &version))
+		schema, err := registry.Schema(r.Context(), r.PathValue("subject"), version)
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(map[string]any{"error_code": 40402, "message": "Version not found."})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"schema": string(schema), "version": version})
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestConfluentSchemaRegistry(t *testing.T) {
+	server := fakeConfluent(t)
+	registry, err := pubsub.NewConfluentSchemaRegistry(pubsub.ConfluentSchemaRegistryConfig{URL: server.URL + "/"})
+	assert.NoError(t, err)
+	testSchemaRegistry(t, registry)
+
+	_, err = registry.Schema(t.Context(), "user", 3)
+	assert.True(t, strings.Contains(err.Error(), "404 Not Found: Version not found."), "%s", err)
+}
@@ -0,0 +1,9 @@
+//go:build sqlite
+
+package pubsub_test
+
+import "testing"
+
+func TestSQLiteSchemaRegistry(t *testing.T) {
+	testSQLSchemaRegistry(t, "sqlite://file:discard?mode=memory&cache=shared")
+}
@@ -0,0 +1,146 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/errors"
+)
+
+// ErrShuttingDown is returned by subscribers tracked by [Subscriptions] for events delivered once shutdown has begun,
+// so that the topic redelivers them later.
+var ErrShuttingDown = errors.New("subscriptions are shutting down")
+
+//zero:config prefix="subscriptions-"
+type SubscriptionsConfig struct {
+	GracePeriod time.Duration `default:"30s" help:"Time to wait on shutdown for subscribers to finish processing in-flight events, before their contexts are cancelled."`
+}
+
+// Subscriptions tracks the subscribers registered by the generated RegisterSubscribers, so that in-flight events can
+// be drained on shutdown, and subscriber failures stop the service rather than going unnoticed.
+type Subscriptions struct {
+	logger      *slog.Logger
+	gracePeriod time.Duration
+	// abort is cancelled once the grace period has elapsed, cancelling the contexts of in-flight subscribers.
+	abort       context.Context
+	cancelAbort context.CancelFunc
+	failures    chan error
+
+	lock     sync.Mutex
+	closing  bool
+	inflight int
+	drained  chan struct{} // Closed once closing and no events are in flight
+}
+
+// NewSubscriptions creates a new [Subscriptions].
+//
+//zero:provider weak
+func NewSubscriptions(config SubscriptionsConfig, logger *slog.Logger) *Subscriptions {
+	abort, cancelAbort := context.WithCancel(context.Background())
+	return &Subscriptions{
+		logger:      logger,
+		gracePeriod: config.GracePeriod,
+		abort:       abort,
+		cancelAbort: cancelAbort,
+		failures:    make(chan error, 1),
+		drained:     make(chan struct{}),
+	}
+}
+
+// Track wraps a subscriber so that its in-flight events are drained on shutdown.
+//
+// The subscriber's context is not cancelled when the topic's is, but only once the grace period has elapsed after
+// shutdown begins. Events delivered after shutdown has begun are rejected with [ErrShuttingDown], and a panicking
+// subscriber fails the event and is reported by [Subscriptions.Run].
+func Track[T any](s *Subscriptions, name string, handler func(context.Context, Event[T]) error) func(context.Context, Event[T]) error {
+	return func(ctx context.Context, event Event[T]) error {
+		return s.track(ctx, name, "event "+event.ID(), func(ctx context.Context) error { return handler(ctx, event) })
+	}
+}
+
+// TrackBatch is [Track] for batch subscribers, tracking each batch as a single in-flight event.
+func TrackBatch[T any](s *Subscriptions, name string, handler func(context.Context, []Event[T]) error) func(context.Context, []Event[T]) error {
+	return func(ctx context.Context, events []Event[T]) error {
+		description := fmt.Sprintf("batch of %d events", len(events))
+		return s.track(ctx, name, description, func(ctx context.Context) error { return handler(ctx, events) })
+	}
+}
+
+func (s *Subscriptions) track(ctx context.Context, name, description string, handler func(context.Context) error) (err error) {
+	if !s.begin() {
+		return errors.Errorf("%s: %s: %w", name, description, ErrShuttingDown)
+	}
+	defer s.end()
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancel()
+	stop := context.AfterFunc(s.abort, cancel)
+	defer stop()
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = errors.Errorf("subscriber %s panicked on %s: %v", name, description, recovered)
+			s.fail(err)
+		}
+	}()
+	return handler(ctx)
+}
+
+// Run blocks until ctx is cancelled or a subscriber fails, then drains in-flight events.
+//
+// Subscribers still running once the grace period has elapsed have their contexts cancelled, and are not waited for.
+// Returns the first subscriber failure, if any.
+func (s *Subscriptions) Run(ctx context.Context) error {
+	var failure error
+	select {
+	case <-ctx.Done():
+	case failure = <-s.failures:
+	}
+	s.lock.Lock()
+	s.closing = true
+	inflight := s.inflight
+	if inflight == 0 {
+		close(s.drained)
+	}
+	s.lock.Unlock()
+	if inflight > 0 {
+		s.logger.Info("Draining in-flight events", "events", inflight, "grace_period", s.gracePeriod)
+	}
+	select {
+	case <-s.drained:
+	case <-time.After(s.gracePeriod):
+		s.lock.Lock()
+		inflight = s.inflight
+		s.lock.Unlock()
+		s.logger.Warn("Grace period elapsed, cancelling in-flight events", "events", inflight)
+	}
+	s.cancelAbort()
+	return failure
+}
+
+func (s *Subscriptions) begin() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closing {
+		return false
+	}
+	s.inflight++
+	return true
+}
+
+func (s *Subscriptions) end() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.inflight--
+	if s.closing && s.inflight == 0 {
+		close(s.drained)
+	}
+}
+
+func (s *Subscriptions) fail(err error) {
+	select {
+	case s.failures <- err:
+	default:
+	}
+}
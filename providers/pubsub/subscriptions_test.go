@@ -0,0 +1,82 @@
+package pubsub_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/alecthomas/zero/providers/pubsub"
+)
+
+func TestSubscriptionsDrain(t *testing.T) {
+	t.Parallel()
+	subscriptions := pubsub.NewSubscriptions(pubsub.SubscriptionsConfig{GracePeriod: time.Minute}, slog.New(slog.DiscardHandler))
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := pubsub.Track(subscriptions, "Service.OnEvent", func(ctx context.Context, event pubsub.Event[string]) error {
+		if event.Payload() == "in-flight" {
+			close(started)
+			<-release
+		}
+		return ctx.Err()
+	})
+
+	topicCtx, cancelTopic := context.WithCancel(t.Context())
+	handled := make(chan error, 1)
+	go func() { handled <- handler(topicCtx, pubsub.NewEvent("in-flight")) }()
+	<-started
+
+	runCtx, stop := context.WithCancel(t.Context())
+	done := make(chan error, 1)
+	go func() { done <- subscriptions.Run(runCtx) }()
+	stop()
+	cancelTopic()
+
+	// Events delivered once shutdown has begun are rejected for redelivery.
+	for !errors.Is(handler(t.Context(), pubsub.NewEvent("late")), pubsub.ErrShuttingDown) {
+		time.Sleep(time.Millisecond)
+	}
+
+	select {
+	case <-done:
+		t.Fatal("Run returned before in-flight events were drained")
+	default:
+	}
+	close(release)
+	assert.NoError(t, <-handled, "subscriber context should outlive the topic context")
+	assert.NoError(t, <-done)
+}
+
+func TestSubscriptionsGracePeriod(t *testing.T) {
+	t.Parallel()
+	subscriptions := pubsub.NewSubscriptions(pubsub.SubscriptionsConfig{GracePeriod: 10 * time.Millisecond}, slog.New(slog.DiscardHandler))
+	started := make(chan struct{})
+	handler := pubsub.Track(subscriptions, "Service.OnEvent", func(ctx context.Context, event pubsub.Event[string]) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	handled := make(chan error, 1)
+	go func() { handled <- handler(t.Context(), pubsub.NewEvent("stuck")) }()
+	<-started
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	assert.NoError(t, subscriptions.Run(ctx))
+	assert.IsError(t, <-handled, context.Canceled)
+}
+
+func TestSubscriptionsPanic(t *testing.T) {
+	t.Parallel()
+	subscriptions := pubsub.NewSubscriptions(pubsub.SubscriptionsConfig{GracePeriod: time.Second}, slog.New(slog.DiscardHandler))
+	handler := pubsub.Track(subscriptions, "Service.OnEvent", func(ctx context.Context, event pubsub.Event[string]) error {
+		panic("boom")
+	})
+	event := pubsub.NewEvent("event")
+	err := handler(t.Context(), event)
+	assert.EqualError(t, err, "subscriber Service.OnEvent panicked on event "+event.ID()+": boom")
+	assert.EqualError(t, subscriptions.Run(t.Context()), err.Error())
+}
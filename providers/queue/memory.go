@@ -0,0 +1,120 @@
+package queue
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/errors"
+)
+
+type MemoryStore struct {
+	lock sync.Mutex
+	jobs map[string][]Message
+	dead map[string][]Message
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore creates a [Store] that holds jobs in memory.
+//
+// Jobs are not shared between replicas of a service, and are lost on restart.
+//
+//zero:provider weak
+func NewMemoryStore() Store {
+	return &MemoryStore{jobs: map[string][]Message{}, dead: map[string][]Message{}}
+}
+
+func (m *MemoryStore) Enqueue(ctx context.Context, message Message) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.jobs[message.Queue] = append(m.jobs[message.Queue], message)
+	return nil
+}
+
+func (m *MemoryStore) Claim(ctx context.Context, queue string, visibility time.Duration) (Message, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	now := time.Now().UTC()
+	jobs := m.jobs[queue]
+	next := -1
+	for i, job := range jobs {
+		if !job.Visible.After(now) && (next == -1 || job.Visible.Before(jobs[next].Visible)) {
+			next = i
+		}
+	}
+	if next == -1 {
+		return Message{}, errors.Errorf("%s: %w", queue, ErrEmpty)
+	}
+	job := &jobs[next]
+	job.Attempt++
+	job.Visible = now.Add(visibility)
+	job.Claim = newClaim()
+	return *job, nil
+}
+
+func (m *MemoryStore) Complete(ctx context.Context, message Message) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	_, err := m.removeNoLock(message)
+	return err
+}
+
+func (m *MemoryStore) Retry(ctx context.Context, message Message, visible time.Time, reason string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	index, err := m.findNoLock(message)
+	if err != nil {
+		return err
+	}
+	job := &m.jobs[message.Queue][index]
+	job.Visible = visible
+	job.Claim = ""
+	job.Error = reason
+	return nil
+}
+
+func (m *MemoryStore) Fail(ctx context.Context, message Message, reason string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	job, err := m.removeNoLock(message)
+	if err != nil {
+		return err
+	}
+	job.Claim = ""
+	job.Error = reason
+	job.Failed = time.Now().UTC()
+	m.dead[message.Queue] = append(m.dead[message.Queue], job)
+	return nil
+}
+
+func (m *MemoryStore) Dead(ctx context.Context, queue string, limit int) ([]Message, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	dead := m.dead[queue]
+	if limit > 0 && len(dead) > limit {
+		dead = dead[len(dead)-limit:]
+	}
+	out := slices.Clone(dead)
+	slices.Reverse(out)
+	return out, nil
+}
+
+func (m *MemoryStore) findNoLock(message Message) (int, error) {
+	index := slices.IndexFunc(m.jobs[message.Queue], func(job Message) bool { return job.ID == message.ID })
+	if index == -1 || m.jobs[message.Queue][index].Claim != message.Claim {
+		return 0, errors.Errorf("job %s: %w", message.ID, ErrClaimLost)
+	}
+	return index, nil
+}
+
+func (m *MemoryStore) removeNoLock(message Message) (Message, error) {
+	index, err := m.findNoLock(message)
+	if err != nil {
+		return Message{}, err
+	}
+	job := m.jobs[message.Queue][index]
+	m.jobs[message.Queue] = slices.Delete(m.jobs[message.Queue], index, index+1)
+	return job, nil
+}
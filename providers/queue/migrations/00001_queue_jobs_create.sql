@@ -0,0 +1,22 @@
+CREATE TABLE queue_jobs (
+    id VARCHAR(255) NOT NULL PRIMARY KEY,
+    queue VARCHAR(255) NOT NULL,
+    payload TEXT NOT NULL,
+    attempt INTEGER NOT NULL,
+    enqueued TIMESTAMP NOT NULL,
+    visible TIMESTAMP NOT NULL,
+    claim VARCHAR(255) NOT NULL,
+    error TEXT NOT NULL
+);
+CREATE INDEX queue_jobs_visible ON queue_jobs (queue, visible);
+
+CREATE TABLE queue_dead_jobs (
+    id VARCHAR(255) NOT NULL PRIMARY KEY,
+    queue VARCHAR(255) NOT NULL,
+    payload TEXT NOT NULL,
+    attempt INTEGER NOT NULL,
+    enqueued TIMESTAMP NOT NULL,
+    failed TIMESTAMP NOT NULL,
+    error TEXT NOT NULL
+);
+CREATE INDEX queue_dead_jobs_failed ON queue_dead_jobs (queue, failed);
@@ -0,0 +1,276 @@
+// Package queue provides durable queues of background jobs, processed by workers with retries.
+//
+// Unlike pubsub topics, where every subscriber receives every event, each job in a queue is processed by a single
+// worker. A claimed job is hidden from other workers for a visibility timeout, after which it is redelivered if the
+// worker has not completed it, eg. because it crashed. Failed jobs are retried with exponential backoff, and once out
+// of attempts are moved to the queue's dead jobs.
+//
+// Jobs may be processed more than once, so workers must be idempotent.
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/errors"
+	"github.com/alecthomas/zero/internal"
+	"github.com/alecthomas/zero/internal/strcase"
+	"go.jetify.com/typeid/v2"
+)
+
+const (
+	// DefaultVisibility is the [WorkerOptions.Visibility] used if none is set.
+	DefaultVisibility = 30 * time.Second
+	// DefaultMaxAttempts is the [RetryPolicy.MaxAttempts] used if none is set.
+	DefaultMaxAttempts = 5
+	// DefaultBackoff is the [RetryPolicy.Backoff] used if none is set.
+	DefaultBackoff = time.Second
+	// DefaultMaxBackoff is the [RetryPolicy.MaxBackoff] used if none is set.
+	DefaultMaxBackoff = 5 * time.Minute
+	// DefaultPollInterval is the [WorkerOptions.PollInterval] used if none is set.
+	DefaultPollInterval = time.Second
+)
+
+// ErrEmpty is returned by [Store.Claim] when no jobs are visible in the queue.
+var ErrEmpty = errors.New("queue is empty")
+
+// ErrClaimLost is returned by [Store] when a job is settled by a worker whose claim on it has expired, and which may
+// since have been claimed by another worker.
+var ErrClaimLost = errors.New("claim on job was lost")
+
+// ErrPermanent can be wrapped by errors returned from workers to move the job to the dead jobs without retrying it.
+var ErrPermanent = errors.New("permanent job failure")
+
+// Job is a job in a queue, with a payload of type T.
+type Job[T any] struct {
+	ID      string
+	Payload T
+	// Attempt is the number of times the job has been claimed, including this one, starting at 1.
+	Attempt  int
+	Enqueued time.Time
+}
+
+// Message is a job as persisted in a [Store], with its payload JSON encoded.
+type Message struct {
+	ID      string          `json:"id"`
+	Queue   string          `json:"queue"`
+	Payload json.RawMessage `json:"payload"`
+	// Attempt is the number of times the job has been claimed.
+	Attempt  int       `json:"attempt"`
+	Enqueued time.Time `json:"enqueued"`
+	// Visible is the time at which the job can next be claimed.
+	Visible time.Time `json:"visible"`
+	// Claim is a token identifying the most recent claim of the job, used to detect claims that have expired.
+	Claim string `json:"claim,omitempty"`
+	// Error is the error of the most recent failed attempt, if any.
+	Error string `json:"error,omitempty"`
+	// Failed is the time at which the job was moved to the dead jobs, if it was.
+	Failed time.Time `json:"failed,omitzero"`
+}
+
+// Store persists the jobs of all queues.
+type Store interface {
+	// Enqueue a job.
+	Enqueue(ctx context.Context, message Message) error
+	// Claim the earliest visible job in a queue, hiding it until the visibility timeout elapses and incrementing its
+	// attempts.
+	//
+	// Returns [ErrEmpty] if no jobs are visible.
+	Claim(ctx context.Context, queue string, visibility time.Duration) (Message, error)
+	// Complete a claimed job, deleting it.
+	Complete(ctx context.Context, message Message) error
+	// Retry a claimed job, making it visible again at the given time.
+	Retry(ctx context.Context, message Message, visible time.Time, reason string) error
+	// Fail a claimed job, moving it to the dead jobs of its queue.
+	Fail(ctx context.Context, message Message, reason string) error
+	// Dead returns up to limit of the most recently failed jobs of a queue, newest first.
+	Dead(ctx context.Context, queue string, limit int) ([]Message, error)
+}
+
+// RetryPolicy controls how failed jobs are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the number of times a job is attempted before it is moved to the dead jobs.
+	MaxAttempts int
+	// Backoff is the delay before the first retry of a job, doubling with each subsequent attempt.
+	Backoff time.Duration
+	// MaxBackoff caps the delay between retries.
+	MaxBackoff time.Duration
+}
+
+// Delay returns the delay before retrying a job after the given attempt failed.
+func (r RetryPolicy) Delay(attempt int) time.Duration {
+	delay := r.Backoff
+	for range max(attempt-1, 0) {
+		delay *= 2
+		if delay >= r.MaxBackoff {
+			return r.MaxBackoff
+		}
+	}
+	return min(delay, r.MaxBackoff)
+}
+
+// WorkerOptions control how a queue's jobs are processed by a worker.
+type WorkerOptions struct {
+	// Concurrency is the maximum number of jobs processed at once.
+	Concurrency int
+	// Visibility is how long a claimed job is hidden from other workers. The worker's context is cancelled once it
+	// elapses, as the job may be redelivered.
+	Visibility time.Duration
+	// PollInterval is how often an idle worker checks for new jobs.
+	PollInterval time.Duration
+	Retry        RetryPolicy
+}
+
+func (w WorkerOptions) withDefaults() WorkerOptions {
+	w.Concurrency = max(w.Concurrency, 1)
+	if w.Visibility <= 0 {
+		w.Visibility = DefaultVisibility
+	}
+	if w.PollInterval <= 0 {
+		w.PollInterval = DefaultPollInterval
+	}
+	if w.Retry.MaxAttempts <= 0 {
+		w.Retry.MaxAttempts = DefaultMaxAttempts
+	}
+	if w.Retry.Backoff <= 0 {
+		w.Retry.Backoff = DefaultBackoff
+	}
+	if w.Retry.MaxBackoff <= 0 {
+		w.Retry.MaxBackoff = DefaultMaxBackoff
+	}
+	return w
+}
+
+// Name returns the name of the queue of jobs with payloads of type T, eg. SendEmail -> send_email.
+func Name[T any]() string {
+	t := reflect.TypeFor[T]()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return strings.ReplaceAll(strings.ToLower(strings.Join(strcase.Split(t.Name()), "_")), "__", "_")
+}
+
+// Queue of jobs with payloads of type T.
+//
+// Queues are identified by their payload type, and share a single [Store].
+type Queue[T any] struct {
+	name   string
+	store  Store
+	logger *slog.Logger
+}
+
+// NewQueue creates a new [Queue] for jobs of type T.
+//
+//zero:provider
+func NewQueue[T any](logger *slog.Logger, store Store) *Queue[T] {
+	name := Name[T]()
+	return &Queue[T]{name: name, store: store, logger: logger.With("queue", name)}
+}
+
+// Name of the queue.
+func (q *Queue[T]) Name() string { return q.name }
+
+// EnqueueOption configures a job being enqueued.
+type EnqueueOption func(*Message)
+
+// Delay the job, so that it is not processed until the delay has elapsed.
+func Delay(delay time.Duration) EnqueueOption {
+	return func(m *Message) { m.Visible = m.Visible.Add(delay) }
+}
+
+// Enqueue a job with the given payload, returning its ID.
+func Enqueue[T any](ctx context.Context, q *Queue[T], payload T, options ...EnqueueOption) (string, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", errors.Errorf("queue %s: failed to marshal job: %w", q.name, err)
+	}
+	id, err := typeid.Generate("job")
+	if err != nil {
+		return "", errors.Errorf("queue %s: failed to generate job ID: %w", q.name, err)
+	}
+	now := time.Now().UTC()
+	message := Message{
+		ID:       id.String(),
+		Queue:    q.name,
+		Payload:  data,
+		Enqueued: now,
+		Visible:  now,
+	}
+	for _, option := range options {
+		option(&message)
+	}
+	if err := q.store.Enqueue(ctx, message); err != nil {
+		return "", errors.Errorf("queue %s: failed to enqueue job: %w", q.name, err)
+	}
+	return message.ID, nil
+}
+
+// newClaim returns a unique token identifying a claim of a job.
+func newClaim() string { return typeid.MustGenerate("claim").String() }
+
+// Work starts processing jobs from the queue with handler, until ctx is cancelled.
+//
+// A job is completed if the handler returns nil. Otherwise it is retried according to the [RetryPolicy], unless it is
+// out of attempts or the error wraps [ErrPermanent], in which case it is moved to the dead jobs.
+func (q *Queue[T]) Work(ctx context.Context, options WorkerOptions, handler func(ctx context.Context, job Job[T]) error) error {
+	options = options.withDefaults()
+	for range options.Concurrency {
+		go q.work(ctx, options, handler)
+	}
+	return nil
+}
+
+func (q *Queue[T]) work(ctx context.Context, options WorkerOptions, handler func(ctx context.Context, job Job[T]) error) {
+	for {
+		message, err := q.store.Claim(ctx, q.name, options.Visibility)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if !errors.Is(err, ErrEmpty) {
+				q.logger.Error("Failed to claim job", "error", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(internal.Jitter(options.PollInterval)):
+			}
+			continue
+		}
+		q.process(ctx, options, message, handler)
+	}
+}
+
+func (q *Queue[T]) process(ctx context.Context, options WorkerOptions, message Message, handler func(ctx context.Context, job Job[T]) error) {
+	// Jobs are settled even if the worker is shutting down, so that they are retried promptly.
+	settleCtx := context.WithoutCancel(ctx)
+	job := Job[T]{ID: message.ID, Attempt: message.Attempt, Enqueued: message.Enqueued}
+	err := json.Unmarshal(message.Payload, &job.Payload)
+	if err != nil {
+		err = errors.Errorf("failed to unmarshal job: %w: %w", err, ErrPermanent)
+	} else {
+		jobCtx, cancel := context.WithTimeout(ctx, options.Visibility)
+		err = handler(jobCtx, job)
+		cancel()
+	}
+	switch {
+	case err == nil:
+		err = q.store.Complete(settleCtx, message)
+
+	case errors.Is(err, ErrPermanent) || message.Attempt >= options.Retry.MaxAttempts:
+		q.logger.Error("Job failed, moving to dead jobs", "job", message.ID, "attempt", message.Attempt, "error", err)
+		err = q.store.Fail(settleCtx, message, err.Error())
+
+	default:
+		delay := options.Retry.Delay(message.Attempt)
+		q.logger.Warn("Job failed, retrying", "job", message.ID, "attempt", message.Attempt, "delay", delay, "error", err)
+		err = q.store.Retry(settleCtx, message, time.Now().UTC().Add(delay), err.Error())
+	}
+	if err != nil {
+		q.logger.Error("Failed to settle job", "job", message.ID, "error", err)
+	}
+}
@@ -0,0 +1,268 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/alecthomas/errors"
+	"github.com/alecthomas/zero/providers/logging/loggingtest"
+)
+
+type SendEmail struct {
+	To string `json:"to"`
+}
+
+func TestName(t *testing.T) {
+	assert.Equal(t, "send_email", Name[SendEmail]())
+	assert.Equal(t, "send_email", Name[*SendEmail]())
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 10, Backoff: time.Second, MaxBackoff: time.Second * 10}
+	assert.Equal(t, time.Second, policy.Delay(1))
+	assert.Equal(t, time.Second*2, policy.Delay(2))
+	assert.Equal(t, time.Second*8, policy.Delay(4))
+	assert.Equal(t, time.Second*10, policy.Delay(5))
+	assert.Equal(t, time.Second*10, policy.Delay(50))
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore())
+}
+
+func testStore(t *testing.T, store Store) {
+	t.Helper()
+	ctx := t.Context()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	newMessage := func(id string, visible time.Time) Message {
+		return Message{ID: id, Queue: "send_email", Payload: json.RawMessage(`{"to":"` + id + `"}`), Enqueued: now, Visible: visible}
+	}
+
+	_, err := store.Claim(ctx, "send_email", time.Minute)
+	assert.IsError(t, err, ErrEmpty)
+
+	assert.NoError(t, store.Enqueue(ctx, newMessage("second", now.Add(-time.Second))))
+	assert.NoError(t, store.Enqueue(ctx, newMessage("first", now.Add(-time.Second*2))))
+	assert.NoError(t, store.Enqueue(ctx, newMessage("delayed", now.Add(time.Hour))))
+
+	first, err := store.Claim(ctx, "send_email", time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, "first", first.ID)
+	assert.Equal(t, 1, first.Attempt)
+	assert.Equal(t, `{"to":"first"}`, string(first.Payload))
+	assert.True(t, first.Enqueued.Equal(now))
+	assert.NotEqual(t, "", first.Claim)
+
+	second, err := store.Claim(ctx, "send_email", time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, "second", second.ID)
+
+	_, err = store.Claim(ctx, "send_email", time.Minute)
+	assert.IsError(t, err, ErrEmpty, "claimed and delayed jobs should not be visible")
+
+	assert.NoError(t, store.Complete(ctx, first))
+	assert.IsError(t, store.Complete(ctx, first), ErrClaimLost)
+
+	// Retrying makes the job visible again, and it is attempted again on its next claim.
+	assert.NoError(t, store.Retry(ctx, second, time.Now().UTC().Add(-time.Millisecond), "boom"))
+	assert.IsError(t, store.Retry(ctx, second, time.Now().UTC(), "boom"), ErrClaimLost)
+	retried, err := store.Claim(ctx, "send_email", time.Millisecond)
+	assert.NoError(t, err)
+	assert.Equal(t, "second", retried.ID)
+	assert.Equal(t, 2, retried.Attempt)
+	assert.Equal(t, "boom", retried.Error)
+
+	// An expired claim is redelivered, and the original claim can no longer settle the job.
+	time.Sleep(time.Millisecond * 10)
+	redelivered, err := store.Claim(ctx, "send_email", time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, "second", redelivered.ID)
+	assert.Equal(t, 3, redelivered.Attempt)
+	assert.IsError(t, store.Complete(ctx, retried), ErrClaimLost)
+
+	assert.NoError(t, store.Fail(ctx, redelivered, "gave up"))
+	_, err = store.Claim(ctx, "send_email", time.Minute)
+	assert.IsError(t, err, ErrEmpty)
+
+	dead, err := store.Dead(ctx, "send_email", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(dead))
+	assert.Equal(t, "second", dead[0].ID)
+	assert.Equal(t, 3, dead[0].Attempt)
+	assert.Equal(t, "gave up", dead[0].Error)
+	assert.Equal(t, `{"to":"second"}`, string(dead[0].Payload))
+	assert.False(t, dead[0].Failed.IsZero())
+
+	dead, err = store.Dead(ctx, "other", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(dead))
+}
+
+func testOptions() WorkerOptions {
+	return WorkerOptions{
+		Visibility:   time.Second,
+		PollInterval: time.Millisecond * 10,
+		Retry:        RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond, MaxBackoff: time.Millisecond * 10},
+	}
+}
+
+func waitForDead(t *testing.T, store Store, queue string, count int) []Message {
+	t.Helper()
+	deadline := time.Now().Add(time.Second * 5)
+	for {
+		dead, err := store.Dead(t.Context(), queue, 0)
+		assert.NoError(t, err)
+		if len(dead) >= count {
+			return dead
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %d dead jobs, have %d", count, len(dead))
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second * 5)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+func TestWorkCompletesJobs(t *testing.T) {
+	store := NewMemoryStore()
+	queue := NewQueue[SendEmail](loggingtest.NewForTesting(), store)
+	received := make(chan Job[SendEmail], 10)
+	options := testOptions()
+	options.Concurrency = 2
+	assert.NoError(t, queue.Work(t.Context(), options, func(ctx context.Context, job Job[SendEmail]) error {
+		received <- job
+		return nil
+	}))
+	id, err := Enqueue(t.Context(), queue, SendEmail{To: "alice@example.com"})
+	assert.NoError(t, err)
+	select {
+	case job := <-received:
+		assert.Equal(t, id, job.ID)
+		assert.Equal(t, SendEmail{To: "alice@example.com"}, job.Payload)
+		assert.Equal(t, 1, job.Attempt)
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out waiting for job")
+	}
+	waitFor(t, func() bool {
+		_, err := store.Claim(t.Context(), queue.Name(), time.Minute)
+		return errors.Is(err, ErrEmpty)
+	})
+}
+
+func TestWorkRetriesThenFails(t *testing.T) {
+	store := NewMemoryStore()
+	queue := NewQueue[SendEmail](loggingtest.NewForTesting(), store)
+	var attempts atomic.Int32
+	assert.NoError(t, queue.Work(t.Context(), testOptions(), func(ctx context.Context, job Job[SendEmail]) error {
+		attempts.Add(1)
+		return errors.New("smtp unavailable")
+	}))
+	_, err := Enqueue(t.Context(), queue, SendEmail{To: "bob@example.com"})
+	assert.NoError(t, err)
+	dead := waitForDead(t, store, queue.Name(), 1)
+	assert.Equal(t, int32(3), attempts.Load())
+	assert.Equal(t, 3, dead[0].Attempt)
+	assert.Contains(t, dead[0].Error, "smtp unavailable")
+}
+
+func TestWorkRetriesUntilSuccess(t *testing.T) {
+	store := NewMemoryStore()
+	queue := NewQueue[SendEmail](loggingtest.NewForTesting(), store)
+	done := make(chan int, 1)
+	assert.NoError(t, queue.Work(t.Context(), testOptions(), func(ctx context.Context, job Job[SendEmail]) error {
+		if job.Attempt < 2 {
+			return errors.New("try again")
+		}
+		done <- job.Attempt
+		return nil
+	}))
+	_, err := Enqueue(t.Context(), queue, SendEmail{To: "carol@example.com"})
+	assert.NoError(t, err)
+	select {
+	case attempt := <-done:
+		assert.Equal(t, 2, attempt)
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out waiting for job")
+	}
+}
+
+func TestWorkPermanentFailure(t *testing.T) {
+	store := NewMemoryStore()
+	queue := NewQueue[SendEmail](loggingtest.NewForTesting(), store)
+	var attempts atomic.Int32
+	assert.NoError(t, queue.Work(t.Context(), testOptions(), func(ctx context.Context, job Job[SendEmail]) error {
+		attempts.Add(1)
+		return errors.Errorf("invalid address: %w", ErrPermanent)
+	}))
+	_, err := Enqueue(t.Context(), queue, SendEmail{To: "nobody"})
+	assert.NoError(t, err)
+	dead := waitForDead(t, store, queue.Name(), 1)
+	assert.Equal(t, int32(1), attempts.Load())
+	assert.Equal(t, 1, dead[0].Attempt)
+}
+
+func TestWorkRedeliversAfterVisibilityTimeout(t *testing.T) {
+	store := NewMemoryStore()
+	queue := NewQueue[SendEmail](loggingtest.NewForTesting(), store)
+	options := testOptions()
+	options.Visibility = time.Millisecond * 50
+	options.Concurrency = 2
+	attempts := make(chan int, 10)
+	assert.NoError(t, queue.Work(t.Context(), options, func(ctx context.Context, job Job[SendEmail]) error {
+		attempts <- job.Attempt
+		if job.Attempt == 1 {
+			// Simulate a hung worker, which is cancelled once its claim expires.
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	}))
+	_, err := Enqueue(t.Context(), queue, SendEmail{To: "dave@example.com"})
+	assert.NoError(t, err)
+	for want := 1; want <= 2; want++ {
+		select {
+		case attempt := <-attempts:
+			assert.Equal(t, want, attempt)
+		case <-time.After(time.Second * 5):
+			t.Fatal("timed out waiting for job")
+		}
+	}
+}
+
+func TestEnqueueDelay(t *testing.T) {
+	store := NewMemoryStore()
+	queue := NewQueue[SendEmail](loggingtest.NewForTesting(), store)
+	_, err := Enqueue(t.Context(), queue, SendEmail{To: "erin@example.com"}, Delay(time.Hour))
+	assert.NoError(t, err)
+	_, err = store.Claim(t.Context(), queue.Name(), time.Minute)
+	assert.IsError(t, err, ErrEmpty)
+}
+
+type SendEmailV2 struct {
+	To string `json:"to"`
+}
+
+func TestEnqueueNameWithDigits(t *testing.T) {
+	store := NewMemoryStore()
+	queue := NewQueue[SendEmailV2](loggingtest.NewForTesting(), store)
+	id, err := Enqueue(t.Context(), queue, SendEmailV2{To: "frank@example.com"})
+	assert.NoError(t, err)
+	message, err := store.Claim(t.Context(), queue.Name(), time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, id, message.ID)
+	assert.Equal(t, queue.Name(), message.Queue)
+}
@@ -0,0 +1,175 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/alecthomas/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+//zero:config prefix="queue-redis-"
+type RedisConfig struct {
+	Prefix string `help:"Prefix of the keys of queued jobs." default:"queue:"`
+}
+
+// claimScript atomically claims the earliest visible job of a queue, returning its fields.
+var claimScript = redis.NewScript(`
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, 1)
+if #ids == 0 then
+	return false
+end
+local key = ARGV[4] .. ids[1]
+redis.call('ZADD', KEYS[1], ARGV[2], ids[1])
+redis.call('HINCRBY', key, 'attempt', 1)
+redis.call('HSET', key, 'claim', ARGV[3])
+return redis.call('HGETALL', key)
+`)
+
+// settleScript atomically completes, retries or fails a claimed job, returning 0 if the claim no longer matched.
+var settleScript = redis.NewScript(`
+if redis.call('HGET', KEYS[2], 'claim') ~= ARGV[3] then
+	return 0
+end
+if ARGV[1] == 'retry' then
+	redis.call('ZADD', KEYS[1], ARGV[4], ARGV[2])
+	redis.call('HSET', KEYS[2], 'claim', '', 'error', ARGV[5])
+else
+	redis.call('ZREM', KEYS[1], ARGV[2])
+	redis.call('DEL', KEYS[2])
+	if ARGV[1] == 'fail' then
+		redis.call('LPUSH', KEYS[3], ARGV[5])
+	end
+end
+return 1
+`)
+
+// RedisStore is a [Store] that holds jobs in Redis, shared between replicas of a service.
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+var _ Store = (*RedisStore)(nil)
+
+// NewRedisStore creates a [Store] backed by Redis, using the client from
+// [github.com/alecthomas/zero/providers/redis.New].
+//
+// Each queue is held in a sorted set of job IDs scored by the time they are next visible, eg.
+// "queue:{send_email}:visible", with a hash per job. Dead jobs are held in a list, eg. "queue:{send_email}:dead".
+//
+//zero:provider weak
+func NewRedisStore(client *redis.Client, config RedisConfig) Store {
+	return &RedisStore{client: client, prefix: config.Prefix}
+}
+
+func (r *RedisStore) Enqueue(ctx context.Context, message Message) error {
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, r.jobKey(message.Queue, message.ID),
+			"id", message.ID,
+			"payload", string(message.Payload),
+			"attempt", message.Attempt,
+			"enqueued", message.Enqueued.UTC().Format(time.RFC3339Nano),
+			"claim", message.Claim,
+			"error", message.Error,
+		)
+		pipe.ZAdd(ctx, r.visibleKey(message.Queue), redis.Z{Score: score(message.Visible), Member: message.ID})
+		return nil
+	})
+	if err != nil {
+		return errors.Errorf("job %s: %w", message.ID, err)
+	}
+	return nil
+}
+
+func (r *RedisStore) Claim(ctx context.Context, queue string, visibility time.Duration) (Message, error) {
+	now := time.Now().UTC()
+	visible := now.Add(visibility)
+	claim := newClaim()
+	result, err := claimScript.Run(ctx, r.client, []string{r.visibleKey(queue)},
+		score(now), score(visible), claim, r.jobKey(queue, "")).StringSlice()
+	if errors.Is(err, redis.Nil) {
+		return Message{}, errors.Errorf("%s: %w", queue, ErrEmpty)
+	} else if err != nil {
+		return Message{}, errors.Errorf("%s: failed to claim job: %w", queue, err)
+	}
+	fields := map[string]string{}
+	for i := 0; i+1 < len(result); i += 2 {
+		fields[result[i]] = result[i+1]
+	}
+	message := Message{
+		ID:      fields["id"],
+		Queue:   queue,
+		Payload: json.RawMessage(fields["payload"]),
+		Visible: visible,
+		Claim:   fields["claim"],
+		Error:   fields["error"],
+	}
+	if message.Attempt, err = strconv.Atoi(fields["attempt"]); err != nil {
+		return Message{}, errors.Errorf("%s: invalid job attempt: %w", queue, err)
+	}
+	if message.Enqueued, err = time.Parse(time.RFC3339Nano, fields["enqueued"]); err != nil {
+		return Message{}, errors.Errorf("%s: invalid job enqueue time: %w", queue, err)
+	}
+	return message, nil
+}
+
+func (r *RedisStore) Complete(ctx context.Context, message Message) error {
+	return r.settle(ctx, message, "complete", 0, "")
+}
+
+func (r *RedisStore) Retry(ctx context.Context, message Message, visible time.Time, reason string) error {
+	return r.settle(ctx, message, "retry", score(visible), reason)
+}
+
+func (r *RedisStore) Fail(ctx context.Context, message Message, reason string) error {
+	dead := message
+	dead.Claim, dead.Error, dead.Failed = "", reason, time.Now().UTC()
+	data, err := json.Marshal(dead)
+	if err != nil {
+		return errors.Errorf("job %s: %w", message.ID, err)
+	}
+	return r.settle(ctx, message, "fail", 0, string(data))
+}
+
+func (r *RedisStore) Dead(ctx context.Context, queue string, limit int) ([]Message, error) {
+	stop := int64(limit) - 1
+	if limit <= 0 {
+		stop = -1
+	}
+	values, err := r.client.LRange(ctx, r.deadKey(queue), 0, stop).Result()
+	if err != nil {
+		return nil, errors.Errorf("%s: failed to query dead jobs: %w", queue, err)
+	}
+	out := make([]Message, 0, len(values))
+	for _, value := range values {
+		var message Message
+		if err := json.Unmarshal([]byte(value), &message); err != nil {
+			return nil, errors.Errorf("%s: invalid dead job: %w", queue, err)
+		}
+		out = append(out, message)
+	}
+	return out, nil
+}
+
+func (r *RedisStore) settle(ctx context.Context, message Message, op string, visible float64, arg string) error {
+	keys := []string{r.visibleKey(message.Queue), r.jobKey(message.Queue, message.ID), r.deadKey(message.Queue)}
+	settled, err := settleScript.Run(ctx, r.client, keys, op, message.ID, message.Claim, visible, arg).Int()
+	if err != nil {
+		return errors.Errorf("job %s: %w", message.ID, err)
+	}
+	if settled == 0 {
+		return errors.Errorf("job %s: %w", message.ID, ErrClaimLost)
+	}
+	return nil
+}
+
+// Keys of a queue share a hash tag, so that they are in the same slot of a Redis cluster.
+func (r *RedisStore) visibleKey(queue string) string { return r.prefix + "{" + queue + "}:visible" }
+func (r *RedisStore) deadKey(queue string) string    { return r.prefix + "{" + queue + "}:dead" }
+func (r *RedisStore) jobKey(queue, id string) string { return r.prefix + "{" + queue + "}:job:" + id }
+
+// score of a time in a sorted set, in milliseconds since the epoch.
+func score(t time.Time) float64 { return float64(t.UnixMilli()) }
@@ -0,0 +1,183 @@
+package queue
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/alecthomas/errors"
+	zerosql "github.com/alecthomas/zero/providers/sql"
+)
+
+// maxClaimAttempts is the number of times [SQLStore.Claim] retries when another worker claims the same job first.
+const maxClaimAttempts = 5
+
+type SQLStore struct {
+	db     *sql.DB
+	q      func(string) string
+	driver zerosql.Driver
+}
+
+var _ Store = (*SQLStore)(nil)
+
+// NewSQLStore creates a [Store] that persists jobs in an SQL database.
+//
+// Jobs are claimed optimistically, by swapping the claim token of the earliest visible job, so that the store is
+// portable across databases.
+//
+//zero:provider weak require="github.com/alecthomas/zero/providers/queue/migrations.Migrations"
+func NewSQLStore(driver zerosql.Driver, db *sql.DB) Store {
+	return &SQLStore{db: db, q: driver.Denormalise, driver: driver}
+}
+
+func (s *SQLStore) Enqueue(ctx context.Context, message Message) error {
+	_, err := s.db.ExecContext(ctx, s.q(`
+		INSERT INTO queue_jobs (id, queue, payload, attempt, enqueued, visible, claim, error)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`), message.ID, message.Queue, string(message.Payload), message.Attempt, message.Enqueued, message.Visible,
+		message.Claim, message.Error)
+	if err != nil {
+		return errors.Errorf("job %s: %w", message.ID, s.driver.TranslateError(err))
+	}
+	return nil
+}
+
+func (s *SQLStore) Claim(ctx context.Context, queue string, visibility time.Duration) (Message, error) {
+	for range maxClaimAttempts {
+		now := time.Now().UTC()
+		var id, previous string
+		err := s.db.QueryRowContext(ctx, s.q(`
+			SELECT id, claim FROM queue_jobs
+			WHERE queue = ? AND visible <= ?
+			ORDER BY visible
+			LIMIT 1
+		`), queue, now).Scan(&id, &previous)
+		if errors.Is(err, sql.ErrNoRows) {
+			return Message{}, errors.Errorf("%s: %w", queue, ErrEmpty)
+		} else if err != nil {
+			return Message{}, errors.Errorf("%s: failed to claim job: %w", queue, s.driver.TranslateError(err))
+		}
+		claim := newClaim()
+		result, err := s.db.ExecContext(ctx, s.q(`
+			UPDATE queue_jobs
+			SET attempt = attempt + 1, visible = ?, claim = ?
+			WHERE id = ? AND claim = ?
+		`), now.Add(visibility), claim, id, previous)
+		if err != nil {
+			return Message{}, errors.Errorf("%s: failed to claim job: %w", queue, s.driver.TranslateError(err))
+		}
+		count, err := result.RowsAffected()
+		if err != nil {
+			return Message{}, errors.WithStack(s.driver.TranslateError(err))
+		}
+		if count == 0 {
+			// Claimed by another worker first.
+			continue
+		}
+		row := s.db.QueryRowContext(ctx, s.q(`
+			SELECT id, queue, payload, attempt, enqueued, visible, claim, error
+			FROM queue_jobs
+			WHERE id = ?
+		`), id)
+		message, err := scanMessage(row)
+		if err != nil {
+			return Message{}, errors.Errorf("%s: failed to claim job: %w", queue, s.driver.TranslateError(err))
+		}
+		return message, nil
+	}
+	return Message{}, errors.Errorf("%s: %w", queue, ErrEmpty)
+}
+
+func (s *SQLStore) Complete(ctx context.Context, message Message) error {
+	result, err := s.db.ExecContext(ctx, s.q(`DELETE FROM queue_jobs WHERE id = ? AND claim = ?`), message.ID, message.Claim)
+	return s.settled(message, result, err)
+}
+
+func (s *SQLStore) Retry(ctx context.Context, message Message, visible time.Time, reason string) error {
+	result, err := s.db.ExecContext(ctx, s.q(`
+		UPDATE queue_jobs
+		SET visible = ?, claim = '', error = ?
+		WHERE id = ? AND claim = ?
+	`), visible, reason, message.ID, message.Claim)
+	return s.settled(message, result, err)
+}
+
+func (s *SQLStore) Fail(ctx context.Context, message Message, reason string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Errorf("job %s: %w", message.ID, s.driver.TranslateError(err))
+	}
+	defer tx.Rollback() //nolint:errcheck
+	result, err := tx.ExecContext(ctx, s.q(`DELETE FROM queue_jobs WHERE id = ? AND claim = ?`), message.ID, message.Claim)
+	if err := s.settled(message, result, err); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, s.q(`
+		INSERT INTO queue_dead_jobs (id, queue, payload, attempt, enqueued, failed, error)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`), message.ID, message.Queue, string(message.Payload), message.Attempt, message.Enqueued, time.Now().UTC(), reason)
+	if err != nil {
+		return errors.Errorf("job %s: %w", message.ID, s.driver.TranslateError(err))
+	}
+	return errors.WithStack(tx.Commit())
+}
+
+func (s *SQLStore) Dead(ctx context.Context, queue string, limit int) ([]Message, error) {
+	rows, err := s.db.QueryContext(ctx, s.q(`
+		SELECT id, queue, payload, attempt, enqueued, failed, error
+		FROM queue_dead_jobs
+		WHERE queue = ?
+		ORDER BY failed DESC
+		LIMIT ?
+	`), queue, limit)
+	if err != nil {
+		return nil, errors.Errorf("%s: failed to query dead jobs: %w", queue, s.driver.TranslateError(err))
+	}
+	defer rows.Close()
+	var out []Message
+	for rows.Next() {
+		var message Message
+		var payload string
+		if err := rows.Scan(&message.ID, &message.Queue, &payload, &message.Attempt, &message.Enqueued, &message.Failed, &message.Error); err != nil {
+			return nil, errors.Errorf("%s: failed to query dead jobs: %w", queue, s.driver.TranslateError(err))
+		}
+		message.Payload = json.RawMessage(payload)
+		message.Enqueued = message.Enqueued.UTC()
+		message.Failed = message.Failed.UTC()
+		out = append(out, message)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Errorf("%s: failed to query dead jobs: %w", queue, s.driver.TranslateError(err))
+	}
+	return out, nil
+}
+
+// settled checks the result of settling a claimed job, returning [ErrClaimLost] if the claim no longer matched.
+func (s *SQLStore) settled(message Message, result sql.Result, err error) error {
+	if err != nil {
+		return errors.Errorf("job %s: %w", message.ID, s.driver.TranslateError(err))
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return errors.WithStack(s.driver.TranslateError(err))
+	}
+	if count == 0 {
+		return errors.Errorf("job %s: %w", message.ID, ErrClaimLost)
+	}
+	return nil
+}
+
+func scanMessage(row interface{ Scan(dest ...any) error }) (Message, error) {
+	var message Message
+	var payload string
+	err := row.Scan(&message.ID, &message.Queue, &payload, &message.Attempt, &message.Enqueued, &message.Visible,
+		&message.Claim, &message.Error)
+	if err != nil {
+		return Message{}, errors.WithStack(err)
+	}
+	message.Payload = json.RawMessage(payload)
+	message.Enqueued = message.Enqueued.UTC()
+	message.Visible = message.Visible.UTC()
+	return message, nil
+}
@@ -0,0 +1,13 @@
+package queue
+
+import (
+	"testing"
+
+	"github.com/alecthomas/zero/providers/queue/migrations"
+	"github.com/alecthomas/zero/providers/sql/sqltest"
+)
+
+func testSQLStore(t *testing.T, dsn string) { //nolint
+	db, driver := sqltest.NewForTesting(t, dsn, migrations.Migrations())
+	testStore(t, NewSQLStore(driver, db))
+}
@@ -0,0 +1,9 @@
+//go:build sqlite
+
+package queue
+
+import "testing"
+
+func TestSQLiteStore(t *testing.T) {
+	testSQLStore(t, "sqlite://file:discard?mode=memory&cache=shared")
+}
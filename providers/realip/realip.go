@@ -0,0 +1,134 @@
+// Package realip provides middleware for resolving the IP address of clients behind trusted reverse proxies.
+//
+// The middleware is applied to any API annotated with the "realip" label, and sets the client [Addr] in the request
+// context, from which it is injected into the API method, eg.
+//
+//	//zero:api POST /login realip
+//	func (s *Service) Login(ctx context.Context, client realip.Addr, login Login) (Session, error)
+package realip
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/alecthomas/errors"
+	"github.com/alecthomas/zero"
+)
+
+// Addr is the IP address of the client of a request.
+//
+//zero:contextkey realip
+type Addr struct{ netip.Addr }
+
+// FromContext returns the client address set in ctx by the realip middleware, if any.
+func FromContext(ctx context.Context) (Addr, bool) {
+	return zero.ContextValue[Addr](ctx)
+}
+
+// WithAddr returns a copy of ctx carrying the client address.
+func WithAddr(ctx context.Context, addr Addr) context.Context {
+	return zero.WithContextValue(ctx, addr)
+}
+
+//zero:config prefix="realip-"
+type Config struct {
+	TrustedProxies []netip.Prefix `help:"CIDRs of reverse proxies trusted to report the client address in forwarding headers." placeholder:"CIDR"`
+}
+
+// Resolve returns the address of the client of r.
+//
+// If the peer is a trusted proxy, the forwarding headers are consulted in order of precedence: Forwarded,
+// X-Forwarded-For, then X-Real-IP. The client is the rightmost address in the chain of proxies that is not itself
+// trusted, as addresses to the left of it may be spoofed. Otherwise the client is the peer.
+func (c Config) Resolve(r *http.Request) Addr {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	peer, err := netip.ParseAddr(host)
+	if err != nil {
+		return Addr{}
+	}
+	peer = peer.Unmap()
+	if !c.trusted(peer) {
+		return Addr{peer}
+	}
+	chain := forwardedFor(r.Header)
+	client := peer
+	for i := len(chain) - 1; i >= 0; i-- {
+		addr, err := parseNode(chain[i])
+		if err != nil {
+			break
+		}
+		client = addr
+		if !c.trusted(addr) {
+			break
+		}
+	}
+	return Addr{client}
+}
+
+func (c Config) trusted(addr netip.Addr) bool {
+	for _, prefix := range c.TrustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedFor returns the chain of client and proxy nodes from the forwarding headers, client first.
+func forwardedFor(header http.Header) []string {
+	var chain []string
+	if values := header.Values("Forwarded"); len(values) > 0 {
+		for _, value := range values {
+			for element := range strings.SplitSeq(value, ",") {
+				for pair := range strings.SplitSeq(element, ";") {
+					key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+					if ok && strings.EqualFold(key, "for") {
+						chain = append(chain, strings.Trim(value, `"`))
+					}
+				}
+			}
+		}
+		return chain
+	}
+	if values := header.Values("X-Forwarded-For"); len(values) > 0 {
+		for _, value := range values {
+			for node := range strings.SplitSeq(value, ",") {
+				chain = append(chain, strings.TrimSpace(node))
+			}
+		}
+		return chain
+	}
+	if value := header.Get("X-Real-IP"); value != "" {
+		return []string{strings.TrimSpace(value)}
+	}
+	return nil
+}
+
+// parseNode parses an IP address with an optional port, and IPv6 addresses optionally in brackets.
+func parseNode(node string) (netip.Addr, error) {
+	if addrPort, err := netip.ParseAddrPort(node); err == nil {
+		return addrPort.Addr().Unmap(), nil
+	}
+	addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(node, "["), "]"))
+	if err != nil {
+		return netip.Addr{}, errors.WithStack(err)
+	}
+	return addr.Unmap(), nil
+}
+
+// Middleware resolves the client address of requests and sets it in the request context.
+//
+//zero:middleware realip
+func Middleware(config Config) zero.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(WithAddr(r.Context(), config.Resolve(r))))
+		})
+	}
+}
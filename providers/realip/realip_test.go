@@ -0,0 +1,63 @@
+package realip
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestResolve(t *testing.T) {
+	config := Config{TrustedProxies: []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("fd00::/8"),
+	}}
+	tests := []struct {
+		name       string
+		remoteAddr string
+		header     http.Header
+		addr       string
+	}{
+		{"UntrustedPeer", "203.0.113.1:1234", http.Header{"X-Forwarded-For": {"198.51.100.1"}}, "203.0.113.1"},
+		{"TrustedPeerWithoutHeaders", "10.0.0.1:1234", nil, "10.0.0.1"},
+		{"XForwardedFor", "10.0.0.1:1234", http.Header{"X-Forwarded-For": {"198.51.100.1, 10.0.0.2"}}, "198.51.100.1"},
+		{"XForwardedForMultipleHeaders", "10.0.0.1:1234", http.Header{"X-Forwarded-For": {"198.51.100.1", "10.0.0.2"}}, "198.51.100.1"},
+		{"SpoofedXForwardedFor", "10.0.0.1:1234", http.Header{"X-Forwarded-For": {"192.0.2.1, 198.51.100.1"}}, "198.51.100.1"},
+		{"InvalidXForwardedFor", "10.0.0.1:1234", http.Header{"X-Forwarded-For": {"garbage, 10.0.0.2"}}, "10.0.0.2"},
+		{"XRealIP", "10.0.0.1:1234", http.Header{"X-Real-Ip": {"198.51.100.1"}}, "198.51.100.1"},
+		{"Forwarded", "10.0.0.1:1234", http.Header{"Forwarded": {`for=198.51.100.1;proto=https, for="[fd00::1]:443"`}}, "198.51.100.1"},
+		{"ForwardedIPv6", "[fd00::2]:1234", http.Header{"Forwarded": {`For="[2001:db8::1]:4711"`}}, "2001:db8::1"},
+		{"ForwardedTakesPrecedence", "10.0.0.1:1234", http.Header{
+			"Forwarded":       {"for=198.51.100.1"},
+			"X-Forwarded-For": {"192.0.2.1"},
+		}, "198.51.100.1"},
+		{"IPv4MappedIPv6", "[::ffff:10.0.0.1]:1234", http.Header{"X-Forwarded-For": {"198.51.100.1"}}, "198.51.100.1"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = test.remoteAddr
+			for key, values := range test.header {
+				r.Header[key] = values
+			}
+			assert.Equal(t, test.addr, config.Resolve(r).String())
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	config := Config{TrustedProxies: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}}
+	var addr Addr
+	var ok bool
+	handler := Middleware(config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		addr, ok = FromContext(r.Context())
+	}))
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set("X-Forwarded-For", "198.51.100.1")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	assert.True(t, ok)
+	assert.Equal(t, netip.MustParseAddr("198.51.100.1"), addr.Addr)
+}
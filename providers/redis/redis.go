@@ -0,0 +1,81 @@
+// Package redis provides a configured Redis client, shared by providers backed by Redis such as the Redis cache.
+package redis
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"time"
+
+	"github.com/alecthomas/errors"
+	"github.com/redis/go-redis/extra/redisotel/v9"
+	"github.com/redis/go-redis/v9"
+)
+
+//zero:config prefix="redis-"
+type Config struct {
+	Addr         string        `help:"Address of the Redis server." default:"localhost:6379"`
+	Username     string        `help:"Username for Redis ACL authentication."`
+	Password     string        `help:"Password for Redis authentication." env:"REDIS_PASSWORD" secret:""`
+	DB           int           `help:"Redis database number." default:"0"`
+	TLS          bool          `help:"Connect to Redis over TLS."`
+	PoolSize     int           `help:"Maximum number of connections (0 for 10 per CPU)." default:"0"`
+	MinIdleConns int           `help:"Minimum number of idle connections." default:"0"`
+	MaxIdleConns int           `help:"Maximum number of idle connections (0 for unlimited)." default:"0"`
+	PingTimeout  time.Duration `help:"Timeout for the startup health check of the connection (0 to disable)." default:"5s"`
+	Telemetry    bool          `help:"Instrument the client with OpenTelemetry tracing and metrics." default:"true" negatable:""`
+}
+
+// New creates a Redis client.
+//
+// The connection is checked with a PING on startup, so the service will fail to start rather than becoming ready
+// without Redis. Traces and metrics are reported to the global OpenTelemetry providers, which are no-ops unless
+// configured by the service.
+//
+//zero:provider weak
+func New(ctx context.Context, config Config) (*redis.Client, error) {
+	options := &redis.Options{
+		Addr:         config.Addr,
+		Username:     config.Username,
+		Password:     config.Password,
+		DB:           config.DB,
+		PoolSize:     config.PoolSize,
+		MinIdleConns: config.MinIdleConns,
+		MaxIdleConns: config.MaxIdleConns,
+	}
+	if config.TLS {
+		host, _, err := net.SplitHostPort(config.Addr)
+		if err != nil {
+			return nil, errors.Errorf("invalid Redis address %q: %w", config.Addr, err)
+		}
+		options.TLSConfig = &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}
+	}
+	client := redis.NewClient(options)
+	if config.Telemetry {
+		if err := redisotel.InstrumentTracing(client); err != nil {
+			_ = client.Close()
+			return nil, errors.Errorf("failed to instrument Redis tracing: %w", err)
+		}
+		if err := redisotel.InstrumentMetrics(client); err != nil {
+			_ = client.Close()
+			return nil, errors.Errorf("failed to instrument Redis metrics: %w", err)
+		}
+	}
+	if config.PingTimeout > 0 {
+		if err := Ping(ctx, client, config.PingTimeout); err != nil {
+			_ = client.Close()
+			return nil, err
+		}
+	}
+	return client, nil
+}
+
+// Ping checks the health of the connection to Redis, eg. for use in health checks.
+func Ping(ctx context.Context, client *redis.Client, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		return errors.Errorf("failed to connect to Redis at %s: %w", client.Options().Addr, err)
+	}
+	return nil
+}
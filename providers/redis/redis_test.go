@@ -0,0 +1,33 @@
+package redis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestNew(t *testing.T) {
+	server := miniredis.RunT(t)
+	server.RequireAuth("secret")
+	client, err := New(t.Context(), Config{Addr: server.Addr(), Password: "secret", PingTimeout: time.Second, Telemetry: true})
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+	assert.NoError(t, client.Set(t.Context(), "key", "value", 0).Err())
+	server.CheckGet(t, "key", "value")
+}
+
+func TestNewUnavailable(t *testing.T) {
+	server := miniredis.RunT(t)
+	addr := server.Addr()
+	server.Close()
+	_, err := New(t.Context(), Config{Addr: addr, PingTimeout: time.Second})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to connect to Redis at "+addr)
+
+	// The startup health check can be disabled.
+	client, err := New(t.Context(), Config{Addr: addr})
+	assert.NoError(t, err)
+	_ = client.Close()
+}
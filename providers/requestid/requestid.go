@@ -0,0 +1,71 @@
+// Package requestid provides middleware that identifies each request with a unique ID.
+//
+// The middleware is applied to any API annotated with the "requestid" label, and sets the request [ID] in the request
+// context, from which it is injected into the API method, eg.
+//
+//	//zero:api POST /orders requestid
+//	func (s *Service) CreateOrder(ctx context.Context, request requestid.ID, order Order) error
+package requestid
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/alecthomas/zero"
+)
+
+// maxLength is the maximum length of a request ID propagated from an incoming request.
+const maxLength = 128
+
+// ID uniquely identifies a request.
+//
+//zero:contextkey requestid
+type ID string
+
+// FromContext returns the request ID set in ctx by the requestid middleware, if any.
+func FromContext(ctx context.Context) (ID, bool) {
+	return zero.ContextValue[ID](ctx)
+}
+
+// WithID returns a copy of ctx carrying the request ID.
+func WithID(ctx context.Context, id ID) context.Context {
+	return zero.WithContextValue(ctx, id)
+}
+
+//zero:config prefix="request-id-"
+type Config struct {
+	Header    string `help:"Header carrying the request ID in requests and responses." default:"X-Request-ID"`
+	Propagate bool   `help:"Use the request ID of incoming requests, eg. as set by a reverse proxy, rather than generating one." default:"true"`
+}
+
+// Middleware sets the ID of requests in the request context and response headers.
+//
+// IDs are propagated from the request header if enabled and present, otherwise they are generated with
+// [zero.IDGenerator]. Propagated IDs are discarded if they are longer than 128 bytes or contain anything other than
+// printable ASCII, so that they are safe to log.
+//
+//zero:middleware requestid
+func Middleware(config Config, generator zero.IDGenerator) zero.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := ID(r.Header.Get(config.Header))
+			if !config.Propagate || !valid(id) {
+				id = ID(generator.NewID())
+			}
+			w.Header().Set(config.Header, string(id))
+			next.ServeHTTP(w, r.WithContext(WithID(r.Context(), id)))
+		})
+	}
+}
+
+func valid(id ID) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := range len(id) {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
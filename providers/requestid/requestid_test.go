@@ -0,0 +1,45 @@
+package requestid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/alecthomas/zero/providers/idgen/idgentest"
+)
+
+func TestMiddleware(t *testing.T) {
+	config := Config{Header: "X-Request-ID", Propagate: true}
+	tests := []struct {
+		name     string
+		config   Config
+		incoming string
+		id       ID
+	}{
+		{"Generated", config, "", "00000000-0000-7000-8000-000000000001"},
+		{"Propagated", config, "abc-123", "abc-123"},
+		{"NotPropagated", Config{Header: "X-Request-ID"}, "abc-123", "00000000-0000-7000-8000-000000000001"},
+		{"InvalidCharacters", config, "abc 123", "00000000-0000-7000-8000-000000000001"},
+		{"TooLong", config, strings.Repeat("a", 129), "00000000-0000-7000-8000-000000000001"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var id ID
+			var ok bool
+			handler := Middleware(test.config, idgentest.NewForTesting())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				id, ok = FromContext(r.Context())
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if test.incoming != "" {
+				r.Header.Set("X-Request-ID", test.incoming)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			assert.True(t, ok)
+			assert.Equal(t, test.id, id)
+			assert.Equal(t, string(test.id), w.Header().Get("X-Request-ID"))
+		})
+	}
+}
@@ -65,7 +65,7 @@ type Migrations []fs.FS
 type Config struct {
 	Create  bool   `help:"Create (or recreate) the database."`
 	Migrate bool   `help:"Apply migrations during connection establishment."`
-	DSN     string `default:"${sqldsn}" help:"DSN for the SQL connection."`
+	DSN     string `default:"${sqldsn}" help:"DSN for the SQL connection." secret:""`
 }
 
 // DriverForConfig returns the [Driver] associated with the given [Config].
@@ -155,8 +155,11 @@ func Open(dsn string) (db *sql.DB, err error) {
 //
 //zero:provider weak
 func New(ctx context.Context, config Config, logger *slog.Logger, migrations Migrations) (db *sql.DB, err error) {
-	dsn := config.DSN
-	if config.Create {
+	return connect(ctx, config.DSN, config.Create, config.Migrate, logger, migrations)
+}
+
+func connect(ctx context.Context, dsn string, create, migrate bool, logger *slog.Logger, migrations Migrations) (db *sql.DB, err error) {
+	if create {
 		if err := CreateDatabase(ctx, dsn); err != nil {
 			return nil, errors.WithStack(err)
 		}
@@ -165,7 +168,7 @@ func New(ctx context.Context, config Config, logger *slog.Logger, migrations Mig
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
-	if config.Migrate {
+	if migrate {
 		if err := Migrate(ctx, logger, dsn, db, migrations); err != nil {
 			return nil, errors.Errorf("failed migrations: %w", err)
 		}
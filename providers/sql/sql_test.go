@@ -54,3 +54,8 @@ func testDB(t *testing.T, dsn string) {
 	})
 
 }
+
+func TestNewTenantDBRequiresPlaceholder(t *testing.T) {
+	_, err := zerosql.NewTenantDB(zerosql.TenantConfig{DSN: "postgres://localhost/app"}, nil, nil)
+	assert.EqualError(t, err, `--sql-tenant-dsn must contain {tenant}: "postgres://localhost/app"`)
+}
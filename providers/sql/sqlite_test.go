@@ -5,6 +5,12 @@ package sql_test
 import (
 	"path/filepath"
 	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/alecthomas/zero/providers/logging/loggingtest"
+	zerosql "github.com/alecthomas/zero/providers/sql"
+	"github.com/alecthomas/zero/providers/tenant"
+	"github.com/psanford/memfs"
 )
 
 func TestSQLite(t *testing.T) {
@@ -17,3 +23,39 @@ func TestSQLite(t *testing.T) {
 		testDB(t, "sqlite://file:discard?mode=memory&cache=shared")
 	})
 }
+
+func TestSQLiteTenantDB(t *testing.T) {
+	fs := memfs.New()
+	err := fs.WriteFile("000_init.sql", []byte(`CREATE TABLE users (name VARCHAR(255) NOT NULL PRIMARY KEY)`), 0600)
+	assert.NoError(t, err)
+	dir := t.TempDir()
+	tenants, err := zerosql.NewTenantDB(zerosql.TenantConfig{
+		DSN:     "sqlite://file:" + filepath.Join(dir, "{tenant}.db"),
+		Create:  true,
+		Migrate: true,
+	}, loggingtest.NewForTesting(), zerosql.Migrations{fs})
+	assert.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, tenants.Close()) })
+
+	_, err = tenants.DB(t.Context())
+	assert.IsError(t, err, tenant.ErrNoTenant)
+
+	acme, err := tenants.DB(tenant.WithID(t.Context(), "acme"))
+	assert.NoError(t, err)
+	_, err = acme.ExecContext(t.Context(), `INSERT INTO users (name) VALUES ('Alice')`)
+	assert.NoError(t, err)
+
+	// Each tenant has its own database.
+	other, err := tenants.ForTenant(t.Context(), "other")
+	assert.NoError(t, err)
+	var count int
+	assert.NoError(t, other.QueryRowContext(t.Context(), `SELECT COUNT(*) FROM users`).Scan(&count))
+	assert.Equal(t, 0, count)
+
+	again, err := tenants.ForTenant(t.Context(), "acme")
+	assert.NoError(t, err)
+	assert.Equal(t, acme, again)
+
+	_, err = tenants.ForTenant(t.Context(), "../acme")
+	assert.IsError(t, err, tenant.ErrInvalidTenant)
+}
@@ -0,0 +1,92 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/alecthomas/errors"
+	"github.com/alecthomas/zero/providers/tenant"
+)
+
+//zero:config prefix="sql-tenant-"
+type TenantConfig struct {
+	Create  bool   `help:"Create (or recreate) each tenant database on first use."`
+	Migrate bool   `help:"Apply migrations to each tenant database on first use."`
+	DSN     string `help:"DSN template for per-tenant SQL connections, with {tenant} replaced by the tenant ID." secret:""`
+}
+
+// TenantDB selects a per-tenant database connection for the tenant in the request context.
+//
+// Connections are established, and migrations applied or checked, on first use by each tenant.
+type TenantDB struct {
+	config     TenantConfig
+	logger     *slog.Logger
+	migrations Migrations
+	lock       sync.Mutex
+	dbs        map[tenant.ID]*sql.DB
+}
+
+// NewTenantDB creates a [TenantDB] from the --sql-tenant-dsn template.
+//
+//zero:provider
+func NewTenantDB(config TenantConfig, logger *slog.Logger, migrations Migrations) (*TenantDB, error) {
+	if !strings.Contains(config.DSN, "{tenant}") {
+		return nil, errors.Errorf("--sql-tenant-dsn must contain {tenant}: %q", config.DSN)
+	}
+	if _, ok := drivers[dsnScheme(config.DSN)]; !ok {
+		return nil, errors.Errorf("unknown SQL driver: %s", dsnScheme(config.DSN))
+	}
+	return &TenantDB{
+		config:     config,
+		logger:     logger,
+		migrations: migrations,
+		dbs:        map[tenant.ID]*sql.DB{},
+	}, nil
+}
+
+// DB returns the database connection for the tenant in ctx.
+func (t *TenantDB) DB(ctx context.Context) (*sql.DB, error) {
+	id, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, errors.Errorf("no tenant in context: %w", tenant.ErrNoTenant)
+	}
+	return t.ForTenant(ctx, id)
+}
+
+// ForTenant returns the database connection for the given tenant.
+func (t *TenantDB) ForTenant(ctx context.Context, id tenant.ID) (*sql.DB, error) {
+	// The ID is substituted into the DSN, so must be validated even if it was not resolved by the tenant middleware.
+	id, err := tenant.Parse(string(id))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if db, ok := t.dbs[id]; ok {
+		return db, nil
+	}
+	dsn := strings.ReplaceAll(t.config.DSN, "{tenant}", string(id))
+	db, err := connect(ctx, dsn, t.config.Create, t.config.Migrate, t.logger, t.migrations)
+	if err != nil {
+		return nil, errors.Errorf("tenant %s: %w", id, err)
+	}
+	t.dbs[id] = db
+	return db, nil
+}
+
+// Close all tenant database connections.
+func (t *TenantDB) Close() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	var errs []error
+	for id, db := range t.dbs {
+		if err := db.Close(); err != nil {
+			errs = append(errs, errors.Errorf("tenant %s: %w", id, err))
+		}
+		delete(t.dbs, id)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,149 @@
+// Package tenant provides middleware for resolving the tenant of multi-tenant services.
+//
+// The middleware is applied to any API annotated with the "tenant" label, and sets the resolved tenant [ID] in the
+// request context, from which it is injected into the API method, eg.
+//
+//	//zero:api GET /projects tenant
+//	func (s *Service) Projects(ctx context.Context, tenant tenant.ID) ([]Project, error)
+//
+// The tenant is also used by the SQL provider to select per-tenant databases, and is propagated through PubSub events.
+package tenant
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/alecthomas/errors"
+	"github.com/alecthomas/zero"
+)
+
+var (
+	// ErrNoTenant is returned by a [Resolver] if the request does not identify a tenant.
+	ErrNoTenant = errors.New("no tenant")
+	// ErrUnknownTenant may be returned by a [Resolver] if the request identifies a tenant that does not exist.
+	ErrUnknownTenant = errors.New("unknown tenant")
+	// ErrInvalidTenant is returned by [Parse] if a tenant ID is malformed.
+	ErrInvalidTenant = errors.New("invalid tenant")
+)
+
+// ID of a tenant.
+//
+//zero:contextkey tenant
+type ID string
+
+var idRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{0,62}$`)
+
+// Parse validates a tenant ID.
+//
+// IDs consist of up to 63 letters, digits, underscores and hyphens, starting with a letter or digit, so that they are
+// safe to use in hostnames, database names and DSNs.
+func Parse(id string) (ID, error) {
+	if !idRe.MatchString(id) {
+		return "", errors.Errorf("%q: %w", id, ErrInvalidTenant)
+	}
+	return ID(id), nil
+}
+
+// FromContext returns the tenant set in ctx by the tenant middleware, if any.
+func FromContext(ctx context.Context) (ID, bool) {
+	return zero.ContextValue[ID](ctx)
+}
+
+// WithID returns a copy of ctx carrying the tenant.
+func WithID(ctx context.Context, id ID) context.Context {
+	return zero.WithContextValue(ctx, id)
+}
+
+// Resolver resolves the tenant of a request.
+type Resolver interface {
+	// Resolve returns the tenant of the request, or [ErrNoTenant] if the request does not identify one.
+	Resolve(r *http.Request) (ID, error)
+}
+
+// ResolverFunc is a function implementing [Resolver].
+type ResolverFunc func(r *http.Request) (ID, error)
+
+func (f ResolverFunc) Resolve(r *http.Request) (ID, error) { return f(r) }
+
+//zero:config prefix="tenant-"
+type Config struct {
+	Header string `default:"X-Tenant-ID" help:"Request header containing the tenant ID."`
+	Domain string `help:"Resolve the tenant from the subdomain of this domain, eg. acme.example.com for example.com."`
+}
+
+// NewResolver creates a [Resolver] that resolves the tenant from the subdomain of the request host if a domain is
+// configured, falling back to the tenant header.
+//
+// Provide a custom [Resolver] to resolve tenants from authentication tokens, eg. with [TokenResolver].
+//
+//zero:provider weak
+func NewResolver(config Config) Resolver {
+	domain := strings.ToLower(strings.Trim(config.Domain, "."))
+	return ResolverFunc(func(r *http.Request) (ID, error) {
+		if domain != "" {
+			host := r.Host
+			if h, _, err := net.SplitHostPort(host); err == nil {
+				host = h
+			}
+			if subdomain, ok := strings.CutSuffix(strings.ToLower(host), "."+domain); ok && !strings.Contains(subdomain, ".") {
+				return Parse(subdomain)
+			}
+		}
+		if config.Header != "" {
+			if header := r.Header.Get(config.Header); header != "" {
+				return Parse(header)
+			}
+		}
+		return "", ErrNoTenant
+	})
+}
+
+// TokenResolver creates a [Resolver] that resolves the tenant from the bearer token in the Authorization header.
+//
+// verify must authenticate the token before returning its tenant, as the tenant would otherwise be trivially spoofed.
+func TokenResolver(verify func(ctx context.Context, token string) (ID, error)) Resolver {
+	return ResolverFunc(func(r *http.Request) (ID, error) {
+		scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+			return "", ErrNoTenant
+		}
+		id, err := verify(r.Context(), token)
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+		return Parse(string(id))
+	})
+}
+
+// Middleware resolves the tenant of requests and sets it in the request context.
+//
+// Requests without a valid tenant are rejected with 400 Bad Request, or 404 Not Found if the tenant does not exist.
+//
+//zero:middleware tenant
+func Middleware(resolver Resolver, logger *slog.Logger, encodeError zero.ErrorEncoder) zero.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, err := resolver.Resolve(r)
+			switch {
+			case errors.Is(err, ErrNoTenant):
+				encodeError(logger, w, "tenant is required", http.StatusBadRequest)
+				return
+			case errors.Is(err, ErrInvalidTenant):
+				encodeError(logger, w, "invalid tenant", http.StatusBadRequest)
+				return
+			case errors.Is(err, ErrUnknownTenant):
+				encodeError(logger, w, "unknown tenant", http.StatusNotFound)
+				return
+			case err != nil:
+				logger.Error("Failed to resolve tenant", "error", err)
+				encodeError(logger, w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(WithID(r.Context(), id)))
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package tenant
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/alecthomas/errors"
+	"github.com/alecthomas/zero"
+	"github.com/alecthomas/zero/providers/logging/loggingtest"
+)
+
+func TestParse(t *testing.T) {
+	for _, valid := range []string{"acme", "ACME-corp_2", "0"} {
+		id, err := Parse(valid)
+		assert.NoError(t, err)
+		assert.Equal(t, ID(valid), id)
+	}
+	for _, invalid := range []string{"", "-acme", "acme.example.com", "acme;drop", "a/b"} {
+		_, err := Parse(invalid)
+		assert.IsError(t, err, ErrInvalidTenant)
+	}
+}
+
+func TestResolver(t *testing.T) {
+	resolver := NewResolver(Config{Header: "X-Tenant-ID", Domain: "example.com"})
+	tests := []struct {
+		name   string
+		host   string
+		header string
+		id     ID
+		err    error
+	}{
+		{"Subdomain", "acme.example.com", "", "acme", nil},
+		{"SubdomainWithPort", "Acme.example.com:8080", "", "acme", nil},
+		{"SubdomainTakesPrecedence", "acme.example.com", "other", "acme", nil},
+		{"Header", "localhost", "acme", "acme", nil},
+		{"NestedSubdomain", "a.b.example.com", "", "", ErrNoTenant},
+		{"InvalidHeader", "example.com", "../acme", "", ErrInvalidTenant},
+		{"None", "example.com", "", "", ErrNoTenant},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Host = test.host
+			if test.header != "" {
+				r.Header.Set("X-Tenant-ID", test.header)
+			}
+			id, err := resolver.Resolve(r)
+			if test.err != nil {
+				assert.IsError(t, err, test.err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.id, id)
+			}
+		})
+	}
+}
+
+func TestTokenResolver(t *testing.T) {
+	resolver := TokenResolver(func(ctx context.Context, token string) (ID, error) {
+		if token != "secret" {
+			return "", ErrUnknownTenant
+		}
+		return "acme", nil
+	})
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	_, err := resolver.Resolve(r)
+	assert.IsError(t, err, ErrNoTenant)
+	r.Header.Set("Authorization", "Bearer secret")
+	id, err := resolver.Resolve(r)
+	assert.NoError(t, err)
+	assert.Equal(t, ID("acme"), id)
+	r.Header.Set("Authorization", "Bearer invalid")
+	_, err = resolver.Resolve(r)
+	assert.IsError(t, err, ErrUnknownTenant)
+}
+
+func TestMiddleware(t *testing.T) {
+	resolver := ResolverFunc(func(r *http.Request) (ID, error) {
+		switch r.Header.Get("X-Tenant-ID") {
+		case "":
+			return "", ErrNoTenant
+		case "missing":
+			return "", ErrUnknownTenant
+		case "broken":
+			return "", errors.New("database unavailable")
+		default:
+			return Parse(r.Header.Get("X-Tenant-ID"))
+		}
+	})
+	handler := Middleware(resolver, loggingtest.NewForTesting(), zero.EncodeError)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, ok := FromContext(r.Context())
+		assert.True(t, ok)
+		_, _ = w.Write([]byte(id))
+	}))
+	tests := []struct {
+		header string
+		status int
+		body   string
+	}{
+		{"acme", http.StatusOK, "acme"},
+		{"", http.StatusBadRequest, "tenant is required"},
+		{"a.b", http.StatusBadRequest, "invalid tenant"},
+		{"missing", http.StatusNotFound, "unknown tenant"},
+		{"broken", http.StatusInternalServerError, "Internal Server Error"},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("X-Tenant-ID", test.header)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		assert.Equal(t, test.status, w.Code, "%q", test.header)
+		assert.Contains(t, w.Body.String(), test.body)
+	}
+}
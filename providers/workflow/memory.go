@@ -0,0 +1,69 @@
+package workflow
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/alecthomas/errors"
+)
+
+type MemoryStore struct {
+	lock      sync.Mutex
+	instances map[string]Instance
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore creates a [Store] that holds workflow instances in memory.
+//
+// Instances are not shared between replicas of a service, and do not survive restarts, so are not resumed after a
+// crash.
+//
+//zero:provider weak
+func NewMemoryStore() Store {
+	return &MemoryStore{instances: map[string]Instance{}}
+}
+
+func (m *MemoryStore) Create(ctx context.Context, instance Instance) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if _, ok := m.instances[instance.ID]; ok {
+		return errors.Errorf("workflow instance %s already exists", instance.ID)
+	}
+	m.instances[instance.ID] = instance
+	return nil
+}
+
+func (m *MemoryStore) Update(ctx context.Context, instance Instance) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if _, ok := m.instances[instance.ID]; !ok {
+		return errors.Errorf("%s: %w", instance.ID, ErrNotFound)
+	}
+	m.instances[instance.ID] = instance
+	return nil
+}
+
+func (m *MemoryStore) Get(ctx context.Context, id string) (Instance, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	instance, ok := m.instances[id]
+	if !ok {
+		return Instance{}, errors.Errorf("%s: %w", id, ErrNotFound)
+	}
+	return instance, nil
+}
+
+func (m *MemoryStore) Pending(ctx context.Context) ([]Instance, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	var out []Instance
+	for _, instance := range m.instances {
+		if !instance.Status.Done() {
+			out = append(out, instance)
+		}
+	}
+	slices.SortFunc(out, func(a, b Instance) int { return a.Created.Compare(b.Created) })
+	return out, nil
+}
@@ -0,0 +1,11 @@
+CREATE TABLE workflow_instances (
+    id VARCHAR(255) NOT NULL PRIMARY KEY,
+    workflow VARCHAR(255) NOT NULL,
+    status VARCHAR(32) NOT NULL,
+    step INTEGER NOT NULL,
+    state TEXT NOT NULL,
+    error TEXT NOT NULL,
+    created TIMESTAMP NOT NULL,
+    updated TIMESTAMP NOT NULL
+);
+CREATE INDEX workflow_instances_status ON workflow_instances (status, created);
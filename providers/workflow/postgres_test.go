@@ -0,0 +1,13 @@
+//go:build postgres
+
+package workflow
+
+import (
+	"testing"
+
+	"github.com/alecthomas/zero/providers/sql/sqltest"
+)
+
+func TestPostgresStore(t *testing.T) {
+	testSQLStore(t, sqltest.PostgresDSN)
+}
@@ -0,0 +1,110 @@
+package workflow
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/alecthomas/errors"
+	zerosql "github.com/alecthomas/zero/providers/sql"
+)
+
+type SQLStore struct {
+	db     *sql.DB
+	q      func(string) string
+	driver zerosql.Driver
+}
+
+var _ Store = (*SQLStore)(nil)
+
+// NewSQLStore creates a [Store] that persists workflow instances in an SQL database.
+//
+//zero:provider weak require="github.com/alecthomas/zero/providers/workflow/migrations.Migrations"
+func NewSQLStore(driver zerosql.Driver, db *sql.DB) Store {
+	return &SQLStore{db: db, q: driver.Denormalise, driver: driver}
+}
+
+func (s *SQLStore) Create(ctx context.Context, instance Instance) error {
+	_, err := s.db.ExecContext(ctx, s.q(`
+		INSERT INTO workflow_instances (id, workflow, status, step, state, error, created, updated)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`), instance.ID, instance.Workflow, instance.Status, instance.Step, string(instance.State), instance.Error,
+		instance.Created, instance.Updated)
+	if err != nil {
+		return errors.Errorf("workflow instance %s: %w", instance.ID, s.driver.TranslateError(err))
+	}
+	return nil
+}
+
+func (s *SQLStore) Update(ctx context.Context, instance Instance) error {
+	result, err := s.db.ExecContext(ctx, s.q(`
+		UPDATE workflow_instances
+		SET status = ?, step = ?, state = ?, error = ?, updated = ?
+		WHERE id = ?
+	`), instance.Status, instance.Step, string(instance.State), instance.Error, instance.Updated, instance.ID)
+	if err != nil {
+		return errors.Errorf("workflow instance %s: %w", instance.ID, s.driver.TranslateError(err))
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return errors.WithStack(s.driver.TranslateError(err))
+	}
+	if count == 0 {
+		return errors.Errorf("%s: %w", instance.ID, ErrNotFound)
+	}
+	return nil
+}
+
+func (s *SQLStore) Get(ctx context.Context, id string) (Instance, error) {
+	row := s.db.QueryRowContext(ctx, s.q(`
+		SELECT id, workflow, status, step, state, error, created, updated
+		FROM workflow_instances
+		WHERE id = ?
+	`), id)
+	instance, err := scanInstance(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Instance{}, errors.Errorf("%s: %w", id, ErrNotFound)
+	} else if err != nil {
+		return Instance{}, errors.Errorf("workflow instance %s: %w", id, s.driver.TranslateError(err))
+	}
+	return instance, nil
+}
+
+func (s *SQLStore) Pending(ctx context.Context) ([]Instance, error) {
+	rows, err := s.db.QueryContext(ctx, s.q(`
+		SELECT id, workflow, status, step, state, error, created, updated
+		FROM workflow_instances
+		WHERE status IN (?, ?)
+		ORDER BY created
+	`), StatusRunning, StatusCompensating)
+	if err != nil {
+		return nil, errors.Errorf("failed to query pending workflows: %w", s.driver.TranslateError(err))
+	}
+	defer rows.Close()
+	var out []Instance
+	for rows.Next() {
+		instance, err := scanInstance(rows)
+		if err != nil {
+			return nil, errors.Errorf("failed to query pending workflows: %w", s.driver.TranslateError(err))
+		}
+		out = append(out, instance)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Errorf("failed to query pending workflows: %w", s.driver.TranslateError(err))
+	}
+	return out, nil
+}
+
+func scanInstance(row interface{ Scan(dest ...any) error }) (Instance, error) {
+	var instance Instance
+	var state string
+	err := row.Scan(&instance.ID, &instance.Workflow, &instance.Status, &instance.Step, &state, &instance.Error,
+		&instance.Created, &instance.Updated)
+	if err != nil {
+		return Instance{}, errors.WithStack(err)
+	}
+	instance.State = json.RawMessage(state)
+	instance.Created = instance.Created.UTC()
+	instance.Updated = instance.Updated.UTC()
+	return instance, nil
+}
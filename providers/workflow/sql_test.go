@@ -0,0 +1,13 @@
+package workflow
+
+import (
+	"testing"
+
+	"github.com/alecthomas/zero/providers/sql/sqltest"
+	"github.com/alecthomas/zero/providers/workflow/migrations"
+)
+
+func testSQLStore(t *testing.T, dsn string) { //nolint
+	db, driver := sqltest.NewForTesting(t, dsn, migrations.Migrations())
+	testStore(t, NewSQLStore(driver, db))
+}
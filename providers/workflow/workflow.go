@@ -0,0 +1,339 @@
+// Package workflow provides durable multi-step workflows, also known as sagas.
+//
+// A workflow is a sequence of steps operating on a JSON serialisable state. The state is persisted in a [Store] after
+// each step, so that a workflow interrupted by a crash or restart is resumed from the step it was on by whichever
+// replica next polls the store. If a step fails, the compensation steps of the preceding steps are run in reverse
+// order to undo their effects.
+//
+// Steps may run more than once, eg. if a replica crashes after a step completes but before its state is persisted, so
+// steps and compensations must be idempotent.
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/errors"
+	"github.com/alecthomas/zero/providers/leases"
+	"go.jetify.com/typeid/v2"
+)
+
+// ErrUnknownWorkflow is returned when a workflow is not registered with the [Engine].
+var ErrUnknownWorkflow = errors.New("unknown workflow")
+
+// ErrNotFound is returned by a [Store] when a workflow instance does not exist.
+var ErrNotFound = errors.New("workflow instance not found")
+
+// Status of a workflow instance.
+type Status string
+
+const (
+	// StatusRunning instances are running their steps.
+	StatusRunning Status = "running"
+	// StatusCompensating instances had a step fail, and are running the compensation steps of the preceding steps.
+	StatusCompensating Status = "compensating"
+	// StatusCompleted instances ran all their steps successfully.
+	StatusCompleted Status = "completed"
+	// StatusFailed instances had a step fail, and have been compensated.
+	StatusFailed Status = "failed"
+)
+
+// Done returns true if the instance has finished, either successfully or not.
+func (s Status) Done() bool { return s == StatusCompleted || s == StatusFailed }
+
+// Step of a workflow.
+type Step[S any] struct {
+	Name string
+	// Run the step, updating the state of the workflow.
+	Run func(ctx context.Context, state *S) error
+	// Compensate undoes the effects of the step if a later step fails, or nil if the step has no effects to undo.
+	Compensate func(ctx context.Context, state *S) error
+}
+
+// Instance is the persisted state of a single run of a workflow.
+type Instance struct {
+	ID       string `json:"id"`
+	Workflow string `json:"workflow"`
+	Status   Status `json:"status"`
+	// Step is the index of the next step to run, or while compensating, of the next step to compensate.
+	Step int `json:"step"`
+	// State is the JSON encoded state of the workflow.
+	State json.RawMessage `json:"state"`
+	// Error is the error returned by the step that failed, if any.
+	Error   string    `json:"error,omitempty"`
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+}
+
+// Store persists workflow instances.
+type Store interface {
+	// Create a new instance.
+	Create(ctx context.Context, instance Instance) error
+	// Update an existing instance.
+	Update(ctx context.Context, instance Instance) error
+	// Get an instance by ID, returning [ErrNotFound] if it does not exist.
+	Get(ctx context.Context, id string) (Instance, error)
+	// Pending returns the instances that have not finished, oldest first.
+	Pending(ctx context.Context) ([]Instance, error)
+}
+
+//zero:config prefix="workflow-"
+type Config struct {
+	PollInterval time.Duration `default:"5s" help:"Interval at which to poll for pending workflows, eg. those interrupted by a crash."`
+	LeaseTimeout time.Duration `default:"1s" help:"Time to wait to acquire the lease of a workflow instance before leaving it to the replica holding it."`
+}
+
+// workflow is the type-erased runner of a registered workflow.
+type workflow func(ctx context.Context, instance *Instance) error
+
+// Engine runs workflows registered with [Register].
+type Engine struct {
+	logger *slog.Logger
+	leaser leases.Leaser
+	store  Store
+	config Config
+	wake   chan struct{}
+
+	lock      sync.Mutex
+	workflows map[string]workflow
+	names     map[reflect.Type]string
+	running   map[string]bool
+}
+
+// NewEngine creates a new workflow [Engine].
+//
+// The [Engine] polls the [Store] for pending workflow instances, and uses [leases.Leaser] to ensure that each instance
+// is run by a single replica at a time.
+//
+//zero:provider
+func NewEngine(ctx context.Context, config Config, logger *slog.Logger, leaser leases.Leaser, store Store) *Engine {
+	e := &Engine{
+		logger:    logger,
+		leaser:    leaser,
+		store:     store,
+		config:    config,
+		wake:      make(chan struct{}, 1),
+		workflows: map[string]workflow{},
+		names:     map[reflect.Type]string{},
+		running:   map[string]bool{},
+	}
+	go e.run(ctx)
+	return e
+}
+
+// Register a workflow with the [Engine].
+//
+// Workflows are identified by their state type, which must be unique, while the name identifies persisted instances
+// of the workflow, and must not change while instances are pending.
+func Register[S any](e *Engine, name string, steps []Step[S]) error {
+	if len(steps) == 0 {
+		return errors.Errorf("workflow %s has no steps", name)
+	}
+	stateType := reflect.TypeFor[S]()
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if _, ok := e.workflows[name]; ok {
+		return errors.Errorf("workflow %s is already registered", name)
+	}
+	if existing, ok := e.names[stateType]; ok {
+		return errors.Errorf("workflow %s: state type %s is already used by workflow %s", name, stateType, existing)
+	}
+	e.workflows[name] = func(ctx context.Context, instance *Instance) error {
+		return runSteps(ctx, e, steps, instance)
+	}
+	e.names[stateType] = name
+	e.logger.Debug("Registered workflow", "workflow", name, "steps", len(steps))
+	e.poll()
+	return nil
+}
+
+// Start a new instance of the workflow with the state type S, returning its ID.
+//
+// The instance is run asynchronously. Use [Engine.Instance] to check its progress.
+func Start[S any](ctx context.Context, e *Engine, state S) (string, error) {
+	stateType := reflect.TypeFor[S]()
+	e.lock.Lock()
+	name, ok := e.names[stateType]
+	e.lock.Unlock()
+	if !ok {
+		return "", errors.Errorf("no workflow with state %s: %w", stateType, ErrUnknownWorkflow)
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return "", errors.Errorf("workflow %s: failed to marshal state: %w", name, err)
+	}
+	now := time.Now().UTC()
+	instance := Instance{
+		ID:       typeid.MustGenerate("workflow").String(),
+		Workflow: name,
+		Status:   StatusRunning,
+		State:    data,
+		Created:  now,
+		Updated:  now,
+	}
+	if err := e.store.Create(ctx, instance); err != nil {
+		return "", errors.Errorf("workflow %s: failed to create instance: %w", name, err)
+	}
+	e.poll()
+	return instance.ID, nil
+}
+
+// Instance returns the current state of a workflow instance.
+func (e *Engine) Instance(ctx context.Context, id string) (Instance, error) {
+	instance, err := e.store.Get(ctx, id)
+	return instance, errors.WithStack(err)
+}
+
+// poll for pending instances as soon as possible.
+func (e *Engine) poll() {
+	select {
+	case e.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (e *Engine) run(ctx context.Context) {
+	ticker := time.NewTicker(e.config.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-e.wake:
+		}
+		pending, err := e.store.Pending(ctx)
+		if err != nil {
+			e.logger.Error("Failed to poll for pending workflows", "error", err)
+			continue
+		}
+		for _, instance := range pending {
+			e.lock.Lock()
+			run, ok := e.workflows[instance.Workflow]
+			if !ok || e.running[instance.ID] {
+				e.lock.Unlock()
+				continue
+			}
+			e.running[instance.ID] = true
+			e.lock.Unlock()
+			go func() {
+				defer func() {
+					e.lock.Lock()
+					delete(e.running, instance.ID)
+					e.lock.Unlock()
+				}()
+				e.resume(ctx, instance.ID, run)
+			}()
+		}
+	}
+}
+
+// resume a pending instance under its lease, running it until it finishes or a compensation fails.
+func (e *Engine) resume(ctx context.Context, id string, run workflow) {
+	lease, err := e.leaser.Acquire(ctx, "workflow/"+id, e.config.LeaseTimeout)
+	if errors.Is(err, leases.ErrLeaseHeld) {
+		return
+	} else if err != nil {
+		e.logger.Error("Failed to acquire lease for workflow", "instance", id, "error", err)
+		return
+	}
+	// Cancel the workflow if the lease is lost while it is running.
+	runCtx, cancel := context.WithCancelCause(ctx)
+	go func() {
+		select {
+		case <-lease.Done:
+			cancel(errors.Errorf("workflow %s: %w", id, leases.ErrLeaseLost))
+		case <-runCtx.Done():
+		}
+	}()
+	defer cancel(nil)
+
+	// Reload the instance now that it is leased, as another replica may have progressed it since it was polled.
+	instance, err := e.store.Get(runCtx, id)
+	if err != nil {
+		e.logger.Error("Failed to load workflow", "instance", id, "error", err)
+	} else if !instance.Status.Done() {
+		if err := run(runCtx, &instance); err != nil {
+			e.logger.Error("Workflow failed", "workflow", instance.Workflow, "instance", id, "error", err)
+		}
+	}
+
+	if lease.Lost() {
+		return
+	}
+	if err := lease.Release(ctx); err != nil {
+		e.logger.Error("Failed to release lease for workflow", "instance", id, "error", err)
+	}
+}
+
+// runSteps runs the remaining steps of an instance, persisting its state after each.
+//
+// A failed step switches the instance to compensating the steps before it. If a compensation fails the instance is
+// left compensating, to be retried when it is next polled.
+func runSteps[S any](ctx context.Context, e *Engine, steps []Step[S], instance *Instance) error {
+	var state S
+	if err := json.Unmarshal(instance.State, &state); err != nil {
+		return errors.Errorf("failed to unmarshal state: %w", err)
+	}
+	for !instance.Status.Done() {
+		if err := ctx.Err(); err != nil {
+			return errors.WithStack(context.Cause(ctx))
+		}
+		switch instance.Status {
+		case StatusRunning:
+			if instance.Step >= len(steps) {
+				instance.Status = StatusCompleted
+				break
+			}
+			step := steps[instance.Step]
+			if err := step.Run(ctx, &state); err != nil {
+				if ctx.Err() != nil {
+					return errors.Errorf("step %s: %w", step.Name, err)
+				}
+				e.logger.Warn("Workflow step failed, compensating", "workflow", instance.Workflow, "instance", instance.ID, "step", step.Name, "error", err)
+				instance.Status = StatusCompensating
+				instance.Error = errors.Errorf("step %s: %w", step.Name, err).Error()
+				instance.Step--
+			} else {
+				instance.Step++
+			}
+
+		case StatusCompensating:
+			if instance.Step < 0 {
+				instance.Status = StatusFailed
+				break
+			}
+			step := steps[instance.Step]
+			if step.Compensate != nil {
+				if err := step.Compensate(ctx, &state); err != nil {
+					return errors.Errorf("compensation of step %s: %w", step.Name, err)
+				}
+			}
+			instance.Step--
+
+		default:
+			return errors.Errorf("unknown status %q", instance.Status)
+		}
+		if err := save(ctx, e.store, instance, state); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func save[S any](ctx context.Context, store Store, instance *Instance, state S) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return errors.Errorf("failed to marshal state: %w", err)
+	}
+	instance.State = data
+	instance.Updated = time.Now().UTC()
+	if err := store.Update(ctx, *instance); err != nil {
+		return errors.Errorf("failed to persist workflow: %w", err)
+	}
+	return nil
+}
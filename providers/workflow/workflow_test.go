@@ -0,0 +1,185 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/alecthomas/zero/providers/leases"
+	"github.com/alecthomas/zero/providers/logging/loggingtest"
+)
+
+type orderState struct {
+	Order    string   `json:"order"`
+	Reserved bool     `json:"reserved"`
+	Charged  bool     `json:"charged"`
+	Log      []string `json:"log"`
+}
+
+func newTestEngine(t *testing.T, store Store) *Engine {
+	t.Helper()
+	return NewEngine(t.Context(), Config{PollInterval: time.Millisecond * 50, LeaseTimeout: time.Millisecond * 100},
+		loggingtest.NewForTesting(), leases.NewMemoryLeaser(), store)
+}
+
+func orderSteps(charge func(ctx context.Context, state *orderState) error) []Step[orderState] {
+	return []Step[orderState]{
+		{
+			Name: "Reserve",
+			Run: func(ctx context.Context, state *orderState) error {
+				state.Reserved = true
+				state.Log = append(state.Log, "reserve")
+				return nil
+			},
+			Compensate: func(ctx context.Context, state *orderState) error {
+				state.Reserved = false
+				state.Log = append(state.Log, "release")
+				return nil
+			},
+		},
+		{Name: "Charge", Run: charge},
+	}
+}
+
+func waitForStatus(t *testing.T, engine *Engine, id string, status Status) Instance {
+	t.Helper()
+	deadline := time.Now().Add(time.Second * 5)
+	for {
+		instance, err := engine.Instance(t.Context(), id)
+		assert.NoError(t, err)
+		if instance.Status == status {
+			return instance
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s, instance is %s at step %d", status, instance.Status, instance.Step)
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+func TestWorkflowCompletes(t *testing.T) {
+	engine := newTestEngine(t, NewMemoryStore())
+	err := Register(engine, "order", orderSteps(func(ctx context.Context, state *orderState) error {
+		state.Charged = true
+		state.Log = append(state.Log, "charge")
+		return nil
+	}))
+	assert.NoError(t, err)
+
+	id, err := Start(t.Context(), engine, orderState{Order: "o1"})
+	assert.NoError(t, err)
+	instance := waitForStatus(t, engine, id, StatusCompleted)
+	assert.Equal(t, 2, instance.Step)
+	assert.Equal(t, `{"order":"o1","reserved":true,"charged":true,"log":["reserve","charge"]}`, string(instance.State))
+}
+
+func TestWorkflowCompensates(t *testing.T) {
+	engine := newTestEngine(t, NewMemoryStore())
+	err := Register(engine, "order", orderSteps(func(ctx context.Context, state *orderState) error {
+		return errors.New("card declined")
+	}))
+	assert.NoError(t, err)
+
+	id, err := Start(t.Context(), engine, orderState{Order: "o1"})
+	assert.NoError(t, err)
+	instance := waitForStatus(t, engine, id, StatusFailed)
+	assert.Equal(t, "step Charge: card declined", instance.Error)
+	assert.Equal(t, `{"order":"o1","reserved":false,"charged":false,"log":["reserve","release"]}`, string(instance.State))
+}
+
+func TestWorkflowResumes(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Now().UTC()
+	// An instance interrupted after its first step, eg. by a crash.
+	err := store.Create(t.Context(), Instance{
+		ID:       "workflow_interrupted",
+		Workflow: "order",
+		Status:   StatusRunning,
+		Step:     1,
+		State:    []byte(`{"order":"o1","reserved":true,"log":["reserve"]}`),
+		Created:  now,
+		Updated:  now,
+	})
+	assert.NoError(t, err)
+
+	engine := newTestEngine(t, store)
+	var charges atomic.Int32
+	err = Register(engine, "order", orderSteps(func(ctx context.Context, state *orderState) error {
+		charges.Add(1)
+		state.Charged = true
+		state.Log = append(state.Log, "charge")
+		return nil
+	}))
+	assert.NoError(t, err)
+
+	instance := waitForStatus(t, engine, "workflow_interrupted", StatusCompleted)
+	assert.Equal(t, int32(1), charges.Load())
+	assert.Equal(t, `{"order":"o1","reserved":true,"charged":true,"log":["reserve","charge"]}`, string(instance.State))
+}
+
+func TestWorkflowRetriesFailedCompensation(t *testing.T) {
+	engine := newTestEngine(t, NewMemoryStore())
+	var attempts atomic.Int32
+	err := Register(engine, "order", []Step[orderState]{
+		{
+			Name: "Reserve",
+			Run:  func(ctx context.Context, state *orderState) error { return nil },
+			Compensate: func(ctx context.Context, state *orderState) error {
+				if attempts.Add(1) == 1 {
+					return errors.New("inventory unavailable")
+				}
+				return nil
+			},
+		},
+		{Name: "Charge", Run: func(ctx context.Context, state *orderState) error { return errors.New("card declined") }},
+	})
+	assert.NoError(t, err)
+
+	id, err := Start(t.Context(), engine, orderState{Order: "o1"})
+	assert.NoError(t, err)
+	waitForStatus(t, engine, id, StatusFailed)
+	assert.Equal(t, int32(2), attempts.Load())
+}
+
+func TestRegisterErrors(t *testing.T) {
+	engine := newTestEngine(t, NewMemoryStore())
+	assert.EqualError(t, Register(engine, "empty", []Step[orderState]{}), "workflow empty has no steps")
+	assert.NoError(t, Register(engine, "order", orderSteps(nil)))
+	assert.EqualError(t, Register(engine, "order", orderSteps(nil)), "workflow order is already registered")
+	assert.EqualError(t, Register(engine, "other", orderSteps(nil)),
+		"workflow other: state type workflow.orderState is already used by workflow order")
+	_, err := Start(t.Context(), engine, struct{}{})
+	assert.IsError(t, err, ErrUnknownWorkflow)
+}
+
+func testStore(t *testing.T, store Store) { //nolint
+	now := time.Now().UTC().Truncate(time.Second)
+	instance := Instance{ID: "workflow_1", Workflow: "order", Status: StatusRunning, State: []byte(`{}`), Created: now, Updated: now}
+	assert.NoError(t, store.Create(t.Context(), instance))
+	assert.Error(t, store.Create(t.Context(), instance))
+	assert.NoError(t, store.Create(t.Context(), Instance{ID: "workflow_2", Workflow: "order", Status: StatusCompleted, State: []byte(`{}`), Created: now, Updated: now}))
+
+	pending, err := store.Pending(t.Context())
+	assert.NoError(t, err)
+	assert.Equal(t, []Instance{instance}, pending)
+
+	instance.Status = StatusCompensating
+	instance.Step = -1
+	instance.State = []byte(`{"order":"o1"}`)
+	instance.Error = "failed"
+	assert.NoError(t, store.Update(t.Context(), instance))
+	got, err := store.Get(t.Context(), "workflow_1")
+	assert.NoError(t, err)
+	assert.Equal(t, instance, got)
+
+	_, err = store.Get(t.Context(), "workflow_missing")
+	assert.IsError(t, err, ErrNotFound)
+	assert.IsError(t, store.Update(t.Context(), Instance{ID: "workflow_missing"}), ErrNotFound)
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore())
+}
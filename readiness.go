@@ -0,0 +1,31 @@
+package zero
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// Readiness flags whether a service is ready to serve requests.
+//
+// The generated Run marks the service as ready once all of its roots have been constructed, including applying
+// database migrations, and its handlers, subscribers and cron jobs have been registered.
+type Readiness struct {
+	ready atomic.Bool
+}
+
+var _ http.Handler = (*Readiness)(nil)
+
+// Ready returns true if the service is ready.
+func (r *Readiness) Ready() bool { return r.ready.Load() }
+
+// SetReady sets whether the service is ready.
+func (r *Readiness) SetReady(ready bool) { r.ready.Store(ready) }
+
+// ServeHTTP responds with 200 OK if the service is ready, or 503 Service Unavailable otherwise.
+func (r *Readiness) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if !r.Ready() {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+	_, _ = w.Write([]byte("ok\n"))
+}
@@ -0,0 +1,23 @@
+package zero
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestReadiness(t *testing.T) {
+	readiness := &Readiness{}
+	w := httptest.NewRecorder()
+	readiness.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/_readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	readiness.SetReady(true)
+	assert.True(t, readiness.Ready())
+	w = httptest.NewRecorder()
+	readiness.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/_readyz", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "ok\n", w.Body.String())
+}